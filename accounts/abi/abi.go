@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/big"
 )
 
 // The ABI holds information about a contract's context and available
@@ -144,3 +145,28 @@ func (abi *ABI) MethodById(sigdata []byte) *Method {
 	}
 	return nil
 }
+
+// revertSelector is the selector of Error(string), with which solidity encodes
+// the reasons given to reverts.
+var revertSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+
+// UnpackRevert decodes the reason from the output of a reverted call, if it was
+// given as a string.
+func UnpackRevert(output []byte) (string, bool) {
+	if len(output) < 4+2*32 || !bytes.Equal(output[:4], revertSelector) {
+		return "", false
+	}
+	data := output[4:]
+
+	offset := new(big.Int).SetBytes(data[:32])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(data)-32) {
+		return "", false
+	}
+	start := offset.Uint64() + 32
+
+	size := new(big.Int).SetBytes(data[start-32 : start])
+	if !size.IsUint64() || size.Uint64() > uint64(len(data))-start {
+		return "", false
+	}
+	return string(data[start : start+size.Uint64()]), true
+}
@@ -698,3 +698,24 @@ func TestABI_MethodById(t *testing.T) {
 	}
 
 }
+
+func TestUnpackRevert(t *testing.T) {
+	tests := []struct {
+		input  string
+		reason string
+		ok     bool
+	}{
+		{"", "", false},
+		{"08c379a1", "", false},
+		{"08c379a00000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000", "", true},
+		{"08c379a00000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000000d72657665727420726561736f6e00000000000000000000000000000000000000", "revert reason", true},
+		{"08c379a00000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000001072657665727420726561736f6e", "", false},
+		{"08c379a0ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff0000000000000000000000000000000000000000000000000000000000000000", "", false},
+	}
+	for i, test := range tests {
+		reason, ok := UnpackRevert(common.Hex2Bytes(test.input))
+		if reason != test.reason || ok != test.ok {
+			t.Errorf("test %d: have (%q, %v), want (%q, %v)", i, reason, ok, test.reason, test.ok)
+		}
+	}
+}
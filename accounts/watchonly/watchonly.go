@@ -0,0 +1,266 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package watchonly implements an account backend tracking addresses without
+// any key material, e.g. cold storage accounts whose balances should be
+// monitored by the node.
+package watchonly
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	Dacchain "github.com/Aurorachain-io/go-aoa"
+	"github.com/Aurorachain-io/go-aoa/accounts"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/event"
+)
+
+// Scheme is the URL scheme of watch-only wallets.
+const Scheme = "watch"
+
+// BackendType is the reflect type of a watch-only backend.
+var BackendType = reflect.TypeOf(&Backend{})
+
+// ErrWatchOnly is returned for any signing request on a watch-only account.
+var ErrWatchOnly = errors.New("watch-only account cannot sign")
+
+// ErrAlreadyWatched is returned if an address is added that is already watched.
+var ErrAlreadyWatched = errors.New("address already watched")
+
+// Backend is an accounts.Backend holding a set of watch-only addresses, each one
+// wrapped into its own wallet. The set is persisted into a JSON file, if a path
+// was given.
+type Backend struct {
+	path    string                     // File to persist the watched addresses into
+	wallets map[common.Address]*wallet // Currently watched addresses
+
+	updateFeed  event.Feed              // Event feed to notify wallet additions/removals
+	updateScope event.SubscriptionScope // Subscription scope tracking current live listeners
+
+	mu sync.RWMutex
+}
+
+// NewBackend creates a watch-only backend, loading any previously watched
+// addresses from the given file. An empty path disables persistence.
+func NewBackend(path string) (*Backend, error) {
+	b := &Backend{
+		path:    path,
+		wallets: make(map[common.Address]*wallet),
+	}
+	if path == "" {
+		return b, nil
+	}
+	blob, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var addrs []common.Address
+	if err := json.Unmarshal(blob, &addrs); err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		b.wallets[addr] = b.newWallet(addr)
+	}
+	return b, nil
+}
+
+// Wallets implements accounts.Backend, returning the watch-only wallets sorted
+// by URL.
+func (b *Backend) Wallets() []accounts.Wallet {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	wallets := make([]accounts.Wallet, 0, len(b.wallets))
+	for _, w := range b.wallets {
+		wallets = append(wallets, w)
+	}
+	sort.Slice(wallets, func(i, j int) bool { return wallets[i].URL().Cmp(wallets[j].URL()) < 0 })
+	return wallets
+}
+
+// Subscribe implements accounts.Backend, creating an async subscription to
+// receive notifications on the addition or removal of watch-only wallets.
+func (b *Backend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return b.updateScope.Track(b.updateFeed.Subscribe(sink))
+}
+
+// Addresses returns the currently watched addresses.
+func (b *Backend) Addresses() []common.Address {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	addrs := make([]common.Address, 0, len(b.wallets))
+	for addr := range b.wallets {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return strings.Compare(addrs[i].Hex(), addrs[j].Hex()) < 0 })
+	return addrs
+}
+
+// Watched reports whether the given address is watched.
+func (b *Backend) Watched(addr common.Address) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	_, ok := b.wallets[addr]
+	return ok
+}
+
+// Watch starts tracking a new address, returning its watch-only account.
+func (b *Backend) Watch(addr common.Address) (accounts.Account, error) {
+	b.mu.Lock()
+	if _, ok := b.wallets[addr]; ok {
+		b.mu.Unlock()
+		return accounts.Account{}, ErrAlreadyWatched
+	}
+	w := b.newWallet(addr)
+	b.wallets[addr] = w
+	if err := b.persist(); err != nil {
+		delete(b.wallets, addr)
+		b.mu.Unlock()
+		return accounts.Account{}, err
+	}
+	b.mu.Unlock()
+
+	b.updateFeed.Send(accounts.WalletEvent{Wallet: w, Kind: accounts.WalletArrived})
+	return w.account, nil
+}
+
+// Unwatch stops tracking an address.
+func (b *Backend) Unwatch(addr common.Address) error {
+	b.mu.Lock()
+	w, ok := b.wallets[addr]
+	if !ok {
+		b.mu.Unlock()
+		return accounts.ErrUnknownAccount
+	}
+	delete(b.wallets, addr)
+	if err := b.persist(); err != nil {
+		b.wallets[addr] = w
+		b.mu.Unlock()
+		return err
+	}
+	b.mu.Unlock()
+
+	b.updateFeed.Send(accounts.WalletEvent{Wallet: w, Kind: accounts.WalletDropped})
+	return nil
+}
+
+// Close terminates all live wallet subscriptions.
+func (b *Backend) Close() {
+	b.updateScope.Close()
+}
+
+// newWallet wraps a watched address into a wallet.
+func (b *Backend) newWallet(addr common.Address) *wallet {
+	return &wallet{account: accounts.Account{
+		Address: addr,
+		URL:     accounts.URL{Scheme: Scheme, Path: strings.ToLower(addr.Hex())},
+	}}
+}
+
+// persist writes the watched addresses into the backing file. The caller must
+// hold the write lock.
+func (b *Backend) persist() error {
+	if b.path == "" {
+		return nil
+	}
+	addrs := make([]common.Address, 0, len(b.wallets))
+	for addr := range b.wallets {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return strings.Compare(addrs[i].Hex(), addrs[j].Hex()) < 0 })
+
+	blob, err := json.MarshalIndent(addrs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0700); err != nil {
+		return err
+	}
+	tmp := b.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, blob, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, b.path)
+}
+
+// wallet implements accounts.Wallet for a single watch-only address. All the
+// signing methods fail with ErrWatchOnly.
+type wallet struct {
+	account accounts.Account
+}
+
+// URL implements accounts.Wallet, returning the URL of the watched account.
+func (w *wallet) URL() accounts.URL { return w.account.URL }
+
+// Status implements accounts.Wallet, always reporting the wallet as watch-only.
+func (w *wallet) Status() (string, error) { return "Watch-only", nil }
+
+// Open implements accounts.Wallet, but is a noop since there is nothing to open.
+func (w *wallet) Open(passphrase string) error { return nil }
+
+// Close implements accounts.Wallet, but is a noop since there is nothing to close.
+func (w *wallet) Close() error { return nil }
+
+// Accounts implements accounts.Wallet, returning the single watched account.
+func (w *wallet) Accounts() []accounts.Account { return []accounts.Account{w.account} }
+
+// Contains implements accounts.Wallet, returning whether a particular account is
+// the watched one.
+func (w *wallet) Contains(account accounts.Account) bool {
+	return account.Address == w.account.Address && (account.URL == (accounts.URL{}) || account.URL == w.account.URL)
+}
+
+// Derive implements accounts.Wallet, but is not supported for watched addresses.
+func (w *wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet, but is a noop for watched addresses.
+func (w *wallet) SelfDerive(base accounts.DerivationPath, chain Dacchain.ChainStateReader) {}
+
+// SignHash implements accounts.Wallet, always failing with ErrWatchOnly.
+func (w *wallet) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	return nil, ErrWatchOnly
+}
+
+// SignTx implements accounts.Wallet, always failing with ErrWatchOnly.
+func (w *wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, ErrWatchOnly
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, always failing with ErrWatchOnly.
+func (w *wallet) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, ErrWatchOnly
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, always failing with ErrWatchOnly.
+func (w *wallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, ErrWatchOnly
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package watchonly
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/accounts"
+	"github.com/Aurorachain-io/go-aoa/common"
+)
+
+func TestWatchPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watchonly-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "watchonly.json")
+
+	backend, err := NewBackend(path)
+	if err != nil {
+		t.Fatalf("failed to create backend: %v", err)
+	}
+	addrs := []common.Address{common.HexToAddress("0x01"), common.HexToAddress("0x02")}
+	for _, addr := range addrs {
+		if _, err := backend.Watch(addr); err != nil {
+			t.Fatalf("failed to watch %x: %v", addr, err)
+		}
+	}
+	if _, err := backend.Watch(addrs[0]); err != ErrAlreadyWatched {
+		t.Fatalf("duplicate watch error mismatch: have %v, want %v", err, ErrAlreadyWatched)
+	}
+	if err := backend.Unwatch(addrs[1]); err != nil {
+		t.Fatalf("failed to unwatch: %v", err)
+	}
+	reloaded, err := NewBackend(path)
+	if err != nil {
+		t.Fatalf("failed to reload backend: %v", err)
+	}
+	if have := reloaded.Addresses(); !reflect.DeepEqual(have, addrs[:1]) {
+		t.Fatalf("reloaded addresses mismatch: have %x, want %x", have, addrs[:1])
+	}
+}
+
+func TestWatchOnlyManager(t *testing.T) {
+	backend, _ := NewBackend("")
+	am := accounts.NewManager(backend)
+	defer am.Close()
+
+	addr := common.HexToAddress("0x0123")
+	if _, err := backend.Watch(addr); err != nil {
+		t.Fatalf("failed to watch: %v", err)
+	}
+	// The manager picks up new wallets asynchronously
+	var wallet accounts.Wallet
+	for i := 0; i < 100 && wallet == nil; i++ {
+		wallet, _ = am.Find(accounts.Account{Address: addr})
+		time.Sleep(10 * time.Millisecond)
+	}
+	if wallet == nil {
+		t.Fatalf("watch-only account not found in manager")
+	}
+	if _, err := wallet.SignHash(accounts.Account{Address: addr}, make([]byte, 32)); err != ErrWatchOnly {
+		t.Fatalf("signing error mismatch: have %v, want %v", err, ErrWatchOnly)
+	}
+}
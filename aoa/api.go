@@ -20,6 +20,7 @@ import (
 	"compress/gzip"
 	"context"
 	"fmt"
+	"github.com/Aurorachain-io/go-aoa/aoa/downloader"
 	"github.com/Aurorachain-io/go-aoa/common"
 	"github.com/Aurorachain-io/go-aoa/common/hexutil"
 	"github.com/Aurorachain-io/go-aoa/core"
@@ -32,9 +33,228 @@ import (
 	"github.com/Aurorachain-io/go-aoa/trie"
 	"io"
 	"os"
+	"runtime"
 	"strings"
 )
 
+// PublicDacchainAPI provides an API to access aurora chain full node-related
+// information.
+type PublicDacchainAPI struct {
+	dac *Dacchain
+}
+
+// NewPublicDacchainAPI creates a new aurora chain protocol API for full nodes.
+func NewPublicDacchainAPI(dac *Dacchain) *PublicDacchainAPI {
+	return &PublicDacchainAPI{dac: dac}
+}
+
+// EpochUtilizationResult is the result of an aoa_getEpochUtilization API call.
+type EpochUtilizationResult struct {
+	Epoch           hexutil.Uint64   `json:"epoch"`
+	FirstBlock      hexutil.Uint64   `json:"firstBlock"`
+	LastBlock       hexutil.Uint64   `json:"lastBlock"`
+	Complete        bool             `json:"complete"`
+	Blocks          hexutil.Uint64   `json:"blocks"`
+	GasUsed         hexutil.Uint64   `json:"gasUsed"`
+	TxCount         hexutil.Uint64   `json:"txCount"`
+	MaxGasUsed      hexutil.Uint64   `json:"maxGasUsed"`
+	MaxTxCount      hexutil.Uint64   `json:"maxTxCount"`
+	GasUsedHist     []hexutil.Uint64 `json:"gasUsedHistogram"`
+	TxCountHist     []hexutil.Uint64 `json:"txCountHistogram"`
+	UtilizationHist []hexutil.Uint64 `json:"utilizationHistogram"`
+}
+
+// GetEpochUtilization returns the per-block gas used, transaction count and gas
+// limit utilization histograms of the given epoch. Epochs that are already
+// indexed are served from the database, the still running epoch is aggregated
+// on the fly from the canonical chain.
+func (api *PublicDacchainAPI) GetEpochUtilization(epoch hexutil.Uint64) (*EpochUtilizationResult, error) {
+	head := api.dac.blockchain.CurrentBlock().NumberU64()
+	if uint64(epoch) > api.dac.chainConfig.EpochOf(head) {
+		return nil, fmt.Errorf("epoch %d not reached yet", epoch)
+	}
+	util := core.GetEpochUtilization(api.dac.chainDb, uint64(epoch))
+	if util == nil {
+		util = core.NewEpochUtilization(uint64(epoch))
+		length := api.dac.chainConfig.Epoch()
+		first, last := uint64(epoch)*length, (uint64(epoch)+1)*length-1
+		if last > head {
+			last = head
+		}
+		err := core.ForEachCanonicalBlock(api.dac.chainDb, first, last, func(block *types.Block, _ types.Receipts) error {
+			util.Add(block.GasUsed(), block.GasLimit(), len(block.Transactions()))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	hist := func(buckets []uint64) []hexutil.Uint64 {
+		res := make([]hexutil.Uint64, len(buckets))
+		for i, count := range buckets {
+			res[i] = hexutil.Uint64(count)
+		}
+		return res
+	}
+	length := api.dac.chainConfig.Epoch()
+	first := util.Epoch * length
+	return &EpochUtilizationResult{
+		Epoch:           hexutil.Uint64(util.Epoch),
+		FirstBlock:      hexutil.Uint64(first),
+		LastBlock:       hexutil.Uint64(first + util.Blocks - 1),
+		Complete:        util.Blocks == length,
+		Blocks:          hexutil.Uint64(util.Blocks),
+		GasUsed:         hexutil.Uint64(util.GasUsed),
+		TxCount:         hexutil.Uint64(util.TxCount),
+		MaxGasUsed:      hexutil.Uint64(util.MaxGas),
+		MaxTxCount:      hexutil.Uint64(util.MaxTxs),
+		GasUsedHist:     hist(util.GasUsedHist),
+		TxCountHist:     hist(util.TxCountHist),
+		UtilizationHist: hist(util.UtilizationHist),
+	}, nil
+}
+
+// PendingLogs returns the logs generated by the transactions of the pending
+// block assembled by the node.
+func (api *PublicDacchainAPI) PendingLogs() []*types.Log {
+	logs := api.dac.dposMiner.PendingLogs()
+	if logs == nil {
+		return []*types.Log{}
+	}
+	return logs
+}
+
+// PendingReceipts returns the receipts of the transactions of the pending block
+// assembled by the node.
+func (api *PublicDacchainAPI) PendingReceipts() types.Receipts {
+	receipts := api.dac.dposMiner.PendingReceipts()
+	if receipts == nil {
+		return types.Receipts{}
+	}
+	return receipts
+}
+
+// IndexedTransaction is a transaction reference returned by the optional chain
+// index lookups.
+type IndexedTransaction struct {
+	BlockHash        common.Hash    `json:"blockHash"`
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	TransactionHash  common.Hash    `json:"transactionHash"`
+	TransactionIndex hexutil.Uint   `json:"transactionIndex"`
+}
+
+// indexedTransactions resolves the transaction hashes of index lookup entries.
+func (api *PublicDacchainAPI) indexedTransactions(entries []core.TxLookupEntry) []*IndexedTransaction {
+	txs := make([]*IndexedTransaction, 0, len(entries))
+	for _, entry := range entries {
+		body := core.GetBody(api.dac.chainDb, entry.BlockHash, entry.BlockIndex)
+		if body == nil || entry.Index >= uint64(len(body.Transactions)) {
+			continue
+		}
+		txs = append(txs, &IndexedTransaction{
+			BlockHash:        entry.BlockHash,
+			BlockNumber:      hexutil.Uint64(entry.BlockIndex),
+			TransactionHash:  body.Transactions[entry.Index].Hash(),
+			TransactionIndex: hexutil.Uint(entry.Index),
+		})
+	}
+	return txs
+}
+
+// blockRange resolves the bounds of a block range lookup.
+func (api *PublicDacchainAPI) blockRange(fromBlock, toBlock rpc.BlockNumber) (uint64, uint64, error) {
+	head := api.dac.blockchain.CurrentBlock().NumberU64()
+	resolve := func(number rpc.BlockNumber) uint64 {
+		if number < 0 {
+			return head
+		}
+		return uint64(number)
+	}
+	from, to := resolve(fromBlock), resolve(toBlock)
+	if from > to {
+		return 0, 0, fmt.Errorf("invalid block range %d > %d", from, to)
+	}
+	return from, to, nil
+}
+
+// GetLogTransactions returns the transactions within the block range which
+// emitted logs from the given address. It requires the logs index.
+func (api *PublicDacchainAPI) GetLogTransactions(address common.Address, fromBlock, toBlock rpc.BlockNumber) ([]*IndexedTransaction, error) {
+	from, to, err := api.blockRange(fromBlock, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := api.dac.lookupAddressIndex(IndexLogs, address, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return api.indexedTransactions(entries), nil
+}
+
+// GetTokenTransfers returns the transactions within the block range which
+// transferred assets or tokens from or to the given address. It requires the
+// transfers index.
+func (api *PublicDacchainAPI) GetTokenTransfers(address common.Address, fromBlock, toBlock rpc.BlockNumber) ([]*IndexedTransaction, error) {
+	from, to, err := api.blockRange(fromBlock, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := api.dac.lookupAddressIndex(IndexTransfers, address, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return api.indexedTransactions(entries), nil
+}
+
+// GetContractCreation returns the transaction which created the contract at the
+// given address, nil if it's unknown. It requires the creations index.
+func (api *PublicDacchainAPI) GetContractCreation(address common.Address) (*IndexedTransaction, error) {
+	entry, err := api.dac.lookupContractCreation(address)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	if txs := api.indexedTransactions([]core.TxLookupEntry{*entry}); len(txs) > 0 {
+		return txs[0], nil
+	}
+	return nil, nil
+}
+
+// PrivateMinerAPI provides private RPC methods to configure the block producer
+// at runtime. Changes apply to the blocks produced afterwards.
+type PrivateMinerAPI struct {
+	dac *Dacchain
+}
+
+// NewPrivateMinerAPI creates a new RPC service to configure the block producer.
+func NewPrivateMinerAPI(dac *Dacchain) *PrivateMinerAPI {
+	return &PrivateMinerAPI{dac: dac}
+}
+
+// SetExtra sets the extra-data of the blocks, embedded as producer vanity into
+// the structured extra-data from the extra-data v1 fork on.
+func (api *PrivateMinerAPI) SetExtra(extra string) (bool, error) {
+	if err := api.dac.DposMiner().SetExtra([]byte(extra)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetGasLimit sets the gas limit the produced blocks move towards. Zero restores
+// the default target.
+func (api *PrivateMinerAPI) SetGasLimit(gasLimit hexutil.Uint64) (bool, error) {
+	if err := api.dac.DposMiner().SetGasLimit(uint64(gasLimit)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetCoinbase sets the beneficiary of the pending blocks, and the producer of
+// instant sealing chains if it is a delegate.
+func (api *PrivateMinerAPI) SetCoinbase(coinbase common.Address) bool {
+	api.dac.DposMiner().SetCoinbase(coinbase)
+	return true
+}
+
 // PrivateAdminAPI is the collection of eminer-pro full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -63,20 +283,165 @@ func (api *PrivateAdminAPI) ExportChain(file string) (bool, error) {
 	}
 
 	// Export the blockchain
-	if err := api.dac.BlockChain().Export(writer); err != nil {
+	chain := api.dac.BlockChain()
+	if err := chain.Export(writer, 0, chain.CurrentBlock().NumberU64(), nil); err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
-func hasAllBlocks(chain *core.BlockChain, bs []*types.Block) bool {
-	for _, b := range bs {
-		if !chain.HasBlock(b.Hash(), b.NumberU64()) {
-			return false
+// DumpTxPool writes all pending and queued transactions of the pool into a local
+// file, allowing them to be restored with LoadTxPool after a planned restart.
+func (api *PrivateAdminAPI) DumpTxPool(file string) (int, error) {
+	return api.dac.TxPool().DumpTransactions(file)
+}
+
+// LoadTxPool restores the transactions saved by DumpTxPool into the pool,
+// returning the number of transactions accepted.
+func (api *PrivateAdminAPI) LoadTxPool(file string) (int, error) {
+	return api.dac.TxPool().LoadTransactions(file)
+}
+
+// SyncPeers retrieves the retrieval statistics the downloader measured for each
+// of the peers it synchronises from.
+func (api *PrivateAdminAPI) SyncPeers() []downloader.PeerSyncStats {
+	return api.dac.Downloader().PeerStats()
+}
+
+// IndexProgress is the backfill progress of an optional chain index.
+type IndexProgress struct {
+	Sections      hexutil.Uint64  `json:"sections"`      // Number of sections indexed
+	KnownSections hexutil.Uint64  `json:"knownSections"` // Number of sections ready to be indexed
+	IndexedBlock  *hexutil.Uint64 `json:"indexedBlock"`  // Last block covered by the index, nil if none
+	Paused        bool            `json:"paused"`        // Whether indexing is paused
+}
+
+// IndexProgress returns the backfill progress of the bloom bits index used by
+// log queries and of the enabled optional chain indexes.
+func (api *PrivateAdminAPI) IndexProgress() map[string]*IndexProgress {
+	progress := map[string]*IndexProgress{
+		"bloombits": newIndexProgress(api.dac.bloomIndexer, params.BloomBitsBlocks),
+	}
+	for kind, indexer := range api.dac.indexers {
+		progress[kind] = newIndexProgress(indexer, addressIndexSectionSize)
+	}
+	return progress
+}
+
+// newIndexProgress reports the progress of a chain indexer with the given
+// section size.
+func newIndexProgress(indexer *core.ChainIndexer, size uint64) *IndexProgress {
+	stored, known, paused := indexer.Progress()
+	progress := &IndexProgress{
+		Sections:      hexutil.Uint64(stored),
+		KnownSections: hexutil.Uint64(known),
+		Paused:        paused,
+	}
+	if stored > 0 {
+		last := hexutil.Uint64(stored*size - 1)
+		progress.IndexedBlock = &last
+	}
+	return progress
+}
+
+// PauseIndexing suspends the backfill of the given optional chain index, or of
+// all of them if none is specified.
+func (api *PrivateAdminAPI) PauseIndexing(kind string) (bool, error) {
+	indexers, err := api.indexers(kind)
+	if err != nil {
+		return false, err
+	}
+	for _, indexer := range indexers {
+		indexer.Pause()
+	}
+	return true, nil
+}
+
+// ResumeIndexing continues the backfill of the given optional chain index, or
+// of all of them if none is specified.
+func (api *PrivateAdminAPI) ResumeIndexing(kind string) (bool, error) {
+	indexers, err := api.indexers(kind)
+	if err != nil {
+		return false, err
+	}
+	for _, indexer := range indexers {
+		indexer.Resume()
+	}
+	return true, nil
+}
+
+// indexers returns the enabled optional chain index of the given kind, or all
+// of them if kind is empty.
+func (api *PrivateAdminAPI) indexers(kind string) ([]*core.ChainIndexer, error) {
+	if kind == "" {
+		indexers := make([]*core.ChainIndexer, 0, len(api.dac.indexers))
+		for _, indexer := range api.dac.indexers {
+			indexers = append(indexers, indexer)
 		}
+		return indexers, nil
 	}
+	indexer := api.dac.indexers[kind]
+	if indexer == nil {
+		return nil, fmt.Errorf("%s index not enabled", kind)
+	}
+	return []*core.ChainIndexer{indexer}, nil
+}
 
-	return true
+// ForkInfo is a fork scheduled in the chain configuration.
+type ForkInfo struct {
+	Name   string       `json:"name"`   // Name of the fork
+	Block  *hexutil.Big `json:"block"`  // Block activating the fork
+	Active bool         `json:"active"` // Whether the fork is active at the current head
+}
+
+// VersionInfo is the build and protocol metadata of a node.
+type VersionInfo struct {
+	Version          string         `json:"version"`          // Release version of the client
+	GitCommit        string         `json:"gitCommit"`        // Source revision the binary was built from
+	GitDate          string         `json:"gitDate"`          // Commit date of the source revision
+	GoVersion        string         `json:"goVersion"`        // Go version the binary was built with
+	OS               string         `json:"os"`               // Operating system the binary was built for
+	Arch             string         `json:"arch"`             // Architecture the binary was built for
+	ProtocolVersions []uint         `json:"protocolVersions"` // Supported aoa wire protocol versions
+	NetworkId        hexutil.Uint64 `json:"networkId"`        // Network the node is connected to
+	DatabaseVersion  int            `json:"databaseVersion"`  // Schema version of the chain database
+	ChainId          *hexutil.Big   `json:"chainId"`          // Chain id used for replay protection
+	HeadNumber       hexutil.Uint64 `json:"headNumber"`       // Number of the current head block
+	HeadHash         common.Hash    `json:"headHash"`         // Hash of the current head block
+	Forks            []ForkInfo     `json:"forks"`            // Forks scheduled in the chain configuration
+}
+
+// VersionInfo returns the build metadata, the supported protocol versions, the
+// database schema version and the forks enabled at the current head, so that
+// fleets of nodes can be audited for homogeneity.
+func (api *PrivateAdminAPI) VersionInfo() *VersionInfo {
+	var (
+		config = api.dac.blockchain.Config()
+		head   = api.dac.blockchain.CurrentBlock()
+	)
+	info := &VersionInfo{
+		Version:          params.Version,
+		GitCommit:        params.GitCommit,
+		GitDate:          params.GitDate,
+		GoVersion:        runtime.Version(),
+		OS:               runtime.GOOS,
+		Arch:             runtime.GOARCH,
+		ProtocolVersions: ProtocolVersions,
+		NetworkId:        hexutil.Uint64(api.dac.networkId),
+		DatabaseVersion:  core.GetBlockChainVersion(api.dac.chainDb),
+		ChainId:          (*hexutil.Big)(config.ChainId),
+		HeadNumber:       hexutil.Uint64(head.NumberU64()),
+		HeadHash:         head.Hash(),
+		Forks:            []ForkInfo{},
+	}
+	for _, fork := range config.Forks() {
+		info.Forks = append(info.Forks, ForkInfo{
+			Name:   fork.Name,
+			Block:  (*hexutil.Big)(fork.Block),
+			Active: fork.Block.Cmp(head.Number()) <= 0,
+		})
+	}
+	return info
 }
 
 // ImportChain imports a blockchain from a local file.
@@ -96,34 +461,8 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	}
 
 	// Run actual the import in pre-configured batches
-	stream := rlp.NewStream(reader, 0)
-
-	blocks, index := make([]*types.Block, 0, 2500), 0
-	for batch := 0; ; batch++ {
-		// Load a batch of blocks from the input file
-		for len(blocks) < cap(blocks) {
-			block := new(types.Block)
-			if err := stream.Decode(block); err == io.EOF {
-				break
-			} else if err != nil {
-				return false, fmt.Errorf("block %d: failed to parse: %v", index, err)
-			}
-			blocks = append(blocks, block)
-			index++
-		}
-		if len(blocks) == 0 {
-			break
-		}
-
-		if hasAllBlocks(api.dac.BlockChain(), blocks) {
-			blocks = blocks[:0]
-			continue
-		}
-		// Import the batch and reset the buffer
-		if _, err := api.dac.BlockChain().InsertChain(blocks); err != nil {
-			return false, fmt.Errorf("batch %d: failed to insert: %v", batch, err)
-		}
-		blocks = blocks[:0]
+	if _, err := api.dac.BlockChain().ImportChain(reader, core.ImportConfig{SkipPresent: true}); err != nil {
+		return false, err
 	}
 	return true, nil
 }
@@ -140,14 +479,41 @@ func NewPublicDebugAPI(dac *Dacchain) *PublicDebugAPI {
 	return &PublicDebugAPI{dac: dac}
 }
 
+// AccountRangeMaxResults is the maximum number of accounts returned by a single
+// debug_accountRange call.
+const AccountRangeMaxResults = 256
+
 // DumpBlock retrieves the entire state of the database at a given block.
 func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error) {
+	stateDb, err := api.stateAt(blockNr)
+	if err != nil {
+		return state.Dump{}, err
+	}
+	return stateDb.RawDump(), nil
+}
+
+// AccountRange enumerates the accounts in the state at a given block, starting
+// at the given hashed trie key. At most maxResults accounts are returned, along
+// with the key to continue from in a subsequent call.
+func (api *PublicDebugAPI) AccountRange(blockNr rpc.BlockNumber, start hexutil.Bytes, maxResults int, nocode, nostorage bool) (state.IteratorDump, error) {
+	stateDb, err := api.stateAt(blockNr)
+	if err != nil {
+		return state.IteratorDump{}, err
+	}
+	if maxResults <= 0 || maxResults > AccountRangeMaxResults {
+		maxResults = AccountRangeMaxResults
+	}
+	return stateDb.IteratorDump(start, maxResults, nocode, nostorage)
+}
+
+// stateAt retrieves the state at a given block, or the pending state.
+func (api *PublicDebugAPI) stateAt(blockNr rpc.BlockNumber) (*state.StateDB, error) {
 	if blockNr == rpc.PendingBlockNumber {
 		// If we're dumping the pending state, we need to request
 		// both the pending block as well as the pending state from
 		// the miner and operate on those
 		_, stateDb := api.dac.dposMiner.Pending()
-		return stateDb.RawDump(), nil
+		return stateDb, nil
 	}
 	var block *types.Block
 	if blockNr == rpc.LatestBlockNumber {
@@ -156,13 +522,9 @@ func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error
 		block = api.dac.blockchain.GetBlockByNumber(uint64(blockNr))
 	}
 	if block == nil {
-		return state.Dump{}, fmt.Errorf("block #%d not found", blockNr)
+		return nil, fmt.Errorf("block #%d not found", blockNr)
 	}
-	stateDb, err := api.dac.BlockChain().StateAt(block.Root())
-	if err != nil {
-		return state.Dump{}, err
-	}
-	return stateDb.RawDump(), nil
+	return api.dac.BlockChain().StateAtHeader(block.Header())
 }
 
 // PrivateDebugAPI is the collection of eminer-pro full node APIs exposed over
@@ -180,8 +542,8 @@ func NewPrivateDebugAPI(config *params.ChainConfig, dac *Dacchain) *PrivateDebug
 
 // Preimage is a debug API function that returns the preimage for a sha3 hash, if known.
 func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
-	db := core.PreimageTable(api.dac.ChainDb())
-	return db.Get(hash.Bytes())
+	// Preimages are buffered in the trie node cache until the next flush
+	return api.dac.blockchain.GetStateDB().TrieDB().Get(core.PreimageKey(hash))
 }
 func (api *PrivateDebugAPI) GetTranTypeNum() interface{} {
 	log.Info("GetTranTypeNum")
@@ -303,11 +665,11 @@ func (api *PrivateDebugAPI) getModifiedAccounts(startBlock, endBlock *types.Bloc
 		return nil, fmt.Errorf("start block height (%d) must be less than end block height (%d)", startBlock.Number().Uint64(), endBlock.Number().Uint64())
 	}
 
-	oldTrie, err := trie.NewSecure(startBlock.Root(), api.dac.chainDb, 0)
+	oldTrie, err := trie.NewSecure(startBlock.Root(), api.dac.blockchain.GetStateDB().TrieDB(), 0)
 	if err != nil {
 		return nil, err
 	}
-	newTrie, err := trie.NewSecure(endBlock.Root(), api.dac.chainDb, 0)
+	newTrie, err := trie.NewSecure(endBlock.Root(), api.dac.blockchain.GetStateDB().TrieDB(), 0)
 	if err != nil {
 		return nil, err
 	}
@@ -325,3 +687,37 @@ func (api *PrivateDebugAPI) getModifiedAccounts(startBlock, endBlock *types.Bloc
 	}
 	return dirty, nil
 }
+
+// GetBlockWitness returns the RLP encoded witness of the block with the given
+// number, containing all state and headers needed to execute it again.
+func (api *PrivateDebugAPI) GetBlockWitness(number rpc.BlockNumber) (hexutil.Bytes, error) {
+	var block *types.Block
+	if number == rpc.LatestBlockNumber {
+		block = api.dac.blockchain.CurrentBlock()
+	} else {
+		block = api.dac.blockchain.GetBlockByNumber(uint64(number))
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	witness, err := api.dac.blockchain.GenerateWitness(block)
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(witness)
+}
+
+// VerifyBlockWitness executes the block with the given hash using only the state
+// contained in the given RLP encoded witness, and checks the results against the
+// block header.
+func (api *PrivateDebugAPI) VerifyBlockWitness(hash common.Hash, blob hexutil.Bytes) error {
+	block := api.dac.blockchain.GetBlockByHash(hash)
+	if block == nil {
+		return fmt.Errorf("block #%x not found", hash)
+	}
+	witness := new(core.Witness)
+	if err := rlp.DecodeBytes(blob, witness); err != nil {
+		return fmt.Errorf("could not decode witness: %v", err)
+	}
+	return core.VerifyWitness(api.dac.blockchain.Config(), api.dac.blockchain.Engine(), block, witness)
+}
@@ -18,7 +18,9 @@ package aoa
 
 import (
 	"context"
+	"errors"
 	"math/big"
+	"time"
 
 	"github.com/Aurorachain-io/go-aoa/accounts"
 	aa "github.com/Aurorachain-io/go-aoa/accounts/walletType"
@@ -48,6 +50,14 @@ func (b *DacApiBackend) ChainConfig() *params.ChainConfig {
 	return b.dac.chainConfig
 }
 
+func (b *DacApiBackend) RPCGasCap() uint64 {
+	return b.dac.config.RPCGasCap
+}
+
+func (b *DacApiBackend) RPCEVMTimeout() time.Duration {
+	return b.dac.config.RPCEVMTimeout
+}
+
 func (b *DacApiBackend) GetDelegateWalletInfoCallback() func(data *aa.DelegateWalletInfo) {
 	return b.dac.protocolManager.GetAddDelegateWalletCallback()
 }
@@ -64,7 +74,7 @@ func (b *DacApiBackend) SetHead(number uint64) {
 func (b *DacApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
 	// Pending block is only known by the delegate
 	if blockNr == rpc.PendingBlockNumber {
-		block := b.dac.dposMiner.PendingBlock()
+		block := b.dac.dposMiner.GetPendingBlock()
 		return block.Header(), nil
 	}
 	// Otherwise resolve and return the block
@@ -74,10 +84,14 @@ func (b *DacApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 	return b.dac.blockchain.GetHeaderByNumber(uint64(blockNr)), nil
 }
 
+func (b *DacApiBackend) HeaderByHash(ctx context.Context, blockHash common.Hash) (*types.Header, error) {
+	return b.dac.blockchain.GetHeaderByHash(blockHash), nil
+}
+
 func (b *DacApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
 	// Pending block is only known by the delegate
 	if blockNr == rpc.PendingBlockNumber {
-		block := b.dac.dposMiner.PendingBlock()
+		block := b.dac.dposMiner.GetPendingBlock()
 		return block, nil
 	}
 	// Otherwise resolve and return the block
@@ -88,18 +102,20 @@ func (b *DacApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 }
 
 func (b *DacApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
-	// Pending state is only known by the delegate
+	// Pending state is the head state with the pending block transactions applied
 	if blockNr == rpc.PendingBlockNumber {
-		currentBlock := b.dac.blockchain.CurrentBlock()
-		statedb, _ := b.dac.blockchain.StateAt(currentBlock.Root())
-		return statedb, currentBlock.Header(), nil
+		block, state := b.dac.dposMiner.Pending()
+		if state == nil {
+			return nil, nil, errors.New("pending state unavailable")
+		}
+		return state, block.Header(), nil
 	}
 	// Otherwise resolve the block number and return its state
 	header, err := b.HeaderByNumber(ctx, blockNr)
 	if header == nil || err != nil {
 		return nil, nil, err
 	}
-	stateDb, err := b.dac.BlockChain().StateAt(header.Root)
+	stateDb, err := b.dac.BlockChain().StateAtHeader(header)
 	return stateDb, header, err
 }
 
@@ -141,6 +157,10 @@ func (b *DacApiBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEven
 	return b.dac.BlockChain().SubscribeRemovedLogsEvent(ch)
 }
 
+func (b *DacApiBackend) SubscribePendingLogsEvent(ch chan<- core.PendingLogsEvent) event.Subscription {
+	return b.dac.dposMiner.SubscribePendingLogsEvent(ch)
+}
+
 func (b *DacApiBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	return b.dac.BlockChain().SubscribeChainEvent(ch)
 }
@@ -153,6 +173,10 @@ func (b *DacApiBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) e
 	return b.dac.BlockChain().SubscribeChainSideEvent(ch)
 }
 
+func (b *DacApiBackend) SubscribeReorgEvent(ch chan<- core.ReorgEvent) event.Subscription {
+	return b.dac.BlockChain().SubscribeReorgEvent(ch)
+}
+
 func (b *DacApiBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return b.dac.BlockChain().SubscribeLogsEvent(ch)
 }
@@ -161,6 +185,10 @@ func (b *DacApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 	return b.dac.txPool.AddLocal(signedTx)
 }
 
+func (b *DacApiBackend) SendTxs(ctx context.Context, signedTxs []*types.Transaction) error {
+	return b.dac.txPool.AddLocalSequence(signedTxs)
+}
+
 func (b *DacApiBackend) GetPoolTransactions() (types.Transactions, error) {
 	pending, err := b.dac.txPool.Pending()
 	if err != nil {
@@ -189,10 +217,22 @@ func (b *DacApiBackend) TxPoolContent() (map[common.Address]types.Transactions,
 	return b.dac.TxPool().Content()
 }
 
+func (b *DacApiBackend) TxPoolContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
+	return b.dac.TxPool().ContentFrom(addr)
+}
+
 func (b *DacApiBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Subscription {
 	return b.dac.TxPool().SubscribeTxPreEvent(ch)
 }
 
+func (b *DacApiBackend) SubscribeTxPromotedEvent(ch chan<- core.TxPromotedEvent) event.Subscription {
+	return b.dac.TxPool().SubscribeTxPromotedEvent(ch)
+}
+
+func (b *DacApiBackend) SubscribeTxDroppedEvent(ch chan<- core.TxDroppedEvent) event.Subscription {
+	return b.dac.TxPool().SubscribeTxDroppedEvent(ch)
+}
+
 func (b *DacApiBackend) Downloader() *downloader.Downloader {
 	return b.dac.Downloader()
 }
@@ -205,6 +245,10 @@ func (b *DacApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *DacApiBackend) FeeHistory(ctx context.Context, blockCount int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	return b.gpo.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
+}
+
 func (b *DacApiBackend) ChainDb() aoadb.Database {
 	return b.dac.ChainDb()
 }
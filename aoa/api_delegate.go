@@ -0,0 +1,300 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/common/hexutil"
+	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/rpc"
+)
+
+// errNoShuffleData is returned if the node did not shuffle any delegate round yet.
+var errNoShuffleData = errors.New("no delegate round shuffled yet")
+
+// PublicDelegateAPI provides an API to inspect the delegate candidates and the
+// shuffled rounds of block producing delegates.
+type PublicDelegateAPI struct {
+	dac *Dacchain
+}
+
+// NewPublicDelegateAPI creates a new delegate API.
+func NewPublicDelegateAPI(dac *Dacchain) *PublicDelegateAPI {
+	return &PublicDelegateAPI{dac: dac}
+}
+
+// DelegateRound is a shuffled round of block producing delegates.
+type DelegateRound struct {
+	ShuffleBlock hexutil.Uint64     `json:"shuffleBlock"`       // Block whose delegate state was shuffled
+	ShuffleHash  common.Hash        `json:"shuffleHash"`        // Hash of the shuffled delegate list
+	ShuffleTime  hexutil.Uint64     `json:"shuffleTime"`        // Time the round began at
+	Delegates    []types.ShuffleDel `json:"delegates"`          // Delegates in block producing order
+	Verified     *bool              `json:"verified,omitempty"` // Whether a recomputed round matches the header
+}
+
+// NextShuffleResult is the result of a delegate_nextShuffle API call.
+type NextShuffleResult struct {
+	Time  hexutil.Uint64 `json:"time"`  // Time the next round begins at
+	Block hexutil.Uint64 `json:"block"` // Estimated number of the first block of the next round
+}
+
+// Candidates returns the delegate candidates at the given block, ordered by the
+// votes they received, weighted by stake and decayed after the vote decay fork.
+func (api *PublicDelegateAPI) Candidates(ctx context.Context, blockNr rpc.BlockNumber) ([]types.Candidate, error) {
+	block, err := api.dac.ApiBackend.BlockByNumber(ctx, blockNr)
+	if block == nil {
+		if err == nil {
+			err = fmt.Errorf("block #%d not found", blockNr)
+		}
+		return nil, err
+	}
+	return api.candidatesAt(block)
+}
+
+// Candidate returns the delegate candidate with the given address at the given
+// block, including the votes it received, or nil if it is not a candidate.
+func (api *PublicDelegateAPI) Candidate(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*types.Candidate, error) {
+	candidates, err := api.Candidates(ctx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	for _, candidate := range candidates {
+		if strings.EqualFold(candidate.Address, address.Hex()) {
+			return &candidate, nil
+		}
+	}
+	return nil, nil
+}
+
+// VoteWeight returns the stake weighted, decayed vote of the delegate candidate
+// computed at the last epoch transition before the given block.
+func (api *PublicDelegateAPI) VoteWeight(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (hexutil.Uint64, error) {
+	block, err := api.dac.ApiBackend.BlockByNumber(ctx, blockNr)
+	if block == nil {
+		if err == nil {
+			err = fmt.Errorf("block #%d not found", blockNr)
+		}
+		return 0, err
+	}
+	delegates, err := api.dac.blockchain.DelegateStateAt(block.DelegateRoot())
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(delegates.GetVoteWeight(address)), nil
+}
+
+// CurrentRound returns the round of delegates currently producing blocks. A round
+// shuffled ahead of its start is only returned once it started.
+func (api *PublicDelegateAPI) CurrentRound() (*DelegateRound, error) {
+	list, hash, number, shuffleTime := api.dac.dposTaskManager.currentRound()
+	if shuffleTime == 0 {
+		return nil, errNoShuffleData
+	}
+	return &DelegateRound{
+		ShuffleBlock: hexutil.Uint64(number),
+		ShuffleHash:  hash,
+		ShuffleTime:  hexutil.Uint64(shuffleTime),
+		Delegates:    list.ShuffleDels,
+	}, nil
+}
+
+// NextShuffle returns the time the next round of delegates is shuffled at, along
+// with the estimated number of its first block.
+func (api *PublicDelegateAPI) NextShuffle() *NextShuffleResult {
+	maxElectDelegate, blockInterval, _ := dposParams(api.dac.chainConfig)
+	roundTime := int64(maxElectDelegate * blockInterval)
+
+	genesis := api.dac.blockchain.Genesis().Time().Int64()
+	next := genesis + ((time.Now().Unix()-genesis)/roundTime+1)*roundTime
+
+	head := api.dac.blockchain.CurrentBlock()
+	block := head.NumberU64() + 1
+	if elapsed := next - head.Time().Int64(); elapsed > 0 {
+		block = head.NumberU64() + uint64(elapsed/int64(blockInterval))
+	}
+	return &NextShuffleResult{Time: hexutil.Uint64(next), Block: hexutil.Uint64(block)}
+}
+
+// RoundAt returns the round of delegates which produced the given block. The
+// round is recomputed from the delegate state of the shuffled block, and flagged
+// as verified if its hash matches the one recorded in the header.
+func (api *PublicDelegateAPI) RoundAt(ctx context.Context, blockNr rpc.BlockNumber) (*DelegateRound, error) {
+	header, err := api.dac.ApiBackend.HeaderByNumber(ctx, blockNr)
+	if header == nil {
+		if err == nil {
+			err = fmt.Errorf("block #%d not found", blockNr)
+		}
+		return nil, err
+	}
+	if header.ShuffleBlockNumber == nil || header.Number.Sign() == 0 {
+		return nil, fmt.Errorf("block #%d was not produced by a delegate round", header.Number)
+	}
+	rounds := newDelegateRounds(api.dac.blockchain)
+	shuffleTime := rounds.Start(header.Time.Int64())
+
+	list, err := rounds.Round(header.ShuffleBlockNumber.Uint64(), shuffleTime)
+	if err != nil {
+		return nil, err
+	}
+	verified := rlpHash(list) == header.ShuffleHash
+
+	return &DelegateRound{
+		ShuffleBlock: hexutil.Uint64(header.ShuffleBlockNumber.Uint64()),
+		ShuffleHash:  header.ShuffleHash,
+		ShuffleTime:  hexutil.Uint64(shuffleTime),
+		Delegates:    list.ShuffleDels,
+		Verified:     &verified,
+	}, nil
+}
+
+// CheckpointResult is the delegate set committed to by an epoch checkpoint.
+type CheckpointResult struct {
+	Number       hexutil.Uint64   `json:"number"`       // Number of the checkpoint block
+	Hash         common.Hash      `json:"hash"`         // Hash of the checkpoint block
+	ShuffleBlock hexutil.Uint64   `json:"shuffleBlock"` // Block the round of the checkpoint was shuffled from
+	Root         common.Hash      `json:"root"`         // Delegate set root committed to by the header
+	Delegates    []common.Address `json:"delegates"`    // Ranked delegate set hashing to the root
+}
+
+// Checkpoint returns the delegate set committed to by the checkpoint block of the
+// given epoch, which light clients verify against the checkpoint header.
+func (api *PublicDelegateAPI) Checkpoint(epoch hexutil.Uint64) (*CheckpointResult, error) {
+	header := api.dac.blockchain.GetHeaderByNumber(uint64(epoch) * api.dac.chainConfig.Epoch())
+	if header == nil {
+		return nil, fmt.Errorf("epoch %d not reached yet", epoch)
+	}
+	root, ok := header.CheckpointRoot()
+	if !ok {
+		return nil, fmt.Errorf("epoch %d has no checkpoint", epoch)
+	}
+	// The checkpoint is canonical, and so is the block its round was shuffled from
+	shuffle := api.dac.blockchain.GetHeaderByNumber(header.ShuffleBlockNumber.Uint64())
+	if shuffle == nil {
+		return nil, fmt.Errorf("shuffle block #%d of epoch %d not found", header.ShuffleBlockNumber, epoch)
+	}
+	delegates, err := api.dac.blockchain.DelegateStateAt(shuffle.DelegateRoot)
+	if err != nil {
+		return nil, err
+	}
+	set := types.NewDelegateSet(delegates.GetDelegates(), int(api.dac.blockchain.Config().MaxElectDelegate.Int64()))
+	return &CheckpointResult{
+		Number:       hexutil.Uint64(header.Number.Uint64()),
+		Hash:         header.Hash(),
+		ShuffleBlock: hexutil.Uint64(shuffle.Number.Uint64()),
+		Root:         root,
+		Delegates:    set,
+	}, nil
+}
+
+// DelegateProductionResult is the block production record of a single delegate.
+type DelegateProductionResult struct {
+	Address  common.Address `json:"address"`
+	Produced hexutil.Uint64 `json:"produced"`
+	Missed   hexutil.Uint64 `json:"missed"`
+}
+
+// ProductionStatsResult is the result of a delegate_getProductionStats API call.
+type ProductionStatsResult struct {
+	Epoch      hexutil.Uint64              `json:"epoch"`
+	FirstBlock hexutil.Uint64              `json:"firstBlock"`
+	LastBlock  hexutil.Uint64              `json:"lastBlock"`
+	Delegates  []*DelegateProductionResult `json:"delegates"`
+}
+
+// GetProductionStats returns the number of block slots each scheduled delegate
+// produced a block in or missed within the given epoch. Only epochs already
+// processed by the production indexer are served, counting an epoch on demand
+// would require iterating over all of its headers.
+func (api *PublicDelegateAPI) GetProductionStats(epoch hexutil.Uint64) (*ProductionStatsResult, error) {
+	prod := core.GetEpochProduction(api.dac.chainDb, uint64(epoch))
+	if prod == nil {
+		return nil, fmt.Errorf("epoch %d not indexed yet", epoch)
+	}
+	length := api.dac.chainConfig.Epoch()
+	first, last := uint64(epoch)*length, (uint64(epoch)+1)*length-1
+
+	delegates := make([]*DelegateProductionResult, len(prod.Delegates))
+	for i, delegate := range prod.Delegates {
+		delegates[i] = &DelegateProductionResult{
+			Address:  delegate.Address,
+			Produced: hexutil.Uint64(delegate.Produced),
+			Missed:   hexutil.Uint64(delegate.Missed),
+		}
+	}
+	return &ProductionStatsResult{
+		Epoch:      epoch,
+		FirstBlock: hexutil.Uint64(first),
+		LastBlock:  hexutil.Uint64(last),
+		Delegates:  delegates,
+	}, nil
+}
+
+// DoubleSignResult is a double sign evidence as returned by the delegate API.
+type DoubleSignResult struct {
+	Hash      common.Hash    `json:"hash"`      // Identifier of the evidence
+	Delegate  common.Address `json:"delegate"`  // Delegate which signed both blocks
+	Number    hexutil.Uint64 `json:"number"`    // Height both blocks were signed at
+	First     *types.Header  `json:"first"`     // Header of the first signed block
+	FirstSig  hexutil.Bytes  `json:"firstSig"`  // Signature over the first block
+	Second    *types.Header  `json:"second"`    // Header of the second signed block
+	SecondSig hexutil.Bytes  `json:"secondSig"` // Signature over the second block
+}
+
+// DoubleSigners returns the delegates this node detected or received evidence of
+// signing two different blocks at the same height, in order of detection.
+func (api *PublicDelegateAPI) DoubleSigners() []common.Address {
+	signers := api.dac.protocolManager.evidences.signers()
+	if signers == nil {
+		signers = []common.Address{}
+	}
+	return signers
+}
+
+// DoubleSignEvidences returns the stored evidences of the given delegate signing
+// two different blocks at the same height.
+func (api *PublicDelegateAPI) DoubleSignEvidences(delegate common.Address) []*DoubleSignResult {
+	evidences := api.dac.protocolManager.evidences.evidences(delegate)
+	results := make([]*DoubleSignResult, len(evidences))
+	for i, evidence := range evidences {
+		results[i] = &DoubleSignResult{
+			Hash:      evidence.Hash(),
+			Delegate:  evidence.Delegate(),
+			Number:    hexutil.Uint64(evidence.Number()),
+			First:     evidence.First,
+			FirstSig:  evidence.FirstSig,
+			Second:    evidence.Second,
+			SecondSig: evidence.SecondSig,
+		}
+	}
+	return results
+}
+
+// candidatesAt returns the delegate candidates in the delegate state of a block.
+func (api *PublicDelegateAPI) candidatesAt(block *types.Block) ([]types.Candidate, error) {
+	delegates, err := api.dac.blockchain.DelegateStateAt(block.DelegateRoot())
+	if err != nil {
+		return nil, err
+	}
+	return delegates.GetDelegates(), nil
+}
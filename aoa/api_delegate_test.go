@@ -0,0 +1,62 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/core/types"
+)
+
+// Tests that the current round is reported with the shuffle block and time of
+// the running round, and not of a round shuffled ahead of its start.
+func TestCurrentRound(t *testing.T) {
+	taskManager := new(DposTaskManager)
+	api := NewPublicDelegateAPI(&Dacchain{dposTaskManager: taskManager})
+
+	if _, err := api.CurrentRound(); err != errNoShuffleData {
+		t.Fatalf("error mismatch before any round: have %v, want %v", err, errNoShuffleData)
+	}
+	now := time.Now().Unix()
+	current := types.ShuffleList{ShuffleDels: []types.ShuffleDel{{Address: "0x01", WorkTime: uint64(now)}}}
+	next := types.ShuffleList{ShuffleDels: []types.ShuffleDel{{Address: "0x02", WorkTime: uint64(now + 60)}}}
+
+	// A round shuffled ahead of its start doesn't replace the running one
+	taskManager.currentNewRound, taskManager.currentNewRoundHash = current, rlpHash(current)
+	taskManager.currentRoundBlockHeight, taskManager.currentRoundTime = 5, now
+	taskManager.nextRound, taskManager.nextRoundHash = next, rlpHash(next)
+	taskManager.nextRoundBlockHeight, taskManager.nextRoundStart = 9, now+60
+
+	round, err := api.CurrentRound()
+	if err != nil {
+		t.Fatalf("failed to retrieve running round: %v", err)
+	}
+	if round.ShuffleBlock != 5 || uint64(round.ShuffleTime) != uint64(now) || round.ShuffleHash != rlpHash(current) || round.Delegates[0].Address != "0x01" {
+		t.Errorf("running round mismatch: have %+v", round)
+	}
+	// Once started, the next round is reported as a whole
+	taskManager.nextRoundStart = now - 1
+
+	round, err = api.CurrentRound()
+	if err != nil {
+		t.Fatalf("failed to retrieve started round: %v", err)
+	}
+	if round.ShuffleBlock != 9 || uint64(round.ShuffleTime) != uint64(now-1) || round.ShuffleHash != rlpHash(next) || round.Delegates[0].Address != "0x02" {
+		t.Errorf("started round mismatch: have %+v", round)
+	}
+}
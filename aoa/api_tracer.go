@@ -19,6 +19,7 @@ package aoa
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -68,7 +69,22 @@ type txTraceResult struct {
 	Error  string      `json:"error,omitempty"`  // Trace failure produced by the tracer
 }
 
+// txTraceStreamResult is the result of a single transaction trace streamed back
+// while an entire block is being traced.
+type txTraceStreamResult struct {
+	Index  hexutil.Uint64 `json:"index"`            // Transaction offset in the block
+	TxHash common.Hash    `json:"txHash"`           // Hash of the traced transaction
+	Result interface{}    `json:"result,omitempty"` // Trace results produced by the tracer
+	Error  string         `json:"error,omitempty"`  // Trace failure produced by the tracer
+}
 
+// blockTraceStreamEnd is the last notification of a streamed block trace, sent
+// once all transactions were traced or the trace was aborted.
+type blockTraceStreamEnd struct {
+	Done   bool           `json:"done"`            // Always true, marking the end of the stream
+	Traced hexutil.Uint64 `json:"traced"`          // Number of transaction traces streamed
+	Error  string         `json:"error,omitempty"` // Failure aborting the block trace
+}
 
 // blockTraceTask represents a single block trace task when an entire chain is
 // being traced.
@@ -98,8 +114,8 @@ type txTraceTask struct {
 // state tries for intermediate blocks without serializing to disk, but at the
 // same time to allow disk fallback for reads that do no hit the memory layer.
 type ephemeralDatabase struct {
-	diskdb aoadb.Database     // Persistent disk database to fall back to with reads
-	maoadb  *aoadb.MemDatabase // Ephemeral memory database for primary reads and writes
+	diskdb trie.DatabaseReader // Persistent chain state to fall back to with reads
+	maoadb *aoadb.MemDatabase  // Ephemeral memory database for primary reads and writes
 }
 
 func (db *ephemeralDatabase) Put(key []byte, value []byte) error { return db.maoadb.Put(key, value) }
@@ -154,7 +170,7 @@ func (api *PrivateDebugAPI) TraceChain(ctx context.Context, start, end rpc.Block
 
 	switch start {
 	case rpc.PendingBlockNumber:
-		from = api.dac.dposMiner.PendingBlock()
+		from = api.dac.dposMiner.GetPendingBlock()
 	case rpc.LatestBlockNumber:
 		from = api.dac.blockchain.CurrentBlock()
 	default:
@@ -162,7 +178,7 @@ func (api *PrivateDebugAPI) TraceChain(ctx context.Context, start, end rpc.Block
 	}
 	switch end {
 	case rpc.PendingBlockNumber:
-		to = api.dac.dposMiner.PendingBlock()
+		to = api.dac.dposMiner.GetPendingBlock()
 	case rpc.LatestBlockNumber:
 		to = api.dac.blockchain.CurrentBlock()
 	default:
@@ -194,7 +210,7 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 
 	maoadb, _ := aoadb.NewMemDatabase()
 	db := &ephemeralDatabase{
-		diskdb: api.dac.ChainDb(),
+		diskdb: api.dac.blockchain.GetStateDB().TrieDB(),
 		maoadb:  maoadb,
 	}
 	if number := start.NumberU64(); number > 0 {
@@ -424,22 +440,102 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 // TraceBlockByNumber returns the structured logs created during the execution of
 // EVM and returns them as a JSON object.
 func (api *PrivateDebugAPI) TraceBlockByNumber(ctx context.Context, number rpc.BlockNumber, config *TraceConfig) ([]*txTraceResult, error) {
-	// Fetch the block that we want to trace
-	var block *types.Block
+	block := api.blockByNumber(number)
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	return api.traceBlock(ctx, block, config)
+}
+
+// TraceBlockStream traces all the transactions of a block like TraceBlockByNumber,
+// but streams the results back over a subscription as soon as they're available,
+// one transaction at a time and in block order. The stream is terminated by a
+// notification marked done, carrying the error aborting the trace if any.
+func (api *PrivateDebugAPI) TraceBlockStream(ctx context.Context, number rpc.BlockNumber, config *TraceConfig) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	block := api.blockByNumber(number)
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	sub := notifier.CreateSubscription()
+
+	go func() {
+		// Abort any pending traces if the subscription is torn down
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		go func() {
+			select {
+			case <-notifier.Closed():
+				cancel()
+			case <-sub.Err():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		trace := func(emit func(index int, result *txTraceResult)) error {
+			return api.traceBlockTxs(ctx, block, config, emit)
+		}
+		send := func(result interface{}) {
+			if err := notifier.Notify(sub.ID, result); err != nil {
+				cancel()
+			}
+		}
+		if err := streamBlockTraces(block.Transactions(), trace, send); err != nil {
+			log.Warn("Block tracing failed", "number", block.NumberU64(), "hash", block.Hash(), "err", err)
+		}
+	}()
+	return sub, nil
+}
+
+// streamBlockTraces runs a block trace, reordering the transaction traces it emits
+// concurrently into block order and sending each on as soon as its predecessors
+// were sent. The stream is terminated by an end marker carrying the failure of
+// the trace, which is also returned.
+func streamBlockTraces(txs types.Transactions, trace func(emit func(index int, result *txTraceResult)) error, send func(result interface{})) error {
+	var (
+		lock sync.Mutex
+		done = make(map[int]*txTraceResult)
+		next int
+	)
+	err := trace(func(index int, result *txTraceResult) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		done[index] = result
+		for result, ok := done[next]; ok; result, ok = done[next] {
+			send(&txTraceStreamResult{
+				Index:  hexutil.Uint64(next),
+				TxHash: txs[next].Hash(),
+				Result: result.Result,
+				Error:  result.Error,
+			})
+			delete(done, next)
+			next++
+		}
+	})
+	end := &blockTraceStreamEnd{Done: true, Traced: hexutil.Uint64(next)}
+	if err != nil {
+		end.Error = err.Error()
+	}
+	send(end)
+	return err
+}
 
+// blockByNumber retrieves the block to trace for the given block number, or nil
+// if it's not available.
+func (api *PrivateDebugAPI) blockByNumber(number rpc.BlockNumber) *types.Block {
 	switch number {
 	case rpc.PendingBlockNumber:
-		block = api.dac.dposMiner.PendingBlock()
+		return api.dac.dposMiner.GetPendingBlock()
 	case rpc.LatestBlockNumber:
-		block = api.dac.blockchain.CurrentBlock()
+		return api.dac.blockchain.CurrentBlock()
 	default:
-		block = api.dac.blockchain.GetBlockByNumber(uint64(number))
-	}
-	// Trace the block if it was found
-	if block == nil {
-		return nil, fmt.Errorf("block #%d not found", number)
+		return api.dac.blockchain.GetBlockByNumber(uint64(number))
 	}
-	return api.traceBlock(ctx, block, config)
 }
 
 // TraceBlockByHash returns the structured logs created during the execution of
@@ -476,13 +572,27 @@ func (api *PrivateDebugAPI) TraceBlockFromFile(ctx context.Context, file string,
 // executes all the transactions contained within. The return value will be one item
 // per transaction, dependent on the requestd tracer.
 func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block, config *TraceConfig) ([]*txTraceResult, error) {
+	results := make([]*txTraceResult, len(block.Transactions()))
+	if err := api.traceBlockTxs(ctx, block, config, func(index int, result *txTraceResult) {
+		results[index] = result
+	}); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// traceBlockTxs executes all the transactions contained within a block on top of
+// its parent state, tracing them on a bounded pool of concurrent workers. Results
+// are handed to emit as soon as they're available, which happens out of order and
+// from multiple goroutines concurrently.
+func (api *PrivateDebugAPI) traceBlockTxs(ctx context.Context, block *types.Block, config *TraceConfig, emit func(index int, result *txTraceResult)) error {
 	// Create the parent state database
 	if err := api.dac.dacEngine.VerifyHeader(api.dac.blockchain, block.Header()); err != nil {
-		return nil, err
+		return err
 	}
 	parent := api.dac.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
 	if parent == nil {
-		return nil, fmt.Errorf("parent %x not found", block.ParentHash())
+		return fmt.Errorf("parent %x not found", block.ParentHash())
 	}
 	reexec := defaultTraceReexec
 	if config != nil && config.Reexec != nil {
@@ -490,22 +600,23 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 	}
 	statedb, err := api.computeStateDB(parent, reexec)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	// Execute all the transaction contained within the block concurrently
 	var (
 		signer = types.MakeSigner(api.config, block.Number())
 
-		txs     = block.Transactions()
-		results = make([]*txTraceResult, len(txs))
-
+		txs  = block.Transactions()
 		pend = new(sync.WaitGroup)
-		jobs = make(chan *txTraceTask, len(txs))
 	)
 	threads := runtime.NumCPU()
 	if threads > len(txs) {
 		threads = len(txs)
 	}
+	// Only queue up as many state copies as there are tracers, otherwise large
+	// blocks would keep a copy of the state around for every transaction
+	jobs := make(chan *txTraceTask, threads)
+
 	for th := 0; th < threads; th++ {
 		pend.Add(1)
 		go func() {
@@ -518,19 +629,25 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 
 				res, err := api.traceTx(ctx, msg, vmctx, task.statedb, config)
 				if err != nil {
-					results[task.index] = &txTraceResult{Error: err.Error()}
+					emit(task.index, &txTraceResult{Error: err.Error()})
 					continue
 				}
-				results[task.index] = &txTraceResult{Result: res}
+				emit(task.index, &txTraceResult{Result: res})
 			}
 		}()
 	}
 	// Feed the transactions into the tracers and return
 	var failed error
 	for i, tx := range txs {
-		// Send the trace task over for execution
-		jobs <- &txTraceTask{statedb: statedb.Copy(), index: i}
-
+		// Send the trace task over for execution, or abort if the caller's gone
+		select {
+		case jobs <- &txTraceTask{statedb: statedb.Copy(), index: i}:
+		case <-ctx.Done():
+			failed = ctx.Err()
+		}
+		if failed != nil {
+			break
+		}
 		// Generate the next state snapshot fast without tracing
 		msg, _ := tx.AsMessage(signer)
 		vmctx := core.NewEVMContext(msg, block.Header(), api.dac.blockchain, nil)
@@ -546,11 +663,7 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 	close(jobs)
 	pend.Wait()
 
-	// If execution failed in between, abort
-	if failed != nil {
-		return nil, failed
-	}
-	return results, nil
+	return failed
 }
 
 // computeStateDB retrieves the state database associated with a certain block.
@@ -567,7 +680,7 @@ func (api *PrivateDebugAPI) computeStateDB(block *types.Block, reexec uint64) (*
 
 	maoadb, _ := aoadb.NewMemDatabase()
 	db := &ephemeralDatabase{
-		diskdb: api.dac.ChainDb(),
+		diskdb: api.dac.blockchain.GetStateDB().TrieDB(),
 		maoadb:  maoadb,
 	}
 	for i := uint64(0); i < reexec; i++ {
@@ -646,6 +759,14 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 	if tx == nil {
 		return nil, fmt.Errorf("transaction %x not found", hash)
 	}
+	// Serve the trace from the cache if it was already produced
+	key, err := traceCacheKey(hash, blockHash, config)
+	if err != nil {
+		return nil, err
+	}
+	if trace, ok := api.dac.traceCache.get(key); ok {
+		return trace, nil
+	}
 	reexec := defaultTraceReexec
 	if config != nil && config.Reexec != nil {
 		reexec = *config.Reexec
@@ -654,15 +775,24 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 	if err != nil {
 		return nil, err
 	}
-	// Trace the transaction and return
-	return api.traceTx(ctx, msg, vmctx, statedb, config)
+	// Trace the transaction and cache the result
+	result, err := api.traceTx(ctx, msg, vmctx, statedb, config)
+	if err != nil || api.dac.traceCache == nil {
+		return result, err
+	}
+	trace, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	api.dac.traceCache.add(key, trace)
+	return json.RawMessage(trace), nil
 }
 
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
 // be tracer dependent.
 func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, vmctx vm.Context, statedb *state.StateDB, config *TraceConfig) (interface{}, error) {
-	// Assemble the structured logger or the JavaScript tracer
+	// Assemble the structured logger or the native or JavaScript tracer
 	var (
 		tracer vm.Tracer
 		err    error
@@ -676,15 +806,15 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 				return nil, err
 			}
 		}
-		// Constuct the JavaScript tracer to execute with
-		if tracer, err = tracers.New(*config.Tracer); err != nil {
+		// Constuct the native or JavaScript tracer to execute with
+		if tracer, err = tracers.NewTracer(*config.Tracer, statedb.Copy()); err != nil {
 			return nil, err
 		}
 		// Handle timeouts and RPC cancellations
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
-			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
+			tracer.(tracers.ResultTracer).Stop(errors.New("execution timeout"))
 		}()
 		defer cancel()
 
@@ -711,7 +841,7 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 			StructLogs:  aoaapi.FormatLogs(tracer.StructLogs()),
 		}, nil
 
-	case *tracers.Tracer:
+	case tracers.ResultTracer:
 		return tracer.GetResult()
 
 	default:
@@ -0,0 +1,78 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+)
+
+// Tests that streamed block traces are delivered in block order regardless of
+// the order they're produced in, terminated by an end marker.
+func TestStreamBlockTraces(t *testing.T) {
+	txs := make(types.Transactions, 4)
+	for i := range txs {
+		txs[i] = types.NewTransaction(uint64(i), common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil, types.ActionTrans, nil, "")
+	}
+	var sent []interface{}
+	send := func(result interface{}) { sent = append(sent, result) }
+
+	// Trace all transactions out of order
+	trace := func(emit func(int, *txTraceResult)) error {
+		for _, index := range []int{2, 0, 3, 1} {
+			emit(index, &txTraceResult{Result: index})
+		}
+		return nil
+	}
+	if err := streamBlockTraces(txs, trace, send); err != nil {
+		t.Fatalf("failed to stream traces: %v", err)
+	}
+	if len(sent) != len(txs)+1 {
+		t.Fatalf("notification count mismatch: have %d, want %d", len(sent), len(txs)+1)
+	}
+	for i, tx := range txs {
+		result, ok := sent[i].(*txTraceStreamResult)
+		if !ok {
+			t.Fatalf("notification %d: type mismatch: have %T", i, sent[i])
+		}
+		if int(result.Index) != i || result.TxHash != tx.Hash() || result.Result != i {
+			t.Errorf("notification %d: trace mismatch: have %+v", i, result)
+		}
+	}
+	if end, ok := sent[len(txs)].(*blockTraceStreamEnd); !ok || !end.Done || end.Traced != 4 || end.Error != "" {
+		t.Errorf("end marker mismatch: have %+v", sent[len(txs)])
+	}
+	// Abort the trace with the first transaction missing
+	sent = nil
+	trace = func(emit func(int, *txTraceResult)) error {
+		emit(1, &txTraceResult{Result: 1})
+		return errors.New("aborted")
+	}
+	if err := streamBlockTraces(txs, trace, send); err == nil {
+		t.Fatalf("aborted trace succeeded")
+	}
+	if len(sent) != 1 {
+		t.Fatalf("notification count mismatch: have %d, want 1", len(sent))
+	}
+	if end, ok := sent[0].(*blockTraceStreamEnd); !ok || !end.Done || end.Traced != 0 || end.Error != "aborted" {
+		t.Errorf("end marker mismatch: have %+v", sent[0])
+	}
+}
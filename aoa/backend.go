@@ -36,10 +36,8 @@ import (
 	"github.com/Aurorachain-io/go-aoa/node"
 	"github.com/Aurorachain-io/go-aoa/p2p"
 	"github.com/Aurorachain-io/go-aoa/params"
-	"github.com/Aurorachain-io/go-aoa/rlp"
 	"github.com/Aurorachain-io/go-aoa/rpc"
 	"math/big"
-	"runtime"
 	"sync"
 )
 
@@ -71,9 +69,16 @@ type Dacchain struct {
 
 	dacEngine      consensus.Engine
 	accountManager *accounts.Manager
+	supervisor     *node.Supervisor // Supervisor restarting crashed non-consensus subsystems
+	ownSupervisor  bool             // Whether the supervisor was created by the service itself
 
 	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
+	utilIndexer   *core.ChainIndexer             // Epoch utilization indexer operating during block imports
+	prodIndexer   *core.ChainIndexer             // Delegate production indexer operating during block imports
+	indexers      map[string]*core.ChainIndexer  // Optional address indexers enabled by the operator, keyed by kind
+	overlays      *chainOverlays                 // In-memory chain overlays created for speculative execution
+	traceCache    *traceCache                    // Cache of transaction traces served over the debug API
 
 	ApiBackend *DacApiBackend
 
@@ -85,6 +90,8 @@ type Dacchain struct {
 	lock            sync.RWMutex // Protects the variadic fields (e.g. gas price )
 	dposTaskManager *DposTaskManager
 	dposMiner       *core.DposMiner
+	instantSealer   *instantSealer  // Sealer of instant sealing developer chains, nil for delegate rounds
+	topics          *topicDiscovery // Topic advertiser on the v5 discovery network, nil without v5 discovery
 }
 
 func (dacchain *Dacchain) AddLesServer(ls LesServer) {
@@ -98,6 +105,12 @@ func New(ctx *node.ServiceContext, config *Config) (*Dacchain, error) {
 	if !config.SyncMode.IsValid() {
 		return nil, fmt.Errorf("invalid sync mode %d", config.SyncMode)
 	}
+	if config.Propagation.Strategy == "" {
+		config.Propagation.Strategy = DefaultPropagationConfig.Strategy
+	}
+	if err := config.Propagation.validate(); err != nil {
+		return nil, err
+	}
 	chainDb, err := CreateDB(ctx, config, "chaindata")
 	if err != nil {
 		return nil, err
@@ -121,16 +134,36 @@ func New(ctx *node.ServiceContext, config *Config) (*Dacchain, error) {
 		chainDb:        chainDb,
 		chainConfig:    chainConfig,
 		accountManager: ctx.AccountManager,
+		supervisor:     ctx.Supervisor,
 		shutdownChan:   make(chan bool),
 		stopDbUpgrade:  stopDbUpgrade,
 		networkId:      config.NetworkId,
 		gasPrice:       config.GasPrice,
 		bloomRequests:  make(chan chan *bloombits.Retrieval),
 		bloomIndexer:   NewBloomIndexer(chainDb, params.BloomBitsBlocks),
+		utilIndexer:    NewUtilizationIndexer(chainDb, chainConfig),
+		indexers:       make(map[string]*core.ChainIndexer),
+		overlays:       newChainOverlays(),
+		traceCache:     newTraceCache(chainDb, config.TraceCache, config.TraceCacheDisk),
 		dacEngine:      CreateDacchainConsensusEngine(),
 		watcherDb:      watcherDb,
 	}
+	// Services created outside of a running node supervise their subsystems on
+	// their own
+	if dac.supervisor == nil {
+		dac.supervisor, dac.ownSupervisor = node.NewSupervisor(nil), true
+	}
 
+	for _, kind := range config.Indexes {
+		if _, ok := dac.indexers[kind]; ok {
+			continue
+		}
+		indexer, err := NewAddressIndexer(chainDb, chainConfig, kind, config.IndexThrottling)
+		if err != nil {
+			return nil, err
+		}
+		dac.indexers[kind] = indexer
+	}
 	log.Info("Initialising eminer-pro protocol", "versions", ProtocolVersions, "network", config.NetworkId)
 
 	if !config.SkipBcVersionCheck {
@@ -142,17 +175,37 @@ func New(ctx *node.ServiceContext, config *Config) (*Dacchain, error) {
 	}
 
 	vmConfig := vm.Config{EnablePreimageRecording: config.EnablePreimageRecording, WatchInnerTx: config.EnableInterTxWatching}
-	dac.blockchain, err = core.NewBlockChain(chainDb, dac.chainConfig, dac.dacEngine, vmConfig, watcherDb)
+	cacheConfig := &core.CacheConfig{
+		Disabled:           config.NoPruning,
+		TrieCleanLimit:     config.TrieCleanCache,
+		TrieDirtyLimit:     config.TrieDirtyCache,
+		TrieCommitInterval: config.TrieCommitInterval,
+	}
+	dac.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, dac.chainConfig, dac.dacEngine, vmConfig, watcherDb)
 	if err != nil {
 		return nil, err
 	}
+	dac.blockchain.SetTxLookupLimit(config.TxLookupLimit)
+	dac.blockchain.SetHistoryLimit(config.HistoryLimit)
+	if config.ProcessorWorkers > 1 {
+		dac.blockchain.SetProcessor(core.NewParallelProcessor(dac.chainConfig, dac.blockchain, dac.dacEngine, config.ProcessorWorkers))
+	}
+	dac.blockchain.SetStateStats(config.StateStats)
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
 		dac.blockchain.SetHead(compat.RewindTo)
 		core.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
-	dac.bloomIndexer.Start(dac.blockchain)
+	dac.prodIndexer = NewProductionIndexer(chainDb, dac.blockchain)
+	indexers := []*core.ChainIndexer{dac.bloomIndexer, dac.utilIndexer, dac.prodIndexer}
+	for _, indexer := range dac.indexers {
+		indexers = append(indexers, indexer)
+	}
+	for _, indexer := range indexers {
+		indexer.SetSupervisor(dac.supervisor)
+		indexer.Start(dac.blockchain)
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
@@ -160,10 +213,24 @@ func New(ctx *node.ServiceContext, config *Config) (*Dacchain, error) {
 
 	dac.txPool = core.NewTxPool(config.TxPool, dac.chainConfig, dac.blockchain)
 	dac.dposMiner = core.NewDposMiner(dac.chainConfig, dac, dac.dacEngine)
-	dac.dposTaskManager = NewDposTaskManager(ctx, dac.blockchain, dac.accountManager, dac.dposMiner.GetProduceCallback(), dac.dposMiner.GetShuffleHashChan())
-	if dac.protocolManager, err = NewProtocolManager(dac.chainConfig, config.SyncMode, config.NetworkId, dac.txPool, dac.dacEngine, dac.blockchain, chainDb, dac.dposTaskManager, dac.dposMiner.GetProduceBlockChan(), dac.dposMiner.AddDelegateWalletCallback, dac.dposMiner.GetDelegateWallets()); err != nil {
+	if err := dac.dposMiner.SetExtra(config.ExtraData); err != nil {
+		log.Warn("Miner extra data rejected", "extra", hexutil.Bytes(config.ExtraData), "err", err)
+	}
+	dac.dposTaskManager = NewDposTaskManager(ctx, dac.blockchain, dac.accountManager, dac.dposMiner.GetProduceCallback(), dac.dposMiner.GetShuffleHashChan(), config.ProduceLeadTime)
+	// Engines not scheduling delegates of their own leave the verification of the
+	// blocks relayed on the delegate network to the default DPoS rules.
+	engine, ok := dac.dacEngine.(consensus.Delegated)
+	if !ok {
+		log.Warn("Consensus engine doesn't schedule delegates, verifying delegate blocks with DPoS")
+		engine = dpos.New()
+	}
+	if dac.protocolManager, err = NewProtocolManager(dac.chainConfig, config.SyncMode, config.NetworkId, dac.txPool, engine, dac.blockchain, chainDb, dac.dposTaskManager, dac.dposMiner.GetProduceBlockChan(), dac.dposMiner.AddDelegateWalletCallback, dac.dposMiner.GetDelegateWallets()); err != nil {
 		return nil, err
 	}
+	dac.protocolManager.propagation = config.Propagation
+	if dac.chainConfig.Instant != nil {
+		dac.instantSealer = newInstantSealer(dac.blockchain, dac.txPool, dac.dposMiner)
+	}
 
 	dac.ApiBackend = &DacApiBackend{dac, nil}
 	gpoParams := config.GPO
@@ -175,23 +242,6 @@ func New(ctx *node.ServiceContext, config *Config) (*Dacchain, error) {
 	return dac, nil
 }
 
-func makeExtraData(extra []byte) []byte {
-	if len(extra) == 0 {
-		// create default extradata
-		extra, _ = rlp.EncodeToBytes([]interface{}{
-			uint(params.VersionMajor<<16 | params.VersionMinor<<8 | params.VersionPatch),
-			"aoa",
-			runtime.Version(),
-			runtime.GOOS,
-		})
-	}
-	if uint64(len(extra)) > params.MaximumExtraDataSize {
-		log.Warn("Miner extra data exceed limit", "extra", hexutil.Bytes(extra), "limit", params.MaximumExtraDataSize)
-		extra = nil
-	}
-	return extra
-}
-
 // CreateDB creates the chain database.
 func CreateDB(ctx *node.ServiceContext, config *Config, name string) (aoadb.Database, error) {
 	db, err := ctx.OpenDatabase(name, config.DatabaseCache, config.DatabaseHandles)
@@ -204,6 +254,7 @@ func CreateDB(ctx *node.ServiceContext, config *Config, name string) (aoadb.Data
 	return db, nil
 }
 
+// CreateDacchainConsensusEngine creates the default, DPoS consensus engine.
 func CreateDacchainConsensusEngine() consensus.Engine {
 	return dpos.New()
 }
@@ -216,9 +267,14 @@ func (dacchain *Dacchain) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, dacchain.dacEngine.APIs(dacchain.BlockChain())...)
 
-	// Append all the local APIs and return
-	return append(apis, []rpc.API{
+	// Append all the local APIs
+	apis = append(apis, []rpc.API{
 		{
+			Namespace: "aoa",
+			Version:   "1.0",
+			Service:   NewPublicDacchainAPI(dacchain),
+			Public:    true,
+		}, {
 			Namespace: "aoa",
 			Version:   "1.0",
 			Service:   downloader.NewPublicDownloaderAPI(dacchain.protocolManager.downloader),
@@ -228,10 +284,19 @@ func (dacchain *Dacchain) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   filters.NewPublicFilterAPI(dacchain.ApiBackend, false),
 			Public:    true,
+		}, {
+			Namespace: "delegate",
+			Version:   "1.0",
+			Service:   NewPublicDelegateAPI(dacchain),
+			Public:    true,
 		}, {
 			Namespace: "admin",
 			Version:   "1.0",
 			Service:   NewPrivateAdminAPI(dacchain),
+		}, {
+			Namespace: "miner",
+			Version:   "1.0",
+			Service:   NewPrivateMinerAPI(dacchain),
 		}, {
 			Namespace: "debug",
 			Version:   "1.0",
@@ -248,6 +313,12 @@ func (dacchain *Dacchain) APIs() []rpc.API {
 			Public:    true,
 		},
 	}...)
+
+	// Alias the aoa namespace for Ethereum tooling if requested
+	if dacchain.config.EthCompat {
+		apis = aoaapi.EthCompatAPIs(dacchain.ApiBackend, apis)
+	}
+	return apis
 }
 
 func (dacchain *Dacchain) ResetWithGenesisBlock(gb *types.Block) {
@@ -258,8 +329,9 @@ func (dacchain *Dacchain) AccountManager() *accounts.Manager { return dacchain.a
 func (dacchain *Dacchain) BlockChain() *core.BlockChain      { return dacchain.blockchain }
 func (dacchain *Dacchain) TxPool() *core.TxPool              { return dacchain.txPool }
 func (dacchain *Dacchain) Engine() consensus.Engine          { return dacchain.dacEngine }
-func (dacchain *Dacchain) ChainDb() aoadb.Database            { return dacchain.chainDb }
-func (dacchain *Dacchain) WatcherDb() aoadb.Database          { return dacchain.watcherDb }
+func (dacchain *Dacchain) DposMiner() *core.DposMiner        { return dacchain.dposMiner }
+func (dacchain *Dacchain) ChainDb() aoadb.Database           { return dacchain.chainDb }
+func (dacchain *Dacchain) WatcherDb() aoadb.Database         { return dacchain.watcherDb }
 func (dacchain *Dacchain) IsListening() bool                 { return true } // Always listening
 func (dacchain *Dacchain) EthVersion() int {
 	return int(dacchain.protocolManager.SubProtocols[0].Version)
@@ -295,21 +367,49 @@ func (dacchain *Dacchain) Start(srvr *p2p.Server) error {
 			maxPeers = srvr.MaxPeers / 2
 		}
 	}
-	// Start the networking layer and the light server if requested
+	// Start the networking layer and the light server if requested. The local
+	// delegates vouch for the node ID, and priority peers are bound by the slots
+	// the server reserves for them.
+	dacchain.protocolManager.self = srvr.Self().ID
+	dacchain.protocolManager.reservedSlots = srvr.ReservedSlots
 	dacchain.protocolManager.Start(maxPeers)
 	if dacchain.lesServer != nil {
 		dacchain.lesServer.Start(srvr)
 	}
+	if dacchain.instantSealer != nil {
+		dacchain.instantSealer.Start()
+	}
+	dacchain.dposMiner.Start()
+	if srvr.DiscV5 != nil {
+		dacchain.topics = newTopicDiscovery(srvr, dacchain.blockchain.Genesis().Hash(), dacchain.hasLocalDelegate, dacchain.protocolManager.delegateNodes)
+		dacchain.topics.start()
+	}
 	return nil
 }
 
 // Stop implements node.Service, terminating all internal goroutines used by the
 // eminer-pro protocol.
 func (dacchain *Dacchain) Stop() error {
+	if dacchain.ownSupervisor {
+		dacchain.supervisor.Stop()
+	}
 	if dacchain.stopDbUpgrade != nil {
 		dacchain.stopDbUpgrade()
 	}
 	dacchain.bloomIndexer.Close()
+	dacchain.utilIndexer.Close()
+	dacchain.prodIndexer.Close()
+	for _, indexer := range dacchain.indexers {
+		indexer.Close()
+	}
+	if dacchain.topics != nil {
+		dacchain.topics.stop()
+	}
+	if dacchain.instantSealer != nil {
+		dacchain.instantSealer.Stop()
+	}
+	dacchain.dposTaskManager.Stop()
+	dacchain.dposMiner.Stop()
 	dacchain.blockchain.Stop()
 	dacchain.protocolManager.Stop()
 	if dacchain.lesServer != nil {
@@ -50,9 +50,12 @@ const (
 // retrievals from possibly a range of filters and serving the data to satisfy.
 func (dacchain *Dacchain) startBloomHandlers() {
 	for i := 0; i < bloomServiceThreads; i++ {
-		go func() {
+		dacchain.supervisor.Go("bloombits", func(quit <-chan struct{}) {
 			for {
 				select {
+				case <-quit:
+					return
+
 				case <-dacchain.shutdownChan:
 					return
 
@@ -74,7 +77,7 @@ func (dacchain *Dacchain) startBloomHandlers() {
 					request <- task
 				}
 			}
-		}()
+		})
 	}
 }
 
@@ -26,22 +26,33 @@ import (
 	"math/big"
 	"os"
 	"os/user"
+	"time"
 )
 
 // DefaultConfig contains default settings for use on the eminer-pro main net.
 var DefaultConfig = Config{
 	SyncMode: downloader.FullSync,
 
-	NetworkId:     1,
-	LightPeers:    20,
-	DatabaseCache: 128,
-	GasPrice:      big.NewInt(4 * params.Shannon),
+	NetworkId:          1,
+	LightPeers:         20,
+	DatabaseCache:      128,
+	TrieCleanCache:     256,
+	TrieDirtyCache:     256,
+	TrieCommitInterval: 4096,
+	GasPrice:           big.NewInt(4 * params.Shannon),
 
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
 		Blocks:     20,
 		Percentile: 60,
+		MaxPrice:   gasprice.DefaultMaxPrice,
 	},
+	Propagation:     DefaultPropagationConfig,
+	IndexThrottling: DefaultIndexThrottling,
+	TraceCache:      DefaultTraceCache,
+	RPCGasCap:       50000000,
+	RPCEVMTimeout:   5 * time.Second,
+	ProduceLeadTime: 2 * time.Second,
 }
 
 func init() {
@@ -73,6 +84,31 @@ type Config struct {
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
+	TxLookupLimit      uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
+	HistoryLimit       uint64 `toml:",omitempty"` // The maximum number of blocks from head whose bodies and receipts are retained.
+	TrieCleanCache     int    // Megabytes of memory for caching clean trie nodes read from disk
+	TrieDirtyCache     int    // Megabytes of dirty trie nodes cached before flushing to disk
+	NoPruning          bool   // Whether to write every state to disk instead of caching trie nodes
+	TrieCommitInterval uint64 // Blocks between two flushes of a complete state to disk when pruning
+
+	// Optional chain indexes, backfilled over the existing chain when enabled
+	Indexes         []string      `toml:",omitempty"` // Optional indexes to maintain (logs, transfers, creations)
+	IndexThrottling time.Duration `toml:",omitempty"` // Time waited between indexing two sections while catching up
+
+	// Block processing options
+	ProcessorWorkers int  `toml:",omitempty"` // Number of goroutines pre-executing block transactions (0 = serial processing)
+	StateStats       bool `toml:",omitempty"` // Whether to collect per-block state access statistics as metrics
+
+	// Transaction trace cache options
+	TraceCache     int `toml:",omitempty"` // Number of transaction traces cached in memory (0 = disabled)
+	TraceCacheDisk int `toml:",omitempty"` // Number of traces evicted from memory kept on disk (0 = disabled)
+
+	// RPC execution caps protecting public nodes from stalling calls
+	RPCGasCap     uint64        `toml:",omitempty"` // Gas cap of the calls executed over RPC (0 = no cap)
+	RPCEVMTimeout time.Duration `toml:",omitempty"` // Time limit of the calls executed over RPC (0 = no limit)
+
+	// Expose the aoa namespace under eth too, in Ethereum compatible shapes
+	EthCompat bool `toml:",omitempty"`
 
 	// Mining-related options
 	Dacchainbase common.Address `toml:",omitempty"`
@@ -80,12 +116,19 @@ type Config struct {
 	ExtraData    []byte         `toml:",omitempty"`
 	GasPrice     *big.Int
 
+	// Time ahead of its slot a local delegate starts assembling its block, kept
+	// below the block interval
+	ProduceLeadTime time.Duration `toml:",omitempty"`
+
 	// Transaction pool options
 	TxPool core.TxPoolConfig
 
 	// Gas Price Oracle options
 	GPO gasprice.Config
 
+	// Block propagation options
+	Propagation PropagationConfig
+
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
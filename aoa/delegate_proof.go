@@ -0,0 +1,119 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"errors"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/p2p/discover"
+)
+
+const (
+	// maxDelegateProofs is the maximum number of proofs accepted in one delegate
+	// proof message.
+	maxDelegateProofs = 16
+
+	// delegateNodesSize is the size of the channel announcing the nodes proven
+	// to run a delegate.
+	delegateNodesSize = 16
+)
+
+// errDelegateProofSigner is returned if a delegate proof is not signed by the
+// delegate it names.
+var errDelegateProofSigner = errors.New("delegate proof not signed by delegate")
+
+// delegateProof binds a node to a delegate: the delegate account signs the ID
+// of the node it runs on, so peers only treat nodes as delegates if a delegate
+// vouched for them.
+type delegateProof struct {
+	Delegate  common.Address
+	Signature []byte
+}
+
+// delegateProofHash returns the hash a delegate signs to vouch for a node.
+func delegateProofHash(id discover.NodeID) []byte {
+	return crypto.Keccak256([]byte("aoa delegate node"), id[:])
+}
+
+// verify checks whether the proof is signed by its delegate for the given node.
+func (proof *delegateProof) verify(id discover.NodeID) error {
+	pub, err := crypto.SigToPub(delegateProofHash(id), proof.Signature)
+	if err != nil {
+		return err
+	}
+	if crypto.PubkeyToAddress(*pub) != proof.Delegate {
+		return errDelegateProofSigner
+	}
+	return nil
+}
+
+// localDelegateProofs vouches for the local node with the local accounts that
+// are registered delegates at the head of the chain.
+func (pm *ProtocolManager) localDelegateProofs() []*delegateProof {
+	if len(pm.delegateWallets) == 0 {
+		return nil
+	}
+	delegates, err := pm.blockchain.DelegateState()
+	if err != nil {
+		return nil
+	}
+	var proofs []*delegateProof
+	for address, key := range pm.delegateWallets {
+		delegate := common.HexToAddress(address)
+		if !delegates.Exist(delegate) {
+			continue
+		}
+		sig, err := crypto.Sign(delegateProofHash(pm.self), key)
+		if err != nil {
+			log.Warn("Failed to sign delegate proof", "delegate", delegate, "err", err)
+			continue
+		}
+		if proofs = append(proofs, &delegateProof{Delegate: delegate, Signature: sig}); len(proofs) == maxDelegateProofs {
+			break
+		}
+	}
+	return proofs
+}
+
+// verifyDelegateProofs checks the proofs a peer sent, returning whether any of
+// them names a registered delegate at the head of the chain.
+func (pm *ProtocolManager) verifyDelegateProofs(p *peer, proofs []*delegateProof) (bool, error) {
+	delegates, err := pm.blockchain.DelegateState()
+	if err != nil {
+		return false, nil
+	}
+	id := p.ID()
+	valid := false
+	for i, proof := range proofs {
+		if proof == nil {
+			return false, errResp(ErrDecode, "proof %d is nil", i)
+		}
+		if err := proof.verify(id); err != nil {
+			return false, errResp(ErrDecode, "proof %d: %v", i, err)
+		}
+		// Delegates may have left the set in the view of either side, skip
+		// those without faulting the peer
+		if delegates.Exist(proof.Delegate) {
+			p.Log().Debug("Peer proved to run a delegate", "delegate", proof.Delegate)
+			valid = true
+		}
+	}
+	return valid, nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/p2p/discover"
+)
+
+// Tests that delegate proofs only verify for the node and delegate they were
+// signed for.
+func TestDelegateProofVerify(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	node := discover.NodeID{1}
+
+	sig, err := crypto.Sign(delegateProofHash(node), key)
+	if err != nil {
+		t.Fatalf("failed to sign proof: %v", err)
+	}
+	proof := &delegateProof{Delegate: crypto.PubkeyToAddress(key.PublicKey), Signature: sig}
+	if err := proof.verify(node); err != nil {
+		t.Errorf("valid proof rejected: %v", err)
+	}
+	if err := proof.verify(discover.NodeID{2}); err == nil {
+		t.Errorf("proof accepted for another node")
+	}
+	forged := &delegateProof{Delegate: crypto.PubkeyToAddress(other.PublicKey), Signature: sig}
+	if err := forged.verify(node); err != errDelegateProofSigner {
+		t.Errorf("forged proof error mismatch: have %v, want %v", err, errDelegateProofSigner)
+	}
+}
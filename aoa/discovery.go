@@ -0,0 +1,228 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/p2p"
+	"github.com/Aurorachain-io/go-aoa/p2p/discover"
+	"github.com/Aurorachain-io/go-aoa/p2p/discv5"
+)
+
+const (
+	// Prefixes of the topics advertised on the v5 discovery network. They are
+	// suffixed with the genesis hash, so nodes of other networks are not found.
+	networkTopicPrefix  = "AOA@"
+	delegateTopicPrefix = "AOADLG@"
+
+	// delegateCheckInterval is the time between two checks whether a local
+	// account is a registered delegate, advertising the delegate topic.
+	delegateCheckInterval = time.Minute
+
+	// delegateSearchPeriod is the time between two lookups of the delegate
+	// topic while the node is a delegate.
+	delegateSearchPeriod = 10 * time.Second
+
+	// maxDelegateCandidates is the maximum number of nodes found under the
+	// delegate topic which are connected while waiting for a delegate proof.
+	maxDelegateCandidates = 32
+
+	// maxDelegatePeers is the maximum number of proven delegate nodes kept
+	// connected as priority peers.
+	maxDelegatePeers = 64
+
+	// delegateProofTimeout is the time a node found under the delegate topic
+	// has to prove it runs a delegate before it is disconnected.
+	delegateProofTimeout = time.Minute
+)
+
+// discoveryTopic returns the discovery topic with the given prefix of the network
+// with the given genesis hash.
+func discoveryTopic(prefix string, genesis common.Hash) discv5.Topic {
+	return discv5.Topic(prefix + common.Bytes2Hex(genesis[:8]))
+}
+
+// topicDiscovery advertises the node on the v5 topic discovery network. While a
+// local account is a registered delegate, it also advertises the delegate topic and
+// connects to the nodes found under it. The ones proving to run a registered
+// delegate are kept connected as priority peers, so delegates reach each other
+// quickly after a restart.
+type topicDiscovery struct {
+	srv        *p2p.Server
+	genesis    common.Hash
+	isDelegate func() bool            // Whether a local account is a registered delegate
+	verified   <-chan discover.NodeID // Peers proven to run a registered delegate
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// delegateCandidate is a node found under the delegate topic which has not
+// proven to run a delegate yet.
+type delegateCandidate struct {
+	node  *discover.Node
+	found time.Time
+}
+
+// newTopicDiscovery creates a topic advertiser on the discovery network of the
+// server, which must run v5 discovery.
+func newTopicDiscovery(srv *p2p.Server, genesis common.Hash, isDelegate func() bool, verified <-chan discover.NodeID) *topicDiscovery {
+	return &topicDiscovery{
+		srv:        srv,
+		genesis:    genesis,
+		isDelegate: isDelegate,
+		verified:   verified,
+		quit:       make(chan struct{}),
+	}
+}
+
+// start advertises the network topic and starts tracking the delegate topic.
+func (d *topicDiscovery) start() {
+	d.wg.Add(2)
+	go func() {
+		defer d.wg.Done()
+		d.srv.DiscV5.RegisterTopic(discoveryTopic(networkTopicPrefix, d.genesis), d.quit)
+	}()
+	go d.loop()
+}
+
+// stop withdraws the advertised topics and waits for the loops to return.
+func (d *topicDiscovery) stop() {
+	close(d.quit)
+	d.wg.Wait()
+}
+
+// loop advertises and searches the delegate topic while a local account is a
+// registered delegate.
+func (d *topicDiscovery) loop() {
+	defer d.wg.Done()
+
+	var (
+		topic  = discoveryTopic(delegateTopicPrefix, d.genesis)
+		found  = make(chan *discv5.Node, 16)
+		lookup = make(chan bool, 16)
+		added  = make(map[discover.NodeID]*discover.Node)
+
+		candidates = make(map[discover.NodeID]*delegateCandidate)
+
+		stopAdvert chan struct{}      // Closed to withdraw the delegate topic, nil if not advertised
+		setPeriod  chan time.Duration // Closed to stop searching the delegate topic
+	)
+	check := time.NewTicker(delegateCheckInterval)
+	defer check.Stop()
+
+	update := func() {
+		active := d.isDelegate()
+		switch {
+		case active && stopAdvert == nil:
+			log.Info("Advertising delegate discovery topic", "topic", topic)
+			stopAdvert, setPeriod = make(chan struct{}), make(chan time.Duration, 1)
+			setPeriod <- delegateSearchPeriod
+
+			d.wg.Add(2)
+			go func(stop chan struct{}) {
+				defer d.wg.Done()
+				d.srv.DiscV5.RegisterTopic(topic, stop)
+			}(stopAdvert)
+			go func(period chan time.Duration) {
+				defer d.wg.Done()
+				d.srv.DiscV5.SearchTopic(topic, period, found, lookup)
+			}(setPeriod)
+
+		case !active && stopAdvert != nil:
+			log.Info("Withdrawing delegate discovery topic", "topic", topic)
+			close(stopAdvert)
+			close(setPeriod)
+			stopAdvert, setPeriod = nil, nil
+
+			for _, node := range added {
+				d.srv.RemovePriorityPeer(node)
+			}
+			for _, c := range candidates {
+				d.srv.RemovePeer(c.node)
+			}
+			added = make(map[discover.NodeID]*discover.Node)
+			candidates = make(map[discover.NodeID]*delegateCandidate)
+		}
+	}
+	update()
+	for {
+		select {
+		case <-check.C:
+			update()
+
+			// Drop the candidates which didn't prove to run a delegate in time
+			for id, c := range candidates {
+				if time.Since(c.found) > delegateProofTimeout {
+					log.Debug("Dropping unproven delegate candidate", "node", c.node)
+					d.srv.RemovePeer(c.node)
+					delete(candidates, id)
+				}
+			}
+
+		case node := <-found:
+			id := discover.NodeID(node.ID)
+			if stopAdvert == nil || added[id] != nil || candidates[id] != nil || id == d.srv.Self().ID {
+				continue
+			}
+			if len(candidates) >= maxDelegateCandidates || len(added) >= maxDelegatePeers {
+				continue
+			}
+			// Anyone may register the delegate topic, so only connect to the
+			// node until it proves to run a delegate
+			peer := discover.NewNode(id, node.IP, node.UDP, node.TCP)
+			log.Debug("Found delegate candidate through topic discovery", "node", peer)
+			candidates[id] = &delegateCandidate{node: peer, found: time.Now()}
+			d.srv.AddPeer(peer)
+
+		case id := <-d.verified:
+			c := candidates[id]
+			if c == nil || len(added) >= maxDelegatePeers {
+				continue
+			}
+			log.Debug("Delegate candidate proved to run a delegate", "node", c.node)
+			delete(candidates, id)
+			added[id] = c.node
+			d.srv.AddPriorityPeer(c.node)
+
+		case <-lookup:
+			// Lookup progress is not tracked, drain to keep the search running
+
+		case <-d.quit:
+			if stopAdvert != nil {
+				close(stopAdvert)
+				close(setPeriod)
+			}
+			return
+		}
+	}
+}
+
+// hasLocalDelegate returns whether a local account is a registered delegate at
+// the head of the chain.
+func (dacchain *Dacchain) hasLocalDelegate() bool {
+	dState, err := dacchain.blockchain.DelegateStateAt(dacchain.blockchain.CurrentBlock().DelegateRoot())
+	if err != nil {
+		return false
+	}
+	exist, _ := dacchain.dposTaskManager.checkLocalExistDelegateWhenShuffle(dState)
+	return exist
+}
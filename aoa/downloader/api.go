@@ -33,19 +33,58 @@ type PublicDownloaderAPI struct {
 }
 
 // NewPublicDownloaderAPI create a new PublicDownloaderAPI. The API has an internal event loop that
-// listens for events from the downloader through the global event mux. In case it receives one of
-// these events it broadcasts it to all syncing subscriptions that are installed through the
-// installSyncSubscription channel.
+// listens for the sync phase changes of the downloader. In case it receives one of these events it
+// broadcasts it to all syncing subscriptions that are installed through the installSyncSubscription
+// channel.
 func NewPublicDownloaderAPI(d *Downloader) *PublicDownloaderAPI {
 	api := &PublicDownloaderAPI{
 		d:                         d,
 		installSyncSubscription:   make(chan chan interface{}),
 		uninstallSyncSubscription: make(chan *uninstallSyncSubscriptionRequest),
 	}
+	go api.eventLoop()
 	return api
 }
 
-// Syncing provides information when this nodes starts synchronising with the em network and when it's finished.
+// eventLoop runs a loop until the downloader is terminated, announcing the sync
+// phase changes of the downloader to all installed syncing subscriptions.
+func (api *PublicDownloaderAPI) eventLoop() {
+	var (
+		phases         = make(chan SyncPhaseEvent, 16)
+		sub            = api.d.SubscribeSyncPhase(phases)
+		syncStatusSubs = make(map[chan interface{}]struct{})
+	)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case i := <-api.installSyncSubscription:
+			syncStatusSubs[i] = struct{}{}
+		case u := <-api.uninstallSyncSubscription:
+			delete(syncStatusSubs, u.c)
+			close(u.uninstalled)
+		case ev := <-phases:
+			status := &SyncingResult{
+				Syncing: ev.Phase != PhaseDone && ev.Phase != PhaseFailed,
+				Phase:   ev.Phase,
+				Status:  api.d.Progress(),
+			}
+			if ev.Err != nil {
+				status.Error = ev.Err.Error()
+			}
+			for c := range syncStatusSubs {
+				c <- status
+			}
+		case <-sub.Err():
+			return
+		case <-api.d.quitCh:
+			return
+		}
+	}
+}
+
+// Syncing provides information when this nodes starts synchronising with the em network, the phases the
+// synchronisation goes through and when it's finished.
 func (api *PublicDownloaderAPI) Syncing(ctx context.Context) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
@@ -78,6 +117,8 @@ func (api *PublicDownloaderAPI) Syncing(ctx context.Context) (*rpc.Subscription,
 // SyncingResult provides information about the current synchronisation status for this node.
 type SyncingResult struct {
 	Syncing bool                 `json:"syncing"`
+	Phase   SyncPhase            `json:"phase"`
+	Error   string               `json:"error,omitempty"`
 	Status  emchain.SyncProgress `json:"status"`
 }
 
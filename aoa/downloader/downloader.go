@@ -23,13 +23,17 @@ import (
 	"fmt"
 	emchain "github.com/Aurorachain-io/go-aoa"
 	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core"
 	"github.com/Aurorachain-io/go-aoa/core/types"
 	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/event"
 	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/p2p"
 	"github.com/Aurorachain-io/go-aoa/params"
 	"github.com/rcrowley/go-metrics"
 	"math"
 	"math/big"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -44,12 +48,12 @@ var (
 	MaxReceiptFetch = 256 // Amount of transaction receipts to allow fetching per request
 	MaxStateFetch   = 384 // Amount of node state values to allow fetching per request
 
-	MaxForkAncestry  = 3 * params.EpochDuration // Maximum chain reorganisation
-	rttMinEstimate   = 2 * time.Second          // Minimum round-trip time to target for download requests
-	rttMaxEstimate   = 20 * time.Second         // Maximum rount-trip time to target for download requests
-	rttMinConfidence = 0.1                      // Worse confidence factor in our estimated RTT value
-	ttlScaling       = 3                        // Constant scaling factor for RTT -> TTL conversion
-	ttlLimit         = time.Minute              // Maximum TTL allowance to prevent reaching crazy timeouts
+	MaxForkEpochs    = uint64(3)        // Maximum chain reorganisation, in delegate epochs
+	rttMinEstimate   = 2 * time.Second  // Minimum round-trip time to target for download requests
+	rttMaxEstimate   = 20 * time.Second // Maximum rount-trip time to target for download requests
+	rttMinConfidence = 0.1              // Worse confidence factor in our estimated RTT value
+	ttlScaling       = 3                // Constant scaling factor for RTT -> TTL conversion
+	ttlLimit         = time.Minute      // Maximum TTL allowance to prevent reaching crazy timeouts
 
 	qosTuningPeers   = 5    // Number of peers to tune based on (best peers)
 	qosConfidenceCap = 10   // Number of peers above which not to modify RTT confidence
@@ -65,6 +69,7 @@ var (
 	fsPivotInterval        = 256        // Number of headers out of which to randomize the pivot point
 	fsMinFullBlocks        = 64         // Number of blocks to retrieve fully even in fast sync
 	fsCriticalTrials       = uint32(32) // Number of times to retry in the cricical section before bailing
+	fsPivotMaxMoves        = uint32(8)  // Number of times to move a stale pivot forward before bailing
 )
 
 var (
@@ -88,6 +93,8 @@ var (
 	errCancelStateFetch        = errors.New("state data download canceled (requested)")
 	errCancelHeaderProcessing  = errors.New("header processing canceled (requested)")
 	errCancelContentProcessing = errors.New("content processing canceled (requested)")
+	errStaleState              = errors.New("state no longer served by any peer")
+	errStalePivot              = errors.New("fast sync pivot state no longer served")
 	errNoSyncActive            = errors.New("no sync active")
 	errTooOld                  = errors.New("peer doesn't speak recent enough protocol version (need version >= 62)")
 )
@@ -101,6 +108,7 @@ type Downloader struct {
 
 	fsPivotLock  *types.Header // Pivot header on critical section entry (cannot change between retries)
 	fsPivotFails uint32        // Number of subsequent fast sync failures in the critical section
+	fsPivotMoves uint32        // Number of times a stale pivot was moved forward
 
 	rttEstimate   uint64 // Round trip time to target for download requests
 	rttConfidence uint64 // Confidence in the estimated RTT (unit: millionths to allow atomic ops)
@@ -109,8 +117,13 @@ type Downloader struct {
 	syncStatsChainOrigin uint64 // Origin block number where syncing started at
 	syncStatsChainHeight uint64 // Highest block number known when syncing started
 	syncStatsState       stateSyncStats
+	syncStatsPhase       SyncPhase    // Phase of the running or last sync cycle
+	syncStatsStart       time.Time    // Time the chain download of the sync cycle started
+	syncStatsStartBlock  uint64       // Block number the chain download of the sync cycle started at
 	syncStatsLock        sync.RWMutex // Lock protecting the sync stats fields
 
+	phaseFeed event.Feed // Feed announcing the phase changes of the sync cycles
+
 	lightchain LightChain
 	blockchain BlockChain
 
@@ -169,6 +182,9 @@ type LightChain interface {
 
 	// Rollback removes a few recently added elements from the local chain.
 	Rollback([]common.Hash)
+
+	// Config retrieves the chain configuration of the local chain.
+	Config() *params.ChainConfig
 }
 
 // BlockChain encapsulates functions required to sync a (full or fast) blockchain.
@@ -223,6 +239,9 @@ func New(mode SyncMode, stateDb aoadb.Database, chain BlockChain, lightchain Lig
 		stateCh:        make(chan dataPack),
 		stateSyncStart: make(chan *stateSync),
 		trackStateReq:  make(chan *stateReq),
+		syncStatsState: stateSyncStats{
+			processed: core.GetTrieSyncProgress(stateDb),
+		},
 	}
 	go dl.qosTuner()
 	go dl.stateFetcher()
@@ -241,20 +260,70 @@ func (d *Downloader) Progress() emchain.SyncProgress {
 	d.syncStatsLock.RLock()
 	defer d.syncStatsLock.RUnlock()
 
-	current := uint64(0)
-	switch d.mode {
-	case FullSync:
-		current = d.blockchain.CurrentBlock().NumberU64()
-	case FastSync:
-		current = d.blockchain.CurrentFastBlock().NumberU64()
-	}
-	return emchain.SyncProgress{
+	current := d.currentBlock()
+
+	progress := emchain.SyncProgress{
 		StartingBlock: d.syncStatsChainOrigin,
 		CurrentBlock:  current,
 		HighestBlock:  d.syncStatsChainHeight,
 		PulledStates:  d.syncStatsState.processed,
 		KnownStates:   d.syncStatsState.processed + d.syncStatsState.pending,
+		Phase:         string(d.syncStatsPhase),
+	}
+	if d.syncStatsPhase == PhaseDownload {
+		progress.Remaining = estimateRemaining(time.Since(d.syncStatsStart), d.syncStatsStartBlock, current, d.syncStatsChainHeight)
+	}
+	return progress
+}
+
+// currentBlock returns the number of the head block the sync mode imports to.
+func (d *Downloader) currentBlock() uint64 {
+	switch d.mode {
+	case FullSync:
+		return d.blockchain.CurrentBlock().NumberU64()
+	case FastSync:
+		return d.blockchain.CurrentFastBlock().NumberU64()
 	}
+	return 0
+}
+
+// estimateRemaining extrapolates the time needed to download the chain up to the
+// highest block from the rate blocks were imported at since the download started.
+// It returns zero if no block was imported yet.
+func estimateRemaining(elapsed time.Duration, start, current, highest uint64) time.Duration {
+	if current <= start || current >= highest {
+		return 0
+	}
+	perBlock := float64(elapsed) / float64(current-start)
+	return time.Duration(perBlock * float64(highest-current))
+}
+
+// SubscribeSyncPhase creates a subscription announcing the phases the sync cycles
+// go through, ending each cycle with either PhaseDone or PhaseFailed.
+func (d *Downloader) SubscribeSyncPhase(ch chan<- SyncPhaseEvent) event.Subscription {
+	return d.phaseFeed.Subscribe(ch)
+}
+
+// setPhase records the phase a sync cycle entered and announces it.
+func (d *Downloader) setPhase(phase SyncPhase, err error) {
+	d.syncStatsLock.Lock()
+	d.syncStatsPhase = phase
+	d.syncStatsLock.Unlock()
+
+	d.phaseFeed.Send(SyncPhaseEvent{Phase: phase, Err: err})
+}
+
+// PeerStats retrieves the retrieval statistics of all the peers registered for
+// synchronisation, ordered by their ids.
+func (d *Downloader) PeerStats() []PeerSyncStats {
+	peers := d.peers.AllPeers()
+
+	stats := make([]PeerSyncStats, 0, len(peers))
+	for _, p := range peers {
+		stats = append(stats, p.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ID < stats[j].ID })
+	return stats
 }
 
 // Synchronising returns whether the downloader is currently retrieving blocks.
@@ -319,7 +388,11 @@ func (d *Downloader) Synchronise(id string, head common.Hash, td *big.Int, mode
 		errInvalidAncestor, errInvalidChain:
 		if err != errInvalidChain {
 			log.Warn("Synchronisation failed, dropping peer", "peer", id, "err", err)
-			d.dropPeer(id)
+			if err == errTimeout {
+				d.dropPeer(id, p2p.RequestTimeout)
+			} else {
+				d.dropPeer(id, p2p.StallingPeer)
+			}
 		} else {
 			err = nil
 			log.Info("dpos Synchronisation,ignore error")
@@ -410,6 +483,15 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 		log.Debug("Synchronisation terminated", "elapsed", time.Since(start))
 	}(time.Now())
 
+	d.setPhase(PhaseAncestor, nil)
+	defer func() {
+		if err != nil {
+			d.setPhase(PhaseFailed, err)
+		} else {
+			d.setPhase(PhaseDone, nil)
+		}
+	}()
+
 	// Look up the sync boundaries: the common ancestor and the target block
 	latest, err := d.fetchHeight(p)
 	if err != nil {
@@ -426,21 +508,19 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 		d.syncStatsChainOrigin = origin
 	}
 	d.syncStatsChainHeight = height
+	d.syncStatsStart = time.Now()
+	d.syncStatsStartBlock = d.currentBlock()
 	d.syncStatsLock.Unlock()
 
+	d.setPhase(PhaseDownload, nil)
+
 	// Initiate the sync using a concurrent header and content retrieval algorithm
 	pivot := uint64(0)
 	switch d.mode {
 	case FastSync:
 		// Calculate the new fast/slow sync pivot point
 		if d.fsPivotLock == nil {
-			pivotOffset, err := rand.Int(rand.Reader, big.NewInt(int64(fsPivotInterval)))
-			if err != nil {
-				panic(fmt.Sprintf("Failed to access crypto random source: %v", err))
-			}
-			if height > uint64(fsMinFullBlocks)+pivotOffset.Uint64() {
-				pivot = height - uint64(fsMinFullBlocks) - pivotOffset.Uint64()
-			}
+			pivot = d.fastSyncPivot(height)
 		} else {
 			// Pivot point locked in, use this and do not pick a new one!
 			pivot = d.fsPivotLock.Number.Uint64()
@@ -472,11 +552,34 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 		fetchers = append(fetchers, d.processFullSyncContent)
 	}
 	err = d.spawnSync(fetchers)
-	if err != nil && d.mode == FastSync && d.fsPivotLock != nil {
+	if err != nil && d.mode == FastSync {
+		d.fastSyncFailed(err, pivot)
+	}
+	return err
+}
+
+// fastSyncFailed updates the pivot state after a failed fast sync cycle. If
+// nobody serves the pivot state anymore, an unlocked pivot is moved forward on
+// the next cycle, keeping the state downloaded so far so only the nodes changed
+// since are fetched. A locked pivot must not change, so failures in the critical
+// section count towards falling back to full sync instead.
+func (d *Downloader) fastSyncFailed(err error, pivot uint64) {
+	if err == errStalePivot && d.fsPivotLock == nil {
+		if d.fsPivotMoves < fsPivotMaxMoves {
+			d.fsPivotMoves++
+			log.Warn("Moving stale fast sync pivot", "number", pivot, "moves", d.fsPivotMoves)
+			core.DeleteFastSyncPivot(d.stateDB)
+			return
+		}
+		// The pivot went stale too often, the state is likely withheld on purpose
+		log.Warn("Fast sync pivot went stale too often, falling back to full sync", "number", pivot)
+		atomic.StoreUint32(&d.fsPivotFails, fsCriticalTrials)
+		return
+	}
+	if d.fsPivotLock != nil {
 		// If sync failed in the critical section, bump the fail counter.
 		atomic.AddUint32(&d.fsPivotFails, 1)
 	}
-	return err
 }
 
 // spawnSync runs d.process and all given fetcher functions to completion in
@@ -583,6 +686,12 @@ func (d *Downloader) fetchHeight(p *peerConnection) (*types.Header, error) {
 	}
 }
 
+// maxForkAncestry returns the maximum number of blocks a chain reorganisation
+// may reach back, MaxForkEpochs delegate epochs of the local chain.
+func (d *Downloader) maxForkAncestry() uint64 {
+	return MaxForkEpochs * d.lightchain.Config().Epoch()
+}
+
 // findAncestor tries to locate the common ancestor link of the local chain and
 // a remote peers blockchain. In the general case when our node was in sync and
 // on the correct chain, checking the top N links should already get us a match.
@@ -598,8 +707,8 @@ func (d *Downloader) findAncestor(p *peerConnection, height uint64) (uint64, err
 	} else if d.mode == FastSync {
 		ceil = d.blockchain.CurrentFastBlock().NumberU64()
 	}
-	if ceil >= MaxForkAncestry {
-		floor = int64(ceil - MaxForkAncestry)
+	if maxForkAncestry := d.maxForkAncestry(); ceil >= maxForkAncestry {
+		floor = int64(ceil - maxForkAncestry)
 	}
 	// Request the topmost blocks to short circuit binary ancestor lookup
 	head := ceil
@@ -846,7 +955,7 @@ func (d *Downloader) fetchHeaders(p *peerConnection, from uint64) error {
 			// Header retrieval timed out, consider the peer bad and drop
 			p.log.Debug("Header request timed out", "elapsed", ttl)
 			headerTimeoutMeter.Mark(1)
-			d.dropPeer(p.id)
+			d.dropPeer(p.id, p2p.RequestTimeout)
 
 			// Finish the sync gracefully instead of dumping the gathered data though
 			for _, ch := range []chan bool{d.bodyWakeCh, d.receiptWakeCh} {
@@ -1061,7 +1170,7 @@ func (d *Downloader) fetchParts(errCancel error, deliveryCh chan dataPack, deliv
 						setIdle(peer, 0)
 					} else {
 						peer.log.Debug("Stalling delivery, dropping", "type", kind)
-						d.dropPeer(pid)
+						d.dropPeer(pid, p2p.StallingPeer)
 					}
 				}
 			}
@@ -1356,20 +1465,28 @@ func (d *Downloader) importBlockResults(results []*fetchResult) error {
 // database. It also controls the synchronisation of state nodes of the pivot block.
 func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 	// Start syncing state of the reported head block.
-	// This should get us most of the state of the pivot block.
+	// This should get us most of the state of the pivot block. If the head state
+	// isn't served, the pivot state is healed from scratch later on.
 	stateSync := d.syncState(latest.Root)
 	defer stateSync.Cancel()
 	go func() {
-		if err := stateSync.Wait(); err != nil {
+		if err := stateSync.Wait(); err != nil && err != errStaleState {
 			d.queue.Close() // wake up WaitResults
 		}
 	}()
 
 	pivot := d.queue.FastSyncPivot()
+	committed := pivot == 0 // Without a pivot all blocks are imported fully
 	for {
 		results := d.queue.WaitResults()
 		if len(results) == 0 {
-			return stateSync.Cancel()
+			// The head state only seeds the pivot state, so once the pivot is in
+			// (or there is none) cutting its download short is no failure.
+			err := stateSync.Cancel()
+			if err == errStaleState || (err == errCancelStateFetch && committed) {
+				return nil
+			}
+			return err
 		}
 		if d.chainInsertHook != nil {
 			d.chainInsertHook(results)
@@ -1383,6 +1500,7 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 			if err := d.commitPivotBlock(P); err != nil {
 				return err
 			}
+			committed = true
 		}
 		if err := d.importBlockResults(afterP); err != nil {
 			return err
@@ -1412,7 +1530,7 @@ func (d *Downloader) commitFastSyncData(results []*fetchResult, stateSync *state
 		case <-d.quitCh:
 			return errCancelContentProcessing
 		case <-stateSync.done:
-			if err := stateSync.Wait(); err != nil {
+			if err := stateSync.Wait(); err != nil && err != errStaleState {
 				return err
 			}
 		default:
@@ -1440,18 +1558,61 @@ func (d *Downloader) commitFastSyncData(results []*fetchResult, stateSync *state
 	return nil
 }
 
+// fastSyncPivot picks the pivot block to download the state of when fast syncing
+// up to the given height. The pivot of an interrupted sync is resumed while it's
+// still recent enough, keeping the state already downloaded for it relevant; a
+// new pivot is randomized and persisted otherwise.
+func (d *Downloader) fastSyncPivot(height uint64) uint64 {
+	if stored := core.GetFastSyncPivot(d.stateDB); stored != nil {
+		if *stored+uint64(fsMinFullBlocks) <= height && *stored+uint64(fsMinFullBlocks+fsPivotInterval) > height {
+			log.Info("Resuming fast sync pivot", "number", *stored, "height", height)
+			return *stored
+		}
+		log.Debug("Discarding stale fast sync pivot", "number", *stored, "height", height)
+	}
+	pivotOffset, err := rand.Int(rand.Reader, big.NewInt(int64(fsPivotInterval)))
+	if err != nil {
+		panic(fmt.Sprintf("Failed to access crypto random source: %v", err))
+	}
+	if height <= uint64(fsMinFullBlocks)+pivotOffset.Uint64() {
+		return 0
+	}
+	pivot := height - uint64(fsMinFullBlocks) - pivotOffset.Uint64()
+	core.WriteFastSyncPivot(d.stateDB, pivot)
+	return pivot
+}
+
 func (d *Downloader) commitPivotBlock(result *fetchResult) error {
 	b := types.NewBlockWithHeader(result.Header).WithBody(result.Transactions)
-	// Sync the pivot block state. This should complete reasonably quickly because
-	// we've already synced up to the reported head block state earlier.
+	// Heal the pivot block state. This should complete reasonably quickly because
+	// we've already synced up to the reported head block state earlier, only the
+	// nodes differing between the two states are missing.
+	d.setPhase(PhaseHealing, nil)
+
+	d.syncStatsLock.RLock()
+	processed := d.syncStatsState.processed
+	d.syncStatsLock.RUnlock()
+
 	if err := d.syncState(b.Root()).Wait(); err != nil {
+		if err == errStaleState {
+			return errStalePivot
+		}
 		return err
 	}
+	d.syncStatsLock.RLock()
+	log.Info("Healed fast sync pivot state", "number", b.Number(), "nodes", d.syncStatsState.processed-processed)
+	d.syncStatsLock.RUnlock()
+
+	d.setPhase(PhaseDownload, nil)
 	log.Debug("Committing fast sync pivot as new head", "number", b.Number(), "hash", b.Hash())
 	if _, err := d.blockchain.InsertReceiptChain([]*types.Block{b}, []types.Receipts{result.Receipts}); err != nil {
 		return err
 	}
-	return d.blockchain.FastSyncCommitHead(b.Hash())
+	if err := d.blockchain.FastSyncCommitHead(b.Hash()); err != nil {
+		return err
+	}
+	core.DeleteFastSyncPivot(d.stateDB)
+	return nil
 }
 
 // DeliverHeaders injects a new batch of block headers received from a remote
@@ -19,3 +19,20 @@ package downloader
 type DoneEvent struct{}
 type StartEvent struct{}
 type FailedEvent struct{ Err error }
+
+// SyncPhase is a stage of a synchronisation cycle.
+type SyncPhase string
+
+const (
+	PhaseAncestor SyncPhase = "ancestor" // Looking up the common ancestor and the sync target
+	PhaseDownload SyncPhase = "download" // Downloading and importing the chain
+	PhaseHealing  SyncPhase = "healing"  // Fetching the pivot state nodes missing after the synced state moved
+	PhaseDone     SyncPhase = "done"     // Synchronisation completed
+	PhaseFailed   SyncPhase = "failed"   // Synchronisation aborted with an error
+)
+
+// SyncPhaseEvent is posted whenever a synchronisation cycle enters a new phase.
+type SyncPhaseEvent struct {
+	Phase SyncPhase
+	Err   error // Reason of the failure in PhaseFailed
+}
@@ -27,17 +27,22 @@ var (
 	headerReqTimer     = metrics.NewTimer("em/downloader/headers/req")
 	headerDropMeter    = metrics.NewMeter("em/downloader/headers/drop")
 	headerTimeoutMeter = metrics.NewMeter("em/downloader/headers/timeout")
+	headerBatchHist    = metrics.NewHistogram("em/downloader/headers/batch")
 
 	bodyInMeter      = metrics.NewMeter("em/downloader/bodies/in")
 	bodyReqTimer     = metrics.NewTimer("em/downloader/bodies/req")
 	bodyDropMeter    = metrics.NewMeter("em/downloader/bodies/drop")
 	bodyTimeoutMeter = metrics.NewMeter("em/downloader/bodies/timeout")
+	bodyBatchHist    = metrics.NewHistogram("em/downloader/bodies/batch")
 
 	receiptInMeter      = metrics.NewMeter("em/downloader/receipts/in")
 	receiptReqTimer     = metrics.NewTimer("em/downloader/receipts/req")
 	receiptDropMeter    = metrics.NewMeter("em/downloader/receipts/drop")
 	receiptTimeoutMeter = metrics.NewMeter("em/downloader/receipts/timeout")
+	receiptBatchHist    = metrics.NewHistogram("em/downloader/receipts/batch")
 
 	stateInMeter   = metrics.NewMeter("em/downloader/states/in")
 	stateDropMeter = metrics.NewMeter("em/downloader/states/drop")
+	stateBatchHist = metrics.NewHistogram("em/downloader/states/batch")
+	stateLateMeter = metrics.NewMeter("em/downloader/states/late")
 )
@@ -61,6 +61,11 @@ type peerConnection struct {
 	receiptThroughput float64 // Number of receipts measured to be retrievable per second
 	stateThroughput   float64 // Number of node data pieces measured to be retrievable per second
 
+	headerStats  fetchStats // Header retrieval statistics over the lifetime of the connection
+	blockStats   fetchStats // Block (body) retrieval statistics over the lifetime of the connection
+	receiptStats fetchStats // Receipt retrieval statistics over the lifetime of the connection
+	stateStats   fetchStats // Node data retrieval statistics over the lifetime of the connection
+
 	rtt time.Duration // Request round trip time to track responsiveness (QoS)
 
 	headerStarted  time.Time // Time instance when the last header fetch was started
@@ -77,6 +82,54 @@ type peerConnection struct {
 	lock    sync.RWMutex
 }
 
+// fetchStats counts the retrievals of one kind of data made from a peer.
+type fetchStats struct {
+	requests  uint64 // Number of retrieval requests issued to the peer
+	delivered uint64 // Number of items delivered by the peer
+	timeouts  uint64 // Number of requests timed out or answered without data
+}
+
+// reliability returns the ratio of the requests issued to the peer that were
+// answered with data, which is 1 for peers not requested anything yet.
+func (s *fetchStats) reliability() float64 {
+	if s.requests == 0 {
+		return 1
+	}
+	return 1 - float64(s.timeouts)/float64(s.requests)
+}
+
+// PeerFetchStats is the retrieval performance of a peer for one kind of data.
+type PeerFetchStats struct {
+	Requests     uint64  `json:"requests"`     // Number of retrieval requests issued
+	Delivered    uint64  `json:"delivered"`    // Number of items delivered
+	Timeouts     uint64  `json:"timeouts"`     // Number of requests timed out or answered empty
+	TimeoutRatio float64 `json:"timeoutRatio"` // Ratio of the requests that timed out
+	Throughput   float64 `json:"throughput"`   // Estimated number of items retrievable per second
+}
+
+// newPeerFetchStats assembles the reported statistics of a kind of data.
+func newPeerFetchStats(stats fetchStats, throughput float64) PeerFetchStats {
+	return PeerFetchStats{
+		Requests:     stats.requests,
+		Delivered:    stats.delivered,
+		Timeouts:     stats.timeouts,
+		TimeoutRatio: 1 - stats.reliability(),
+		Throughput:   throughput,
+	}
+}
+
+// PeerSyncStats is the synchronisation performance of a peer, as measured by
+// the downloader.
+type PeerSyncStats struct {
+	ID       string         `json:"id"`
+	Version  int            `json:"version"`
+	RTT      string         `json:"rtt"` // Estimated request round trip time
+	Headers  PeerFetchStats `json:"headers"`
+	Bodies   PeerFetchStats `json:"bodies"`
+	Receipts PeerFetchStats `json:"receipts"`
+	States   PeerFetchStats `json:"states"`
+}
+
 // LightPeer encapsulates the methods required to synchronise with a remote light peer.
 type LightPeer interface {
 	Head() (common.Hash, *big.Int)
@@ -92,6 +145,12 @@ type Peer interface {
 	RequestNodeData([]common.Hash) error
 }
 
+// historyPeer is implemented by peers advertising the oldest block whose body
+// and receipts they still serve (e.g. after pruning old chain segments).
+type historyPeer interface {
+	HistoryTail() uint64
+}
+
 // lightPeerWrapper wraps a LightPeer struct, stubbing out the Peer-only methods.
 type lightPeerWrapper struct {
 	peer LightPeer
@@ -154,6 +213,8 @@ func (p *peerConnection) FetchHeaders(from uint64, count int) error {
 		return errAlreadyFetching
 	}
 	p.headerStarted = time.Now()
+	p.countRequest(&p.headerStats)
+	headerBatchHist.Update(int64(count))
 
 	// Issue the header retrieval request (absolut upwards without gaps)
 	go p.peer.RequestHeadersByNumber(from, count, 0, false)
@@ -172,6 +233,8 @@ func (p *peerConnection) FetchBodies(request *fetchRequest) error {
 		return errAlreadyFetching
 	}
 	p.blockStarted = time.Now()
+	p.countRequest(&p.blockStats)
+	bodyBatchHist.Update(int64(len(request.Headers)))
 
 	// Convert the header set to a retrievable slice
 	hashes := make([]common.Hash, 0, len(request.Headers))
@@ -194,6 +257,8 @@ func (p *peerConnection) FetchReceipts(request *fetchRequest) error {
 		return errAlreadyFetching
 	}
 	p.receiptStarted = time.Now()
+	p.countRequest(&p.receiptStats)
+	receiptBatchHist.Update(int64(len(request.Headers)))
 
 	// Convert the header set to a retrievable slice
 	hashes := make([]common.Hash, 0, len(request.Headers))
@@ -216,6 +281,8 @@ func (p *peerConnection) FetchNodeData(hashes []common.Hash) error {
 		return errAlreadyFetching
 	}
 	p.stateStarted = time.Now()
+	p.countRequest(&p.stateStats)
+	stateBatchHist.Update(int64(len(hashes)))
 
 	go p.peer.RequestNodeData(hashes)
 
@@ -226,40 +293,48 @@ func (p *peerConnection) FetchNodeData(hashes []common.Hash) error {
 // requests. Its estimated header retrieval throughput is updated with that measured
 // just now.
 func (p *peerConnection) SetHeadersIdle(delivered int) {
-	p.setIdle(p.headerStarted, delivered, &p.headerThroughput, &p.headerIdle)
+	p.setIdle(p.headerStarted, delivered, &p.headerThroughput, &p.headerStats, &p.headerIdle)
 }
 
 // SetBlocksIdle sets the peer to idle, allowing it to execute new block retrieval
 // requests. Its estimated block retrieval throughput is updated with that measured
 // just now.
 func (p *peerConnection) SetBlocksIdle(delivered int) {
-	p.setIdle(p.blockStarted, delivered, &p.blockThroughput, &p.blockIdle)
+	p.setIdle(p.blockStarted, delivered, &p.blockThroughput, &p.blockStats, &p.blockIdle)
 }
 
 // SetBodiesIdle sets the peer to idle, allowing it to execute block body retrieval
 // requests. Its estimated body retrieval throughput is updated with that measured
 // just now.
 func (p *peerConnection) SetBodiesIdle(delivered int) {
-	p.setIdle(p.blockStarted, delivered, &p.blockThroughput, &p.blockIdle)
+	p.setIdle(p.blockStarted, delivered, &p.blockThroughput, &p.blockStats, &p.blockIdle)
 }
 
 // SetReceiptsIdle sets the peer to idle, allowing it to execute new receipt
 // retrieval requests. Its estimated receipt retrieval throughput is updated
 // with that measured just now.
 func (p *peerConnection) SetReceiptsIdle(delivered int) {
-	p.setIdle(p.receiptStarted, delivered, &p.receiptThroughput, &p.receiptIdle)
+	p.setIdle(p.receiptStarted, delivered, &p.receiptThroughput, &p.receiptStats, &p.receiptIdle)
 }
 
 // SetNodeDataIdle sets the peer to idle, allowing it to execute new state trie
 // data retrieval requests. Its estimated state retrieval throughput is updated
 // with that measured just now.
 func (p *peerConnection) SetNodeDataIdle(delivered int) {
-	p.setIdle(p.stateStarted, delivered, &p.stateThroughput, &p.stateIdle)
+	p.setIdle(p.stateStarted, delivered, &p.stateThroughput, &p.stateStats, &p.stateIdle)
+}
+
+// countRequest records a retrieval request issued to the peer.
+func (p *peerConnection) countRequest(stats *fetchStats) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	stats.requests++
 }
 
 // setIdle sets the peer to idle, allowing it to execute new retrieval requests.
 // Its estimated retrieval throughput is updated with that measured just now.
-func (p *peerConnection) setIdle(started time.Time, delivered int, throughput *float64, idle *int32) {
+func (p *peerConnection) setIdle(started time.Time, delivered int, throughput *float64, stats *fetchStats, idle *int32) {
 	// Irrelevant of the scaling, make sure the peer ends up idle
 	defer atomic.StoreInt32(idle, 0)
 
@@ -268,9 +343,12 @@ func (p *peerConnection) setIdle(started time.Time, delivered int, throughput *f
 
 	// If nothing was delivered (hard timeout / unavailable data), reduce throughput to minimum
 	if delivered == 0 {
+		stats.timeouts++
 		*throughput = 0
 		return
 	}
+	stats.delivered += uint64(delivered)
+
 	// Otherwise update the throughput with a new measurement
 	elapsed := time.Since(started) + 1 // +1 (ns) to ensure non-zero divisor
 	measured := float64(delivered) / (float64(elapsed) / float64(time.Second))
@@ -284,40 +362,73 @@ func (p *peerConnection) setIdle(started time.Time, delivered int, throughput *f
 		"miss", len(p.lacking), "rtt", p.rtt)
 }
 
+// Stats retrieves the retrieval statistics of the peer.
+func (p *peerConnection) Stats() PeerSyncStats {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return PeerSyncStats{
+		ID:       p.id,
+		Version:  p.version,
+		RTT:      p.rtt.String(),
+		Headers:  newPeerFetchStats(p.headerStats, p.headerThroughput),
+		Bodies:   newPeerFetchStats(p.blockStats, p.blockThroughput),
+		Receipts: newPeerFetchStats(p.receiptStats, p.receiptThroughput),
+		States:   newPeerFetchStats(p.stateStats, p.stateThroughput),
+	}
+}
+
+// requestWindow returns the time a request to the peer is sized to take at its
+// measured throughput. It's the target round trip time, stretched to that of the
+// peer on high-latency links, so that their batches grow large enough to cover
+// the latency instead of shrinking along with the throughput measured.
+//
+// The caller must hold the peer lock.
+func (p *peerConnection) requestWindow(targetRTT time.Duration) time.Duration {
+	switch {
+	case p.rtt > rttMaxEstimate:
+		return rttMaxEstimate
+	case p.rtt > targetRTT:
+		return p.rtt
+	default:
+		return targetRTT
+	}
+}
+
 // HeaderCapacity retrieves the peers header download allowance based on its
-// previously discovered throughput.
+// previously discovered throughput and round trip time.
 func (p *peerConnection) HeaderCapacity(targetRTT time.Duration) int {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
 
-	return int(math.Min(1+math.Max(1, p.headerThroughput*float64(targetRTT)/float64(time.Second)), float64(MaxHeaderFetch)))
+	return int(math.Min(1+math.Max(1, p.headerThroughput*float64(p.requestWindow(targetRTT))/float64(time.Second)), float64(MaxHeaderFetch)))
 }
 
 // BlockCapacity retrieves the peers block download allowance based on its
-// previously discovered throughput.
+// previously discovered throughput and round trip time.
 func (p *peerConnection) BlockCapacity(targetRTT time.Duration) int {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
 
-	return int(math.Min(1+math.Max(1, p.blockThroughput*float64(targetRTT)/float64(time.Second)), float64(MaxBlockFetch)))
+	return int(math.Min(1+math.Max(1, p.blockThroughput*float64(p.requestWindow(targetRTT))/float64(time.Second)), float64(MaxBlockFetch)))
 }
 
 // ReceiptCapacity retrieves the peers receipt download allowance based on its
-// previously discovered throughput.
+// previously discovered throughput and round trip time.
 func (p *peerConnection) ReceiptCapacity(targetRTT time.Duration) int {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
 
-	return int(math.Min(1+math.Max(1, p.receiptThroughput*float64(targetRTT)/float64(time.Second)), float64(MaxReceiptFetch)))
+	return int(math.Min(1+math.Max(1, p.receiptThroughput*float64(p.requestWindow(targetRTT))/float64(time.Second)), float64(MaxReceiptFetch)))
 }
 
 // NodeDataCapacity retrieves the peers state download allowance based on its
-// previously discovered throughput.
+// previously discovered throughput and round trip time.
 func (p *peerConnection) NodeDataCapacity(targetRTT time.Duration) int {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
 
-	return int(math.Min(1+math.Max(1, p.stateThroughput*float64(targetRTT)/float64(time.Second)), float64(MaxStateFetch)))
+	return int(math.Min(1+math.Max(1, p.stateThroughput*float64(p.requestWindow(targetRTT))/float64(time.Second)), float64(MaxStateFetch)))
 }
 
 // MarkLacking appends a new entity to the set of items (blocks, receipts, states)
@@ -346,6 +457,16 @@ func (p *peerConnection) Lacks(hash common.Hash) bool {
 	return ok
 }
 
+// Serves retrieves whether the peer advertised to serve the body and receipts of
+// the block with the given number. Peers not advertising it are assumed to serve
+// the entire chain.
+func (p *peerConnection) Serves(number uint64) bool {
+	if hp, ok := p.peer.(historyPeer); ok {
+		return number >= hp.HistoryTail()
+	}
+	return true
+}
+
 // peerSet represents the collection of active peer participating in the chain
 // download procedure.
 type peerSet struct {
@@ -475,7 +596,7 @@ func (ps *peerSet) HeaderIdlePeers() ([]*peerConnection, int) {
 	throughput := func(p *peerConnection) float64 {
 		p.lock.RLock()
 		defer p.lock.RUnlock()
-		return p.headerThroughput
+		return p.headerThroughput * p.headerStats.reliability()
 	}
 	return ps.idlePeers(dac01, dac02+1, idle, throughput)
 }
@@ -489,7 +610,7 @@ func (ps *peerSet) BodyIdlePeers() ([]*peerConnection, int) {
 	throughput := func(p *peerConnection) float64 {
 		p.lock.RLock()
 		defer p.lock.RUnlock()
-		return p.blockThroughput
+		return p.blockThroughput * p.blockStats.reliability()
 	}
 	return ps.idlePeers(dac01, dac02+1, idle, throughput)
 }
@@ -503,7 +624,7 @@ func (ps *peerSet) ReceiptIdlePeers() ([]*peerConnection, int) {
 	throughput := func(p *peerConnection) float64 {
 		p.lock.RLock()
 		defer p.lock.RUnlock()
-		return p.receiptThroughput
+		return p.receiptThroughput * p.receiptStats.reliability()
 	}
 	return ps.idlePeers(dac02, dac02+1, idle, throughput)
 }
@@ -517,14 +638,15 @@ func (ps *peerSet) NodeDataIdlePeers() ([]*peerConnection, int) {
 	throughput := func(p *peerConnection) float64 {
 		p.lock.RLock()
 		defer p.lock.RUnlock()
-		return p.stateThroughput
+		return p.stateThroughput * p.stateStats.reliability()
 	}
 	return ps.idlePeers(dac02, dac02+1, idle, throughput)
 }
 
 // idlePeers retrieves a flat list of all currently idle peers satisfying the
 // protocol version constraints, using the provided function to check idleness.
-// The resulting set of peers are sorted by their measure throughput.
+// The resulting set of peers are sorted by their measure throughput, weighted
+// by the ratio of their requests delivering data.
 func (ps *peerSet) idlePeers(minProtocol, maxProtocol int, idleCheck func(*peerConnection) bool, throughput func(*peerConnection) float64) ([]*peerConnection, int) {
 	ps.lock.RLock()
 	defer ps.lock.RUnlock()
@@ -0,0 +1,160 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/log"
+)
+
+// statsPeer is a remote peer ignoring all requests made to it.
+type statsPeer struct{}
+
+func (statsPeer) Head() (common.Hash, *big.Int)                          { return common.Hash{}, new(big.Int) }
+func (statsPeer) RequestHeadersByHash(common.Hash, int, int, bool) error { return nil }
+func (statsPeer) RequestHeadersByNumber(uint64, int, int, bool) error    { return nil }
+func (statsPeer) RequestBodies([]common.Hash) error                      { return nil }
+func (statsPeer) RequestReceipts([]common.Hash) error                    { return nil }
+func (statsPeer) RequestNodeData([]common.Hash) error                    { return nil }
+
+// Tests that the retrieval statistics of peers are tracked, and that peers often
+// timing out are ranked below equally fast but more reliable ones.
+func TestPeerSyncStats(t *testing.T) {
+	peers := newPeerSet()
+	for _, id := range []string{"reliable", "flaky"} {
+		if err := peers.Register(newPeerConnection(id, dac02, statsPeer{}, log.New("peer", id))); err != nil {
+			t.Fatalf("failed to register peer %s: %v", id, err)
+		}
+	}
+	reliable, flaky := peers.Peer("reliable"), peers.Peer("flaky")
+
+	// Have both peers deliver the same amount of headers, but the flaky one time out twice
+	for i := 0; i < 4; i++ {
+		for _, p := range []*peerConnection{reliable, flaky} {
+			if err := p.FetchHeaders(0, 10); err != nil {
+				t.Fatalf("failed to fetch headers from %s: %v", p.id, err)
+			}
+			if p == flaky && i%2 == 0 {
+				p.SetHeadersIdle(0)
+			} else {
+				p.SetHeadersIdle(10)
+			}
+		}
+	}
+	// Equalise the throughputs, which the timeouts reset
+	reliable.headerThroughput, flaky.headerThroughput = 100, 100
+
+	stats := reliable.Stats().Headers
+	if stats.Requests != 4 || stats.Delivered != 40 || stats.Timeouts != 0 || stats.TimeoutRatio != 0 {
+		t.Errorf("reliable peer stats mismatch: %+v", stats)
+	}
+	stats = flaky.Stats().Headers
+	if stats.Requests != 4 || stats.Delivered != 20 || stats.Timeouts != 2 || stats.TimeoutRatio != 0.5 {
+		t.Errorf("flaky peer stats mismatch: %+v", stats)
+	}
+	if stats := flaky.Stats().Bodies; stats.Requests != 0 || stats.TimeoutRatio != 0 {
+		t.Errorf("unused body stats mismatch: %+v", stats)
+	}
+	idle, _ := peers.HeaderIdlePeers()
+	if len(idle) != 2 || idle[0] != reliable || idle[1] != flaky {
+		t.Errorf("idle peer order mismatch: have %v, want [reliable flaky]", []string{idle[0].id, idle[1].id})
+	}
+}
+
+// Tests that peers on high-latency links are handed larger batches than equally
+// fast peers answering within the target round trip time.
+func TestPeerCapacityLatency(t *testing.T) {
+	fast := newPeerConnection("fast", dac02, statsPeer{}, log.New("peer", "fast"))
+	slow := newPeerConnection("slow", dac02, statsPeer{}, log.New("peer", "slow"))
+
+	target := time.Second
+	for _, p := range []*peerConnection{fast, slow} {
+		p.headerThroughput, p.blockThroughput = 10, 10
+		p.receiptThroughput, p.stateThroughput = 10, 10
+	}
+	fast.rtt, slow.rtt = target/2, 3*target
+
+	if have, want := fast.HeaderCapacity(target), 11; have != want {
+		t.Errorf("fast header capacity mismatch: have %d, want %d", have, want)
+	}
+	if have, want := slow.HeaderCapacity(target), 31; have != want {
+		t.Errorf("slow header capacity mismatch: have %d, want %d", have, want)
+	}
+	if have, want := slow.BlockCapacity(target), 31; have != want {
+		t.Errorf("slow block capacity mismatch: have %d, want %d", have, want)
+	}
+	// Round trip times beyond the estimation cap must not inflate batches further
+	slow.rtt = 2 * rttMaxEstimate
+	if have, want := slow.NodeDataCapacity(target), int(1+10*rttMaxEstimate/time.Second); have != want {
+		t.Errorf("capped state capacity mismatch: have %d, want %d", have, want)
+	}
+}
+
+// tailPeer is a remote peer advertising to only serve the bodies and receipts of
+// blocks from a certain number onwards.
+type tailPeer struct {
+	statsPeer
+	tail uint64
+}
+
+func (p tailPeer) HistoryTail() uint64 { return p.tail }
+
+// Tests that block bodies are only requested from peers advertising to serve
+// them, leaving older blocks to peers retaining the entire history.
+func TestPeerHistoryTail(t *testing.T) {
+	q := newQueue()
+	q.Prepare(1, FullSync, 0, nil)
+
+	headers := make([]*types.Header, 8)
+	for i := range headers {
+		headers[i] = &types.Header{Number: big.NewInt(int64(i + 1)), TxHash: common.Hash{1}}
+		if i > 0 {
+			headers[i].ParentHash = headers[i-1].Hash()
+		}
+	}
+	if inserts := q.Schedule(headers, 1); len(inserts) != len(headers) {
+		t.Fatalf("scheduled header count mismatch: have %d, want %d", len(inserts), len(headers))
+	}
+	pruned := newPeerConnection("pruned", dac02, tailPeer{tail: 5}, log.New("peer", "pruned"))
+	full := newPeerConnection("full", dac02, statsPeer{}, log.New("peer", "full"))
+
+	for _, tt := range []struct {
+		peer       *peerConnection
+		start, end uint64
+	}{
+		{pruned, 5, 8},
+		{full, 1, 4},
+	} {
+		request, _, err := q.ReserveBodies(tt.peer, len(headers))
+		if err != nil {
+			t.Fatalf("peer %s: failed to reserve bodies: %v", tt.peer.id, err)
+		}
+		if request == nil || len(request.Headers) != int(tt.end-tt.start+1) {
+			t.Fatalf("peer %s: reserved bodies mismatch: have %v, want %d-%d", tt.peer.id, request, tt.start, tt.end)
+		}
+		for i, header := range request.Headers {
+			if header.Number.Uint64() != tt.start+uint64(i) {
+				t.Errorf("peer %s: body %d mismatch: have block %d, want %d", tt.peer.id, i, header.Number, tt.start+uint64(i))
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests that the remaining sync time is extrapolated from the import rate since
+// the chain download started.
+func TestEstimateRemaining(t *testing.T) {
+	tests := []struct {
+		elapsed                 time.Duration
+		start, current, highest uint64
+		want                    time.Duration
+	}{
+		{time.Minute, 100, 100, 1000, 0},               // nothing imported yet
+		{time.Minute, 100, 1000, 1000, 0},              // download complete
+		{time.Minute, 100, 200, 1100, 9 * time.Minute}, // 100 blocks a minute, 900 to go
+		{10 * time.Second, 0, 500, 750, 5 * time.Second},
+	}
+	for i, tt := range tests {
+		if have := estimateRemaining(tt.elapsed, tt.start, tt.current, tt.highest); have != tt.want {
+			t.Errorf("test %d: remaining mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
@@ -24,10 +24,12 @@ import (
 	"time"
 
 	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core"
 	"github.com/Aurorachain-io/go-aoa/core/state"
 	"github.com/Aurorachain-io/go-aoa/crypto/sha3"
 	"github.com/Aurorachain-io/go-aoa/aoadb"
 	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/p2p"
 	"github.com/Aurorachain-io/go-aoa/trie"
 )
 
@@ -91,6 +93,7 @@ func (d *Downloader) stateFetcher() {
 func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 	var (
 		active   = make(map[string]*stateReq) // Currently in-flight requests
+		expired  = make(map[string]struct{})  // Peers whose last request timed out
 		finished []*stateReq                  // Completed or failed requests
 		timeout  = make(chan *stateReq)       // Timed out active requests
 	)
@@ -142,6 +145,18 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 			// Discard any data not requested (or previsouly timed out)
 			req := active[pack.PeerId()]
 			if req == nil {
+				// If the peer's last request timed out, its tasks were already rescheduled,
+				// but the late data may still spare re-downloading them from someone else
+				if _, ok := expired[pack.PeerId()]; ok && pack.Items() > 0 {
+					delete(expired, pack.PeerId())
+					if p := d.peers.Peer(pack.PeerId()); p != nil {
+						log.Debug("Late node data", "peer", pack.PeerId(), "len", pack.Items())
+						stateLateMeter.Mark(int64(pack.Items()))
+
+						finished = append(finished, &stateReq{peer: p, response: pack.(*statePack).states})
+						continue
+					}
+				}
 				log.Debug("Unrequested node data", "peer", pack.PeerId(), "len", pack.Items())
 				continue
 			}
@@ -177,6 +192,7 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 			// Move the timed out data back into the download queue
 			finished = append(finished, req)
 			delete(active, req.peer.id)
+			expired[req.peer.id] = struct{}{}
 
 		// Track outgoing state requests:
 		case req := <-d.trackStateReq:
@@ -205,6 +221,7 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 				}
 			})
 			active[req.peer.id] = req
+			delete(expired, req.peer.id)
 		}
 	}
 }
@@ -292,6 +309,11 @@ func (s *stateSync) loop() error {
 			// New peer arrived, try to assign it download tasks
 
 		case <-s.cancel:
+			// Keep the completed part of the download, a later sync of another
+			// root only heals the nodes still missing
+			if err := s.commit(true); err != nil {
+				return err
+			}
 			return errCancelStateFetch
 
 		case req := <-s.deliver:
@@ -301,10 +323,16 @@ func (s *stateSync) loop() error {
 				// 2 items are the minimum requested, if even that times out, we've no use of
 				// this peer at the moment.
 				log.Warn("Stalling state sync, dropping peer", "peer", req.peer.id)
-				s.d.dropPeer(req.peer.id)
+				s.d.dropPeer(req.peer.id, p2p.StallingPeer)
 			}
 			// Process all the received blobs and check for stale delivery
 			stale, err := s.process(req)
+			if err == errStaleState {
+				if err := s.commit(true); err != nil {
+					return err
+				}
+				return errStaleState
+			}
 			if err != nil {
 				log.Warn("Node data write error", "err", err)
 				return err
@@ -325,6 +353,12 @@ func (s *stateSync) commit(force bool) error {
 	start := time.Now()
 	b := s.d.stateDB.NewBatch()
 	s.sched.Commit(b)
+
+	// Persist the progress along with the entries, so an interrupted sync resumes it
+	s.d.syncStatsLock.RLock()
+	core.WriteTrieSyncProgress(b, s.d.syncStatsState.processed+uint64(s.numUncommitted))
+	s.d.syncStatsLock.RUnlock()
+
 	if err := b.Write(); err != nil {
 		return fmt.Errorf("DB write error: %v", err)
 	}
@@ -421,6 +455,9 @@ func (s *stateSync) process(req *stateReq) (bool, error) {
 		if _, ok := req.tasks[hash]; ok {
 			delete(req.tasks, hash)
 			stale = false
+		} else if _, ok := s.tasks[hash]; ok && (err == nil || err == trie.ErrAlreadyProcessed) {
+			// Item rescheduled after a timeout, but delivered late: don't request it again
+			delete(s.tasks, hash)
 		}
 	}
 	// If we're inside the critical section, reset fail counter since we progressed.
@@ -438,10 +475,12 @@ func (s *stateSync) process(req *stateReq) (bool, error) {
 		if len(req.response) > 0 || req.timedOut() {
 			delete(task.attempts, req.peer.id)
 		}
-		// If we've requested the node too many times already, it may be a malicious
-		// sync where nobody has the right data. Abort.
+		// If we've requested the node too many times already, nobody has the data
+		// anymore, either because the synced state went stale or due to a malicious
+		// sync. Abort, letting the downloader move on to a more recent state.
 		if len(task.attempts) >= npeers {
-			return stale, fmt.Errorf("state node %s failed with all peers (%d tries, %d peers)", hash.TerminalString(), len(task.attempts), npeers)
+			log.Warn("State node unavailable from all peers", "hash", hash.TerminalString(), "tries", len(task.attempts), "peers", npeers)
+			return stale, errStaleState
 		}
 		// Missing item, place into the retry queue.
 		s.tasks[hash] = task
@@ -0,0 +1,186 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"math/big"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/log"
+)
+
+// Tests that the pivot of an interrupted fast sync is resumed while still recent
+// enough, and replaced by a newly persisted one when gone stale.
+func TestFastSyncPivotResume(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+	d := &Downloader{stateDB: db}
+
+	// A fresh sync must pick and persist a pivot within the allowed window
+	height := uint64(10000)
+	pivot := d.fastSyncPivot(height)
+	if pivot+uint64(fsMinFullBlocks) > height || pivot+uint64(fsMinFullBlocks+fsPivotInterval) <= height {
+		t.Fatalf("pivot %d out of window for height %d", pivot, height)
+	}
+	if stored := core.GetFastSyncPivot(db); stored == nil || *stored != pivot {
+		t.Fatalf("persisted pivot mismatch: have %v, want %d", stored, pivot)
+	}
+	// A restarted sync with the chain slightly advanced must resume the same pivot
+	if resumed := d.fastSyncPivot(pivot + uint64(fsMinFullBlocks+fsPivotInterval) - 1); resumed != pivot {
+		t.Errorf("resumed pivot mismatch: have %d, want %d", resumed, pivot)
+	}
+	// Once the chain progressed beyond the window, a new pivot must replace it
+	height = pivot + uint64(fsMinFullBlocks+fsPivotInterval)
+	if renewed := d.fastSyncPivot(height); renewed <= pivot {
+		t.Errorf("stale pivot %d not replaced: have %d", pivot, renewed)
+	} else if stored := core.GetFastSyncPivot(db); stored == nil || *stored != renewed {
+		t.Errorf("persisted pivot mismatch: have %v, want %d", stored, renewed)
+	}
+}
+
+// Tests that a failed sync cycle only moves an unlocked stale pivot, at most
+// fsPivotMaxMoves times, and counts every other critical section failure.
+func TestFastSyncStalePivot(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+	d := &Downloader{mode: FastSync, stateDB: db}
+
+	// An unlocked stale pivot is dropped, so the next cycle picks a new one
+	for i := uint32(0); i < fsPivotMaxMoves; i++ {
+		core.WriteFastSyncPivot(db, 100)
+		d.fastSyncFailed(errStalePivot, 100)
+		if stored := core.GetFastSyncPivot(db); stored != nil {
+			t.Fatalf("move %d: stale pivot kept: %d", i, *stored)
+		}
+	}
+	if fails := atomic.LoadUint32(&d.fsPivotFails); fails != 0 {
+		t.Errorf("pivot moves counted as failures: have %d, want 0", fails)
+	}
+	// Once out of moves, the downloader falls back to full sync
+	core.WriteFastSyncPivot(db, 100)
+	d.fastSyncFailed(errStalePivot, 100)
+	if fails := atomic.LoadUint32(&d.fsPivotFails); fails != fsCriticalTrials {
+		t.Errorf("exhausted pivot moves: fail count mismatch: have %d, want %d", fails, fsCriticalTrials)
+	}
+	// A locked pivot is never moved, but counts as a critical section failure
+	d = &Downloader{mode: FastSync, stateDB: db, fsPivotLock: &types.Header{Number: big.NewInt(100)}}
+	d.fastSyncFailed(errStalePivot, 100)
+	if stored := core.GetFastSyncPivot(db); stored == nil || *stored != 100 {
+		t.Errorf("locked pivot moved: have %v, want 100", stored)
+	}
+	if d.fsPivotLock == nil {
+		t.Errorf("pivot lock released")
+	}
+	if fails := atomic.LoadUint32(&d.fsPivotFails); fails != 1 {
+		t.Errorf("locked stale pivot fail count mismatch: have %d, want 1", fails)
+	}
+}
+
+// Tests that the downloader restores the state sync progress of a previous run.
+func TestTrieSyncProgressResume(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+	core.WriteTrieSyncProgress(db, 1234)
+
+	d := New(FullSync, db, nil, nil, nil)
+	defer d.Terminate()
+
+	if processed := d.syncStatsState.processed; processed != 1234 {
+		t.Errorf("restored state progress mismatch: have %d, want %d", processed, 1234)
+	}
+}
+
+// Tests that trie nodes delivered late by a peer whose request timed out are
+// processed, and not requested again from anyone else.
+func TestStateSyncLateDelivery(t *testing.T) {
+	// Create a small state to synchronise
+	srcdb, _ := aoadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(srcdb))
+	statedb.AddBalance(common.Address{1}, big.NewInt(1))
+	root, _ := statedb.CommitTo(srcdb, false)
+	blob, _ := srcdb.Get(root.Bytes())
+
+	dstdb, _ := aoadb.NewMemDatabase()
+	d := &Downloader{stateDB: dstdb, peers: newPeerSet()}
+	slow := newPeerConnection("slow", dac02, statsPeer{}, log.New("peer", "slow"))
+	if err := d.peers.Register(slow); err != nil {
+		t.Fatalf("failed to register peer: %v", err)
+	}
+	s := newStateSync(d, root)
+
+	// Request the root from the peer and time it out, rescheduling the task
+	req := &stateReq{peer: slow}
+	s.fillTasks(1, req)
+	if len(req.items) != 1 || req.items[0] != root {
+		t.Fatalf("requested items mismatch: have %x, want [%x]", req.items, root)
+	}
+	if _, err := s.process(req); err != nil {
+		t.Fatalf("failed to process timed out request: %v", err)
+	}
+	if _, ok := s.tasks[root]; !ok {
+		t.Fatalf("timed out task not rescheduled")
+	}
+	// Deliver the root late and ensure it's not requested anew
+	if _, err := s.process(&stateReq{peer: slow, response: [][]byte{blob}}); err != nil {
+		t.Fatalf("failed to process late delivery: %v", err)
+	}
+	if _, ok := s.tasks[root]; ok {
+		t.Errorf("late delivered task still scheduled for retrieval")
+	}
+	if s.numUncommitted != 1 {
+		t.Errorf("processed node count mismatch: have %d, want 1", s.numUncommitted)
+	}
+}
+
+// Tests that a state no peer serves anymore is reported as stale, allowing the
+// downloader to move on to a more recent state instead of failing the sync.
+func TestStateSyncStaleState(t *testing.T) {
+	srcdb, _ := aoadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(srcdb))
+	statedb.AddBalance(common.Address{1}, big.NewInt(1))
+	root, _ := statedb.CommitTo(srcdb, false)
+
+	dstdb, _ := aoadb.NewMemDatabase()
+	d := &Downloader{stateDB: dstdb, peers: newPeerSet()}
+	for _, id := range []string{"a", "b"} {
+		if err := d.peers.Register(newPeerConnection(id, dac02, statsPeer{}, log.New("peer", id))); err != nil {
+			t.Fatalf("failed to register peer: %v", err)
+		}
+	}
+	s := newStateSync(d, root)
+
+	// The first peer lacking the root leaves it to be retried with the other one
+	first := &stateReq{peer: d.peers.Peer("a")}
+	s.fillTasks(1, first)
+	first.response = [][]byte{}
+	if _, err := s.process(first); err != nil {
+		t.Fatalf("failed to process empty response: %v", err)
+	}
+	// Once all peers lack it, the state is stale
+	second := &stateReq{peer: d.peers.Peer("b")}
+	s.fillTasks(1, second)
+	if len(second.items) != 1 || second.items[0] != root {
+		t.Fatalf("retried items mismatch: have %x, want [%x]", second.items, root)
+	}
+	second.response = [][]byte{}
+	if _, err := s.process(second); err != errStaleState {
+		t.Errorf("error mismatch: have %v, want %v", err, errStaleState)
+	}
+}
@@ -20,10 +20,12 @@ import (
 	"fmt"
 
 	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/p2p"
 )
 
-// peerDropFn is a callback type for dropping a peer detected as malicious.
-type peerDropFn func(id string)
+// peerDropFn is a callback type for dropping a peer detected as malicious or
+// failing to serve the synchronisation, reporting its misbehaviour.
+type peerDropFn func(id string, kind p2p.Misbehaviour)
 
 // dataPack is a data message returned by a peer for some query.
 type dataPack interface {
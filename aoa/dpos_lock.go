@@ -54,6 +54,8 @@ type lockManager struct {
 	lockBlock          *pendBlock
 	storeChan          chan *storeSigns
 	signMap            *blocksSignMap
+	blockInterval      int // seconds between two blocks of the chain
+	delegateAmount     int // number of delegate signatures needed to confirm a block
 }
 
 type pendBlock struct {
@@ -84,7 +86,7 @@ type commmitBlockData struct {
 	rlpEncodeSigns []byte
 }
 
-func newBlockLockManager(insertBlockFunc func(blocks types.Blocks) (int, error), delegateWallets map[string]*ecdsa.PrivateKey) *lockManager {
+func newBlockLockManager(insertBlockFunc func(blocks types.Blocks) (int, error), delegateWallets map[string]*ecdsa.PrivateKey, blockInterval int, delegateAmount int) *lockManager {
 	// create SimpleBlockPool
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	timingWheel := task.NewTimingWheel(ctx)
@@ -98,6 +100,8 @@ func newBlockLockManager(insertBlockFunc func(blocks types.Blocks) (int, error),
 		delegateWallets:    delegateWallets,
 		storeChan:          make(chan *storeSigns, 1024),
 		signMap:            newBlocksSignMap(),
+		blockInterval:      blockInterval,
+		delegateAmount:     delegateAmount,
 	}
 	go lockManager.storeBlockSign()
 	return lockManager
@@ -123,7 +127,7 @@ func (l *lockManager) addPendingBlock(b *types.Block) error {
 		},
 		Ctx: context.Background(),
 	}
-	expireTimeUnix := b.Time().Int64() + int64(blockInterval)
+	expireTimeUnix := b.Time().Int64() + int64(l.blockInterval)
 	expireTime := time.Unix(expireTimeUnix, 0)
 	id := l.tw.AddTimer(expireTime, -1, blockTimeOutTask)
 	l.taskIds.Store(b.Hash().Hex(), id)
@@ -150,6 +154,10 @@ func (l *lockManager) addSignToPendingBlock(blockHash common.Hash, sign []byte,
 		return errors.New(fmt.Sprintf("sign address is not current delegatePeers|address:%s", pubAddress))
 	}
 
+	// Only ever assemble canonical signatures into blocks, malleated ones are
+	// rejected after the low-s fork
+	sign = crypto.NormalizeSignature(sign)
+
 	// check do not deal with repeat msg
 	signMap, ok := l.signMap.get(blockHash.Hex())
 	if ok {
@@ -213,11 +221,11 @@ func (l *lockManager) dealBlockSign(storeSign *storeSigns) {
 			},
 			Ctx: context.Background(),
 		}
-		expireTimeUnix := time.Now().Unix() + int64(blockInterval*10)
+		expireTimeUnix := time.Now().Unix() + int64(l.blockInterval*10)
 		expireTime := time.Unix(expireTimeUnix, 0)
 		l.tw.AddTimer(expireTime, -1, timeOutTask)
 	} else {
-		if signMap.countApproveVote() > uint64(delegateAmount) {
+		if signMap.countApproveVote() > uint64(l.delegateAmount) {
 			return
 		}
 		if !signMap.exist(address) {
@@ -227,15 +235,15 @@ func (l *lockManager) dealBlockSign(storeSign *storeSigns) {
 		}
 
 	}
-	if signMap.countApproveVote() > uint64(delegateAmount) {
+	if signMap.countApproveVote() > uint64(l.delegateAmount) {
 		log.Info("lockBlockManager|collect 2/3 sign success", "blockHash", blockHashHex, "signLength", len(signMap.data))
-		signs := make([]types.VoteSign, 0, delegateAmount+1)
+		signs := make([]types.VoteSign, 0, l.delegateAmount+1)
 		approveVoteCount := 0
 		for _, v := range signMap.data {
 			if v.VoteAction == approveVote {
 				signs = append(signs, v)
 				approveVoteCount++
-				if approveVoteCount > delegateAmount {
+				if approveVoteCount > l.delegateAmount {
 					break
 				}
 			}
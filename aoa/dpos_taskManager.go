@@ -30,6 +30,7 @@ import (
 	"github.com/Aurorachain-io/go-aoa/aoadb"
 	"github.com/Aurorachain-io/go-aoa/log"
 	"github.com/Aurorachain-io/go-aoa/node"
+	"github.com/Aurorachain-io/go-aoa/params"
 	"github.com/Aurorachain-io/go-aoa/rlp"
 	"github.com/Aurorachain-io/go-aoa/task"
 	"github.com/Aurorachain-io/go-aoa/util"
@@ -48,6 +49,8 @@ const (
 type DposTaskManager struct {
 	runningTimeIds          []int64
 	timingWheel             *task.TimingWheel // time schedule
+	slotTimers              []*time.Timer     // timers assembling the blocks of the local delegates
+	leadTime                time.Duration     // time ahead of its slot a block starts being assembled
 	shuffleCallback         func(ctx context.Context)
 	produceBlockCallback    func(ctx context.Context)
 	blockchain              *core.BlockChain
@@ -56,32 +59,66 @@ type DposTaskManager struct {
 	shuffleNewRoundChan     chan types.ShuffleList
 	currentNewRound         types.ShuffleList
 	currentRoundBlockHeight int64
+	currentRoundTime        int64 // shuffle time of the running round, 0 if none is running
 	currentNewRoundHash     common.Hash
+	nextRound               types.ShuffleList // round shuffled ahead of its start, taking over at nextRoundStart
+	nextRoundHash           common.Hash
+	nextRoundBlockHeight    int64
+	nextRoundStart          int64 // start of the next round, 0 if not shuffled yet
+	quit                    chan struct{}
 	shuffleHashChan         chan *types.ShuffleData // use by produce call back
 	delegateStoredb         aoadb.Database
 	mu                      sync.Mutex
+
+	initTaskBeginTime int64 // begin time of the first shuffle round
+	shuffleCount      int64 // number of rounds shuffled since the first one
+
+	maxElectDelegate int // number of delegates producing blocks in a round
+	blockInterval    int // seconds between two blocks
+	delegateAmount   int // number of delegate signatures needed to confirm a block
 }
 
-var initTaskBeginTime int64
-var shuffleCount int64
+// dposParams returns the number of delegates elected per round, the block interval
+// and the number of signatures confirming a block of the given chain.
+func dposParams(config *params.ChainConfig) (maxElectDelegate int, blockInterval int, delegateAmount int) {
+	maxElectDelegate = int(config.MaxElectDelegate.Int64())
+	blockInterval = int(config.BlockInterval.Int64())
+	return maxElectDelegate, blockInterval, (maxElectDelegate / 3) * 2
+}
 
-var maxElectDelegate int
-var blockInterval int
-var delegateAmount int
+// produceLeadTime clamps the time ahead of their slots local delegates start
+// assembling their blocks below the block interval. Longer lead times would start
+// the assembly before the block of the previous slot was released.
+func produceLeadTime(leadTime time.Duration, blockInterval int) time.Duration {
+	interval := time.Duration(blockInterval) * time.Second
+	if leadTime < 0 {
+		return 0
+	}
+	if leadTime >= interval {
+		return interval / 2
+	}
+	return leadTime
+}
 
-func NewDposTaskManager(ctx *node.ServiceContext, blockchain *core.BlockChain, accountManager *accounts.Manager, produceBlockCallback func(ctx context.Context), shuffleHashChan chan *types.ShuffleData) *DposTaskManager {
-	genesisConfig := blockchain.Config()
-	maxElectDelegate = int(genesisConfig.MaxElectDelegate.Int64())
-	blockInterval = int(genesisConfig.BlockInterval.Int64())
-	delegateAmount = (maxElectDelegate / 3) * 2
+func NewDposTaskManager(ctx *node.ServiceContext, blockchain *core.BlockChain, accountManager *accounts.Manager, produceBlockCallback func(ctx context.Context), shuffleHashChan chan *types.ShuffleData, leadTime time.Duration) *DposTaskManager {
+	maxElectDelegate, blockInterval, delegateAmount := dposParams(blockchain.Config())
 	log.Info("NewDposTaskManager", "maxElectDelegate", maxElectDelegate, "blockInterval", blockInterval, "delegateAmount", delegateAmount)
+	if clamped := produceLeadTime(leadTime, blockInterval); clamped != leadTime {
+		log.Warn("Block assembly lead time out of range", "provided", leadTime, "updated", clamped)
+		leadTime = clamped
+	}
 	taskManager := &DposTaskManager{
+		maxElectDelegate:     maxElectDelegate,
+		blockInterval:        blockInterval,
+		delegateAmount:       delegateAmount,
 		runningTimeIds:       make([]int64, 0, maxElectDelegate+1),
 		timingWheel:          task.NewTimingWheel(context.Background()),
+		leadTime:             leadTime,
 		produceBlockCallback: produceBlockCallback,
 		blockchain:           blockchain,
 		accountManager:       accountManager,
 		shuffleNewRoundChan:  make(chan types.ShuffleList),
+		quit:                 make(chan struct{}),
 		shuffleHashChan:      shuffleHashChan,
 	}
 
@@ -99,7 +136,7 @@ func NewDposTaskManager(ctx *node.ServiceContext, blockchain *core.BlockChain, a
 			return
 		}
 		// cal shuffle time of current round
-		shuffleTime := initTaskBeginTime + shuffleCount*int64(maxElectDelegate*blockInterval)
+		shuffleTime := taskManager.initTaskBeginTime + taskManager.shuffleCount*int64(maxElectDelegate*blockInterval)
 		exist, candidates := taskManager.checkLocalExistDelegateWhenShuffle(dState)
 		if !exist {
 			log.Info("DposTaskManager| shuffle end because doesn't exist delegate in this node", "blockNumber", currentBlock.NumberU64(), "len", len(candidates))
@@ -117,26 +154,41 @@ func NewDposTaskManager(ctx *node.ServiceContext, blockchain *core.BlockChain, a
 		for _, v := range candidates {
 			log.Info("shuffle candidate", "blockNumber", currentBlock.NumberU64(), "address", v.Address, "vote", v.Vote, "nick", v.Nickname, "registerTime", v.RegisterTime)
 		}
-		shuffleNewRound := util.ShuffleNewRound(shuffleTime, maxElectDelegate, topDelegates, int64(blockInterval))
+		shuffleNewRound := util.ShuffleNewRound(taskManager.blockchain.Config().ShuffleVersion(currentBlock.Number()), shuffleTime, maxElectDelegate, topDelegates, int64(blockInterval))
 		shuffleData := types.ShuffleDelegateData{BlockNumber: *currentBlock.Number(), ShuffleTime: *big.NewInt(shuffleTime)}
 		err = taskManager.loadShuffleDataToDB(shuffleData)
 		if err != nil {
 			log.Error("DposTaskManager| shuffle data fail load to db", "err", err)
 			return
 		}
-		taskManager.mu.Lock()
-		defer taskManager.mu.Unlock()
-		taskManager.currentRoundBlockHeight = currentBlock.Number().Int64()
+		// The round is shuffled a lead time ahead of its start, so that the block
+		// of its first slot is assembled in time too. Local blocks are built on
+		// it right away, but blocks are verified against the running round until
+		// the new one starts.
 		shuffleList := types.ShuffleList{ShuffleDels: shuffleNewRound}
-		taskManager.currentNewRound = shuffleList
 		rlpShufflehash := rlpHash(shuffleList)
-		taskManager.currentNewRoundHash = rlpShufflehash
+
+		taskManager.mu.Lock()
+		taskManager.nextRound = shuffleList
+		taskManager.nextRoundHash = rlpShufflehash
+		taskManager.nextRoundBlockHeight = currentBlock.Number().Int64()
+		taskManager.nextRoundStart = shuffleTime
+		taskManager.mu.Unlock()
+
 		taskManager.shuffleHashChan <- &types.ShuffleData{ShuffleHash: &rlpShufflehash, ShuffleBlockNumber: currentBlock.Number()}
-		log.Info("shuffle", "shuffleHash", rlpShufflehash)
-		taskManager.shuffleNewRoundChan <- shuffleList
-		shuffleCount++
+		log.Info("shuffle", "shuffleHash", rlpShufflehash, "start", time.Unix(shuffleTime, 0))
+		select {
+		case taskManager.shuffleNewRoundChan <- shuffleList:
+		case <-taskManager.quit:
+			return
+		}
+		taskManager.shuffleCount++
 	}
 	taskManager.shuffleCallback = shuffleCallback
+	// Instant sealing chains produce their blocks on demand, without rounds
+	if blockchain.Config().Instant != nil {
+		return taskManager
+	}
 	// init dpos task
 	taskManager.initTask()
 	taskManager.initShuffleDataFromLevelDB()
@@ -147,6 +199,12 @@ func NewDposTaskManager(ctx *node.ServiceContext, blockchain *core.BlockChain, a
 func (taskManager *DposTaskManager) update() {
 	for {
 		select {
+		case <-taskManager.quit:
+			for _, timer := range taskManager.slotTimers {
+				timer.Stop()
+			}
+			return
+
 		case shuffleList := <-taskManager.shuffleNewRoundChan:
 			shuffleNewRound := shuffleList.ShuffleDels
 			for _, v := range shuffleNewRound {
@@ -159,22 +217,20 @@ func (taskManager *DposTaskManager) update() {
 					localDelegates = append(localDelegates, localDelegate)
 				}
 			}
-			if len(taskManager.runningTimeIds) > 1 {
-				taskManager.runningTimeIds = taskManager.runningTimeIds[:1]
+			// Drop the slots of the previous round and schedule the assembly of
+			// the local delegates' blocks ahead of their slots
+			for _, timer := range taskManager.slotTimers {
+				timer.Stop()
 			}
-			if len(localDelegates) > 0 {
-				for _, v := range localDelegates {
-					ctx := context.WithValue(context.Background(), types.DelegatePrefix, v)
-					onTimeOut := &task.OnTimeOut{
-						Callback: taskManager.produceBlockCallback,
-						Ctx:      ctx,
-					}
-					timeId := taskManager.timingWheel.AddTimer(time.Unix(int64(v.WorkTime), 0), time.Duration(-1), onTimeOut)
-					taskManager.runningTimeIds = append(taskManager.runningTimeIds, timeId)
-				}
-				log.Info("dposTaskManager", "timeIds", taskManager.runningTimeIds)
+			taskManager.slotTimers = taskManager.slotTimers[:0]
+			for _, v := range localDelegates {
+				ctx := context.WithValue(context.Background(), types.DelegatePrefix, v)
+				start := time.Unix(int64(v.WorkTime), 0).Add(-taskManager.leadTime)
+
+				timer := time.AfterFunc(time.Until(start), func() { taskManager.produceBlockCallback(ctx) })
+				taskManager.slotTimers = append(taskManager.slotTimers, timer)
+				log.Info("dposTaskManager scheduled block assembly", "delegate", v.Address, "slot", v.WorkTime, "start", start)
 			}
-			taskManager.currentNewRound = shuffleList
 		}
 	}
 }
@@ -183,14 +239,14 @@ func (taskManager *DposTaskManager) initTask() {
 	for _, timeId := range taskManager.runningTimeIds {
 		taskManager.timingWheel.CancelTimer(timeId)
 	}
-	taskManager.runningTimeIds = make([]int64, 0, maxElectDelegate+1)
+	taskManager.runningTimeIds = make([]int64, 0, taskManager.maxElectDelegate+1)
 	onTimeOut := &task.OnTimeOut{Callback: taskManager.shuffleCallback, Ctx: context.Background()}
 
 	genesisTime := taskManager.blockchain.Genesis().Header().Time.Int64()
-	nextRoundBeginTime, _ := generateNextRoundBeginTime(genesisTime)
-	initTimeId := taskManager.timingWheel.AddTimer(time.Unix(nextRoundBeginTime, 0), time.Duration(blockInterval*maxElectDelegate*secondDuration), onTimeOut)
+	nextRoundBeginTime, _ := generateNextRoundBeginTime(genesisTime, int64(taskManager.maxElectDelegate*taskManager.blockInterval))
+	initTimeId := taskManager.timingWheel.AddTimer(time.Unix(nextRoundBeginTime, 0).Add(-taskManager.leadTime), time.Duration(taskManager.blockInterval*taskManager.maxElectDelegate*secondDuration), onTimeOut)
 	log.Info("dposTaskManager", "initTask|beginTime", time.Unix(nextRoundBeginTime, 0), "initTimeId", initTimeId)
-	initTaskBeginTime = nextRoundBeginTime
+	taskManager.initTaskBeginTime = nextRoundBeginTime
 	taskManager.runningTimeIds = append(taskManager.runningTimeIds, initTimeId)
 	//log.Info("dposTaskManager","timeIds",taskManager.runningTimeIds)
 }
@@ -212,11 +268,11 @@ func (taskManager *DposTaskManager) ShuffleWhenVerifyFail(receiveBlockNumber int
 	if len(topDelegates) == 0 {
 		return errors.New("delegate not exist")
 	}
-	if len(topDelegates) > maxElectDelegate {
-		topDelegates = topDelegates[:maxElectDelegate]
+	if len(topDelegates) > taskManager.maxElectDelegate {
+		topDelegates = topDelegates[:taskManager.maxElectDelegate]
 	}
-	shuffleTime := util.CalShuffleTimeByHeaderTime(initTaskBeginTime, receiveBlockTime, int64(blockInterval), int64(maxElectDelegate))
-	shuffleNewRound := util.ShuffleNewRound(shuffleTime, maxElectDelegate, topDelegates, int64(blockInterval))
+	shuffleTime := util.CalShuffleTimeByHeaderTime(taskManager.initTaskBeginTime, receiveBlockTime, int64(taskManager.blockInterval), int64(taskManager.maxElectDelegate))
+	shuffleNewRound := util.ShuffleNewRound(taskManager.blockchain.Config().ShuffleVersion(shuffleBlock.Number()), shuffleTime, taskManager.maxElectDelegate, topDelegates, int64(taskManager.blockInterval))
 	log.Info("dposTaskManager|verifyFail|shuffleEnd", "shuffleTime", shuffleTime, "blockNumber", shuffleBlock.NumberU64(), "lenCandidates", len(topDelegates), "result", shuffleNewRound)
 	shuffleData := types.ShuffleDelegateData{BlockNumber: *shuffleBlock.Number(), ShuffleTime: *big.NewInt(shuffleTime)}
 	err = taskManager.loadShuffleDataToDB(shuffleData)
@@ -226,8 +282,12 @@ func (taskManager *DposTaskManager) ShuffleWhenVerifyFail(receiveBlockNumber int
 	taskManager.mu.Lock()
 	defer taskManager.mu.Unlock()
 	taskManager.currentRoundBlockHeight = shuffleBlock.Number().Int64()
+	taskManager.currentRoundTime = shuffleTime
 	shuffleList := types.ShuffleList{ShuffleDels: shuffleNewRound}
 	taskManager.currentNewRound = shuffleList
+	if taskManager.nextRoundStart <= shuffleTime {
+		taskManager.nextRoundStart = 0 // superseded by the reshuffled round
+	}
 	rlpShufflehash := rlpHash(shuffleList)
 	taskManager.currentNewRoundHash = rlpShufflehash
 	taskManager.shuffleHashChan <- &types.ShuffleData{ShuffleHash: &rlpShufflehash, ShuffleBlockNumber: shuffleBlock.Number()}
@@ -235,7 +295,10 @@ func (taskManager *DposTaskManager) ShuffleWhenVerifyFail(receiveBlockNumber int
 	if !exist || shuffleTime < time.Now().Unix() { // shuffleTime already expire
 		return nil
 	}
-	taskManager.shuffleNewRoundChan <- shuffleList
+	select {
+	case taskManager.shuffleNewRoundChan <- shuffleList:
+	case <-taskManager.quit:
+	}
 	return nil
 }
 
@@ -283,7 +346,7 @@ func (taskManager *DposTaskManager) checkAddressInAccounts(address string) bool
 // get all top delegatePeers in current node
 func (taskManager *DposTaskManager) GetLocalCurrentRound() []string {
 	localDelegates := make([]string, 0)
-	for _, v := range taskManager.currentNewRound.ShuffleDels {
+	for _, v := range taskManager.GetCurrentShuffleRound().ShuffleDels {
 	Loop:
 		for _, wallet := range taskManager.accountManager.Wallets() {
 			for _, account := range wallet.Accounts() {
@@ -299,7 +362,7 @@ func (taskManager *DposTaskManager) GetLocalCurrentRound() []string {
 
 // get coinbase shuffle info by address, only use by delegate node
 func (taskManager *DposTaskManager) GetCurrentDelegateByAddress(coinbase string) *types.ShuffleDel {
-	for _, v := range taskManager.currentNewRound.ShuffleDels {
+	for _, v := range taskManager.GetCurrentShuffleRound().ShuffleDels {
 		if strings.EqualFold(v.Address, coinbase) {
 			return &v
 		}
@@ -307,16 +370,51 @@ func (taskManager *DposTaskManager) GetCurrentDelegateByAddress(coinbase string)
 	return nil
 }
 
+// currentRound returns the shuffled delegates of the running round, its hash, the
+// number of the block whose delegate state was shuffled and the shuffle time. The
+// shuffle time is 0 if no round is running yet.
+func (taskManager *DposTaskManager) currentRound() (types.ShuffleList, common.Hash, int64, int64) {
+	taskManager.mu.Lock()
+	defer taskManager.mu.Unlock()
+
+	taskManager.startNextRound()
+	return taskManager.currentNewRound, taskManager.currentNewRoundHash, taskManager.currentRoundBlockHeight, taskManager.currentRoundTime
+}
+
 func (taskManager *DposTaskManager) GetCurrentShuffleRound() *types.ShuffleList {
+	taskManager.mu.Lock()
+	defer taskManager.mu.Unlock()
+
+	taskManager.startNextRound()
 	return &taskManager.currentNewRound
 }
 
+// startNextRound makes the round shuffled ahead of its start the running one,
+// once it started. The caller must hold taskManager.mu.
+func (taskManager *DposTaskManager) startNextRound() {
+	if taskManager.nextRoundStart == 0 || time.Now().Unix() < taskManager.nextRoundStart {
+		return
+	}
+	taskManager.currentNewRound = taskManager.nextRound
+	taskManager.currentNewRoundHash = taskManager.nextRoundHash
+	taskManager.currentRoundBlockHeight = taskManager.nextRoundBlockHeight
+	taskManager.currentRoundTime = taskManager.nextRoundStart
+	taskManager.nextRoundStart = 0
+}
+
+// Stop terminates the round scheduling, dropping the block assembly scheduled
+// for the local delegates.
+func (taskManager *DposTaskManager) Stop() {
+	close(taskManager.quit)
+	taskManager.timingWheel.Stop()
+}
+
 // check address is in current top delegatePeers
 func (taskManager *DposTaskManager) checkAddressInCurrentTopAndVerify(address string, sign []byte, blockHash string) bool {
 	b := sha3.Sum256(common.FromHex(blockHash))
 	pubkey, _ := secp256k1.RecoverPubkey(b[:], sign)
 	pubAddress := crypto.PubkeyToAddress(*crypto.ToECDSAPub(pubkey)).Hex()
-	for _, v := range taskManager.currentNewRound.ShuffleDels {
+	for _, v := range taskManager.GetCurrentShuffleRound().ShuffleDels {
 		if strings.EqualFold(v.Address, address) {
 			if strings.EqualFold(pubAddress, address) {
 				return true
@@ -380,8 +478,8 @@ func (taskManager *DposTaskManager) initShuffleDataFromLevelDB() error {
 		return err
 	}
 	topDelegates := delegatedb.GetDelegates()
-	if len(topDelegates) > maxElectDelegate {
-		topDelegates = topDelegates[:maxElectDelegate]
+	if len(topDelegates) > taskManager.maxElectDelegate {
+		topDelegates = topDelegates[:taskManager.maxElectDelegate]
 	}
 	log.Info("dposTaskManager read shuffle data from db success", "blockNumber", sdd.BlockNumber.Int64(), "shuffleTime", sdd.ShuffleTime.Int64())
 	if len(topDelegates) == 0 {
@@ -390,11 +488,12 @@ func (taskManager *DposTaskManager) initShuffleDataFromLevelDB() error {
 		return errors.New(errMsg)
 
 	}
-	shuffleNewRound := util.ShuffleNewRound(sdd.ShuffleTime.Int64(), maxElectDelegate, topDelegates, int64(blockInterval))
+	shuffleNewRound := util.ShuffleNewRound(taskManager.blockchain.Config().ShuffleVersion(&sdd.BlockNumber), sdd.ShuffleTime.Int64(), taskManager.maxElectDelegate, topDelegates, int64(taskManager.blockInterval))
 	shuffleList := types.ShuffleList{ShuffleDels: shuffleNewRound}
 	taskManager.mu.Lock()
 	defer taskManager.mu.Unlock()
 	taskManager.currentRoundBlockHeight = sdd.BlockNumber.Int64()
+	taskManager.currentRoundTime = sdd.ShuffleTime.Int64()
 	taskManager.currentNewRound = shuffleList
 	rlpShuffleHash := rlpHash(shuffleList)
 	taskManager.currentNewRoundHash = rlpShuffleHash
@@ -403,9 +502,8 @@ func (taskManager *DposTaskManager) initShuffleDataFromLevelDB() error {
 }
 
 // cal begin time of next round
-func generateNextRoundBeginTime(genesisBlockTime int64) (int64, error) {
+func generateNextRoundBeginTime(genesisBlockTime int64, roundTime int64) (int64, error) {
 	currentTime := time.Now().Unix()
-	roundTime := int64(maxElectDelegate * blockInterval)
 	finishTime := (currentTime - genesisBlockTime) % int64(roundTime)
 	return currentTime + (roundTime - finishTime), nil
 }
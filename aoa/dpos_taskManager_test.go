@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"github.com/Aurorachain-io/go-aoa/core/types"
 	"testing"
+	"time"
 )
 
 func TestRlpHash(t *testing.T) {
@@ -31,3 +32,23 @@ func TestRlpHash(t *testing.T) {
 	h := rlpHash(list)
 	fmt.Println(h.Hex())
 }
+
+// Tests that the block assembly lead time is kept below the block interval.
+func TestProduceLeadTime(t *testing.T) {
+	tests := []struct {
+		lead     time.Duration
+		interval int
+		want     time.Duration
+	}{
+		{2 * time.Second, 10, 2 * time.Second},
+		{0, 10, 0},
+		{-time.Second, 10, 0},
+		{2 * time.Second, 2, time.Second},
+		{5 * time.Second, 1, 500 * time.Millisecond},
+	}
+	for i, tt := range tests {
+		if have := produceLeadTime(tt.lead, tt.interval); have != tt.want {
+			t.Errorf("test %d: lead time mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
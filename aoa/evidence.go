@@ -0,0 +1,210 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/log"
+)
+
+const (
+	// evidenceWindow is the number of recent heights whose signed blocks are kept
+	// around to detect delegates signing another block at the same height.
+	evidenceWindow = 256
+
+	// evidenceFuture is the number of heights above the local head whose signed
+	// blocks are kept, bounding what unverified blocks may fill the pool with.
+	evidenceFuture = 16
+
+	// maxDelegateEvidences is the maximum number of double sign evidences stored
+	// per delegate, a single one already being enough to slash it.
+	maxDelegateEvidences = 64
+
+	// maxEvidenceBroadcast is the maximum number of evidences accepted in one
+	// double sign message.
+	maxEvidenceBroadcast = 16
+)
+
+// errUnknownDoubleSigner is returned if an evidence accuses an address which is
+// not a delegate.
+var errUnknownDoubleSigner = errors.New("double signer is not a delegate")
+
+// evidenceSlot identifies the height a delegate signed a block at. Of the blocks
+// at the same height, the delegate may only sign one per slot time.
+type evidenceSlot struct {
+	number   uint64
+	delegate common.Address
+}
+
+// evidencePool detects delegates signing two different blocks in the same slot
+// by remembering the first signed block seen of every delegate at the recent
+// heights, and stores the resulting double sign evidences in the database.
+type evidencePool struct {
+	db   aoadb.Database
+	seen map[evidenceSlot]*types.Block // First signed block seen in every slot
+	head uint64                        // Local head height the pool was last pruned at
+	lock sync.Mutex
+}
+
+// newEvidencePool creates a double sign detector storing evidences into db.
+func newEvidencePool(db aoadb.Database) *evidencePool {
+	return &evidencePool{
+		db:   db,
+		seen: make(map[evidenceSlot]*types.Block),
+	}
+}
+
+// observe records a signed block, returning the evidence of its producer double
+// signing if a different block it signed in the same slot was seen before. Only
+// blocks within the evidence window around the local head are kept, and blocks
+// not signed by their producer are ignored.
+func (p *evidencePool) observe(block *types.Block, head uint64) *core.DoubleSignEvidence {
+	if len(block.Signature) == 0 {
+		return nil
+	}
+	number := block.NumberU64()
+	if number+evidenceWindow <= head || number > head+evidenceFuture {
+		return nil
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	slot := evidenceSlot{number: number, delegate: block.Coinbase()}
+	prev := p.seen[slot]
+	if prev != nil && (prev.Hash() == block.Hash() || prev.Time().Cmp(block.Time()) != 0) {
+		return nil
+	}
+	if signer, err := core.BlockSigner(block.Hash(), block.Signature); err != nil || signer != block.Coinbase() {
+		return nil
+	}
+	if prev != nil {
+		return core.NewDoubleSignEvidence(prev, block)
+	}
+	p.seen[slot] = block
+	if head > p.head {
+		p.head = head
+		for slot := range p.seen {
+			if slot.number+evidenceWindow <= p.head {
+				delete(p.seen, slot)
+			}
+		}
+	}
+	return nil
+}
+
+// add verifies and stores an evidence, returning whether it wasn't known yet.
+func (p *evidencePool) add(evidence *core.DoubleSignEvidence) (bool, error) {
+	if err := evidence.Verify(); err != nil {
+		return false, err
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var (
+		delegate  = evidence.Delegate()
+		hash      = evidence.Hash()
+		evidences = core.GetDoubleSignEvidences(p.db, delegate)
+	)
+	for _, known := range evidences {
+		if known.Hash() == hash {
+			return false, nil
+		}
+	}
+	if len(evidences) >= maxDelegateEvidences {
+		return false, nil
+	}
+	if len(evidences) == 0 {
+		if err := core.WriteDoubleSigners(p.db, append(core.GetDoubleSigners(p.db), delegate)); err != nil {
+			return false, err
+		}
+	}
+	if err := core.WriteDoubleSignEvidences(p.db, delegate, append(evidences, evidence)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// signers returns the delegates with double sign evidence, in order of detection.
+func (p *evidencePool) signers() []common.Address {
+	return core.GetDoubleSigners(p.db)
+}
+
+// evidences returns the double sign evidences of a delegate.
+func (p *evidencePool) evidences(delegate common.Address) []*core.DoubleSignEvidence {
+	return core.GetDoubleSignEvidences(p.db, delegate)
+}
+
+// observeBlock checks whether the producer of a block received from the network
+// signed a different block in the same slot, storing and gossiping the evidence
+// if so. The block itself is not validated yet, so only blocks of known delegates
+// close to the local head are considered.
+func (pm *ProtocolManager) observeBlock(block *types.Block) {
+	if len(block.Signature) == 0 {
+		return
+	}
+	head := pm.blockchain.CurrentBlock().NumberU64()
+	if number := block.NumberU64(); number+evidenceWindow <= head || number > head+evidenceFuture {
+		return
+	}
+	delegates, err := pm.blockchain.DelegateState()
+	if err != nil || !delegates.SubExist(block.Coinbase()) {
+		return
+	}
+	if evidence := pm.evidences.observe(block, head); evidence != nil {
+		if err := pm.addEvidence(evidence); err != nil {
+			log.Debug("Failed to add double sign evidence", "delegate", evidence.Delegate(), "number", evidence.Number(), "err", err)
+		}
+	}
+}
+
+// addEvidence stores an evidence of a known delegate double signing, and
+// gossips it to the peers not knowing it yet.
+func (pm *ProtocolManager) addEvidence(evidence *core.DoubleSignEvidence) error {
+	delegates, err := pm.blockchain.DelegateState()
+	if err != nil {
+		return err
+	}
+	if !delegates.SubExist(evidence.Delegate()) {
+		return errUnknownDoubleSigner
+	}
+	added, err := pm.evidences.add(evidence)
+	if err != nil || !added {
+		return err
+	}
+	log.Warn("Delegate signed two blocks at the same height", "delegate", evidence.Delegate(), "number", evidence.Number(),
+		"first", evidence.First.Hash(), "second", evidence.Second.Hash())
+
+	go pm.BroadcastEvidence(evidence)
+	return nil
+}
+
+// BroadcastEvidence propagates a double sign evidence to all peers which are not
+// known to already have it, unless they don't speak a protocol version
+// supporting it.
+func (pm *ProtocolManager) BroadcastEvidence(evidence *core.DoubleSignEvidence) {
+	peers := pm.peers.PeersWithoutEvidence(evidence.Hash())
+	for _, peer := range peers {
+		peer.SendDoubleSignEvidences([]*core.DoubleSignEvidence{evidence})
+	}
+	log.Trace("Broadcast double sign evidence", "hash", evidence.Hash(), "recipients", len(peers))
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+)
+
+// Tests that the evidence pool only reports two blocks signed in the same slot,
+// and ignores blocks outside of the window around the local head.
+func TestEvidencePoolObserve(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signed := func(number, time int64, extra byte) *types.Block {
+		block := types.NewBlockWithHeader(&types.Header{
+			Number:   big.NewInt(number),
+			Time:     big.NewInt(time),
+			Coinbase: crypto.PubkeyToAddress(key.PublicKey),
+			Extra:    []byte{extra},
+		})
+		block.Signature, _ = crypto.Sign(block.Hash().Bytes(), key)
+		return block
+	}
+	db, _ := aoadb.NewMemDatabase()
+	pool := newEvidencePool(db)
+
+	if evidence := pool.observe(signed(100, 1000, 1), 100); evidence != nil {
+		t.Fatalf("evidence reported for the first block")
+	}
+	if evidence := pool.observe(signed(100, 1010, 2), 100); evidence != nil {
+		t.Fatalf("evidence reported for a block in a later slot")
+	}
+	evidence := pool.observe(signed(100, 1000, 3), 100)
+	if evidence == nil {
+		t.Fatalf("no evidence reported for a block in the same slot")
+	}
+	if err := evidence.Verify(); err != nil {
+		t.Fatalf("reported evidence invalid: %v", err)
+	}
+	// Blocks far away from the local head are not remembered
+	for _, number := range []int64{100 - evidenceWindow, 101 + evidenceFuture} {
+		pool.observe(signed(number, 1000, 1), 100)
+		if evidence := pool.observe(signed(number, 1000, 2), 100); evidence != nil {
+			t.Errorf("evidence reported at #%d outside of the window", number)
+		}
+	}
+	if len(pool.seen) != 1 {
+		t.Errorf("remembered blocks mismatch: have %d, want 1", len(pool.seen))
+	}
+	// Advancing the head prunes the blocks falling out of the window
+	pool.observe(signed(100+evidenceWindow, 1000, 1), 100+evidenceWindow)
+	if len(pool.seen) != 1 {
+		t.Errorf("remembered blocks mismatch after pruning: have %d, want 1", len(pool.seen))
+	}
+}
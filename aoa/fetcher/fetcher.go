@@ -628,6 +628,10 @@ func (f *Fetcher) enqueue(peer string, block *types.Block) {
 		f.queues[peer] = count
 		f.queued[hash] = op
 		f.queue.Push(op, -float32(block.NumberU64()))
+
+		if slot := time.Unix(block.Time().Int64(), 0); block.ReceivedAt.After(slot) {
+			propBlockLatencyTimer.Update(block.ReceivedAt.Sub(slot))
+		}
 		if f.queueChangeHook != nil {
 			f.queueChangeHook(op.block.Hash(), true)
 		}
@@ -664,6 +668,12 @@ func (f *Fetcher) insert(peer string, block *types.Block) {
 			// Weird future block, don't fail, but neither propagate
 
 		default:
+			if consensus.IsViewError(err) {
+				// The block may be valid in another view of the chain, like
+				// another shuffle round, drop it without blaming the peer
+				log.Debug("Propagated block not verifiable locally", "peer", peer, "number", block.Number(), "hash", hash, "err", err)
+				return
+			}
 			// Something went very wrong, drop the peer
 			log.Debug("Propagated block verification failed", "peer", peer, "number", block.Number(), "hash", hash, "err", err)
 			f.dropPeer(peer)
@@ -33,6 +33,11 @@ var (
 	propBroadcastDropMeter = metrics.NewMeter("em/fetcher/prop/broadcasts/drop")
 	propBroadcastDOSMeter  = metrics.NewMeter("em/fetcher/prop/broadcasts/dos")
 
+	// Time from the slot of a propagated block until it was received, whether
+	// pushed or announced and retrieved. The slot is taken from the block
+	// timestamp, which has a resolution of one second.
+	propBlockLatencyTimer = metrics.NewTimer("em/fetcher/prop/blocks/latency")
+
 	headerFetchMeter = metrics.NewMeter("em/fetcher/fetch/headers")
 	bodyFetchMeter   = metrics.NewMeter("em/fetcher/fetch/bodies")
 
@@ -28,8 +28,10 @@ import (
 	emchain "github.com/Aurorachain-io/go-aoa"
 	"github.com/Aurorachain-io/go-aoa/common"
 	"github.com/Aurorachain-io/go-aoa/common/hexutil"
+	"github.com/Aurorachain-io/go-aoa/core"
 	"github.com/Aurorachain-io/go-aoa/core/types"
 	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/internal/aoaapi"
 	"github.com/Aurorachain-io/go-aoa/rpc"
 )
 
@@ -57,6 +59,11 @@ type PublicFilterAPI struct {
 	events    *EventSystem
 	filtersMu sync.Mutex
 	filters   map[rpc.ID]*filter
+
+	stored    map[rpc.ID]struct{}      // Ids of the persisted log filters
+	dirty     map[rpc.ID]*storedFilter // Filter changes not yet persisted, nil if deleted
+	persist   chan struct{}            // Channel to wake the persistence loop up
+	persistMu sync.Mutex               // Lock serialising the filter writes
 }
 
 // NewPublicFilterAPI returns a new PublicFilterAPI instance.
@@ -66,8 +73,13 @@ func NewPublicFilterAPI(backend Backend, lightMode bool) *PublicFilterAPI {
 		chainDb: backend.ChainDb(),
 		events:  NewEventSystem(backend, lightMode),
 		filters: make(map[rpc.ID]*filter),
+		stored:  make(map[rpc.ID]struct{}),
+		dirty:   make(map[rpc.ID]*storedFilter),
+		persist: make(chan struct{}, 1),
 	}
+	api.restoreFilters()
 	go api.timeoutLoop()
+	go api.persistLoop()
 
 	return api
 }
@@ -84,6 +96,9 @@ func (api *PublicFilterAPI) timeoutLoop() {
 			case <-f.deadline.C:
 				f.s.Unsubscribe()
 				delete(api.filters, id)
+				if f.typ == LogsSubscription {
+					api.deleteStoredFilter(id)
+				}
 			default:
 				continue
 			}
@@ -131,7 +146,8 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 
 // NewPendingTransactions creates a subscription that is triggered each time a transaction
 // enters the transaction pool and was signed from one of the transactions this nodes manages.
-func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+// The transaction hashes are sent by default, the full transactions if fullTx is set.
+func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
@@ -140,13 +156,24 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	rpcSub := notifier.CreateSubscription()
 
 	go func() {
-		txHashes := make(chan common.Hash)
-		pendingTxSub := api.events.SubscribePendingTxEvents(txHashes)
+		var (
+			txHashes     = make(chan common.Hash)
+			txs          chan *types.Transaction
+			pendingTxSub *Subscription
+		)
+		if fullTx != nil && *fullTx {
+			txs = make(chan *types.Transaction)
+			pendingTxSub = api.events.SubscribePendingTxs(txs)
+		} else {
+			pendingTxSub = api.events.SubscribePendingTxEvents(txHashes)
+		}
 
 		for {
 			select {
 			case h := <-txHashes:
 				notifier.Notify(rpcSub.ID, h)
+			case tx := <-txs:
+				notifier.Notify(rpcSub.ID, aoaapi.NewRPCPendingTransaction(tx))
 			case <-rpcSub.Err():
 				pendingTxSub.Unsubscribe()
 				return
@@ -262,6 +289,146 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 	return rpcSub, nil
 }
 
+// reorgBlock identifies a single block taking part in a chain reorganisation.
+type reorgBlock struct {
+	Number hexutil.Uint64 `json:"number"`
+	Hash   common.Hash    `json:"hash"`
+}
+
+// reorgNotification is the payload sent to reorg subscribers. Dropped and
+// Adopted are ordered by ascending block number, starting right above the
+// common ancestor.
+type reorgNotification struct {
+	CommonNumber hexutil.Uint64 `json:"commonNumber"`
+	CommonHash   common.Hash    `json:"commonHash"`
+	Dropped      []reorgBlock   `json:"dropped"`
+	Adopted      []reorgBlock   `json:"adopted"`
+}
+
+func newReorgNotification(ev core.ReorgEvent) *reorgNotification {
+	segment := func(blocks types.Blocks) []reorgBlock {
+		refs := make([]reorgBlock, len(blocks))
+		for i, block := range blocks {
+			refs[len(blocks)-1-i] = reorgBlock{Number: hexutil.Uint64(block.NumberU64()), Hash: block.Hash()}
+		}
+		return refs
+	}
+	return &reorgNotification{
+		CommonNumber: hexutil.Uint64(ev.CommonBlock.NumberU64()),
+		CommonHash:   ev.CommonBlock.Hash(),
+		Dropped:      segment(ev.OldChain),
+		Adopted:      segment(ev.NewChain),
+	}
+}
+
+// Reorgs creates a subscription that fires each time the canonical chain is
+// reorganised, reporting the dropped and the adopted block ranges.
+func (api *PublicFilterAPI) Reorgs(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		reorgs := make(chan core.ReorgEvent, chainEvChanSize)
+		reorgSub := api.backend.SubscribeReorgEvent(reorgs)
+		defer reorgSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-reorgs:
+				notifier.Notify(rpcSub.ID, newReorgNotification(ev))
+			case <-reorgSub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// PromotedTransactions creates a subscription that fires with the hash of each
+// queued transaction becoming executable, such as once the nonce gap in front of
+// it was filled.
+func (api *PublicFilterAPI) PromotedTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		promoted := make(chan core.TxPromotedEvent, txChanSize)
+		promotedSub := api.backend.SubscribeTxPromotedEvent(promoted)
+		defer promotedSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-promoted:
+				for _, tx := range ev.Txs {
+					notifier.Notify(rpcSub.ID, tx.Hash())
+				}
+			case <-promotedSub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// droppedTransaction is the payload sent to dropped transaction subscribers.
+type droppedTransaction struct {
+	Hash   common.Hash `json:"hash"`
+	Reason string      `json:"reason"`
+}
+
+// DroppedTransactions creates a subscription that fires with the hash of each
+// transaction dropped from the pool without being included, along with the
+// reason of the drop.
+func (api *PublicFilterAPI) DroppedTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		dropped := make(chan core.TxDroppedEvent, txChanSize)
+		droppedSub := api.backend.SubscribeTxDroppedEvent(dropped)
+		defer droppedSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-dropped:
+				for _, tx := range ev.Txs {
+					notifier.Notify(rpcSub.ID, &droppedTransaction{Hash: tx.Hash(), Reason: ev.Reason})
+				}
+			case <-droppedSub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // FilterCriteria represents a request to create a new filter.
 //
 type FilterCriteria struct {
@@ -283,15 +450,34 @@ type FilterCriteria struct {
 //
 // In case "fromBlock" > "toBlock" an error is returned.
 //
+// Up to maxStoredFilters log filters are persisted in the background, so they
+// keep their id and don't miss any logs if the node is restarted before they
+// time out.
+//
 func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
-	logs := make(chan []*types.Log)
-	logsSub, err := api.events.SubscribeLogs(emchain.FilterQuery(crit), logs)
+	head := api.headNumber()
+
+	id, err := api.installLogFilter(rpc.NewID(), crit, deadline)
 	if err != nil {
 		return rpc.ID(""), err
 	}
+	api.filtersMu.Lock()
+	api.storeFilter(id, crit, head)
+	api.filtersMu.Unlock()
+
+	return id, nil
+}
 
+// installLogFilter subscribes a log filter under the given id, collecting its
+// logs until polled.
+func (api *PublicFilterAPI) installLogFilter(id rpc.ID, crit FilterCriteria, timeout time.Duration) (rpc.ID, error) {
+	logs := make(chan []*types.Log)
+	logsSub, err := api.events.subscribeLogsWithID(id, emchain.FilterQuery(crit), logs)
+	if err != nil {
+		return rpc.ID(""), err
+	}
 	api.filtersMu.Lock()
-	api.filters[logsSub.ID] = &filter{typ: LogsSubscription, crit: crit, deadline: time.NewTimer(deadline), logs: make([]*types.Log, 0), s: logsSub}
+	api.filters[logsSub.ID] = &filter{typ: LogsSubscription, crit: crit, deadline: time.NewTimer(timeout), logs: make([]*types.Log, 0), s: logsSub}
 	api.filtersMu.Unlock()
 
 	go func() {
@@ -342,6 +528,9 @@ func (api *PublicFilterAPI) UninstallFilter(id rpc.ID) bool {
 	f, found := api.filters[id]
 	if found {
 		delete(api.filters, id)
+		if f.typ == LogsSubscription {
+			api.deleteStoredFilter(id)
+		}
 	}
 	api.filtersMu.Unlock()
 	if found {
@@ -407,6 +596,7 @@ func (api *PublicFilterAPI) GetFilterChanges(id rpc.ID) (interface{}, error) {
 		case LogsSubscription:
 			logs := f.logs
 			f.logs = nil
+			api.storeFilter(id, f.crit, api.headNumber())
 			return returnLogs(logs), nil
 		}
 	}
@@ -29,7 +29,6 @@ import (
 	"github.com/Aurorachain-io/go-aoa/common"
 	"github.com/Aurorachain-io/go-aoa/core"
 	"github.com/Aurorachain-io/go-aoa/core/types"
-	"github.com/Aurorachain-io/go-aoa/event"
 	"github.com/Aurorachain-io/go-aoa/rpc"
 )
 
@@ -79,6 +78,7 @@ type subscription struct {
 	logsCrit  dacchain.FilterQuery
 	logs      chan []*types.Log
 	hashes    chan common.Hash
+	txs       chan *types.Transaction // full transactions of pending subscriptions, hashes are sent if nil
 	headers   chan *types.Header
 	installed chan struct{} // closed when the filter is installed
 	err       chan error    // closed when the filter is uninstalled
@@ -140,6 +140,7 @@ func (sub *Subscription) Unsubscribe() {
 				break uninstallLoop
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
+			case <-sub.f.txs:
 			case <-sub.f.headers:
 			}
 		}
@@ -162,6 +163,12 @@ func (es *EventSystem) subscribe(sub *subscription) *Subscription {
 // given criteria to the given logs channel. Default value for the from and to
 // block is "latest". If the fromBlock > toBlock an error is returned.
 func (es *EventSystem) SubscribeLogs(crit dacchain.FilterQuery, logs chan []*types.Log) (*Subscription, error) {
+	return es.subscribeLogsWithID(rpc.NewID(), crit, logs)
+}
+
+// subscribeLogsWithID creates a logs subscription like SubscribeLogs, but using
+// the given subscription id, allowing a persisted filter to be reinstalled.
+func (es *EventSystem) subscribeLogsWithID(id rpc.ID, crit dacchain.FilterQuery, logs chan []*types.Log) (*Subscription, error) {
 	var from, to rpc.BlockNumber
 	if crit.FromBlock == nil {
 		from = rpc.LatestBlockNumber
@@ -176,32 +183,32 @@ func (es *EventSystem) SubscribeLogs(crit dacchain.FilterQuery, logs chan []*typ
 
 	// only interested in pending logs
 	if from == rpc.PendingBlockNumber && to == rpc.PendingBlockNumber {
-		return es.subscribePendingLogs(crit, logs), nil
+		return es.subscribePendingLogs(id, crit, logs), nil
 	}
 	// only interested in new mined logs
 	if from == rpc.LatestBlockNumber && to == rpc.LatestBlockNumber {
-		return es.subscribeLogs(crit, logs), nil
+		return es.subscribeLogs(id, crit, logs), nil
 	}
 	// only interested in mined logs within a specific block range
 	if from >= 0 && to >= 0 && to >= from {
-		return es.subscribeLogs(crit, logs), nil
+		return es.subscribeLogs(id, crit, logs), nil
 	}
 	// interested in mined logs from a specific block number, new logs and pending logs
 	if from >= rpc.LatestBlockNumber && to == rpc.PendingBlockNumber {
-		return es.subscribeMinedPendingLogs(crit, logs), nil
+		return es.subscribeMinedPendingLogs(id, crit, logs), nil
 	}
 	// interested in logs from a specific block number to new mined blocks
 	if from >= 0 && to == rpc.LatestBlockNumber {
-		return es.subscribeLogs(crit, logs), nil
+		return es.subscribeLogs(id, crit, logs), nil
 	}
 	return nil, fmt.Errorf("invalid from and to block combination: from > to")
 }
 
 // subscribeMinedPendingLogs creates a subscription that returned mined and
 // pending logs that match the given criteria.
-func (es *EventSystem) subscribeMinedPendingLogs(crit dacchain.FilterQuery, logs chan []*types.Log) *Subscription {
+func (es *EventSystem) subscribeMinedPendingLogs(id rpc.ID, crit dacchain.FilterQuery, logs chan []*types.Log) *Subscription {
 	sub := &subscription{
-		id:        rpc.NewID(),
+		id:        id,
 		typ:       MinedAndPendingLogsSubscription,
 		logsCrit:  crit,
 		created:   time.Now(),
@@ -216,9 +223,9 @@ func (es *EventSystem) subscribeMinedPendingLogs(crit dacchain.FilterQuery, logs
 
 // subscribeLogs creates a subscription that will write all logs matching the
 // given criteria to the given logs channel.
-func (es *EventSystem) subscribeLogs(crit dacchain.FilterQuery, logs chan []*types.Log) *Subscription {
+func (es *EventSystem) subscribeLogs(id rpc.ID, crit dacchain.FilterQuery, logs chan []*types.Log) *Subscription {
 	sub := &subscription{
-		id:        rpc.NewID(),
+		id:        id,
 		typ:       LogsSubscription,
 		logsCrit:  crit,
 		created:   time.Now(),
@@ -233,9 +240,9 @@ func (es *EventSystem) subscribeLogs(crit dacchain.FilterQuery, logs chan []*typ
 
 // subscribePendingLogs creates a subscription that writes transaction hashes for
 // transactions that enter the transaction pool.
-func (es *EventSystem) subscribePendingLogs(crit dacchain.FilterQuery, logs chan []*types.Log) *Subscription {
+func (es *EventSystem) subscribePendingLogs(id rpc.ID, crit dacchain.FilterQuery, logs chan []*types.Log) *Subscription {
 	sub := &subscription{
-		id:        rpc.NewID(),
+		id:        id,
 		typ:       PendingLogsSubscription,
 		logsCrit:  crit,
 		created:   time.Now(),
@@ -280,6 +287,23 @@ func (es *EventSystem) SubscribePendingTxEvents(hashes chan common.Hash) *Subscr
 	return es.subscribe(sub)
 }
 
+// SubscribePendingTxs creates a subscription that writes the full transactions
+// entering the transaction pool.
+func (es *EventSystem) SubscribePendingTxs(txs chan *types.Transaction) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       PendingTransactionsSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan common.Hash),
+		txs:       txs,
+		headers:   make(chan *types.Header),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
 type filterIndex map[Type]map[rpc.ID]*subscription
 
 // broadcast event to filters that match criteria.
@@ -303,20 +327,19 @@ func (es *EventSystem) broadcast(filters filterIndex, ev interface{}) {
 				f.logs <- matchedLogs
 			}
 		}
-	case *event.TypeMuxEvent:
-		switch muxe := e.Data.(type) {
-		case core.PendingLogsEvent:
-			for _, f := range filters[PendingLogsSubscription] {
-				if e.Time.After(f.created) {
-					if matchedLogs := filterLogs(muxe.Logs, nil, f.logsCrit.ToBlock, f.logsCrit.Addresses, f.logsCrit.Topics); len(matchedLogs) > 0 {
-						f.logs <- matchedLogs
-					}
-				}
+	case core.PendingLogsEvent:
+		for _, f := range filters[PendingLogsSubscription] {
+			if matchedLogs := filterLogs(e.Logs, nil, f.logsCrit.ToBlock, f.logsCrit.Addresses, f.logsCrit.Topics); len(matchedLogs) > 0 {
+				f.logs <- matchedLogs
 			}
 		}
 	case core.TxPreEvent:
 		for _, f := range filters[PendingTransactionsSubscription] {
-			f.hashes <- e.Tx.Hash()
+			if f.txs != nil {
+				f.txs <- e.Tx
+			} else {
+				f.hashes <- e.Tx.Hash()
+			}
 		}
 	case core.ChainEvent:
 		for _, f := range filters[BlocksSubscription] {
@@ -404,6 +427,9 @@ func (es *EventSystem) eventLoop() {
 		// Subscribe []*walletType.Log
 		logsCh  = make(chan []*types.Log, logsChanSize)
 		logsSub = es.backend.SubscribeLogsEvent(logsCh)
+		// Subscribe logs of the pending block
+		pendingLogsCh  = make(chan core.PendingLogsEvent, logsChanSize)
+		pendingLogsSub = es.backend.SubscribePendingLogsEvent(pendingLogsCh)
 		// Subscribe ChainEvent
 		chainEvCh  = make(chan core.ChainEvent, chainEvChanSize)
 		chainEvSub = es.backend.SubscribeChainEvent(chainEvCh)
@@ -413,6 +439,7 @@ func (es *EventSystem) eventLoop() {
 	defer txSub.Unsubscribe()
 	defer rmLogsSub.Unsubscribe()
 	defer logsSub.Unsubscribe()
+	defer pendingLogsSub.Unsubscribe()
 	defer chainEvSub.Unsubscribe()
 
 	for i := UnknownSubscription; i < LastIndexSubscription; i++ {
@@ -428,6 +455,8 @@ func (es *EventSystem) eventLoop() {
 			es.broadcast(index, ev)
 		case ev := <-logsCh:
 			es.broadcast(index, ev)
+		case ev := <-pendingLogsCh:
+			es.broadcast(index, ev)
 		case ev := <-chainEvCh:
 			es.broadcast(index, ev)
 
@@ -457,6 +486,8 @@ func (es *EventSystem) eventLoop() {
 			return
 		case <-logsSub.Err():
 			return
+		case <-pendingLogsSub.Err():
+			return
 		case <-chainEvSub.Err():
 			return
 		}
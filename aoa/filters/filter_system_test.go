@@ -18,6 +18,7 @@ package filters
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"math/rand"
@@ -26,27 +27,33 @@ import (
 	"time"
 
 	dacchain "github.com/Aurorachain-io/go-aoa"
+	"github.com/Aurorachain-io/go-aoa/aoadb"
 	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus/dpos"
 	"github.com/Aurorachain-io/go-aoa/core"
 	"github.com/Aurorachain-io/go-aoa/core/bloombits"
 	"github.com/Aurorachain-io/go-aoa/core/types"
-	"github.com/Aurorachain-io/go-aoa/emdb"
 	"github.com/Aurorachain-io/go-aoa/event"
+	"github.com/Aurorachain-io/go-aoa/internal/aoaapi"
 	"github.com/Aurorachain-io/go-aoa/params"
 	"github.com/Aurorachain-io/go-aoa/rpc"
 )
 
 type testBackend struct {
-	mux        *event.TypeMux
-	db         emdb.Database
-	sections   uint64
-	txFeed     *event.Feed
-	rmLogsFeed *event.Feed
-	logsFeed   *event.Feed
-	chainFeed  *event.Feed
+	mux         *event.TypeMux
+	db          aoadb.Database
+	sections    uint64
+	txFeed      *event.Feed
+	rmLogsFeed  *event.Feed
+	logsFeed    *event.Feed
+	chainFeed   *event.Feed
+	reorgFeed   event.Feed
+	promoteFeed event.Feed
+	dropFeed    event.Feed
+	pendFeed    event.Feed
 }
 
-func (b *testBackend) ChainDb() emdb.Database {
+func (b *testBackend) ChainDb() aoadb.Database {
 	return b.db
 }
 
@@ -76,6 +83,14 @@ func (b *testBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Subsc
 	return b.txFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeTxPromotedEvent(ch chan<- core.TxPromotedEvent) event.Subscription {
+	return b.promoteFeed.Subscribe(ch)
+}
+
+func (b *testBackend) SubscribeTxDroppedEvent(ch chan<- core.TxDroppedEvent) event.Subscription {
+	return b.dropFeed.Subscribe(ch)
+}
+
 func (b *testBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
 	return b.rmLogsFeed.Subscribe(ch)
 }
@@ -84,10 +99,18 @@ func (b *testBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscript
 	return b.logsFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribePendingLogsEvent(ch chan<- core.PendingLogsEvent) event.Subscription {
+	return b.pendFeed.Subscribe(ch)
+}
+
 func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	return b.chainFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeReorgEvent(ch chan<- core.ReorgEvent) event.Subscription {
+	return b.reorgFeed.Subscribe(ch)
+}
+
 func (b *testBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, b.sections
 }
@@ -129,15 +152,15 @@ func TestBlockSubscription(t *testing.T) {
 
 	var (
 		mux         = new(event.TypeMux)
-		db, _       = emdb.NewMemDatabase()
+		db, _       = aoadb.NewMemDatabase()
 		txFeed      = new(event.Feed)
 		rmLogsFeed  = new(event.Feed)
 		logsFeed    = new(event.Feed)
 		chainFeed   = new(event.Feed)
-		backend     = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend     = &testBackend{mux: mux, db: db, txFeed: txFeed, rmLogsFeed: rmLogsFeed, logsFeed: logsFeed, chainFeed: chainFeed}
 		api         = NewPublicFilterAPI(backend, false)
 		genesis     = new(core.Genesis).MustCommit(db)
-		chain, _    = core.GenerateChain(params.TestChainConfig, genesis, nil, db, 10, func(i int, gen *core.BlockGen) {})
+		chain, _    = core.GenerateChain(params.TestChainConfig, genesis, dpos.New(), db, 10, func(i int, gen *core.BlockGen) {})
 		chainEvents = []core.ChainEvent{}
 	)
 
@@ -180,26 +203,88 @@ func TestBlockSubscription(t *testing.T) {
 	<-sub1.Err()
 }
 
+// TestReorgSubscription tests if a reorg subscription reports the dropped and the
+// adopted blocks of a reorg in ascending order, starting above the common block.
+func TestReorgSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db, _   = aoadb.NewMemDatabase()
+		backend = &testBackend{mux: new(event.TypeMux), db: db, txFeed: new(event.Feed), rmLogsFeed: new(event.Feed), logsFeed: new(event.Feed), chainFeed: new(event.Feed)}
+		server  = rpc.NewServer()
+	)
+	if err := server.RegisterName("aoa", NewPublicFilterAPI(backend, false)); err != nil {
+		t.Fatalf("failed to register API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	reorgs := make(chan *reorgNotification)
+	sub, err := client.Subscribe(context.Background(), "aoa", reorgs, "reorgs")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	block := func(number, time int64) *types.Block {
+		return types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number), Time: big.NewInt(time)})
+	}
+	ev := core.ReorgEvent{
+		CommonBlock: block(10, 0),
+		OldChain:    types.Blocks{block(12, 1), block(11, 1)},
+		NewChain:    types.Blocks{block(13, 2), block(12, 2), block(11, 2)},
+	}
+	// The subscription attaches to the backend asynchronously, retry until it's in
+	for backend.reorgFeed.Send(ev) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case reorg := <-reorgs:
+		if reorg.CommonHash != ev.CommonBlock.Hash() || uint64(reorg.CommonNumber) != 10 {
+			t.Errorf("common block mismatch: have #%d [%x], want #10 [%x]", reorg.CommonNumber, reorg.CommonHash, ev.CommonBlock.Hash())
+		}
+		for _, segment := range []struct {
+			name   string
+			have   []reorgBlock
+			blocks types.Blocks
+		}{{"dropped", reorg.Dropped, ev.OldChain}, {"adopted", reorg.Adopted, ev.NewChain}} {
+			if len(segment.have) != len(segment.blocks) {
+				t.Fatalf("%s block count mismatch: have %d, want %d", segment.name, len(segment.have), len(segment.blocks))
+			}
+			for i, ref := range segment.have {
+				want := segment.blocks[len(segment.blocks)-1-i]
+				if uint64(ref.Number) != want.NumberU64() || ref.Hash != want.Hash() {
+					t.Errorf("%s block %d mismatch: have #%d [%x], want #%d [%x]", segment.name, i, ref.Number, ref.Hash, want.NumberU64(), want.Hash())
+				}
+			}
+		}
+	case err := <-sub.Err():
+		t.Fatalf("subscription failed: %v", err)
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for reorg notification")
+	}
+}
+
 // TestPendingTxFilter tests whether pending tx filters retrieve all pending transactions that are posted to the event mux.
 func TestPendingTxFilter(t *testing.T) {
 	t.Parallel()
 
 	var (
 		mux        = new(event.TypeMux)
-		db, _      = emdb.NewMemDatabase()
+		db, _      = aoadb.NewMemDatabase()
 		txFeed     = new(event.Feed)
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux: mux, db: db, txFeed: txFeed, rmLogsFeed: rmLogsFeed, logsFeed: logsFeed, chainFeed: chainFeed}
 		api        = NewPublicFilterAPI(backend, false)
 
 		transactions = []*types.Transaction{
-			types.NewTransaction(0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil, 0, nil, nil, nil, nil, ""),
-			types.NewTransaction(1, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil, 0, nil, nil, nil, nil, ""),
-			types.NewTransaction(2, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil, 0, nil, nil, nil, nil, ""),
-			types.NewTransaction(3, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil, 0, nil, nil, nil, nil, ""),
-			types.NewTransaction(4, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil, 0, nil, nil, nil, nil, ""),
+			types.NewTransaction(0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil, 0, nil, ""),
+			types.NewTransaction(1, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil, 0, nil, ""),
+			types.NewTransaction(2, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil, 0, nil, ""),
+			types.NewTransaction(3, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil, 0, nil, ""),
+			types.NewTransaction(4, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil, 0, nil, ""),
 		}
 
 		hashes []common.Hash
@@ -244,17 +329,331 @@ func TestPendingTxFilter(t *testing.T) {
 	}
 }
 
+// TestPromotedDroppedTxSubscription tests that promoted transaction subscriptions
+// deliver the hashes of the promoted transactions, and dropped transaction ones
+// the hashes of the dropped transactions together with the reason of the drop.
+func TestPromotedDroppedTxSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db, _   = aoadb.NewMemDatabase()
+		backend = &testBackend{mux: new(event.TypeMux), db: db, txFeed: new(event.Feed), rmLogsFeed: new(event.Feed), logsFeed: new(event.Feed), chainFeed: new(event.Feed)}
+		server  = rpc.NewServer()
+
+		transactions = []*types.Transaction{
+			types.NewTransaction(0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil, 0, nil, ""),
+			types.NewTransaction(1, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil, 0, nil, ""),
+			types.NewTransaction(2, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil, 0, nil, ""),
+		}
+	)
+	if err := server.RegisterName("aoa", NewPublicFilterAPI(backend, false)); err != nil {
+		t.Fatalf("failed to register API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	promoted := make(chan common.Hash)
+	promotedSub, err := client.Subscribe(context.Background(), "aoa", promoted, "promotedTransactions")
+	if err != nil {
+		t.Fatalf("failed to subscribe to promoted transactions: %v", err)
+	}
+	defer promotedSub.Unsubscribe()
+
+	dropped := make(chan *droppedTransaction)
+	droppedSub, err := client.Subscribe(context.Background(), "aoa", dropped, "droppedTransactions")
+	if err != nil {
+		t.Fatalf("failed to subscribe to dropped transactions: %v", err)
+	}
+	defer droppedSub.Unsubscribe()
+
+	// The subscriptions attach to the backend asynchronously, retry until they're in
+	for backend.promoteFeed.Send(core.TxPromotedEvent{Txs: transactions[:2]}) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	for backend.dropFeed.Send(core.TxDroppedEvent{Txs: transactions[2:], Reason: "underpriced"}) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	timeout := time.After(time.Second)
+	for i, seenPromoted, seenDropped := 0, 0, 0; i < len(transactions); i++ {
+		select {
+		case hash := <-promoted:
+			if want := transactions[seenPromoted].Hash(); hash != want {
+				t.Errorf("promoted transaction %d mismatch: have %x, want %x", seenPromoted, hash, want)
+			}
+			seenPromoted++
+		case tx := <-dropped:
+			if want := transactions[2+seenDropped].Hash(); tx.Hash != want || tx.Reason != "underpriced" {
+				t.Errorf("dropped transaction %d mismatch: have %x (%s), want %x (underpriced)", seenDropped, tx.Hash, tx.Reason, want)
+			}
+			seenDropped++
+		case <-timeout:
+			t.Fatalf("timeout waiting for promoted and dropped transactions")
+		}
+	}
+}
+
+// TestPendingTxSubscription tests that pending transaction subscriptions deliver
+// either the hashes or the full transactions entering the pool.
+func TestPendingTxSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux        = new(event.TypeMux)
+		db, _      = aoadb.NewMemDatabase()
+		txFeed     = new(event.Feed)
+		rmLogsFeed = new(event.Feed)
+		logsFeed   = new(event.Feed)
+		chainFeed  = new(event.Feed)
+		backend    = &testBackend{mux: mux, db: db, txFeed: txFeed, rmLogsFeed: rmLogsFeed, logsFeed: logsFeed, chainFeed: chainFeed}
+		es         = NewEventSystem(backend, false)
+
+		transactions = []*types.Transaction{
+			types.NewTransaction(0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil, 0, nil, ""),
+			types.NewTransaction(1, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil, 0, nil, ""),
+		}
+		hashes = make(chan common.Hash)
+		txs    = make(chan *types.Transaction)
+	)
+	hashSub := es.SubscribePendingTxEvents(hashes)
+	defer hashSub.Unsubscribe()
+	txSub := es.SubscribePendingTxs(txs)
+	defer txSub.Unsubscribe()
+
+	go func() {
+		for _, tx := range transactions {
+			txFeed.Send(core.TxPreEvent{Tx: tx})
+		}
+	}()
+	timeout := time.After(time.Second)
+	for i, seenHashes, seenTxs := 0, 0, 0; i < 2*len(transactions); i++ {
+		select {
+		case hash := <-hashes:
+			if want := transactions[seenHashes].Hash(); hash != want {
+				t.Errorf("hash %d mismatch: have %x, want %x", seenHashes, hash, want)
+			}
+			seenHashes++
+		case tx := <-txs:
+			if want := transactions[seenTxs]; tx != want {
+				t.Errorf("transaction %d mismatch: have %x, want %x", seenTxs, tx.Hash(), want.Hash())
+			}
+			seenTxs++
+		case <-timeout:
+			t.Fatalf("timeout waiting for pending transactions")
+		}
+	}
+}
+
+// TestPendingTxRPCSubscription tests that the newPendingTransactions subscription
+// sends the transaction hashes by default and the full transactions if requested.
+func TestPendingTxRPCSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db, _   = aoadb.NewMemDatabase()
+		txFeed  = new(event.Feed)
+		backend = &testBackend{mux: new(event.TypeMux), db: db, txFeed: txFeed, rmLogsFeed: new(event.Feed), logsFeed: new(event.Feed), chainFeed: new(event.Feed)}
+		server  = rpc.NewServer()
+
+		transactions = []*types.Transaction{
+			types.NewTransaction(0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil, 0, nil, ""),
+			types.NewTransaction(1, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil, 0, nil, ""),
+		}
+	)
+	if err := server.RegisterName("aoa", NewPublicFilterAPI(backend, false)); err != nil {
+		t.Fatalf("failed to register API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	hashes := make(chan common.Hash)
+	hashSub, err := client.Subscribe(context.Background(), "aoa", hashes, "newPendingTransactions")
+	if err != nil {
+		t.Fatalf("failed to subscribe to pending transaction hashes: %v", err)
+	}
+	defer hashSub.Unsubscribe()
+
+	txs := make(chan *aoaapi.RPCTransaction)
+	txSub, err := client.Subscribe(context.Background(), "aoa", txs, "newPendingTransactions", true)
+	if err != nil {
+		t.Fatalf("failed to subscribe to pending transactions: %v", err)
+	}
+	defer txSub.Unsubscribe()
+
+	// raise events once the subscriptions are installed
+	time.Sleep(1 * time.Second)
+	for _, tx := range transactions {
+		txFeed.Send(core.TxPreEvent{Tx: tx})
+	}
+	timeout := time.After(time.Second)
+	for i, seenHashes, seenTxs := 0, 0, 0; i < 2*len(transactions); i++ {
+		select {
+		case hash := <-hashes:
+			if want := transactions[seenHashes].Hash(); hash != want {
+				t.Errorf("hash %d mismatch: have %x, want %x", seenHashes, hash, want)
+			}
+			seenHashes++
+		case tx := <-txs:
+			if want := transactions[seenTxs]; tx.Hash != want.Hash() || uint64(tx.Nonce) != want.Nonce() {
+				t.Errorf("transaction %d mismatch: have %x (nonce %d), want %x (nonce %d)", seenTxs, tx.Hash, tx.Nonce, want.Hash(), want.Nonce())
+			}
+			seenTxs++
+		case <-timeout:
+			t.Fatalf("timeout waiting for pending transactions")
+		}
+	}
+}
+
+// TestPersistentLogFilter tests that log filters are installed again under the
+// same id after a restart, handing out the logs emitted while the node was down.
+func TestPersistentLogFilter(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux        = new(event.TypeMux)
+		db, _      = aoadb.NewMemDatabase()
+		txFeed     = new(event.Feed)
+		rmLogsFeed = new(event.Feed)
+		logsFeed   = new(event.Feed)
+		chainFeed  = new(event.Feed)
+		backend    = &testBackend{mux: mux, db: db, txFeed: txFeed, rmLogsFeed: rmLogsFeed, logsFeed: logsFeed, chainFeed: chainFeed}
+
+		addr   = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		parent common.Hash
+	)
+	// insert writes a new head block with the given receipts into the database
+	insert := func(number uint64, receipts types.Receipts) {
+		header := &types.Header{Number: new(big.Int).SetUint64(number), ParentHash: parent, Bloom: types.CreateBloom(receipts)}
+		parent = header.Hash()
+
+		core.WriteHeader(db, header)
+		core.WriteCanonicalHash(db, parent, number)
+		core.WriteBlockReceipts(db, parent, number, receipts)
+		core.WriteHeadBlockHash(db, parent)
+	}
+	insert(0, nil)
+
+	api := NewPublicFilterAPI(backend, false)
+	id, err := api.NewFilter(FilterCriteria{Addresses: []common.Address{addr}})
+	if err != nil {
+		t.Fatalf("Unable to create filter: %v", err)
+	}
+	api.persistFilters()
+
+	// Emit a log while the node is down and restart the API
+	insert(1, types.Receipts{&types.Receipt{Logs: []*types.Log{{Address: addr, BlockNumber: 1}}}})
+	api = NewPublicFilterAPI(backend, false)
+
+	var logs []*types.Log
+	for timeout := time.Now().Add(time.Second); len(logs) == 0 && time.Now().Before(timeout); time.Sleep(10 * time.Millisecond) {
+		changes, err := api.GetFilterChanges(id)
+		if err != nil {
+			t.Fatalf("Restored filter not found: %v", err)
+		}
+		logs = append(logs, changes.([]*types.Log)...)
+	}
+	if len(logs) != 1 || logs[0].Address != addr || logs[0].BlockNumber != 1 {
+		t.Fatalf("Missed logs mismatch: have %v", logs)
+	}
+	// Uninstalled filters must not be restored again
+	if !api.UninstallFilter(id) {
+		t.Fatalf("Unable to uninstall restored filter")
+	}
+	api.persistFilters()
+
+	if _, err := NewPublicFilterAPI(backend, false).GetFilterChanges(id); err == nil {
+		t.Fatalf("Uninstalled filter restored")
+	}
+}
+
+// TestPersistentLogFilterLimit tests that only up to maxStoredFilters log filters
+// are persisted, while further ones are still served from memory.
+func TestPersistentLogFilterLimit(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db, _   = aoadb.NewMemDatabase()
+		backend = &testBackend{mux: new(event.TypeMux), db: db, txFeed: new(event.Feed), rmLogsFeed: new(event.Feed), logsFeed: new(event.Feed), chainFeed: new(event.Feed)}
+		api     = NewPublicFilterAPI(backend, false)
+	)
+	var last rpc.ID
+	for i := 0; i <= maxStoredFilters; i++ {
+		id, err := api.NewFilter(FilterCriteria{})
+		if err != nil {
+			t.Fatalf("Unable to create filter %d: %v", i, err)
+		}
+		last = id
+	}
+	api.persistFilters()
+
+	if ids := api.storedFilterIDs(); len(ids) != maxStoredFilters {
+		t.Fatalf("Persisted filter count mismatch: have %d, want %d", len(ids), maxStoredFilters)
+	}
+	if data, _ := db.Get(filterKey(last)); len(data) != 0 {
+		t.Fatalf("Filter beyond the limit persisted")
+	}
+	if _, err := api.GetFilterChanges(last); err != nil {
+		t.Fatalf("Filter beyond the limit not served: %v", err)
+	}
+}
+
+// TestPersistentLogFilterTimeout tests that persisted log filters which timed out
+// while the node was down are not restored, but removed from the database.
+func TestPersistentLogFilterTimeout(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db, _   = aoadb.NewMemDatabase()
+		backend = &testBackend{mux: new(event.TypeMux), db: db, txFeed: new(event.Feed), rmLogsFeed: new(event.Feed), logsFeed: new(event.Feed), chainFeed: new(event.Feed)}
+		api     = NewPublicFilterAPI(backend, false)
+	)
+	live, err := api.NewFilter(FilterCriteria{})
+	if err != nil {
+		t.Fatalf("Unable to create live filter: %v", err)
+	}
+	expired, err := api.NewFilter(FilterCriteria{})
+	if err != nil {
+		t.Fatalf("Unable to create expired filter: %v", err)
+	}
+	api.persistFilters()
+
+	// Let the second filter time out while the node is down and restart the API
+	data, _ := db.Get(filterKey(expired))
+	stored := new(storedFilter)
+	if err := json.Unmarshal(data, stored); err != nil {
+		t.Fatalf("Invalid persisted filter: %v", err)
+	}
+	stored.Deadline = time.Now().Add(-time.Second)
+	if data, err = json.Marshal(stored); err != nil {
+		t.Fatalf("Unable to encode persisted filter: %v", err)
+	}
+	db.Put(filterKey(expired), data)
+
+	api = NewPublicFilterAPI(backend, false)
+	if _, err := api.GetFilterChanges(live); err != nil {
+		t.Fatalf("Live filter not restored: %v", err)
+	}
+	if _, err := api.GetFilterChanges(expired); err == nil {
+		t.Fatalf("Timed out filter restored")
+	}
+	if data, _ := db.Get(filterKey(expired)); len(data) != 0 {
+		t.Fatalf("Timed out filter not removed from the database")
+	}
+	if ids := api.storedFilterIDs(); len(ids) != 1 || ids[0] != live {
+		t.Fatalf("Persisted filter ids mismatch: have %v, want [%v]", ids, live)
+	}
+}
+
 // TestLogFilterCreation test whether a given filter criteria makes sense.
 // If not it must return an error.
 func TestLogFilterCreation(t *testing.T) {
 	var (
 		mux        = new(event.TypeMux)
-		db, _      = emdb.NewMemDatabase()
+		db, _      = aoadb.NewMemDatabase()
 		txFeed     = new(event.Feed)
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux: mux, db: db, txFeed: txFeed, rmLogsFeed: rmLogsFeed, logsFeed: logsFeed, chainFeed: chainFeed}
 		api        = NewPublicFilterAPI(backend, false)
 
 		testCases = []struct {
@@ -298,12 +697,12 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 
 	var (
 		mux        = new(event.TypeMux)
-		db, _      = emdb.NewMemDatabase()
+		db, _      = aoadb.NewMemDatabase()
 		txFeed     = new(event.Feed)
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux: mux, db: db, txFeed: txFeed, rmLogsFeed: rmLogsFeed, logsFeed: logsFeed, chainFeed: chainFeed}
 		api        = NewPublicFilterAPI(backend, false)
 	)
 
@@ -328,12 +727,12 @@ func TestLogFilter(t *testing.T) {
 
 	var (
 		mux        = new(event.TypeMux)
-		db, _      = emdb.NewMemDatabase()
+		db, _      = aoadb.NewMemDatabase()
 		txFeed     = new(event.Feed)
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux: mux, db: db, txFeed: txFeed, rmLogsFeed: rmLogsFeed, logsFeed: logsFeed, chainFeed: chainFeed}
 		api        = NewPublicFilterAPI(backend, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
@@ -400,8 +799,8 @@ func TestLogFilter(t *testing.T) {
 	if nsend := logsFeed.Send(allLogs); nsend == 0 {
 		t.Fatal("Shoud have at least one subscription")
 	}
-	if err := mux.Post(core.PendingLogsEvent{Logs: allLogs}); err != nil {
-		t.Fatal(err)
+	if nsend := backend.pendFeed.Send(core.PendingLogsEvent{Logs: allLogs}); nsend == 0 {
+		t.Fatal("Shoud have at least one subscription")
 	}
 
 	for i, tt := range testCases {
@@ -447,12 +846,12 @@ func TestPendingLogsSubscription(t *testing.T) {
 
 	var (
 		mux        = new(event.TypeMux)
-		db, _      = emdb.NewMemDatabase()
+		db, _      = aoadb.NewMemDatabase()
 		txFeed     = new(event.Feed)
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux: mux, db: db, txFeed: txFeed, rmLogsFeed: rmLogsFeed, logsFeed: logsFeed, chainFeed: chainFeed}
 		api        = NewPublicFilterAPI(backend, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
@@ -464,6 +863,7 @@ func TestPendingLogsSubscription(t *testing.T) {
 		thirdTopic     = common.HexToHash("0x3333333333333333333333333333333333333333333333333333333333333333")
 		fourthTopic    = common.HexToHash("0x4444444444444444444444444444444444444444444444444444444444444444")
 		notUsedTopic   = common.HexToHash("0x9999999999999999999999999999999999999999999999999999999999999999")
+		pending        = big.NewInt(rpc.PendingBlockNumber.Int64())
 
 		allLogs = []core.PendingLogsEvent{
 			{Logs: []*types.Log{{Address: firstAddr, Topics: []common.Hash{}, BlockNumber: 0}}},
@@ -492,23 +892,22 @@ func TestPendingLogsSubscription(t *testing.T) {
 			expected []*types.Log
 			c        chan []*types.Log
 			sub      *Subscription
+			err      chan error
 		}{
 			// match all
-			{dacchain.FilterQuery{}, convertLogs(allLogs), nil, nil},
+			{dacchain.FilterQuery{FromBlock: pending, ToBlock: pending}, convertLogs(allLogs), nil, nil, nil},
 			// match none due to no matching addresses
-			{dacchain.FilterQuery{Addresses: []common.Address{{}, notUsedAddress}, Topics: [][]common.Hash{nil}}, []*types.Log{}, nil, nil},
+			{dacchain.FilterQuery{Addresses: []common.Address{{}, notUsedAddress}, Topics: [][]common.Hash{nil}, FromBlock: pending, ToBlock: pending}, []*types.Log{}, nil, nil, nil},
 			// match logs based on addresses, ignore topics
-			{dacchain.FilterQuery{Addresses: []common.Address{firstAddr}}, append(convertLogs(allLogs[:2]), allLogs[5].Logs[3]), nil, nil},
+			{dacchain.FilterQuery{Addresses: []common.Address{firstAddr}, FromBlock: pending, ToBlock: pending}, append(convertLogs(allLogs[:2]), allLogs[5].Logs[3]), nil, nil, nil},
 			// match none due to no matching topics (match with address)
-			{dacchain.FilterQuery{Addresses: []common.Address{secondAddr}, Topics: [][]common.Hash{{notUsedTopic}}}, []*types.Log{}, nil, nil},
+			{dacchain.FilterQuery{Addresses: []common.Address{secondAddr}, Topics: [][]common.Hash{{notUsedTopic}}, FromBlock: pending, ToBlock: pending}, []*types.Log{}, nil, nil, nil},
 			// match logs based on addresses and topics
-			{dacchain.FilterQuery{Addresses: []common.Address{thirdAddress}, Topics: [][]common.Hash{{firstTopic, secondTopic}}}, append(convertLogs(allLogs[3:5]), allLogs[5].Logs[0]), nil, nil},
+			{dacchain.FilterQuery{Addresses: []common.Address{thirdAddress}, Topics: [][]common.Hash{{firstTopic, secondTopic}}, FromBlock: pending, ToBlock: pending}, append(convertLogs(allLogs[3:5]), allLogs[5].Logs[0]), nil, nil, nil},
 			// match logs based on multiple addresses and "or" topics
-			{dacchain.FilterQuery{Addresses: []common.Address{secondAddr, thirdAddress}, Topics: [][]common.Hash{{firstTopic, secondTopic}}}, append(convertLogs(allLogs[2:5]), allLogs[5].Logs[0]), nil, nil},
-			// block numbers are ignored for filters created with New***Filter, these return all logs that match the given criteria when the state changes
-			{dacchain.FilterQuery{Addresses: []common.Address{firstAddr}, FromBlock: big.NewInt(2), ToBlock: big.NewInt(3)}, append(convertLogs(allLogs[:2]), allLogs[5].Logs[3]), nil, nil},
+			{dacchain.FilterQuery{Addresses: []common.Address{secondAddr, thirdAddress}, Topics: [][]common.Hash{{firstTopic, secondTopic}}, FromBlock: pending, ToBlock: pending}, append(convertLogs(allLogs[2:5]), allLogs[5].Logs[0]), nil, nil, nil},
 			// multiple pending logs, should match only 2 topics from the logs in block 5
-			{dacchain.FilterQuery{Addresses: []common.Address{thirdAddress}, Topics: [][]common.Hash{{firstTopic, fourthTopic}}}, []*types.Log{allLogs[5].Logs[0], allLogs[5].Logs[2]}, nil, nil},
+			{dacchain.FilterQuery{Addresses: []common.Address{thirdAddress}, Topics: [][]common.Hash{{firstTopic, fourthTopic}}, FromBlock: pending, ToBlock: pending}, []*types.Log{allLogs[5].Logs[0], allLogs[5].Logs[2]}, nil, nil, nil},
 		}
 	)
 
@@ -517,6 +916,7 @@ func TestPendingLogsSubscription(t *testing.T) {
 	// (some) events are posted.
 	for i := range testCases {
 		testCases[i].c = make(chan []*types.Log)
+		testCases[i].err = make(chan error, 1)
 		testCases[i].sub, _ = api.events.SubscribeLogs(testCases[i].crit, testCases[i].c)
 	}
 
@@ -525,27 +925,26 @@ func TestPendingLogsSubscription(t *testing.T) {
 		tt := test
 		go func() {
 			var fetched []*types.Log
-		fetchLoop:
-			for {
-				logs := <-tt.c
-				fetched = append(fetched, logs...)
-				if len(fetched) >= len(tt.expected) {
-					break fetchLoop
-				}
+			for len(fetched) < len(tt.expected) {
+				fetched = append(fetched, <-tt.c...)
 			}
 
 			if len(fetched) != len(tt.expected) {
-				panic(fmt.Sprintf("invalid number of logs for case %d, want %d log(s), got %d", i, len(tt.expected), len(fetched)))
+				tt.err <- fmt.Errorf("invalid number of logs for case %d, want %d log(s), got %d", i, len(tt.expected), len(fetched))
+				return
 			}
 
 			for l := range fetched {
 				if fetched[l].Removed {
-					panic(fmt.Sprintf("expected log not to be removed for log %d in case %d", l, i))
+					tt.err <- fmt.Errorf("expected log not to be removed for log %d in case %d", l, i)
+					return
 				}
 				if !reflect.DeepEqual(fetched[l], tt.expected[l]) {
-					panic(fmt.Sprintf("invalid log on index %d for case %d", l, i))
+					tt.err <- fmt.Errorf("invalid log on index %d for case %d", l, i)
+					return
 				}
 			}
+			tt.err <- nil
 		}()
 	}
 
@@ -553,8 +952,18 @@ func TestPendingLogsSubscription(t *testing.T) {
 	time.Sleep(1 * time.Second)
 	// allLogs are type of core.PendingLogsEvent
 	for _, l := range allLogs {
-		if err := mux.Post(l); err != nil {
-			t.Fatal(err)
+		backend.pendFeed.Send(l)
+	}
+	// wait for the subscriptions to check their logs
+	for i := range testCases {
+		select {
+		case err := <-testCases[i].err:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for the pending logs of case %d", i)
 		}
+		testCases[i].sub.Unsubscribe()
 	}
 }
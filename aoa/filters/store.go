@@ -0,0 +1,266 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/rpc"
+)
+
+// maxStoredFilters is the maximum number of log filters persisted at once, any
+// further ones are only kept in memory.
+const maxStoredFilters = 256
+
+var (
+	filterIndexKey = []byte("FilterIndex") // ids of the persisted log filters
+	filterPrefix   = []byte("filter-")     // filterPrefix + id -> persisted log filter
+)
+
+// filterKey = filterPrefix + id
+func filterKey(id rpc.ID) []byte {
+	return append(append([]byte{}, filterPrefix...), id...)
+}
+
+// storedFilter is the persisted form of a log filter, allowing it to be installed
+// again under the same id after a restart.
+type storedFilter struct {
+	FromBlock *int64           `json:"fromBlock,omitempty"`
+	ToBlock   *int64           `json:"toBlock,omitempty"`
+	Addresses []common.Address `json:"address,omitempty"`
+	Topics    [][]common.Hash  `json:"topics,omitempty"`
+	Head      uint64           `json:"head"`     // Last block whose logs were handed out
+	Deadline  time.Time        `json:"deadline"` // Time the filter times out unless polled
+}
+
+// criteria returns the filter criteria of a persisted filter.
+func (s *storedFilter) criteria() FilterCriteria {
+	crit := FilterCriteria{Addresses: s.Addresses, Topics: s.Topics}
+	if s.FromBlock != nil {
+		crit.FromBlock = big.NewInt(*s.FromBlock)
+	}
+	if s.ToBlock != nil {
+		crit.ToBlock = big.NewInt(*s.ToBlock)
+	}
+	return crit
+}
+
+// headNumber returns the number of the current head block, zero if unknown.
+func (api *PublicFilterAPI) headNumber() uint64 {
+	header, _ := api.backend.HeaderByNumber(context.Background(), rpc.LatestBlockNumber)
+	if header == nil {
+		return 0
+	}
+	return header.Number.Uint64()
+}
+
+// storedFilterIDs retrieves the ids of the persisted log filters.
+func (api *PublicFilterAPI) storedFilterIDs() []rpc.ID {
+	data, _ := api.chainDb.Get(filterIndexKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var ids []rpc.ID
+	if err := json.Unmarshal(data, &ids); err != nil {
+		log.Error("Invalid filter index JSON", "err", err)
+		return nil
+	}
+	return ids
+}
+
+// writeStoredFilterIDs stores the ids of the persisted log filters.
+func writeStoredFilterIDs(db aoadb.Putter, ids []rpc.ID) error {
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	return db.Put(filterIndexKey, data)
+}
+
+// storeFilter schedules a log filter to be persisted, recording the block up to
+// which its logs were handed out. Beyond maxStoredFilters, new filters are only
+// kept in memory. The caller must hold filtersMu.
+func (api *PublicFilterAPI) storeFilter(id rpc.ID, crit FilterCriteria, head uint64) {
+	if _, ok := api.stored[id]; !ok && len(api.stored) >= maxStoredFilters {
+		return
+	}
+	stored := &storedFilter{
+		Addresses: crit.Addresses,
+		Topics:    crit.Topics,
+		Head:      head,
+		Deadline:  time.Now().Add(deadline),
+	}
+	if crit.FromBlock != nil {
+		from := crit.FromBlock.Int64()
+		stored.FromBlock = &from
+	}
+	if crit.ToBlock != nil {
+		to := crit.ToBlock.Int64()
+		stored.ToBlock = &to
+	}
+	api.stored[id] = struct{}{}
+	api.dirty[id] = stored
+	api.schedulePersist()
+}
+
+// deleteStoredFilter schedules a persisted log filter to be removed. The caller
+// must hold filtersMu.
+func (api *PublicFilterAPI) deleteStoredFilter(id rpc.ID) {
+	if _, ok := api.stored[id]; !ok {
+		return
+	}
+	delete(api.stored, id)
+	api.dirty[id] = nil
+	api.schedulePersist()
+}
+
+// schedulePersist wakes the persistence loop up, unless it's already pending.
+func (api *PublicFilterAPI) schedulePersist() {
+	select {
+	case api.persist <- struct{}{}:
+	default:
+	}
+}
+
+// persistLoop writes the filter changes into the database in the background,
+// keeping the database writes out of the RPC handlers.
+func (api *PublicFilterAPI) persistLoop() {
+	for range api.persist {
+		api.persistFilters()
+	}
+}
+
+// persistFilters writes the pending filter changes together with the filter
+// index into the database in a single batch.
+func (api *PublicFilterAPI) persistFilters() {
+	api.persistMu.Lock()
+	defer api.persistMu.Unlock()
+
+	api.filtersMu.Lock()
+	dirty := api.dirty
+	api.dirty = make(map[rpc.ID]*storedFilter)
+	ids := make([]rpc.ID, 0, len(api.stored))
+	for id := range api.stored {
+		ids = append(ids, id)
+	}
+	api.filtersMu.Unlock()
+
+	if len(dirty) == 0 {
+		return
+	}
+	batch := api.chainDb.NewBatch()
+	for id, stored := range dirty {
+		if stored == nil {
+			batch.Delete(filterKey(id))
+			continue
+		}
+		data, err := json.Marshal(stored)
+		if err != nil {
+			log.Warn("Failed to encode log filter", "id", id, "err", err)
+			continue
+		}
+		batch.Put(filterKey(id), data)
+	}
+	err := writeStoredFilterIDs(batch, ids)
+	if err == nil {
+		err = batch.Write()
+	}
+	if err != nil {
+		log.Warn("Failed to store log filters", "err", err)
+	}
+}
+
+// restoreFilters installs the persisted log filters again under their original
+// ids, dropping the ones which timed out meanwhile. Logs emitted while the node
+// was down are retrieved in the background and handed out on the next poll.
+//
+// It must be called before the API is served, as the filter index is updated
+// without holding filtersMu.
+func (api *PublicFilterAPI) restoreFilters() {
+	var (
+		head     = api.headNumber()
+		restored []rpc.ID
+	)
+	for _, id := range api.storedFilterIDs() {
+		if len(restored) >= maxStoredFilters {
+			api.chainDb.Delete(filterKey(id))
+			continue
+		}
+		data, _ := api.chainDb.Get(filterKey(id))
+
+		stored := new(storedFilter)
+		if err := json.Unmarshal(data, stored); err != nil || time.Now().After(stored.Deadline) {
+			api.chainDb.Delete(filterKey(id))
+			continue
+		}
+		crit := stored.criteria()
+		if _, err := api.installLogFilter(id, crit, time.Until(stored.Deadline)); err != nil {
+			log.Warn("Failed to restore log filter", "id", id, "err", err)
+			api.chainDb.Delete(filterKey(id))
+			continue
+		}
+		restored = append(restored, id)
+		api.stored[id] = struct{}{}
+
+		// Retrieve the logs of the blocks imported since the filter was last polled,
+		// unless it's only interested in pending logs
+		if crit.FromBlock != nil && crit.FromBlock.Int64() == rpc.PendingBlockNumber.Int64() {
+			continue
+		}
+		from, to := int64(stored.Head)+1, int64(head)
+		if crit.FromBlock != nil && crit.FromBlock.Int64() > from {
+			from = crit.FromBlock.Int64()
+		}
+		if crit.ToBlock != nil && crit.ToBlock.Int64() >= 0 && crit.ToBlock.Int64() < to {
+			to = crit.ToBlock.Int64()
+		}
+		if from <= to {
+			go api.backfillFilter(id, New(api.backend, from, to, crit.Addresses, crit.Topics))
+		}
+	}
+	if err := writeStoredFilterIDs(api.chainDb, restored); err != nil {
+		log.Warn("Failed to store filter index", "err", err)
+	}
+	if len(restored) > 0 {
+		log.Info("Restored persisted log filters", "count", len(restored))
+	}
+}
+
+// backfillFilter runs a historical filter, handing its logs out before the ones
+// delivered since the filter was restored.
+func (api *PublicFilterAPI) backfillFilter(id rpc.ID, filter *Filter) {
+	logs, err := filter.Logs(context.Background())
+	if err != nil {
+		log.Warn("Failed to retrieve missed filter logs", "id", id, "err", err)
+	}
+	if len(logs) == 0 {
+		return
+	}
+	api.filtersMu.Lock()
+	defer api.filtersMu.Unlock()
+
+	if f, found := api.filters[id]; found {
+		f.logs = append(append([]*types.Log{}, logs...), f.logs...)
+	}
+}
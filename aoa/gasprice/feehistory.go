@@ -0,0 +1,141 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/rpc"
+)
+
+// maxFeeHistory is the maximum number of blocks a fee history is reported for.
+const maxFeeHistory = 1024
+
+var errInvalidPercentile = errors.New("invalid reward percentile")
+
+// txGasAndPrice is the gas used by a transaction and the price paid for it.
+type txGasAndPrice struct {
+	gasUsed uint64
+	price   *big.Int
+}
+
+// blockRewards returns the gas prices paid in a block at the given percentiles
+// of its gas used, i.e. weighting each transaction price by the gas it used.
+// Empty blocks report zero prices.
+func blockRewards(block *types.Block, receipts types.Receipts, percentiles []float64) ([]*big.Int, error) {
+	rewards := make([]*big.Int, len(percentiles))
+
+	txs := block.Transactions()
+	if len(txs) == 0 {
+		for i := range rewards {
+			rewards[i] = new(big.Int)
+		}
+		return rewards, nil
+	}
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("receipt count mismatch in block #%d: have %d, want %d", block.NumberU64(), len(receipts), len(txs))
+	}
+	sorted := make([]txGasAndPrice, len(txs))
+	for i, tx := range txs {
+		sorted[i] = txGasAndPrice{gasUsed: receipts[i].GasUsed, price: tx.GasPrice()}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].price.Cmp(sorted[j].price) < 0 })
+
+	var (
+		index   = 0
+		sumUsed = sorted[0].gasUsed
+	)
+	for i, percentile := range percentiles {
+		threshold := uint64(float64(block.GasUsed()) * percentile / 100)
+		for sumUsed < threshold && index < len(sorted)-1 {
+			index++
+			sumUsed += sorted[index].gasUsed
+		}
+		rewards[i] = new(big.Int).Set(sorted[index].price)
+	}
+	return rewards, nil
+}
+
+// FeeHistory returns the history of the gas prices paid in a range of blocks up
+// to and including lastBlock: the number of the oldest block reported, the gas
+// prices paid in each block at the requested percentiles of its gas used, and
+// the ratios of the gas limits of the blocks used. The range is capped to 1024
+// blocks, the pending block is not reported.
+func (gpo *Oracle) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	for i, percentile := range rewardPercentiles {
+		if percentile < 0 || percentile > 100 || (i > 0 && percentile < rewardPercentiles[i-1]) {
+			return nil, nil, nil, fmt.Errorf("%v: %f", errInvalidPercentile, percentile)
+		}
+	}
+	if blocks < 1 {
+		return new(big.Int), nil, nil, nil
+	}
+	if blocks > maxFeeHistory {
+		blocks = maxFeeHistory
+	}
+	if lastBlock == rpc.PendingBlockNumber {
+		lastBlock = rpc.LatestBlockNumber
+	}
+	head, err := gpo.backend.HeaderByNumber(ctx, lastBlock)
+	if head == nil {
+		if err == nil {
+			err = fmt.Errorf("block #%d not found", lastBlock)
+		}
+		return nil, nil, nil, err
+	}
+	last := head.Number.Uint64()
+	if uint64(blocks) > last+1 {
+		blocks = int(last + 1)
+	}
+	oldest := last + 1 - uint64(blocks)
+
+	var (
+		rewards      [][]*big.Int
+		gasUsedRatio = make([]float64, blocks)
+	)
+	if len(rewardPercentiles) > 0 {
+		rewards = make([][]*big.Int, blocks)
+	}
+	for i := 0; i < blocks; i++ {
+		block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(oldest+uint64(i)))
+		if block == nil {
+			if err == nil {
+				err = fmt.Errorf("block #%d not found", oldest+uint64(i))
+			}
+			return nil, nil, nil, err
+		}
+		if block.GasLimit() > 0 {
+			gasUsedRatio[i] = float64(block.GasUsed()) / float64(block.GasLimit())
+		}
+		if rewards == nil {
+			continue
+		}
+		receipts, err := gpo.backend.GetReceipts(ctx, block.Hash())
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if rewards[i], err = blockRewards(block, receipts, rewardPercentiles); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return new(big.Int).SetUint64(oldest), rewards, gasUsedRatio, nil
+}
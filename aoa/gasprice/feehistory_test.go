@@ -0,0 +1,61 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+)
+
+// Tests that the rewards of a block are the gas prices paid at the percentiles
+// of its gas used, weighted by the gas used by each transaction.
+func TestBlockRewards(t *testing.T) {
+	var (
+		txs      []*types.Transaction
+		receipts types.Receipts
+		gasUsed  uint64
+	)
+	// Transactions paying 3, 1 and 2 wei, using 100000, 100000 and 200000 gas
+	for i, tx := range []struct{ price, gas uint64 }{{3, 100000}, {1, 100000}, {2, 200000}} {
+		txs = append(txs, types.NewTransaction(uint64(i), common.Address{}, new(big.Int), tx.gas, new(big.Int).SetUint64(tx.price), nil, types.ActionTrans, nil, ""))
+		receipts = append(receipts, &types.Receipt{GasUsed: tx.gas})
+		gasUsed += tx.gas
+	}
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1), GasUsed: gasUsed}, txs, nil)
+
+	rewards, err := blockRewards(block, receipts, []float64{0, 20, 25, 26, 60, 75, 76, 100})
+	if err != nil {
+		t.Fatalf("failed to compute rewards: %v", err)
+	}
+	want := []int64{1, 1, 1, 2, 2, 2, 3, 3}
+	for i, reward := range rewards {
+		if reward.Int64() != want[i] {
+			t.Errorf("reward %d mismatch: have %v, want %d", i, reward, want[i])
+		}
+	}
+	// Empty blocks report zero prices, mismatching receipts are rejected
+	empty := types.NewBlock(&types.Header{Number: big.NewInt(2)}, nil, nil)
+	if rewards, err := blockRewards(empty, nil, []float64{50}); err != nil || len(rewards) != 1 || rewards[0].Sign() != 0 {
+		t.Errorf("empty block rewards mismatch: have %v, %v, want [0]", rewards, err)
+	}
+	if _, err := blockRewards(block, receipts[:2], []float64{50}); err == nil {
+		t.Errorf("mismatching receipts accepted")
+	}
+}
@@ -4,6 +4,7 @@ package aoa
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/Aurorachain-io/go-aoa/common"
 	"github.com/Aurorachain-io/go-aoa/common/hexutil"
@@ -25,14 +26,32 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		SkipBcVersionCheck      bool `toml:"-"`
 		DatabaseHandles         int  `toml:"-"`
 		DatabaseCache           int
+		TxLookupLimit           uint64 `toml:",omitempty"`
+		HistoryLimit            uint64 `toml:",omitempty"`
+		TrieCleanCache          int
+		TrieDirtyCache          int
+		NoPruning               bool
+		TrieCommitInterval      uint64
+		Indexes                 []string       `toml:",omitempty"`
+		IndexThrottling         time.Duration  `toml:",omitempty"`
+		ProcessorWorkers        int            `toml:",omitempty"`
+		StateStats              bool           `toml:",omitempty"`
+		TraceCache              int            `toml:",omitempty"`
+		TraceCacheDisk          int            `toml:",omitempty"`
+		RPCGasCap               uint64         `toml:",omitempty"`
+		RPCEVMTimeout           time.Duration  `toml:",omitempty"`
+		EthCompat               bool           `toml:",omitempty"`
 		Etherbase               common.Address `toml:",omitempty"`
 		MinerThreads            int            `toml:",omitempty"`
 		ExtraData               hexutil.Bytes  `toml:",omitempty"`
 		GasPrice                *big.Int
+		ProduceLeadTime         time.Duration `toml:",omitempty"`
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
+		Propagation             PropagationConfig
 		EnablePreimageRecording bool
 		DocRoot                 string `toml:"-"`
+		EnableInterTxWatching   bool
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
@@ -43,14 +62,32 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
+	enc.TxLookupLimit = c.TxLookupLimit
+	enc.HistoryLimit = c.HistoryLimit
+	enc.TrieCleanCache = c.TrieCleanCache
+	enc.TrieDirtyCache = c.TrieDirtyCache
+	enc.NoPruning = c.NoPruning
+	enc.TrieCommitInterval = c.TrieCommitInterval
+	enc.Indexes = c.Indexes
+	enc.IndexThrottling = c.IndexThrottling
+	enc.ProcessorWorkers = c.ProcessorWorkers
+	enc.StateStats = c.StateStats
+	enc.TraceCache = c.TraceCache
+	enc.TraceCacheDisk = c.TraceCacheDisk
+	enc.RPCGasCap = c.RPCGasCap
+	enc.RPCEVMTimeout = c.RPCEVMTimeout
+	enc.EthCompat = c.EthCompat
 	enc.Etherbase = c.Dacchainbase
 	enc.MinerThreads = c.MinerThreads
 	enc.ExtraData = c.ExtraData
 	enc.GasPrice = c.GasPrice
+	enc.ProduceLeadTime = c.ProduceLeadTime
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
+	enc.Propagation = c.Propagation
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
 	enc.DocRoot = c.DocRoot
+	enc.EnableInterTxWatching = c.EnableInterTxWatching
 	return &enc, nil
 }
 
@@ -65,14 +102,32 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		SkipBcVersionCheck      *bool `toml:"-"`
 		DatabaseHandles         *int  `toml:"-"`
 		DatabaseCache           *int
+		TxLookupLimit           *uint64 `toml:",omitempty"`
+		HistoryLimit            *uint64 `toml:",omitempty"`
+		TrieCleanCache          *int
+		TrieDirtyCache          *int
+		NoPruning               *bool
+		TrieCommitInterval      *uint64
+		Indexes                 []string        `toml:",omitempty"`
+		IndexThrottling         *time.Duration  `toml:",omitempty"`
+		ProcessorWorkers        *int            `toml:",omitempty"`
+		StateStats              *bool           `toml:",omitempty"`
+		TraceCache              *int            `toml:",omitempty"`
+		TraceCacheDisk          *int            `toml:",omitempty"`
+		RPCGasCap               *uint64         `toml:",omitempty"`
+		RPCEVMTimeout           *time.Duration  `toml:",omitempty"`
+		EthCompat               *bool           `toml:",omitempty"`
 		Etherbase               *common.Address `toml:",omitempty"`
 		MinerThreads            *int            `toml:",omitempty"`
 		ExtraData               *hexutil.Bytes  `toml:",omitempty"`
 		GasPrice                *big.Int
+		ProduceLeadTime         *time.Duration `toml:",omitempty"`
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
+		Propagation             *PropagationConfig
 		EnablePreimageRecording *bool
 		DocRoot                 *string `toml:"-"`
+		EnableInterTxWatching   *bool
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -102,6 +157,51 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseCache != nil {
 		c.DatabaseCache = *dec.DatabaseCache
 	}
+	if dec.TxLookupLimit != nil {
+		c.TxLookupLimit = *dec.TxLookupLimit
+	}
+	if dec.HistoryLimit != nil {
+		c.HistoryLimit = *dec.HistoryLimit
+	}
+	if dec.TrieCleanCache != nil {
+		c.TrieCleanCache = *dec.TrieCleanCache
+	}
+	if dec.TrieDirtyCache != nil {
+		c.TrieDirtyCache = *dec.TrieDirtyCache
+	}
+	if dec.NoPruning != nil {
+		c.NoPruning = *dec.NoPruning
+	}
+	if dec.TrieCommitInterval != nil {
+		c.TrieCommitInterval = *dec.TrieCommitInterval
+	}
+	if dec.Indexes != nil {
+		c.Indexes = dec.Indexes
+	}
+	if dec.IndexThrottling != nil {
+		c.IndexThrottling = *dec.IndexThrottling
+	}
+	if dec.ProcessorWorkers != nil {
+		c.ProcessorWorkers = *dec.ProcessorWorkers
+	}
+	if dec.StateStats != nil {
+		c.StateStats = *dec.StateStats
+	}
+	if dec.TraceCache != nil {
+		c.TraceCache = *dec.TraceCache
+	}
+	if dec.TraceCacheDisk != nil {
+		c.TraceCacheDisk = *dec.TraceCacheDisk
+	}
+	if dec.RPCGasCap != nil {
+		c.RPCGasCap = *dec.RPCGasCap
+	}
+	if dec.RPCEVMTimeout != nil {
+		c.RPCEVMTimeout = *dec.RPCEVMTimeout
+	}
+	if dec.EthCompat != nil {
+		c.EthCompat = *dec.EthCompat
+	}
 	if dec.Etherbase != nil {
 		c.Dacchainbase = *dec.Etherbase
 	}
@@ -114,17 +214,26 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.GasPrice != nil {
 		c.GasPrice = dec.GasPrice
 	}
+	if dec.ProduceLeadTime != nil {
+		c.ProduceLeadTime = *dec.ProduceLeadTime
+	}
 	if dec.TxPool != nil {
 		c.TxPool = *dec.TxPool
 	}
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
+	if dec.Propagation != nil {
+		c.Propagation = *dec.Propagation
+	}
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
+	if dec.EnableInterTxWatching != nil {
+		c.EnableInterTxWatching = *dec.EnableInterTxWatching
+	}
 	return nil
 }
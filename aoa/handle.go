@@ -23,6 +23,7 @@ import (
 	"github.com/Aurorachain-io/go-aoa/common"
 	"github.com/Aurorachain-io/go-aoa/consensus"
 	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/forkid"
 	"github.com/Aurorachain-io/go-aoa/core/types"
 	"github.com/Aurorachain-io/go-aoa/aoa/downloader"
 	"github.com/Aurorachain-io/go-aoa/aoa/fetcher"
@@ -31,6 +32,7 @@ import (
 	"github.com/Aurorachain-io/go-aoa/p2p"
 	"github.com/Aurorachain-io/go-aoa/p2p/discover"
 	"github.com/Aurorachain-io/go-aoa/params"
+	"math"
 	"math/big"
 	"sync"
 	"sync/atomic"
@@ -39,7 +41,6 @@ import (
 	"crypto/ecdsa"
 	aa "github.com/Aurorachain-io/go-aoa/accounts/walletType"
 	"github.com/Aurorachain-io/go-aoa/crypto"
-	"math"
 	"sort"
 	"strings"
 )
@@ -57,8 +58,42 @@ const (
 // not compatible (low protocol version restrictions and high requirements).
 var errIncompatibleConfig = errors.New("incompatible configuration")
 
+// protocolError is a violation of the protocol by a remote peer.
+type protocolError struct {
+	code errCode
+	msg  string
+}
+
+func (e *protocolError) Error() string {
+	return fmt.Sprintf("%v - %v", e.code, e.msg)
+}
+
+// incompatible returns whether a handshake failed because the remote node runs
+// another network, genesis or fork schedule, so reconnecting to it is useless.
+func incompatible(err error) bool {
+	if err, ok := err.(*protocolError); ok {
+		switch err.code {
+		case ErrNetworkIdMismatch, ErrGenesisBlockMismatch, ErrForkIDRejected:
+			return true
+		}
+	}
+	return false
+}
+
+// useless returns whether a message handling failed because the remote node sent
+// a malformed, oversized or unexpected message.
+func useless(err error) bool {
+	if err, ok := err.(*protocolError); ok {
+		switch err.code {
+		case ErrMsgTooLarge, ErrDecode, ErrInvalidMsgCode, ErrNoStatusMsg, ErrExtraStatusMsg:
+			return true
+		}
+	}
+	return false
+}
+
 func errResp(code errCode, format string, v ...interface{}) error {
-	return fmt.Errorf("%v - %v", code, fmt.Sprintf(format, v...))
+	return &protocolError{code: code, msg: fmt.Sprintf(format, v...)}
 }
 
 type ProtocolManager struct {
@@ -72,6 +107,7 @@ type ProtocolManager struct {
 	maxPeers      int
 	peers         *peerSet
 	delegatePeers *peerSet
+	forkFilter    forkid.Filter // Fork ID filter, constant across the lifetime of the node
 
 	SubProtocols []p2p.Protocol
 
@@ -93,14 +129,22 @@ type ProtocolManager struct {
 	taskManager               *DposTaskManager
 	blockChan                 chan *types.Block
 	lockBlockManager          *lockManager
-	engine                    consensus.Engine
+	engine                    consensus.Delegated
 	addDelegateWalletCallback func(data *aa.DelegateWalletInfo)
 	delegateWallets           map[string]*ecdsa.PrivateKey
+
+	propagation PropagationConfig // Block propagation strategy and parameters
+	txRequests  *txRequests       // Announced transactions being pulled from peers
+	evidences   *evidencePool     // Detector and store of delegates double signing
+
+	self          discover.NodeID      // Local node ID the local delegates vouch for
+	reservedSlots func() int           // Slots reserved for priority peers in addition to maxPeers, nil if none
+	delegateNodes chan discover.NodeID // Peers proven to run a registered delegate
 }
 
 // NewProtocolManager returns a new dacchain sub protocol manager. The dacchain sub protocol manages peers capable
 // with the dacchain network.
-func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, networkId uint64, txpool txPool, engine consensus.Engine, blockchain *core.BlockChain, chaindb aoadb.Database, taskManager *DposTaskManager, blockChan chan *types.Block, addDelegateWalletCallback func(data *aa.DelegateWalletInfo), delegateWallets map[string]*ecdsa.PrivateKey) (*ProtocolManager, error) {
+func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, networkId uint64, txpool txPool, engine consensus.Delegated, blockchain *core.BlockChain, chaindb aoadb.Database, taskManager *DposTaskManager, blockChan chan *types.Block, addDelegateWalletCallback func(data *aa.DelegateWalletInfo), delegateWallets map[string]*ecdsa.PrivateKey) (*ProtocolManager, error) {
 	// Create the protocol manager with the base fields
 
 	manager := &ProtocolManager{
@@ -111,6 +155,7 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		chainconfig:               config,
 		peers:                     newPeerSet(),
 		delegatePeers:             newPeerSet(),
+		forkFilter:                forkid.NewFilter(blockchain),
 		newPeerCh:                 make(chan *peer),
 		noMorePeers:               make(chan struct{}),
 		txsyncCh:                  make(chan *txsync),
@@ -120,6 +165,10 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		blockChan:                 blockChan,
 		addDelegateWalletCallback: addDelegateWalletCallback,
 		delegateWallets:           delegateWallets,
+		propagation:               DefaultPropagationConfig,
+		txRequests:                newTxRequests(),
+		evidences:                 newEvidencePool(chaindb),
+		delegateNodes:             make(chan discover.NodeID, delegateNodesSize),
 	}
 
 	// Figure out whether to allow fast sync or not
@@ -169,16 +218,16 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		return nil, errIncompatibleConfig
 	}
 	// Construct the different synchronisation mechanisms
-	manager.downloader = downloader.New(mode, chaindb, blockchain, nil, manager.removePeer)
+	manager.downloader = downloader.New(mode, chaindb, blockchain, nil, manager.penalizePeer)
 
 	//validator := func(block *types.Block) error {
 	//	return manager.blockchain.PreInsertChain(block)
 	//}
 	validator := func(block *types.Block) error {
-		err := manager.engine.VerifyHeaderAndSign(manager.blockchain, block, manager.taskManager.GetCurrentShuffleRound(), blockInterval)
+		err := manager.engine.VerifyHeaderAndSign(manager.blockchain, block, manager.taskManager.GetCurrentShuffleRound(), manager.taskManager.blockInterval)
 		if err != nil {
 			manager.shuffleIfVerify(block)
-			return manager.engine.VerifyHeaderAndSign(manager.blockchain, block, manager.taskManager.GetCurrentShuffleRound(), blockInterval)
+			return manager.engine.VerifyHeaderAndSign(manager.blockchain, block, manager.taskManager.GetCurrentShuffleRound(), manager.taskManager.blockInterval)
 		}
 		return nil
 	}
@@ -197,23 +246,35 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		return manager.blockchain.InsertChain(blocks)
 	}
 
-	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, insertBlockfunc, manager.removePeer)
-	manager.lockBlockManager = newBlockLockManager(insertBlockfunc, delegateWallets)
+	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, insertBlockfunc, func(id string) {
+		manager.penalizePeer(id, p2p.InvalidBlock)
+	})
+	_, blockInterval, delegateAmount := dposParams(config)
+	manager.lockBlockManager = newBlockLockManager(insertBlockfunc, delegateWallets, blockInterval, delegateAmount)
 	return manager, nil
 }
 
 // handle is the callback invoked to manage the life cycle of an em peer. When
 // this function terminates, the peer is disconnected.
 func (pm *ProtocolManager) handle(p *peer) error {
-	if pm.peers.Len() >= pm.maxPeers {
+	if !pm.hasSlot(p) {
 		return p2p.DiscTooManyPeers
 	}
 	p.Log().Debug("eminer-pro peer connected", "name", p.Name())
 
 	// Execute the Em handshake
 	td, head, genesis := pm.blockchain.Status()
-	if err := p.Handshake(pm.networkId, td, head, genesis); err != nil {
+	forkID := forkid.NewID(pm.blockchain.Config(), genesis, pm.blockchain.CurrentHeader().Number.Uint64())
+	if err := p.Handshake(pm.networkId, td, head, genesis, core.GetHistoryTail(pm.chaindb), forkID, pm.forkFilter); err != nil {
 		p.Log().Debug("eminer-pro handshake failed", "err", err)
+		switch {
+		case err == p2p.DiscReadTimeout:
+			p.Penalize(p2p.RequestTimeout)
+		case incompatible(err):
+			// Ban nodes of other networks right away, so they don't keep
+			// occupying peer slots by reconnecting
+			p.Penalize(p2p.IncompatiblePeer)
+		}
 		return err
 	}
 	if rw, ok := p.rw.(*meteredMsgReadWriter); ok {
@@ -244,10 +305,22 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	// after this will be sent via broadcasts.
 	pm.syncTransactions(p)
 
+	// Vouch for the local node with the local delegates
+	if p.version >= aoa07 {
+		if proofs := pm.localDelegateProofs(); len(proofs) > 0 {
+			if err := p.SendDelegateProofs(proofs); err != nil {
+				return err
+			}
+		}
+	}
+
 	// main loop. handle incoming messages.
 	for {
 		if err := pm.handleMsg(p); err != nil {
 			p.Log().Debug("eminer-pro message handling failed", "err", err)
+			if useless(err) {
+				p.Penalize(p2p.UselessMessage)
+			}
 			return err
 		}
 	}
@@ -292,6 +365,16 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		return pm.dealNewBlockMsg(msg, p)
 	case msg.Code == TxMsg:
 		return pm.dealTxMsg(msg, p)
+	case p.version >= aoa04 && msg.Code == NewPooledTxHashesMsg:
+		return pm.dealNewPooledTxHashesMsg(msg, p)
+	case p.version >= aoa04 && msg.Code == GetPooledTxsMsg:
+		return pm.dealGetPooledTxsMsg(msg, p)
+	case p.version >= aoa04 && msg.Code == PooledTxsMsg:
+		return pm.dealPooledTxsMsg(msg, p)
+	case p.version >= aoa05 && msg.Code == DoubleSignMsg:
+		return pm.dealDoubleSignMsg(msg, p)
+	case p.version >= aoa07 && msg.Code == DelegateProofMsg:
+		return pm.dealDelegateProofMsg(msg, p)
 	case msg.Code == PreBlockMsg:
 		return pm.dealPreBlockMsg(msg, p)
 	case msg.Code == SignaturesBlockMsg:
@@ -332,6 +415,15 @@ func (pm *ProtocolManager) removePeer(id string) {
 	}
 }
 
+// penalizePeer reports a misbehaviour of a peer to the networking layer, which
+// bans it if it misbehaved too much, and drops the peer.
+func (pm *ProtocolManager) penalizePeer(id string, kind p2p.Misbehaviour) {
+	if peer := pm.peers.Peer(id); peer != nil {
+		peer.Penalize(kind)
+	}
+	pm.removePeer(id)
+}
+
 //
 func (pm *ProtocolManager) localProduceBlockLoop() {
 
@@ -449,39 +541,12 @@ func (pm *ProtocolManager) signsToPendingBlock(block *types.Block, action uint64
 	go pm.BroadcastBlockSignatures(block.Hash().Bytes(), signVotes)
 }
 
-// get all top net delegates who not know block hash , get  some common net
-func (pm *ProtocolManager) getTopPeersFromCommonPeers(hash common.Hash) []*peer {
-	ps := pm.peers
-	delegatePeers := pm.delegatePeers
-	ps.lock.RLock()
-	defer ps.lock.RUnlock()
-	delegatePeers.lock.RLock()
-	defer delegatePeers.lock.RUnlock()
-
-	delegateList := make([]*peer, 0, len(delegatePeers.peers))
-	for _, p := range delegatePeers.peers {
-		if !p.knownBlocks.Has(hash) {
-			delegateList = append(delegateList, p)
-		}
-	}
-
-	list := make([]*peer, 0, len(ps.peers))
-	for _, p := range ps.peers {
-		if !p.knownBlocks.Has(hash) && delegatePeers.peers[p.id] == nil {
-			list = append(list, p)
-		}
-	}
-	transfer := list[:int(math.Sqrt(float64(len(list))))]
-	delegateList = append(delegateList, transfer...)
-	return delegateList
-}
-
-// BroadcastBlock will either propagate a block to a subset of it's peers, or
-// will only announce it's availability (depending what's requested).
+// BroadcastBlock will either propagate a block to the peers picked by the block
+// propagation strategy, or will only announce its availability to them
+// (depending what's requested).
 func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 	hash := block.Hash()
-	// peers := pm.peers.PeersWithoutBlock(hash)
-	peers := pm.getTopPeersFromCommonPeers(hash)
+	peers := pm.blockPeers(hash, propagate)
 	// Add log
 	log.Info("broadcastNewBlockMsg start", "blockNumber", block.NumberU64(), "unknownPeersNumber：", len(peers))
 
@@ -495,8 +560,7 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 			log.Error("Propagating dangling block", "number", block.Number(), "hash", hash)
 			return
 		}
-		// Send the block to a subset of our peers
-		// transfer := peers[:int(math.Sqrt(float64(len(peers))))]
+		// Send the block to the peers picked by the propagation strategy
 		for _, peer := range peers {
 			peer.SendNewBlock(block, td)
 		}
@@ -514,12 +578,12 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 	log.Debug("broadcastNewBlockMsg end", "blockInfo:", block.NumberU64())
 }
 
-// broadcast to agent p2p network
+// PreBroadcastBlock pushes a newly produced block to the delegate peers picked
+// by the propagation strategy, which relay it further after verifying it.
 func (pm *ProtocolManager) PreBroadcastBlock(block *types.Block) {
 	hash := block.Hash()
-	peers := pm.delegatePeers.PeersWithoutPreBlock(hash)
-	// transfer := peers[:int(math.Sqrt(float64(len(peers))))]
-	log.Info("PreBroadcastBlock|start", "blockNumber", block.NumberU64(), "blockHash", block.Hash().Hex(), "unKnownPeer", len(peers), "topPeerCount", len(pm.delegatePeers.peers))
+	peers := pm.propagation.selectPreBlockPeers(pm.delegatePeers.PeersWithoutPreBlock(hash))
+	log.Info("PreBroadcastBlock|start", "blockNumber", block.NumberU64(), "blockHash", block.Hash().Hex(), "recipients", len(peers), "topPeerCount", len(pm.delegatePeers.peers))
 	for _, peer := range peers {
 		peer.SendNewPreBlock(block)
 	}
@@ -547,6 +611,24 @@ func (pm *ProtocolManager) BroadcastCommitBlock(block types.CommitBlock) {
 	}
 }
 
+// hasSlot checks whether a peer slot is free for the peer. Priority peers occupy
+// the reserved slots first, overflowing into the regular ones.
+func (pm *ProtocolManager) hasSlot(p *peer) bool {
+	reserved := 0
+	if pm.reservedSlots != nil {
+		reserved = pm.reservedSlots()
+	}
+	prio := pm.peers.PriorityLen()
+	regular := pm.peers.Len() - prio
+	if prio > reserved {
+		regular += prio - reserved
+	}
+	if p.Priority() {
+		return prio < reserved || regular < pm.maxPeers
+	}
+	return regular < pm.maxPeers
+}
+
 func (pm *ProtocolManager) Start(maxPeers int) {
 	pm.maxPeers = maxPeers
 
@@ -602,15 +684,23 @@ func (pm *ProtocolManager) txBroadcastLoop() {
 }
 
 // BroadcastTx will propagate a transaction to all peers which are not known to
-// already have the given transaction.
+// already have the given transaction. The transaction itself is only sent to a
+// square root of them, the others are just announced its hash to pull it on
+// demand, unless they don't speak a protocol version supporting it.
 func (pm *ProtocolManager) BroadcastTx(hash common.Hash, tx *types.Transaction) {
-	// Broadcast transaction to a batch of peers not knowing about it
 	peers := pm.peers.PeersWithoutTx(hash)
-	//FIXME include this again: peers = peers[:int(math.Sqrt(float64(len(peers))))]
-	for _, peer := range peers {
-		peer.SendTransactions(types.Transactions{tx})
+	push := int(math.Sqrt(float64(len(peers))))
+
+	var announced int
+	for i, peer := range peers {
+		if i < push || peer.version < aoa04 {
+			peer.SendTransactions(types.Transactions{tx})
+		} else {
+			peer.SendPooledTransactionHashes([]common.Hash{hash})
+			announced++
+		}
 	}
-	log.Trace("Broadcast transaction", "hash", hash, "recipients", len(peers))
+	log.Trace("Broadcast transaction", "hash", hash, "recipients", len(peers)-announced, "announced", announced)
 }
 
 func (pm *ProtocolManager) GetAddDelegateWalletCallback() func(data *aa.DelegateWalletInfo) {
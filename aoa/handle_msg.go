@@ -46,6 +46,9 @@ type broadcastMsgHandle interface {
 	dealReceiptsMsg(msg p2p.Msg, p *peer) error
 	dealNewBlockHashesMsg(msg p2p.Msg, p *peer) error
 	dealTxMsg(msg p2p.Msg, p *peer) error
+	dealNewPooledTxHashesMsg(msg p2p.Msg, p *peer) error
+	dealGetPooledTxsMsg(msg p2p.Msg, p *peer) error
+	dealPooledTxsMsg(msg p2p.Msg, p *peer) error
 
 	dealNewBlockMsg(msg p2p.Msg, p *peer) error
 	dealPreBlockMsg(msg p2p.Msg, p *peer) error
@@ -131,6 +134,7 @@ func (pm *ProtocolManager) dealGetBlockHeadersMsg(msg p2p.Msg, p *peer) error {
 			query.Origin.Number += query.Skip + 1
 		}
 	}
+	reqHeaderOutBatchHist.Update(int64(len(headers)))
 	return p.SendBlockHeaders(headers)
 }
 
@@ -204,6 +208,7 @@ func (pm *ProtocolManager) dealGetBlockBodiesMsg(msg p2p.Msg, p *peer) error {
 			bytes += len(data)
 		}
 	}
+	reqBodyOutBatchHist.Update(int64(len(bodies)))
 	return p.SendBlockBodiesRLP(bodies)
 }
 
@@ -253,8 +258,9 @@ func (pm *ProtocolManager) dealGetNodeDataMsg(msg p2p.Msg, p *peer) error {
 		} else if err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
-		// Retrieve the requested state entry, stopping if enough was found
-		if entry, err := pm.chaindb.Get(hash.Bytes()); err == nil {
+		// Retrieve the requested state entry, stopping if enough was found. Recent
+		// tries may only live in the node cache, which falls back to disk.
+		if entry, err := pm.blockchain.GetStateDB().TrieDB().Get(hash.Bytes()); err == nil {
 			data = append(data, entry)
 			bytes += len(entry)
 		}
@@ -358,6 +364,7 @@ func (pm *ProtocolManager) dealNewBlockMsg(msg p2p.Msg, p *peer) error {
 	request.Block.ReceivedAt = msg.ReceivedAt
 	request.Block.ReceivedFrom = p
 	block := request.Block
+	pm.observeBlock(block)
 	rlpEncodeSigns := request.RlpEncodeSigns
 	block.RlpEncodeSigns = rlpEncodeSigns
 	currentBlock := pm.blockchain.CurrentBlock()
@@ -373,11 +380,11 @@ func (pm *ProtocolManager) dealNewBlockMsg(msg p2p.Msg, p *peer) error {
 		return nil
 	}
 
-	err := pm.engine.VerifyBlockGenerate(pm.blockchain, block, pm.taskManager.GetCurrentShuffleRound(), blockInterval)
+	err := pm.engine.VerifyBlockGenerate(pm.blockchain, block, pm.taskManager.GetCurrentShuffleRound(), pm.taskManager.blockInterval)
 	if err != nil {
 		log.Error("New block Msg|verify block fail", "blockNumber", block.NumberU64(), "err", err)
 		pm.shuffleIfVerify(block)
-		err = pm.engine.VerifyBlockGenerate(pm.blockchain, block, pm.taskManager.GetCurrentShuffleRound(), blockInterval)
+		err = pm.engine.VerifyBlockGenerate(pm.blockchain, block, pm.taskManager.GetCurrentShuffleRound(), pm.taskManager.blockInterval)
 		if err != nil {
 			log.Error("New block Msg|verify block fail again", "blockNumber", block.NumberU64(), "err", err)
 			return nil
@@ -416,6 +423,7 @@ func (pm *ProtocolManager) dealPreBlockMsg(msg p2p.Msg, p *peer) error {
 		return errResp(ErrDecode, "%v: %v", msg, err)
 	}
 	block := request.Block
+	pm.observeBlock(block)
 	log.Info("PreBlockMsg receive", "blockNumber", block.NumberU64(), "blockHash", block.Hash().Hex(), "coinbase", block.Coinbase().Hex())
 	// lost block
 	currentBlock := pm.blockchain.CurrentBlock()
@@ -436,7 +444,7 @@ func (pm *ProtocolManager) dealPreBlockMsg(msg p2p.Msg, p *peer) error {
 		log.Error("dealPreBlockMsg block hash is not same", "blockNumber", currentBlock.NumberU64(), "localBlockHash", currentBlock.Hash().Hex(), "remoteBlockHash", block.ParentHash().Hex())
 	}
 	verifyBlock := true
-	err := pm.engine.VerifyHeaderAndSign(pm.blockchain, block, pm.taskManager.GetCurrentShuffleRound(), blockInterval)
+	err := pm.engine.VerifyHeaderAndSign(pm.blockchain, block, pm.taskManager.GetCurrentShuffleRound(), pm.taskManager.blockInterval)
 	if err != nil {
 		log.Error("PreBlockMsg verify block fail", "blockNumber", block.NumberU64(), "err", err)
 		verifyBlock = false
@@ -509,7 +517,127 @@ func (pm *ProtocolManager) dealTxMsg(msg p2p.Msg, p *peer) error {
 	return nil
 }
 
+func (pm *ProtocolManager) dealNewPooledTxHashesMsg(msg p2p.Msg, p *peer) error {
+	// Transactions announced, make sure we have a valid and fresh chain to handle them
+	if atomic.LoadUint32(&pm.acceptTxs) == 0 {
+		return nil
+	}
+	var hashes []common.Hash
+	if err := msg.Decode(&hashes); err != nil {
+		return errResp(ErrDecode, "msg %v: %v", msg, err)
+	}
+	if len(hashes) > maxTxAnnounces {
+		return errResp(ErrMsgTooLarge, "%d transaction hashes > %d", len(hashes), maxTxAnnounces)
+	}
+	// Pull the transactions we don't know about from the announcing peer, unless
+	// they are already being pulled from another one
+	unknown := make([]common.Hash, 0, len(hashes))
+	for _, hash := range hashes {
+		p.MarkTransaction(hash)
+		if pm.txpool.Get(hash) == nil {
+			unknown = append(unknown, hash)
+		}
+	}
+	if unknown = pm.txRequests.claim(unknown); len(unknown) == 0 {
+		return nil
+	}
+	return p.RequestTransactions(unknown)
+}
+
+func (pm *ProtocolManager) dealGetPooledTxsMsg(msg p2p.Msg, p *peer) error {
+	var hashes []common.Hash
+	if err := msg.Decode(&hashes); err != nil {
+		return errResp(ErrDecode, "msg %v: %v", msg, err)
+	}
+	if len(hashes) > maxTxAnnounces {
+		return errResp(ErrMsgTooLarge, "%d transaction hashes > %d", len(hashes), maxTxAnnounces)
+	}
+	// Gather the requested transactions still pooled until the network limit is reached
+	var (
+		bytes common.StorageSize
+		txs   types.Transactions
+	)
+	for _, hash := range hashes {
+		if bytes >= softResponseLimit {
+			break
+		}
+		if tx := pm.txpool.Get(hash); tx != nil {
+			txs = append(txs, tx)
+			bytes += tx.Size()
+		}
+	}
+	return p.SendPooledTransactions(txs)
+}
+
+func (pm *ProtocolManager) dealPooledTxsMsg(msg p2p.Msg, p *peer) error {
+	// Requested transactions arrived, deliver them to the pool like broadcast ones
+	var txs []*types.Transaction
+	if err := msg.Decode(&txs); err != nil {
+		return errResp(ErrDecode, "msg %v: %v", msg, err)
+	}
+	for i, tx := range txs {
+		if tx == nil {
+			return errResp(ErrDecode, "transaction %d is nil", i)
+		}
+		p.MarkTransaction(tx.Hash())
+	}
+	pm.txRequests.deliver(txs)
+	if atomic.LoadUint32(&pm.acceptTxs) == 0 {
+		return nil
+	}
+	pm.txpool.AddRemotes(txs)
+	return nil
+}
+
 // generate correct shuffleList when verify fail,only try once
+func (pm *ProtocolManager) dealDoubleSignMsg(msg p2p.Msg, p *peer) error {
+	// Double sign evidences arrived, verify and store the ones of known delegates
+	var evidences []*core.DoubleSignEvidence
+	if err := msg.Decode(&evidences); err != nil {
+		return errResp(ErrDecode, "msg %v: %v", msg, err)
+	}
+	if len(evidences) > maxEvidenceBroadcast {
+		return errResp(ErrDecode, "too many evidences: %d > %d", len(evidences), maxEvidenceBroadcast)
+	}
+	for i, evidence := range evidences {
+		// Validate and mark the remote evidence
+		if evidence == nil || evidence.First == nil || evidence.Second == nil {
+			return errResp(ErrDecode, "evidence %d is nil", i)
+		}
+		if err := evidence.Verify(); err != nil {
+			return errResp(ErrDecode, "evidence %d: %v", i, err)
+		}
+		p.MarkEvidence(evidence.Hash())
+		if err := pm.addEvidence(evidence); err != nil {
+			log.Debug("Dropped double sign evidence", "delegate", evidence.Delegate(), "number", evidence.Number(), "err", err)
+		}
+	}
+	return nil
+}
+
+func (pm *ProtocolManager) dealDelegateProofMsg(msg p2p.Msg, p *peer) error {
+	// Proofs of the delegates running on the peer arrived, announce the node if
+	// any of them is a registered delegate
+	var proofs []*delegateProof
+	if err := msg.Decode(&proofs); err != nil {
+		return errResp(ErrDecode, "msg %v: %v", msg, err)
+	}
+	if len(proofs) > maxDelegateProofs {
+		return errResp(ErrDecode, "too many delegate proofs: %d > %d", len(proofs), maxDelegateProofs)
+	}
+	valid, err := pm.verifyDelegateProofs(p, proofs)
+	if err != nil {
+		return err
+	}
+	if valid {
+		select {
+		case pm.delegateNodes <- p.ID():
+		default:
+		}
+	}
+	return nil
+}
+
 func (pm *ProtocolManager) shuffleIfVerify(block *types.Block) {
 	pm.taskManager.ShuffleWhenVerifyFail(block.Number().Int64(), block.Time().Int64(), block.Header().ShuffleBlockNumber)
 }
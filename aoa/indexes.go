@@ -0,0 +1,315 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// Optional chain indexes which may be enabled by the operator. Enabling one on a
+// node with an existing chain backfills it over the historical blocks.
+const (
+	IndexLogs      = "logs"      // Transactions emitting logs, by log address
+	IndexTransfers = "transfers" // Asset and token transfers, by sender and recipient
+	IndexCreations = "creations" // Contract creating transactions, by contract address
+)
+
+const (
+	// addressIndexSectionSize is the number of blocks whose index entries are
+	// grouped together under a single database key per address.
+	addressIndexSectionSize = 1024
+
+	// addressIndexConfirms is the number of confirmation blocks before a section
+	// is considered final and indexed.
+	addressIndexConfirms = 256
+
+	// DefaultIndexThrottling is the default time waited between processing two
+	// consecutive sections, keeping a backfill from hogging the disk.
+	DefaultIndexThrottling = 100 * time.Millisecond
+)
+
+// transferEventTopic is the topic of the standard token contract event emitted
+// on every transfer, Transfer(address indexed from, address indexed to, uint256).
+var transferEventTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// indexCollector extracts the addresses a block's transactions need indexing
+// under, calling add with each address and the index of the transaction.
+type indexCollector func(header *types.Header, txs types.Transactions, receipts types.Receipts, add func(common.Address, int))
+
+// indexWriter stores the entries collected for an address within a section.
+type indexWriter func(db aoadb.Putter, address common.Address, section uint64, entries []core.TxLookupEntry) error
+
+// AddressIndexer implements a core.ChainIndexer, indexing the transactions of
+// the canonical chain by the addresses they relate to, as defined by the kind of
+// the index.
+type AddressIndexer struct {
+	db      aoadb.Database // database instance to read blocks from and write the index into
+	collect indexCollector // extractor of the addresses to index transactions under
+	write   indexWriter    // writer of the entries of an address in a section
+
+	section uint64                                  // section being processed currently
+	entries map[common.Address][]core.TxLookupEntry // entries collected for the current section
+}
+
+// NewAddressIndexer returns a chain indexer that generates the given kind of
+// optional address index over the canonical chain.
+func NewAddressIndexer(db aoadb.Database, config *params.ChainConfig, kind string, throttling time.Duration) (*core.ChainIndexer, error) {
+	collect, write, prefix, err := addressIndex(config, kind)
+	if err != nil {
+		return nil, err
+	}
+	backend := &AddressIndexer{db: db, collect: collect, write: write}
+	table := aoadb.NewTable(db, string(prefix))
+
+	return core.NewChainIndexer(db, table, backend, addressIndexSectionSize, addressIndexConfirms, throttling, kind), nil
+}
+
+// addressIndex returns the collector, writer and indexer table prefix of the
+// given kind of index.
+func addressIndex(config *params.ChainConfig, kind string) (indexCollector, indexWriter, []byte, error) {
+	switch kind {
+	case IndexLogs:
+		return collectLogs, core.WriteLogIndex, core.LogIndexPrefix, nil
+	case IndexTransfers:
+		return collectTransfers(config), core.WriteTransferIndex, core.TransferIndexPrefix, nil
+	case IndexCreations:
+		return collectCreations, writeCreations, core.CreationIndexPrefix, nil
+	}
+	return nil, nil, nil, fmt.Errorf("unknown chain index %q", kind)
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new section.
+func (a *AddressIndexer) Reset(section uint64, lastSectionHead common.Hash) error {
+	a.section, a.entries = section, make(map[common.Address][]core.TxLookupEntry)
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, collecting the entries of a new
+// block into the section. Blocks whose bodies were pruned are skipped.
+func (a *AddressIndexer) Process(header *types.Header) {
+	hash, number := header.Hash(), header.Number.Uint64()
+
+	body := core.GetBody(a.db, hash, number)
+	if body == nil {
+		return
+	}
+	receipts := core.GetBlockReceipts(a.db, hash, number)
+	a.collect(header, body.Transactions, receipts, func(address common.Address, index int) {
+		entries := a.entries[address]
+		if n := len(entries); n > 0 && entries[n-1].BlockHash == hash && entries[n-1].Index == uint64(index) {
+			return
+		}
+		a.entries[address] = append(entries, core.TxLookupEntry{BlockHash: hash, BlockIndex: number, Index: uint64(index)})
+	})
+}
+
+// Commit implements core.ChainIndexerBackend, writing the entries collected for
+// the section into the database.
+func (a *AddressIndexer) Commit() error {
+	batch := a.db.NewBatch()
+	for address, entries := range a.entries {
+		if err := a.write(batch, address, a.section, entries); err != nil {
+			return err
+		}
+		if batch.ValueSize() >= aoadb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch = a.db.NewBatch()
+		}
+	}
+	return batch.Write()
+}
+
+// collectLogs indexes transactions under the addresses of the logs they emitted.
+func collectLogs(header *types.Header, txs types.Transactions, receipts types.Receipts, add func(common.Address, int)) {
+	for i, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			add(log.Address, i)
+		}
+	}
+}
+
+// collectTransfers indexes transactions under the senders and recipients of the
+// assets or tokens they transferred. Token transfers are recognised by the
+// standard Transfer event of the token contract.
+func collectTransfers(config *params.ChainConfig) indexCollector {
+	return func(header *types.Header, txs types.Transactions, receipts types.Receipts, add func(common.Address, int)) {
+		signer := types.MakeSigner(config, header.Number)
+		for i, tx := range txs {
+			if tx.TxDataAction() != types.ActionTrans || tx.Asset() == nil || tx.To() == nil {
+				continue
+			}
+			if from, err := types.Sender(signer, tx); err == nil {
+				add(from, i)
+			}
+			add(*tx.To(), i)
+		}
+		for i, receipt := range receipts {
+			for _, log := range receipt.Logs {
+				if len(log.Topics) == 3 && log.Topics[0] == transferEventTopic {
+					add(common.BytesToAddress(log.Topics[1].Bytes()), i)
+					add(common.BytesToAddress(log.Topics[2].Bytes()), i)
+				}
+			}
+		}
+	}
+}
+
+// collectCreations indexes contract creating transactions under the address of
+// the contract they deployed.
+func collectCreations(header *types.Header, txs types.Transactions, receipts types.Receipts, add func(common.Address, int)) {
+	for i, receipt := range receipts {
+		if i < len(txs) && txs[i].TxDataAction() == types.ActionCreateContract && receipt.ContractAddress != (common.Address{}) {
+			add(receipt.ContractAddress, i)
+		}
+	}
+}
+
+// writeCreations stores the transaction creating a contract, which being unique
+// per address isn't grouped into sections.
+func writeCreations(db aoadb.Putter, address common.Address, section uint64, entries []core.TxLookupEntry) error {
+	return core.WriteContractCreation(db, address, entries[0])
+}
+
+const (
+	// maxIndexScan is the maximum number of blocks not covered by an index yet
+	// which are scanned directly when serving a lookup.
+	maxIndexScan = 2*addressIndexSectionSize + addressIndexConfirms
+
+	// maxIndexResults is the maximum number of transactions returned by a lookup.
+	maxIndexResults = 10000
+)
+
+// lookupAddressIndex retrieves the lookup entries of the canonical transactions
+// indexed under the address in the block range [from, to]. Blocks not covered by
+// the index yet are scanned directly, as long as the index is caught up with the
+// chain.
+func (dacchain *Dacchain) lookupAddressIndex(kind string, address common.Address, from, to uint64) ([]core.TxLookupEntry, error) {
+	indexer := dacchain.indexers[kind]
+	if indexer == nil {
+		return nil, fmt.Errorf("%s index not enabled", kind)
+	}
+	var get func(core.DatabaseReader, common.Address, uint64) []core.TxLookupEntry
+	switch kind {
+	case IndexLogs:
+		get = core.GetLogIndex
+	case IndexTransfers:
+		get = core.GetTransferIndex
+	default:
+		return nil, fmt.Errorf("%s index not searchable by block range", kind)
+	}
+	sections, _, _ := indexer.Progress()
+	indexed := sections * addressIndexSectionSize // First block not covered by the index
+
+	var entries []core.TxLookupEntry
+	for section := from / addressIndexSectionSize; section*addressIndexSectionSize <= to && section < sections; section++ {
+		for _, entry := range get(dacchain.chainDb, address, section) {
+			if entry.BlockIndex < from || entry.BlockIndex > to {
+				continue
+			}
+			if core.GetCanonicalHash(dacchain.chainDb, entry.BlockIndex) != entry.BlockHash {
+				continue
+			}
+			if entries = append(entries, entry); len(entries) > maxIndexResults {
+				return nil, fmt.Errorf("more than %d results, narrow the block range", maxIndexResults)
+			}
+		}
+	}
+	if to < indexed {
+		return entries, nil
+	}
+	if from < indexed {
+		from = indexed
+	}
+	scanned, err := dacchain.scanAddressIndex(kind, address, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if entries = append(entries, scanned...); len(entries) > maxIndexResults {
+		return nil, fmt.Errorf("more than %d results, narrow the block range", maxIndexResults)
+	}
+	return entries, nil
+}
+
+// lookupContractCreation retrieves the lookup entry of the canonical transaction
+// which created the contract at the given address, nil if it's not known.
+func (dacchain *Dacchain) lookupContractCreation(address common.Address) (*core.TxLookupEntry, error) {
+	indexer := dacchain.indexers[IndexCreations]
+	if indexer == nil {
+		return nil, fmt.Errorf("%s index not enabled", IndexCreations)
+	}
+	if entry := core.GetContractCreation(dacchain.chainDb, address); entry != nil {
+		if core.GetCanonicalHash(dacchain.chainDb, entry.BlockIndex) == entry.BlockHash {
+			return entry, nil
+		}
+	}
+	sections, _, _ := indexer.Progress()
+	head := dacchain.blockchain.CurrentBlock().NumberU64()
+	if indexed := sections * addressIndexSectionSize; indexed <= head {
+		entries, err := dacchain.scanAddressIndex(IndexCreations, address, indexed, head)
+		if err != nil || len(entries) == 0 {
+			return nil, err
+		}
+		return &entries[0], nil
+	}
+	return nil, nil
+}
+
+// scanAddressIndex collects the entries an index would contain for the address
+// in the block range [from, to] directly from the canonical blocks.
+func (dacchain *Dacchain) scanAddressIndex(kind string, address common.Address, from, to uint64) ([]core.TxLookupEntry, error) {
+	if head := dacchain.blockchain.CurrentBlock().NumberU64(); to > head {
+		to = head
+	}
+	if from > to {
+		return nil, nil
+	}
+	if to-from >= maxIndexScan {
+		return nil, fmt.Errorf("%s index still backfilling, %d blocks not indexed yet", kind, to-from+1)
+	}
+	collect, _, _, err := addressIndex(dacchain.chainConfig, kind)
+	if err != nil {
+		return nil, err
+	}
+	var entries []core.TxLookupEntry
+	err = core.ForEachCanonicalBlock(dacchain.chainDb, from, to, func(block *types.Block, receipts types.Receipts) error {
+		hash, number := block.Hash(), block.NumberU64()
+		collect(block.Header(), block.Transactions(), receipts, func(addr common.Address, index int) {
+			if addr != address {
+				return
+			}
+			if n := len(entries); n > 0 && entries[n-1].BlockHash == hash && entries[n-1].Index == uint64(index) {
+				return
+			}
+			entries = append(entries, core.TxLookupEntry{BlockHash: hash, BlockIndex: number, Index: uint64(index)})
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// Tests that the address indexers collect the expected transactions of a block
+// and that each index is written into its own database table.
+func TestAddressIndexer(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+
+	var (
+		token    = common.HexToAddress("0x01")
+		emitter  = common.HexToAddress("0x02")
+		from     = common.HexToAddress("0x03")
+		to       = common.HexToAddress("0x04")
+		contract = common.HexToAddress("0x05")
+	)
+	txs := types.Transactions{
+		types.NewTransaction(0, token, big.NewInt(0), 50000, big.NewInt(1), nil, types.ActionCallContract, nil, ""),
+		types.NewContractCreation(1, big.NewInt(0), 100000, big.NewInt(1), nil, "", nil),
+	}
+	receipts := types.Receipts{
+		&types.Receipt{Logs: []*types.Log{
+			{Address: token, Topics: []common.Hash{transferEventTopic, from.Hash(), to.Hash()}},
+			{Address: emitter},
+			{Address: token},
+		}},
+		&types.Receipt{ContractAddress: contract},
+	}
+	header := &types.Header{Number: big.NewInt(1)}
+	hash := header.Hash()
+
+	core.WriteBody(db, hash, 1, &types.Body{Transactions: txs})
+	core.WriteBlockReceipts(db, hash, 1, receipts)
+
+	for _, kind := range []string{IndexLogs, IndexTransfers, IndexCreations} {
+		collect, write, _, err := addressIndex(params.TestChainConfig, kind)
+		if err != nil {
+			t.Fatalf("%s: failed to create index: %v", kind, err)
+		}
+		backend := &AddressIndexer{db: db, collect: collect, write: write}
+		backend.Reset(0, common.Hash{})
+		backend.Process(header)
+		if err := backend.Commit(); err != nil {
+			t.Fatalf("%s: failed to commit section: %v", kind, err)
+		}
+	}
+	want := []core.TxLookupEntry{{BlockHash: hash, BlockIndex: 1, Index: 0}}
+	check := func(name string, have, want []core.TxLookupEntry) {
+		if len(have) != len(want) {
+			t.Errorf("%s: entry count mismatch: have %d, want %d", name, len(have), len(want))
+			return
+		}
+		for i := range have {
+			if have[i] != want[i] {
+				t.Errorf("%s: entry %d mismatch: have %v, want %v", name, i, have[i], want[i])
+			}
+		}
+	}
+	check("token logs", core.GetLogIndex(db, token, 0), want)
+	check("emitter logs", core.GetLogIndex(db, emitter, 0), want)
+	check("sender transfers", core.GetTransferIndex(db, from, 0), want)
+	check("recipient transfers", core.GetTransferIndex(db, to, 0), want)
+	check("token transfers", core.GetTransferIndex(db, token, 0), nil)
+	check("other section", core.GetLogIndex(db, token, 1), nil)
+
+	if entry := core.GetContractCreation(db, contract); entry == nil || *entry != (core.TxLookupEntry{BlockHash: hash, BlockIndex: 1, Index: 1}) {
+		t.Errorf("contract creation mismatch: have %v", entry)
+	}
+	if _, _, _, err := addressIndex(params.TestChainConfig, "balances"); err == nil {
+		t.Errorf("unknown index accepted")
+	}
+}
+
+// Tests that a paused indexer reports its state and doesn't process sections
+// until resumed.
+func TestAddressIndexerPause(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+
+	indexer, err := NewAddressIndexer(db, params.TestChainConfig, IndexLogs, 0)
+	if err != nil {
+		t.Fatalf("failed to create indexer: %v", err)
+	}
+	defer indexer.Close()
+
+	indexer.Pause()
+	if stored, _, paused := indexer.Progress(); stored != 0 || !paused {
+		t.Fatalf("progress mismatch: have %d sections, paused %v", stored, paused)
+	}
+	indexer.Resume()
+	if _, _, paused := indexer.Progress(); paused {
+		t.Fatalf("indexer still paused after resume")
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/event"
+	"github.com/Aurorachain-io/go-aoa/log"
+)
+
+// instantSealer produces the blocks of instant sealing developer chains. Instead
+// of waiting for its slot in a shuffled round, the single local delegate seals a
+// block as soon as a transaction enters the pool, and every period seconds if
+// the chain configures one.
+type instantSealer struct {
+	blockchain *core.BlockChain
+	txPool     *core.TxPool
+	miner      *core.DposMiner
+	period     time.Duration
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newInstantSealer(blockchain *core.BlockChain, txPool *core.TxPool, miner *core.DposMiner) *instantSealer {
+	return &instantSealer{
+		blockchain: blockchain,
+		txPool:     txPool,
+		miner:      miner,
+		period:     time.Duration(blockchain.Config().Instant.Period) * time.Second,
+		quit:       make(chan struct{}),
+	}
+}
+
+// Start starts sealing blocks in the background. Transactions entering the pool
+// after it returns are sealed.
+func (s *instantSealer) Start() {
+	txCh := make(chan core.TxPreEvent, txChanSize)
+	txSub := s.txPool.SubscribeTxPreEvent(txCh)
+
+	s.wg.Add(1)
+	go s.loop(txCh, txSub)
+}
+
+// Stop stops sealing blocks, waiting for a block being sealed to be imported.
+func (s *instantSealer) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+func (s *instantSealer) loop(txCh chan core.TxPreEvent, txSub event.Subscription) {
+	defer s.wg.Done()
+	defer txSub.Unsubscribe()
+
+	var tick <-chan time.Time
+	if s.period > 0 {
+		ticker := time.NewTicker(s.period)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-txCh:
+			// Transactions arriving while a block is sealed are included in it,
+			// skip their events once the pool has been emptied. Pending ones the
+			// block can't include don't warrant an empty block either.
+			if pending, _ := s.txPool.Stats(); pending > 0 {
+				s.seal(false)
+			}
+		case <-tick:
+			s.seal(true)
+		case <-txSub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// seal produces a block of all pending transactions on top of the current head
+// and imports it, unless it is empty and empty blocks are not wanted. Blocks must
+// be younger than their parent, so a block following its parent within the same
+// second waits for the next one.
+func (s *instantSealer) seal(empty bool) {
+	parent := s.blockchain.CurrentBlock()
+	now := time.Now().Unix()
+	if next := parent.Time().Int64() + 1; now < next {
+		select {
+		case <-time.After(time.Duration(next-now) * time.Second):
+		case <-s.quit:
+			return
+		}
+		now = next
+	}
+	delegates, err := s.blockchain.DelegateStateAt(parent.DelegateRoot())
+	if err != nil {
+		log.Error("Failed to open delegate state for instant sealing", "number", parent.Number(), "err", err)
+		return
+	}
+	candidates := delegates.GetDelegates()
+	if len(candidates) == 0 {
+		log.Error("No delegate to seal instant blocks", "number", parent.Number())
+		return
+	}
+	// Seal with the configured coinbase if it is a delegate, the first one otherwise
+	producer := candidates[0]
+	if coinbase := s.miner.Coinbase(); coinbase != (common.Address{}) {
+		for _, candidate := range candidates {
+			if common.HexToAddress(candidate.Address) == coinbase {
+				producer = candidate
+				break
+			}
+		}
+	}
+	block, err := s.miner.ProduceBlock(common.HexToAddress(producer.Address), producer.Nickname, new(big.Int).SetInt64(now))
+	if err != nil {
+		log.Error("Failed to seal instant block", "number", parent.NumberU64()+1, "err", err)
+		return
+	}
+	if !empty && len(block.Transactions()) == 0 {
+		log.Debug("Skipping empty instant block", "number", block.Number())
+		return
+	}
+	if _, err := s.blockchain.InsertChain(types.Blocks{block}); err != nil {
+		log.Error("Failed to import instant block", "number", block.Number(), "hash", block.Hash(), "err", err)
+		return
+	}
+	log.Info("Sealed instant block", "number", block.Number(), "hash", block.Hash(), "txs", len(block.Transactions()))
+}
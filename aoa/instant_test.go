@@ -0,0 +1,146 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/accounts"
+	aa "github.com/Aurorachain-io/go-aoa/accounts/walletType"
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus/dpos"
+	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/core/vm"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// testInstantBackend serves a chain and transaction pool to the block producer.
+type testInstantBackend struct {
+	db     aoadb.Database
+	chain  *core.BlockChain
+	txPool *core.TxPool
+}
+
+func (b *testInstantBackend) AccountManager() *accounts.Manager { return nil }
+func (b *testInstantBackend) BlockChain() *core.BlockChain      { return b.chain }
+func (b *testInstantBackend) TxPool() *core.TxPool              { return b.txPool }
+func (b *testInstantBackend) ChainDb() aoadb.Database           { return b.db }
+func (b *testInstantBackend) WatcherDb() aoadb.Database         { return nil }
+
+// skippingOrdering offers all pending transactions except those of one sender,
+// standing in for transactions the pool accepts but blocks can't include.
+type skippingOrdering struct {
+	signer types.Signer
+	skip   common.Address
+}
+
+func (o skippingOrdering) Order(signer types.Signer, pending types.TxByPrice) core.TransactionIterator {
+	var included types.TxByPrice
+	for _, tx := range pending {
+		if from, _ := types.Sender(o.signer, tx); from != o.skip {
+			included = append(included, tx)
+		}
+	}
+	return core.DefaultTxOrdering.Order(signer, included)
+}
+
+// Tests that instant sealing chains seal a block as soon as a transaction enters
+// the pool, but don't seal empty blocks for pending transactions that can't be
+// included.
+func TestInstantSealer(t *testing.T) {
+	var (
+		db, _       = aoadb.NewMemDatabase()
+		config      = *params.AllDacchainProtocolChanges
+		delegate, _ = crypto.GenerateKey()
+		key, _      = crypto.GenerateKey()
+		addr        = crypto.PubkeyToAddress(key.PublicKey)
+		stuckKey, _ = crypto.GenerateKey()
+		stuckAddr   = crypto.PubkeyToAddress(stuckKey.PublicKey)
+		to          = common.HexToAddress("0x01")
+	)
+	config.Instant = &params.InstantConfig{}
+	signer := types.MakeSigner(&config, big.NewInt(1))
+
+	genesis := (&core.Genesis{
+		Config: &config,
+		Alloc: core.GenesisAlloc{
+			addr:      {Balance: big.NewInt(params.Em)},
+			stuckAddr: {Balance: big.NewInt(params.Em)},
+		},
+		Agents: core.GenesisAgents{{Address: crypto.PubkeyToAddress(delegate.PublicKey).Hex(), Vote: 1, Nickname: "test"}},
+	}).MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, nil, &config, dpos.New(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	poolConfig := core.DefaultTxPoolConfig
+	poolConfig.Journal = ""
+	pool := core.NewTxPool(poolConfig, &config, chain)
+	defer pool.Stop()
+
+	miner := core.NewDposMiner(&config, &testInstantBackend{db: db, chain: chain, txPool: pool}, dpos.New())
+	miner.AddDelegateWalletCallback(&aa.DelegateWalletInfo{Address: crypto.PubkeyToAddress(delegate.PublicKey).Hex(), PrivateKey: delegate})
+
+	miner.SetTxOrdering(skippingOrdering{signer: signer, skip: stuckAddr})
+
+	headCh := make(chan core.ChainHeadEvent, 10)
+	headSub := chain.SubscribeChainHeadEvent(headCh)
+	defer headSub.Unsubscribe()
+
+	sealer := newInstantSealer(chain, pool, miner)
+	sealer.Start()
+	defer sealer.Stop()
+
+	stuck, _ := types.SignTx(types.NewTransaction(0, to, big.NewInt(10), 100000, big.NewInt(1), nil, types.ActionTrans, nil, ""), signer, stuckKey)
+	if err := pool.AddLocal(stuck); err != nil {
+		t.Fatalf("failed to add unexecutable transaction: %v", err)
+	}
+	select {
+	case ev := <-headCh:
+		t.Fatalf("sealed block #%d with %d transactions for an unexecutable one", ev.Block.NumberU64(), len(ev.Block.Transactions()))
+	case <-time.After(1500 * time.Millisecond):
+	}
+	// An executable transaction is sealed right away, next to the pending one
+	tx, _ := types.SignTx(types.NewTransaction(0, to, big.NewInt(10), 100000, big.NewInt(1), nil, types.ActionTrans, nil, ""), signer, key)
+	if err := pool.AddLocal(tx); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	select {
+	case ev := <-headCh:
+		if ev.Block.ParentHash() != genesis.Hash() {
+			t.Fatalf("block #%d not sealed on genesis", ev.Block.NumberU64())
+		}
+		if txs := ev.Block.Transactions(); len(txs) != 1 || txs[0].Hash() != tx.Hash() {
+			t.Fatalf("sealed block transactions mismatch: have %d, want %x", len(txs), tx.Hash())
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("transaction not sealed")
+	}
+	select {
+	case ev := <-headCh:
+		t.Fatalf("sealed extra block #%d with %d transactions", ev.Block.NumberU64(), len(ev.Block.Transactions()))
+	case <-time.After(1500 * time.Millisecond):
+	}
+}
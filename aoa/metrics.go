@@ -26,6 +26,10 @@ var (
 	propTxnInTrafficMeter       = metrics.NewMeter("em/prop/txns/in/traffic")
 	propTxnOutPacketsMeter      = metrics.NewMeter("em/prop/txns/out/packets")
 	propTxnOutTrafficMeter      = metrics.NewMeter("em/prop/txns/out/traffic")
+	propTxnHashInPacketsMeter   = metrics.NewMeter("em/prop/txhashes/in/packets")
+	propTxnHashInTrafficMeter   = metrics.NewMeter("em/prop/txhashes/in/traffic")
+	propTxnHashOutPacketsMeter  = metrics.NewMeter("em/prop/txhashes/out/packets")
+	propTxnHashOutTrafficMeter  = metrics.NewMeter("em/prop/txhashes/out/traffic")
 	propHashInPacketsMeter      = metrics.NewMeter("em/prop/hashes/in/packets")
 	propHashInTrafficMeter      = metrics.NewMeter("em/prop/hashes/in/traffic")
 	propHashOutPacketsMeter     = metrics.NewMeter("em/prop/hashes/out/packets")
@@ -55,10 +59,16 @@ var (
 	reqStateInTrafficMeter    = metrics.NewMeter("em/req/states/in/traffic")
 	reqStateOutPacketsMeter   = metrics.NewMeter("em/req/states/out/packets")
 	reqStateOutTrafficMeter   = metrics.NewMeter("em/req/states/out/traffic")
+	reqTxnInPacketsMeter      = metrics.NewMeter("em/req/txns/in/packets")
+	reqTxnInTrafficMeter      = metrics.NewMeter("em/req/txns/in/traffic")
+	reqTxnOutPacketsMeter     = metrics.NewMeter("em/req/txns/out/packets")
+	reqTxnOutTrafficMeter     = metrics.NewMeter("em/req/txns/out/traffic")
 	reqReceiptInPacketsMeter  = metrics.NewMeter("em/req/receipts/in/packets")
 	reqReceiptInTrafficMeter  = metrics.NewMeter("em/req/receipts/in/traffic")
 	reqReceiptOutPacketsMeter = metrics.NewMeter("em/req/receipts/out/packets")
 	reqReceiptOutTrafficMeter = metrics.NewMeter("em/req/receipts/out/traffic")
+	reqHeaderOutBatchHist     = metrics.NewHistogram("em/req/headers/out/batch")
+	reqBodyOutBatchHist       = metrics.NewHistogram("em/req/bodies/out/batch")
 	miscInPacketsMeter        = metrics.NewMeter("em/misc/in/packets")
 	miscInTrafficMeter        = metrics.NewMeter("em/misc/in/traffic")
 	miscOutPacketsMeter       = metrics.NewMeter("em/misc/out/packets")
@@ -112,6 +122,10 @@ func (rw *meteredMsgReadWriter) ReadMsg() (p2p.Msg, error) {
 		packets, traffic = propBlockInPacketsMeter, propBlockInTrafficMeter
 	case msg.Code == TxMsg:
 		packets, traffic = propTxnInPacketsMeter, propTxnInTrafficMeter
+	case rw.version >= aoa04 && msg.Code == NewPooledTxHashesMsg:
+		packets, traffic = propTxnHashInPacketsMeter, propTxnHashInTrafficMeter
+	case rw.version >= aoa04 && msg.Code == PooledTxsMsg:
+		packets, traffic = reqTxnInPacketsMeter, reqTxnInTrafficMeter
 	case msg.Code == PreBlockMsg:
 		packets, traffic = propPreBlockInPacketsMeter, propPreBlockInTrafficMeter
 	case msg.Code == SignaturesBlockMsg:
@@ -144,6 +158,10 @@ func (rw *meteredMsgReadWriter) WriteMsg(msg p2p.Msg) error {
 		packets, traffic = propBlockOutPacketsMeter, propBlockOutTrafficMeter
 	case msg.Code == TxMsg:
 		packets, traffic = propTxnOutPacketsMeter, propTxnOutTrafficMeter
+	case rw.version >= aoa04 && msg.Code == NewPooledTxHashesMsg:
+		packets, traffic = propTxnHashOutPacketsMeter, propTxnHashOutTrafficMeter
+	case rw.version >= aoa04 && msg.Code == PooledTxsMsg:
+		packets, traffic = reqTxnOutPacketsMeter, reqTxnOutTrafficMeter
 	case msg.Code == PreBlockMsg:
 		packets, traffic = propPreBlockOutPacketsMeter, propPreBlockOutTrafficMeter
 	case msg.Code == SignaturesBlockMsg:
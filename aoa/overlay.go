@@ -0,0 +1,264 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/common/hexutil"
+	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/internal/aoaapi"
+	"github.com/Aurorachain-io/go-aoa/rlp"
+	"github.com/Aurorachain-io/go-aoa/rpc"
+)
+
+// maxOverlays is the maximum number of chain overlays kept alive at the same
+// time, each of them holding all state it produced in memory.
+const maxOverlays = 16
+
+var (
+	errOverlayNotFound = errors.New("overlay not found")
+	errTooManyOverlays = errors.New("too many overlays, drop unused ones first")
+)
+
+// chainOverlays tracks the in-memory chain overlays created over RPC by id.
+type chainOverlays struct {
+	overlays map[string]*core.ChainOverlay
+	lock     sync.RWMutex
+}
+
+func newChainOverlays() *chainOverlays {
+	return &chainOverlays{overlays: make(map[string]*core.ChainOverlay)}
+}
+
+func (o *chainOverlays) add(overlay *core.ChainOverlay) (string, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if len(o.overlays) >= maxOverlays {
+		return "", errTooManyOverlays
+	}
+	id := string(rpc.NewID())
+	o.overlays[id] = overlay
+	return id, nil
+}
+
+func (o *chainOverlays) get(id string) (*core.ChainOverlay, error) {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	if overlay, ok := o.overlays[id]; ok {
+		return overlay, nil
+	}
+	return nil, errOverlayNotFound
+}
+
+func (o *chainOverlays) remove(id string) bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	_, ok := o.overlays[id]
+	delete(o.overlays, id)
+	return ok
+}
+
+// overlayBackend serves the chain and state accessors of the aoa API on top of a
+// chain overlay. The latest and pending blocks resolve to the overlay head.
+type overlayBackend struct {
+	aoaapi.Backend
+	overlay *core.ChainOverlay
+}
+
+func (b *overlayBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
+	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
+		return b.overlay.BlockByNumber(b.overlay.Head().Number.Uint64()), nil
+	}
+	return b.overlay.BlockByNumber(uint64(blockNr)), nil
+}
+
+func (b *overlayBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
+		return b.overlay.Head(), nil
+	}
+	if block := b.overlay.BlockByNumber(uint64(blockNr)); block != nil {
+		return block.Header(), nil
+	}
+	return nil, nil
+}
+
+func (b *overlayBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	header, err := b.HeaderByNumber(ctx, blockNr)
+	if header == nil || err != nil {
+		return nil, nil, err
+	}
+	statedb, err := b.overlay.StateAt(header.Root)
+	return statedb, header, err
+}
+
+func (b *overlayBackend) GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
+	if receipts := b.overlay.GetReceipts(blockHash); receipts != nil {
+		return receipts, nil
+	}
+	return b.Backend.GetReceipts(ctx, blockHash)
+}
+
+// overlayAPI returns the aoa block chain API serving from the given overlay.
+func (api *PrivateDebugAPI) overlayAPI(id string) (*aoaapi.PublicBlockChainAPI, *overlayBackend, error) {
+	overlay, err := api.dac.overlays.get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	backend := &overlayBackend{Backend: api.dac.ApiBackend, overlay: overlay}
+	return aoaapi.NewPublicBlockChainAPI(backend), backend, nil
+}
+
+// OverlayCreate creates an in-memory fork of the chain on top of the given
+// canonical block and returns its id. Hypothetical blocks inserted into it and
+// the state they produce never reach the chain database.
+func (api *PrivateDebugAPI) OverlayCreate(blockNr rpc.BlockNumber) (string, error) {
+	var block *types.Block
+	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
+		block = api.dac.blockchain.CurrentBlock()
+	} else {
+		block = api.dac.blockchain.GetBlockByNumber(uint64(blockNr))
+	}
+	if block == nil {
+		return "", errors.New("block not found")
+	}
+	overlay, err := api.dac.blockchain.NewOverlay(block.Header())
+	if err != nil {
+		return "", err
+	}
+	return api.dac.overlays.add(overlay)
+}
+
+// OverlayDrop discards an overlay together with all state it holds.
+func (api *PrivateDebugAPI) OverlayDrop(id string) bool {
+	return api.dac.overlays.remove(id)
+}
+
+// OverlayBlockResult is a hypothetical block inserted into an overlay.
+type OverlayBlockResult struct {
+	Number    hexutil.Uint64   `json:"number"`
+	Hash      common.Hash      `json:"hash"`
+	StateRoot common.Hash      `json:"stateRoot"`
+	GasUsed   hexutil.Uint64   `json:"gasUsed"`
+	Receipts  []*types.Receipt `json:"receipts"`
+}
+
+// OverlayInsertBlock executes the given signed, RLP encoded transactions in a
+// hypothetical block on top of the overlay head. The block is produced by the
+// head's coinbase unless one is given and placed one block interval after the
+// head unless a timestamp is given. Any transaction failing to apply rejects
+// the whole block.
+func (api *PrivateDebugAPI) OverlayInsertBlock(id string, encodedTxs []hexutil.Bytes, coinbase *common.Address, timestamp *hexutil.Uint64) (*OverlayBlockResult, error) {
+	overlay, err := api.dac.overlays.get(id)
+	if err != nil {
+		return nil, err
+	}
+	txs := make(types.Transactions, len(encodedTxs))
+	for i, encoded := range encodedTxs {
+		tx := new(types.Transaction)
+		if err := rlp.DecodeBytes(encoded, tx); err != nil {
+			return nil, err
+		}
+		txs[i] = tx
+	}
+	author := overlay.Head().Coinbase
+	if coinbase != nil {
+		author = *coinbase
+	}
+	var time uint64
+	if timestamp != nil {
+		time = uint64(*timestamp)
+	}
+	block, receipts, err := overlay.InsertBlock(author, time, txs)
+	if err != nil {
+		return nil, err
+	}
+	return &OverlayBlockResult{
+		Number:    hexutil.Uint64(block.NumberU64()),
+		Hash:      block.Hash(),
+		StateRoot: block.Root(),
+		GasUsed:   hexutil.Uint64(block.GasUsed()),
+		Receipts:  receipts,
+	}, nil
+}
+
+// OverlayGetBlockByNumber returns a block of the overlay, see aoa_getBlockByNumber.
+func (api *PrivateDebugAPI) OverlayGetBlockByNumber(ctx context.Context, id string, blockNr rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
+	chain, _, err := api.overlayAPI(id)
+	if err != nil {
+		return nil, err
+	}
+	return chain.GetBlockByNumber(ctx, blockNr, fullTx)
+}
+
+// OverlayGetBalance returns the balance of an account in the overlay, see
+// aoa_getBalance.
+func (api *PrivateDebugAPI) OverlayGetBalance(ctx context.Context, id string, address common.Address, blockNr rpc.BlockNumber) (*big.Int, error) {
+	chain, _, err := api.overlayAPI(id)
+	if err != nil {
+		return nil, err
+	}
+	return chain.GetBalance(ctx, address, blockNr)
+}
+
+// OverlayGetTransactionCount returns the nonce of an account in the overlay, see
+// aoa_getTransactionCount.
+func (api *PrivateDebugAPI) OverlayGetTransactionCount(ctx context.Context, id string, address common.Address, blockNr rpc.BlockNumber) (*hexutil.Uint64, error) {
+	_, backend, err := api.overlayAPI(id)
+	if err != nil {
+		return nil, err
+	}
+	return aoaapi.NewPublicTransactionPoolAPI(backend, nil).GetTransactionCount(ctx, address, blockNr)
+}
+
+// OverlayGetCode returns the code of a contract in the overlay, see aoa_getCode.
+func (api *PrivateDebugAPI) OverlayGetCode(ctx context.Context, id string, address common.Address, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+	chain, _, err := api.overlayAPI(id)
+	if err != nil {
+		return nil, err
+	}
+	return chain.GetCode(ctx, address, blockNr)
+}
+
+// OverlayGetStorageAt returns a storage slot of a contract in the overlay, see
+// aoa_getStorageAt.
+func (api *PrivateDebugAPI) OverlayGetStorageAt(ctx context.Context, id string, address common.Address, key string, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+	chain, _, err := api.overlayAPI(id)
+	if err != nil {
+		return nil, err
+	}
+	return chain.GetStorageAt(ctx, address, key, blockNr)
+}
+
+// OverlayCall executes a message call against the overlay without creating a
+// transaction, see aoa_call.
+func (api *PrivateDebugAPI) OverlayCall(ctx context.Context, id string, args aoaapi.CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+	chain, _, err := api.overlayAPI(id)
+	if err != nil {
+		return nil, err
+	}
+	return chain.Call(ctx, args, blockNr, nil, nil)
+}
@@ -20,6 +20,8 @@ import (
 	"errors"
 	"fmt"
 	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/forkid"
 	"github.com/Aurorachain-io/go-aoa/core/types"
 	"github.com/Aurorachain-io/go-aoa/p2p"
 	"github.com/Aurorachain-io/go-aoa/rlp"
@@ -41,6 +43,7 @@ const (
 	maxKnownPrepare    = 32768
 	maxKnownSignatures = 32768
 	maxKnownPreBlocks  = 1024
+	maxKnownEvidences  = 1024 // Maximum double sign evidence hashes to keep in the known list (prevent DOS)
 	handshakeTimeout   = 5 * time.Second
 )
 
@@ -50,6 +53,7 @@ type PeerInfo struct {
 	Version    int      `json:"version"` // eminer-pro protocol version negotiated
 	difficulty *big.Int // `json:"difficulty"` // Total difficulty of the peer's blockchain
 	Head       string   `json:"head"` // SHA3 hash of the peer's best owned block
+	Tail       uint64   `json:"tail"` // Number of the oldest block the peer serves bodies and receipts for
 }
 
 type peer struct {
@@ -63,6 +67,7 @@ type peer struct {
 
 	head common.Hash
 	td   *big.Int
+	tail uint64 // Oldest block whose body and receipts the peer serves
 	lock sync.RWMutex
 
 	knownTxs *set.Set // Set of transaction hashes known to be known by this peer
@@ -75,6 +80,8 @@ type peer struct {
 
 	KnownPrepare *set.Set
 
+	knownEvidences *set.Set // Set of double sign evidence hashes known to be known by this peer
+
 	netType byte
 }
 
@@ -91,6 +98,7 @@ func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 		KnownPrepare:    set.New(),
 		knownPreBlocks:  set.New(),
 		knownSignatures: set.New(),
+		knownEvidences:  set.New(),
 		netType:         p.GetNetType(),
 	}
 }
@@ -103,6 +111,7 @@ func (p *peer) Info() *PeerInfo {
 		Version: p.version,
 		// difficulty: td,
 		Head: hash.Hex(),
+		Tail: p.HistoryTail(),
 	}
 }
 
@@ -116,6 +125,15 @@ func (p *peer) Head() (hash common.Hash, td *big.Int) {
 	return hash, new(big.Int).Set(p.td)
 }
 
+// HistoryTail retrieves the number of the oldest block whose body and receipts
+// the peer advertised to serve, zero for peers not advertising it.
+func (p *peer) HistoryTail() uint64 {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.tail
+}
+
 // SetHead updates the head hash and total difficulty of the peer.
 func (p *peer) SetHead(hash common.Hash, td *big.Int) {
 	p.lock.Lock()
@@ -192,6 +210,16 @@ func (p *peer) MarkTransaction(hash common.Hash) {
 	p.knownTxs.Add(hash)
 }
 
+// MarkEvidence marks a double sign evidence as known for the peer, ensuring that
+// it will never be propagated to this particular peer.
+func (p *peer) MarkEvidence(hash common.Hash) {
+	// If we reached the memory allowance, drop a previously known evidence hash
+	for p.knownEvidences.Size() >= maxKnownEvidences {
+		p.knownEvidences.Pop()
+	}
+	p.knownEvidences.Add(hash)
+}
+
 // SendTransactions sends transactions to the peer and includes the hashes
 // in its transaction hash set for future reference.
 func (p *peer) SendTransactions(txs types.Transactions) error {
@@ -201,6 +229,46 @@ func (p *peer) SendTransactions(txs types.Transactions) error {
 	return p2p.Send(p.rw, TxMsg, txs)
 }
 
+// SendPooledTransactionHashes announces the hashes of transactions the peer can
+// pull from us, and includes them in its transaction hash set for future
+// reference.
+func (p *peer) SendPooledTransactionHashes(hashes []common.Hash) error {
+	for _, hash := range hashes {
+		p.MarkTransaction(hash)
+	}
+	return p2p.Send(p.rw, NewPooledTxHashesMsg, hashes)
+}
+
+// SendPooledTransactions sends the transactions the peer requested, and includes
+// their hashes in its transaction hash set for future reference.
+func (p *peer) SendPooledTransactions(txs types.Transactions) error {
+	for _, tx := range txs {
+		p.MarkTransaction(tx.Hash())
+	}
+	return p2p.Send(p.rw, PooledTxsMsg, txs)
+}
+
+// RequestTransactions fetches a batch of announced transactions from the peer.
+func (p *peer) RequestTransactions(hashes []common.Hash) error {
+	p.Log().Debug("Fetching batch of transactions", "count", len(hashes))
+	return p2p.Send(p.rw, GetPooledTxsMsg, hashes)
+}
+
+// SendDoubleSignEvidences sends double sign evidences to the peer and includes
+// their hashes in its evidence hash set for future reference.
+func (p *peer) SendDoubleSignEvidences(evidences []*core.DoubleSignEvidence) error {
+	for _, evidence := range evidences {
+		p.MarkEvidence(evidence.Hash())
+	}
+	return p2p.Send(p.rw, DoubleSignMsg, evidences)
+}
+
+// SendDelegateProofs sends the proofs of the delegates running on the local node
+// to the peer.
+func (p *peer) SendDelegateProofs(proofs []*delegateProof) error {
+	return p2p.Send(p.rw, DelegateProofMsg, proofs)
+}
+
 // SendNewBlockHashes announces the availability of a number of blocks through
 // a hash notification.
 func (p *peer) SendNewBlockHashes(hashes []common.Hash, numbers []uint64) error {
@@ -309,23 +377,47 @@ func (p *peer) RequestReceipts(hashes []common.Hash) error {
 }
 
 // Handshake executes the em protocol handshake, negotiating version number,
-// network IDs, difficulties, head and genesis blocks.
-func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash) error {
+// network IDs, difficulties, head and genesis blocks, as well as the oldest
+// block whose body and receipts are served since aoa/23 and the fork identifier
+// since aoa/26, which forkFilter validates.
+func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash, tail uint64, forkID forkid.ID, forkFilter forkid.Filter) error {
 	// Send out own handshake in a new thread
 	errc := make(chan error, 2)
-	var status statusData // safe to read after two values have been received from errc
+	var status statusData06 // safe to read after two values have been received from errc
 
 	go func() {
-		errc <- p2p.Send(p.rw, StatusMsg, &statusData{
-			ProtocolVersion: uint32(p.version),
-			NetworkId:       network,
-			TD:              td,
-			CurrentBlock:    head,
-			GenesisBlock:    genesis,
-		})
+		switch {
+		case p.version < aoa03:
+			errc <- p2p.Send(p.rw, StatusMsg, &statusData{
+				ProtocolVersion: uint32(p.version),
+				NetworkId:       network,
+				TD:              td,
+				CurrentBlock:    head,
+				GenesisBlock:    genesis,
+			})
+		case p.version < aoa06:
+			errc <- p2p.Send(p.rw, StatusMsg, &statusData03{
+				ProtocolVersion: uint32(p.version),
+				NetworkId:       network,
+				TD:              td,
+				CurrentBlock:    head,
+				GenesisBlock:    genesis,
+				HistoryTail:     tail,
+			})
+		default:
+			errc <- p2p.Send(p.rw, StatusMsg, &statusData06{
+				ProtocolVersion: uint32(p.version),
+				NetworkId:       network,
+				TD:              td,
+				CurrentBlock:    head,
+				GenesisBlock:    genesis,
+				HistoryTail:     tail,
+				ForkID:          forkID,
+			})
+		}
 	}()
 	go func() {
-		errc <- p.readStatus(network, &status, genesis)
+		errc <- p.readStatus(network, &status, genesis, forkFilter)
 	}()
 	timeout := time.NewTimer(handshakeTimeout)
 	defer timeout.Stop()
@@ -339,11 +431,11 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 			return p2p.DiscReadTimeout
 		}
 	}
-	p.td, p.head = status.TD, status.CurrentBlock
+	p.td, p.head, p.tail = status.TD, status.CurrentBlock, status.HistoryTail
 	return nil
 }
 
-func (p *peer) readStatus(network uint64, status *statusData, genesis common.Hash) (err error) {
+func (p *peer) readStatus(network uint64, status *statusData06, genesis common.Hash, forkFilter forkid.Filter) (err error) {
 	msg, err := p.rw.ReadMsg()
 	if err != nil {
 		return err
@@ -355,8 +447,36 @@ func (p *peer) readStatus(network uint64, status *statusData, genesis common.Has
 		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
 	}
 	// Decode the handshake and make sure everything matches
-	if err := msg.Decode(&status); err != nil {
-		return errResp(ErrDecode, "msg %v: %v", msg, err)
+	switch {
+	case p.version < aoa03:
+		var legacy statusData
+		if err := msg.Decode(&legacy); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		*status = statusData06{
+			ProtocolVersion: legacy.ProtocolVersion,
+			NetworkId:       legacy.NetworkId,
+			TD:              legacy.TD,
+			CurrentBlock:    legacy.CurrentBlock,
+			GenesisBlock:    legacy.GenesisBlock,
+		}
+	case p.version < aoa06:
+		var legacy statusData03
+		if err := msg.Decode(&legacy); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		*status = statusData06{
+			ProtocolVersion: legacy.ProtocolVersion,
+			NetworkId:       legacy.NetworkId,
+			TD:              legacy.TD,
+			CurrentBlock:    legacy.CurrentBlock,
+			GenesisBlock:    legacy.GenesisBlock,
+			HistoryTail:     legacy.HistoryTail,
+		}
+	default:
+		if err := msg.Decode(status); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
 	}
 	if status.GenesisBlock != genesis {
 		return errResp(ErrGenesisBlockMismatch, "%x (!= %x)", status.GenesisBlock[:8], genesis[:8])
@@ -367,6 +487,11 @@ func (p *peer) readStatus(network uint64, status *statusData, genesis common.Has
 	if int(status.ProtocolVersion) != p.version {
 		return errResp(ErrProtocolVersionMismatch, "%d (!= %d)", status.ProtocolVersion, p.version)
 	}
+	if p.version >= aoa06 && forkFilter != nil {
+		if err := forkFilter(status.ForkID); err != nil {
+			return errResp(ErrForkIDRejected, "%v", err)
+		}
+	}
 	return nil
 }
 
@@ -437,6 +562,20 @@ func (ps *peerSet) Len() int {
 	return len(ps.peers)
 }
 
+// PriorityLen returns the number of priority peers in the set.
+func (ps *peerSet) PriorityLen() int {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	count := 0
+	for _, p := range ps.peers {
+		if p.Priority() {
+			count++
+		}
+	}
+	return count
+}
+
 // PeersWithoutBlock retrieves a list of peers that do not have a given block in
 // their set of known hashes.
 func (ps *peerSet) PeersWithoutBlock(hash common.Hash) []*peer {
@@ -451,6 +590,20 @@ func (ps *peerSet) PeersWithoutBlock(hash common.Hash) []*peer {
 	return list
 }
 
+// PeersWithoutEvidence retrieves a list of peers supporting double sign
+// evidences that do not have a given evidence in their set of known hashes.
+func (ps *peerSet) PeersWithoutEvidence(hash common.Hash) []*peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+	list := make([]*peer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		if p.version >= aoa05 && !p.knownEvidences.Has(hash) {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
 func (ps *peerSet) PeersWithoutPreBlock(hash common.Hash) []*peer {
 	ps.lock.RLock()
 	defer ps.lock.RUnlock()
@@ -0,0 +1,137 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/forkid"
+	"github.com/Aurorachain-io/go-aoa/p2p"
+	"github.com/Aurorachain-io/go-aoa/p2p/discover"
+)
+
+// Tests that the oldest block served is exchanged in the handshake since aoa/23,
+// and that older protocol versions still handshake without it.
+func TestHandshakeHistoryTail(t *testing.T) {
+	for _, tt := range []struct {
+		version  int
+		tailA    uint64
+		tailB    uint64
+		wantA    uint64
+		wantB    uint64
+		protocol string
+	}{
+		{aoa02, 100, 200, 0, 0, "aoa/22"},
+		{aoa03, 100, 200, 100, 200, "aoa/23"},
+		{aoa06, 100, 200, 100, 200, "aoa/26"},
+	} {
+		app, net := p2p.MsgPipe()
+		a := newPeer(tt.version, p2p.NewPeer(discover.NodeID{1}, "a", nil), app)
+		b := newPeer(tt.version, p2p.NewPeer(discover.NodeID{2}, "b", nil), net)
+
+		td, head, genesis := big.NewInt(1), common.Hash{1}, common.Hash{2}
+		errc := make(chan error, 2)
+		go func() { errc <- a.Handshake(DefaultConfig.NetworkId, td, head, genesis, tt.tailA, forkid.ID{}, nil) }()
+		go func() { errc <- b.Handshake(DefaultConfig.NetworkId, td, head, genesis, tt.tailB, forkid.ID{}, nil) }()
+		for i := 0; i < 2; i++ {
+			if err := <-errc; err != nil {
+				t.Fatalf("%s: handshake failed: %v", tt.protocol, err)
+			}
+		}
+		if tail := b.HistoryTail(); tail != tt.wantA {
+			t.Errorf("%s: advertised tail of a mismatch: have %d, want %d", tt.protocol, tail, tt.wantA)
+		}
+		if tail := a.HistoryTail(); tail != tt.wantB {
+			t.Errorf("%s: advertised tail of b mismatch: have %d, want %d", tt.protocol, tail, tt.wantB)
+		}
+		app.Close()
+		net.Close()
+	}
+}
+
+// Tests that peers advertising a fork identifier rejected by the local filter are
+// disconnected during the handshake since aoa/26.
+func TestHandshakeForkID(t *testing.T) {
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+	defer net.Close()
+
+	a := newPeer(aoa06, p2p.NewPeer(discover.NodeID{1}, "a", nil), app)
+	b := newPeer(aoa06, p2p.NewPeer(discover.NodeID{2}, "b", nil), net)
+
+	var (
+		td, head, genesis = big.NewInt(1), common.Hash{1}, common.Hash{2}
+		idA               = forkid.ID{Hash: [4]byte{1}}
+		idB               = forkid.ID{Hash: [4]byte{2}}
+		reject            = func(id forkid.ID) error {
+			if id != idB {
+				return forkid.ErrLocalIncompatibleOrStale
+			}
+			return nil
+		}
+	)
+	errA, errB := make(chan error, 1), make(chan error, 1)
+	go func() { errA <- a.Handshake(DefaultConfig.NetworkId, td, head, genesis, 0, idA, nil) }()
+	go func() { errB <- b.Handshake(DefaultConfig.NetworkId, td, head, genesis, 0, idB, reject) }()
+
+	if err := <-errA; err != nil {
+		t.Fatalf("accepting side failed: %v", err)
+	}
+	err := <-errB
+	if err == nil {
+		t.Fatalf("handshake succeeded with incompatible fork IDs")
+	}
+	if want := errCode(ErrForkIDRejected).String(); !strings.HasPrefix(err.Error(), want) {
+		t.Fatalf("handshake error mismatch: have %v, want %s", err, want)
+	}
+}
+
+// Tests that handshakes with nodes of another network, genesis or fork schedule
+// fail as incompatible, getting the remote node banned.
+func TestHandshakeIncompatible(t *testing.T) {
+	var (
+		td, head, genesis = big.NewInt(1), common.Hash{1}, common.Hash{2}
+		reject            = func(forkid.ID) error { return forkid.ErrLocalIncompatibleOrStale }
+	)
+	for _, tt := range []struct {
+		name         string
+		network      uint64
+		genesis      common.Hash
+		filter       forkid.Filter
+		incompatible bool
+	}{
+		{"compatible", DefaultConfig.NetworkId, genesis, nil, false},
+		{"network", DefaultConfig.NetworkId + 1, genesis, nil, true},
+		{"genesis", DefaultConfig.NetworkId, common.Hash{3}, nil, true},
+		{"fork", DefaultConfig.NetworkId, genesis, reject, true},
+	} {
+		app, net := p2p.MsgPipe()
+		a := newPeer(aoa06, p2p.NewPeer(discover.NodeID{1}, "a", nil), app)
+		b := newPeer(aoa06, p2p.NewPeer(discover.NodeID{2}, "b", nil), net)
+
+		go a.Handshake(tt.network, td, head, tt.genesis, 0, forkid.ID{}, nil)
+		err := b.Handshake(DefaultConfig.NetworkId, td, head, genesis, 0, forkid.ID{}, tt.filter)
+		if (err != nil) != tt.incompatible || incompatible(err) != tt.incompatible {
+			t.Errorf("%s: handshake error mismatch: have %v, want incompatible %v", tt.name, err, tt.incompatible)
+		}
+		app.Close()
+		net.Close()
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus/dpos"
+	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/log"
+)
+
+const (
+	// productionConfirms is the number of confirmation blocks before an epoch is
+	// considered final and its delegate production counters are stored.
+	productionConfirms = 256
+
+	// productionThrottling is the time to wait between processing two
+	// consecutive epochs, keeping the initial backfill from hogging the disk.
+	productionThrottling = 100 * time.Millisecond
+)
+
+// newDelegateRounds creates a calculator of the delegate rounds of the canonical
+// chain.
+func newDelegateRounds(chain *core.BlockChain) *dpos.Rounds {
+	return dpos.NewRounds(chain.Config(), chain.Genesis().Time().Int64(), func(number uint64) ([]types.Candidate, error) {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, dpos.ErrUnknownShuffleBlock
+		}
+		delegates, err := chain.DelegateStateAt(block.DelegateRoot())
+		if err != nil {
+			return nil, err
+		}
+		return delegates.GetDelegates(), nil
+	})
+}
+
+// ProductionIndexer implements a core.ChainIndexer, counting the block slots each
+// scheduled delegate produced a block in or missed within every epoch.
+type ProductionIndexer struct {
+	db     aoadb.Database        // database instance to read headers from and write counters into
+	rounds *dpos.Rounds          // calculator of the delegate rounds scheduling the slots
+	prod   *core.EpochProduction // counters of the epoch being processed currently
+}
+
+// NewProductionIndexer returns a chain indexer that generates the delegate
+// production counters of the canonical chain.
+func NewProductionIndexer(db aoadb.Database, chain *core.BlockChain) *core.ChainIndexer {
+	backend := &ProductionIndexer{db: db, rounds: newDelegateRounds(chain)}
+	table := aoadb.NewTable(db, string(core.ProductionIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, chain.Config().Epoch(), productionConfirms, productionThrottling, "production")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new epoch summary.
+func (p *ProductionIndexer) Reset(section uint64, lastSectionHead common.Hash) error {
+	p.prod = core.NewEpochProduction(section)
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, crediting the producer of a block
+// and charging the delegates scheduled in the slots skipped since its parent.
+func (p *ProductionIndexer) Process(header *types.Header) {
+	number := header.Number.Uint64()
+	if number == 0 {
+		return
+	}
+	p.prod.AddProduced(header.Coinbase)
+
+	parent := core.GetHeader(p.db, header.ParentHash, number-1)
+	if parent == nil {
+		return
+	}
+	missed, err := p.rounds.MissedSlots(parent, header)
+	if err != nil {
+		log.Debug("Failed to recompute delegate rounds", "block", number, "err", err)
+	}
+	for _, delegate := range missed {
+		p.prod.AddMissed(delegate)
+	}
+}
+
+// Commit implements core.ChainIndexerBackend, writing the finished epoch
+// counters into the database.
+func (p *ProductionIndexer) Commit() error {
+	return core.WriteEpochProduction(p.db, p.prod)
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus/dpos"
+	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// Tests that the production indexer credits block producers and charges the
+// delegates scheduled in the slots skipped between two blocks.
+func TestProductionIndexerProcess(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+
+	config := &params.ChainConfig{MaxElectDelegate: big.NewInt(3), BlockInterval: big.NewInt(10)}
+	candidates := func(uint64) ([]types.Candidate, error) {
+		return []types.Candidate{
+			{Address: common.Address{1}.Hex(), Vote: 3},
+			{Address: common.Address{2}.Hex(), Vote: 2},
+			{Address: common.Address{3}.Hex(), Vote: 1},
+		}, nil
+	}
+	var (
+		producer = common.Address{9}
+		genesis  = &types.Header{Number: big.NewInt(0), Time: big.NewInt(1000)}
+		first    = &types.Header{ParentHash: genesis.Hash(), Number: big.NewInt(1), Time: big.NewInt(1010), Coinbase: producer, ShuffleBlockNumber: big.NewInt(0)}
+		second   = &types.Header{ParentHash: first.Hash(), Number: big.NewInt(2), Time: big.NewInt(1040), Coinbase: producer, ShuffleBlockNumber: big.NewInt(0)}
+	)
+	for _, header := range []*types.Header{genesis, first, second} {
+		if err := core.WriteHeader(db, header); err != nil {
+			t.Fatalf("failed to write header #%d: %v", header.Number, err)
+		}
+	}
+	indexer := &ProductionIndexer{db: db, rounds: dpos.NewRounds(config, genesis.Time.Int64(), candidates)}
+	indexer.Reset(0, common.Hash{})
+	for _, header := range []*types.Header{genesis, first, second} {
+		indexer.Process(header)
+	}
+	// The second block skipped the slots at 1020 and 1030, belonging to two rounds
+	rounds := dpos.NewRounds(config, genesis.Time.Int64(), candidates)
+	missed := make(map[common.Address]uint64)
+	for _, slot := range []int64{1020, 1030} {
+		round, err := rounds.Round(0, rounds.Start(slot))
+		if err != nil {
+			t.Fatalf("failed to shuffle round: %v", err)
+		}
+		for _, delegate := range round.ShuffleDels {
+			if int64(delegate.WorkTime) == slot {
+				missed[common.HexToAddress(delegate.Address)]++
+			}
+		}
+	}
+	if len(missed) == 0 {
+		t.Fatalf("no delegates scheduled in the skipped slots")
+	}
+	if delegate := indexer.prod.Delegate(producer); delegate == nil || delegate.Produced != 2 || delegate.Missed != 0 {
+		t.Errorf("producer counters mismatch: have %v, want 2 produced", delegate)
+	}
+	for address, want := range missed {
+		if delegate := indexer.prod.Delegate(address); delegate == nil || delegate.Missed != want || delegate.Produced != 0 {
+			t.Errorf("delegate %x counters mismatch: have %v, want %d missed", address, delegate, want)
+		}
+	}
+	if have, want := len(indexer.prod.Delegates), len(missed)+1; have != want {
+		t.Errorf("counted delegate count mismatch: have %d, want %d", have, want)
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+)
+
+const (
+	// txRequestTimeout is the time after which an announced transaction requested
+	// from a peer may be requested again from another one announcing it.
+	txRequestTimeout = 5 * time.Second
+
+	// maxTxRequests is the maximum number of announced transactions being
+	// requested at once, not to be flooded with announcements.
+	maxTxRequests = 16384
+
+	// maxTxAnnounces is the maximum number of transaction hashes accepted in one
+	// announcement or request.
+	maxTxAnnounces = 4096
+)
+
+// Block propagation strategies, trading bandwidth for latency.
+const (
+	PropagateFull     = "full"     // Push blocks to all peers
+	PropagateSqrt     = "sqrt"     // Push blocks to a subset of all peers, announce to the rest
+	PropagateMesh     = "mesh"     // Push blocks to all delegate peers and a subset of the rest
+	PropagateAnnounce = "announce" // Only announce blocks, letting peers fetch them
+)
+
+// PropagationConfig are the configuration parameters of block propagation.
+type PropagationConfig struct {
+	Strategy string // Block propagation strategy (full, sqrt, mesh or announce)
+	Fanout   int    `toml:",omitempty"` // Number of peers the sqrt and mesh strategies push to (0 = square root of the peers)
+}
+
+// DefaultPropagationConfig pushes blocks, as well as newly produced blocks to be
+// signed, to a square root of the peers, starting with the delegates as they
+// need them the soonest. Imported blocks are announced to the rest, which
+// retrieve them on demand. Pushing to all delegates spikes their bandwidth at
+// every block interval.
+var DefaultPropagationConfig = PropagationConfig{
+	Strategy: PropagateSqrt,
+}
+
+// validate checks that the propagation parameters are sane.
+func (config *PropagationConfig) validate() error {
+	switch config.Strategy {
+	case PropagateFull, PropagateSqrt, PropagateMesh, PropagateAnnounce:
+	default:
+		return fmt.Errorf("invalid block propagation strategy %q", config.Strategy)
+	}
+	if config.Fanout < 0 {
+		return fmt.Errorf("invalid block propagation fan-out %d", config.Fanout)
+	}
+	return nil
+}
+
+// fanout returns the number of peers out of n a block is pushed to.
+func (config *PropagationConfig) fanout(n int) int {
+	if config.Fanout > 0 {
+		if config.Fanout < n {
+			return config.Fanout
+		}
+		return n
+	}
+	return int(math.Sqrt(float64(n)))
+}
+
+// selectPeers picks the peers, out of the delegate and other ones not knowing
+// about a block yet, to either push the block to or to announce it to.
+func (config *PropagationConfig) selectPeers(delegates, others []*peer, push bool) []*peer {
+	switch config.Strategy {
+	case PropagateFull:
+		return append(delegates, others...)
+
+	case PropagateSqrt:
+		all := append(delegates, others...)
+		if push {
+			return all[:config.fanout(len(all))]
+		}
+		return all
+
+	case PropagateAnnounce:
+		if push {
+			return nil
+		}
+		return append(delegates, others...)
+
+	default:
+		return append(delegates, others[:config.fanout(len(others))]...)
+	}
+}
+
+// selectPreBlockPeers picks the delegate peers, out of the ones not knowing
+// about a newly produced block yet, to push the block to for signing. Such
+// blocks can't be announced, but every delegate relays them to its own delegate
+// peers, so pushing to a subset still reaches all of them.
+func (config *PropagationConfig) selectPreBlockPeers(delegates []*peer) []*peer {
+	switch config.Strategy {
+	case PropagateFull, PropagateMesh:
+		return delegates
+	default:
+		return delegates[:config.fanout(len(delegates))]
+	}
+}
+
+// blockPeers retrieves the peers to push a block to, or to announce it to,
+// according to the block propagation strategy.
+func (pm *ProtocolManager) blockPeers(hash common.Hash, push bool) []*peer {
+	ps, delegatePeers := pm.peers, pm.delegatePeers
+
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+	delegatePeers.lock.RLock()
+	defer delegatePeers.lock.RUnlock()
+
+	delegates := make([]*peer, 0, len(delegatePeers.peers))
+	for _, p := range delegatePeers.peers {
+		if !p.knownBlocks.Has(hash) {
+			delegates = append(delegates, p)
+		}
+	}
+	others := make([]*peer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		if !p.knownBlocks.Has(hash) && delegatePeers.peers[p.id] == nil {
+			others = append(others, p)
+		}
+	}
+	return pm.propagation.selectPeers(delegates, others, push)
+}
+
+// txRequests tracks the announced transactions requested from peers, so each is
+// only pulled from one of the peers announcing it at a time.
+type txRequests struct {
+	requested map[common.Hash]time.Time // Request times of the transactions being requested
+	lock      sync.Mutex
+}
+
+func newTxRequests() *txRequests {
+	return &txRequests{requested: make(map[common.Hash]time.Time)}
+}
+
+// claim returns the given hashes which aren't being requested yet, or whose
+// request timed out, marking them as requested.
+func (r *txRequests) claim(hashes []common.Hash) []common.Hash {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	if len(r.requested)+len(hashes) > maxTxRequests {
+		for hash, requested := range r.requested {
+			if now.Sub(requested) > txRequestTimeout {
+				delete(r.requested, hash)
+			}
+		}
+	}
+	var claimed []common.Hash
+	for _, hash := range hashes {
+		if len(r.requested) >= maxTxRequests {
+			break
+		}
+		if requested, ok := r.requested[hash]; ok && now.Sub(requested) <= txRequestTimeout {
+			continue
+		}
+		r.requested[hash] = now
+		claimed = append(claimed, hash)
+	}
+	return claimed
+}
+
+// deliver marks the given transactions as no longer being requested.
+func (r *txRequests) deliver(txs []*types.Transaction) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, tx := range txs {
+		delete(r.requested, tx.Hash())
+	}
+}
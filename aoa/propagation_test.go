@@ -0,0 +1,117 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+)
+
+// Tests that the block propagation strategies pick the expected peers to push
+// blocks to and to announce them to.
+func TestPropagationPeers(t *testing.T) {
+	newPeers := func(prefix string, n int) []*peer {
+		peers := make([]*peer, n)
+		for i := range peers {
+			peers[i] = &peer{id: fmt.Sprintf("%s%d", prefix, i)}
+		}
+		return peers
+	}
+	tests := []struct {
+		config   PropagationConfig
+		push     int
+		announce int
+		preBlock int // Out of 16 delegates
+	}{
+		{PropagationConfig{Strategy: PropagateFull}, 19, 19, 16},
+		{PropagationConfig{Strategy: PropagateSqrt}, 4, 19, 4},
+		{PropagationConfig{Strategy: PropagateSqrt, Fanout: 7}, 7, 19, 7},
+		{PropagationConfig{Strategy: PropagateMesh}, 3 + 4, 3 + 4, 16},
+		{PropagationConfig{Strategy: PropagateMesh, Fanout: 10}, 3 + 10, 3 + 10, 16},
+		{PropagationConfig{Strategy: PropagateMesh, Fanout: 100}, 19, 19, 16},
+		{PropagationConfig{Strategy: PropagateAnnounce}, 0, 19, 4},
+	}
+	for i, tt := range tests {
+		if err := tt.config.validate(); err != nil {
+			t.Fatalf("test %d: invalid config: %v", i, err)
+		}
+		for _, push := range []bool{true, false} {
+			delegates, others := newPeers("delegate", 3), newPeers("other", 16)
+
+			want := tt.announce
+			if push {
+				want = tt.push
+			}
+			peers := tt.config.selectPeers(delegates, others, push)
+			if len(peers) != want {
+				t.Errorf("test %d, push %v: peer count mismatch: have %d, want %d", i, push, len(peers), want)
+			}
+			if tt.config.Strategy == PropagateMesh {
+				for j, delegate := range delegates {
+					if peers[j] != delegate {
+						t.Errorf("test %d, push %v: delegate %d not prioritised", i, push, j)
+					}
+				}
+			}
+		}
+	}
+	// Check that newly produced blocks reach the expected number of delegates
+	for i, tt := range tests {
+		if peers := tt.config.selectPreBlockPeers(newPeers("delegate", 16)); len(peers) != tt.preBlock {
+			t.Errorf("test %d: pre-block peer count mismatch: have %d, want %d", i, len(peers), tt.preBlock)
+		}
+	}
+	// Check that invalid configurations are rejected
+	for i, config := range []PropagationConfig{{Strategy: "gossip"}, {Strategy: PropagateSqrt, Fanout: -1}} {
+		if err := config.validate(); err == nil {
+			t.Errorf("invalid config %d accepted: %+v", i, config)
+		}
+	}
+}
+
+// Tests that announced transactions are only requested once until they are
+// delivered or their request times out.
+func TestTxRequestsClaim(t *testing.T) {
+	txs := make([]*types.Transaction, 3)
+	hashes := make([]common.Hash, len(txs))
+	for i := range txs {
+		txs[i] = types.NewTransaction(uint64(i), common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil, 0, nil, "")
+		hashes[i] = txs[i].Hash()
+	}
+	r := newTxRequests()
+	if claimed := r.claim(hashes[:2]); len(claimed) != 2 {
+		t.Fatalf("first claim mismatch: have %d, want %d", len(claimed), 2)
+	}
+	if claimed := r.claim(hashes); len(claimed) != 1 || claimed[0] != hashes[2] {
+		t.Fatalf("overlapping claim mismatch: have %x, want %x", claimed, hashes[2:])
+	}
+	// Delivered transactions may be requested again if announced anew
+	r.deliver(txs[:1])
+	if claimed := r.claim(hashes); len(claimed) != 1 || claimed[0] != hashes[0] {
+		t.Fatalf("delivered claim mismatch: have %x, want %x", claimed, hashes[:1])
+	}
+	// Timed out requests may be retried from another peer
+	r.requested[hashes[1]] = time.Now().Add(-2 * txRequestTimeout)
+	if claimed := r.claim(hashes); len(claimed) != 1 || claimed[0] != hashes[1] {
+		t.Fatalf("timed out claim mismatch: have %x, want %x", claimed, hashes[1:2])
+	}
+}
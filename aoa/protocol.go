@@ -24,6 +24,7 @@ import (
 	"bytes"
 	"github.com/Aurorachain-io/go-aoa/common"
 	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/forkid"
 	"github.com/Aurorachain-io/go-aoa/core/types"
 	"github.com/Aurorachain-io/go-aoa/event"
 	"github.com/Aurorachain-io/go-aoa/rlp"
@@ -33,16 +34,21 @@ import (
 const (
 	aoa01 = 21
 	aoa02 = 22
+	aoa03 = 23
+	aoa04 = 24
+	aoa05 = 25
+	aoa06 = 26
+	aoa07 = 27
 )
 
 // Official short name of the protocol used during capability negotiation.
 var ProtocolName = "aoa"
 
 // Supported versions of the em protocol (first is primary).
-var ProtocolVersions = []uint{aoa01, aoa02}
+var ProtocolVersions = []uint{aoa01, aoa02, aoa03, aoa04, aoa05, aoa06, aoa07}
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{17, 17}
+var ProtocolLengths = []uint64{17, 17, 17, 17, 18, 18, 19}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -61,11 +67,19 @@ const (
 	SignaturesBlockMsg = 0x08
 	// broadcast to delegate p2p network
 	PreBlockMsg = 0x09
+	// Protocol messages belonging to aoa/24
+	NewPooledTxHashesMsg = 0x0a
+	GetPooledTxsMsg      = 0x0b
+	PooledTxsMsg         = 0x0c
 	// Protocol messages belonging to em/63
 	GetNodeDataMsg = 0x0d
 	NodeDataMsg    = 0x0e
 	GetReceiptsMsg = 0x0f
 	ReceiptsMsg    = 0x10
+	// Protocol messages belonging to aoa/25
+	DoubleSignMsg = 0x11
+	// Protocol messages belonging to aoa/27
+	DelegateProofMsg = 0x12
 )
 
 type errCode int
@@ -80,6 +94,7 @@ const (
 	ErrNoStatusMsg
 	ErrExtraStatusMsg
 	ErrSuspendedPeer
+	ErrForkIDRejected
 )
 
 func (e errCode) String() string {
@@ -97,6 +112,7 @@ var errorToString = map[int]string{
 	ErrNoStatusMsg:             "No status message",
 	ErrExtraStatusMsg:          "Extra status message",
 	ErrSuspendedPeer:           "Suspended peer",
+	ErrForkIDRejected:          "Fork ID rejected",
 }
 
 type txPool interface {
@@ -107,6 +123,10 @@ type txPool interface {
 	// The slice should be modifiable by the caller.
 	Pending() (map[common.Address]types.Transactions, error)
 
+	// Get should return the pooled transaction with the given hash, or nil if
+	// the pool doesn't hold it.
+	Get(hash common.Hash) *types.Transaction
+
 	// SubscribeTxPreEvent should return an event subscription of
 	// TxPreEvent and send events to the given channel.
 	SubscribeTxPreEvent(chan<- core.TxPreEvent) event.Subscription
@@ -123,6 +143,29 @@ type statusData struct {
 	GenesisBlock    common.Hash
 }
 
+// statusData03 is the network packet for the status message since aoa/23, also
+// advertising the oldest block whose body and receipts the sender still serves.
+type statusData03 struct {
+	ProtocolVersion uint32
+	NetworkId       uint64
+	TD              *big.Int
+	CurrentBlock    common.Hash
+	GenesisBlock    common.Hash
+	HistoryTail     uint64
+}
+
+// statusData06 is the network packet for the status message since aoa/26, also
+// advertising the fork identifier of the sender's chain.
+type statusData06 struct {
+	ProtocolVersion uint32
+	NetworkId       uint64
+	TD              *big.Int
+	CurrentBlock    common.Hash
+	GenesisBlock    common.Hash
+	HistoryTail     uint64
+	ForkID          forkid.ID
+}
+
 // newBlockHashesData is the network packet for the block announcements.
 type newBlockHashesData []struct {
 	Hash   common.Hash // Hash of one particular block being announced
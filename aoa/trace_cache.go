@@ -0,0 +1,175 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"sync"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+// DefaultTraceCache is the number of transaction traces kept in memory by default.
+const DefaultTraceCache = 128
+
+var (
+	traceCacheHeadKey    = []byte("head")  // Key tracking the next disk slot to overwrite
+	traceCacheSlotPrefix = []byte("slot-") // Prefix of the slot -> cache key mappings
+)
+
+// traceCacheKey derives the cache key of a transaction trace. The hash of the
+// block containing the transaction is part of the key, so traces of a block
+// reorged out of the canonical chain are never served again.
+func traceCacheKey(hash common.Hash, blockHash common.Hash, config *TraceConfig) (common.Hash, error) {
+	var blob []byte
+	if config != nil {
+		var err error
+		if blob, err = json.Marshal(struct {
+			LogConfig interface{}
+			Tracer    *string
+		}{config.LogConfig, config.Tracer}); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	return crypto.Keccak256Hash(hash[:], blockHash[:], blob), nil
+}
+
+// traceCache is an LRU cache of serialized transaction traces. Traces evicted
+// from memory are spilled into a fixed size ring on disk, if one is configured.
+type traceCache struct {
+	memory  *simplelru.LRU // Recently requested traces, keyed by traceCacheKey
+	evicted []spilledTrace // Traces evicted from memory, waiting to be spilled
+	lock    sync.Mutex     // Protects the memory cache and the evicted traces
+
+	disk     aoadb.Database // Spillover table of evicted traces, nil if disabled
+	slots    uint64         // Number of traces retained on disk
+	head     uint64         // Next disk slot to overwrite (modulo slots)
+	diskLock sync.Mutex     // Serializes the writes into the disk ring
+}
+
+// spilledTrace is a trace evicted from memory.
+type spilledTrace struct {
+	key   common.Hash
+	trace json.RawMessage
+}
+
+// newTraceCache creates a trace cache holding memory traces in memory and up to
+// disk evicted ones in db. A nil cache is returned if caching is disabled.
+func newTraceCache(db aoadb.Database, memory int, disk int) *traceCache {
+	if memory <= 0 {
+		return nil
+	}
+	cache := new(traceCache)
+	if disk > 0 && db != nil {
+		cache.disk = aoadb.NewTable(db, string(core.TraceCachePrefix))
+		cache.slots = uint64(disk)
+		if blob, err := cache.disk.Get(traceCacheHeadKey); err == nil && len(blob) == 8 {
+			cache.head = binary.BigEndian.Uint64(blob)
+		}
+	}
+	cache.memory, _ = simplelru.NewLRU(memory, cache.evict)
+	return cache
+}
+
+// get retrieves a cached trace, falling back to the disk ring on a memory miss.
+func (c *traceCache) get(key common.Hash) (json.RawMessage, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.lock.Lock()
+	trace, ok := c.memory.Get(key)
+	c.lock.Unlock()
+	if ok {
+		return trace.(json.RawMessage), true
+	}
+	if c.disk == nil {
+		return nil, false
+	}
+	blob, err := c.disk.Get(key[:])
+	if err != nil {
+		return nil, false
+	}
+	// Promote the trace, it stays on disk too until its slot gets reused
+	c.add(key, json.RawMessage(blob))
+	return blob, true
+}
+
+// add inserts a serialized trace into the memory cache. Traces evicted by the
+// insertion are spilled to disk after the memory cache is released.
+func (c *traceCache) add(key common.Hash, trace json.RawMessage) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	c.memory.Add(key, trace)
+	evicted := c.evicted
+	c.evicted = nil
+	c.lock.Unlock()
+
+	c.spill(evicted)
+}
+
+// evict is the eviction callback of the memory cache, queueing the evicted
+// trace to be spilled to disk. It runs with the memory cache locked.
+func (c *traceCache) evict(key interface{}, value interface{}) {
+	if c.disk == nil {
+		return
+	}
+	c.evicted = append(c.evicted, spilledTrace{key.(common.Hash), value.(json.RawMessage)})
+}
+
+// spill moves the evicted traces into the next disk slots, dropping the traces
+// that occupied them before.
+func (c *traceCache) spill(traces []spilledTrace) {
+	if len(traces) == 0 {
+		return
+	}
+	c.diskLock.Lock()
+	defer c.diskLock.Unlock()
+
+	for _, spilled := range traces {
+		hash := spilled.key
+		if ok, _ := c.disk.Has(hash[:]); ok {
+			continue
+		}
+		slot := make([]byte, len(traceCacheSlotPrefix)+8)
+		copy(slot, traceCacheSlotPrefix)
+		binary.BigEndian.PutUint64(slot[len(traceCacheSlotPrefix):], c.head%c.slots)
+
+		batch := c.disk.NewBatch()
+		if old, err := c.disk.Get(slot); err == nil {
+			batch.Delete(old)
+		}
+		head := make([]byte, 8)
+		binary.BigEndian.PutUint64(head, c.head+1)
+
+		batch.Put(hash[:], spilled.trace)
+		batch.Put(slot, hash[:])
+		batch.Put(traceCacheHeadKey, head)
+		if err := batch.Write(); err != nil {
+			log.Warn("Failed to spill transaction trace", "hash", hash, "err", err)
+			return
+		}
+		c.head++
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+)
+
+// Tests that traces evicted from memory are spilled to disk, and that the disk
+// ring only retains the configured number of most recently evicted traces.
+func TestTraceCacheSpill(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+	cache := newTraceCache(db, 2, 2)
+
+	keys := make([]common.Hash, 5)
+	for i := range keys {
+		keys[i], _ = traceCacheKey(common.Hash{byte(i)}, common.Hash{}, nil)
+		cache.add(keys[i], json.RawMessage{byte('0' + i)})
+	}
+	// Traces 3 and 4 are in memory, 1 and 2 on disk and 0 is dropped
+	if _, ok := cache.get(keys[0]); ok {
+		t.Errorf("trace 0 retained after ring wraparound")
+	}
+	for i := 1; i < len(keys); i++ {
+		trace, ok := cache.get(keys[i])
+		if !ok {
+			t.Errorf("trace %d missing", i)
+			continue
+		}
+		if string(trace) != string(byte('0'+i)) {
+			t.Errorf("trace %d mismatch: have %s", i, trace)
+		}
+	}
+	// A reopened cache must continue the ring where the previous one stopped
+	if reopened := newTraceCache(db, 2, 2); reopened.head != cache.head {
+		t.Errorf("ring head mismatch: have %d, want %d", reopened.head, cache.head)
+	}
+}
+
+// blockingTraceDB is a database whose batch writes block until released.
+type blockingTraceDB struct {
+	*aoadb.MemDatabase
+	release chan struct{}
+}
+
+func (db *blockingTraceDB) NewBatch() aoadb.Batch {
+	return &blockingTraceBatch{db.MemDatabase.NewBatch(), db.release}
+}
+
+type blockingTraceBatch struct {
+	aoadb.Batch
+	release chan struct{}
+}
+
+func (b *blockingTraceBatch) Write() error {
+	<-b.release
+	return b.Batch.Write()
+}
+
+// Tests that traces in memory are served while evicted ones are being written
+// to disk.
+func TestTraceCacheSpillUnlocked(t *testing.T) {
+	mem, _ := aoadb.NewMemDatabase()
+	db := &blockingTraceDB{mem, make(chan struct{})}
+	cache := newTraceCache(db, 1, 2)
+
+	first, _ := traceCacheKey(common.Hash{1}, common.Hash{}, nil)
+	second, _ := traceCacheKey(common.Hash{2}, common.Hash{}, nil)
+	cache.add(first, json.RawMessage("1"))
+
+	done := make(chan struct{})
+	go func() {
+		cache.add(second, json.RawMessage("2"))
+		close(done)
+	}()
+	// Wait for the eviction of the first trace to block on the disk write
+	for {
+		cache.lock.Lock()
+		inserted := cache.memory.Contains(second)
+		cache.lock.Unlock()
+		if inserted {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	served := make(chan bool)
+	go func() {
+		_, ok := cache.get(second)
+		served <- ok
+	}()
+	select {
+	case ok := <-served:
+		if !ok {
+			t.Errorf("memory trace missing")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("memory trace blocked by disk write")
+	}
+	close(db.release)
+	<-done
+	if trace, ok := cache.get(first); !ok || string(trace) != "1" {
+		t.Errorf("spilled trace mismatch: have %s, %v", trace, ok)
+	}
+}
+
+// Tests that the trace cache key depends on the tracer config and the block
+// containing the transaction.
+func TestTraceCacheKey(t *testing.T) {
+	tracer := "callTracer"
+
+	base, _ := traceCacheKey(common.Hash{1}, common.Hash{2}, nil)
+	if key, _ := traceCacheKey(common.Hash{1}, common.Hash{3}, nil); key == base {
+		t.Errorf("key unchanged after reorg")
+	}
+	if key, _ := traceCacheKey(common.Hash{1}, common.Hash{2}, &TraceConfig{Tracer: &tracer}); key == base {
+		t.Errorf("key unchanged by tracer")
+	}
+	if newTraceCache(nil, 0, 0) != nil {
+		t.Errorf("disabled cache created")
+	}
+}
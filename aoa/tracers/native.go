@@ -0,0 +1,311 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/accounts/abi"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/common/hexutil"
+	"github.com/Aurorachain-io/go-aoa/core/vm"
+)
+
+// ResultTracer is a vm.Tracer assembling a JSON result of the traced execution,
+// which can be stopped before the execution finishes.
+type ResultTracer interface {
+	vm.Tracer
+	GetResult() (json.RawMessage, error)
+	Stop(err error)
+}
+
+// natives contains all the built in Go tracers by name. These take precedence
+// over the JavaScript tracers of the same name.
+var natives = map[string]func(prestate vm.StateDB) ResultTracer{
+	"callTracer":     newCallTracer,
+	"prestateTracer": newPrestateTracer,
+}
+
+// NewTracer instantiates the built in Go tracer with the given name, or else a
+// JavaScript tracer from the given code or name. The state given must not be
+// modified by the traced execution, i.e. be a copy of the state it starts from.
+func NewTracer(code string, prestate vm.StateDB) (ResultTracer, error) {
+	if constructor, ok := natives[code]; ok {
+		return constructor(prestate), nil
+	}
+	tracer, err := New(code)
+	if err != nil {
+		return nil, err
+	}
+	return tracer, nil
+}
+
+// interruptible implements stopping a native tracer, after which it ignores any
+// further events and reports the given error as its result.
+type interruptible struct {
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *interruptible) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// stopped reports whether the tracer was stopped.
+func (t *interruptible) stopped() bool {
+	return atomic.LoadUint32(&t.interrupt) > 0
+}
+
+// callFrame is a single call of a transaction, along with the calls it made.
+type callFrame struct {
+	Type         string         `json:"type"`
+	From         common.Address `json:"from"`
+	To           common.Address `json:"to"`
+	Value        *hexutil.Big   `json:"value,omitempty"`
+	Gas          hexutil.Uint64 `json:"gas"`
+	GasUsed      hexutil.Uint64 `json:"gasUsed"`
+	Input        hexutil.Bytes  `json:"input"`
+	Output       hexutil.Bytes  `json:"output,omitempty"`
+	Error        string         `json:"error,omitempty"`
+	RevertReason string         `json:"revertReason,omitempty"`
+	Calls        []callFrame    `json:"calls,omitempty"`
+}
+
+// newCallFrame creates a call frame from the parameters of a call.
+func newCallFrame(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) callFrame {
+	frame := callFrame{
+		Type:  typ.String(),
+		From:  from,
+		To:    to,
+		Gas:   hexutil.Uint64(gas),
+		Input: common.CopyBytes(input),
+	}
+	if value != nil {
+		frame.Value = (*hexutil.Big)(new(big.Int).Set(value))
+	}
+	return frame
+}
+
+// finish fills in the results of a call frame. The output of reverted calls is
+// kept, decoding the reason if one was given.
+func (f *callFrame) finish(output []byte, gasUsed uint64, err error) {
+	f.GasUsed = hexutil.Uint64(gasUsed)
+	if err == nil {
+		f.Output = common.CopyBytes(output)
+		return
+	}
+	f.Error = err.Error()
+	if err == vm.ErrExecutionReverted && len(output) > 0 {
+		f.Output = common.CopyBytes(output)
+		f.RevertReason, _ = abi.UnpackRevert(output)
+	}
+}
+
+// callTracer is a native tracer reporting the tree of calls made by a
+// transaction, along with their results. It's the Go equivalent of the
+// JavaScript callTracer, additionally reporting the reasons of reverts.
+type callTracer struct {
+	interruptible
+	callstack []callFrame
+}
+
+func newCallTracer(vm.StateDB) ResultTracer {
+	return &callTracer{callstack: make([]callFrame, 1)}
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *callTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	typ := vm.CALL
+	if create {
+		typ = vm.CREATE
+	}
+	t.callstack[0] = newCallFrame(typ, from, to, input, gas, value)
+	return nil
+}
+
+// CaptureState implements the Tracer interface, calls are traced per frame.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureFault implements the Tracer interface, faults are reported when the
+// failed call frame is exited.
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) error {
+	t.callstack[0].finish(output, gasUsed, err)
+	return nil
+}
+
+// CaptureEnter implements the Tracer interface to start tracing an inner call.
+func (t *callTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	if t.stopped() {
+		return nil
+	}
+	t.callstack = append(t.callstack, newCallFrame(typ, from, to, input, gas, value))
+	return nil
+}
+
+// CaptureExit implements the Tracer interface to finish tracing an inner call,
+// adding it to the calls of its parent.
+func (t *callTracer) CaptureExit(output []byte, gasUsed uint64, err error) error {
+	size := len(t.callstack)
+	if t.stopped() || size <= 1 {
+		return nil
+	}
+	frame := t.callstack[size-1]
+	frame.finish(output, gasUsed, err)
+
+	t.callstack = t.callstack[:size-1]
+	t.callstack[size-2].Calls = append(t.callstack[size-2].Calls, frame)
+	return nil
+}
+
+// GetResult returns the call tree of the transaction.
+func (t *callTracer) GetResult() (json.RawMessage, error) {
+	if t.stopped() {
+		return nil, t.reason
+	}
+	if len(t.callstack) != 1 {
+		return nil, errors.New("incorrect number of top-level calls")
+	}
+	return json.Marshal(t.callstack[0])
+}
+
+// prestateAccount is the state of an account before a transaction, limited to
+// the storage slots it accessed.
+type prestateAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   uint64                      `json:"nonce"`
+	Code    hexutil.Bytes               `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// prestateTracer is a native tracer reporting the state of all accounts and
+// storage slots a transaction accessed, as they were before its execution. It's
+// sufficient to execute the transaction again from a custom genesis block.
+type prestateTracer struct {
+	interruptible
+	prestate vm.StateDB
+	accessed map[common.Address]map[common.Hash]struct{} // Storage slots accessed per account
+}
+
+func newPrestateTracer(prestate vm.StateDB) ResultTracer {
+	return &prestateTracer{
+		prestate: prestate,
+		accessed: make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+// lookupAccount marks an account as accessed.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.accessed[addr]; !ok {
+		t.accessed[addr] = make(map[common.Hash]struct{})
+	}
+}
+
+// lookupStorage marks a storage slot of an account as accessed.
+func (t *prestateTracer) lookupStorage(addr common.Address, slot common.Hash) {
+	t.lookupAccount(addr)
+	t.accessed[addr][slot] = struct{}{}
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *prestateTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.lookupAccount(from)
+	t.lookupAccount(to)
+	return nil
+}
+
+// CaptureState implements the Tracer interface to record the state accessed by
+// a single step of VM execution.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.stopped() || len(stack.Data()) == 0 {
+		return nil
+	}
+	switch op {
+	case vm.SLOAD, vm.SSTORE:
+		t.lookupStorage(contract.Address(), common.BigToHash(stack.Back(0)))
+	case vm.BALANCE, vm.EXTCODESIZE, vm.EXTCODECOPY, vm.SELFDESTRUCT:
+		t.lookupAccount(common.BigToAddress(stack.Back(0)))
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface, the state accessed by failing
+// steps was already recorded by CaptureState.
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements the Tracer interface, the prestate is assembled once the
+// result is requested.
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) error {
+	return nil
+}
+
+// CaptureEnter implements the Tracer interface to record the accounts of an
+// inner call.
+func (t *prestateTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	if t.stopped() {
+		return nil
+	}
+	t.lookupAccount(from)
+	t.lookupAccount(to)
+	return nil
+}
+
+// CaptureExit implements the Tracer interface, nothing is accessed on exit.
+func (t *prestateTracer) CaptureExit(output []byte, gasUsed uint64, err error) error {
+	return nil
+}
+
+// GetResult returns the accessed accounts and storage slots which existed
+// before the transaction, omitting empty slots.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if t.stopped() {
+		return nil, t.reason
+	}
+	result := make(map[common.Address]*prestateAccount)
+	for addr, slots := range t.accessed {
+		if !t.prestate.Exist(addr) {
+			continue
+		}
+		account := &prestateAccount{
+			Balance: (*hexutil.Big)(t.prestate.GetBalance(addr)),
+			Nonce:   t.prestate.GetNonce(addr),
+			Code:    t.prestate.GetCode(addr),
+			Storage: make(map[common.Hash]common.Hash),
+		}
+		for slot := range slots {
+			if value := t.prestate.GetState(addr, slot); value != (common.Hash{}) {
+				account.Storage[slot] = value
+			}
+		}
+		result[addr] = account
+	}
+	return json.Marshal(result)
+}
@@ -0,0 +1,135 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/core/vm"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+var (
+	nativeCaller   = common.HexToAddress("0x0100")
+	nativeOuter    = common.HexToAddress("0x0200")
+	nativeReverter = common.HexToAddress("0x0300")
+)
+
+// revertCode returns contract code reverting with the given reason.
+func revertCode(reason string) []byte {
+	payload := append(append([]byte{}, crypto.Keccak256([]byte("Error(string)"))[:4]...), common.LeftPadBytes([]byte{0x20}, 32)...)
+	payload = append(payload, common.LeftPadBytes(big.NewInt(int64(len(reason))).Bytes(), 32)...)
+	payload = append(payload, common.RightPadBytes([]byte(reason), 32)...)
+
+	// PUSH1 size PUSH1 12 PUSH1 0 CODECOPY PUSH1 size PUSH1 0 REVERT
+	code := []byte{0x60, byte(len(payload)), 0x60, 12, 0x60, 0x00, 0x39, 0x60, byte(len(payload)), 0x60, 0x00, 0xfd}
+	return append(code, payload...)
+}
+
+// traceNative runs a call into a contract storing to slot 0 and calling into a
+// reverting contract with the named native tracer.
+func traceNative(t *testing.T, name string) json.RawMessage {
+	db, _ := aoadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	statedb.AddBalance(nativeCaller, big.NewInt(params.Em))
+	statedb.SetState(nativeOuter, common.Hash{}, common.BytesToHash([]byte{0x05}))
+	// PUSH1 1 PUSH1 0 SSTORE, then CALL reverter with all gas and no data
+	outer := []byte{0x60, 0x01, 0x60, 0x00, 0x55, 0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x73}
+	outer = append(append(outer, nativeReverter.Bytes()...), 0x5a, 0xf1, 0x00)
+	statedb.SetCode(nativeOuter, outer)
+	statedb.SetCode(nativeReverter, revertCode("boom"))
+	statedb.Finalise(true)
+
+	tracer, err := NewTracer(name, statedb.Copy())
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", name, err)
+	}
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Origin:      nativeCaller,
+		BlockNumber: big.NewInt(1),
+		Time:        big.NewInt(1),
+		Difficulty:  new(big.Int),
+		GasLimit:    1000000,
+		GasPrice:    big.NewInt(1),
+	}
+	evm := vm.NewEVM(context, statedb, params.AllDacchainProtocolChanges, vm.Config{Debug: true, Tracer: tracer})
+	if _, _, err := evm.Call(vm.AccountRef(nativeCaller), nativeOuter, nil, 100000, types.ActionCallContract, new(big.Int)); err != nil {
+		t.Fatalf("failed to execute call: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return res
+}
+
+// Tests that the native call tracer reports inner calls and revert reasons.
+func TestNativeCallTracer(t *testing.T) {
+	var frame callFrame
+	if err := json.Unmarshal(traceNative(t, "callTracer"), &frame); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	if frame.Type != "CALL" || frame.From != nativeCaller || frame.To != nativeOuter || frame.Error != "" {
+		t.Errorf("outer call mismatch: %+v", frame)
+	}
+	if len(frame.Calls) != 1 {
+		t.Fatalf("inner call count mismatch: have %d, want 1", len(frame.Calls))
+	}
+	inner := frame.Calls[0]
+	if inner.Type != "CALL" || inner.From != nativeOuter || inner.To != nativeReverter {
+		t.Errorf("inner call mismatch: %+v", inner)
+	}
+	if inner.Error != vm.ErrExecutionReverted.Error() || inner.RevertReason != "boom" {
+		t.Errorf("inner revert mismatch: error %q, reason %q", inner.Error, inner.RevertReason)
+	}
+	if inner.GasUsed == 0 || inner.GasUsed > frame.GasUsed {
+		t.Errorf("inner gas used mismatch: have %d, outer %d", inner.GasUsed, frame.GasUsed)
+	}
+}
+
+// Tests that the native prestate tracer reports the accessed state as it was
+// before the execution.
+func TestNativePrestateTracer(t *testing.T) {
+	var prestate map[common.Address]*prestateAccount
+	if err := json.Unmarshal(traceNative(t, "prestateTracer"), &prestate); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	if len(prestate) != 3 {
+		t.Errorf("account count mismatch: have %d, want 3", len(prestate))
+	}
+	if account := prestate[nativeCaller]; account == nil || account.Balance.ToInt().Cmp(big.NewInt(params.Em)) != 0 {
+		t.Errorf("caller prestate mismatch: %+v", account)
+	}
+	account := prestate[nativeOuter]
+	if account == nil {
+		t.Fatalf("outer contract missing from prestate")
+	}
+	if have, want := account.Storage[common.Hash{}], common.BytesToHash([]byte{0x05}); have != want {
+		t.Errorf("storage prestate mismatch: have %x, want %x", have, want)
+	}
+}
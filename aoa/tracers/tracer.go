@@ -274,6 +274,94 @@ func (cw *contractWrapper) pushObject(vm *duktape.Context) {
 	vm.PutPropString(obj, "getInput")
 }
 
+// frameWrapper provides a JavaScript wrapper around a call frame being entered.
+type frameWrapper struct {
+	typ   vm.OpCode
+	from  common.Address
+	to    common.Address
+	input []byte
+	gas   uint64
+	value *big.Int
+}
+
+// pushObject assembles a JSVM object wrapping a swappable call frame and pushes
+// it onto the VM stack.
+func (fw *frameWrapper) pushObject(vm *duktape.Context) {
+	obj := vm.PushObject()
+
+	vm.PushGoFunction(func(ctx *duktape.Context) int { ctx.PushString(fw.typ.String()); return 1 })
+	vm.PutPropString(obj, "getType")
+
+	vm.PushGoFunction(func(ctx *duktape.Context) int {
+		ptr := ctx.PushFixedBuffer(20)
+		copy(makeSlice(ptr, 20), fw.from[:])
+		return 1
+	})
+	vm.PutPropString(obj, "getFrom")
+
+	vm.PushGoFunction(func(ctx *duktape.Context) int {
+		ptr := ctx.PushFixedBuffer(20)
+		copy(makeSlice(ptr, 20), fw.to[:])
+		return 1
+	})
+	vm.PutPropString(obj, "getTo")
+
+	vm.PushGoFunction(func(ctx *duktape.Context) int {
+		ptr := ctx.PushFixedBuffer(len(fw.input))
+		copy(makeSlice(ptr, uint(len(fw.input))), fw.input)
+		return 1
+	})
+	vm.PutPropString(obj, "getInput")
+
+	vm.PushGoFunction(func(ctx *duktape.Context) int { ctx.PushUint(uint(fw.gas)); return 1 })
+	vm.PutPropString(obj, "getGas")
+
+	// Delegate calls carry no value of their own
+	vm.PushGoFunction(func(ctx *duktape.Context) int {
+		if fw.value == nil {
+			ctx.PushUndefined()
+		} else {
+			pushBigInt(fw.value, ctx)
+		}
+		return 1
+	})
+	vm.PutPropString(obj, "getValue")
+}
+
+// frameResultWrapper provides a JavaScript wrapper around the result of a call
+// frame being exited.
+type frameResultWrapper struct {
+	output  []byte
+	gasUsed uint64
+	err     error
+}
+
+// pushObject assembles a JSVM object wrapping a swappable call frame result and
+// pushes it onto the VM stack.
+func (rw *frameResultWrapper) pushObject(vm *duktape.Context) {
+	obj := vm.PushObject()
+
+	vm.PushGoFunction(func(ctx *duktape.Context) int {
+		ptr := ctx.PushFixedBuffer(len(rw.output))
+		copy(makeSlice(ptr, uint(len(rw.output))), rw.output)
+		return 1
+	})
+	vm.PutPropString(obj, "getOutput")
+
+	vm.PushGoFunction(func(ctx *duktape.Context) int { ctx.PushUint(uint(rw.gasUsed)); return 1 })
+	vm.PutPropString(obj, "getGasUsed")
+
+	vm.PushGoFunction(func(ctx *duktape.Context) int {
+		if rw.err != nil {
+			ctx.PushString(rw.err.Error())
+		} else {
+			ctx.PushUndefined()
+		}
+		return 1
+	})
+	vm.PutPropString(obj, "getError")
+}
+
 // Tracer provides an implementation of Tracer that evaluates a Javascript
 // function for each VM execution step.
 type Tracer struct {
@@ -290,6 +378,10 @@ type Tracer struct {
 	contractWrapper *contractWrapper // Wrapper around the contract object
 	dbWrapper       *dbWrapper       // Wrapper around the VM environment
 
+	traceCallFrames    bool                // Whether the tracer exposes enter() and exit()
+	frameWrapper       *frameWrapper       // Wrapper around the call frame being entered
+	frameResultWrapper *frameResultWrapper // Wrapper around the call frame being exited
+
 	pcValue    *uint   // Swappable pc value wrapped by a log accessor
 	gasValue   *uint   // Swappable gas value wrapped by a log accessor
 	costValue  *uint   // Swappable cost value wrapped by a log accessor
@@ -299,30 +391,36 @@ type Tracer struct {
 	ctx map[string]interface{} // Transaction context gathered throughout execution
 	err error                  // Error, if one has occurred
 
+	precompiles map[common.Address]vm.PrecompiledContract // Pre-compiled contracts active in the traced EVM
+
 	interrupt uint32 // Atomic flag to signal execution interruption
 	reason    error  // Textual reason for the interruption
 }
 
 // New instantiates a new tracer instance. code specifies a Javascript snippet,
 // which must evaluate to an expression returning an object with 'step', 'fault'
-// and 'result' functions.
+// and 'result' functions. The object may optionally expose 'enter' and 'exit'
+// functions too, which get invoked on entering and exiting inner call frames.
 func New(code string) (*Tracer, error) {
 	// Resolve any tracers by name and assemble the tracer object
 	if tracer, ok := tracer(code); ok {
 		code = tracer
 	}
 	tracer := &Tracer{
-		vm:              duktape.New(),
-		ctx:             make(map[string]interface{}),
-		opWrapper:       new(opWrapper),
-		stackWrapper:    new(stackWrapper),
-		memoryWrapper:   new(memoryWrapper),
-		contractWrapper: new(contractWrapper),
-		dbWrapper:       new(dbWrapper),
-		pcValue:         new(uint),
-		gasValue:        new(uint),
-		costValue:       new(uint),
-		depthValue:      new(uint),
+		vm:                 duktape.New(),
+		ctx:                make(map[string]interface{}),
+		precompiles:        vm.PrecompiledContracts,
+		opWrapper:          new(opWrapper),
+		stackWrapper:       new(stackWrapper),
+		memoryWrapper:      new(memoryWrapper),
+		contractWrapper:    new(contractWrapper),
+		dbWrapper:          new(dbWrapper),
+		frameWrapper:       new(frameWrapper),
+		frameResultWrapper: new(frameResultWrapper),
+		pcValue:            new(uint),
+		gasValue:           new(uint),
+		costValue:          new(uint),
+		depthValue:         new(uint),
 	}
 	// Set up builtins for this environment
 	tracer.vm.PushGlobalGoFunction("toHex", func(ctx *duktape.Context) int {
@@ -366,7 +464,7 @@ func New(code string) (*Tracer, error) {
 		return 1
 	})
 	tracer.vm.PushGlobalGoFunction("isPrecompiled", func(ctx *duktape.Context) int {
-		_, ok := vm.PrecompiledContracts[common.BytesToAddress(popSlice(ctx))]
+		_, ok := tracer.precompiles[common.BytesToAddress(popSlice(ctx))]
 		ctx.PushBoolean(ok)
 		return 1
 	})
@@ -409,6 +507,16 @@ func New(code string) (*Tracer, error) {
 	}
 	tracer.vm.Pop()
 
+	hasEnter := tracer.vm.GetPropString(tracer.tracerObject, "enter")
+	tracer.vm.Pop()
+	hasExit := tracer.vm.GetPropString(tracer.tracerObject, "exit")
+	tracer.vm.Pop()
+
+	if hasEnter != hasExit {
+		return nil, fmt.Errorf("Trace object must expose either both or none of enter() and exit()")
+	}
+	tracer.traceCallFrames = hasEnter
+
 	// Tracer is valid, inject the big int library to access large numbers
 	tracer.vm.EvalString(bigIntegerJS)
 	tracer.vm.PutGlobalString("bigInt")
@@ -457,6 +565,12 @@ func New(code string) (*Tracer, error) {
 	tracer.dbWrapper.pushObject(tracer.vm)
 	tracer.vm.PutPropString(tracer.stateObject, "db")
 
+	tracer.frameWrapper.pushObject(tracer.vm)
+	tracer.vm.PutPropString(tracer.stateObject, "frame")
+
+	tracer.frameResultWrapper.pushObject(tracer.vm)
+	tracer.vm.PutPropString(tracer.stateObject, "frameResult")
+
 	return tracer, nil
 }
 
@@ -515,6 +629,7 @@ func (jst *Tracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost
 		// Initialize the context if it wasn't done yet
 		if !jst.inited {
 			jst.ctx["block"] = env.BlockNumber.Uint64()
+			jst.precompiles = env.Precompiles()
 			jst.inited = true
 		}
 		// If tracing was interrupted, set the error and stop
@@ -574,6 +689,44 @@ func (jst *Tracer) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, er
 	return nil
 }
 
+// CaptureEnter is called when the EVM enters a new inner call frame, invoking
+// the tracer's 'enter' function if it has one.
+func (jst *Tracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	if !jst.traceCallFrames || jst.err != nil {
+		return nil
+	}
+	// If tracing was interrupted, set the error and stop
+	if atomic.LoadUint32(&jst.interrupt) > 0 {
+		jst.err = jst.reason
+		return nil
+	}
+	*jst.frameWrapper = frameWrapper{typ: typ, from: from, to: to, input: input, gas: gas, value: value}
+
+	if _, err := jst.call("enter", "frame"); err != nil {
+		jst.err = wrapError("enter", err)
+	}
+	return nil
+}
+
+// CaptureExit is called when the EVM exits an inner call frame, invoking the
+// tracer's 'exit' function if it has one.
+func (jst *Tracer) CaptureExit(output []byte, gasUsed uint64, err error) error {
+	if !jst.traceCallFrames || jst.err != nil {
+		return nil
+	}
+	// If tracing was interrupted, set the error and stop
+	if atomic.LoadUint32(&jst.interrupt) > 0 {
+		jst.err = jst.reason
+		return nil
+	}
+	*jst.frameResultWrapper = frameResultWrapper{output: output, gasUsed: gasUsed, err: err}
+
+	if _, err := jst.call("exit", "frameResult"); err != nil {
+		jst.err = wrapError("exit", err)
+	}
+	return nil
+}
+
 // GetResult calls the Javascript 'result' function and returns its value, or any accumulated error
 func (jst *Tracer) GetResult() (json.RawMessage, error) {
 	// Transform the context into a JavaScript object and inject into the state
@@ -138,3 +138,28 @@ func TestHaltBetweenSteps(t *testing.T) {
 		t.Errorf("Expected timeout error, got %v", err)
 	}
 }
+
+func TestEnterExit(t *testing.T) {
+	// Tracers must expose either both or none of enter and exit
+	if _, err := New("{step: function() {}, fault: function() {}, result: function() { return null; }, enter: function() {}}"); err == nil {
+		t.Fatal("tracer creation should've failed without exit()")
+	}
+	tracer, err := New("{frames: [], step: function() {}, fault: function() {}, result: function() { return this.frames; }, enter: function(frame) { this.frames.push(frame.getType() + ' ' + toHex(frame.getTo()) + ' ' + frame.getGas() + ' ' + frame.getValue()); }, exit: function(res) { this.frames.push(res.getGasUsed() + ' ' + toHex(res.getOutput()) + ' ' + res.getError()); }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := common.HexToAddress("0x01")
+	tracer.CaptureEnter(vm.CALL, common.Address{}, to, nil, 1000, big.NewInt(7))
+	tracer.CaptureExit([]byte{0xff}, 400, errors.New("boom"))
+	tracer.CaptureEnter(vm.DELEGATECALL, common.Address{}, to, nil, 500, nil)
+	tracer.CaptureExit(nil, 100, nil)
+
+	ret, err := tracer.GetResult()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `["CALL 0x0000000000000000000000000000000000000001 1000 7","400 0xff boom","DELEGATECALL 0x0000000000000000000000000000000000000001 500 undefined","100 0x undefined"]`
+	if string(ret) != want {
+		t.Errorf("trace result mismatch: have %s, want %s", ret, want)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoa
+
+import (
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+const (
+	// utilizationConfirms is the number of confirmation blocks before an epoch is
+	// considered final and its utilization histograms are stored.
+	utilizationConfirms = 256
+
+	// utilizationThrottling is the time to wait between processing two
+	// consecutive epochs, keeping the initial backfill from hogging the disk.
+	utilizationThrottling = 100 * time.Millisecond
+)
+
+// UtilizationIndexer implements a core.ChainIndexer, aggregating the gas used,
+// transaction count and gas limit utilization of every canonical block into
+// per-epoch histograms.
+type UtilizationIndexer struct {
+	db   aoadb.Database         // database instance to read bodies from and write summaries into
+	util *core.EpochUtilization // summary of the epoch being processed currently
+}
+
+// NewUtilizationIndexer returns a chain indexer that generates the epoch
+// utilization histograms of the canonical chain.
+func NewUtilizationIndexer(db aoadb.Database, config *params.ChainConfig) *core.ChainIndexer {
+	backend := &UtilizationIndexer{db: db}
+	table := aoadb.NewTable(db, string(core.UtilizationIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, config.Epoch(), utilizationConfirms, utilizationThrottling, "utilization")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new epoch summary.
+func (u *UtilizationIndexer) Reset(section uint64, lastSectionHead common.Hash) error {
+	u.util = core.NewEpochUtilization(section)
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding a new block into the
+// epoch summary.
+func (u *UtilizationIndexer) Process(header *types.Header) {
+	txs := 0
+	if body := core.GetBody(u.db, header.Hash(), header.Number.Uint64()); body != nil {
+		txs = len(body.Transactions)
+	}
+	u.util.Add(header.GasUsed, header.GasLimit, txs)
+}
+
+// Commit implements core.ChainIndexerBackend, writing the finished epoch
+// summary into the database.
+func (u *UtilizationIndexer) Commit() error {
+	return core.WriteEpochUtilization(u.db, u.util)
+}
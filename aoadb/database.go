@@ -35,9 +35,14 @@ import (
 
 var OpenFileLimit = 64
 
+// RecoverCorrupted sets whether databases reported corrupted on open are repaired
+// automatically, possibly losing their most recent writes, instead of refused.
+var RecoverCorrupted = false
+
 type LDBDatabase struct {
-	fn string      // filename for reporting
-	db *leveldb.DB // LevelDB instance
+	fn        string      // filename for reporting
+	db        *leveldb.DB // LevelDB instance
+	recovered bool        // Whether the database was repaired after a corruption
 
 	getTimer       gometrics.Timer // Timer for measuring the database get request counts and latencies
 	putTimer       gometrics.Timer // Timer for measuring the database put request counts and latencies
@@ -75,17 +80,27 @@ func NewLDBDatabase(file string, cache int, handles int) (*LDBDatabase, error) {
 		WriteBuffer:            cache / 4 * opt.MiB, // Two of these are used internally
 		Filter:                 filter.NewBloomFilter(10),
 	})
+	recovered := false
 	if _, corrupted := err.(*errors.ErrCorrupted); corrupted {
-		db, err = leveldb.RecoverFile(file, nil)
+		if !RecoverCorrupted {
+			logger.Error("Database corrupted, enable automatic recovery to repair it", "err", err)
+			return nil, err
+		}
+		logger.Warn("Database corrupted, attempting recovery", "err", err)
+		if db, err = leveldb.RecoverFile(file, nil); err == nil {
+			logger.Warn("Database recovered, recent writes may have been lost")
+			recovered = true
+		}
 	}
 	// (Re)check for errors and abort if opening of the db failed
 	if err != nil {
 		return nil, err
 	}
 	return &LDBDatabase{
-		fn:  file,
-		db:  db,
-		log: logger,
+		fn:        file,
+		db:        db,
+		recovered: recovered,
+		log:       logger,
 	}, nil
 }
 
@@ -94,6 +109,12 @@ func (db *LDBDatabase) Path() string {
 	return db.fn
 }
 
+// Recovered reports whether the database was repaired after being reported
+// corrupted on open, in which case its content should be verified.
+func (db *LDBDatabase) Recovered() bool {
+	return db.recovered
+}
+
 // Put puts the given key / value to the queue
 func (db *LDBDatabase) Put(key []byte, value []byte) error {
 	// Measure the database put latency, if requested
@@ -288,6 +309,12 @@ func (b *ldbBatch) Put(key, value []byte) error {
 	return nil
 }
 
+func (b *ldbBatch) Delete(key []byte) error {
+	b.b.Delete(key)
+	b.size += 1
+	return nil
+}
+
 func (b *ldbBatch) Write() error {
 	return b.db.Write(b.b, nil)
 }
@@ -348,6 +375,10 @@ func (tb *tableBatch) Put(key, value []byte) error {
 	return tb.batch.Put(append([]byte(tb.prefix), key...), value)
 }
 
+func (tb *tableBatch) Delete(key []byte) error {
+	return tb.batch.Delete(append([]byte(tb.prefix), key...))
+}
+
 func (tb *tableBatch) Write() error {
 	return tb.batch.Write()
 }
@@ -0,0 +1,59 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoadb
+
+// OverlayDatabase is a database writing into an upper database, while reading
+// from both the upper and a lower one, the latter never being modified. Deleting
+// only removes entries from the upper database.
+type OverlayDatabase struct {
+	upper Database
+	lower Database
+}
+
+// NewOverlayDatabase creates a database layering upper on top of lower.
+func NewOverlayDatabase(upper, lower Database) *OverlayDatabase {
+	return &OverlayDatabase{upper: upper, lower: lower}
+}
+
+func (db *OverlayDatabase) Put(key []byte, value []byte) error {
+	return db.upper.Put(key, value)
+}
+
+func (db *OverlayDatabase) Get(key []byte) ([]byte, error) {
+	if value, err := db.upper.Get(key); err == nil {
+		return value, nil
+	}
+	return db.lower.Get(key)
+}
+
+func (db *OverlayDatabase) Has(key []byte) (bool, error) {
+	if has, err := db.upper.Has(key); err != nil || has {
+		return has, err
+	}
+	return db.lower.Has(key)
+}
+
+func (db *OverlayDatabase) Delete(key []byte) error {
+	return db.upper.Delete(key)
+}
+
+// Close closes neither of the layered databases, they're owned by the caller.
+func (db *OverlayDatabase) Close() {}
+
+func (db *OverlayDatabase) NewBatch() Batch {
+	return db.upper.NewBatch()
+}
@@ -238,6 +238,8 @@ func buildFlags(env build.Environment) (flags []string) {
 	var ld []string
 	if env.Commit != "" {
 		ld = append(ld, "-X", "main.gitCommit="+env.Commit)
+		ld = append(ld, "-X", "github.com/Aurorachain-io/go-aoa/params.GitCommit="+env.Commit)
+		ld = append(ld, "-X", "github.com/Aurorachain-io/go-aoa/params.GitDate="+env.Date)
 	}
 	if runtime.GOOS == "darwin" {
 		ld = append(ld, "-s")
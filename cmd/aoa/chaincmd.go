@@ -19,19 +19,22 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/Aurorachain-io/go-aoa/aoa"
 	"github.com/Aurorachain-io/go-aoa/cmd/utils"
 	"github.com/Aurorachain-io/go-aoa/common"
 	"github.com/Aurorachain-io/go-aoa/console"
 	"github.com/Aurorachain-io/go-aoa/core"
-	"github.com/Aurorachain-io/go-aoa/core/state"
 	"github.com/Aurorachain-io/go-aoa/core/types"
 	"github.com/Aurorachain-io/go-aoa/aoa/downloader"
 	"github.com/Aurorachain-io/go-aoa/aoadb"
 	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/params"
 	"github.com/Aurorachain-io/go-aoa/trie"
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"gopkg.in/urfave/cli.v1"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"sync/atomic"
@@ -104,6 +107,29 @@ if already existing.`,
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 The first argument must be the directory containing the blockchain to download from`,
+	}
+	replayCommand = cli.Command{
+		Action:    utils.MigrateFlags(replay),
+		Name:      "replay",
+		Usage:     "Replay canonical blocks on a shadow fork, reporting diverging results",
+		ArgsUsage: "<sourceChaindataDir> [<firstBlockNum> [<lastBlockNum>]]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.ReplayConfigFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Executes the canonical blocks of the chain in the given chaindata folder again
+with the rules of this build, e.g. to validate a fork or gas table change before
+its activation. The source chain is never modified, the shadow fork resulting
+from the replay is kept in the database of the datadir, which must differ from
+the source one.
+
+The state of the block preceding the first one replayed must be available in the
+source chain. By default all blocks after the genesis up to the head are replayed.
+The state roots, delegate roots, gas used and receipts of every block are compared
+to the canonical ones, and all differences are reported.`,
 	}
 	removedbCommand = cli.Command{
 		Action:    utils.MigrateFlags(removeDB),
@@ -131,6 +157,21 @@ Remove blockchain and state databases`,
 The arguments are interpreted as block numbers or hashes.
 Use "eminer dump 0" to dump the genesis block.`,
 	}
+	exportGenesisCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportGenesis),
+		Name:      "exportgenesis",
+		Usage:     "Export the state of a block into a new genesis file",
+		ArgsUsage: "<filename> [<blockHash> | <blockNum>]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Writes a genesis JSON file recreating the accounts and the delegate set of the
+given block, or of the current head block if none is given. Initializing a new
+data directory with it starts a fresh chain from that state.`,
+	}
 )
 
 // initGenesis will initialise the given JSON format genesis file and writes it as
@@ -330,6 +371,79 @@ func copyDb(ctx *cli.Context) error {
 	return nil
 }
 
+// replay executes the canonical blocks of a source chain on a shadow fork,
+// reporting all blocks whose results differ from the canonical ones.
+func replay(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 || len(ctx.Args()) > 3 {
+		utils.Fatalf("Source chaindata directory path argument missing")
+	}
+	stack, _ := makeConfigNode(ctx)
+	sourceDir, _ := filepath.Abs(ctx.Args().First())
+	if sourceDir == stack.ResolvePath("chaindata") {
+		utils.Fatalf("Shadow fork must be kept in a separate datadir")
+	}
+	source, err := aoadb.NewLDBDatabase(sourceDir, ctx.GlobalInt(utils.CacheFlag.Name), 256)
+	if err != nil {
+		utils.Fatalf("Could not open source database: %v", err)
+	}
+	defer source.Close()
+
+	shadow, _ := utils.MakeChainDatabase(ctx, stack)
+	defer shadow.Close()
+
+	var config *params.ChainConfig
+	if file := ctx.GlobalString(utils.ReplayConfigFlag.Name); file != "" {
+		blob, err := ioutil.ReadFile(file)
+		if err != nil {
+			utils.Fatalf("Could not read chain config: %v", err)
+		}
+		config = new(params.ChainConfig)
+		if err := json.Unmarshal(blob, config); err != nil {
+			utils.Fatalf("Invalid chain config: %v", err)
+		}
+	}
+	// Replay all blocks after the genesis up to the source head by default
+	first, last := uint64(1), core.GetBlockNumber(source, core.GetHeadBlockHash(source))
+	if len(ctx.Args()) > 1 {
+		if first, err = strconv.ParseUint(ctx.Args().Get(1), 10, 64); err != nil || first == 0 {
+			utils.Fatalf("Invalid first block number: %s", ctx.Args().Get(1))
+		}
+	}
+	if len(ctx.Args()) > 2 {
+		if last, err = strconv.ParseUint(ctx.Args().Get(2), 10, 64); err != nil {
+			utils.Fatalf("Invalid last block number: %s", ctx.Args().Get(2))
+		}
+	}
+	if last < first {
+		utils.Fatalf("Last block #%d precedes first block #%d", last, first)
+	}
+	replayer, err := core.NewShadowReplayer(source, shadow, config, aoa.CreateDacchainConsensusEngine(), first-1)
+	if err != nil {
+		utils.Fatalf("Could not start replay: %v", err)
+	}
+	var (
+		start    = time.Now()
+		report   = time.Now()
+		diverged int
+	)
+	for number := first; number <= last; number++ {
+		result, err := replayer.Next()
+		if err != nil {
+			utils.Fatalf("Replay failed: %v", err)
+		}
+		if result.Diverged() {
+			diverged++
+			fmt.Println(result)
+		}
+		if time.Since(report) > 8*time.Second {
+			log.Info("Replaying blocks", "number", number, "last", last, "diverged", diverged, "elapsed", common.PrettyDuration(time.Since(start)))
+			report = time.Now()
+		}
+	}
+	fmt.Printf("Replayed %d blocks in %v, %d diverged\n", last-first+1, time.Since(start), diverged)
+	return nil
+}
+
 func removeDB(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
 
@@ -374,7 +488,7 @@ func dump(ctx *cli.Context) error {
 			fmt.Println("{}")
 			utils.Fatalf("block not found")
 		} else {
-			stateDB, err := state.New(block.Root(), state.NewDatabase(chainDb))
+			stateDB, err := chain.StateAtHeader(block.Header())
 			if err != nil {
 				utils.Fatalf("could not create new stateDB: %v", err)
 			}
@@ -385,6 +499,50 @@ func dump(ctx *cli.Context) error {
 	return nil
 }
 
+// exportGenesis writes the state of a block into a new genesis file.
+func exportGenesis(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	block := chain.CurrentBlock()
+	if arg := ctx.Args().Get(1); arg != "" {
+		if hashish(arg) {
+			block = chain.GetBlockByHash(common.HexToHash(arg))
+		} else {
+			num, _ := strconv.ParseUint(arg, 10, 64)
+			block = chain.GetBlockByNumber(num)
+		}
+	}
+	if block == nil {
+		utils.Fatalf("block not found")
+	}
+	statedb, err := chain.StateAtHeader(block.Header())
+	if err != nil {
+		utils.Fatalf("could not create new stateDB: %v", err)
+	}
+	delegatedb, err := chain.DelegateStateAt(block.Header().DelegateRoot)
+	if err != nil {
+		utils.Fatalf("could not create new delegateDB: %v", err)
+	}
+	genesis, err := core.ExportGenesis(chain.Config(), block.Header(), statedb, delegatedb)
+	if err != nil {
+		utils.Fatalf("Genesis export failed: %v", err)
+	}
+	out, err := json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		utils.Fatalf("Genesis encoding failed: %v", err)
+	}
+	if err := ioutil.WriteFile(ctx.Args().First(), out, 0644); err != nil {
+		utils.Fatalf("Genesis write failed: %v", err)
+	}
+	log.Info("Exported genesis", "number", block.NumberU64(), "hash", block.Hash(), "accounts", len(genesis.Alloc), "delegates", len(genesis.Agents))
+	return nil
+}
+
 // hashish returns true for strings that look like hashes.
 func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
@@ -28,7 +28,9 @@ import (
 	"gopkg.in/urfave/cli.v1"
 	"io"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"unicode"
 )
 
@@ -47,6 +49,11 @@ var (
 		Name:  "config",
 		Usage: "TOML configuration file",
 	}
+
+	chainsFlag = cli.StringFlag{
+		Name:  "chains",
+		Usage: "Comma separated TOML configuration files of additional chains to run in this process",
+	}
 )
 
 // These settings ensure that TOML keys use the same names as Go struct fields.
@@ -76,6 +83,10 @@ type gdacConfig struct {
 	Node     node.Config
 	Dacstats dacstatsConfig
 	// Dashboard dashboard.Config
+
+	// Chains lists the configuration files of additional chains run next to
+	// this one, each with its own data directory, p2p stack and RPC endpoints.
+	Chains []string `toml:",omitempty"`
 }
 
 func loadConfig(file string, cfg *gdacConfig) error {
@@ -129,6 +140,14 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gdacConfig) {
 	if ctx.GlobalIsSet(utils.EthStatsURLFlag.Name) {
 		cfg.Dacstats.URL = ctx.GlobalString(utils.EthStatsURLFlag.Name)
 	}
+	if ctx.GlobalIsSet(chainsFlag.Name) {
+		cfg.Chains = nil
+		for _, file := range strings.Split(ctx.GlobalString(chainsFlag.Name), ",") {
+			if file = strings.TrimSpace(file); file != "" {
+				cfg.Chains = append(cfg.Chains, file)
+			}
+		}
+	}
 
 	// utils.SetShhConfig(ctx, stack, &cfg.Shh)
 	// utils.SetDashboardConfig(ctx, &cfg.Dashboard)
@@ -147,6 +166,13 @@ func enableWhisper(ctx *cli.Context) bool {
 }
 
 func makeFullNode(ctx *cli.Context) *node.Node {
+	stack, _ := makeFullNodeConfig(ctx)
+	return stack
+}
+
+// makeFullNodeConfig creates the primary protocol stack like makeFullNode, also
+// returning the configuration it was assembled from.
+func makeFullNodeConfig(ctx *cli.Context) (*node.Node, gdacConfig) {
 	stack, cfg := makeConfigNode(ctx)
 
 	utils.RegisteraoaService(stack, &cfg.Dac)
@@ -167,12 +193,115 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 	//	utils.RegisterShhService(stack, &cfg.Shh)
 	//}
 
+	// Add the transaction scheduler if requested.
+	if ctx.GlobalBool(utils.SchedulerEnabledFlag.Name) {
+		utils.RegisterSchedulerService(stack)
+	}
+
 	// Add the eminer-pro Stats daemon if requested.
 	if cfg.Dacstats.URL != "" {
 		utils.RegisteraoaStatsService(stack, cfg.Dacstats.URL)
 	}
 
-	return stack
+	return stack, cfg
+}
+
+// makeChainNodes creates a protocol stack for every additional chain configured
+// next to the primary one. Command line flags only apply to the primary chain,
+// the additional ones are configured solely by their own files. The exception
+// are the process wide settings (--cache.gc, --db.recover and --targetgaslimit)
+// which cannot be scoped to a single chain and are shared by all of them.
+func makeChainNodes(cfg *gdacConfig) []*node.Node {
+	chains, err := loadChainConfigs(cfg)
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	var stacks []*node.Node
+	for i := range chains {
+		chain := &chains[i]
+
+		stack, err := node.New(&chain.Node)
+		if err != nil {
+			utils.Fatalf("Failed to create the protocol stack of chain %s: %v", cfg.Chains[i], err)
+		}
+		utils.RegisteraoaService(stack, &chain.Dac)
+		if chain.Dacstats.URL != "" {
+			utils.RegisteraoaStatsService(stack, chain.Dacstats.URL)
+		}
+		stacks = append(stacks, stack)
+	}
+	return stacks
+}
+
+// loadChainConfigs loads the configuration files of the additional chains and
+// checks that they don't clash with each other or with the primary chain. Every
+// chain must use its own data directory, p2p listening address and HTTP and
+// WebSocket ports.
+func loadChainConfigs(cfg *gdacConfig) ([]gdacConfig, error) {
+	var (
+		chains    []gdacConfig
+		datadirs  = make(map[string]string)
+		listeners = map[string]string{cfg.Node.P2P.ListenAddr: "primary chain"}
+		ports     = make(map[int]string)
+	)
+	if cfg.Node.DataDir != "" {
+		if datadir, err := filepath.Abs(cfg.Node.DataDir); err == nil {
+			datadirs[datadir] = "primary chain"
+		}
+	}
+	if cfg.Node.HTTPHost != "" {
+		ports[cfg.Node.HTTPPort] = "primary chain"
+	}
+	if cfg.Node.WSHost != "" {
+		ports[cfg.Node.WSPort] = "primary chain"
+	}
+	for _, file := range cfg.Chains {
+		chain := gdacConfig{
+			Dac:  aoa.DefaultConfig,
+			Node: defaultNodeConfig(),
+		}
+		chain.Node.DataDir = ""
+		chain.Dac.DatabaseHandles = cfg.Dac.DatabaseHandles
+
+		if err := loadConfig(file, &chain); err != nil {
+			return nil, err
+		}
+		if len(chain.Chains) > 0 {
+			return nil, fmt.Errorf("chain %s: nested chain configurations are not supported", file)
+		}
+		if chain.Node.DataDir == "" {
+			return nil, fmt.Errorf("chain %s: no data directory configured", file)
+		}
+		datadir, err := filepath.Abs(chain.Node.DataDir)
+		if err != nil {
+			return nil, fmt.Errorf("chain %s: invalid data directory: %v", file, err)
+		}
+		if owner, ok := datadirs[datadir]; ok {
+			return nil, fmt.Errorf("chain %s: data directory %s already used by %s", file, datadir, owner)
+		}
+		datadirs[datadir] = file
+
+		if addr := chain.Node.P2P.ListenAddr; addr != "" {
+			if owner, ok := listeners[addr]; ok {
+				return nil, fmt.Errorf("chain %s: p2p listening address %s already used by %s", file, addr, owner)
+			}
+			listeners[addr] = file
+		}
+		if chain.Node.HTTPHost != "" {
+			if owner, ok := ports[chain.Node.HTTPPort]; ok {
+				return nil, fmt.Errorf("chain %s: HTTP port %d already used by %s", file, chain.Node.HTTPPort, owner)
+			}
+			ports[chain.Node.HTTPPort] = file
+		}
+		if chain.Node.WSHost != "" {
+			if owner, ok := ports[chain.Node.WSPort]; ok {
+				return nil, fmt.Errorf("chain %s: WebSocket port %d already used by %s", file, chain.Node.WSPort, owner)
+			}
+			ports[chain.Node.WSPort] = file
+		}
+		chains = append(chains, chain)
+	}
+	return chains, nil
 }
 
 // dumpConfig is the dumpconfig command.
@@ -0,0 +1,145 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of go-eminer.
+//
+// go-eminer is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-eminer is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-eminer. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoa"
+)
+
+var chainConfigTests = []struct {
+	chains []string // TOML contents of the additional chain configurations
+	err    string   // expected error fragment, empty if the configs are valid
+}{
+	// Distinct data directories and endpoints
+	{
+		chains: []string{
+			"[Node]\nDataDir = \"chain1\"\nHTTPHost = \"127.0.0.1\"\nHTTPPort = 8555\nWSHost = \"127.0.0.1\"\nWSPort = 8556\n[Node.P2P]\nListenAddr = \":30304\"\n",
+			"[Node]\nDataDir = \"chain2\"\nHTTPHost = \"127.0.0.1\"\nHTTPPort = 8565\n[Node.P2P]\nListenAddr = \":30305\"\n",
+		},
+	},
+	// Missing data directory
+	{
+		chains: []string{"[Node.P2P]\nListenAddr = \":30304\"\n"},
+		err:    "no data directory configured",
+	},
+	// Data directory of the primary chain
+	{
+		chains: []string{"[Node]\nDataDir = \"primary\"\n[Node.P2P]\nListenAddr = \":30304\"\n"},
+		err:    "already used by primary chain",
+	},
+	// Default p2p listening address, clashing with the primary chain
+	{
+		chains: []string{"[Node]\nDataDir = \"chain1\"\n"},
+		err:    "p2p listening address :30303 already used",
+	},
+	// HTTP port of the primary chain
+	{
+		chains: []string{"[Node]\nDataDir = \"chain1\"\nHTTPHost = \"127.0.0.1\"\nHTTPPort = 8545\n[Node.P2P]\nListenAddr = \":30304\"\n"},
+		err:    "HTTP port 8545 already used by primary chain",
+	},
+	// WebSocket port clashing with the HTTP port of another chain
+	{
+		chains: []string{
+			"[Node]\nDataDir = \"chain1\"\nHTTPHost = \"127.0.0.1\"\nHTTPPort = 8555\n[Node.P2P]\nListenAddr = \":30304\"\n",
+			"[Node]\nDataDir = \"chain2\"\nWSHost = \"127.0.0.1\"\nWSPort = 8555\n[Node.P2P]\nListenAddr = \":30305\"\n",
+		},
+		err: "WebSocket port 8555 already used",
+	},
+	// Disabled endpoints don't clash
+	{
+		chains: []string{"[Node]\nDataDir = \"chain1\"\nHTTPPort = 8545\nWSPort = 8546\n[Node.P2P]\nListenAddr = \":30304\"\n"},
+	},
+	// Nested chains
+	{
+		chains: []string{"Chains = [\"other.toml\"]\n[Node]\nDataDir = \"chain1\"\n[Node.P2P]\nListenAddr = \":30304\"\n"},
+		err:    "nested chain configurations are not supported",
+	},
+}
+
+// Tests that the configurations of additional chains are loaded and rejected if
+// they clash with each other or with the primary chain.
+func TestLoadChainConfigs(t *testing.T) {
+	for i, tt := range chainConfigTests {
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatalf("failed to create temporary directory: %v", err)
+		}
+		defer os.RemoveAll(dir)
+
+		cfg := gdacConfig{Dac: aoa.DefaultConfig, Node: defaultNodeConfig()}
+		cfg.Node.DataDir = filepath.Join(dir, "primary")
+		cfg.Node.HTTPHost = "127.0.0.1"
+		cfg.Dac.DatabaseHandles = 512
+
+		for j, chain := range tt.chains {
+			// Keep the data directories inside the temporary directory
+			chain = strings.Replace(chain, "DataDir = \"", "DataDir = \""+filepath.ToSlash(dir)+"/", 1)
+			file := filepath.Join(dir, fmt.Sprintf("chain%d.toml", j))
+			if err := ioutil.WriteFile(file, []byte(chain), 0600); err != nil {
+				t.Fatalf("test %d: failed to write chain config: %v", i, err)
+			}
+			cfg.Chains = append(cfg.Chains, file)
+		}
+		chains, err := loadChainConfigs(&cfg)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("test %d: error mismatch: have %v, want %q", i, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to load chain configs: %v", i, err)
+			continue
+		}
+		if len(chains) != len(tt.chains) {
+			t.Errorf("test %d: chain count mismatch: have %d, want %d", i, len(chains), len(tt.chains))
+		}
+		for j, chain := range chains {
+			if chain.Dac.DatabaseHandles != cfg.Dac.DatabaseHandles {
+				t.Errorf("test %d, chain %d: database handles mismatch: have %d, want %d", i, j, chain.Dac.DatabaseHandles, cfg.Dac.DatabaseHandles)
+			}
+		}
+	}
+}
+
+// Tests that a dumped configuration reloads with the same trie commit interval,
+// including the zero interval flushing the state on shutdown only.
+func TestDumpConfigTrieCommitInterval(t *testing.T) {
+	for _, interval := range []uint64{0, 128} {
+		cfg := aoa.DefaultConfig
+		cfg.TrieCommitInterval = interval
+
+		out, err := tomlSettings.Marshal(&cfg)
+		if err != nil {
+			t.Fatalf("interval %d: failed to dump config: %v", interval, err)
+		}
+		reloaded := aoa.DefaultConfig
+		if err := tomlSettings.Unmarshal(out, &reloaded); err != nil {
+			t.Fatalf("interval %d: failed to reload config: %v", interval, err)
+		}
+		if reloaded.TrieCommitInterval != interval {
+			t.Errorf("interval %d: reloaded interval mismatch: have %d", interval, reloaded.TrieCommitInterval)
+		}
+	}
+}
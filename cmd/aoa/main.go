@@ -63,6 +63,7 @@ var (
 		//utils.DashboardPortFlag,
 		//utils.DashboardRefreshFlag,
 		//utils.DashboardAssetsFlag,
+		utils.TxPoolLocalsFlag,
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
@@ -79,18 +80,38 @@ var (
 		utils.LightPeersFlag,
 		utils.LightKDFFlag,
 		utils.CacheFlag,
+		utils.DatabaseRecoverFlag,
+		utils.TxLookupLimitFlag,
+		utils.HistoryLimitFlag,
+		utils.IndexFlag,
+		utils.IndexThrottleFlag,
+		utils.TraceCacheFlag,
+		utils.TraceCacheDiskFlag,
+		utils.GCModeFlag,
+		utils.TrieCacheFlag,
+		utils.TrieDirtyCacheFlag,
+		utils.TrieCommitIntervalFlag,
+		utils.ProcessorWorkersFlag,
 		utils.TrieCacheGenFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
+		utils.MaxInboundPeersFlag,
+		utils.MaxOutboundPeersFlag,
+		utils.PriorityNodesFlag,
+		utils.PrioritySlotsFlag,
 		utils.AoachainbaseFlag,
 		utils.GasPriceFlag,
 		utils.MinerThreadsFlag,
 		utils.MiningEnabledFlag,
 		utils.TargetGasLimitFlag,
 		utils.NATFlag,
+		utils.NATExternalPortFlag,
+		utils.PropagationFlag,
+		utils.PropagationFanoutFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
+		utils.DNSDiscoveryFlag,
 		utils.NetrestrictFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
@@ -103,13 +124,18 @@ var (
 		utils.RPCCORSDomainFlag,
 		utils.EthStatsURLFlag,
 		utils.MetricsEnabledFlag,
+		utils.MetricsStateAccessFlag,
 		utils.FakePoWFlag,
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
 		utils.GpoPercentileFlag,
+		utils.GpoMaxGasPriceFlag,
 		utils.ExtraDataFlag,
+		utils.ProduceLeadTimeFlag,
 		configFileFlag,
+		chainsFlag,
 		utils.WatchInnerTxFlag,
+		utils.SchedulerEnabledFlag,
 	}
 
 	rpcFlags = []cli.Flag{
@@ -117,6 +143,7 @@ var (
 		utils.RPCListenAddrFlag,
 		utils.RPCPortFlag,
 		utils.RPCApiFlag,
+		utils.RPCVirtualHostsFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
@@ -124,6 +151,13 @@ var (
 		utils.WSAllowedOriginsFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
+		utils.IPCApiFlag,
+		utils.RPCBatchRequestLimitFlag,
+		utils.RPCBatchResponseMaxSizeFlag,
+		utils.RPCMethodTimeoutsFlag,
+		utils.RPCGasCapFlag,
+		utils.RPCEVMTimeoutFlag,
+		utils.RPCEthCompatFlag,
 	}
 
 	whisperFlags = []cli.Flag{
@@ -144,8 +178,10 @@ func init() {
 		importCommand,
 		exportCommand,
 		copydbCommand,
+		replayCommand,
 		removedbCommand,
 		dumpCommand,
+		exportGenesisCommand,
 		// See monitorcmd.go:
 		monitorCommand,
 		// See accountcmd.go:
@@ -203,9 +239,19 @@ func main() {
 // It creates a default node based on the command line arguments and runs it in
 // blocking mode, waiting for it to be shut down.
 func gDac(ctx *cli.Context) error {
-	fullNode := makeFullNode(ctx)
+	fullNode, cfg := makeFullNodeConfig(ctx)
+	chains := makeChainNodes(&cfg)
+
 	startNode(ctx, fullNode)
+	for _, stack := range chains {
+		utils.StartNode(stack)
+	}
 	fullNode.Wait()
+
+	// The primary chain went down, tear down the additional ones with it
+	for _, stack := range chains {
+		stack.Stop()
+	}
 	return nil
 }
 
@@ -67,6 +67,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "eminer",
 		Flags: []cli.Flag{
 			configFileFlag,
+			chainsFlag,
 			utils.DataDirFlag,
 			utils.KeyStoreDirFlag,
 			utils.NoUSBFlag,
@@ -80,6 +81,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.LightPeersFlag,
 			utils.LightKDFFlag,
 			utils.WatchInnerTxFlag,
+			utils.SchedulerEnabledFlag,
 		},
 	},
 	{Name: "DEVELOPER CHAIN",
@@ -101,6 +103,7 @@ var AppHelpFlagGroups = []flagGroup{
 	{
 		Name: "TRANSACTION POOL",
 		Flags: []cli.Flag{
+			utils.TxPoolLocalsFlag,
 			utils.TxPoolNoLocalsFlag,
 			utils.TxPoolJournalFlag,
 			utils.TxPoolRejournalFlag,
@@ -117,6 +120,18 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "PERFORMANCE TUNING",
 		Flags: []cli.Flag{
 			utils.CacheFlag,
+			utils.DatabaseRecoverFlag,
+			utils.TxLookupLimitFlag,
+			utils.HistoryLimitFlag,
+			utils.IndexFlag,
+			utils.IndexThrottleFlag,
+			utils.TraceCacheFlag,
+			utils.TraceCacheDiskFlag,
+			utils.GCModeFlag,
+			utils.TrieCacheFlag,
+			utils.TrieDirtyCacheFlag,
+			utils.TrieCommitIntervalFlag,
+			utils.ProcessorWorkersFlag,
 			utils.TrieCacheGenFlag,
 		},
 	},
@@ -134,6 +149,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.RPCListenAddrFlag,
 			utils.RPCPortFlag,
 			utils.RPCApiFlag,
+			utils.RPCVirtualHostsFlag,
 			utils.WSEnabledFlag,
 			utils.WSListenAddrFlag,
 			utils.WSPortFlag,
@@ -141,6 +157,13 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.WSAllowedOriginsFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
+			utils.IPCApiFlag,
+			utils.RPCBatchRequestLimitFlag,
+			utils.RPCBatchResponseMaxSizeFlag,
+			utils.RPCMethodTimeoutsFlag,
+			utils.RPCGasCapFlag,
+			utils.RPCEVMTimeoutFlag,
+			utils.RPCEthCompatFlag,
 			utils.RPCCORSDomainFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
@@ -156,9 +179,17 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ListenPortFlag,
 			utils.MaxPeersFlag,
 			utils.MaxPendingPeersFlag,
+			utils.MaxInboundPeersFlag,
+			utils.MaxOutboundPeersFlag,
+			utils.PriorityNodesFlag,
+			utils.PrioritySlotsFlag,
 			utils.NATFlag,
+			utils.NATExternalPortFlag,
+			utils.PropagationFlag,
+			utils.PropagationFanoutFlag,
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
+			utils.DNSDiscoveryFlag,
 			utils.NetrestrictFlag,
 			utils.NodeKeyFileFlag,
 			utils.NodeKeyHexFlag,
@@ -173,6 +204,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TargetGasLimitFlag,
 			utils.GasPriceFlag,
 			utils.ExtraDataFlag,
+			utils.ProduceLeadTimeFlag,
 		},
 	},
 	{
@@ -180,6 +212,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.GpoBlocksFlag,
 			utils.GpoPercentileFlag,
+			utils.GpoMaxGasPriceFlag,
 		},
 	},
 	{
@@ -192,6 +225,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "LOGGING AND DEBUGGING",
 		Flags: append([]cli.Flag{
 			utils.MetricsEnabledFlag,
+			utils.MetricsStateAccessFlag,
 			utils.FakePoWFlag,
 			utils.NoCompactionFlag,
 		}, debug.Flags...),
@@ -31,7 +31,6 @@ import (
 	"github.com/Aurorachain-io/go-aoa/internal/debug"
 	"github.com/Aurorachain-io/go-aoa/log"
 	"github.com/Aurorachain-io/go-aoa/node"
-	"github.com/Aurorachain-io/go-aoa/rlp"
 )
 
 const (
@@ -80,6 +79,9 @@ func StartNode(stack *node.Node) {
 	}()
 }
 
+// ImportChain imports the RLP encoded blocks stored in the given file, which
+// may be gzip compressed, into the chain. Batches that are already present are
+// skipped and the import stops at the next batch on Ctrl-C.
 func ImportChain(chain *core.BlockChain, fn string) error {
 	// Watch for Ctrl-C while the import is running.
 	// If a signal is received, the import will stop at the next batch.
@@ -94,14 +96,6 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 		}
 		close(stop)
 	}()
-	checkInterrupt := func() bool {
-		select {
-		case <-stop:
-			return true
-		default:
-			return false
-		}
-	}
 
 	log.Info("Importing blockchain", "file", fn)
 	fh, err := os.Open(fn)
@@ -116,59 +110,24 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 			return err
 		}
 	}
-
-	stream := rlp.NewStream(reader, 0)
-
-	// Run actual the import.
-	blocks := make(types.Blocks, importBatchSize)
-	n := 0
-	for batch := 0; ; batch++ {
-		// Load a batch of RLP blocks.
-		if checkInterrupt() {
-			return fmt.Errorf("interrupted")
-		}
-		i := 0
-		for ; i < importBatchSize; i++ {
-			var b types.Block
-			if err := stream.Decode(&b); err == io.EOF {
-				break
-			} else if err != nil {
-				return fmt.Errorf("at block %d: %v", n, err)
-			}
-			// don't import first block
-			if b.NumberU64() == 0 {
-				i--
-				continue
-			}
-			blocks[i] = &b
-			n++
-		}
-		if i == 0 {
-			break
-		}
-		// Import the batch.
-		if checkInterrupt() {
-			return fmt.Errorf("interrupted")
-		}
-		if hasAllBlocks(chain, blocks[:i]) {
-			log.Info("Skipping batch as all blocks present", "batch", batch, "first", blocks[0].Hash(), "last", blocks[i-1].Hash())
-			continue
-		}
-
-		if _, err := chain.InsertChain(blocks[:i]); err != nil {
-			return fmt.Errorf("invalid block %d: %v", n, err)
-		}
+	config := core.ImportConfig{
+		BatchSize:   importBatchSize,
+		SkipPresent: true,
+		Contiguous:  true,
+		Interrupt:   stop,
+		Progress:    logChainProgress("Importing blockchain"),
 	}
-	return nil
+	_, err = chain.ImportChain(reader, config)
+	return err
 }
 
-func hasAllBlocks(chain *core.BlockChain, bs []*types.Block) bool {
-	for _, b := range bs {
-		if !chain.HasBlock(b.Hash(), b.NumberU64()) {
-			return false
+// logChainProgress returns a progress callback logging the last processed block.
+func logChainProgress(msg string) core.ChainProgressFn {
+	return func(processed uint64, last *types.Block) {
+		if last != nil {
+			log.Info(msg, "blocks", processed, "number", last.Number(), "hash", last.Hash())
 		}
 	}
-	return true
 }
 
 func ExportChain(blockchain *core.BlockChain, fn string) error {
@@ -185,7 +144,7 @@ func ExportChain(blockchain *core.BlockChain, fn string) error {
 		defer writer.(*gzip.Writer).Close()
 	}
 
-	if err := blockchain.Export(writer); err != nil {
+	if err := blockchain.Export(writer, 0, blockchain.CurrentBlock().NumberU64(), logChainProgress("Exporting blockchain")); err != nil {
 		return err
 	}
 	log.Info("Exported blockchain", "file", fn)
@@ -207,7 +166,7 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 		defer writer.(*gzip.Writer).Close()
 	}
 
-	if err := blockchain.ExportN(writer, first, last); err != nil {
+	if err := blockchain.Export(writer, first, last, logChainProgress("Exporting blockchain")); err != nil {
 		return err
 	}
 	log.Info("Exported blockchain to", "file", fn)
@@ -24,6 +24,7 @@ import (
 	"github.com/Aurorachain-io/go-aoa/accounts/keystore"
 	"github.com/Aurorachain-io/go-aoa/common"
 	"github.com/Aurorachain-io/go-aoa/common/fdlimit"
+	"github.com/Aurorachain-io/go-aoa/common/hexutil"
 	"github.com/Aurorachain-io/go-aoa/core"
 	"github.com/Aurorachain-io/go-aoa/core/state"
 	"github.com/Aurorachain-io/go-aoa/core/vm"
@@ -38,9 +39,11 @@ import (
 	"github.com/Aurorachain-io/go-aoa/node"
 	"github.com/Aurorachain-io/go-aoa/p2p"
 	"github.com/Aurorachain-io/go-aoa/p2p/discover"
+	"github.com/Aurorachain-io/go-aoa/p2p/discv5"
 	"github.com/Aurorachain-io/go-aoa/p2p/nat"
 	"github.com/Aurorachain-io/go-aoa/p2p/netutil"
 	"github.com/Aurorachain-io/go-aoa/params"
+	"github.com/Aurorachain-io/go-aoa/txscheduler"
 	cli "gopkg.in/urfave/cli.v1"
 	"io/ioutil"
 	"math/big"
@@ -49,6 +52,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -133,11 +137,11 @@ var (
 	}
 	DeveloperFlag = cli.BoolFlag{
 		Name:  "dev",
-		Usage: "Ephaoaeral proof-of-authority network with a pre-funded developer account, mining enabled",
+		Usage: "Ephemeral single delegate network with pre-funded developer accounts, sealing blocks instantly",
 	}
 	DeveloperPeriodFlag = cli.IntFlag{
 		Name:  "dev.period",
-		Usage: "Block period to use in developer mode (0 = mine only if transaction pending)",
+		Usage: "Block period to use in developer mode (0 = seal only if transactions are pending)",
 	}
 	IdentityFlag = cli.StringFlag{
 		Name:  "identity",
@@ -178,6 +182,10 @@ var (
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
 	}
 	// Transaction pool settings
+	TxPoolLocalsFlag = cli.StringFlag{
+		Name:  "txpool.locals",
+		Usage: "Comma separated accounts to treat as locals (no flush, priority inclusion)",
+	}
 	TxPoolNoLocalsFlag = cli.BoolFlag{
 		Name:  "txpool.nolocals",
 		Usage: "Disables price exaoaptions for locally submitted transactions",
@@ -233,6 +241,68 @@ var (
 		Usage: "Megabytes of maoaory allocated to internal caching (min 16MB / database forced)",
 		Value: 128,
 	}
+	DatabaseRecoverFlag = cli.BoolFlag{
+		Name:  "db.recover",
+		Usage: "Repair the database if it's reported corrupted on startup, rewinding the chain to the last intact block",
+	}
+	ReplayConfigFlag = cli.StringFlag{
+		Name:  "replay.config",
+		Usage: "Chain config JSON file with the rules to replay blocks with (default = source chain config)",
+	}
+	TxLookupLimitFlag = cli.Uint64Flag{
+		Name:  "txlookuplimit",
+		Usage: "Number of recent blocks to maintain transactions index by-hash for (default = index all blocks)",
+		Value: 0,
+	}
+	HistoryLimitFlag = cli.Uint64Flag{
+		Name:  "historylimit",
+		Usage: "Number of recent blocks to retain bodies and receipts for (default = retain all blocks)",
+		Value: 0,
+	}
+	IndexFlag = cli.StringFlag{
+		Name:  "index",
+		Usage: "Comma separated optional chain indexes to maintain and backfill (logs, transfers, creations)",
+	}
+	IndexThrottleFlag = cli.DurationFlag{
+		Name:  "index.throttle",
+		Usage: "Time to wait between indexing two sections of blocks while backfilling the optional indexes",
+		Value: aoa.DefaultConfig.IndexThrottling,
+	}
+	TraceCacheFlag = cli.IntFlag{
+		Name:  "trace.cache",
+		Usage: "Number of transaction traces cached in memory (0 = disabled)",
+		Value: aoa.DefaultConfig.TraceCache,
+	}
+	TraceCacheDiskFlag = cli.IntFlag{
+		Name:  "trace.cachedisk",
+		Usage: "Number of transaction traces evicted from memory kept on disk (0 = disabled)",
+		Value: aoa.DefaultConfig.TraceCacheDisk,
+	}
+	GCModeFlag = cli.StringFlag{
+		Name:  "gcmode",
+		Usage: `Blockchain garbage collection mode ("full", "archive")`,
+		Value: "full",
+	}
+	TrieCacheFlag = cli.IntFlag{
+		Name:  "cache.trie",
+		Usage: "Megabytes of memory allocated to caching clean trie nodes",
+		Value: aoa.DefaultConfig.TrieCleanCache,
+	}
+	TrieDirtyCacheFlag = cli.IntFlag{
+		Name:  "cache.trie.dirty",
+		Usage: "Megabytes of memory allocated to dirty trie nodes before flushing them to disk",
+		Value: aoa.DefaultConfig.TrieDirtyCache,
+	}
+	TrieCommitIntervalFlag = cli.Uint64Flag{
+		Name:  "cache.trie.interval",
+		Usage: "Number of blocks between two flushes of a complete state to disk in full gcmode (0 = on shutdown only)",
+		Value: aoa.DefaultConfig.TrieCommitInterval,
+	}
+	ProcessorWorkersFlag = cli.IntFlag{
+		Name:  "processor.workers",
+		Usage: "Number of goroutines pre-executing block transactions in parallel (0 = serial processing)",
+		Value: 0,
+	}
 	TrieCacheGenFlag = cli.IntFlag{
 		Name:  "trie-cache-gens",
 		Usage: "Number of trie node generations to keep in maoaory",
@@ -265,7 +335,22 @@ var (
 	}
 	ExtraDataFlag = cli.StringFlag{
 		Name:  "extradata",
-		Usage: "Block extra data set by the miner (default = client version)",
+		Usage: "Block extra data set by the miner (max 8 bytes once embedded as vanity from the extra-data v1 fork on)",
+	}
+	ProduceLeadTimeFlag = cli.DurationFlag{
+		Name:  "produce.leadtime",
+		Usage: "Time ahead of its slot a local delegate starts assembling its block",
+		Value: aoa.DefaultConfig.ProduceLeadTime,
+	}
+	PropagationFlag = cli.StringFlag{
+		Name:  "propagation",
+		Usage: "Block propagation strategy (full|sqrt|mesh|announce)",
+		Value: aoa.DefaultConfig.Propagation.Strategy,
+	}
+	PropagationFanoutFlag = cli.IntFlag{
+		Name:  "propagation.fanout",
+		Usage: "Number of peers blocks are pushed to by the sqrt and mesh strategies (square root of the peers if set to 0)",
+		Value: aoa.DefaultConfig.Propagation.Fanout,
 	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
@@ -292,6 +377,10 @@ var (
 		Name:  metrics.MetricsEnabledFlag,
 		Usage: "Enable metrics collection and reporting",
 	}
+	MetricsStateAccessFlag = cli.BoolFlag{
+		Name:  "metrics.stateaccess",
+		Usage: "Collect per-block state access statistics (accounts, slots, trie depths, cache hits) as metrics",
+	}
 	FakePoWFlag = cli.BoolFlag{
 		Name:  "fakepow",
 		Usage: "Disables proof-of-work verification",
@@ -325,6 +414,16 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCVirtualHostsFlag = cli.StringFlag{
+		Name:  "rpcvhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
+		Value: strings.Join(node.DefaultConfig.HTTPVirtualHosts, ","),
+	}
+	IPCApiFlag = cli.StringFlag{
+		Name:  "ipcapi",
+		Usage: "API's offered over the IPC interface (default = all)",
+		Value: "",
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -357,6 +456,34 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	RPCBatchRequestLimitFlag = cli.IntFlag{
+		Name:  "rpc.batchlimit",
+		Usage: "Maximum number of requests in a JSON-RPC batch (0 = unlimited)",
+		Value: node.DefaultConfig.BatchRequestLimit,
+	}
+	RPCBatchResponseMaxSizeFlag = cli.IntFlag{
+		Name:  "rpc.batchresponsesize",
+		Usage: "Maximum number of bytes returned for a JSON-RPC batch (0 = unlimited)",
+		Value: node.DefaultConfig.BatchResponseMaxSize,
+	}
+	RPCMethodTimeoutsFlag = cli.StringFlag{
+		Name:  "rpc.methodtimeouts",
+		Usage: "Comma separated execution time caps of JSON-RPC methods (e.g. aoa_getLogs=10s,debug_traceTransaction=1m)",
+	}
+	RPCGasCapFlag = cli.Uint64Flag{
+		Name:  "rpc.gascap",
+		Usage: "Gas cap of aoa_call and aoa_estimateGas executions (0 = no cap)",
+		Value: aoa.DefaultConfig.RPCGasCap,
+	}
+	RPCEVMTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.evmtimeout",
+		Usage: "Time limit of aoa_call and aoa_estimateGas executions (0 = no limit)",
+		Value: aoa.DefaultConfig.RPCEVMTimeout,
+	}
+	RPCEthCompatFlag = cli.BoolFlag{
+		Name:  "rpc.ethcompat",
+		Usage: "Expose the aoa API under the eth namespace with Ethereum compatible results",
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript stataoaent",
@@ -377,6 +504,26 @@ var (
 		Usage: "Maximum number of pending connection attaoapts (defaults used if set to 0)",
 		Value: 0,
 	}
+	MaxInboundPeersFlag = cli.IntFlag{
+		Name:  "maxpeers.inbound",
+		Usage: "Maximum number of inbound network peers (only --maxpeers applies if set to 0)",
+		Value: 0,
+	}
+	MaxOutboundPeersFlag = cli.IntFlag{
+		Name:  "maxpeers.outbound",
+		Usage: "Maximum number of dialed network peers (half of --maxpeers if set to 0)",
+		Value: 0,
+	}
+	PriorityNodesFlag = cli.StringFlag{
+		Name:  "prioritynodes",
+		Usage: "Comma separated enode URLs of the delegates always kept connected in reserved slots",
+		Value: "",
+	}
+	PrioritySlotsFlag = cli.IntFlag{
+		Name:  "priorityslots",
+		Usage: "Number of peer slots reserved for priority nodes in addition to --maxpeers (number of priority nodes if set to 0)",
+		Value: 0,
+	}
 	ListenPortFlag = cli.IntFlag{
 		Name:  "port",
 		Usage: "Network listening port",
@@ -410,6 +557,11 @@ var (
 		Usage: "NAT port mapping mechanism (any|none|upnp|pmp|extip:<IP>)",
 		Value: "any",
 	}
+	NATExternalPortFlag = cli.IntFlag{
+		Name:  "nat.extport",
+		Usage: "Network port reachable from the Internet if it differs from --port (e.g. forwarded manually)",
+		Value: 0,
+	}
 	NoDiscoverFlag = cli.BoolFlag{
 		Name:  "nodiscover",
 		Usage: "Disables the peer discovery mechanism (manual peer addition)",
@@ -418,6 +570,10 @@ var (
 		Name:  "v5disc",
 		Usage: "Enables the experimental RLPx V5 (Topic Discovery) mechanism",
 	}
+	DNSDiscoveryFlag = cli.StringFlag{
+		Name:  "discovery.dns",
+		Usage: "Comma separated enrtree:// URLs of DNS node trees for bootstrapping discovery",
+	}
 	NetrestrictFlag = cli.StringFlag{
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
@@ -441,10 +597,19 @@ var (
 		Usage: "Suggested gas price is the given percentile of a set of recent transaction gas prices",
 		Value: aoa.DefaultConfig.GPO.Percentile,
 	}
+	GpoMaxGasPriceFlag = BigFlag{
+		Name:  "gpomaxprice",
+		Usage: "Maximum gas price that will be recommended by gpo",
+		Value: aoa.DefaultConfig.GPO.MaxPrice,
+	}
 	WatchInnerTxFlag = cli.BoolFlag{
 		Name:  "watchinnertx",
 		Usage: "Enable watching internal transactions",
 	}
+	SchedulerEnabledFlag = cli.BoolFlag{
+		Name:  "scheduler",
+		Usage: "Enable the transaction scheduler holding transactions back until their earliest inclusion block",
+	}
 	//WhisperEnabledFlag = cli.BoolFlag{
 	//	Name:  "shh",
 	//	Usage: "Enable Whisper",
@@ -539,6 +704,50 @@ func setBootstrapNodes(ctx *cli.Context, cfg *p2p.Config) {
 	}
 }
 
+// setPriorityNodes creates a list of priority nodes from the command line flags,
+// keeping the configured ones if none have been specified.
+func setPriorityNodes(ctx *cli.Context, cfg *p2p.Config) {
+	if !ctx.GlobalIsSet(PriorityNodesFlag.Name) {
+		return
+	}
+	urls := strings.Split(ctx.GlobalString(PriorityNodesFlag.Name), ",")
+
+	cfg.PriorityNodes = make([]*discover.Node, 0, len(urls))
+	for _, url := range urls {
+		node, err := discover.ParseNode(url)
+		if err != nil {
+			Fatalf("Option %q: invalid enode %q: %v", PriorityNodesFlag.Name, url, err)
+		}
+		cfg.PriorityNodes = append(cfg.PriorityNodes, node)
+	}
+}
+
+// setBootstrapNodesV5 creates a list of bootstrap nodes from the command line
+// flags, reverting to pre-configured ones if none have been specified.
+func setBootstrapNodesV5(ctx *cli.Context, cfg *p2p.Config) {
+	urls := params.DiscoveryV5Bootnodes
+	switch {
+	case ctx.GlobalIsSet(BootnodesFlag.Name) || ctx.GlobalIsSet(BootnodesV5Flag.Name):
+		if ctx.GlobalIsSet(BootnodesV5Flag.Name) {
+			urls = strings.Split(ctx.GlobalString(BootnodesV5Flag.Name), ",")
+		} else {
+			urls = strings.Split(ctx.GlobalString(BootnodesFlag.Name), ",")
+		}
+	case cfg.BootstrapNodesV5 != nil:
+		return // already set, don't apply defaults.
+	}
+
+	cfg.BootstrapNodesV5 = make([]*discv5.Node, 0, len(urls))
+	for _, url := range urls {
+		node, err := discv5.ParseNode(url)
+		if err != nil {
+			log.Error("Bootstrap URL invalid", "enode", url, "err", err)
+			continue
+		}
+		cfg.BootstrapNodesV5 = append(cfg.BootstrapNodesV5, node)
+	}
+}
+
 // setListenAddress creates a TCP listening address string from set command
 // line flags.
 func setListenAddress(ctx *cli.Context, cfg *p2p.Config) {
@@ -556,6 +765,9 @@ func setNAT(ctx *cli.Context, cfg *p2p.Config) {
 		}
 		cfg.NAT = natif
 	}
+	if ctx.GlobalIsSet(NATExternalPortFlag.Name) {
+		cfg.ExternalPort = ctx.GlobalInt(NATExternalPortFlag.Name)
+	}
 }
 
 // splitAndTrim splits input separated by a comma
@@ -587,6 +799,9 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(RPCApiFlag.Name) {
 		cfg.HTTPModules = splitAndTrim(ctx.GlobalString(RPCApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(RPCVirtualHostsFlag.Name) {
+		cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -610,6 +825,37 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setRPCBatchLimits applies the JSON-RPC batch limits from the command line
+// flags, which apply to all RPC endpoints.
+func setRPCBatchLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCBatchRequestLimitFlag.Name) {
+		cfg.BatchRequestLimit = ctx.GlobalInt(RPCBatchRequestLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCBatchResponseMaxSizeFlag.Name) {
+		cfg.BatchResponseMaxSize = ctx.GlobalInt(RPCBatchResponseMaxSizeFlag.Name)
+	}
+}
+
+// setRPCMethodTimeouts parses the per-method execution time caps of the JSON-RPC
+// servers from the command line flags.
+func setRPCMethodTimeouts(ctx *cli.Context, cfg *node.Config) {
+	if !ctx.GlobalIsSet(RPCMethodTimeoutsFlag.Name) {
+		return
+	}
+	cfg.MethodTimeouts = make(map[string]time.Duration)
+	for _, entry := range splitAndTrim(ctx.GlobalString(RPCMethodTimeoutsFlag.Name)) {
+		parts := strings.Split(entry, "=")
+		if len(parts) != 2 {
+			Fatalf("Option %q: invalid entry %q, want method=duration", RPCMethodTimeoutsFlag.Name, entry)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			Fatalf("Option %q: %v", RPCMethodTimeoutsFlag.Name, err)
+		}
+		cfg.MethodTimeouts[strings.TrimSpace(parts[0])] = timeout
+	}
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an aoapty string if IPC was explicitly disabled, or the set path.
 func setIPC(ctx *cli.Context, cfg *node.Config) {
@@ -620,6 +866,9 @@ func setIPC(ctx *cli.Context, cfg *node.Config) {
 	case ctx.GlobalIsSet(IPCPathFlag.Name):
 		cfg.IPCPath = ctx.GlobalString(IPCPathFlag.Name)
 	}
+	if ctx.GlobalIsSet(IPCApiFlag.Name) {
+		cfg.IPCModules = splitAndTrim(ctx.GlobalString(IPCApiFlag.Name))
+	}
 }
 
 // makeDatabaseHandles raises out the number of allowed file handles per process
@@ -698,6 +947,8 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	setNAT(ctx, cfg)
 	setListenAddress(ctx, cfg)
 	setBootstrapNodes(ctx, cfg)
+	setBootstrapNodesV5(ctx, cfg)
+	setPriorityNodes(ctx, cfg)
 
 	if ctx.GlobalIsSet(MaxPeersFlag.Name) {
 		cfg.MaxPeers = ctx.GlobalInt(MaxPeersFlag.Name)
@@ -705,9 +956,26 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.GlobalIsSet(MaxPendingPeersFlag.Name) {
 		cfg.MaxPendingPeers = ctx.GlobalInt(MaxPendingPeersFlag.Name)
 	}
+	if ctx.GlobalIsSet(MaxInboundPeersFlag.Name) {
+		cfg.MaxInboundPeers = ctx.GlobalInt(MaxInboundPeersFlag.Name)
+	}
+	if ctx.GlobalIsSet(MaxOutboundPeersFlag.Name) {
+		cfg.MaxOutboundPeers = ctx.GlobalInt(MaxOutboundPeersFlag.Name)
+	}
+	if ctx.GlobalIsSet(PrioritySlotsFlag.Name) {
+		cfg.PrioritySlots = ctx.GlobalInt(PrioritySlotsFlag.Name)
+	}
 	if ctx.GlobalIsSet(NoDiscoverFlag.Name) {
 		cfg.NoDiscovery = true
 	}
+	if ctx.GlobalIsSet(DNSDiscoveryFlag.Name) {
+		cfg.DiscoveryDNS = nil
+		for _, url := range strings.Split(ctx.GlobalString(DNSDiscoveryFlag.Name), ",") {
+			if url = strings.TrimSpace(url); url != "" {
+				cfg.DiscoveryDNS = append(cfg.DiscoveryDNS, url)
+			}
+		}
+	}
 
 	// if we're running a light client or server, force enable the v5 peer discovery
 	// unless it is explicitly disabled with --nodiscover note that explicitly specifying
@@ -742,6 +1010,8 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCBatchLimits(ctx, cfg)
+	setRPCMethodTimeouts(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
 	switch {
@@ -773,9 +1043,22 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	if ctx.GlobalIsSet(GpoPercentileFlag.Name) {
 		cfg.Percentile = ctx.GlobalInt(GpoPercentileFlag.Name)
 	}
+	if ctx.GlobalIsSet(GpoMaxGasPriceFlag.Name) {
+		cfg.MaxPrice = GlobalBig(ctx, GpoMaxGasPriceFlag.Name)
+	}
 }
 
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
+	if ctx.GlobalIsSet(TxPoolLocalsFlag.Name) {
+		locals := strings.Split(ctx.GlobalString(TxPoolLocalsFlag.Name), ",")
+		for _, account := range locals {
+			if trimmed := strings.TrimSpace(account); !common.IsHexAddress(trimmed) {
+				Fatalf("Invalid account in --txpool.locals: %s", trimmed)
+			} else {
+				cfg.Locals = append(cfg.Locals, common.HexToAddress(trimmed))
+			}
+		}
+	}
 	if ctx.GlobalIsSet(TxPoolNoLocalsFlag.Name) {
 		cfg.NoLocals = ctx.GlobalBool(TxPoolNoLocalsFlag.Name)
 	}
@@ -886,6 +1169,58 @@ func SetaoaConfig(ctx *cli.Context, stack *node.Node, cfg *aoa.Config) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name)
 	}
 	cfg.DatabaseHandles = makeDatabaseHandles()
+	if ctx.GlobalIsSet(DatabaseRecoverFlag.Name) {
+		aoadb.RecoverCorrupted = ctx.GlobalBool(DatabaseRecoverFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
+		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(HistoryLimitFlag.Name) {
+		cfg.HistoryLimit = ctx.GlobalUint64(HistoryLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(IndexFlag.Name) {
+		cfg.Indexes = strings.Split(ctx.GlobalString(IndexFlag.Name), ",")
+		for i, kind := range cfg.Indexes {
+			cfg.Indexes[i] = strings.TrimSpace(kind)
+		}
+	}
+	if ctx.GlobalIsSet(IndexThrottleFlag.Name) {
+		cfg.IndexThrottling = ctx.GlobalDuration(IndexThrottleFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCGasCapFlag.Name) {
+		cfg.RPCGasCap = ctx.GlobalUint64(RPCGasCapFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCEVMTimeoutFlag.Name) {
+		cfg.RPCEVMTimeout = ctx.GlobalDuration(RPCEVMTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCEthCompatFlag.Name) {
+		cfg.EthCompat = ctx.GlobalBool(RPCEthCompatFlag.Name)
+	}
+	if ctx.GlobalIsSet(TraceCacheFlag.Name) {
+		cfg.TraceCache = ctx.GlobalInt(TraceCacheFlag.Name)
+	}
+	if ctx.GlobalIsSet(TraceCacheDiskFlag.Name) {
+		cfg.TraceCacheDisk = ctx.GlobalInt(TraceCacheDiskFlag.Name)
+	}
+	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
+		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
+	}
+	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
+	if ctx.GlobalIsSet(TrieCacheFlag.Name) {
+		cfg.TrieCleanCache = ctx.GlobalInt(TrieCacheFlag.Name)
+	}
+	if ctx.GlobalIsSet(TrieDirtyCacheFlag.Name) {
+		cfg.TrieDirtyCache = ctx.GlobalInt(TrieDirtyCacheFlag.Name)
+	}
+	if ctx.GlobalIsSet(TrieCommitIntervalFlag.Name) {
+		cfg.TrieCommitInterval = ctx.GlobalUint64(TrieCommitIntervalFlag.Name)
+	}
+	if ctx.GlobalIsSet(ProcessorWorkersFlag.Name) {
+		cfg.ProcessorWorkers = ctx.GlobalInt(ProcessorWorkersFlag.Name)
+	}
+	if ctx.GlobalIsSet(MetricsStateAccessFlag.Name) {
+		cfg.StateStats = ctx.GlobalBool(MetricsStateAccessFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(MinerThreadsFlag.Name) {
 		cfg.MinerThreads = ctx.GlobalInt(MinerThreadsFlag.Name)
@@ -899,6 +1234,15 @@ func SetaoaConfig(ctx *cli.Context, stack *node.Node, cfg *aoa.Config) {
 	if ctx.GlobalIsSet(GasPriceFlag.Name) {
 		cfg.GasPrice = GlobalBig(ctx, GasPriceFlag.Name)
 	}
+	if ctx.GlobalIsSet(ProduceLeadTimeFlag.Name) {
+		cfg.ProduceLeadTime = ctx.GlobalDuration(ProduceLeadTimeFlag.Name)
+	}
+	if ctx.GlobalIsSet(PropagationFlag.Name) {
+		cfg.Propagation.Strategy = ctx.GlobalString(PropagationFlag.Name)
+	}
+	if ctx.GlobalIsSet(PropagationFanoutFlag.Name) {
+		cfg.Propagation.Fanout = ctx.GlobalInt(PropagationFanoutFlag.Name)
+	}
 	if ctx.GlobalIsSet(VMEnableDebugFlag.Name) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
@@ -923,17 +1267,20 @@ func SetaoaConfig(ctx *cli.Context, stack *node.Node, cfg *aoa.Config) {
 		if accs := ks.Accounts(); len(accs) > 0 {
 			developer = ks.Accounts()[0]
 		} else {
-			developer, err = ks.NewAccount("")
+			developer, err = ks.ImportECDSA(core.DeveloperKey(0), "")
 			if err != nil {
-				Fatalf("Failed to create developer account: %v", err)
+				Fatalf("Failed to import developer account: %v", err)
 			}
 		}
 		if err := ks.Unlock(developer, ""); err != nil {
 			Fatalf("Failed to unlock developer account: %v", err)
 		}
 		log.Info("Using developer account", "address", developer.Address)
-
-		cfg.Genesis = core.DeveloperGenesisBlock(developer.Address)
+		for i := 0; i < core.DeveloperAccounts; i++ {
+			key := core.DeveloperKey(i)
+			log.Info("Pre-funded developer account", "address", crypto.PubkeyToAddress(key.PublicKey), "key", hexutil.Encode(crypto.FromECDSA(key)))
+		}
+		cfg.Genesis = core.DeveloperGenesisBlock(uint64(ctx.GlobalInt(DeveloperPeriodFlag.Name)), developer.Address)
 		if !ctx.GlobalIsSet(GasPriceFlag.Name) {
 			cfg.GasPrice = big.NewInt(4000000000)
 		}
@@ -996,6 +1343,20 @@ func RegisteraoaStatsService(stack *node.Node, url string) {
 	}
 }
 
+// RegisterSchedulerService configures the transaction scheduler and adds it to
+// the given node.
+func RegisterSchedulerService(stack *node.Node) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var dacServ *aoa.Dacchain
+		if err := ctx.Service(&dacServ); err != nil {
+			return nil, err
+		}
+		return txscheduler.New(ctx.ResolvePath("scheduled.rlp"), dacServ.BlockChain(), dacServ.TxPool()), nil
+	}); err != nil {
+		Fatalf("Failed to register the transaction scheduler service: %v", err)
+	}
+}
+
 // SetupNetwork configures the systaoa for either the main net or some test network.
 func SetupNetwork(ctx *cli.Context) {
 	// TODO(fjl): move target gas limit into config
@@ -1047,10 +1408,22 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 		Fatalf("%v", err)
 	}
 	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
-	chain, err = core.NewBlockChain(chainDb, config, aoa.CreateDacchainConsensusEngine(), vmcfg, itxDb)
+	cache := &core.CacheConfig{
+		Disabled:           ctx.GlobalString(GCModeFlag.Name) == "archive",
+		TrieCleanLimit:     ctx.GlobalInt(TrieCacheFlag.Name),
+		TrieDirtyLimit:     ctx.GlobalInt(TrieDirtyCacheFlag.Name),
+		TrieCommitInterval: ctx.GlobalUint64(TrieCommitIntervalFlag.Name),
+	}
+	chain, err = core.NewBlockChain(chainDb, cache, config, aoa.CreateDacchainConsensusEngine(), vmcfg, itxDb)
 	if err != nil {
 		Fatalf("Can't create BlockChain: %v", err)
 	}
+	chain.SetTxLookupLimit(ctx.GlobalUint64(TxLookupLimitFlag.Name))
+	chain.SetHistoryLimit(ctx.GlobalUint64(HistoryLimitFlag.Name))
+	if workers := ctx.GlobalInt(ProcessorWorkersFlag.Name); workers > 1 {
+		chain.SetProcessor(core.NewParallelProcessor(config, chain, chain.Engine(), workers))
+	}
+	chain.SetStateStats(ctx.GlobalBool(MetricsStateAccessFlag.Name))
 	return chain, chainDb
 }
 
@@ -47,38 +47,62 @@ type ChainReader interface {
 	GetBlock(hash common.Hash, number uint64) *types.Block
 }
 
-// DAC consensus engine.
+// Engine is an algorithm agnostic consensus engine. The DPoS engine is the
+// default, other engines let test networks run without delegates.
 type Engine interface {
-
 	// Author retrieves the dacchain address of the account that minted the given
 	// block, which may be different from the header's coinbase if a consensus
 	// engine is based on signatures.
 	Author(header *types.Header) (common.Address, error)
 
-	Prepare(chain ChainReader, header *types.Header) error
-
-	Finalize(chain ChainReader, header *types.Header, state *state.StateDB, dState *delegatestate.DelegateDB, txs []*types.Transaction, receipts []*types.Receipt) (*types.Block, error)
-
 	// VerifyHeader checks whether a header conforms to the consensus rules of a
 	// given engine. Verifying the seal may be done optionally here, or explicitly
 	// via the VerifySeal method.
 	VerifyHeader(chain ChainReader, header *types.Header) error
 
-	// check the block header sign,check coinbase when receive block by delegate p2p net
-	VerifyHeaderAndSign(chain ChainReader, block *types.Block, currentShuffleList *types.ShuffleList, blockInterval int) error
-
-	// verify confirm sign is correct
-	VerifySignatureSend(blockHash common.Hash, confirmSign []byte, currentShuffleList *types.ShuffleList) error
-
-	// verify block when ordinary node receive
-	VerifyBlockGenerate(chain ChainReader, block *types.Block, currentShuffleList *types.ShuffleList, blockInterval int) error
-
 	// VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers
 	// concurrently. The method returns a quit channel to abort the operations and
 	// a results channel to retrieve the async verifications (the order is that of
 	// the input slice).
 	VerifyHeaders(chain ChainReader, headers []*types.Header) (chan<- struct{}, <-chan error)
 
+	// Prepare initializes the consensus fields of a block header according to the
+	// rules of a particular engine. The changes are executed inline.
+	Prepare(chain ChainReader, header *types.Header) error
+
+	// Finalize runs any post-transaction state modifications (e.g. block rewards)
+	// and assembles the final block. The block header and state database might be
+	// updated to reflect any consensus rules that happen at finalization.
+	Finalize(chain ChainReader, header *types.Header, state *state.StateDB, dState *delegatestate.DelegateDB, txs []*types.Transaction, receipts []*types.Receipt) (*types.Block, error)
+
+	// Seal generates a new block for the given input block with the local
+	// producer's seal on top. Closing stop aborts engines sealing asynchronously.
+	Seal(chain ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error)
+
 	// APIs returns the RPC APIs this consensus engine provides.
 	APIs(chain ChainReader) []rpc.API
 }
+
+// SignerFn signs the given hash with the key of the given account.
+type SignerFn func(signer common.Address, hash []byte) ([]byte, error)
+
+// Authorizer is implemented by engines sealing blocks with a signature of their
+// producer, which the block producer authorizes to sign on its behalf.
+type Authorizer interface {
+	Authorize(signFn SignerFn)
+}
+
+// Delegated is a consensus engine scheduling its block producers in rounds of
+// delegates, verifying blocks relayed on the delegate network against them.
+type Delegated interface {
+	Engine
+
+	// check the block header sign,check coinbase when receive block by delegate p2p net
+	VerifyHeaderAndSign(chain ChainReader, block *types.Block, currentShuffleList *types.ShuffleList, blockInterval int) error
+
+	// verify confirm sign is correct
+	VerifySignatureSend(blockHash common.Hash, confirmSign []byte, currentShuffleList *types.ShuffleList) error
+
+	// verify block when ordinary node receive
+	VerifyBlockGenerate(chain ChainReader, block *types.Block, currentShuffleList *types.ShuffleList, blockInterval int) error
+}
@@ -169,6 +169,7 @@ func (d *DelegateDB) Logs() []*types.Log {
 // get current sort delegates
 func (d *DelegateDB) GetDelegates() []types.Candidate {
 	list := make([]types.Candidate, 0)
+	weights := make([]uint64, 0)
 
 	for k, v := range d.delegateObjects {
 		if v.data.Delete || v.suicided {
@@ -176,13 +177,36 @@ func (d *DelegateDB) GetDelegates() []types.Candidate {
 		}
 		candidate := types.Candidate{Address: k.Hex(), Vote: v.data.Vote.Uint64(), Nickname: v.data.Nickname, RegisterTime: v.data.RegisterTime}
 		list = append(list, candidate)
+		weights = append(weights, v.GetState(d.db, voteWeightKey).Big().Uint64())
 	}
 	if len(list) > 1 {
-		sort.Sort(types.CandidateSlice(list))
+		sort.Sort(weightedCandidates{list, weights})
 	}
 	return list
 }
 
+// weightedCandidates sorts candidates by their weighted votes, falling back to
+// the candidate order for equal weights. Weights are zero before the vote decay
+// fork, leaving the candidate order unchanged.
+type weightedCandidates struct {
+	candidates types.CandidateSlice
+	weights    []uint64
+}
+
+func (w weightedCandidates) Len() int { return len(w.candidates) }
+
+func (w weightedCandidates) Swap(i, j int) {
+	w.candidates.Swap(i, j)
+	w.weights[i], w.weights[j] = w.weights[j], w.weights[i]
+}
+
+func (w weightedCandidates) Less(i, j int) bool {
+	if w.weights[i] != w.weights[j] {
+		return w.weights[i] > w.weights[j]
+	}
+	return w.candidates.Less(i, j)
+}
+
 func (d *DelegateDB) clearJournal() {
 	d.journal = nil
 	d.validRevisions = d.validRevisions[:0]
@@ -331,6 +355,20 @@ func (d *DelegateDB) SetVote(addr common.Address, amount *big.Int) {
 	}
 }
 
+// missedSlotsKey is the storage slot of a delegate counting the consecutive block
+// slots it was scheduled in but did not produce a block.
+var missedSlotsKey = common.BytesToHash([]byte("missedSlots"))
+
+// GetMissedSlots returns the number of consecutive block slots the delegate missed.
+func (d *DelegateDB) GetMissedSlots(addr common.Address) uint64 {
+	return d.GetState(addr, missedSlotsKey).Big().Uint64()
+}
+
+// SetMissedSlots sets the number of consecutive block slots the delegate missed.
+func (d *DelegateDB) SetMissedSlots(addr common.Address, slots uint64) {
+	d.SetState(addr, missedSlotsKey, common.BigToHash(new(big.Int).SetUint64(slots)))
+}
+
 func (d *DelegateDB) Suicide(addr common.Address) bool {
 	stateObject := d.GetStateObject(addr)
 	if stateObject == nil {
@@ -0,0 +1,270 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package delegatestate
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+var (
+	// voteWeightKey is the storage slot of a delegate holding its stake weighted,
+	// decayed vote as computed at the last epoch transition.
+	voteWeightKey = common.BytesToHash([]byte("voteWeight"))
+
+	// votedStakesKey is the storage slot of a delegate counting the votes backed
+	// by a stake record. Its remaining votes were cast before the vote decay fork.
+	votedStakesKey = common.BytesToHash([]byte("votedStakes"))
+
+	// votersKey is the storage slot of a delegate counting its indexed voters.
+	votersKey = common.BytesToHash([]byte("voters"))
+)
+
+// voteStakeKey returns the storage slot of a delegate holding the stake record of
+// a voter: the stake in its upper and the decay period plus one in its lower 8 bytes.
+func voteStakeKey(voter common.Address) common.Hash {
+	return crypto.Keccak256Hash([]byte("voteStake"), voter[:])
+}
+
+// votePeriodKey returns the storage slot of a delegate summing the stakes of the
+// votes cast or refreshed in the given decay period.
+func votePeriodKey(period uint64) common.Hash {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, period)
+	return crypto.Keccak256Hash([]byte("votePeriod"), enc)
+}
+
+// voterKey returns the storage slot of a delegate holding its indexed voter at
+// the given position.
+func voterKey(index uint64) common.Hash {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, index)
+	return crypto.Keccak256Hash([]byte("voter"), enc)
+}
+
+// voterIndexKey returns the storage slot of a delegate holding the position plus
+// one of an indexed voter.
+func voterIndexKey(voter common.Address) common.Hash {
+	return crypto.Keccak256Hash([]byte("voterIndex"), voter[:])
+}
+
+// getBig and setBig read and write a storage slot of a delegate as a number.
+func (d *DelegateDB) getBig(addr common.Address, key common.Hash) *big.Int {
+	return d.GetState(addr, key).Big()
+}
+
+func (d *DelegateDB) setBig(addr common.Address, key common.Hash, value *big.Int) {
+	if value.Sign() < 0 {
+		value = new(big.Int)
+	}
+	d.SetState(addr, key, common.BigToHash(value))
+}
+
+// voteStake returns the stake and decay period of the vote of the voter on the
+// delegate, ok is false if the vote has no stake record.
+func (d *DelegateDB) voteStake(delegate, voter common.Address) (stake *big.Int, period uint64, ok bool) {
+	record := d.GetState(delegate, voteStakeKey(voter))
+	if record == (common.Hash{}) {
+		return nil, 0, false
+	}
+	return new(big.Int).SetBytes(record[:24]), binary.BigEndian.Uint64(record[24:]) - 1, true
+}
+
+// CastVote records the vote of the voter on the delegate as backed by the given
+// stake in the given decay period, replacing the previous record of the vote,
+// and indexes the voter among the voters of the delegate.
+func (d *DelegateDB) CastVote(delegate, voter common.Address, stake *big.Int, period uint64) {
+	if !d.Exist(delegate) {
+		return
+	}
+	if prevStake, prevPeriod, ok := d.voteStake(delegate, voter); ok {
+		d.setBig(delegate, votePeriodKey(prevPeriod), new(big.Int).Sub(d.getBig(delegate, votePeriodKey(prevPeriod)), prevStake))
+	} else {
+		d.setBig(delegate, votedStakesKey, new(big.Int).Add(d.getBig(delegate, votedStakesKey), common.Big1))
+	}
+	d.setBig(delegate, votePeriodKey(period), new(big.Int).Add(d.getBig(delegate, votePeriodKey(period)), stake))
+
+	var record common.Hash
+	copy(record[:24], common.LeftPadBytes(stake.Bytes(), 24))
+	binary.BigEndian.PutUint64(record[24:], period+1)
+	d.SetState(delegate, voteStakeKey(voter), record)
+
+	d.addVoter(delegate, voter, stake)
+}
+
+// VoterState gives access to the balances and votes of the accounts of a state.
+type VoterState interface {
+	GetBalance(addr common.Address) *big.Int
+	GetLockBalance(addr common.Address) *big.Int
+	GetVoteList(addr common.Address) []common.Address
+}
+
+// VoterStake returns the stake backing the votes of the voter, its free and
+// locked balance in units of params.Em.
+func VoterStake(state VoterState, voter common.Address) *big.Int {
+	stake := new(big.Int).Add(state.GetBalance(voter), state.GetLockBalance(voter))
+	return stake.Div(stake, big.NewInt(params.Em))
+}
+
+// RestakeVoters updates the stake records of the votes of the given voters to
+// their current stake, keeping the decay periods the votes were cast in. It is
+// called with the accounts whose balance changed, so the votes stay weighted by
+// the stake of their voters.
+func (d *DelegateDB) RestakeVoters(state VoterState, voters []common.Address) {
+	for _, voter := range voters {
+		votes := state.GetVoteList(voter)
+		if len(votes) == 0 {
+			continue
+		}
+		stake := VoterStake(state, voter)
+		for _, delegate := range votes {
+			if prevStake, period, ok := d.voteStake(delegate, voter); ok && prevStake.Cmp(stake) != 0 {
+				d.CastVote(delegate, voter, stake, period)
+			}
+		}
+	}
+}
+
+// WithdrawVote drops the stake record of the vote of the voter on the delegate,
+// and the voter from the index of its voters.
+func (d *DelegateDB) WithdrawVote(delegate, voter common.Address) {
+	stake, period, ok := d.voteStake(delegate, voter)
+	if !ok {
+		return
+	}
+	d.setBig(delegate, votePeriodKey(period), new(big.Int).Sub(d.getBig(delegate, votePeriodKey(period)), stake))
+	d.setBig(delegate, votedStakesKey, new(big.Int).Sub(d.getBig(delegate, votedStakesKey), common.Big1))
+	d.SetState(delegate, voteStakeKey(voter), common.Hash{})
+
+	d.removeVoter(delegate, voter)
+}
+
+// VoteWeight computes the weighted vote of the delegate in the given decay period.
+// The stake of every vote halves with each period passed since it was cast or
+// refreshed. Votes cast before the fork period count a stake of one each.
+func (d *DelegateDB) VoteWeight(delegate common.Address, period, forkPeriod uint64) *big.Int {
+	weight := new(big.Int)
+	if period < forkPeriod {
+		return weight
+	}
+	for age := uint64(0); age < params.MaxVoteDecayPeriods && age <= period-forkPeriod; age++ {
+		stake := d.getBig(delegate, votePeriodKey(period-age))
+		weight.Add(weight, stake.Rsh(stake, uint(age)))
+	}
+	if age := period - forkPeriod; age < params.MaxVoteDecayPeriods {
+		legacy := new(big.Int).Sub(d.GetVote(delegate), d.getBig(delegate, votedStakesKey))
+		if legacy.Sign() > 0 {
+			weight.Add(weight, legacy.Rsh(legacy, uint(age)))
+		}
+	}
+	return weight
+}
+
+// GetVoteWeight returns the weighted vote of the delegate stored at the last
+// epoch transition.
+func (d *DelegateDB) GetVoteWeight(addr common.Address) uint64 {
+	return d.getBig(addr, voteWeightKey).Uint64()
+}
+
+// UpdateVoteWeights recomputes and stores the weighted votes of all delegates in
+// the given decay period, ranking the delegates by them from then on.
+func (d *DelegateDB) UpdateVoteWeights(period, forkPeriod uint64) {
+	for _, delegate := range d.GetDelegates() {
+		addr := common.HexToAddress(delegate.Address)
+		d.setBig(addr, voteWeightKey, d.VoteWeight(addr, period, forkPeriod))
+	}
+}
+
+// VoterWeight returns the weight of the vote of the voter on the delegate in the
+// given decay period, its stake halved with every period passed since the vote
+// was cast or refreshed. Votes without stake record weigh nothing.
+func (d *DelegateDB) VoterWeight(delegate, voter common.Address, period uint64) *big.Int {
+	stake, cast, ok := d.voteStake(delegate, voter)
+	if !ok || cast > period || period-cast >= params.MaxVoteDecayPeriods {
+		return new(big.Int)
+	}
+	return stake.Rsh(stake, uint(period-cast))
+}
+
+// addVoter indexes the voter among the voters of the delegate, unless it is
+// indexed already. Once params.MaxFeeShareVoters are indexed, the voter replaces
+// the indexed voter with the lowest stake if its own stake is higher.
+func (d *DelegateDB) addVoter(delegate, voter common.Address, stake *big.Int) {
+	if !d.Exist(delegate) || d.GetState(delegate, voterIndexKey(voter)) != (common.Hash{}) {
+		return
+	}
+	count := d.getBig(delegate, votersKey).Uint64()
+	if count >= params.MaxFeeShareVoters {
+		var (
+			lowest      common.Address
+			lowestStake *big.Int
+		)
+		for i := uint64(0); i < count; i++ {
+			hash := d.GetState(delegate, voterKey(i))
+			indexed := common.BytesToAddress(hash[:])
+			indexedStake, _, ok := d.voteStake(delegate, indexed)
+			if !ok {
+				indexedStake = new(big.Int)
+			}
+			if lowestStake == nil || indexedStake.Cmp(lowestStake) < 0 {
+				lowest, lowestStake = indexed, indexedStake
+			}
+		}
+		if lowestStake.Cmp(stake) >= 0 {
+			return
+		}
+		d.removeVoter(delegate, lowest)
+		count--
+	}
+	d.SetState(delegate, voterKey(count), voter.Hash())
+	d.setBig(delegate, voterIndexKey(voter), new(big.Int).SetUint64(count+1))
+	d.setBig(delegate, votersKey, new(big.Int).SetUint64(count+1))
+}
+
+// removeVoter drops the voter from the index of the voters of the delegate,
+// moving the last indexed voter into its position.
+func (d *DelegateDB) removeVoter(delegate, voter common.Address) {
+	position := d.getBig(delegate, voterIndexKey(voter)).Uint64()
+	if position == 0 {
+		return
+	}
+	last := d.getBig(delegate, votersKey).Uint64() - 1
+	if index := position - 1; index != last {
+		moved := d.GetState(delegate, voterKey(last))
+		d.SetState(delegate, voterKey(index), moved)
+		d.setBig(delegate, voterIndexKey(common.BytesToAddress(moved[:])), new(big.Int).SetUint64(position))
+	}
+	d.SetState(delegate, voterKey(last), common.Hash{})
+	d.SetState(delegate, voterIndexKey(voter), common.Hash{})
+	d.setBig(delegate, votersKey, new(big.Int).SetUint64(last))
+}
+
+// Voters returns the indexed voters of the delegate, in index order. At most
+// params.MaxFeeShareVoters voters are indexed.
+func (d *DelegateDB) Voters(delegate common.Address) []common.Address {
+	count := d.getBig(delegate, votersKey).Uint64()
+	voters := make([]common.Address, 0, count)
+	for i := uint64(0); i < count; i++ {
+		hash := d.GetState(delegate, voterKey(i))
+		voters = append(voters, common.BytesToAddress(hash[:]))
+	}
+	return voters
+}
@@ -0,0 +1,119 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+var (
+	// errMissingCheckpoint is returned if a checkpoint header doesn't commit to the
+	// delegate set.
+	errMissingCheckpoint = errors.New("missing checkpoint delegate set root")
+
+	// errUnexpectedCheckpoint is returned if a header other than the checkpoints
+	// carries a delegate set commitment.
+	errUnexpectedCheckpoint = errors.New("unexpected checkpoint delegate set root")
+
+	// errInvalidCheckpoint is returned if the delegate set root of a checkpoint
+	// doesn't match the delegate set its round was shuffled from.
+	errInvalidCheckpoint = errors.New("invalid checkpoint delegate set root")
+)
+
+// checkpointsActive returns whether the block with the given number is past the
+// checkpoint fork. The genesis block never is a checkpoint.
+func checkpointsActive(config *params.ChainConfig, num *big.Int) bool {
+	return config.CheckpointBlock != nil && num.Sign() > 0 && num.Cmp(config.CheckpointBlock) >= 0
+}
+
+// isCheckpoint returns whether the header must commit to the delegate set its
+// round was shuffled from. Besides the epoch checkpoints, these are the fork block
+// and every later header switching to a newly shuffled round, as rounds within
+// an epoch are shuffled from other blocks than the epoch checkpoint.
+func isCheckpoint(config *params.ChainConfig, parent, header *types.Header) bool {
+	if config.IsCheckpoint(header.Number) {
+		return true
+	}
+	if !checkpointsActive(config, header.Number) {
+		return false
+	}
+	if header.Number.Cmp(config.CheckpointBlock) == 0 {
+		return true
+	}
+	if header.ShuffleBlockNumber == nil {
+		return false
+	}
+	return parent.ShuffleBlockNumber == nil || parent.ShuffleBlockNumber.Cmp(header.ShuffleBlockNumber) != 0
+}
+
+// verifyCheckpoint checks that exactly the checkpoint headers commit to a delegate
+// set. The commitment itself is verified when finalizing the block.
+func verifyCheckpoint(config *params.ChainConfig, parent, header *types.Header) error {
+	if !isCheckpoint(config, parent, header) {
+		if len(header.Checkpoint) != 0 {
+			return errUnexpectedCheckpoint
+		}
+		return nil
+	}
+	if len(header.Checkpoint) != 1 {
+		return errMissingCheckpoint
+	}
+	return nil
+}
+
+// commitCheckpoint commits a checkpoint header to the top ranked delegates of the
+// block its round was shuffled from, or verifies the commitment if the header
+// already carries one. Headers other than checkpoints are left untouched.
+func commitCheckpoint(chain consensus.ChainReader, header *types.Header) error {
+	config := chain.Config()
+	if !checkpointsActive(config, header.Number) {
+		return nil
+	}
+	reader, ok := chain.(delegateStateReader)
+	if !ok {
+		return errNoDelegateState
+	}
+	parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	if !isCheckpoint(config, parent, header) {
+		return nil
+	}
+	if header.ShuffleBlockNumber == nil {
+		return ErrUnknownShuffleBlock
+	}
+	candidates, err := ancestorCandidates(chain, reader, parent, header.ShuffleBlockNumber.Uint64())
+	if err != nil {
+		return err
+	}
+	root := types.NewDelegateSet(candidates, int(config.MaxElectDelegate.Int64())).Root()
+	if have, ok := header.CheckpointRoot(); ok {
+		if have != root {
+			return errInvalidCheckpoint
+		}
+		return nil
+	}
+	header.Checkpoint = []common.Hash{root}
+	return nil
+}
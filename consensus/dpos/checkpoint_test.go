@@ -0,0 +1,145 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus/delegatestate"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// Tests that exactly the epoch checkpoints and the headers switching to a newly
+// shuffled round commit to a delegate set, and that the commitment is checked
+// against the delegate state of the block the round was shuffled from.
+func TestCheckpoint(t *testing.T) {
+	epoch := params.EpochDuration
+	config := &params.ChainConfig{MaxElectDelegate: big.NewInt(2), CheckpointBlock: new(big.Int).SetUint64(epoch)}
+
+	// Create two delegate states ranking the delegates differently
+	db, _ := aoadb.NewMemDatabase()
+	dState, _ := delegatestate.New(common.Hash{}, delegatestate.NewDatabase(db))
+	for i := byte(1); i <= 3; i++ {
+		addr := common.Address{i}
+		dState.GetOrNewStateObject(addr, "delegate", uint64(i))
+		dState.AddVote(addr, big.NewInt(int64(i)))
+	}
+	root1, _ := dState.CommitTo(db, false)
+
+	dState, _ = delegatestate.New(root1, delegatestate.NewDatabase(db))
+	dState.AddVote(common.Address{1}, big.NewInt(10))
+	root2, _ := dState.CommitTo(db, false)
+
+	chain := &testJailChain{
+		config:    config,
+		headers:   make(map[common.Hash]*types.Header),
+		delegates: delegatestate.NewDatabase(db),
+	}
+	newHeader := func(parent *types.Header, shuffleBlock uint64, root common.Hash) *types.Header {
+		header := &types.Header{
+			Number:             new(big.Int).SetUint64(epoch - 2),
+			ShuffleBlockNumber: new(big.Int).SetUint64(shuffleBlock),
+			DelegateRoot:       root,
+		}
+		if parent != nil {
+			header.ParentHash = parent.Hash()
+			header.Number = new(big.Int).Add(parent.Number, common.Big1)
+		}
+		return header
+	}
+	commit := func(header *types.Header, want types.DelegateSet) {
+		parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+		if want == nil {
+			if err := verifyCheckpoint(config, parent, header); err != nil {
+				t.Errorf("block %d: verification failed: %v", header.Number, err)
+			}
+			if err := commitCheckpoint(chain, header); err != nil {
+				t.Errorf("block %d: failed to commit: %v", header.Number, err)
+			}
+			if len(header.Checkpoint) != 0 {
+				t.Errorf("block %d: unexpected checkpoint", header.Number)
+			}
+			header.Checkpoint = []common.Hash{{1}}
+			if err := verifyCheckpoint(config, parent, header); err != errUnexpectedCheckpoint {
+				t.Errorf("block %d: error mismatch: have %v, want %v", header.Number, err, errUnexpectedCheckpoint)
+			}
+			header.Checkpoint = nil
+		} else {
+			if err := verifyCheckpoint(config, parent, header); err != errMissingCheckpoint {
+				t.Errorf("block %d: error mismatch: have %v, want %v", header.Number, err, errMissingCheckpoint)
+			}
+			if err := commitCheckpoint(chain, header); err != nil {
+				t.Fatalf("block %d: failed to commit: %v", header.Number, err)
+			}
+			if err := verifyCheckpoint(config, parent, header); err != nil {
+				t.Errorf("block %d: verification failed: %v", header.Number, err)
+			}
+			if root, _ := header.CheckpointRoot(); root != want.Root() {
+				t.Errorf("block %d: root mismatch: have %x, want %x", header.Number, root, want.Root())
+			}
+			if err := commitCheckpoint(chain, header); err != nil {
+				t.Errorf("block %d: failed to verify commitment: %v", header.Number, err)
+			}
+		}
+		chain.headers[header.Hash()] = header
+	}
+	// Blocks before the fork carry no commitment, even if switching rounds
+	first := newHeader(nil, epoch-3, root1)
+	chain.headers[first.Hash()] = first
+
+	beforeFork := newHeader(first, epoch-2, root2)
+	commit(beforeFork, nil)
+
+	// The epoch checkpoint commits to the delegates of its shuffle block, not the
+	// ones ranked by itself or its parent
+	checkpoint := newHeader(beforeFork, epoch-2, root2)
+	commit(checkpoint, types.DelegateSet{{3}, {2}})
+
+	// Blocks of the same round carry no commitment, ones of new rounds do
+	sameRound := newHeader(checkpoint, epoch-2, root2)
+	commit(sameRound, nil)
+
+	newRound := newHeader(sameRound, epoch-1, root2)
+	commit(newRound, types.DelegateSet{{1}, {3}})
+
+	// A commitment not matching the shuffle block is rejected
+	invalid := newHeader(newRound, epoch-2, root2)
+	invalid.Checkpoint = []common.Hash{types.DelegateSet{{1}, {3}}.Root()}
+	if err := commitCheckpoint(chain, invalid); err != errInvalidCheckpoint {
+		t.Errorf("error mismatch: have %v, want %v", err, errInvalidCheckpoint)
+	}
+}
+
+// Tests that the checkpoint is only encoded into checkpoint headers, leaving the
+// hashes of all other headers unchanged.
+func TestCheckpointEncoding(t *testing.T) {
+	header := &types.Header{Number: big.NewInt(1), Time: big.NewInt(1), ShuffleBlockNumber: big.NewInt(0)}
+	hash := header.Hash()
+
+	header.Checkpoint = []common.Hash{{1}}
+	if header.Hash() == hash {
+		t.Fatalf("checkpoint not committed to by the header hash")
+	}
+	header.Checkpoint = nil
+	if header.Hash() != hash {
+		t.Fatalf("header hash changed without checkpoint")
+	}
+}
@@ -23,14 +23,15 @@ import (
 	"github.com/Aurorachain-io/go-aoa/consensus"
 	"github.com/Aurorachain-io/go-aoa/consensus/delegatestate"
 	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/systemcontracts"
 	"github.com/Aurorachain-io/go-aoa/core/types"
 	"github.com/Aurorachain-io/go-aoa/crypto"
 	"github.com/Aurorachain-io/go-aoa/crypto/secp256k1"
 	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/metrics"
 	"github.com/Aurorachain-io/go-aoa/params"
 	"github.com/Aurorachain-io/go-aoa/rlp"
 	"github.com/Aurorachain-io/go-aoa/rpc"
-	"math"
 	"math/big"
 	"runtime"
 	"strings"
@@ -41,6 +42,11 @@ import (
 var (
 	allowedFutureBlockTime = 15 * time.Second // Max time from current time allowed for blocks, before they're considered future blocks
 	errZeroBlockTime       = errors.New("timestamp equals parent's")
+	errMissingExtra        = errors.New("missing structured extra-data")
+	errExtraShuffleEpoch   = errors.New("extra-data shuffle epoch mismatch")
+	errMalleableSignature  = errors.New("non-canonical high-s signature")
+
+	malleableSignCounter = metrics.NewCounter("dpos/malleable") // Block and vote signatures rejected for a high-s value
 )
 
 // Config are the configuration parameters of the ethash.
@@ -51,27 +57,38 @@ type Config struct {
 type DacchainDpos struct {
 	// config Config
 	lock sync.Mutex
+
+	signFn consensus.SignerFn // Signer of the blocks produced by local delegates
 }
 
 func New() *DacchainDpos {
 	return &DacchainDpos{}
 }
 
-// annul with 15% profit,AccumulateRewards credits the coinbase of the given block with the produce reward
-func accumulateEmRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header) {
-	// begin with 100 reward
-	var (
-		// begin with 500 reward
-		basicReward        float64 = 500
-		annulProfit                = params.AnnulProfit
-		annulBlockAmount           = params.AnnulBlockAmount
-		blockReward                = big.NewInt(1e+18)
-	)
-	yearNumber := header.Number.Int64() / annulBlockAmount.Int64()
-	currentReward := (int64)(basicReward * math.Pow(annulProfit, float64(yearNumber)))
-	precisionReward := new(big.Int).Mul(big.NewInt(currentReward), blockReward)
-	reward := new(big.Int).Set(precisionReward)
-	state.AddBalance(header.Coinbase, reward)
+// Authorize implements consensus.Authorizer, injecting the signer of the blocks
+// produced by the local delegates.
+func (d *DacchainDpos) Authorize(signFn consensus.SignerFn) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.signFn = signFn
+}
+
+// Seal implements consensus.Engine, signing the block by its producer. Blocks are
+// produced in their delegate's slot, so sealing never waits.
+func (d *DacchainDpos) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error) {
+	d.lock.Lock()
+	signFn := d.signFn
+	d.lock.Unlock()
+
+	if signFn == nil {
+		return nil, consensus.ErrUnauthorized
+	}
+	sig, err := signFn(block.Coinbase(), block.Hash().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return block.WithSignature(sig), nil
 }
 
 // check parent exist and cache header
@@ -84,7 +101,43 @@ func (d *DacchainDpos) Prepare(chain consensus.ChainReader, header *types.Header
 }
 
 func (d *DacchainDpos) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, dState *delegatestate.DelegateDB, txs []*types.Transaction, receipts []*types.Receipt) (*types.Block, error) {
-	accumulateEmRewards(chain.Config(), state, header)
+	policy, err := rewardPolicy(chain.Config())
+	if err != nil {
+		return nil, err
+	}
+	if err := policy.Distribute(chain.Config(), header, state, dState, blockFees(txs, receipts)); err != nil {
+		return nil, err
+	}
+	// Keep the votes of the accounts rewarded weighted by their stake
+	if chain.Config().IsVoteDecay(header.Number) {
+		dState.RestakeVoters(state, state.BalanceChanges())
+	}
+
+	// Deactivate the delegates repeatedly missing their slots, so they are left
+	// out of the next shuffled round
+	if chain.Config().IsDelegateJail(header.Number) {
+		if err := jailDelegates(chain, header, dState); err != nil {
+			return nil, err
+		}
+	}
+	// Rerank the delegates by their stake weighted, decayed votes at every epoch
+	if config := chain.Config(); config.IsVoteDecay(header.Number) && header.Number.Uint64()%config.Epoch() == 0 {
+		dState.UpdateVoteWeights(config.VoteDecayPeriod(header.Number), config.VoteDecayPeriod(config.VoteDecayBlock))
+	}
+	// Commit checkpoints to the delegate set their round was shuffled from
+	if err := commitCheckpoint(chain, header); err != nil {
+		return nil, err
+	}
+
+	// Install scheduled system contract upgrades and let the reward contract
+	// account for the block. A failing contract call reverts its own changes
+	// but doesn't invalidate the block.
+	if err := systemcontracts.Upgrade(chain.Config(), header.Number, state); err != nil {
+		return nil, err
+	}
+	if err := systemcontracts.NotifyBlockFinalized(chain, header, state); err != nil {
+		log.Warn("System contract call failed", "contract", systemcontracts.RewardContract, "number", header.Number, "err", err)
+	}
 
 	header.Root = state.IntermediateRoot(false)
 	header.DelegateRoot = dState.IntermediateRoot(false)
@@ -101,11 +154,11 @@ func (d *DacchainDpos) VerifyHeaderAndSign(chain consensus.ChainReader, block *t
 	currentTime := time.Now().Unix()
 	if currentTime < int64(blockTime) || (currentTime >= (int64(blockTime) + int64(blockInterval))) {
 		errMsg := fmt.Sprintf("block time is expire|blockNumber:%d blockTime:%d currentTime:%d", block.NumberU64(), blockTime, currentTime)
-		return errors.New(errMsg)
+		return &consensus.ViewError{Err: errors.New(errMsg)}
 	}
 	if parent == nil {
 		errMsg := fmt.Sprintf("unknown ancestor|currentBlockNumber:%d blockParentHash:%s", block.NumberU64(), block.ParentHash().Hex())
-		return errors.New(errMsg)
+		return &consensus.ViewError{Err: errors.New(errMsg)}
 	}
 	err := d.verifyHeader(chain, header, parent)
 	if err != nil {
@@ -117,6 +170,10 @@ func (d *DacchainDpos) VerifyHeaderAndSign(chain consensus.ChainReader, block *t
 		errMsg := fmt.Sprintf("miss Signature blockNumber:%d", block.NumberU64())
 		return errors.New(errMsg)
 	}
+	if chain.Config().IsLowS(header.Number) && !crypto.IsCanonicalSignature(coinbaseSign) {
+		malleableSignCounter.Inc(1)
+		return errMalleableSignature
+	}
 	var delegate types.ShuffleDel
 	var exist bool
 	log.Debug("VerifyHeaderAndSign", "coinbase", coinbase, "shuffleDelsLen", len(currentShuffleList.ShuffleDels))
@@ -128,12 +185,12 @@ func (d *DacchainDpos) VerifyHeaderAndSign(chain consensus.ChainReader, block *t
 		}
 	}
 	if !exist {
-		return errors.New(fmt.Sprintf("coinbase:%s not exist in current shuffle list", coinbase))
+		return &consensus.ViewError{Err: errors.New(fmt.Sprintf("coinbase:%s not exist in current shuffle list", coinbase))}
 	}
 	log.Debug("VerifyHeaderAndSign", "delegate", delegate)
 	if blockTime != delegate.WorkTime {
 		errMsg := fmt.Sprintf("timeStamp not same blockNumber:%d coinbase:%s blockTime:%d shuffleTime:%d", block.NumberU64(), coinbase, blockTime, delegate.WorkTime)
-		return errors.New(errMsg)
+		return &consensus.ViewError{Err: errors.New(errMsg)}
 	}
 	pubkey, err := secp256k1.RecoverPubkey(block.Hash().Bytes()[:32], coinbaseSign)
 	log.Debug("VerifyHeaderAndSign", "coinbaseSign", coinbaseSign, "blockHash", block.Hash().Bytes(), "pubkey", pubkey, "err", err)
@@ -181,7 +238,12 @@ func (d *DacchainDpos) VerifyBlockGenerate(chain consensus.ChainReader, block *t
 
 	checkSignAddressMap := make(map[string]byte, 0)
 	blockHashBytes := block.Hash().Bytes()
+	lowS := genesisConfig.IsLowS(block.Number())
 	for _, sign := range signs {
+		if lowS && !crypto.IsCanonicalSignature(sign.Sign) {
+			malleableSignCounter.Inc(1)
+			return errMalleableSignature
+		}
 		tempPubKey, _ := secp256k1.RecoverPubkey(blockHashBytes[:32], sign.Sign)
 		tempPubAddress := crypto.PubkeyToAddress(*crypto.ToECDSAPub(tempPubKey)).Hex()
 		if checkInShuffleList(currentShuffleList, tempPubAddress) {
@@ -282,6 +344,23 @@ func (d *DacchainDpos) verifyHeader(chain consensus.ChainReader, header, parent
 	if uint64(len(header.Extra)) > params.MaximumExtraDataSize {
 		return fmt.Errorf("extra-data too long: %d > %d", len(header.Extra), params.MaximumExtraDataSize)
 	}
+	// Ensure that the structured extra-data is well formed and matches the header
+	// once it became mandatory, earlier blocks carry free-form extra-data
+	if chain.Config().IsExtraV1(header.Number) {
+		extra, err := header.DecodeExtra()
+		if err != nil {
+			return err
+		}
+		if extra == nil {
+			return errMissingExtra
+		}
+		if header.ShuffleBlockNumber != nil && (!header.ShuffleBlockNumber.IsUint64() || extra.ShuffleEpoch != header.ShuffleBlockNumber.Uint64()) {
+			return errExtraShuffleEpoch
+		}
+	}
+	if err := verifyCheckpoint(chain.Config(), parent, header); err != nil {
+		return err
+	}
 
 	if header.Time.Cmp(big.NewInt(time.Now().Add(allowedFutureBlockTime).Unix())) > 0 {
 		return consensus.ErrFutureBlock
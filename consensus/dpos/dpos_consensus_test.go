@@ -17,9 +17,18 @@
 package dpos
 
 import (
+	"crypto/ecdsa"
 	"math"
 	"math/big"
 	"testing"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+	"github.com/Aurorachain-io/go-aoa/rlp"
 )
 
 func TestBlockReward(t *testing.T) {
@@ -49,3 +58,214 @@ func TestBlockReward(t *testing.T) {
 	}
 
 }
+
+// Tests that blocks are sealed with the signature of their producer, and only
+// once the engine was authorized to sign.
+func TestSeal(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	producer := crypto.PubkeyToAddress(key.PublicKey)
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), Time: big.NewInt(10), Coinbase: producer})
+
+	engine := New()
+	if _, err := engine.Seal(nil, block, nil); err != consensus.ErrUnauthorized {
+		t.Fatalf("error mismatch: have %v, want %v", err, consensus.ErrUnauthorized)
+	}
+	engine.Authorize(func(signer common.Address, hash []byte) ([]byte, error) {
+		if signer != producer {
+			t.Fatalf("signer mismatch: have %x, want %x", signer, producer)
+		}
+		return crypto.Sign(hash, key)
+	})
+	sealed, err := engine.Seal(nil, block, nil)
+	if err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	if sealed.Hash() != block.Hash() || len(block.Signature) != 0 {
+		t.Fatalf("sealing modified the block")
+	}
+	pub, err := crypto.SigToPub(sealed.Hash().Bytes(), sealed.Signature)
+	if err != nil {
+		t.Fatalf("failed to recover signer: %v", err)
+	}
+	if signer := crypto.PubkeyToAddress(*pub); signer != producer {
+		t.Errorf("signer mismatch: have %x, want %x", signer, producer)
+	}
+}
+
+// Tests that free-form extra-data is accepted before the extra-data v1 fork, and
+// only the structured layout of the header's shuffle round from the fork on.
+func TestVerifyExtraFork(t *testing.T) {
+	config := &params.ChainConfig{
+		MaxElectDelegate: big.NewInt(3),
+		BlockInterval:    big.NewInt(10),
+		ExtraV1Block:     big.NewInt(2),
+	}
+	chain := &testJailChain{config: config}
+	engine := New()
+
+	structured, err := types.EncodeHeaderExtra(types.NewHeaderExtra(1, []byte("aoa")))
+	if err != nil {
+		t.Fatalf("failed to encode extra-data: %v", err)
+	}
+	tests := []struct {
+		number uint64
+		extra  []byte
+		valid  bool
+	}{
+		{1, nil, true},
+		{1, []byte("free-form"), true},
+		{1, structured, true},
+		{2, nil, false},
+		{2, []byte("free-form"), false},
+		{2, structured[:4], false},
+		{2, structured, true},
+		{3, structured, true},
+	}
+	for i, tt := range tests {
+		parent := &types.Header{Number: new(big.Int).SetUint64(tt.number - 1), Time: big.NewInt(10), GasLimit: params.GenesisGasLimit}
+		header := &types.Header{
+			Number:             new(big.Int).SetUint64(tt.number),
+			Time:               big.NewInt(20),
+			GasLimit:           params.GenesisGasLimit,
+			Extra:              tt.extra,
+			ShuffleBlockNumber: big.NewInt(1),
+		}
+		if err := engine.verifyHeader(chain, header, parent); (err == nil) != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want valid %v", i, err, tt.valid)
+		}
+	}
+	// Structured extra-data must match the shuffle round of the header
+	header := &types.Header{Number: big.NewInt(2), Time: big.NewInt(20), GasLimit: params.GenesisGasLimit, Extra: structured, ShuffleBlockNumber: big.NewInt(7)}
+	parent := &types.Header{Number: big.NewInt(1), Time: big.NewInt(10), GasLimit: params.GenesisGasLimit}
+	if err := engine.verifyHeader(chain, header, parent); err != errExtraShuffleEpoch {
+		t.Errorf("error mismatch: have %v, want %v", err, errExtraShuffleEpoch)
+	}
+}
+
+// Tests that the verification failures depending on the local view of the chain
+// are reported as such, so relaying peers aren't blamed for them.
+func TestVerifyViewErrors(t *testing.T) {
+	config := &params.ChainConfig{
+		MaxElectDelegate: big.NewInt(3),
+		BlockInterval:    big.NewInt(10),
+	}
+	now := uint64(time.Now().Unix())
+	parent := &types.Header{Number: big.NewInt(1), Time: new(big.Int).SetUint64(now - 10), GasLimit: params.GenesisGasLimit}
+	chain := &testJailChain{config: config, headers: map[common.Hash]*types.Header{parent.Hash(): parent}}
+	engine := New()
+
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	coinbase := crypto.PubkeyToAddress(key.PublicKey)
+	block := func(parentHash common.Hash, time uint64, signer *ecdsa.PrivateKey) *types.Block {
+		block := types.NewBlockWithHeader(&types.Header{
+			ParentHash:         parentHash,
+			Number:             big.NewInt(2),
+			Time:               new(big.Int).SetUint64(time),
+			GasLimit:           params.GenesisGasLimit,
+			Coinbase:           coinbase,
+			ShuffleBlockNumber: big.NewInt(1),
+		})
+		block.Signature, _ = crypto.Sign(block.Hash().Bytes(), signer)
+		return block
+	}
+	shuffle := func(workTime uint64) *types.ShuffleList {
+		return &types.ShuffleList{ShuffleDels: []types.ShuffleDel{{Address: coinbase.Hex(), WorkTime: workTime}}}
+	}
+	tests := []struct {
+		block   *types.Block
+		shuffle *types.ShuffleList
+		view    bool
+	}{
+		{block(parent.Hash(), now-100, key), shuffle(now - 100), true}, // expired slot
+		{block(common.Hash{1}, now, key), shuffle(now), true},          // unknown parent
+		{block(parent.Hash(), now, key), &types.ShuffleList{}, true},   // not in the shuffle round
+		{block(parent.Hash(), now, key), shuffle(now + 10), true},      // another slot of the round
+		{block(parent.Hash(), now, other), shuffle(now), false},        // signed by someone else
+	}
+	for i, tt := range tests {
+		err := engine.VerifyHeaderAndSign(chain, tt.block, tt.shuffle, 10)
+		if err == nil {
+			t.Errorf("test %d: invalid block accepted", i)
+			continue
+		}
+		if consensus.IsViewError(err) != tt.view {
+			t.Errorf("test %d: view error mismatch: have %v (%v), want %v", i, consensus.IsViewError(err), err, tt.view)
+		}
+	}
+}
+
+// malleate converts a signature into its high-s twin, recovering the same key.
+func malleate(sig []byte) []byte {
+	n := crypto.S256().Params().N
+	s := new(big.Int).Sub(n, new(big.Int).SetBytes(sig[32:64]))
+	high := make([]byte, 65)
+	copy(high, sig[:32])
+	copy(high[64-len(s.Bytes()):64], s.Bytes())
+	high[64] = sig[64] ^ 1
+	return high
+}
+
+// Tests that high-s producer and vote signatures are accepted before the low-s
+// fork and rejected from it on.
+func TestVerifyLowSFork(t *testing.T) {
+	now := uint64(time.Now().Unix())
+	parent := &types.Header{Number: big.NewInt(1), Time: new(big.Int).SetUint64(now - 10), GasLimit: params.GenesisGasLimit}
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	shuffle := new(types.ShuffleList)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		shuffle.ShuffleDels = append(shuffle.ShuffleDels, types.ShuffleDel{Address: crypto.PubkeyToAddress(keys[i].PublicKey).Hex(), WorkTime: now})
+	}
+	// block creates a block produced by the first delegate and voted for by all,
+	// with the producer or one of the vote signatures malleated
+	block := func(highProducer bool, highVote bool) *types.Block {
+		block := types.NewBlockWithHeader(&types.Header{
+			ParentHash:         parent.Hash(),
+			Number:             big.NewInt(2),
+			Time:               new(big.Int).SetUint64(now),
+			GasLimit:           params.GenesisGasLimit,
+			Coinbase:           crypto.PubkeyToAddress(keys[0].PublicKey),
+			ShuffleBlockNumber: big.NewInt(1),
+		})
+		block.Signature, _ = crypto.Sign(block.Hash().Bytes(), keys[0])
+		if highProducer {
+			block.Signature = malleate(block.Signature)
+		}
+		var votes []types.VoteSign
+		for i, key := range keys {
+			sig, _ := crypto.Sign(block.Hash().Bytes(), key)
+			if highVote && i == len(keys)-1 {
+				sig = malleate(sig)
+			}
+			votes = append(votes, types.VoteSign{Sign: sig})
+		}
+		block.RlpEncodeSigns, _ = rlp.EncodeToBytes(votes)
+		return block
+	}
+	tests := []struct {
+		fork         int64
+		highProducer bool
+		highVote     bool
+		err          error
+	}{
+		{3, false, false, nil},
+		{3, true, false, nil},
+		{3, false, true, nil},
+		{2, false, false, nil},
+		{2, true, false, errMalleableSignature},
+		{2, false, true, errMalleableSignature},
+	}
+	for i, tt := range tests {
+		config := &params.ChainConfig{
+			MaxElectDelegate: big.NewInt(3),
+			BlockInterval:    big.NewInt(10),
+			LowSBlock:        big.NewInt(tt.fork),
+		}
+		chain := &testJailChain{config: config, headers: map[common.Hash]*types.Header{parent.Hash(): parent}}
+		if err := New().VerifyBlockGenerate(chain, block(tt.highProducer, tt.highVote), shuffle, 10); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"errors"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus"
+	"github.com/Aurorachain-io/go-aoa/consensus/delegatestate"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/metrics"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+var (
+	// errNoDelegateState is returned if the delegate jail is active but the chain
+	// cannot open the delegate states the skipped rounds were shuffled from.
+	errNoDelegateState = errors.New("delegate state of past blocks unavailable")
+
+	jailedDelegateCounter = metrics.NewCounter("dpos/jailed") // Delegates deactivated for missing too many slots
+)
+
+// delegateStateReader is implemented by chains able to open the delegate state of
+// past blocks, which the delegate jail needs to recompute the skipped rounds and
+// checkpoints to commit to the delegates their rounds were shuffled from.
+type delegateStateReader interface {
+	DelegateStateAt(root common.Hash) (*delegatestate.DelegateDB, error)
+}
+
+// ancestorCandidates retrieves the delegate candidates in the delegate state of
+// the ancestor of head with the given number. The shuffle block is resolved among
+// the ancestors, as head may not be on the canonical chain.
+func ancestorCandidates(chain consensus.ChainReader, reader delegateStateReader, head *types.Header, number uint64) ([]types.Candidate, error) {
+	ancestor := head
+	for ancestor != nil && ancestor.Number.Uint64() > number {
+		ancestor = chain.GetHeader(ancestor.ParentHash, ancestor.Number.Uint64()-1)
+	}
+	if ancestor == nil || ancestor.Number.Uint64() != number {
+		return nil, ErrUnknownShuffleBlock
+	}
+	delegates, err := reader.DelegateStateAt(ancestor.DelegateRoot)
+	if err != nil {
+		return nil, err
+	}
+	return delegates.GetDelegates(), nil
+}
+
+// jailDelegates counts the consecutive block slots missed by the delegates
+// scheduled between the parent and the given block, deactivating those missing
+// more than params.MaxMissedSlots. The count of the block producer is reset.
+// Deactivated delegates keep their votes and rejoin by registering again.
+func jailDelegates(chain consensus.ChainReader, header *types.Header, dState *delegatestate.DelegateDB) error {
+	reader, ok := chain.(delegateStateReader)
+	if !ok {
+		return errNoDelegateState
+	}
+	parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	genesis := chain.GetHeaderByNumber(0)
+	if genesis == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	rounds := NewRounds(chain.Config(), genesis.Time.Int64(), func(number uint64) ([]types.Candidate, error) {
+		return ancestorCandidates(chain, reader, parent, number)
+	})
+	missed, err := rounds.MissedSlots(parent, header)
+	if err != nil {
+		return err
+	}
+	for _, delegate := range missed {
+		// Skip the producer of the block and delegates already deactivated
+		if delegate == header.Coinbase || !dState.Exist(delegate) {
+			continue
+		}
+		slots := dState.GetMissedSlots(delegate) + 1
+		if slots > params.MaxMissedSlots {
+			log.Info("Deactivating delegate for missed slots", "number", header.Number, "delegate", delegate, "missed", slots)
+			dState.SetMissedSlots(delegate, 0)
+			dState.Suicide(delegate)
+			jailedDelegateCounter.Inc(1)
+			continue
+		}
+		dState.SetMissedSlots(delegate, slots)
+	}
+	if dState.GetMissedSlots(header.Coinbase) != 0 {
+		dState.SetMissedSlots(header.Coinbase, 0)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus/delegatestate"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// testJailChain is a minimal chain serving headers and delegate states to the
+// delegate jail.
+type testJailChain struct {
+	config    *params.ChainConfig
+	headers   map[common.Hash]*types.Header
+	genesis   *types.Header
+	delegates delegatestate.Database
+}
+
+func (c *testJailChain) Config() *params.ChainConfig                    { return c.config }
+func (c *testJailChain) CurrentHeader() *types.Header                   { return nil }
+func (c *testJailChain) GetHeaderByHash(hash common.Hash) *types.Header { return c.headers[hash] }
+func (c *testJailChain) GetBlock(common.Hash, uint64) *types.Block      { return nil }
+
+func (c *testJailChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.headers[hash]; header != nil && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+func (c *testJailChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number == 0 {
+		return c.genesis
+	}
+	return nil
+}
+
+func (c *testJailChain) DelegateStateAt(root common.Hash) (*delegatestate.DelegateDB, error) {
+	return delegatestate.New(root, c.delegates)
+}
+
+// Tests that a delegate missing all of its slots is deactivated once it missed
+// more than the allowed consecutive slots, while producing delegates are not.
+func TestJailDelegates(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+	config := &params.ChainConfig{
+		MaxElectDelegate:  big.NewInt(3),
+		BlockInterval:     big.NewInt(10),
+		DelegateJailBlock: big.NewInt(0),
+	}
+	dState, _ := delegatestate.New(common.Hash{}, delegatestate.NewDatabase(db))
+	for i := byte(1); i <= 3; i++ {
+		addr := common.Address{i}
+		dState.GetOrNewStateObject(addr, "delegate", uint64(i))
+		dState.AddVote(addr, big.NewInt(int64(i)))
+	}
+	root, err := dState.CommitTo(db, false)
+	if err != nil {
+		t.Fatalf("failed to commit delegate state: %v", err)
+	}
+	genesis := &types.Header{Number: big.NewInt(0), Time: big.NewInt(1000), DelegateRoot: root}
+	chain := &testJailChain{
+		config:    config,
+		headers:   map[common.Hash]*types.Header{genesis.Hash(): genesis},
+		genesis:   genesis,
+		delegates: delegatestate.NewDatabase(db),
+	}
+	rounds := NewRounds(config, genesis.Time.Int64(), func(uint64) ([]types.Candidate, error) {
+		return dState.GetDelegates(), nil
+	})
+	// Produce blocks in all slots, except the ones of the dead delegate
+	var (
+		dead     = common.Address{2}
+		parent   = genesis
+		misses   uint64
+		lastMiss int64
+	)
+	for slot := genesis.Time.Int64() + 10; misses <= params.MaxMissedSlots; slot += 10 {
+		round, err := rounds.Round(0, rounds.Start(slot))
+		if err != nil {
+			t.Fatalf("failed to shuffle round: %v", err)
+		}
+		var producer common.Address
+		for _, delegate := range round.ShuffleDels {
+			if int64(delegate.WorkTime) == slot {
+				producer = common.HexToAddress(delegate.Address)
+			}
+		}
+		if producer == dead {
+			misses, lastMiss = misses+1, slot
+			continue
+		}
+		if !dState.Exist(dead) {
+			t.Fatalf("delegate deactivated after %d missed slots", misses)
+		}
+		header := &types.Header{
+			ParentHash:         parent.Hash(),
+			Number:             new(big.Int).Add(parent.Number, common.Big1),
+			Time:               big.NewInt(slot),
+			Coinbase:           producer,
+			ShuffleBlockNumber: big.NewInt(0),
+			DelegateRoot:       root,
+		}
+		if err := jailDelegates(chain, header, dState); err != nil {
+			t.Fatalf("failed to jail delegates: %v", err)
+		}
+		if missed := dState.GetMissedSlots(dead); missed != misses {
+			t.Fatalf("missed slots mismatch: have %d, want %d", missed, misses)
+		}
+		chain.headers[header.Hash()] = header
+		parent = header
+	}
+	// The block following the last missed slot deactivates the delegate
+	header := &types.Header{
+		ParentHash:         parent.Hash(),
+		Number:             new(big.Int).Add(parent.Number, common.Big1),
+		Time:               big.NewInt(lastMiss + 10),
+		Coinbase:           common.Address{1},
+		ShuffleBlockNumber: big.NewInt(0),
+		DelegateRoot:       root,
+	}
+	if err := jailDelegates(chain, header, dState); err != nil {
+		t.Fatalf("failed to jail delegates: %v", err)
+	}
+	if dState.Exist(dead) {
+		t.Fatalf("delegate still active after %d missed slots", misses)
+	}
+	for _, addr := range []common.Address{{1}, {3}} {
+		if !dState.Exist(addr) {
+			t.Errorf("producing delegate %x deactivated", addr)
+		}
+	}
+}
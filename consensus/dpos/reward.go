@@ -0,0 +1,147 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/Aurorachain-io/go-aoa/consensus/delegatestate"
+	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// RewardPolicy distributes the block reward and the transaction fees of a block
+// when it is finalized. The fees are credited to the block producer while the
+// transactions execute, policies sharing them move them on from there. All nodes
+// of a network must run the same policy, it is part of the consensus rules.
+type RewardPolicy interface {
+	// Distribute credits the rewards of the block, given the fees its
+	// transactions paid.
+	Distribute(config *params.ChainConfig, header *types.Header, state *state.StateDB, dState *delegatestate.DelegateDB, fees *big.Int) error
+}
+
+// DefaultRewardPolicy credits the block producer with the block reward and
+// leaves it the transaction fees, sharing them with its voters since the fee
+// sharing fork.
+var DefaultRewardPolicy RewardPolicy = producerRewardPolicy{}
+
+// rewardPolicies are the reward policies a chain configuration may select by
+// name, the unnamed one being the default.
+var rewardPolicies = map[string]RewardPolicy{
+	"":         DefaultRewardPolicy,
+	"producer": DefaultRewardPolicy,
+}
+
+// RegisterRewardPolicy makes a reward policy selectable by chain configurations
+// under the given name. It must be called before any chain is opened.
+func RegisterRewardPolicy(name string, policy RewardPolicy) {
+	if _, exists := rewardPolicies[name]; exists {
+		panic(fmt.Sprintf("reward policy %q registered twice", name))
+	}
+	rewardPolicies[name] = policy
+}
+
+// rewardPolicy returns the reward policy selected by the chain configuration.
+func rewardPolicy(config *params.ChainConfig) (RewardPolicy, error) {
+	policy, ok := rewardPolicies[config.RewardPolicy]
+	if !ok {
+		return nil, fmt.Errorf("unknown reward policy %q", config.RewardPolicy)
+	}
+	return policy, nil
+}
+
+// producerRewardPolicy is the reward policy of the original chain rules.
+type producerRewardPolicy struct{}
+
+// Distribute implements RewardPolicy, crediting the block reward to the producer.
+func (producerRewardPolicy) Distribute(config *params.ChainConfig, header *types.Header, state *state.StateDB, dState *delegatestate.DelegateDB, fees *big.Int) error {
+	state.AddBalance(header.Coinbase, BlockReward(config, header.Number))
+	if config.IsFeeSharing(header.Number) {
+		shareFees(config, header, state, dState, fees)
+	}
+	return nil
+}
+
+// shareFees moves params.FeeSharePercent of the block fees from the producer to
+// its indexed voters, proportionally to the current weights of their votes. The
+// index holds the voters with a stake record, at most params.MaxFeeShareVoters
+// of the highest staked ones, bounding the payouts per block. The rounding
+// remainder stays with the producer, and it never pays out more than its balance.
+func shareFees(config *params.ChainConfig, header *types.Header, state *state.StateDB, dState *delegatestate.DelegateDB, fees *big.Int) {
+	if fees.Sign() <= 0 {
+		return
+	}
+	var (
+		period  = config.VoteDecayPeriod(header.Number)
+		voters  = dState.Voters(header.Coinbase)
+		weights = make([]*big.Int, len(voters))
+		total   = new(big.Int)
+	)
+	for i, voter := range voters {
+		weights[i] = dState.VoterWeight(header.Coinbase, voter, period)
+		total.Add(total, weights[i])
+	}
+	if total.Sign() == 0 {
+		return
+	}
+	shared := new(big.Int).Mul(fees, new(big.Int).SetUint64(params.FeeSharePercent))
+	shared.Div(shared, big.NewInt(100))
+	if balance := state.GetBalance(header.Coinbase); shared.Cmp(balance) > 0 {
+		shared.Set(balance)
+	}
+	for i, voter := range voters {
+		share := new(big.Int).Mul(shared, weights[i])
+		share.Div(share, total)
+		if share.Sign() > 0 {
+			state.SubBalance(header.Coinbase, share)
+			state.AddBalance(voter, share)
+		}
+	}
+}
+
+// BlockReward returns the reward of producing the block with the given number,
+// following the active reward fork of the chain. Without one the reward starts
+// at 500 and grows with the annual profit every year of blocks.
+func BlockReward(config *params.ChainConfig, number *big.Int) *big.Int {
+	if reward := config.BlockReward(number); reward != nil {
+		return reward
+	}
+	var (
+		basicReward      float64 = 500
+		annulProfit              = params.AnnulProfit
+		annulBlockAmount         = params.AnnulBlockAmount
+		blockReward              = big.NewInt(1e+18)
+	)
+	yearNumber := number.Int64() / annulBlockAmount.Int64()
+	currentReward := (int64)(basicReward * math.Pow(annulProfit, float64(yearNumber)))
+	return new(big.Int).Mul(big.NewInt(currentReward), blockReward)
+}
+
+// blockFees returns the transaction fees paid by the transactions of a block.
+func blockFees(txs []*types.Transaction, receipts []*types.Receipt) *big.Int {
+	fees := new(big.Int)
+	for i, tx := range txs {
+		if i >= len(receipts) {
+			break
+		}
+		fees.Add(fees, new(big.Int).Mul(new(big.Int).SetUint64(receipts[i].GasUsed), tx.GasPrice()))
+	}
+	return fees
+}
@@ -0,0 +1,142 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus/delegatestate"
+	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// testRewardPolicy is a reward policy crediting the fees to a fixed account.
+type testRewardPolicy struct{ beneficiary common.Address }
+
+func (p testRewardPolicy) Distribute(config *params.ChainConfig, header *types.Header, state *state.StateDB, dState *delegatestate.DelegateDB, fees *big.Int) error {
+	state.AddBalance(p.beneficiary, fees)
+	return nil
+}
+
+// Tests that the default reward policy credits the producer with the reward of
+// the active reward fork, and the original yearly reward without one.
+func TestDefaultRewardPolicy(t *testing.T) {
+	config := &params.ChainConfig{RewardForks: []params.RewardFork{
+		{Block: big.NewInt(100), Reward: big.NewInt(1000), HalvingInterval: 10},
+	}}
+	tests := []struct {
+		number int64
+		want   *big.Int
+	}{
+		{1, new(big.Int).Mul(big.NewInt(500), big.NewInt(1e+18))},
+		{100, big.NewInt(1000)},
+		{125, big.NewInt(250)},
+	}
+	for _, tt := range tests {
+		db, _ := aoadb.NewMemDatabase()
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+		header := &types.Header{Number: big.NewInt(tt.number), Coinbase: common.Address{1}}
+
+		if err := DefaultRewardPolicy.Distribute(config, header, statedb, nil, big.NewInt(10)); err != nil {
+			t.Fatalf("block %d: failed to distribute reward: %v", tt.number, err)
+		}
+		if balance := statedb.GetBalance(header.Coinbase); balance.Cmp(tt.want) != 0 {
+			t.Errorf("block %d: producer balance mismatch: have %v, want %v", tt.number, balance, tt.want)
+		}
+	}
+}
+
+// Tests that since the fee sharing fork the fees of a block are shared with the
+// voters of its producer proportionally to their vote weights.
+func TestShareFees(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	dState, _ := delegatestate.New(common.Hash{}, delegatestate.NewDatabase(db))
+
+	var (
+		producer = common.Address{1}
+		voters   = []common.Address{{0xa}, {0xb}, {0xc}}
+		config   = &params.ChainConfig{
+			VoteDecayBlock:  big.NewInt(0),
+			FeeSharingBlock: big.NewInt(10),
+			RewardForks:     []params.RewardFork{{Block: big.NewInt(0), Reward: big.NewInt(0)}},
+		}
+	)
+	dState.GetOrNewStateObject(producer, "producer", 1)
+	for i, voter := range voters {
+		dState.CastVote(producer, voter, big.NewInt(int64(i+1)), 0) // Including votes cast before the fork
+	}
+
+	fees := big.NewInt(1000)
+	for _, number := range []int64{9, 10} {
+		statedb.AddBalance(producer, fees) // Credited while executing the transactions
+		header := &types.Header{Number: big.NewInt(number), Coinbase: producer}
+		if err := DefaultRewardPolicy.Distribute(config, header, statedb, dState, fees); err != nil {
+			t.Fatalf("block %d: failed to distribute reward: %v", number, err)
+		}
+	}
+	// Half of the second block's fees are shared 1:2:3, the remainder rounded down
+	want := map[common.Address]int64{producer: 1000 + 500 + 1, voters[0]: 83, voters[1]: 166, voters[2]: 250}
+	for addr, balance := range want {
+		if have := statedb.GetBalance(addr); have.Cmp(big.NewInt(balance)) != 0 {
+			t.Errorf("balance of %x mismatch: have %v, want %d", addr, have, balance)
+		}
+	}
+}
+
+// Tests that chains select their reward policy by name, defaulting to the
+// producer policy.
+func TestRewardPolicySelection(t *testing.T) {
+	policy := testRewardPolicy{beneficiary: common.Address{2}}
+	RegisterRewardPolicy("test", policy)
+	defer delete(rewardPolicies, "test")
+
+	tests := []struct {
+		name string
+		want RewardPolicy
+	}{
+		{"", DefaultRewardPolicy},
+		{"producer", DefaultRewardPolicy},
+		{"test", policy},
+	}
+	for _, tt := range tests {
+		have, err := rewardPolicy(&params.ChainConfig{RewardPolicy: tt.name})
+		if err != nil || have != tt.want {
+			t.Errorf("policy %q mismatch: have %v/%v, want %v", tt.name, have, err, tt.want)
+		}
+	}
+	if _, err := rewardPolicy(&params.ChainConfig{RewardPolicy: "unknown"}); err == nil {
+		t.Errorf("unknown reward policy selected")
+	}
+}
+
+// Tests that the fees of a block sum the gas used by its transactions at their
+// gas prices.
+func TestBlockFees(t *testing.T) {
+	txs := []*types.Transaction{
+		types.NewTransaction(0, common.Address{1}, new(big.Int), 50000, big.NewInt(2), nil, uint64(types.ActionTrans), nil, ""),
+		types.NewTransaction(1, common.Address{1}, new(big.Int), 50000, big.NewInt(3), nil, uint64(types.ActionTrans), nil, ""),
+	}
+	receipts := []*types.Receipt{{GasUsed: 21000}, {GasUsed: 30000}}
+	if fees := blockFees(txs, receipts); fees.Cmp(big.NewInt(2*21000+3*30000)) != 0 {
+		t.Errorf("block fees mismatch: have %v, want %v", fees, 2*21000+3*30000)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/params"
+	"github.com/Aurorachain-io/go-aoa/util"
+)
+
+// roundsCandidatesCache is the number of shuffled blocks whose candidates are
+// kept around while recomputing rounds.
+const roundsCandidatesCache = 16
+
+// ErrUnknownShuffleBlock is returned if the block a round was shuffled from is
+// not available.
+var ErrUnknownShuffleBlock = errors.New("unknown shuffle block")
+
+// CandidatesFn retrieves the delegate candidates in the delegate state of the
+// block with the given number.
+type CandidatesFn func(number uint64) ([]types.Candidate, error)
+
+// Rounds recomputes the shuffled rounds of block producing delegates. Rounds are
+// aligned to the genesis time and shuffle the top candidates of the chain head
+// at their start, so they can be rederived from the chain alone. Rounds is not
+// safe for concurrent use.
+type Rounds struct {
+	config           *params.ChainConfig
+	maxElectDelegate int          // number of delegates producing blocks in a round
+	blockInterval    int64        // seconds between two block slots
+	genesisTime      int64        // time the first round is aligned to
+	candidates       CandidatesFn // retriever of the candidates of shuffled blocks
+
+	cache map[uint64][]types.Candidate // Top candidates of recently shuffled blocks
+}
+
+// NewRounds creates a round calculator for the chain with the given config and
+// genesis time.
+func NewRounds(config *params.ChainConfig, genesisTime int64, candidates CandidatesFn) *Rounds {
+	return &Rounds{
+		config:           config,
+		maxElectDelegate: int(config.MaxElectDelegate.Int64()),
+		blockInterval:    config.BlockInterval.Int64(),
+		genesisTime:      genesisTime,
+		candidates:       candidates,
+		cache:            make(map[uint64][]types.Candidate),
+	}
+}
+
+// Length returns the duration of a round in seconds.
+func (r *Rounds) Length() int64 {
+	return int64(r.maxElectDelegate) * r.blockInterval
+}
+
+// Slot returns the start of the block slot the given time falls into.
+func (r *Rounds) Slot(t int64) int64 {
+	return t - (t-r.genesisTime)%r.blockInterval
+}
+
+// Start returns the start of the round the given time falls into.
+func (r *Rounds) Start(t int64) int64 {
+	return t - (t-r.genesisTime)%r.Length()
+}
+
+// Round shuffles the top candidates of the given block into the round starting
+// at the given time.
+func (r *Rounds) Round(shuffleBlock uint64, start int64) (types.ShuffleList, error) {
+	candidates, ok := r.cache[shuffleBlock]
+	if !ok {
+		var err error
+		if candidates, err = r.candidates(shuffleBlock); err != nil {
+			return types.ShuffleList{}, err
+		}
+		if len(candidates) > r.maxElectDelegate {
+			candidates = candidates[:r.maxElectDelegate]
+		}
+		if len(r.cache) >= roundsCandidatesCache {
+			r.cache = make(map[uint64][]types.Candidate)
+		}
+		r.cache[shuffleBlock] = candidates
+	}
+	return types.ShuffleList{ShuffleDels: util.ShuffleRound(r.config.ShuffleVersion(new(big.Int).SetUint64(shuffleBlock)), start, r.maxElectDelegate, candidates, r.blockInterval)}, nil
+}
+
+// MissedSlots returns the delegates scheduled in the block slots skipped between
+// a block and its parent, one entry per slot.
+func (r *Rounds) MissedSlots(parent, header *types.Header) ([]common.Address, error) {
+	if header.ShuffleBlockNumber == nil {
+		return nil, nil
+	}
+	var (
+		number     = header.Number.Uint64()
+		headTime   = header.Time.Int64()
+		parentTime = parent.Time.Int64()
+		missed     []common.Address
+	)
+	for slot := r.Slot(parentTime) + r.blockInterval; slot < r.Slot(headTime); slot += r.blockInterval {
+		// Rounds between the parent and the block were shuffled from the parent,
+		// it being the chain head at the time
+		shuffleBlock := number - 1
+		start := r.Start(slot)
+		switch {
+		case start == r.Start(headTime):
+			shuffleBlock = header.ShuffleBlockNumber.Uint64()
+		case start == r.Start(parentTime) && parent.ShuffleBlockNumber != nil:
+			shuffleBlock = parent.ShuffleBlockNumber.Uint64()
+		}
+		round, err := r.Round(shuffleBlock, start)
+		if err != nil {
+			return nil, err
+		}
+		for _, delegate := range round.ShuffleDels {
+			if int64(delegate.WorkTime) == slot {
+				missed = append(missed, common.HexToAddress(delegate.Address))
+				break
+			}
+		}
+	}
+	return missed, nil
+}
@@ -30,4 +30,26 @@ var (
 	// ErrInvalidNumber is returned if a block's number doesn't equal it's parent's
 	// plus one.
 	ErrInvalidNumber = errors.New("invalid block number")
+
+	// ErrUnauthorized is returned if a block is to be sealed by an engine lacking
+	// the key of its producer.
+	ErrUnauthorized = errors.New("unauthorized block producer")
 )
+
+// ViewError is returned if a block fails verification against the local view of
+// the chain, like the current shuffle round or the local clock, which its producer
+// and relayers may not share. It doesn't prove the block invalid.
+type ViewError struct {
+	Err error
+}
+
+func (e *ViewError) Error() string {
+	return e.Err.Error()
+}
+
+// IsViewError returns whether a block verification failed because of the local
+// view of the chain.
+func IsViewError(err error) bool {
+	_, ok := err.(*ViewError)
+	return ok
+}
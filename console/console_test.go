@@ -95,7 +95,7 @@ func newTester(t *testing.T, confOverride func(*em.Config)) *tester {
 		t.Fatalf("failed to create node: %v", err)
 	}
 	ethConf := &em.Config{
-		Genesis:      core.DeveloperGenesisBlock(common.Address{}),
+		Genesis:      core.DeveloperGenesisBlock(0, common.Address{}),
 		Dacchainbase: common.HexToAddress(testAddress),
 	}
 	if confOverride != nil {
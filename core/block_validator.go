@@ -23,6 +23,7 @@ import (
 	"github.com/Aurorachain-io/go-aoa/core/state"
 	"github.com/Aurorachain-io/go-aoa/core/types"
 	"github.com/Aurorachain-io/go-aoa/params"
+	"math/big"
 )
 
 // BlockValidator is responsible for validating block headers, uncles and
@@ -61,6 +62,23 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 	if hash := types.DeriveSha(block.Transactions()); hash != header.TxHash {
 		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
 	}
+	return ValidateBlockLimits(v.config, header.Number, block.Transactions())
+}
+
+// ValidateBlockLimits checks the transactions of a block against the block
+// limits of the chain configuration active at the given height.
+func ValidateBlockLimits(config *params.ChainConfig, number *big.Int, txs types.Transactions) error {
+	limits := config.BlockLimits(number)
+	if limits.MaxTxs > 0 && uint64(len(txs)) > limits.MaxTxs {
+		return ErrTooManyTxs
+	}
+	if limits.MaxTxSize > 0 {
+		for _, tx := range txs {
+			if uint64(tx.Size()) > limits.MaxTxSize {
+				return ErrOversizedTx
+			}
+		}
+	}
 	return nil
 }
 
@@ -127,3 +145,27 @@ func CalcGasLimit(parent *types.Block) uint64 {
 	}
 	return limit
 }
+
+// CalcGasLimitTarget computes the gas limit of the next block after parent like
+// CalcGasLimit, but moves it towards the given target instead, as far as the
+// bound divisor allows. A zero target falls back to CalcGasLimit.
+func CalcGasLimitTarget(parent *types.Block, target uint64) uint64 {
+	limit := CalcGasLimit(parent)
+	if target == 0 {
+		return limit
+	}
+	decay := parent.GasLimit()/params.GasLimitBoundDivisor - 1
+	switch {
+	case limit > target:
+		limit = parent.GasLimit() - decay
+		if limit < target {
+			limit = target
+		}
+	case limit < target:
+		limit = parent.GasLimit() + decay
+		if limit > target {
+			limit = target
+		}
+	}
+	return limit
+}
@@ -20,7 +20,7 @@ import (
 	"github.com/Aurorachain-io/go-aoa/consensus/dpos"
 	"github.com/Aurorachain-io/go-aoa/core/types"
 	"github.com/Aurorachain-io/go-aoa/core/vm"
-	"github.com/Aurorachain-io/go-aoa/emdb"
+	"github.com/Aurorachain-io/go-aoa/aoadb"
 	"github.com/Aurorachain-io/go-aoa/params"
 	"runtime"
 	"testing"
@@ -31,7 +31,7 @@ import (
 func TestHeaderVerification(t *testing.T) {
 	// Create a simple chain to verify
 	var (
-		testdb, _ = emdb.NewMemDatabase()
+		testdb, _ = aoadb.NewMemDatabase()
 		gspec     = &Genesis{Config: params.TestChainConfig}
 		genesis   = gspec.MustCommit(testdb)
 		blocks, _ = GenerateChain(params.TestChainConfig, genesis, dpos.New(), testdb, 8, nil)
@@ -41,7 +41,7 @@ func TestHeaderVerification(t *testing.T) {
 		headers[i] = block.Header()
 	}
 	// Run the header checker for blocks one-by-one, checking for both valid and invalid nonces
-	chain, _ := NewBlockChain(testdb, params.TestChainConfig, dpos.New(), vm.Config{}, nil)
+	chain, _ := NewBlockChain(testdb, nil, params.TestChainConfig, dpos.New(), vm.Config{}, nil)
 	defer chain.Stop()
 
 	for i := 0; i < len(blocks); i++ {
@@ -79,7 +79,7 @@ func TestHeaderConcurrentVerification32(t *testing.T) { testHeaderConcurrentVeri
 func testHeaderConcurrentVerification(t *testing.T, threads int) {
 	// Create a simple chain to verify
 	var (
-		testdb, _ = emdb.NewMemDatabase()
+		testdb, _ = aoadb.NewMemDatabase()
 		gspec     = &Genesis{Config: params.TestChainConfig}
 		genesis   = gspec.MustCommit(testdb)
 		blocks, _ = GenerateChain(params.TestChainConfig, genesis, dpos.New(), testdb, 8, nil)
@@ -100,7 +100,7 @@ func testHeaderConcurrentVerification(t *testing.T, threads int) {
 	for i, valid := range []bool{true, false} {
 		var results <-chan error
 
-		chain, _ := NewBlockChain(testdb, params.TestChainConfig, dpos.New(), vm.Config{}, nil)
+		chain, _ := NewBlockChain(testdb, nil, params.TestChainConfig, dpos.New(), vm.Config{}, nil)
 		_, results = chain.dacEngine.VerifyHeaders(chain, headers)
 		chain.Stop()
 		// Wait for all the verification results
@@ -145,7 +145,7 @@ func TestHeaderConcurrentAbortion32(t *testing.T) { testHeaderConcurrentAbortion
 func testHeaderConcurrentAbortion(t *testing.T, threads int) {
 	// Create a simple chain to verify
 	var (
-		testdb, _ = emdb.NewMemDatabase()
+		testdb, _ = aoadb.NewMemDatabase()
 		gspec     = &Genesis{Config: params.TestChainConfig}
 		genesis   = gspec.MustCommit(testdb)
 		blocks, _ = GenerateChain(params.TestChainConfig, genesis, dpos.New(), testdb, 1024, nil)
@@ -162,7 +162,7 @@ func testHeaderConcurrentAbortion(t *testing.T, threads int) {
 	defer runtime.GOMAXPROCS(old)
 
 	// Start the verifications and immediately abort
-	chain, _ := NewBlockChain(testdb, params.TestChainConfig, dpos.New(), vm.Config{}, nil)
+	chain, _ := NewBlockChain(testdb, nil, params.TestChainConfig, dpos.New(), vm.Config{}, nil)
 	defer chain.Stop()
 
 	abort, results := chain.dacEngine.VerifyHeaders(chain, headers)
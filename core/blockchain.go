@@ -20,7 +20,6 @@ package core
 import (
 	"errors"
 	"fmt"
-	"io"
 	"math/big"
 	mrand "math/rand"
 	"sync"
@@ -43,6 +42,7 @@ import (
 	"github.com/hashicorp/golang-lru"
 	"github.com/Aurorachain-io/go-aoa/consensus/delegatestate"
 	"github.com/Aurorachain-io/go-aoa/core/watch"
+	"gopkg.in/karalabe/cookiejar.v2/collections/prque"
 )
 
 var (
@@ -77,7 +77,8 @@ const (
 // included in the canonical one where as GetBlockByNumber always represents the
 // canonical chain.
 type BlockChain struct {
-	config *params.ChainConfig // chain & network configuration
+	config      *params.ChainConfig // chain & network configuration
+	cacheConfig *CacheConfig        // Cache configuration for pruning
 
 	hc            *HeaderChain
 	chainDb       aoadb.Database
@@ -86,6 +87,7 @@ type BlockChain struct {
 	chainSideFeed event.Feed
 	chainHeadFeed event.Feed
 	logsFeed      event.Feed
+	reorgFeed     event.Feed
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
 
@@ -98,6 +100,7 @@ type BlockChain struct {
 	currentFastBlock *types.Block // Current head of the fast-sync chain (may be above the block chain!)
 
 	stateCache    state.Database // State database to reuse between imports (contains state cache)
+	triegc        *prque.Prque   // Priority queue mapping block numbers to tries to gc
 	delegateCache delegatestate.Database
 	bodyCache     *lru.Cache      // Cache for the most recent block bodies
 	bodyRLPCache  *lru.Cache      // Cache for the most recent block bodies in RLP encoded format
@@ -116,6 +119,11 @@ type BlockChain struct {
 	validator Validator // block and state validator interface
 	vmConfig  vm.Config
 
+	txLookupLimit uint64        // Number of recent blocks to keep transactions indexed for (0 = all), atomic
+	historyLimit  uint64        // Number of recent blocks to retain bodies and receipts for (0 = all), atomic
+	txIndexReq    chan struct{} // Notification channel to re-evaluate the transaction index and history tails
+	stateStats    int32         // Whether state access statistics are collected during processing, atomic
+
 	badBlocks            *lru.Cache // Bad block cache
 	candidateWrapperChan chan *types.CandidateWrapper
 	delegateList         *map[string]types.Candidate
@@ -123,8 +131,11 @@ type BlockChain struct {
 
 // NewBlockChain returns a fully initialised block chain using information
 // available in the database. It initialises the default eminer-pro Validator and
-// Processor.
-func NewBlockChain(chainDb aoadb.Database, config *params.ChainConfig, dacEngine consensus.Engine, vmConfig vm.Config, itxDb aoadb.Database) (*BlockChain, error) {
+// Processor. A nil cache config selects the default trie caching settings.
+func NewBlockChain(chainDb aoadb.Database, cacheConfig *CacheConfig, config *params.ChainConfig, dacEngine consensus.Engine, vmConfig vm.Config, itxDb aoadb.Database) (*BlockChain, error) {
+	if cacheConfig == nil {
+		cacheConfig = defaultCacheConfig
+	}
 	bodyCache, _ := lru.New(bodyCacheLimit)
 	bodyRLPCache, _ := lru.New(bodyCacheLimit)
 	blockCache, _ := lru.New(blockCacheLimit)
@@ -133,8 +144,10 @@ func NewBlockChain(chainDb aoadb.Database, config *params.ChainConfig, dacEngine
 
 	bc := &BlockChain{
 		config:               config,
+		cacheConfig:          cacheConfig,
 		chainDb:              chainDb,
-		stateCache:           state.NewDatabase(chainDb),
+		stateCache:           state.NewDatabaseWithCache(chainDb, cacheConfig.TrieCleanLimit),
+		triegc:               prque.New(),
 		quit:                 make(chan struct{}),
 		bodyCache:            bodyCache,
 		bodyRLPCache:         bodyRLPCache,
@@ -146,10 +159,17 @@ func NewBlockChain(chainDb aoadb.Database, config *params.ChainConfig, dacEngine
 		delegateCache:        delegatestate.NewDatabase(chainDb),
 		dacEngine:            dacEngine,
 		innerTxDb:            watch.NewInnerTxDb(itxDb),
+		txIndexReq:           make(chan struct{}, 1),
 	}
 	bc.SetValidator(NewBlockValidator(config, bc, dacEngine))
 	bc.SetProcessor(NewStateProcessor(config, bc, dacEngine))
 
+	// Make sure the chain head survived a repair of the database intact
+	if db, ok := chainDb.(*aoadb.LDBDatabase); ok && db.Recovered() {
+		if err := RepairHead(chainDb); err != nil {
+			return nil, err
+		}
+	}
 	var err error
 	bc.hc, err = NewHeaderChain(chainDb, config, dacEngine, bc.getProcInterrupt)
 	if err != nil {
@@ -162,6 +182,9 @@ func NewBlockChain(chainDb aoadb.Database, config *params.ChainConfig, dacEngine
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
+	if err := bc.resolveHeadIntent(); err != nil {
+		return nil, err
+	}
 	// Check the current state of the block hashes and make sure that we do not have any of the bad blocks in our chain
 	for hash := range BadHashes {
 		if header := bc.GetHeaderByHash(hash); header != nil {
@@ -177,6 +200,9 @@ func NewBlockChain(chainDb aoadb.Database, config *params.ChainConfig, dacEngine
 	}
 	// Take ownership of this particular state
 	go bc.update()
+
+	bc.wg.Add(1)
+	go bc.maintainTxIndex()
 	return bc, nil
 }
 
@@ -220,9 +246,12 @@ func (bc *BlockChain) loadLastState() error {
 	}
 	// Make sure the state associated with the block is available
 	if _, err := state.New(currentBlock.Root(), bc.stateCache); err != nil {
-		// Dangling block without a state associated, init from scratch
-		log.Warn("Head state missing, resetting chain", "number", currentBlock.Number(), "hash", currentBlock.Hash())
-		return bc.Reset()
+		// Dangling block without a state associated, e.g. the cached state was
+		// lost in a crash, rewind to the last block with one
+		log.Warn("Head state missing, repairing chain", "number", currentBlock.Number(), "hash", currentBlock.Hash())
+		if err := bc.repair(&currentBlock); err != nil {
+			return err
+		}
 	}
 
 	if _, err := delegatestate.New(currentBlock.DelegateRoot(), bc.delegateCache); err != nil {
@@ -261,6 +290,25 @@ func (bc *BlockChain) loadLastState() error {
 	return nil
 }
 
+// repair rewinds the given head block to its most recent ancestor whose state
+// is available. The blocks above are kept and reprocessed when imported again.
+func (bc *BlockChain) repair(head **types.Block) error {
+	for {
+		if _, err := state.New((*head).Root(), bc.stateCache); err == nil {
+			log.Info("Rewound blockchain to past state", "number", (*head).Number(), "hash", (*head).Hash())
+			return nil
+		}
+		if (*head).NumberU64() == 0 {
+			return fmt.Errorf("missing genesis state [%x]", (*head).Root())
+		}
+		block := bc.GetBlock((*head).ParentHash(), (*head).NumberU64()-1)
+		if block == nil {
+			return fmt.Errorf("missing block %d [%x]", (*head).NumberU64()-1, (*head).ParentHash())
+		}
+		*head = block
+	}
+}
+
 // SetHead rewinds the local chain to a new head. In the case of headers, everything
 // above the new head will be deleted and the new one set. In the case of blocks
 // though, the head may be further rewound if block bodies are missing (non-archive
@@ -325,7 +373,7 @@ func (bc *BlockChain) FastSyncCommitHead(hash common.Hash) error {
 	if block == nil {
 		return fmt.Errorf("non existent block [%x…]", hash[:4])
 	}
-	if _, err := trie.NewSecure(block.Root(), bc.chainDb, 0); err != nil {
+	if _, err := trie.NewSecure(block.Root(), bc.stateCache.TrieDB(), 0); err != nil {
 		return err
 	}
 	// If all checks out, manually set the head block
@@ -459,35 +507,6 @@ func (bc *BlockChain) ResetWithGenesisBlock(genesis *types.Block) error {
 	return nil
 }
 
-// Export writes the active chain to the given writer.
-func (bc *BlockChain) Export(w io.Writer) error {
-	return bc.ExportN(w, uint64(0), bc.currentBlock.NumberU64())
-}
-
-// ExportN writes a subset of the active chain to the given writer.
-func (bc *BlockChain) ExportN(w io.Writer, first uint64, last uint64) error {
-	bc.mu.RLock()
-	defer bc.mu.RUnlock()
-
-	if first > last {
-		return fmt.Errorf("export failed: first (%d) is greater than last (%d)", first, last)
-	}
-	log.Info("Exporting batch of blocks", "count", last-first+1)
-
-	for nr := first; nr <= last; nr++ {
-		block := bc.GetBlockByNumber(nr)
-		if block == nil {
-			return fmt.Errorf("export failed on #%d: not found", nr)
-		}
-
-		if err := block.EncodeRLP(w); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
 // insert injects a new head block into the current block chain. This method
 // assumes that the block is indeed a true head. It will also reset the head
 // header and the head fast sync block to this very same block if they are older
@@ -498,24 +517,80 @@ func (bc *BlockChain) insert(block *types.Block) {
 	// If the block is on a side chain or an unknown one, force other heads onto it too
 	updateHeads := GetCanonicalHash(bc.chainDb, block.NumberU64()) != block.Hash()
 
-	// Add the block to the canonical chain number scheme and mark as the head
-	if err := WriteCanonicalHash(bc.chainDb, block.Hash(), block.NumberU64()); err != nil {
-		log.Crit("Failed to insert block number", "err", err)
+	// Add the block to the canonical chain number scheme and mark as the head,
+	// atomically dropping any head intent recorded along with the block
+	batch := bc.chainDb.NewBatch()
+	WriteCanonicalHash(batch, block.Hash(), block.NumberU64())
+	WriteHeadBlockHash(batch, block.Hash())
+	if updateHeads {
+		WriteHeadFastBlockHash(batch, block.Hash())
 	}
-	if err := WriteHeadBlockHash(bc.chainDb, block.Hash()); err != nil {
-		log.Crit("Failed to insert head block hash", "err", err)
+	DeleteHeadIntent(batch)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to insert head block", "err", err)
 	}
 	bc.currentBlock = block
 
 	// If the block is better than our head or is on a different chain, force update heads
 	if updateHeads {
 		bc.hc.SetCurrentHeader(block.Header())
+		bc.currentFastBlock = block
+	}
+}
+
+// resolveHeadIntent completes or discards a head update interrupted by a crash
+// between committing a block with its state and marking it as the chain head.
+// If the state of the block survived, the head is rolled forward onto it;
+// otherwise the blocks between the current head and the intended one are
+// re-executed to regenerate their state.
+func (bc *BlockChain) resolveHeadIntent() error {
+	hash := GetHeadIntent(bc.chainDb)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	block := bc.GetBlockByHash(hash)
+	switch {
+	case block == nil:
+		log.Warn("Discarding head intent of missing block", "hash", hash)
+		DeleteHeadIntent(bc.chainDb)
+		return nil
+
+	case block.Hash() == bc.currentBlock.Hash():
+		DeleteHeadIntent(bc.chainDb)
+		return nil
 
-		if err := WriteHeadFastBlockHash(bc.chainDb, block.Hash()); err != nil {
-			log.Crit("Failed to insert head fast block hash", "err", err)
+	case block.ParentHash() == bc.currentBlock.Hash() && bc.HasBlockAndState(hash):
+		log.Warn("Rolling chain head forward", "number", block.Number(), "hash", hash)
+		bc.insert(block)
+		return nil
+	}
+	// Collect the blocks leading from the current head to the intended one
+	var chain types.Blocks
+	for current := block; current.Hash() != bc.currentBlock.Hash(); {
+		if current.NumberU64() <= bc.currentBlock.NumberU64() {
+			log.Warn("Discarding head intent of disconnected block", "number", block.Number(), "hash", hash)
+			DeleteHeadIntent(bc.chainDb)
+			return nil
 		}
-		bc.currentFastBlock = block
+		chain = append(chain, current)
+		if current = bc.GetBlock(current.ParentHash(), current.NumberU64()-1); current == nil {
+			log.Warn("Discarding head intent of dangling block", "number", block.Number(), "hash", hash)
+			DeleteHeadIntent(bc.chainDb)
+			return nil
+		}
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	log.Warn("Re-executing blocks of interrupted head update", "count", len(chain), "number", block.Number(), "hash", hash)
+	if _, err := bc.InsertChain(chain); err != nil {
+		return err
+	}
+	if bc.currentBlock.Hash() != hash {
+		// Re-execution may legitimately settle on a different head, only drop the marker
+		DeleteHeadIntent(bc.chainDb)
 	}
+	return nil
 }
 
 // Genesis retrieves the chain's genesis block.
@@ -647,6 +722,7 @@ func (bc *BlockChain) Stop() {
 	atomic.StoreInt32(&bc.procInterrupt, 1)
 
 	bc.wg.Wait()
+	bc.flushState()
 	log.Info("Blockchain manager stopped")
 }
 
@@ -849,7 +925,11 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 	if err := WriteBlock(batch, block); err != nil {
 		return NonStatTy, err
 	}
-	if _, err := state.CommitTo(batch, false); err != nil {
+	root, err := state.Commit(false)
+	if err != nil {
+		return NonStatTy, err
+	}
+	if err := bc.commitState(block, root); err != nil {
 		return NonStatTy, err
 	}
 
@@ -885,6 +965,11 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 		if err := WritePreimages(bc.chainDb, block.NumberU64(), state.Preimages()); err != nil {
 			return NonStatTy, err
 		}
+		// Record the head update about to happen along with the block, so that a
+		// crash before it completes is resolved on restart
+		if err := WriteHeadIntent(batch, block.Hash()); err != nil {
+			return NonStatTy, err
+		}
 		status = CanonStatTy
 	} else {
 		status = SideStatTy
@@ -1132,6 +1217,9 @@ func (bc *BlockChain) insertChain(chain types.Blocks, syncCallback ...func()) (i
 			log.Debug("Blockchain stateDB", "err", err)
 			return i, events, coalescedLogs, err
 		}
+		if bc.StateStats() {
+			stateDB.EnableAccessStats()
+		}
 		delegateDB, err := delegatestate.New(parent.DelegateRoot(), bc.delegateCache)
 		if err != nil {
 			return i, events, coalescedLogs, err
@@ -1144,6 +1232,9 @@ func (bc *BlockChain) insertChain(chain types.Blocks, syncCallback ...func()) (i
 			bc.reportBlock(block, receipts, err)
 			return i, events, coalescedLogs, err
 		}
+		if stats := stateDB.AccessStats(); stats != nil {
+			reportStateStats(block, stats)
+		}
 		// Validate the stateDB using the default validator
 		err = bc.Validator().ValidateState(block, parent, stateDB, receipts, usedGas, delegateDB)
 		if err != nil {
@@ -1347,6 +1438,9 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 			}
 		}()
 	}
+	if len(oldChain) > 0 && len(newChain) > 0 {
+		go bc.reorgFeed.Send(ReorgEvent{CommonBlock: commonBlock, OldChain: oldChain, NewChain: newChain})
+	}
 
 	return nil
 }
@@ -1585,6 +1679,11 @@ func (bc *BlockChain) SubscribeChainSideEvent(ch chan<- ChainSideEvent) event.Su
 	return bc.scope.Track(bc.chainSideFeed.Subscribe(ch))
 }
 
+// SubscribeReorgEvent registers a subscription of ReorgEvent.
+func (bc *BlockChain) SubscribeReorgEvent(ch chan<- ReorgEvent) event.Subscription {
+	return bc.scope.Track(bc.reorgFeed.Subscribe(ch))
+}
+
 // SubscribeLogsEvent registers a subscription of []*walletType.Log.
 func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return bc.scope.Track(bc.logsFeed.Subscribe(ch))
@@ -45,6 +45,14 @@ type ChainIndexerBackend interface {
 	Commit() error
 }
 
+// Supervisor runs the background loops of long lived modules, restarting them if
+// they panic. It is implemented by node.Supervisor.
+type Supervisor interface {
+	// Go starts run on a new goroutine under supervision. The function has to
+	// return when quit is closed.
+	Go(module string, run func(quit <-chan struct{}))
+}
+
 // ChainIndexerChain interface is used for connecting the indexer to a blockchain
 type ChainIndexerChain interface {
 	// CurrentHeader retrieves the latest locally known header.
@@ -69,9 +77,15 @@ type ChainIndexer struct {
 	backend  ChainIndexerBackend // Background processor generating the index data content
 	children []*ChainIndexer     // Child indexers to cascade chain updates to
 
-	active uint32          // Flag whether the event loop was started
-	update chan struct{}   // Notification channel that headers should be processed
-	quit   chan chan error // Quit channel to tear down running goroutines
+	supervisor Supervisor      // Supervisor restarting the loops if a backend panics, nil if unsupervised
+	kind       string          // Kind of the index, naming the supervised loops
+	startOnce  sync.Once       // Ensures the update loop is started only once
+	started    uint32          // Flag whether the update loop was started
+	active     uint32          // Flag whether the event loop was started
+	update     chan struct{}   // Notification channel that headers should be processed
+	quit       chan chan error // Quit channel to tear down running goroutines
+	halted     chan struct{}   // Channel closed when the supervisor terminated the loops
+	haltOnce   sync.Once       // Ensures halted is closed only once
 
 	sectionSize uint64 // Number of blocks in a single chain segment to process
 	confirmsReq uint64 // Number of confirmations before processing a completed segment
@@ -81,6 +95,7 @@ type ChainIndexer struct {
 	cascadedHead   uint64 // Block number of the last completed section cascaded to subindexers
 
 	throttling time.Duration // Disk throttling to prevent a heavy upgrade from hogging resources
+	paused     bool          // Whether section processing is suspended by the operator
 
 	log  log.Logger
 	lock sync.RWMutex
@@ -88,26 +103,61 @@ type ChainIndexer struct {
 
 // NewChainIndexer creates a new chain indexer to do background processing on
 // chain segments of a given size after certain number of confirmations passed.
-// The throttling parameter might be used to prevent database thrashing.
+// The throttling parameter might be used to prevent database thrashing. The
+// processing starts once the indexer is started or added as a child.
 func NewChainIndexer(chainDb, indexDb aoadb.Database, backend ChainIndexerBackend, section, confirm uint64, throttling time.Duration, kind string) *ChainIndexer {
 	c := &ChainIndexer{
 		chainDb:     chainDb,
 		indexDb:     indexDb,
 		backend:     backend,
+		kind:        kind,
 		update:      make(chan struct{}, 1),
 		quit:        make(chan chan error),
+		halted:      make(chan struct{}),
 		sectionSize: section,
 		confirmsReq: confirm,
 		throttling:  throttling,
 		log:         log.New("type", kind),
 	}
-	// Initialize database dependent fields and start the updater
+	// Initialize database dependent fields
 	c.loadValidSections()
-	go c.updateLoop()
-
 	return c
 }
 
+// SetSupervisor runs the loops of the indexer and of its children under the
+// given supervisor, restarting them if the backend panics. It must be called
+// before the indexer is started.
+func (c *ChainIndexer) SetSupervisor(supervisor Supervisor) {
+	c.supervisor = supervisor
+}
+
+// spawn runs a loop of the indexer on a new goroutine, supervised if the indexer
+// has a supervisor. Loops terminate when the indexer is closed, or when the
+// supervisor terminates them.
+func (c *ChainIndexer) spawn(name string, loop func(quit <-chan struct{})) {
+	if c.supervisor == nil {
+		go loop(nil)
+		return
+	}
+	c.supervisor.Go(c.kind+" indexer "+name, func(quit <-chan struct{}) {
+		c.haltOnce.Do(func() {
+			go func() {
+				<-quit
+				close(c.halted)
+			}()
+		})
+		loop(quit)
+	})
+}
+
+// start launches the update loop processing the sections, unless already running.
+func (c *ChainIndexer) start() {
+	c.startOnce.Do(func() {
+		atomic.StoreUint32(&c.started, 1)
+		c.spawn("update", c.updateLoop)
+	})
+}
+
 // AddKnownSectionHead marks a new section head as known/processed if it is newer
 // than the already known best section head
 func (c *ChainIndexer) AddKnownSectionHead(section uint64, shead common.Hash) {
@@ -125,10 +175,13 @@ func (c *ChainIndexer) AddKnownSectionHead(section uint64, shead common.Hash) {
 // cascading background processing. Children do not need to be started, they
 // are notified about new events by their parents.
 func (c *ChainIndexer) Start(chain ChainIndexerChain) {
-	events := make(chan ChainEvent, 10)
-	sub := chain.SubscribeChainEvent(events)
+	c.start()
+	c.spawn("events", func(quit <-chan struct{}) {
+		events := make(chan ChainEvent, 10)
+		sub := chain.SubscribeChainEvent(events)
 
-	go c.eventLoop(chain.CurrentHeader(), events, sub)
+		c.eventLoop(chain.CurrentHeader(), events, sub, quit)
+	})
 }
 
 // Close tears down all goroutines belonging to the indexer and returns any error
@@ -136,17 +189,19 @@ func (c *ChainIndexer) Start(chain ChainIndexerChain) {
 func (c *ChainIndexer) Close() error {
 	var errs []error
 
-	// Tear down the primary update loop
+	// Tear down the primary update loop and if needed, the secondary event loop,
+	// unless they were terminated by the supervisor already
 	errc := make(chan error)
-	c.quit <- errc
-	if err := <-errc; err != nil {
-		errs = append(errs, err)
-	}
-	// If needed, tear down the secondary event loop
-	if atomic.LoadUint32(&c.active) != 0 {
-		c.quit <- errc
-		if err := <-errc; err != nil {
-			errs = append(errs, err)
+	for _, running := range []*uint32{&c.started, &c.active} {
+		if atomic.LoadUint32(running) == 0 {
+			continue
+		}
+		select {
+		case c.quit <- errc:
+			if err := <-errc; err != nil {
+				errs = append(errs, err)
+			}
+		case <-c.halted:
 		}
 	}
 	// Close all children
@@ -171,7 +226,7 @@ func (c *ChainIndexer) Close() error {
 // eventLoop is a secondary - optional - event loop of the indexer which is only
 // started for the outermost indexer to push chain head events into a processing
 // queue.
-func (c *ChainIndexer) eventLoop(currentHeader *types.Header, events chan ChainEvent, sub event.Subscription) {
+func (c *ChainIndexer) eventLoop(currentHeader *types.Header, events chan ChainEvent, sub event.Subscription, quit <-chan struct{}) {
 	// Mark the chain indexer as active, requiring an additional teardown
 	atomic.StoreUint32(&c.active, 1)
 
@@ -191,11 +246,17 @@ func (c *ChainIndexer) eventLoop(currentHeader *types.Header, events chan ChainE
 			errc <- nil
 			return
 
+		case <-quit:
+			return
+
 		case ev, ok := <-events:
 			// Received a new event, ensure it's not nil (closing) and update
 			if !ok {
-				errc := <-c.quit
-				errc <- nil
+				select {
+				case errc := <-c.quit:
+					errc <- nil
+				case <-quit:
+				}
 				return
 			}
 			header := ev.Block.Header()
@@ -257,12 +318,16 @@ func (c *ChainIndexer) newHead(head uint64, reorg bool) {
 
 // updateLoop is the main event loop of the indexer which pushes chain segments
 // down into the processing backend.
-func (c *ChainIndexer) updateLoop() {
+func (c *ChainIndexer) updateLoop(quit <-chan struct{}) {
 	var (
 		updating bool
 		updated  time.Time
 	)
-
+	// Resume any processing interrupted by a crash of a previous run
+	select {
+	case c.update <- struct{}{}:
+	default:
+	}
 	for {
 		select {
 		case errc := <-c.quit:
@@ -270,9 +335,16 @@ func (c *ChainIndexer) updateLoop() {
 			errc <- nil
 			return
 
+		case <-quit:
+			return
+
 		case <-c.update:
 			// Section headers completed (or rolled back), update the index
 			c.lock.Lock()
+			if c.paused {
+				c.lock.Unlock()
+				continue
+			}
 			if c.knownSections > c.storedSections {
 				// Periodically print an upgrade log message to the user
 				if time.Since(updated) > 8*time.Second {
@@ -375,12 +447,64 @@ func (c *ChainIndexer) Sections() (uint64, uint64, common.Hash) {
 	return c.storedSections, c.storedSections*c.sectionSize - 1, c.SectionHead(c.storedSections - 1)
 }
 
+// Progress returns the number of sections indexed into the database and the
+// number of sections known to be complete, alongside whether processing of the
+// remaining ones is paused.
+func (c *ChainIndexer) Progress() (stored uint64, known uint64, paused bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.storedSections, c.knownSections, c.paused
+}
+
+// Pause suspends the processing of further sections until Resume is called. A
+// section being processed currently is still finished.
+func (c *ChainIndexer) Pause() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.paused {
+		c.paused = true
+		c.log.Info("Paused chain indexing", "sections", c.storedSections)
+	}
+}
+
+// Resume continues the processing of sections suspended by Pause.
+func (c *ChainIndexer) Resume() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.paused {
+		return
+	}
+	c.paused = false
+	c.log.Info("Resumed chain indexing", "sections", c.storedSections)
+
+	select {
+	case c.update <- struct{}{}:
+	default:
+	}
+}
+
+// SetThrottling changes the time waited between processing two consecutive
+// sections while catching up with the chain.
+func (c *ChainIndexer) SetThrottling(throttling time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.throttling = throttling
+}
+
 // AddChildIndexer adds a child ChainIndexer that can use the output of this one
 func (c *ChainIndexer) AddChildIndexer(indexer *ChainIndexer) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
 	c.children = append(c.children, indexer)
+	if indexer.supervisor == nil {
+		indexer.supervisor = c.supervisor
+	}
+	indexer.start()
 
 	// Cascade any pending updates to new children too
 	if c.storedSections > 0 {
@@ -20,11 +20,13 @@ import (
 	"fmt"
 	"math/big"
 	"math/rand"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 	"github.com/Aurorachain-io/go-aoa/common"
 	"github.com/Aurorachain-io/go-aoa/core/types"
-	"github.com/Aurorachain-io/go-aoa/emdb"
+	"github.com/Aurorachain-io/go-aoa/aoadb"
 )
 
 // Runs multiple tests with randomized parameters.
@@ -47,7 +49,7 @@ func TestChainIndexerWithChildren(t *testing.T) {
 // multiple backends. The section size and required confirmation count parameters
 // are randomized.
 func testChainIndexer(t *testing.T, count int) {
-	db, _ := emdb.NewMemDatabase()
+	db, _ := aoadb.NewMemDatabase()
 	defer db.Close()
 
 	// Create a chain of indexers and ensure they all report empty
@@ -58,7 +60,7 @@ func testChainIndexer(t *testing.T, count int) {
 			confirmsReq = uint64(rand.Intn(10))
 		)
 		backends[i] = &testChainIndexBackend{t: t, processCh: make(chan uint64)}
-		backends[i].indexer = NewChainIndexer(db, emdb.NewTable(db, string([]byte{byte(i)})), backends[i], sectionSize, confirmsReq, 0, fmt.Sprintf("indexer-%d", i))
+		backends[i].indexer = NewChainIndexer(db, aoadb.NewTable(db, string([]byte{byte(i)})), backends[i], sectionSize, confirmsReq, 0, fmt.Sprintf("indexer-%d", i))
 
 		if sections, _, _ := backends[i].indexer.Sections(); sections != 0 {
 			t.Fatalf("Canonical section count mismatch: have %v, want %v", sections, 0)
@@ -67,6 +69,7 @@ func testChainIndexer(t *testing.T, count int) {
 			backends[i-1].indexer.AddChildIndexer(backends[i].indexer)
 		}
 	}
+	backends[0].indexer.start()
 	defer backends[0].indexer.Close() // parent indexer shuts down children
 	// notify pings the root indexer about a new head or reorg, then expect
 	// processed blocks if a section is processable
@@ -132,6 +135,94 @@ func testChainIndexer(t *testing.T, count int) {
 	}
 }
 
+// Tests that a supervised indexer whose backend panics is restarted, resumes
+// the interrupted section and terminates together with its supervisor.
+func TestChainIndexerSupervised(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+	defer db.Close()
+
+	for i := uint64(0); i < 3; i++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(i)}
+		if i > 0 {
+			header.ParentHash = GetCanonicalHash(db, i-1)
+		}
+		WriteHeader(db, header)
+		WriteCanonicalHash(db, header.Hash(), i)
+	}
+	supervisor := &testSupervisor{quit: make(chan struct{})}
+	indexer := NewChainIndexer(db, aoadb.NewTable(db, "i"), new(panickingIndexBackend), 1, 0, 0, "panicking")
+	indexer.SetSupervisor(supervisor)
+	indexer.start()
+	indexer.newHead(2, false)
+
+	for i := 0; ; i++ {
+		if sections, _, _ := indexer.Sections(); sections == 3 {
+			break
+		}
+		if i == 300 {
+			t.Fatalf("sections not indexed after backend crash")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if crashes := atomic.LoadInt32(&supervisor.crashes); crashes != 1 {
+		t.Fatalf("crash count mismatch: have %d, want 1", crashes)
+	}
+	close(supervisor.quit)
+	supervisor.wg.Wait()
+
+	done := make(chan error)
+	go func() { done <- indexer.Close() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("failed to close indexer: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("indexer failed to close after its supervisor terminated")
+	}
+}
+
+// testSupervisor restarts crashed modules right away.
+type testSupervisor struct {
+	quit    chan struct{}
+	wg      sync.WaitGroup
+	crashes int32
+}
+
+func (s *testSupervisor) Go(module string, run func(quit <-chan struct{})) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for crashed := true; crashed; {
+			crashed = func() (crashed bool) {
+				defer func() {
+					if recover() != nil {
+						atomic.AddInt32(&s.crashes, 1)
+						crashed = true
+					}
+				}()
+				run(s.quit)
+				return false
+			}()
+		}
+	}()
+}
+
+// panickingIndexBackend is a ChainIndexerBackend panicking on the first header.
+type panickingIndexBackend struct {
+	panicked bool
+}
+
+func (b *panickingIndexBackend) Reset(section uint64, prevHead common.Hash) error { return nil }
+func (b *panickingIndexBackend) Commit() error                                    { return nil }
+
+func (b *panickingIndexBackend) Process(header *types.Header) {
+	if !b.panicked {
+		b.panicked = true
+		panic("boom")
+	}
+}
+
 // testChainIndexBackend implements ChainIndexerBackend
 type testChainIndexBackend struct {
 	t                          *testing.T
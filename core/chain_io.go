@@ -0,0 +1,180 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/rlp"
+)
+
+const (
+	// defaultImportBatchSize is the number of blocks inserted into the chain at
+	// once if no explicit batch size is configured.
+	defaultImportBatchSize = 2500
+
+	// chainIOReportInterval is the minimum time between two progress callbacks.
+	chainIOReportInterval = 8 * time.Second
+)
+
+var (
+	// ErrImportInterrupted is returned if a chain import is aborted through the
+	// interrupt channel of its configuration.
+	ErrImportInterrupted = errors.New("import interrupted")
+
+	// ErrGenesisMismatch is returned if an imported block stream starts with a
+	// genesis block different from the local one.
+	ErrGenesisMismatch = errors.New("imported genesis does not match local genesis")
+)
+
+// ChainProgressFn is called periodically during chain exports and imports with
+// the number of blocks processed so far and the last processed block. It is
+// always called once more after the final block.
+type ChainProgressFn func(processed uint64, last *types.Block)
+
+// ImportConfig contains the options of a chain import.
+type ImportConfig struct {
+	BatchSize   int             // Number of blocks to insert at once (default 2500)
+	SkipPresent bool            // Skip batches that are already fully present locally
+	Contiguous  bool            // Require every block to be the child of the previous one
+	Interrupt   <-chan struct{} // Aborts the import at the next batch if closed
+	Progress    ChainProgressFn // Optional callback reporting import progress
+}
+
+// Export writes the canonical blocks first..last (inclusive) of the active chain
+// to the given writer as a stream of RLP encoded blocks. The optional progress
+// callback may be nil.
+func (bc *BlockChain) Export(w io.Writer, first, last uint64, progress ChainProgressFn) error {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if first > last {
+		return fmt.Errorf("export failed: first (%d) is greater than last (%d)", first, last)
+	}
+	log.Info("Exporting batch of blocks", "count", last-first+1)
+
+	var (
+		block  *types.Block
+		report = time.Now()
+	)
+	err := ForEachCanonicalBlock(bc.chainDb, first, last, func(exported *types.Block, _ types.Receipts) error {
+		if err := exported.EncodeRLP(w); err != nil {
+			return err
+		}
+		if block = exported; progress != nil && time.Since(report) > chainIOReportInterval {
+			progress(block.NumberU64()-first+1, block)
+			report = time.Now()
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("export failed: %v", err)
+	}
+	if progress != nil {
+		progress(last-first+1, block)
+	}
+	return nil
+}
+
+// ImportChain reads a stream of RLP encoded blocks as produced by Export and
+// inserts them into the chain in batches, returning the number of blocks read.
+// A leading genesis block is checked against the local one and skipped.
+func (bc *BlockChain) ImportChain(r io.Reader, config ImportConfig) (uint64, error) {
+	batchSize := config.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultImportBatchSize
+	}
+	interrupted := func() bool {
+		select {
+		case <-config.Interrupt:
+			return true
+		default:
+			return false
+		}
+	}
+	var (
+		stream = rlp.NewStream(r, 0)
+		blocks = make(types.Blocks, 0, batchSize)
+		report = time.Now()
+		parent *types.Block
+		n      uint64
+	)
+	for batch := 0; ; batch++ {
+		if interrupted() {
+			return n, ErrImportInterrupted
+		}
+		// Load the next batch of blocks from the stream
+		blocks = blocks[:0]
+		for len(blocks) < batchSize {
+			block := new(types.Block)
+			if err := stream.Decode(block); err == io.EOF {
+				break
+			} else if err != nil {
+				return n, fmt.Errorf("at block %d: %v", n, err)
+			}
+			n++
+			if block.NumberU64() == 0 {
+				if block.Hash() != bc.genesisBlock.Hash() {
+					return n, ErrGenesisMismatch
+				}
+				parent = block
+				continue
+			}
+			if config.Contiguous && parent != nil && (block.ParentHash() != parent.Hash() || block.NumberU64() != parent.NumberU64()+1) {
+				return n, fmt.Errorf("non contiguous block #%d [%x…], parent #%d [%x…]", block.NumberU64(), block.Hash().Bytes()[:4], parent.NumberU64(), parent.Hash().Bytes()[:4])
+			}
+			blocks, parent = append(blocks, block), block
+		}
+		if len(blocks) == 0 {
+			break
+		}
+		// Insert the batch unless it's already known
+		if config.SkipPresent && hasAllBlocks(bc, blocks) {
+			log.Info("Skipping batch as all blocks present", "batch", batch, "first", blocks[0].Hash(), "last", blocks[len(blocks)-1].Hash())
+		} else {
+			if interrupted() {
+				return n, ErrImportInterrupted
+			}
+			if _, err := bc.InsertChain(blocks); err != nil {
+				return n, fmt.Errorf("batch %d: failed to insert: %v", batch, err)
+			}
+		}
+		if config.Progress != nil && time.Since(report) > chainIOReportInterval {
+			config.Progress(n, blocks[len(blocks)-1])
+			report = time.Now()
+		}
+	}
+	if config.Progress != nil {
+		config.Progress(n, parent)
+	}
+	return n, nil
+}
+
+// hasAllBlocks reports whether all the given blocks are already stored locally.
+func hasAllBlocks(bc *BlockChain, blocks types.Blocks) bool {
+	for _, block := range blocks {
+		if !bc.HasBlock(block.Hash(), block.NumberU64()) {
+			return false
+		}
+	}
+	return true
+}
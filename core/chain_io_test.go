@@ -0,0 +1,144 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus/dpos"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/core/vm"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+	"github.com/Aurorachain-io/go-aoa/rlp"
+)
+
+// newChainIOTestChain returns a fresh chain of the given genesis, backed by its
+// own in memory database.
+func newChainIOTestChain(t *testing.T, gspec *Genesis) *BlockChain {
+	db, _ := aoadb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	chain, err := NewBlockChain(db, nil, gspec.Config, dpos.New(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	return chain
+}
+
+// Tests that a chain exported to a stream is imported back into an empty chain
+// of the same genesis, and that the progress callbacks report the final block.
+func TestExportImportChain(t *testing.T) {
+	var (
+		config = params.AllDacchainProtocolChanges
+		signer = types.MakeSigner(config, big.NewInt(1))
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config: config,
+			Alloc:  GenesisAlloc{addr: {Balance: big.NewInt(params.Em)}},
+			Agents: GenesisAgents{{Address: "0x0200", Vote: 1, Nickname: "test"}},
+		}
+	)
+	gendb, _ := aoadb.NewMemDatabase()
+	genesis := gspec.MustCommit(gendb)
+	blocks, _ := GenerateChain(config, genesis, dpos.New(), gendb, 6, func(i int, gen *BlockGen) {
+		tx := types.NewTransaction(gen.TxNonce(addr), common.HexToAddress("0x01"), big.NewInt(10), 100000, big.NewInt(1), nil, types.ActionTrans, nil, "")
+		tx, _ = types.SignTx(tx, signer, key)
+		gen.AddTx(tx)
+	})
+	source := newChainIOTestChain(t, gspec)
+	defer source.Stop()
+	if _, err := source.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	head := blocks[len(blocks)-1]
+
+	// Export the whole chain, including the genesis block
+	var (
+		stream   bytes.Buffer
+		exported uint64
+		last     *types.Block
+	)
+	err := source.Export(&stream, 0, head.NumberU64(), func(processed uint64, block *types.Block) {
+		exported, last = processed, block
+	})
+	if err != nil {
+		t.Fatalf("failed to export chain: %v", err)
+	}
+	if exported != head.NumberU64()+1 || last == nil || last.Hash() != head.Hash() {
+		t.Fatalf("export progress mismatch: have %d blocks, want %d up to #%d", exported, head.NumberU64()+1, head.NumberU64())
+	}
+	data := common.CopyBytes(stream.Bytes())
+
+	// Import it into an empty chain and check that it ends up identical
+	target := newChainIOTestChain(t, gspec)
+	defer target.Stop()
+
+	var imported uint64
+	n, err := target.ImportChain(bytes.NewReader(data), ImportConfig{
+		BatchSize:  4,
+		Contiguous: true,
+		Progress:   func(processed uint64, block *types.Block) { imported, last = processed, block },
+	})
+	if err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	if n != exported || imported != exported || last.Hash() != head.Hash() {
+		t.Fatalf("import progress mismatch: have %d read, %d reported, want %d", n, imported, exported)
+	}
+	if current := target.CurrentBlock(); current.Hash() != head.Hash() {
+		t.Fatalf("head mismatch: have #%d [%x…], want #%d [%x…]", current.NumberU64(), current.Hash().Bytes()[:4], head.NumberU64(), head.Hash().Bytes()[:4])
+	}
+	for _, block := range blocks {
+		if hash := GetCanonicalHash(target.chainDb, block.NumberU64()); hash != block.Hash() {
+			t.Errorf("block #%d: canonical hash mismatch: have %x, want %x", block.NumberU64(), hash, block.Hash())
+		}
+	}
+	// Re-importing the same stream skips the known blocks
+	if _, err := target.ImportChain(bytes.NewReader(data), ImportConfig{SkipPresent: true}); err != nil {
+		t.Fatalf("failed to re-import chain: %v", err)
+	}
+	// Exporting a sub range emits exactly the requested blocks
+	stream.Reset()
+	if err := source.Export(&stream, 2, 4, nil); err != nil {
+		t.Fatalf("failed to export range: %v", err)
+	}
+	decoder := rlp.NewStream(&stream, 0)
+	for i := 2; i <= 4; i++ {
+		block := new(types.Block)
+		if err := decoder.Decode(block); err != nil {
+			t.Fatalf("failed to decode block #%d: %v", i, err)
+		}
+		if block.Hash() != blocks[i-1].Hash() {
+			t.Errorf("range block #%d mismatch: have %x, want %x", i, block.Hash(), blocks[i-1].Hash())
+		}
+	}
+	if stream.Len() != 0 {
+		t.Errorf("range export left %d trailing bytes", stream.Len())
+	}
+	// Importing into a chain with a different genesis is rejected
+	other := newChainIOTestChain(t, &Genesis{Config: config, Agents: gspec.Agents, ExtraData: []byte("other")})
+	defer other.Stop()
+	if _, err := other.ImportChain(bytes.NewReader(data), ImportConfig{}); err != ErrGenesisMismatch {
+		t.Fatalf("foreign genesis error mismatch: have %v, want %v", err, ErrGenesisMismatch)
+	}
+}
@@ -165,7 +165,7 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, dacEngine co
 	genblock := func(i int, parent *types.Block, statedb *state.StateDB, delegatedb *delegatestate.DelegateDB) (*types.Block, types.Receipts) {
 		// TODO(karalabe): This is needed for clique, which depends on multiple blocks.
 		// It's nonetheless ugly to spin up a blockchain here. Get rid of this somehow.
-		blockchain, _ := NewBlockChain(db, config, dacEngine, vm.Config{}, nil)
+		blockchain, _ := NewBlockChain(db, nil, config, dacEngine, vm.Config{}, nil)
 		defer blockchain.Stop()
 
 		b := &BlockGen{i: i, parent: parent, chain: blocks, chainReader: blockchain, statedb: statedb, config: config, engine: dacEngine, delegatedb: delegatedb}
@@ -236,7 +236,7 @@ func newCanonical(dacEngine consensus.Engine, n int, full bool) (aoadb.Database,
 	db, _ := aoadb.NewMemDatabase()
 	genesis := gspec.MustCommit(db)
 
-	blockchain, _ := NewBlockChain(db, params.AllDacchainProtocolChanges, dacEngine, vm.Config{}, nil)
+	blockchain, _ := NewBlockChain(db, nil, params.AllDacchainProtocolChanges, dacEngine, vm.Config{}, nil)
 	// Create and inject the requested chain
 	if n == 0 {
 		return db, blockchain, nil
@@ -0,0 +1,163 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/rlp"
+)
+
+// walkAhead is the number of canonical blocks read from the database ahead of
+// the one being handed to the callback of ForEachCanonicalBlock.
+const walkAhead = 256
+
+// walkTask is a canonical block read from the database by the chain walker in
+// its raw form, decoded concurrently by one of its workers.
+type walkTask struct {
+	number uint64
+	hash   common.Hash
+
+	headerRLP   rlp.RawValue
+	bodyRLP     rlp.RawValue
+	receiptsRLP rlp.RawValue
+
+	block    *types.Block
+	receipts types.Receipts
+	err      error
+
+	done chan struct{} // Closed when the task is ready to be handed out
+}
+
+// readWalkTask reads the raw data of a canonical block from the database.
+func readWalkTask(db DatabaseReader, number uint64) *walkTask {
+	task := &walkTask{number: number, done: make(chan struct{})}
+
+	if task.hash = GetCanonicalHash(db, number); task.hash == (common.Hash{}) {
+		task.err = fmt.Errorf("canonical block #%d not found", number)
+		return task
+	}
+	task.headerRLP = GetHeaderRLP(db, task.hash, number)
+	task.bodyRLP = GetBodyRLP(db, task.hash, number)
+	if len(task.headerRLP) == 0 || len(task.bodyRLP) == 0 {
+		task.err = fmt.Errorf("block #%d [%x…] not found", number, task.hash[:4])
+		return task
+	}
+	task.receiptsRLP, _ = db.Get(blockReceiptsKey(task.hash, number))
+	return task
+}
+
+// decode assembles the block and its receipts from the raw data read.
+func (task *walkTask) decode() {
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(task.headerRLP, header); err != nil {
+		task.err = fmt.Errorf("invalid header RLP of block #%d [%x…]: %v", task.number, task.hash[:4], err)
+		return
+	}
+	body := new(types.Body)
+	if err := rlp.DecodeBytes(task.bodyRLP, body); err != nil {
+		task.err = fmt.Errorf("invalid body RLP of block #%d [%x…]: %v", task.number, task.hash[:4], err)
+		return
+	}
+	task.block = types.NewBlockWithHeader(header).WithBody(body.Transactions)
+
+	if len(task.receiptsRLP) > 0 {
+		if task.receipts, task.err = decodeBlockReceipts(task.receiptsRLP); task.err != nil {
+			task.err = fmt.Errorf("invalid receipts RLP of block #%d [%x…]: %v", task.number, task.hash[:4], task.err)
+		}
+	}
+}
+
+// ForEachCanonicalBlock invokes cb with every canonical block in the range
+// [from, to] and its receipts, in ascending order. Blocks are read sequentially
+// ahead of the callback and decoded by a pool of concurrent workers, so indexers
+// only need to deal with the data itself.
+//
+// Walking stops at the first error returned by cb, which is passed through, or
+// at the first block missing from the database. Blocks without stored receipts
+// are handed out with nil receipts.
+func ForEachCanonicalBlock(db DatabaseReader, from, to uint64, cb func(*types.Block, types.Receipts) error) error {
+	if from > to {
+		return fmt.Errorf("invalid block range #%d-#%d", from, to)
+	}
+	var (
+		tasks   = make(chan *walkTask, walkAhead)
+		results = make(chan *walkTask, walkAhead)
+		quit    = make(chan struct{})
+		pend    = new(sync.WaitGroup)
+	)
+	// Tear down the reader and the decoders on any exit path
+	defer func() {
+		close(quit)
+		pend.Wait()
+	}()
+
+	for i := 0; i < runtime.NumCPU(); i++ {
+		pend.Add(1)
+		go func() {
+			defer pend.Done()
+
+			for task := range tasks {
+				task.decode()
+				close(task.done)
+			}
+		}()
+	}
+	// Read the blocks in order, queueing them up both for decoding and delivery
+	pend.Add(1)
+	go func() {
+		defer pend.Done()
+		defer close(tasks)
+		defer close(results)
+
+		for number := from; ; number++ {
+			task := readWalkTask(db, number)
+			select {
+			case results <- task:
+			case <-quit:
+				return
+			}
+			if task.err != nil {
+				close(task.done)
+				return
+			}
+			select {
+			case tasks <- task:
+			case <-quit:
+				return
+			}
+			if number == to {
+				return
+			}
+		}
+	}()
+	// Hand the decoded blocks over to the callback in order
+	for task := range results {
+		<-task.done
+		if task.err != nil {
+			return task.err
+		}
+		if err := cb(task.block, task.receipts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
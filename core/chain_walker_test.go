@@ -0,0 +1,110 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus/dpos"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// Tests that the canonical chain walker hands out all blocks of a range in order
+// together with their receipts, and stops on errors.
+func TestForEachCanonicalBlock(t *testing.T) {
+	var (
+		db, _  = aoadb.NewMemDatabase()
+		config = params.AllDacchainProtocolChanges
+		signer = types.MakeSigner(config, big.NewInt(1))
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+	)
+	genesis := (&Genesis{
+		Config: config,
+		Alloc:  GenesisAlloc{addr: {Balance: big.NewInt(params.Em)}},
+		Agents: GenesisAgents{{Address: "0x0200", Vote: 1, Nickname: "test"}},
+	}).MustCommit(db)
+
+	blocks, receipts := GenerateChain(config, genesis, dpos.New(), db, 300, func(i int, gen *BlockGen) {
+		if i%2 == 0 {
+			tx := types.NewTransaction(gen.TxNonce(addr), common.HexToAddress("0x01"), big.NewInt(10), 100000, big.NewInt(1), nil, types.ActionTrans, nil, "")
+			tx, _ = types.SignTx(tx, signer, key)
+			gen.AddTx(tx)
+		}
+	})
+	for i, block := range blocks {
+		WriteBlock(db, block)
+		WriteBlockReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+	}
+	// Walk a range spanning more than the read-ahead and check the contents
+	next := uint64(1)
+	err := ForEachCanonicalBlock(db, 1, 300, func(block *types.Block, have types.Receipts) error {
+		if block.NumberU64() != next {
+			t.Fatalf("block order mismatch: have #%d, want #%d", block.NumberU64(), next)
+		}
+		want := blocks[next-1]
+		if block.Hash() != want.Hash() || len(block.Transactions()) != len(want.Transactions()) {
+			t.Fatalf("block #%d mismatch: have %x, want %x", next, block.Hash(), want.Hash())
+		}
+		if len(have) != len(receipts[next-1]) {
+			t.Fatalf("block #%d: receipt count mismatch: have %d, want %d", next, len(have), len(receipts[next-1]))
+		}
+		for i := range have {
+			if !equalReceipts(have[i], receipts[next-1][i]) {
+				t.Fatalf("block #%d: receipt %d mismatch", next, i)
+			}
+		}
+		next++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk chain: %v", err)
+	}
+	if next != 301 {
+		t.Fatalf("walked block count mismatch: have %d, want 300", next-1)
+	}
+	// Check that callback errors abort the walk and are passed through
+	var (
+		failure = errors.New("failure")
+		walked  int
+	)
+	err = ForEachCanonicalBlock(db, 10, 300, func(block *types.Block, receipts types.Receipts) error {
+		if walked++; block.NumberU64() == 20 {
+			return failure
+		}
+		return nil
+	})
+	if err != failure || walked != 11 {
+		t.Fatalf("aborted walk mismatch: have %d blocks, error %v, want 11 blocks, error %v", walked, err, failure)
+	}
+	// Check that walking beyond the canonical head fails
+	walked = 0
+	err = ForEachCanonicalBlock(db, 295, 310, func(block *types.Block, receipts types.Receipts) error {
+		walked++
+		return nil
+	})
+	if err == nil || walked != 6 {
+		t.Fatalf("overrun walk mismatch: have %d blocks, error %v, want 6 blocks and an error", walked, err)
+	}
+}
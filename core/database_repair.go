@@ -0,0 +1,67 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/log"
+)
+
+// RepairHead verifies that the head block recorded in a database repaired after
+// a corruption is intact, i.e. that it's canonical and its header, body and total
+// difficulty are all present. Otherwise the head markers are rewound to the most
+// recent intact canonical block, and the blocks lost are synced again. The state
+// of the new head is checked when the chain is loaded.
+func RepairHead(db aoadb.Database) error {
+	head := GetHeadBlockHash(db)
+	if head == (common.Hash{}) {
+		return nil // Empty database, the chain is reset on load
+	}
+	number := GetBlockNumber(db, head)
+	if number == missingNumber {
+		// The head is unknown, start from the header chain head instead
+		if number = GetBlockNumber(db, GetHeadHeaderHash(db)); number == missingNumber {
+			number = 0
+		}
+	}
+	for ; ; number-- {
+		hash := GetCanonicalHash(db, number)
+		if hash != (common.Hash{}) && intactBlock(db, hash, number) {
+			if hash == head {
+				return nil
+			}
+			log.Warn("Rewinding corrupted chain head", "head", head, "number", number, "hash", hash)
+
+			batch := db.NewBatch()
+			WriteHeadBlockHash(batch, hash)
+			WriteHeadHeaderHash(batch, hash)
+			WriteHeadFastBlockHash(batch, hash)
+			return batch.Write()
+		}
+		if number == 0 {
+			// Without an intact genesis the chain is reset on load
+			log.Error("No intact canonical block found", "head", head)
+			return nil
+		}
+	}
+}
+
+// intactBlock reports whether all parts of a block are present in the database.
+func intactBlock(db aoadb.Database, hash common.Hash, number uint64) bool {
+	return GetHeader(db, hash, number) != nil && GetBody(db, hash, number) != nil && GetTd(db, hash, number) != nil
+}
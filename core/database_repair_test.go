@@ -0,0 +1,92 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+)
+
+// Tests that the head of a repaired database is rewound to the most recent
+// intact canonical block.
+func TestRepairHead(t *testing.T) {
+	tests := []struct {
+		corrupt func(db *aoadb.MemDatabase, blocks []*types.Block)
+		want    int
+	}{
+		{ // Intact chain
+			corrupt: func(db *aoadb.MemDatabase, blocks []*types.Block) {},
+			want:    9,
+		},
+		{ // Head body lost
+			corrupt: func(db *aoadb.MemDatabase, blocks []*types.Block) {
+				DeleteBody(db, blocks[9].Hash(), 9)
+			},
+			want: 8,
+		},
+		{ // Head and parent canonical mappings lost
+			corrupt: func(db *aoadb.MemDatabase, blocks []*types.Block) {
+				DeleteCanonicalHash(db, 9)
+				DeleteCanonicalHash(db, 8)
+			},
+			want: 7,
+		},
+		{ // Head header lost, rewinding from the header chain head
+			corrupt: func(db *aoadb.MemDatabase, blocks []*types.Block) {
+				DeleteHeader(db, blocks[9].Hash(), 9)
+				WriteHeadHeaderHash(db, blocks[6].Hash())
+				DeleteTd(db, blocks[6].Hash(), 6)
+			},
+			want: 5,
+		},
+	}
+	for i, tt := range tests {
+		db, _ := aoadb.NewMemDatabase()
+
+		blocks := make([]*types.Block, 10)
+		for n := range blocks {
+			header := &types.Header{Number: big.NewInt(int64(n)), Time: big.NewInt(int64(n))}
+			if n > 0 {
+				header.ParentHash = blocks[n-1].Hash()
+			}
+			blocks[n] = types.NewBlock(header, nil, nil)
+
+			WriteBlock(db, blocks[n])
+			WriteTd(db, blocks[n].Hash(), uint64(n), big.NewInt(int64(n+1)))
+			WriteCanonicalHash(db, blocks[n].Hash(), uint64(n))
+		}
+		head := blocks[len(blocks)-1].Hash()
+		WriteHeadBlockHash(db, head)
+		WriteHeadHeaderHash(db, head)
+		WriteHeadFastBlockHash(db, head)
+
+		tt.corrupt(db, blocks)
+		if err := RepairHead(db); err != nil {
+			t.Fatalf("test %d: failed to repair head: %v", i, err)
+		}
+		want := blocks[tt.want].Hash()
+		if have := GetHeadBlockHash(db); have != want {
+			t.Errorf("test %d: head block mismatch: have %x, want %x", i, have, want)
+		}
+		if have := GetHeadFastBlockHash(db); have != want {
+			t.Errorf("test %d: head fast block mismatch: have %x, want %x", i, have, want)
+		}
+	}
+}
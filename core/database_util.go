@@ -46,6 +46,12 @@ var (
 	headHeaderKey = []byte("LastHeader")
 	headBlockKey  = []byte("LastBlock")
 	headFastKey   = []byte("LastFast")
+	headIntentKey = []byte("HeadIntent") // hash of a block committed as the new head, but not yet marked as such
+	txIndexTail   = []byte("TxIndexTail") // number of the oldest block whose transactions are indexed
+	historyTail   = []byte("HistoryTail") // number of the oldest block whose body and receipts are retained
+	fastPivotKey  = []byte("FastPivot")   // number of the pivot block whose state a fast sync is downloading
+	trieSyncKey   = []byte("TrieSync")    // number of state trie entries downloaded by fast sync so far
+	doubleSignKey = []byte("DoubleSigns") // addresses of the delegates with double sign evidence
 
 	// Data item prefixes (use single byte to avoid mixing data walletType, avoid `i`).
 	headerPrefix        = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
@@ -56,12 +62,25 @@ var (
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 	lookupPrefix        = []byte("l") // lookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix     = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	epochUtilPrefix     = []byte("u") // epochUtilPrefix + epoch (uint64 big endian) -> epoch utilization histograms
+	epochProdPrefix     = []byte("p") // epochProdPrefix + epoch (uint64 big endian) -> delegate production counters
+	logIndexPrefix      = []byte("x") // logIndexPrefix + address + section (uint64 big endian) -> lookup entries of transactions emitting logs
+	transferIndexPrefix = []byte("y") // transferIndexPrefix + address + section (uint64 big endian) -> lookup entries of token transfers
+	creationIndexPrefix = []byte("c") // creationIndexPrefix + address -> lookup entry of the transaction creating the contract
+	doubleSignPrefix    = []byte("e") // doubleSignPrefix + address -> double sign evidences of the delegate
 
 	preimagePrefix = "secure-key-"              // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("dacchain-config-") // config prefix for the db
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data walletType).
-	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	BloomBitsIndexPrefix   = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	UtilizationIndexPrefix = []byte("iU") // UtilizationIndexPrefix is the data table of the epoch utilization indexer
+	ProductionIndexPrefix  = []byte("iP") // ProductionIndexPrefix is the data table of the delegate production indexer
+	LogIndexPrefix         = []byte("iL") // LogIndexPrefix is the data table of the log emitter indexer
+	TransferIndexPrefix    = []byte("iT") // TransferIndexPrefix is the data table of the token transfer indexer
+	CreationIndexPrefix    = []byte("iC") // CreationIndexPrefix is the data table of the contract creation indexer
+
+	TraceCachePrefix = []byte("trace-cache-") // TraceCachePrefix is the data table of transaction traces spilled over from memory
 
 	// used by old db, now only used for conversion
 	oldReceiptsPrefix = []byte("receipts-")
@@ -148,6 +167,59 @@ func GetHeadFastBlockHash(db DatabaseReader) common.Hash {
 	return common.BytesToHash(data)
 }
 
+// GetHeadIntent retrieves the hash of the block whose data and state were
+// committed as the new canonical head, but whose head update didn't complete.
+func GetHeadIntent(db DatabaseReader) common.Hash {
+	data, _ := db.Get(headIntentKey)
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// GetTxIndexTail retrieves the number of the oldest block whose transactions
+// are indexed, nil if the tail has never been stored (i.e. everything indexed).
+func GetTxIndexTail(db DatabaseReader) *uint64 {
+	data, _ := db.Get(txIndexTail)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// GetHistoryTail retrieves the number of the oldest block whose body and receipts
+// are retained locally, zero if the tail has never been stored (i.e. everything
+// retained since genesis).
+func GetHistoryTail(db DatabaseReader) uint64 {
+	data, _ := db.Get(historyTail)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// GetFastSyncPivot retrieves the number of the pivot block an interrupted fast
+// sync was downloading the state of, nil if no fast sync is in progress.
+func GetFastSyncPivot(db DatabaseReader) *uint64 {
+	data, _ := db.Get(fastPivotKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// GetTrieSyncProgress retrieves the number of state trie entries downloaded by
+// fast sync so far, to resume the progress reports of an interrupted sync.
+func GetTrieSyncProgress(db DatabaseReader) uint64 {
+	data, _ := db.Get(trieSyncKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
 // GetHeaderRLP retrieves a block header in its raw RLP database encoding, or nil
 // if the header's not found.
 func GetHeaderRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
@@ -234,23 +306,72 @@ func GetBlock(db DatabaseReader, hash common.Hash, number uint64) *types.Block {
 	return types.NewBlockWithHeader(header).WithBody(body.Transactions)
 }
 
+// GetBlockRLP retrieves an entire block in its RLP encoding, assembled straight
+// from the stored header and body encodings without decoding them. If either
+// the header or body could not be retrieved nil is returned.
+func GetBlockRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
+	header := GetHeaderRLP(db, hash, number)
+	if len(header) == 0 {
+		return nil
+	}
+	txs := getBodyTxsRLP(db, hash, number)
+	if txs == nil {
+		return nil
+	}
+	// Block signatures aren't persisted, encode them as empty like GetBlock does
+	data, err := rlp.EncodeToBytes([]interface{}{header, txs, []byte{}})
+	if err != nil {
+		log.Error("Failed to assemble block RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return data
+}
+
+// getBodyTxsRLP retrieves the raw RLP encoding of the transaction list stored in
+// a block body, or nil if the body's not found.
+func getBodyTxsRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
+	data := GetBodyRLP(db, hash, number)
+	if len(data) == 0 {
+		return nil
+	}
+	var fields []rlp.RawValue
+	if err := rlp.DecodeBytes(data, &fields); err != nil || len(fields) == 0 {
+		log.Error("Invalid block body RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return fields[0]
+}
+
 // GetBlockReceipts retrieves the receipts generated by the transactions included
 // in a block given by its hash.
 func GetBlockReceipts(db DatabaseReader, hash common.Hash, number uint64) types.Receipts {
-	data, _ := db.Get(append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash[:]...))
+	data, _ := db.Get(blockReceiptsKey(hash, number))
 	if len(data) == 0 {
 		return nil
 	}
-	storageReceipts := []*types.ReceiptForStorage{}
-	if err := rlp.DecodeBytes(data, &storageReceipts); err != nil {
+	receipts, err := decodeBlockReceipts(data)
+	if err != nil {
 		log.Error("Invalid receipt array RLP", "hash", hash, "err", err)
 		return nil
 	}
+	return receipts
+}
+
+func blockReceiptsKey(hash common.Hash, number uint64) []byte {
+	return append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// decodeBlockReceipts decodes the receipts of a block from their storage encoding.
+func decodeBlockReceipts(data []byte) (types.Receipts, error) {
+	storageReceipts := []*types.ReceiptForStorage{}
+	if err := rlp.DecodeBytes(data, &storageReceipts); err != nil {
+		return nil, err
+	}
 	receipts := make(types.Receipts, len(storageReceipts))
 	for i, receipt := range storageReceipts {
 		receipts[i] = (*types.Receipt)(receipt)
 	}
-	return receipts
+	return receipts, nil
 }
 
 // GetTxLookupEntry retrieves the positional metadata associated with a transaction
@@ -305,6 +426,25 @@ func GetTransaction(db DatabaseReader, hash common.Hash) (*types.Transaction, co
 	return &tx, entry.BlockHash, entry.BlockIndex, entry.Index
 }
 
+// GetTransactionRLP retrieves a specific transaction in its raw RLP encoding, as
+// stored in the body of its block, or nil if it's not found.
+func GetTransactionRLP(db DatabaseReader, hash common.Hash) rlp.RawValue {
+	blockHash, blockNumber, txIndex := GetTxLookupEntry(db, hash)
+	if blockHash == (common.Hash{}) {
+		return nil
+	}
+	txs := getBodyTxsRLP(db, blockHash, blockNumber)
+	if txs == nil {
+		return nil
+	}
+	var list []rlp.RawValue
+	if err := rlp.DecodeBytes(txs, &list); err != nil || len(list) <= int(txIndex) {
+		log.Error("Transaction referenced missing", "number", blockNumber, "hash", blockHash, "index", txIndex)
+		return nil
+	}
+	return list[txIndex]
+}
+
 // GetReceipt retrieves a specific transaction receipt from the database, along with
 // its added positional metadata.
 func GetReceipt(db DatabaseReader, hash common.Hash) (*types.Receipt, common.Hash, uint64, uint64) {
@@ -343,6 +483,85 @@ func GetBloomBits(db DatabaseReader, bit uint, section uint64, head common.Hash)
 	return db.Get(key)
 }
 
+// GetEpochUtilization retrieves the utilization histograms aggregated for the
+// given epoch, nil if the epoch has not been indexed yet.
+func GetEpochUtilization(db DatabaseReader, epoch uint64) *EpochUtilization {
+	data, _ := db.Get(append(epochUtilPrefix, encodeBlockNumber(epoch)...))
+	if len(data) == 0 {
+		return nil
+	}
+	util := new(EpochUtilization)
+	if err := rlp.DecodeBytes(data, util); err != nil {
+		log.Error("Invalid epoch utilization RLP", "epoch", epoch, "err", err)
+		return nil
+	}
+	return util
+}
+
+// GetEpochProduction retrieves the produced and missed block slots of the
+// delegates in the given epoch, nil if the epoch has not been indexed yet.
+func GetEpochProduction(db DatabaseReader, epoch uint64) *EpochProduction {
+	data, _ := db.Get(append(epochProdPrefix, encodeBlockNumber(epoch)...))
+	if len(data) == 0 {
+		return nil
+	}
+	prod := new(EpochProduction)
+	if err := rlp.DecodeBytes(data, prod); err != nil {
+		log.Error("Invalid epoch production RLP", "epoch", epoch, "err", err)
+		return nil
+	}
+	return prod
+}
+
+// addressSectionKey = prefix + address + section (uint64 big endian)
+func addressSectionKey(prefix []byte, address common.Address, section uint64) []byte {
+	key := make([]byte, 0, len(prefix)+common.AddressLength+8)
+	key = append(append(append(key, prefix...), address.Bytes()...), encodeBlockNumber(section)...)
+	return key
+}
+
+// getLookupEntries retrieves a list of transaction lookup entries stored under
+// the given key.
+func getLookupEntries(db DatabaseReader, key []byte) []TxLookupEntry {
+	data, _ := db.Get(key)
+	if len(data) == 0 {
+		return nil
+	}
+	var entries []TxLookupEntry
+	if err := rlp.DecodeBytes(data, &entries); err != nil {
+		log.Error("Invalid lookup entries RLP", "key", common.Bytes2Hex(key), "err", err)
+		return nil
+	}
+	return entries
+}
+
+// GetLogIndex retrieves the lookup entries of the transactions in the given
+// section which emitted logs from the address.
+func GetLogIndex(db DatabaseReader, address common.Address, section uint64) []TxLookupEntry {
+	return getLookupEntries(db, addressSectionKey(logIndexPrefix, address, section))
+}
+
+// GetTransferIndex retrieves the lookup entries of the transactions in the given
+// section which transferred tokens from or to the address.
+func GetTransferIndex(db DatabaseReader, address common.Address, section uint64) []TxLookupEntry {
+	return getLookupEntries(db, addressSectionKey(transferIndexPrefix, address, section))
+}
+
+// GetContractCreation retrieves the lookup entry of the transaction which created
+// the contract at the given address, nil if it's not indexed.
+func GetContractCreation(db DatabaseReader, address common.Address) *TxLookupEntry {
+	data, _ := db.Get(append(creationIndexPrefix, address.Bytes()...))
+	if len(data) == 0 {
+		return nil
+	}
+	entry := new(TxLookupEntry)
+	if err := rlp.DecodeBytes(data, entry); err != nil {
+		log.Error("Invalid contract creation RLP", "address", address, "err", err)
+		return nil
+	}
+	return entry
+}
+
 // WriteCanonicalHash stores the canonical hash for the given block number.
 func WriteCanonicalHash(db aoadb.Putter, hash common.Hash, number uint64) error {
 	key := append(append(headerPrefix, encodeBlockNumber(number)...), numSuffix...)
@@ -368,6 +587,14 @@ func WriteHeadBlockHash(db aoadb.Putter, hash common.Hash) error {
 	return nil
 }
 
+// WriteHeadIntent stores the hash of a block about to become the canonical head.
+func WriteHeadIntent(db aoadb.Putter, hash common.Hash) error {
+	if err := db.Put(headIntentKey, hash.Bytes()); err != nil {
+		log.Crit("Failed to store head intent", "err", err)
+	}
+	return nil
+}
+
 // WriteHeadFastBlockHash stores the fast head block's hash.
 func WriteHeadFastBlockHash(db aoadb.Putter, hash common.Hash) error {
 	if err := db.Put(headFastKey, hash.Bytes()); err != nil {
@@ -376,6 +603,42 @@ func WriteHeadFastBlockHash(db aoadb.Putter, hash common.Hash) error {
 	return nil
 }
 
+// WriteTxIndexTail stores the number of the oldest block whose transactions are
+// indexed.
+func WriteTxIndexTail(db aoadb.Putter, number uint64) error {
+	if err := db.Put(txIndexTail, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store transaction index tail", "err", err)
+	}
+	return nil
+}
+
+// WriteHistoryTail stores the number of the oldest block whose body and receipts
+// are retained, to be updated whenever older ones are pruned or offloaded.
+func WriteHistoryTail(db aoadb.Putter, number uint64) error {
+	if err := db.Put(historyTail, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store history tail", "err", err)
+	}
+	return nil
+}
+
+// WriteFastSyncPivot stores the number of the pivot block a fast sync downloads
+// the state of.
+func WriteFastSyncPivot(db aoadb.Putter, number uint64) error {
+	if err := db.Put(fastPivotKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store fast sync pivot", "err", err)
+	}
+	return nil
+}
+
+// WriteTrieSyncProgress stores the number of state trie entries downloaded by
+// fast sync so far.
+func WriteTrieSyncProgress(db aoadb.Putter, count uint64) error {
+	if err := db.Put(trieSyncKey, encodeBlockNumber(count)); err != nil {
+		log.Crit("Failed to store trie sync progress", "err", err)
+	}
+	return nil
+}
+
 // WriteHeader serializes a block header into the database.
 func WriteHeader(db aoadb.Putter, header *types.Header) error {
 	data, err := rlp.EncodeToBytes(header)
@@ -454,7 +717,7 @@ func WriteBlockReceipts(db aoadb.Putter, hash common.Hash, number uint64, receip
 		return err
 	}
 	// Store the flattened receipt slice
-	key := append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+	key := blockReceiptsKey(hash, number)
 	if err := db.Put(key, bytes); err != nil {
 		log.Crit("Failed to store block receipts", "err", err)
 	}
@@ -495,6 +758,124 @@ func WriteBloomBits(db aoadb.Putter, bit uint, section uint64, head common.Hash,
 	}
 }
 
+// WriteEpochUtilization stores the utilization histograms of an epoch.
+func WriteEpochUtilization(db aoadb.Putter, util *EpochUtilization) error {
+	data, err := rlp.EncodeToBytes(util)
+	if err != nil {
+		return err
+	}
+	if err := db.Put(append(epochUtilPrefix, encodeBlockNumber(util.Epoch)...), data); err != nil {
+		log.Crit("Failed to store epoch utilization", "err", err)
+	}
+	return nil
+}
+
+// WriteEpochProduction stores the delegate production counters of an epoch.
+func WriteEpochProduction(db aoadb.Putter, prod *EpochProduction) error {
+	data, err := rlp.EncodeToBytes(prod)
+	if err != nil {
+		return err
+	}
+	if err := db.Put(append(epochProdPrefix, encodeBlockNumber(prod.Epoch)...), data); err != nil {
+		log.Crit("Failed to store epoch production", "err", err)
+	}
+	return nil
+}
+
+// WriteLogIndex stores the lookup entries of the transactions in the given
+// section which emitted logs from the address.
+func WriteLogIndex(db aoadb.Putter, address common.Address, section uint64, entries []TxLookupEntry) error {
+	data, err := rlp.EncodeToBytes(entries)
+	if err != nil {
+		return err
+	}
+	if err := db.Put(addressSectionKey(logIndexPrefix, address, section), data); err != nil {
+		log.Crit("Failed to store log index", "err", err)
+	}
+	return nil
+}
+
+// WriteTransferIndex stores the lookup entries of the transactions in the given
+// section which transferred tokens from or to the address.
+func WriteTransferIndex(db aoadb.Putter, address common.Address, section uint64, entries []TxLookupEntry) error {
+	data, err := rlp.EncodeToBytes(entries)
+	if err != nil {
+		return err
+	}
+	if err := db.Put(addressSectionKey(transferIndexPrefix, address, section), data); err != nil {
+		log.Crit("Failed to store transfer index", "err", err)
+	}
+	return nil
+}
+
+// WriteContractCreation stores the lookup entry of the transaction which created
+// the contract at the given address.
+func WriteContractCreation(db aoadb.Putter, address common.Address, entry TxLookupEntry) error {
+	data, err := rlp.EncodeToBytes(entry)
+	if err != nil {
+		return err
+	}
+	if err := db.Put(append(creationIndexPrefix, address.Bytes()...), data); err != nil {
+		log.Crit("Failed to store contract creation", "err", err)
+	}
+	return nil
+}
+
+// GetDoubleSigners retrieves the addresses of the delegates with double sign
+// evidence, in order of detection.
+func GetDoubleSigners(db DatabaseReader) []common.Address {
+	data, _ := db.Get(doubleSignKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var signers []common.Address
+	if err := rlp.DecodeBytes(data, &signers); err != nil {
+		log.Error("Invalid double signers RLP", "err", err)
+		return nil
+	}
+	return signers
+}
+
+// GetDoubleSignEvidences retrieves the double sign evidences of the given
+// delegate, in order of detection.
+func GetDoubleSignEvidences(db DatabaseReader, delegate common.Address) []*DoubleSignEvidence {
+	data, _ := db.Get(append(doubleSignPrefix, delegate.Bytes()...))
+	if len(data) == 0 {
+		return nil
+	}
+	var evidences []*DoubleSignEvidence
+	if err := rlp.DecodeBytes(data, &evidences); err != nil {
+		log.Error("Invalid double sign evidences RLP", "delegate", delegate, "err", err)
+		return nil
+	}
+	return evidences
+}
+
+// WriteDoubleSigners stores the addresses of the delegates with double sign
+// evidence.
+func WriteDoubleSigners(db aoadb.Putter, signers []common.Address) error {
+	data, err := rlp.EncodeToBytes(signers)
+	if err != nil {
+		return err
+	}
+	if err := db.Put(doubleSignKey, data); err != nil {
+		log.Crit("Failed to store double signers", "err", err)
+	}
+	return nil
+}
+
+// WriteDoubleSignEvidences stores the double sign evidences of the given delegate.
+func WriteDoubleSignEvidences(db aoadb.Putter, delegate common.Address, evidences []*DoubleSignEvidence) error {
+	data, err := rlp.EncodeToBytes(evidences)
+	if err != nil {
+		return err
+	}
+	if err := db.Put(append(doubleSignPrefix, delegate.Bytes()...), data); err != nil {
+		log.Crit("Failed to store double sign evidences", "err", err)
+	}
+	return nil
+}
+
 // WriteDelegateBodyRLP writes a serialized body of delegate data into the database
 func WriteDelegateBodyRLP(db aoadb.Putter, rlp rlp.RawValue) error {
 	key := []byte(datagateDataPrefix)
@@ -517,6 +898,16 @@ func DeleteCanonicalHash(db DatabaseDeleter, number uint64) {
 	db.Delete(append(append(headerPrefix, encodeBlockNumber(number)...), numSuffix...))
 }
 
+// DeleteHeadIntent removes the pending head intent once the head is updated.
+func DeleteHeadIntent(db DatabaseDeleter) {
+	db.Delete(headIntentKey)
+}
+
+// DeleteFastSyncPivot removes the fast sync pivot once its state is complete.
+func DeleteFastSyncPivot(db DatabaseDeleter) {
+	db.Delete(fastPivotKey)
+}
+
 // DeleteHeader removes all block header data associated with a hash.
 func DeleteHeader(db DatabaseDeleter, hash common.Hash, number uint64) {
 	db.Delete(append(blockHashPrefix, hash.Bytes()...))
@@ -543,7 +934,7 @@ func DeleteBlock(db DatabaseDeleter, hash common.Hash, number uint64) {
 
 // DeleteBlockReceipts removes all receipt data associated with a block hash.
 func DeleteBlockReceipts(db DatabaseDeleter, hash common.Hash, number uint64) {
-	db.Delete(append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+	db.Delete(blockReceiptsKey(hash, number))
 }
 
 // DeleteTxLookupEntry removes all transaction data associated with a hash.
@@ -556,6 +947,11 @@ func PreimageTable(db aoadb.Database) aoadb.Database {
 	return aoadb.NewTable(db, preimagePrefix)
 }
 
+// PreimageKey returns the database key of the preimage of a hash.
+func PreimageKey(hash common.Hash) []byte {
+	return append([]byte(preimagePrefix), hash.Bytes()...)
+}
+
 // WritePreimages writes the provided set of preimages to the database. `number` is the
 // current block number, and is used for debug messages only.
 func WritePreimages(db aoadb.Database, number uint64, preimages map[common.Hash][]byte) error {
@@ -0,0 +1,314 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"math/rand"
+	"os"
+	"testing"
+	"testing/quick"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/rlp"
+)
+
+// quickConfig is the configuration of the randomized round-trip tests.
+var quickConfig = &quick.Config{MaxCount: 64}
+
+// quickKey signs the randomly generated transactions.
+var quickKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+
+// quickGoldenHash is the hash of the encodings of all objects generated from seed
+// one. It changes whenever the storage encoding of any of them changes, which
+// breaks the compatibility with existing databases. Only update it along with a
+// database schema upgrade (or when deliberately changing the generators).
+var quickGoldenHash = common.HexToHash("0xb5c76968cdafd9e4144e5450cc8151bf6086b82ad9603cb7b4c8017437be6398")
+
+// quickBackends runs fn against every database backend.
+func quickBackends(t *testing.T, fn func(t *testing.T, db aoadb.Database)) {
+	memdb, _ := aoadb.NewMemDatabase()
+	t.Run("memory", func(t *testing.T) { fn(t, memdb) })
+
+	dir, err := ioutil.TempDir("", "quick-")
+	if err != nil {
+		t.Fatalf("failed to create temporary datadir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ldb, err := aoadb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to create leveldb database: %v", err)
+	}
+	defer ldb.Close()
+	t.Run("leveldb", func(t *testing.T) { fn(t, ldb) })
+}
+
+func randomBytes(r *rand.Rand, max int) []byte {
+	b := make([]byte, r.Intn(max+1))
+	r.Read(b)
+	return b
+}
+
+func randomHash(r *rand.Rand) (hash common.Hash) {
+	r.Read(hash[:])
+	return hash
+}
+
+func randomAddress(r *rand.Rand) (addr common.Address) {
+	r.Read(addr[:])
+	return addr
+}
+
+func randomBig(r *rand.Rand) *big.Int {
+	return new(big.Int).SetBytes(randomBytes(r, 32))
+}
+
+func randomHeader(r *rand.Rand) *types.Header {
+	header := &types.Header{
+		ParentHash:         randomHash(r),
+		Coinbase:           randomAddress(r),
+		Root:               randomHash(r),
+		TxHash:             randomHash(r),
+		ReceiptHash:        randomHash(r),
+		Number:             new(big.Int).SetUint64(uint64(r.Int63())),
+		GasLimit:           r.Uint64(),
+		GasUsed:            r.Uint64(),
+		Time:               randomBig(r),
+		Extra:              randomBytes(r, 64),
+		AgentName:          randomBytes(r, 32),
+		DelegateRoot:       randomHash(r),
+		ShuffleHash:        randomHash(r),
+		ShuffleBlockNumber: randomBig(r),
+	}
+	r.Read(header.Bloom[:])
+	return header
+}
+
+func randomBody(r *rand.Rand) *types.Body {
+	signer := types.NewAuroraSigner(big.NewInt(1))
+
+	body := new(types.Body)
+	for i := r.Intn(8); i > 0; i-- {
+		var tx *types.Transaction
+		if r.Intn(4) == 0 {
+			tx = types.NewContractCreation(r.Uint64(), randomBig(r), r.Uint64(), randomBig(r), randomBytes(r, 128), "", nil)
+		} else {
+			tx = types.NewTransaction(r.Uint64(), randomAddress(r), randomBig(r), r.Uint64(), randomBig(r), randomBytes(r, 128), types.ActionTrans, nil, "")
+		}
+		tx, _ = types.SignTx(tx, signer, quickKey)
+		body.Transactions = append(body.Transactions, tx)
+	}
+	return body
+}
+
+func randomReceipts(r *rand.Rand) types.Receipts {
+	var receipts types.Receipts
+	for i := r.Intn(8); i > 0; i-- {
+		receipt := &types.Receipt{
+			CumulativeGasUsed: r.Uint64(),
+			TxHash:            randomHash(r),
+			ContractAddress:   randomAddress(r),
+			GasUsed:           r.Uint64(),
+			Action:            uint64(r.Intn(8)),
+		}
+		if r.Intn(2) == 0 {
+			receipt.PostState = randomHash(r).Bytes()
+		} else {
+			receipt.Status = uint(r.Intn(2))
+		}
+		r.Read(receipt.Bloom[:])
+		for j := r.Intn(4); j > 0; j-- {
+			log := &types.Log{Address: randomAddress(r), Data: randomBytes(r, 64)}
+			for k := r.Intn(5); k > 0; k-- {
+				log.Topics = append(log.Topics, randomHash(r))
+			}
+			receipt.Logs = append(receipt.Logs, log)
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts
+}
+
+func randomDelegateData(r *rand.Rand) *types.StoreData {
+	data := &types.StoreData{LastBlockHeight: r.Uint64()}
+	for i := r.Intn(8); i > 0; i-- {
+		data.Votes = append(data.Votes, types.Candidate{
+			Address:      randomAddress(r).Hex(),
+			Vote:         r.Uint64(),
+			Nickname:     string(randomBytes(r, 16)),
+			RegisterTime: r.Uint64(),
+		})
+	}
+	return data
+}
+
+// encodeReceipts returns the storage encoding of a receipt list.
+func encodeReceipts(receipts types.Receipts) []byte {
+	storage := make([]*types.ReceiptForStorage, len(receipts))
+	for i, receipt := range receipts {
+		storage[i] = (*types.ReceiptForStorage)(receipt)
+	}
+	blob, _ := rlp.EncodeToBytes(storage)
+	return blob
+}
+
+// Tests that random headers survive a database round-trip byte for byte.
+func TestQuickHeaderStorage(t *testing.T) {
+	quickBackends(t, func(t *testing.T, db aoadb.Database) {
+		check := func(seed int64) bool {
+			header := randomHeader(rand.New(rand.NewSource(seed)))
+			want, _ := rlp.EncodeToBytes(header)
+
+			if err := WriteHeader(db, header); err != nil {
+				t.Errorf("seed %d: failed to write header: %v", seed, err)
+				return false
+			}
+			if have := GetHeaderRLP(db, header.Hash(), header.Number.Uint64()); !bytes.Equal(have, want) {
+				t.Errorf("seed %d: stored header mismatch: have %x, want %x", seed, have, want)
+				return false
+			}
+			have, _ := rlp.EncodeToBytes(GetHeader(db, header.Hash(), header.Number.Uint64()))
+			if !bytes.Equal(have, want) {
+				t.Errorf("seed %d: decoded header mismatch: have %x, want %x", seed, have, want)
+				return false
+			}
+			return true
+		}
+		if err := quick.Check(check, quickConfig); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// Tests that random block bodies survive a database round-trip byte for byte.
+func TestQuickBodyStorage(t *testing.T) {
+	quickBackends(t, func(t *testing.T, db aoadb.Database) {
+		check := func(seed int64, number uint64) bool {
+			r := rand.New(rand.NewSource(seed))
+			body, hash := randomBody(r), randomHash(r)
+			want, _ := rlp.EncodeToBytes(body)
+
+			if err := WriteBody(db, hash, number, body); err != nil {
+				t.Errorf("seed %d: failed to write body: %v", seed, err)
+				return false
+			}
+			if have := GetBodyRLP(db, hash, number); !bytes.Equal(have, want) {
+				t.Errorf("seed %d: stored body mismatch: have %x, want %x", seed, have, want)
+				return false
+			}
+			have, _ := rlp.EncodeToBytes(GetBody(db, hash, number))
+			if !bytes.Equal(have, want) {
+				t.Errorf("seed %d: decoded body mismatch: have %x, want %x", seed, have, want)
+				return false
+			}
+			return true
+		}
+		if err := quick.Check(check, quickConfig); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// Tests that random receipts survive a database round-trip byte for byte.
+func TestQuickReceiptStorage(t *testing.T) {
+	quickBackends(t, func(t *testing.T, db aoadb.Database) {
+		check := func(seed int64, number uint64) bool {
+			r := rand.New(rand.NewSource(seed))
+			receipts, hash := randomReceipts(r), randomHash(r)
+			want := encodeReceipts(receipts)
+
+			if err := WriteBlockReceipts(db, hash, number, receipts); err != nil {
+				t.Errorf("seed %d: failed to write receipts: %v", seed, err)
+				return false
+			}
+			if have := encodeReceipts(GetBlockReceipts(db, hash, number)); !bytes.Equal(have, want) {
+				t.Errorf("seed %d: decoded receipts mismatch: have %x, want %x", seed, have, want)
+				return false
+			}
+			return true
+		}
+		if err := quick.Check(check, quickConfig); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// Tests that random delegate data survives a database round-trip byte for byte.
+func TestQuickDelegateStorage(t *testing.T) {
+	quickBackends(t, func(t *testing.T, db aoadb.Database) {
+		check := func(seed int64) bool {
+			r := rand.New(rand.NewSource(seed))
+			want, _ := rlp.EncodeToBytes(randomDelegateData(r))
+
+			if err := WriteDelegateBodyRLP(db, want); err != nil {
+				t.Errorf("seed %d: failed to write delegate data: %v", seed, err)
+				return false
+			}
+			blob, _ := db.Get([]byte(datagateDataPrefix))
+			data := new(types.StoreData)
+			if err := rlp.DecodeBytes(blob, data); err != nil {
+				t.Errorf("seed %d: failed to decode delegate data: %v", seed, err)
+				return false
+			}
+			if have, _ := rlp.EncodeToBytes(data); !bytes.Equal(have, want) {
+				t.Errorf("seed %d: decoded delegate data mismatch: have %x, want %x", seed, have, want)
+				return false
+			}
+			shuffle := types.ShuffleDelegateData{BlockNumber: *randomBig(r), ShuffleTime: *randomBig(r)}
+			want, _ = rlp.EncodeToBytes(shuffle)
+
+			if err := WriteDelegateShuffleBlockHeightRLP(db, want); err != nil {
+				t.Errorf("seed %d: failed to write shuffle data: %v", seed, err)
+				return false
+			}
+			blob, _ = db.Get([]byte(delegateStorePrefix))
+			if err := rlp.DecodeBytes(blob, &shuffle); err != nil {
+				t.Errorf("seed %d: failed to decode shuffle data: %v", seed, err)
+				return false
+			}
+			if have, _ := rlp.EncodeToBytes(shuffle); !bytes.Equal(have, want) {
+				t.Errorf("seed %d: decoded shuffle data mismatch: have %x, want %x", seed, have, want)
+				return false
+			}
+			return true
+		}
+		if err := quick.Check(check, quickConfig); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// Tests that the storage encodings of the generated objects didn't change, which
+// would render existing databases unreadable.
+func TestQuickEncodingStability(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	header, _ := rlp.EncodeToBytes(randomHeader(r))
+	body, _ := rlp.EncodeToBytes(randomBody(r))
+	delegates, _ := rlp.EncodeToBytes(randomDelegateData(r))
+	receipts := encodeReceipts(randomReceipts(r))
+
+	if hash := crypto.Keccak256Hash(header, body, receipts, delegates); hash != quickGoldenHash {
+		t.Errorf("storage encoding changed: have %x, want %x", hash, quickGoldenHash)
+	}
+}
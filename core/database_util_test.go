@@ -24,7 +24,7 @@ import (
 	"github.com/Aurorachain-io/go-aoa/common"
 	"github.com/Aurorachain-io/go-aoa/core/types"
 	"github.com/Aurorachain-io/go-aoa/crypto/sha3"
-	"github.com/Aurorachain-io/go-aoa/emdb"
+	"github.com/Aurorachain-io/go-aoa/aoadb"
 	"github.com/Aurorachain-io/go-aoa/rlp"
 	"time"
 )
@@ -46,7 +46,7 @@ type candidateData struct {
 
 // Tests block header storage and retrieval operations.
 func TestHeaderStorage(t *testing.T) {
-	db, _ := emdb.NewMemDatabase()
+	db, _ := aoadb.NewMemDatabase()
 
 	// Create a test header to move around the database and make sure it's really new
 	header := &types.Header{Number: big.NewInt(42), Extra: []byte("test header")}
@@ -81,7 +81,7 @@ func TestHeaderStorage(t *testing.T) {
 
 // Tests block body storage and retrieval operations.
 func TestBodyStorage(t *testing.T) {
-	db, _ := emdb.NewMemDatabase()
+	db, _ := aoadb.NewMemDatabase()
 
 	// Create a test body to move around the database and make sure it's really new
 	body := &types.Body{}
@@ -121,7 +121,7 @@ func TestBodyStorage(t *testing.T) {
 
 // Tests block storage and retrieval operations.
 func TestBlockStorage(t *testing.T) {
-	db, _ := emdb.NewMemDatabase()
+	db, _ := aoadb.NewMemDatabase()
 
 	// Create a test block to move around the database and make sure it's really new
 	block := types.NewBlockWithHeader(&types.Header{
@@ -172,7 +172,7 @@ func TestBlockStorage(t *testing.T) {
 
 // Tests that partial block contents don't get reassembled into full blocks.
 func TestPartialBlockStorage(t *testing.T) {
-	db, _ := emdb.NewMemDatabase()
+	db, _ := aoadb.NewMemDatabase()
 	block := types.NewBlockWithHeader(&types.Header{
 		Extra:       []byte("test block"),
 		TxHash:      types.EmptyRootHash,
@@ -212,7 +212,7 @@ func TestPartialBlockStorage(t *testing.T) {
 
 // Tests block total difficulty storage and retrieval operations.
 func TestTdStorage(t *testing.T) {
-	db, _ := emdb.NewMemDatabase()
+	db, _ := aoadb.NewMemDatabase()
 
 	// Create a test TD to move around the database and make sure it's really new
 	hash, td := common.Hash{}, big.NewInt(314)
@@ -237,7 +237,7 @@ func TestTdStorage(t *testing.T) {
 
 // Tests that canonical numbers can be mapped to hashes and retrieved.
 func TestCanonicalMappingStorage(t *testing.T) {
-	db, _ := emdb.NewMemDatabase()
+	db, _ := aoadb.NewMemDatabase()
 
 	// Create a test canonical number and assinged hash to move around
 	hash, number := common.Hash{0: 0xff}, uint64(314)
@@ -262,7 +262,7 @@ func TestCanonicalMappingStorage(t *testing.T) {
 
 // Tests that head headers and head blocks can be assigned, individually.
 func TestHeadStorage(t *testing.T) {
-	db, _ := emdb.NewMemDatabase()
+	db, _ := aoadb.NewMemDatabase()
 
 	blockHead := types.NewBlockWithHeader(&types.Header{Extra: []byte("test block header")})
 	blockFull := types.NewBlockWithHeader(&types.Header{Extra: []byte("test block full")})
@@ -300,9 +300,43 @@ func TestHeadStorage(t *testing.T) {
 	}
 }
 
+// Tests that blocks and transactions can be retrieved in their raw encodings
+// without decoding them.
+func TestRawStorage(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+
+	tx1 := types.NewTransaction(1, common.BytesToAddress([]byte{0x11}), big.NewInt(111), 1111, big.NewInt(11111), []byte{0x11, 0x11, 0x11}, types.ActionTrans, nil, "")
+	tx2 := types.NewTransaction(2, common.BytesToAddress([]byte{0x22}), big.NewInt(222), 2222, big.NewInt(22222), []byte{0x22, 0x22, 0x22}, types.ActionTrans, nil, "")
+	txs := []*types.Transaction{tx1, tx2}
+
+	block := types.NewBlock(&types.Header{Number: big.NewInt(314)}, txs, nil)
+	if entry := GetBlockRLP(db, block.Hash(), block.NumberU64()); entry != nil {
+		t.Fatalf("non existent block returned: %x", entry)
+	}
+	if err := WriteBlock(db, block); err != nil {
+		t.Fatalf("failed to write block contents: %v", err)
+	}
+	if err := WriteTxLookupEntries(db, block); err != nil {
+		t.Fatalf("failed to write transactions: %v", err)
+	}
+	want, _ := rlp.EncodeToBytes(block)
+	if have := GetBlockRLP(db, block.Hash(), block.NumberU64()); !bytes.Equal(have, want) {
+		t.Fatalf("block RLP mismatch: have %x, want %x", have, want)
+	}
+	for i, tx := range txs {
+		want, _ := rlp.EncodeToBytes(tx)
+		if have := GetTransactionRLP(db, tx.Hash()); !bytes.Equal(have, want) {
+			t.Fatalf("tx #%d [%x]: transaction RLP mismatch: have %x, want %x", i, tx.Hash(), have, want)
+		}
+	}
+	if entry := GetTransactionRLP(db, common.Hash{0x01}); entry != nil {
+		t.Fatalf("non existent transaction returned: %x", entry)
+	}
+}
+
 // Tests that positional lookup metadata can be stored and retrieved.
 func TestLookupStorage(t *testing.T) {
-	//db, _ := emdb.NewMemDatabase()
+	//db, _ := aoadb.NewMemDatabase()
 
 	//tx1 := walletType.NewTransaction(1, common.BytesToAddress([]byte{0x11}), big.NewInt(111), 1111, big.NewInt(11111), []byte{0x11, 0x11, 0x11})
 	//tx2 := walletType.NewTransaction(2, common.BytesToAddress([]byte{0x22}), big.NewInt(222), 2222, big.NewInt(22222), []byte{0x22, 0x22, 0x22})
@@ -347,7 +381,7 @@ func TestLookupStorage(t *testing.T) {
 
 // Tests that receipts associated with a single block can be stored and retrieved.
 func TestBlockReceiptStorage(t *testing.T) {
-	db, _ := emdb.NewMemDatabase()
+	db, _ := aoadb.NewMemDatabase()
 
 	receipt1 := &types.Receipt{
 		Status:            types.ReceiptStatusFailed,
@@ -403,7 +437,7 @@ func TestBlockReceiptStorage(t *testing.T) {
 
 func TestWriteDelegateBodyRLP(t *testing.T) {
 
-	db, _ := emdb.NewLDBDatabase("234", 0, 0)
+	db, _ := aoadb.NewLDBDatabase("234", 0, 0)
 	can := []Candidate{
 		{"0x70715a2a44255ddce2779d60ba95968b770fc759", uint64(2), "node1", nil},
 		{"0xfd48a829397a16b3bc6c319a06a47cd2ce6b3f58", uint64(3), "node2", nil},
@@ -428,7 +462,7 @@ func TestWriteDelegateBodyRLP(t *testing.T) {
 }
 
 func TestWriteDelegateShuffleBlockHeightRLP(t *testing.T) {
-	db, _ := emdb.NewLDBDatabase("456", 0, 0)
+	db, _ := aoadb.NewLDBDatabase("456", 0, 0)
 
 	shuffleDelegateData := types.ShuffleDelegateData{BlockNumber: *big.NewInt(2), ShuffleTime: *big.NewInt(time.Now().Unix())}
 	data, err := rlp.EncodeToBytes(shuffleDelegateData)
@@ -0,0 +1,125 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus/delegatestate"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/core/vm"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// DelegateInfoAddress is the address of the pre-compiled contract letting
+// contracts query the delegates, active since the ares fork. It implements
+//
+//	delegates() returns (address[])  // Delegates of the producing round, in producing order
+//	votes(address) returns (uint256) // Votes of a candidate, zero for others
+//	round() returns (uint256)        // Index of the producing round
+//
+// answering from the shuffled round of the block and the delegate state of its
+// parent.
+var DelegateInfoAddress = common.BytesToAddress([]byte{1, 0})
+
+var (
+	delegatesMethod = crypto.Keccak256([]byte("delegates()"))[:4]
+	votesMethod     = crypto.Keccak256([]byte("votes(address)"))[:4]
+	roundMethod     = crypto.Keccak256([]byte("round()"))[:4]
+
+	// errNoDelegateSnapshot is returned if the delegates are queried on a chain
+	// unable to open the delegate states of past blocks.
+	errNoDelegateSnapshot = errors.New("delegate snapshot unavailable")
+
+	// errDelegateInfoInput is returned if the delegate information contract is
+	// called with an unknown method or malformed arguments.
+	errDelegateInfoInput = errors.New("invalid delegate info call")
+)
+
+func init() {
+	vm.RegisterPrecompiles(func(rules params.Rules) bool { return rules.IsAres }, map[common.Address]vm.PrecompiledContract{
+		DelegateInfoAddress: &delegateInfo{},
+	})
+}
+
+// delegateSnapshotReader is implemented by chains able to open the delegate
+// state of past blocks, which the delegate snapshot of the EVM is taken from.
+type delegateSnapshotReader interface {
+	DelegateStateAt(root common.Hash) (*delegatestate.DelegateDB, error)
+	GetHeaderByNumber(number uint64) *types.Header
+}
+
+// delegateInfo implements the delegate information contract as a stateful
+// native contract.
+type delegateInfo struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *delegateInfo) RequiredGas(input []byte) uint64 {
+	return params.DelegateInfoGas
+}
+
+// Run fails, the contract can only run with access to the EVM.
+func (c *delegateInfo) Run(input []byte) ([]byte, error) {
+	return nil, errNoDelegateSnapshot
+}
+
+// RunStateful answers a query of the delegates from the snapshot of the EVM.
+func (c *delegateInfo) RunStateful(evm *vm.EVM, caller common.Address, input []byte) ([]byte, error) {
+	if len(input) < 4 {
+		return nil, errDelegateInfoInput
+	}
+	if evm.Delegates == nil {
+		return nil, errNoDelegateSnapshot
+	}
+	snapshot, err := evm.Delegates()
+	if err != nil {
+		return nil, err
+	}
+	switch method, args := input[:4], input[4:]; {
+	case bytes.Equal(method, delegatesMethod):
+		producers := snapshot.Producers
+
+		// Encode the dynamic array as its offset, length and elements
+		output := make([]byte, 0, 64+32*len(producers))
+		output = append(output, common.LeftPadBytes(big.NewInt(32).Bytes(), 32)...)
+		output = append(output, common.LeftPadBytes(big.NewInt(int64(len(producers))).Bytes(), 32)...)
+		for _, producer := range producers {
+			output = append(output, common.LeftPadBytes(producer.Bytes(), 32)...)
+		}
+		return output, nil
+
+	case bytes.Equal(method, votesMethod):
+		if len(args) != 32 {
+			return nil, errDelegateInfoInput
+		}
+		candidate := common.BytesToAddress(args)
+		for _, delegate := range snapshot.Candidates {
+			if common.HexToAddress(delegate.Address) == candidate {
+				return common.LeftPadBytes(new(big.Int).SetUint64(delegate.Vote).Bytes(), 32), nil
+			}
+		}
+		return make([]byte, 32), nil
+
+	case bytes.Equal(method, roundMethod):
+		return common.LeftPadBytes(new(big.Int).SetUint64(snapshot.Round).Bytes(), 32), nil
+	}
+	return nil, errDelegateInfoInput
+}
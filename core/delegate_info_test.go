@@ -0,0 +1,188 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus"
+	"github.com/Aurorachain-io/go-aoa/consensus/delegatestate"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/core/vm"
+	"github.com/Aurorachain-io/go-aoa/params"
+	"github.com/Aurorachain-io/go-aoa/util"
+)
+
+// Tests that the delegate information contract answers from the snapshot of the
+// EVM and is only active since the ares fork.
+func TestDelegateInfo(t *testing.T) {
+	config := &params.ChainConfig{MaxElectDelegate: big.NewInt(2), BlockInterval: big.NewInt(10), AresBlock: big.NewInt(1)}
+	if _, ok := vm.ActivePrecompiles(config.Rules(big.NewInt(0)))[DelegateInfoAddress]; ok {
+		t.Fatalf("delegate info contract active before ares")
+	}
+	contract, ok := vm.ActivePrecompiles(config.Rules(big.NewInt(1)))[DelegateInfoAddress].(vm.StatefulPrecompiledContract)
+	if !ok {
+		t.Fatalf("delegate info contract not active or not stateful since ares")
+	}
+	snapshot := &vm.DelegateSnapshot{
+		Candidates: []types.Candidate{
+			{Address: common.Address{1}.Hex(), Vote: 300},
+			{Address: common.Address{2}.Hex(), Vote: 200},
+			{Address: common.Address{3}.Hex(), Vote: 100},
+		},
+		Producers: []common.Address{{3}, {1}},
+		Round:     7,
+	}
+	ctx := vm.Context{
+		BlockNumber: big.NewInt(1),
+		Delegates:   func() (*vm.DelegateSnapshot, error) { return snapshot, nil },
+	}
+	evm := vm.NewEVM(ctx, nil, config, vm.Config{})
+
+	word := func(n uint64) []byte { return common.LeftPadBytes(new(big.Int).SetUint64(n).Bytes(), 32) }
+	concat := func(words ...[]byte) []byte { return bytes.Join(words, nil) }
+
+	tests := []struct {
+		input  []byte
+		output []byte
+	}{
+		{delegatesMethod, concat(word(32), word(2), common.LeftPadBytes(common.Address{3}.Bytes(), 32), common.LeftPadBytes(common.Address{1}.Bytes(), 32))},
+		{concat(votesMethod, common.LeftPadBytes(common.Address{3}.Bytes(), 32)), word(100)},
+		{concat(votesMethod, common.LeftPadBytes(common.Address{4}.Bytes(), 32)), word(0)},
+		{roundMethod, word(7)},
+	}
+	for i, tt := range tests {
+		output, err := contract.RunStateful(evm, common.Address{}, tt.input)
+		if err != nil {
+			t.Errorf("test %d: call failed: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(output, tt.output) {
+			t.Errorf("test %d: output mismatch: have %x, want %x", i, output, tt.output)
+		}
+	}
+	for i, input := range [][]byte{nil, {1, 2, 3, 4}, votesMethod} {
+		if _, err := contract.RunStateful(evm, common.Address{}, input); err != errDelegateInfoInput {
+			t.Errorf("invalid call %d: error mismatch: have %v, want %v", i, err, errDelegateInfoInput)
+		}
+	}
+}
+
+// testDelegateChain is a chain stub serving headers and the delegate states they
+// commit to.
+type testDelegateChain struct {
+	config  *params.ChainConfig
+	db      aoadb.Database
+	headers map[common.Hash]*types.Header
+	genesis *types.Header
+}
+
+func (c *testDelegateChain) Engine() consensus.Engine { return nil }
+
+func (c *testDelegateChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.headers[hash]
+}
+
+func (c *testDelegateChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number == 0 {
+		return c.genesis
+	}
+	return nil
+}
+
+func (c *testDelegateChain) GetDelegatePoll() (*map[common.Address]types.Candidate, error) {
+	return nil, nil
+}
+
+func (c *testDelegateChain) GetGenesisConfig() *params.ChainConfig { return c.config }
+
+func (c *testDelegateChain) DelegateStateAt(root common.Hash) (*delegatestate.DelegateDB, error) {
+	return delegatestate.New(root, delegatestate.NewDatabase(c.db))
+}
+
+// Tests that the delegates snapshot lists the producers of the round shuffled
+// from the block recorded in the header, not the top candidates of the parent.
+func TestDelegatesFn(t *testing.T) {
+	config := &params.ChainConfig{MaxElectDelegate: big.NewInt(3), BlockInterval: big.NewInt(10)}
+	chain := &testDelegateChain{config: config, headers: make(map[common.Hash]*types.Header)}
+	chain.db, _ = aoadb.NewMemDatabase()
+
+	commit := func(votes map[common.Address]int64) common.Hash {
+		delegates, _ := delegatestate.New(common.Hash{}, delegatestate.NewDatabase(chain.db))
+		for addr, vote := range votes {
+			delegates.GetOrNewStateObject(addr, addr.Hex(), 0).AddVote(big.NewInt(vote))
+		}
+		root, err := delegates.CommitTo(chain.db, false)
+		if err != nil {
+			t.Fatalf("failed to commit delegate state: %v", err)
+		}
+		return root
+	}
+	chain.genesis = &types.Header{Number: big.NewInt(0), Time: big.NewInt(0)}
+	shuffled := &types.Header{
+		Number:       big.NewInt(1),
+		Time:         big.NewInt(10),
+		ParentHash:   chain.genesis.Hash(),
+		DelegateRoot: commit(map[common.Address]int64{{1}: 300, {2}: 200, {3}: 100, {4}: 50}),
+	}
+	// The parent ranks another delegate on top, elected in a later round only
+	parent := &types.Header{
+		Number:       big.NewInt(2),
+		Time:         big.NewInt(20),
+		ParentHash:   shuffled.Hash(),
+		DelegateRoot: commit(map[common.Address]int64{{1}: 300, {2}: 200, {3}: 100, {5}: 1000}),
+	}
+	for _, header := range []*types.Header{chain.genesis, shuffled, parent} {
+		chain.headers[header.Hash()] = header
+	}
+	header := &types.Header{Number: big.NewInt(3), Time: big.NewInt(40), ParentHash: parent.Hash(), ShuffleBlockNumber: big.NewInt(1)}
+
+	snapshot, err := DelegatesFn(header, chain)()
+	if err != nil {
+		t.Fatalf("failed to snapshot delegates: %v", err)
+	}
+	if snapshot.Round != 1 {
+		t.Errorf("round mismatch: have %d, want 1", snapshot.Round)
+	}
+	if len(snapshot.Candidates) != 4 || snapshot.Candidates[0].Address != (common.Address{5}).Hex() {
+		t.Errorf("candidates not taken from the parent: %v", snapshot.Candidates)
+	}
+	delegates, _ := chain.DelegateStateAt(shuffled.DelegateRoot)
+	round := util.ShuffleRound(config.ShuffleVersion(shuffled.Number), 30, 3, delegates.GetDelegates()[:3], 10)
+	want := make([]common.Address, len(round))
+	for i, delegate := range round {
+		want[i] = common.HexToAddress(delegate.Address)
+	}
+	if !reflect.DeepEqual(snapshot.Producers, want) {
+		t.Errorf("producers mismatch: have %x, want %x", snapshot.Producers, want)
+	}
+	for _, producer := range snapshot.Producers {
+		if producer == (common.Address{4}) || producer == (common.Address{5}) {
+			t.Errorf("producer %x not elected in the shuffled round", producer)
+		}
+	}
+	// Rounds shuffled from blocks outside the ancestry can't be resolved
+	header.ShuffleBlockNumber = big.NewInt(7)
+	if _, err := DelegatesFn(header, chain)(); err != consensus.ErrUnknownAncestor {
+		t.Errorf("error mismatch: have %v, want %v", err, consensus.ErrUnknownAncestor)
+	}
+}
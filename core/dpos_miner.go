@@ -31,7 +31,9 @@ import (
 	"github.com/Aurorachain-io/go-aoa/core/vm"
 	"github.com/Aurorachain-io/go-aoa/crypto"
 	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/event"
 	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/metrics"
 	"github.com/Aurorachain-io/go-aoa/params"
 	"github.com/pkg/errors"
 	"math/big"
@@ -40,6 +42,18 @@ import (
 	"time"
 )
 
+var (
+	// errSlotMissed is returned if a block of a local delegate could not be
+	// assembled before the end of its slot.
+	errSlotMissed = errors.New("block slot missed")
+
+	// errMinerStopped is returned if the miner stopped while a block of a local
+	// delegate was waiting for its slot.
+	errMinerStopped = errors.New("miner stopped")
+
+	missedSlotCounter = metrics.NewCounter("dpos/slots/missed") // Slots of local delegates whose block was dropped
+)
+
 // Backend wraps all methods required for mining.
 type Backend interface {
 	AccountManager() *accounts.Manager
@@ -53,17 +67,28 @@ type DposMiner struct {
 	produceBlockCallBack      func(ctx context.Context)
 	blockChan                 chan *types.Block
 	mu                        sync.Mutex
-	extra                     []byte // maybe can be set by rpc
+	extra                     []byte         // extra-data, or the producer vanity embedded into it from the v1 fork on
+	ordering                  TxOrdering     // strategy selecting the order of pending transactions
+	gasTarget                 uint64         // gas limit the produced blocks move towards (0 = params.TargetGasLimit)
+	coinbase                  common.Address // beneficiary of pending blocks and producer of instant blocks
 	dac                       Backend
 	config                    *params.ChainConfig
-	current                   *worker
 	engine                    consensus.Engine
 	currentNewRoundHash       *types.ShuffleData
 	shuffleHashChan           chan *types.ShuffleData
 	delegateInfoMap           map[string]*ecdsa.PrivateKey
 	AddDelegateWalletCallback func(data *aa.DelegateWalletInfo)
+
+	pending         *pendingWork // Block assembled from the pool on top of the head
+	pendingMu       sync.RWMutex // Lock protecting the pending block
+	pendingLogsFeed event.Feed   // Feed announcing the logs of re-assembled pending blocks
+
+	quit chan struct{}
+	wg   sync.WaitGroup
 }
 
+// worker is the environment a block is assembled in, either to be sealed in a
+// slot of the local delegates or as the pending block.
 type worker struct {
 	config     *params.ChainConfig
 	state      *state.StateDB // apply state changes here
@@ -73,9 +98,7 @@ type worker struct {
 	createdAt  time.Time
 	signer     types.Signer
 	header     *types.Header
-	Block      *types.Block // the new block
 	delegatedb *delegatestate.DelegateDB
-	currentMu  sync.Mutex
 }
 
 func NewDposMiner(config *params.ChainConfig, dac Backend, engine consensus.Engine) *DposMiner {
@@ -85,8 +108,10 @@ func NewDposMiner(config *params.ChainConfig, dac Backend, engine consensus.Engi
 		dac:             dac,
 		config:          config,
 		engine:          engine,
+		ordering:        DefaultTxOrdering,
 		shuffleHashChan: make(chan *types.ShuffleData),
 		delegateInfoMap: make(map[string]*ecdsa.PrivateKey, 0),
+		quit:            make(chan struct{}),
 	}
 
 	addDelegateWalletCallback := func(data *aa.DelegateWalletInfo) {
@@ -100,6 +125,9 @@ func NewDposMiner(config *params.ChainConfig, dac Backend, engine consensus.Engi
 		}
 	}
 	dposMiner.AddDelegateWalletCallback = addDelegateWalletCallback
+	if authorizer, ok := engine.(consensus.Authorizer); ok {
+		authorizer.Authorize(dposMiner.signHashWithoutWallet)
+	}
 	produceBlockCallback := func(ctx context.Context) {
 		value := ctx.Value(types.DelegatePrefix)
 		candidate, ok := value.(types.ProduceDelegate)
@@ -107,83 +135,141 @@ func NewDposMiner(config *params.ChainConfig, dac Backend, engine consensus.Engi
 			log.Error("dposMiner| convert error,stop produce block")
 			return
 		}
-		dposMiner.mu.Lock()
-		defer dposMiner.mu.Unlock()
+		block, err := dposMiner.produceInSlot(common.HexToAddress(candidate.Address), candidate.NickName, time.Unix(int64(candidate.WorkTime), 0))
+		if err != nil {
+			log.Error("dpos|produceBlockCallback|fail", "err", err)
+			return
+		}
+		log.Info("dpos|produceBlockCallback push block to chan", "blockNumber", block.NumberU64(), "trxLen", block.Transactions().Len())
+		go func() {
+			dposMiner.blockChan <- block
+		}()
+	}
 
-		tstamp := candidate.WorkTime
-		blockTime := big.NewInt(int64(tstamp))
-		parent := dposMiner.dac.BlockChain().CurrentBlock()
+	dposMiner.produceBlockCallBack = produceBlockCallback
+	go dposMiner.readNewShufflehash()
+	return dposMiner
+}
 
-		lastBlockNumber := parent.Number()
-		gasLimit := CalcGasLimit(parent)
+// ProduceBlock assembles and seals a block of the pending transactions on top of
+// the current head at the given time, outside of the delegate rounds. It serves
+// chains sealing blocks on demand, which keep the shuffle of their parent.
+func (d *DposMiner) ProduceBlock(coinbase common.Address, nickname string, blockTime *big.Int) (*types.Block, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	parent := d.dac.BlockChain().CurrentBlock().Header()
+	shuffleData := &types.ShuffleData{ShuffleHash: &parent.ShuffleHash, ShuffleBlockNumber: parent.ShuffleBlockNumber}
+	if shuffleData.ShuffleBlockNumber == nil {
+		shuffleData.ShuffleBlockNumber = new(big.Int)
+	}
+	return d.produce(coinbase, nickname, blockTime, shuffleData, time.Time{})
+}
 
-		if gasLimit > params.MaxGasLimit {
-			gasLimit = params.MaxGasLimit
-		}
+// produceInSlot assembles the block of a local delegate ahead of its slot and
+// holds it back until the slot begins. The block is re-assembled if the head
+// moved in the meantime, and dropped if it cannot be released before the slot
+// ends, leaving its transactions in the pool for the following producers.
+func (d *DposMiner) produceInSlot(coinbase common.Address, nickname string, slot time.Time) (*types.Block, error) {
+	interval := time.Duration(d.config.BlockInterval.Int64()) * time.Second
+	deadline := slot.Add(interval)
+	if time.Now().After(deadline) {
+		missedSlotCounter.Inc(1)
+		return nil, errSlotMissed
+	}
+	d.mu.Lock()
+	shuffleData := d.currentNewRoundHash
+	log.Info("dpos|produceInSlot", "slot", slot, "shuffleHash", shuffleData.ShuffleHash.Hex(), "shuffleBlockNumber", shuffleData.ShuffleBlockNumber)
+	block, err := d.produce(coinbase, nickname, big.NewInt(slot.Unix()), shuffleData, slot)
+	d.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	// Wait for the slot, re-assembling the block if it was overtaken meanwhile
+	select {
+	case <-time.After(time.Until(slot)):
+	case <-d.quit:
+		return nil, errMinerStopped
+	}
+	if head := d.dac.BlockChain().CurrentBlock(); block.ParentHash() != head.Hash() {
+		log.Info("dpos|produceInSlot head changed, reassembling", "number", block.NumberU64(), "head", head.NumberU64())
 
-		encodeBytes := hexutil.Encode([]byte(candidate.NickName))
-		agentName := hexutil.MustDecode(encodeBytes)
-		shuffleData := dposMiner.currentNewRoundHash
-		log.Info("dpos|produceBlockCallback", "shuffleHash", shuffleData.ShuffleHash.Hex(), "shuffleBlockNumber", shuffleData.ShuffleBlockNumber)
-		header := &types.Header{
-			ParentHash:         parent.Hash(),
-			Number:             lastBlockNumber.Add(lastBlockNumber, common.Big1),
-			GasLimit:           gasLimit,
-			Extra:              dposMiner.extra,
-			Time:               blockTime,
-			Coinbase:           common.HexToAddress(candidate.Address),
-			AgentName:          agentName,
-			ShuffleHash:        *shuffleData.ShuffleHash,
-			ShuffleBlockNumber: shuffleData.ShuffleBlockNumber,
+		d.mu.Lock()
+		block, err = d.produce(coinbase, nickname, big.NewInt(slot.Unix()), shuffleData, slot.Add(interval/2))
+		d.mu.Unlock()
+		if err != nil {
+			return nil, err
 		}
-		log.Info("dpos|produceBlockCallback", "blockNumber", header.Number.Uint64(), "blockGasLimit", gasLimit, "beginTime", tstamp, "currentTime", time.Now().Unix(), "coinbase", header.Coinbase.Hex())
+	}
+	if time.Now().After(deadline) {
+		missedSlotCounter.Inc(1)
+		return nil, errSlotMissed
+	}
+	return block, nil
+}
 
-		if err := engine.Prepare(dposMiner.dac.BlockChain(), header); err != nil {
-			log.Error("dpos|Failed to prepare header", "err", err)
-			return
-		}
+// produce assembles a block of the pending transactions on top of the current
+// head and seals it through the consensus engine. Transactions stop being added
+// once the cutoff passed, unless it is zero. The caller must hold d.mu.
+func (d *DposMiner) produce(coinbase common.Address, nickname string, blockTime *big.Int, shuffleData *types.ShuffleData, cutoff time.Time) (*types.Block, error) {
+	parent := d.dac.BlockChain().CurrentBlock()
 
-		if err := dposMiner.makeCurrent(parent, header); err != nil {
-			log.Error("Failed to create dpos produce context", "err", err)
-			return
-		}
+	lastBlockNumber := parent.Number()
+	gasLimit := CalcGasLimitTarget(parent, d.gasTarget)
 
-		//pending, err := dposMiner.em.TxPool().Pending()
-		now := time.Now()
-		pending, err := dposMiner.dac.TxPool().PendingTxsByPrice()
-		log.Info("PendingTxsByPrice end", "timestamp", time.Now().Sub(now))
+	if gasLimit > params.MaxGasLimit {
+		gasLimit = params.MaxGasLimit
+	}
 
-		if err != nil {
-			log.Error("Failed to fetch pending transactions", "err", err)
-			return
-		}
-		work := dposMiner.current
-
-		txs := types.NewTransactionsByPriceAndNonce2(work.signer, pending)
-		no := time.Now()
-		dposMiner.commitTransactions(txs, header.Coinbase)
-		log.Info("commitTransactions end", "timestamp", time.Now().Sub(no), "whole Time", time.Now().Sub(now))
-		if work.Block, err = engine.Finalize(dposMiner.dac.BlockChain(), header, work.state, work.delegatedb, work.txs, work.receipts); err != nil {
-			log.Error("Failed to finalize block for sealing", "err", err)
-			return
+	encodeBytes := hexutil.Encode([]byte(nickname))
+	agentName := hexutil.MustDecode(encodeBytes)
+	number := new(big.Int).Add(lastBlockNumber, common.Big1)
+	extra := common.CopyBytes(d.extra)
+	if d.config.IsExtraV1(number) {
+		var err error
+		if extra, err = types.EncodeHeaderExtra(types.NewHeaderExtra(shuffleData.ShuffleBlockNumber.Uint64(), d.extra)); err != nil {
+			return nil, fmt.Errorf("failed to encode header extra-data: %v", err)
 		}
+	}
+	header := &types.Header{
+		ParentHash:         parent.Hash(),
+		Number:             number,
+		GasLimit:           gasLimit,
+		Extra:              extra,
+		Time:               blockTime,
+		Coinbase:           coinbase,
+		AgentName:          agentName,
+		ShuffleHash:        *shuffleData.ShuffleHash,
+		ShuffleBlockNumber: shuffleData.ShuffleBlockNumber,
+	}
+	log.Info("dpos|produceBlock", "blockNumber", header.Number.Uint64(), "blockGasLimit", gasLimit, "beginTime", blockTime, "currentTime", time.Now().Unix(), "coinbase", header.Coinbase.Hex())
 
-		if work.Block != nil {
-			err := dposMiner.signBlockWithoutWallet(work.Block, header.Coinbase)
-			if err != nil {
-				log.Error("dpos|produceBlockCallback|fail", "err", err)
-				return
-			}
-			log.Info("dpos|produceBlockCallback push block to chan", "blockNumber", work.Block.NumberU64(), "trxLen", work.Block.Transactions().Len())
-			go func() {
-				dposMiner.blockChan <- work.Block
-			}()
-		}
+	if err := d.engine.Prepare(d.dac.BlockChain(), header); err != nil {
+		return nil, fmt.Errorf("failed to prepare header: %v", err)
 	}
 
-	dposMiner.produceBlockCallBack = produceBlockCallback
-	go dposMiner.readNewShufflehash()
-	return dposMiner
+	work, err := d.makeWork(parent, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dpos produce context: %v", err)
+	}
+
+	//pending, err := dposMiner.em.TxPool().Pending()
+	now := time.Now()
+	pending, err := d.dac.TxPool().PendingTxsByPrice()
+	log.Info("PendingTxsByPrice end", "timestamp", time.Now().Sub(now))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pending transactions: %v", err)
+	}
+	txs := d.ordering.Order(work.signer, pending)
+	no := time.Now()
+	d.commitTransactions(work, txs, header.Coinbase, cutoff)
+	log.Info("commitTransactions end", "timestamp", time.Now().Sub(no), "whole Time", time.Now().Sub(now))
+	block, err := d.engine.Finalize(d.dac.BlockChain(), header, work.state, work.delegatedb, work.txs, work.receipts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finalize block for sealing: %v", err)
+	}
+	return d.engine.Seal(d.dac.BlockChain(), block, nil)
 }
 
 // sign block with coinbase,need to unlock wallet
@@ -204,21 +290,37 @@ func (d *DposMiner) signBlock(block *types.Block, coinbase common.Address) error
 	return nil
 }
 
-// sign block with coinbase,pwd store in memory
-func (d *DposMiner) signBlockWithoutWallet(block *types.Block, coinbase common.Address) error {
+// signHashWithoutWallet signs a block hash with the key of the coinbase, pwd store
+// in memory. Coinbases without a key in memory, like the developer account of
+// instant sealing chains, sign with their unlocked wallet. It is the signer the
+// miner authorizes the consensus engine with.
+func (d *DposMiner) signHashWithoutWallet(coinbase common.Address, hash []byte) ([]byte, error) {
 	address := strings.ToLower(coinbase.Hex())
 	if _, ok := d.delegateInfoMap[address]; !ok {
+		if d.config.Instant != nil {
+			return d.signHashWithWallet(coinbase, hash)
+		}
 		errMsg := fmt.Sprintf("sign block fail because can not find pwd in memory address:%s lenMap:%d", coinbase.Hex(), len(d.delegateInfoMap))
-		return errors.New(errMsg)
+		return nil, errors.New(errMsg)
 	}
 	privateKey := d.delegateInfoMap[address]
-	signature, err := crypto.Sign(block.Hash().Bytes()[:32], privateKey)
+	signature, err := crypto.Sign(hash[:32], privateKey)
 	if err != nil {
 		log.Error("Failed to sign block", "coinbaseAddress", coinbase.Hex(), "err", err)
-		return errors.New("sign error")
+		return nil, errors.New("sign error")
 	}
-	block.Signature = signature
-	return nil
+	return signature, nil
+}
+
+// signHashWithWallet signs a block hash with the unlocked wallet of the coinbase.
+func (d *DposMiner) signHashWithWallet(coinbase common.Address, hash []byte) ([]byte, error) {
+	account := accounts.Account{Address: coinbase}
+	wallet, err := d.dac.AccountManager().Find(account)
+	if err != nil {
+		log.Error("Failed to find coinbase wallet", "coinbaseAddress", coinbase.Hex(), "err", err)
+		return nil, errors.New("sign error")
+	}
+	return wallet.SignHash(account, hash)
 }
 
 func (d *DposMiner) GetCurrentNewRoundHash() *types.ShuffleData {
@@ -237,6 +339,69 @@ func (d *DposMiner) GetDelegateWallets() map[string]*ecdsa.PrivateKey {
 	return d.delegateInfoMap
 }
 
+// SetExtra sets the extra-data of the blocks produced from now on. From the
+// extra-data v1 fork on it is embedded as producer vanity into the structured
+// extra-data, so it is limited to the vanity size once the fork is scheduled.
+func (d *DposMiner) SetExtra(extra []byte) error {
+	if uint64(len(extra)) > params.MaximumExtraDataSize {
+		return fmt.Errorf("extra-data too long: %d > %d", len(extra), params.MaximumExtraDataSize)
+	}
+	if d.config.ExtraV1Block != nil {
+		if err := types.NewHeaderExtra(0, extra).Validate(); err != nil {
+			return err
+		}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.extra = common.CopyBytes(extra)
+	return nil
+}
+
+// SetGasLimit sets the gas limit the blocks produced from now on move towards, as
+// far as the bound divisor allows per block. A zero target restores the default
+// params.TargetGasLimit.
+func (d *DposMiner) SetGasLimit(target uint64) error {
+	if target != 0 && (target < params.MinGasLimit || target > params.MaxGasLimit) {
+		return fmt.Errorf("gas limit target %d outside of [%d, %d]", target, params.MinGasLimit, params.MaxGasLimit)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.gasTarget = target
+	return nil
+}
+
+// SetCoinbase sets the beneficiary of the pending blocks. Blocks sealed in the
+// delegate rounds are credited to the delegate of their slot, while instant
+// sealing chains seal with the coinbase if it is a delegate.
+func (d *DposMiner) SetCoinbase(coinbase common.Address) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.coinbase = coinbase
+}
+
+// Coinbase returns the beneficiary of the pending blocks.
+func (d *DposMiner) Coinbase() common.Address {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.coinbase
+}
+
+// SetTxOrdering sets the strategy ordering the pending transactions of the
+// blocks produced from now on. A nil ordering restores DefaultTxOrdering.
+func (d *DposMiner) SetTxOrdering(ordering TxOrdering) {
+	if ordering == nil {
+		ordering = DefaultTxOrdering
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.ordering = ordering
+}
+
 func (d *DposMiner) readNewShufflehash() {
 	for {
 		select {
@@ -247,20 +412,21 @@ func (d *DposMiner) readNewShufflehash() {
 	}
 }
 
-// makeCurrent creates a new environment for the current cycle
-func (d *DposMiner) makeCurrent(parent *types.Block, header *types.Header) error {
+// makeWork creates a new environment assembling the given header on top of its
+// parent block.
+func (d *DposMiner) makeWork(parent *types.Block, header *types.Header) (*worker, error) {
 	statedb, err := d.dac.BlockChain().StateAt(parent.Root())
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 	delegatedb, err := d.dac.BlockChain().DelegateStateAt(parent.DelegateRoot())
 
 	if err != nil {
-		log.Error("dposMiner|makeCurrent|delegatedb err", "err", err)
-		return err
+		log.Error("dposMiner|makeWork|delegatedb err", "err", err)
+		return nil, err
 	}
-	work := &worker{
+	return &worker{
 		config:     d.config,
 		state:      statedb,
 		createdAt:  time.Now(),
@@ -268,35 +434,20 @@ func (d *DposMiner) makeCurrent(parent *types.Block, header *types.Header) error
 		signer:     types.NewAuroraSigner(d.config.ChainId),
 		header:     header,
 		delegatedb: delegatedb,
-	}
-	d.current = work
-	return nil
-
-}
-
-func (d *DposMiner) Pending() (*types.Block, *state.StateDB) {
-	work := d.current
-	work.currentMu.Lock()
-	defer work.currentMu.Unlock()
-	return work.Block, work.state.Copy()
-}
-
-func (d *DposMiner) PendingBlock() *types.Block {
-	return d.dac.BlockChain().CurrentBlock()
-	//work := d.current
-	//work.currentMu.Lock()
-	//defer work.currentMu.Unlock()
-	//return work.Block
+	}, nil
 }
 
 func (d *DposMiner) GetProduceCallback() func(ctx context.Context) {
 	return d.produceBlockCallBack
 }
 
-func (d *DposMiner) commitTransactions(txs *types.TransactionsByPriceAndNonce, coinbase common.Address) {
-	env := d.current
+// commitTransactions applies the transactions of the iterator fitting into the
+// block of the environment until the cutoff, if non-zero, passed, returning the
+// logs they generated.
+func (d *DposMiner) commitTransactions(env *worker, txs TransactionIterator, coinbase common.Address, cutoff time.Time) []*types.Log {
 	gp := new(GasPool).AddGas(env.header.GasLimit)
 	contractGasLimit := new(GasPool).AddGas(params.MaxContractGasLimit)
+	limits := env.config.BlockLimits(env.header.Number)
 
 	var coalescedLogs []*types.Log
 
@@ -306,11 +457,27 @@ func (d *DposMiner) commitTransactions(txs *types.TransactionsByPriceAndNonce, c
 			log.Trace("Not enough gas for further transactions", "gp", gp)
 			break
 		}
+		// Stop if the block must be ready for its slot
+		if !cutoff.IsZero() && time.Now().After(cutoff) {
+			log.Debug("Block assembly cutoff reached", "number", env.header.Number, "txs", env.tcount)
+			break
+		}
+		// Stop if the block can't hold any more transactions
+		if limits.MaxTxs > 0 && uint64(env.tcount) >= limits.MaxTxs {
+			log.Trace("Transaction limit reached for current block", "limit", limits.MaxTxs)
+			break
+		}
 		// Retrieve the next transaction and abort if all done
 		tx := txs.Peek()
 		if tx == nil {
 			break
 		}
+		// Skip the account if the transaction can't be included in any block
+		if limits.MaxTxSize > 0 && uint64(tx.Size()) > limits.MaxTxSize {
+			log.Trace("Skipping oversized transaction", "tx", tx.Hash(), "size", tx.Size(), "limit", limits.MaxTxSize)
+			txs.Pop()
+			continue
+		}
 
 		var isContract bool
 		contract := tx.GetIsContract()
@@ -400,17 +567,7 @@ func (d *DposMiner) commitTransactions(txs *types.TransactionsByPriceAndNonce, c
 		}
 
 	}
-
-	if len(coalescedLogs) > 0 || env.tcount > 0 {
-		// make a copy, the state caches the logs and these logs get "upgraded" from pending to mined
-		// logs by filling in the block hash when the block was mined by the local miner. This can
-		// cause a race condition if a log was "upgraded" before the PendingLogsEvent is processed.
-		cpy := make([]*types.Log, len(coalescedLogs))
-		for i, l := range coalescedLogs {
-			cpy[i] = new(types.Log)
-			*cpy[i] = *l
-		}
-	}
+	return coalescedLogs
 }
 
 func (env *worker) commitTransaction(tx *types.Transaction, bc *BlockChain, coinbase common.Address, gp *GasPool) (error, uint64, []*types.Log) {
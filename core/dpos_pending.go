@@ -0,0 +1,228 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/event"
+	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+const (
+	// pendingTxChanSize is the size of channel listening to TxPreEvent.
+	pendingTxChanSize = 4096
+
+	// pendingRecommit is the minimum interval between re-assemblies of the
+	// pending block when new transactions enter the pool. Head changes
+	// re-assemble it right away.
+	pendingRecommit = time.Second
+)
+
+// pendingWork is a block assembled from the transaction pool on top of the
+// current head, which the local node would produce if it sealed the next block.
+// It is neither finalized by the consensus engine nor sealed, so it doesn't
+// account for block rewards.
+type pendingWork struct {
+	block    *types.Block
+	receipts types.Receipts
+	logs     []*types.Log
+	state    *state.StateDB
+}
+
+// Start starts re-assembling the pending block in the background whenever the
+// head changes or transactions enter the pool.
+func (d *DposMiner) Start() {
+	d.wg.Add(1)
+	go d.pendingLoop()
+}
+
+// Stop stops re-assembling the pending block.
+func (d *DposMiner) Stop() {
+	close(d.quit)
+	d.wg.Wait()
+}
+
+// GetPendingBlock returns the pending block, or the current head if none was
+// assembled yet.
+func (d *DposMiner) GetPendingBlock() *types.Block {
+	d.pendingMu.RLock()
+	defer d.pendingMu.RUnlock()
+
+	if d.pending == nil {
+		return d.dac.BlockChain().CurrentBlock()
+	}
+	return d.pending.block
+}
+
+// Pending returns the pending block and a copy of the state it results in. If
+// none was assembled yet, the current head and its state are returned.
+func (d *DposMiner) Pending() (*types.Block, *state.StateDB) {
+	d.pendingMu.RLock()
+	defer d.pendingMu.RUnlock()
+
+	if d.pending == nil {
+		head := d.dac.BlockChain().CurrentBlock()
+		statedb, err := d.dac.BlockChain().StateAt(head.Root())
+		if err != nil {
+			return head, nil
+		}
+		return head, statedb
+	}
+	return d.pending.block, d.pending.state.Copy()
+}
+
+// PendingReceipts returns the receipts of the transactions of the pending block.
+func (d *DposMiner) PendingReceipts() types.Receipts {
+	d.pendingMu.RLock()
+	defer d.pendingMu.RUnlock()
+
+	if d.pending == nil {
+		return nil
+	}
+	return d.pending.receipts
+}
+
+// PendingLogs returns the logs generated by the transactions of the pending
+// block.
+func (d *DposMiner) PendingLogs() []*types.Log {
+	d.pendingMu.RLock()
+	defer d.pendingMu.RUnlock()
+
+	if d.pending == nil {
+		return nil
+	}
+	return copyLogs(d.pending.logs)
+}
+
+// SubscribePendingLogsEvent registers a subscription of PendingLogsEvent, posted
+// with the logs of every re-assembled pending block generating any.
+func (d *DposMiner) SubscribePendingLogsEvent(ch chan<- PendingLogsEvent) event.Subscription {
+	return d.pendingLogsFeed.Subscribe(ch)
+}
+
+// pendingLoop re-assembles the pending block on every head change, and at most
+// every pendingRecommit while transactions enter the pool.
+func (d *DposMiner) pendingLoop() {
+	defer d.wg.Done()
+
+	headCh := make(chan ChainHeadEvent, chainHeadChanSize)
+	headSub := d.dac.BlockChain().SubscribeChainHeadEvent(headCh)
+	defer headSub.Unsubscribe()
+
+	txCh := make(chan TxPreEvent, pendingTxChanSize)
+	txSub := d.dac.TxPool().SubscribeTxPreEvent(txCh)
+	defer txSub.Unsubscribe()
+
+	recommit := time.NewTicker(pendingRecommit)
+	defer recommit.Stop()
+
+	d.updatePending()
+	stale := false
+	for {
+		select {
+		case <-headCh:
+			d.updatePending()
+			stale = false
+
+		case <-txCh:
+			stale = true
+
+		case <-recommit.C:
+			if stale {
+				d.updatePending()
+				stale = false
+			}
+
+		case <-headSub.Err():
+			return
+		case <-txSub.Err():
+			return
+		case <-d.quit:
+			return
+		}
+	}
+}
+
+// updatePending assembles a new pending block of the pool transactions on top
+// of the current head.
+func (d *DposMiner) updatePending() {
+	parent := d.dac.BlockChain().CurrentBlock()
+
+	d.mu.Lock()
+	ordering, gasTarget, coinbase := d.ordering, d.gasTarget, d.coinbase
+	d.mu.Unlock()
+
+	gasLimit := CalcGasLimitTarget(parent, gasTarget)
+	if gasLimit > params.MaxGasLimit {
+		gasLimit = params.MaxGasLimit
+	}
+	blockTime := new(big.Int).Set(parent.Time())
+	if interval := d.config.BlockInterval; interval != nil {
+		blockTime.Add(blockTime, interval)
+	}
+	header := &types.Header{
+		ParentHash:         parent.Hash(),
+		Number:             new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:           gasLimit,
+		Extra:              common.CopyBytes(parent.Extra()),
+		Time:               blockTime,
+		Coinbase:           coinbase,
+		ShuffleHash:        parent.Header().ShuffleHash,
+		ShuffleBlockNumber: parent.Header().ShuffleBlockNumber,
+	}
+	work, err := d.makeWork(parent, header)
+	if err != nil {
+		log.Warn("Failed to create pending block context", "number", header.Number, "err", err)
+		return
+	}
+	pending, err := d.dac.TxPool().PendingTxsByPrice()
+	if err != nil {
+		log.Warn("Failed to fetch pending transactions", "err", err)
+		return
+	}
+	logs := d.commitTransactions(work, ordering.Order(work.signer, pending), header.Coinbase, time.Time{})
+
+	header.Root = work.state.IntermediateRoot(false)
+	header.DelegateRoot = work.delegatedb.IntermediateRoot(false)
+	block := types.NewBlock(header, work.txs, work.receipts)
+
+	d.pendingMu.Lock()
+	d.pending = &pendingWork{block: block, receipts: work.receipts, logs: logs, state: work.state}
+	d.pendingMu.Unlock()
+
+	log.Debug("Assembled pending block", "number", header.Number, "txs", len(work.txs), "gas", header.GasUsed, "elapsed", common.PrettyDuration(time.Since(work.createdAt)))
+	if len(logs) > 0 {
+		d.pendingLogsFeed.Send(PendingLogsEvent{Logs: copyLogs(logs)})
+	}
+}
+
+// copyLogs returns a copy of the logs, so that subscribers and callers can't
+// modify the logs cached in the pending block.
+func copyLogs(logs []*types.Log) []*types.Log {
+	cpy := make([]*types.Log, len(logs))
+	for i, l := range logs {
+		cpy[i] = new(types.Log)
+		*cpy[i] = *l
+	}
+	return cpy
+}
@@ -0,0 +1,220 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/accounts"
+	aa "github.com/Aurorachain-io/go-aoa/accounts/walletType"
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus/dpos"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/core/vm"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// testMinerBackend serves a chain and transaction pool to the block producer.
+type testMinerBackend struct {
+	db     aoadb.Database
+	chain  *BlockChain
+	txPool *TxPool
+}
+
+func (b *testMinerBackend) AccountManager() *accounts.Manager { return nil }
+func (b *testMinerBackend) BlockChain() *BlockChain           { return b.chain }
+func (b *testMinerBackend) TxPool() *TxPool                   { return b.txPool }
+func (b *testMinerBackend) ChainDb() aoadb.Database           { return b.db }
+func (b *testMinerBackend) WatcherDb() aoadb.Database         { return nil }
+
+// Tests that the pending block is assembled from the pool transactions on top
+// of the head, and falls back to the head before any assembly.
+func TestPendingBlock(t *testing.T) {
+	var (
+		db, _  = aoadb.NewMemDatabase()
+		config = params.AllDacchainProtocolChanges
+		signer = types.MakeSigner(config, big.NewInt(1))
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		to     = common.HexToAddress("0x01")
+	)
+	genesis := (&Genesis{
+		Config: config,
+		Alloc:  GenesisAlloc{addr: {Balance: big.NewInt(params.Em)}},
+		Agents: GenesisAgents{{Address: "0x0200", Vote: 1, Nickname: "test"}},
+	}).MustCommit(db)
+
+	chain, err := NewBlockChain(db, nil, config, dpos.New(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	poolConfig := DefaultTxPoolConfig
+	poolConfig.Journal = ""
+	pool := NewTxPool(poolConfig, config, chain)
+	defer pool.Stop()
+
+	miner := NewDposMiner(config, &testMinerBackend{db: db, chain: chain, txPool: pool}, dpos.New())
+	if block := miner.GetPendingBlock(); block.Hash() != genesis.Hash() {
+		t.Fatalf("pending block before assembly: have %x, want head %x", block.Hash(), genesis.Hash())
+	}
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		tx, err := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(10), 100000, big.NewInt(1), nil, uint64(types.ActionTrans), nil, ""), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		if err := pool.AddLocal(tx); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", nonce, err)
+		}
+	}
+	miner.updatePending()
+
+	block, statedb := miner.Pending()
+	if block.NumberU64() != 1 || block.ParentHash() != genesis.Hash() {
+		t.Fatalf("pending block #%d on %x, want #1 on %x", block.NumberU64(), block.ParentHash(), genesis.Hash())
+	}
+	if len(block.Transactions()) != 2 || len(miner.PendingReceipts()) != 2 {
+		t.Fatalf("pending block has %d transactions and %d receipts, want 2", len(block.Transactions()), len(miner.PendingReceipts()))
+	}
+	if balance := statedb.GetBalance(to); balance.Cmp(big.NewInt(20)) != 0 {
+		t.Errorf("pending balance mismatch: have %v, want 20", balance)
+	}
+	if block.Root() != statedb.IntermediateRoot(false) {
+		t.Errorf("pending state root mismatch")
+	}
+	if state, _ := chain.State(); state.GetBalance(to).Sign() != 0 {
+		t.Errorf("pending transactions applied to the head state")
+	}
+}
+
+// Tests that the gas limit moves towards the configured target within the bound
+// divisor, in both directions.
+func TestCalcGasLimitTarget(t *testing.T) {
+	parent := types.NewBlockWithHeader(&types.Header{GasLimit: params.GenesisGasLimit})
+	step := params.GenesisGasLimit/params.GasLimitBoundDivisor - 1
+
+	tests := []struct {
+		target, want uint64
+	}{
+		{0, CalcGasLimit(parent)},
+		{params.GenesisGasLimit + step + 1, params.GenesisGasLimit + step},
+		{params.GenesisGasLimit + 1, params.GenesisGasLimit + 1},
+		{params.GenesisGasLimit - step - 1, params.GenesisGasLimit - step},
+		{params.GenesisGasLimit - 1, params.GenesisGasLimit - 1},
+	}
+	for _, tt := range tests {
+		if have := CalcGasLimitTarget(parent, tt.target); have != tt.want {
+			t.Errorf("target %d: gas limit mismatch: have %d, want %d", tt.target, have, tt.want)
+		}
+	}
+}
+
+// Tests that the runtime settings of the block producer are validated and apply
+// to the blocks assembled afterwards.
+func TestMinerSettings(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+	config := params.AllDacchainProtocolChanges
+	(&Genesis{Config: config, Agents: GenesisAgents{{Address: "0x0200", Vote: 1, Nickname: "test"}}}).MustCommit(db)
+
+	chain, err := NewBlockChain(db, nil, config, dpos.New(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	poolConfig := DefaultTxPoolConfig
+	poolConfig.Journal = ""
+	pool := NewTxPool(poolConfig, config, chain)
+	defer pool.Stop()
+
+	miner := NewDposMiner(config, &testMinerBackend{db: db, chain: chain, txPool: pool}, dpos.New())
+	for _, target := range []uint64{params.MinGasLimit - 1, params.MaxGasLimit + 1} {
+		if err := miner.SetGasLimit(target); err == nil {
+			t.Errorf("gas limit target %d accepted", target)
+		}
+	}
+	if err := miner.SetGasLimit(params.MinGasLimit); err != nil {
+		t.Fatalf("failed to set gas limit target: %v", err)
+	}
+	coinbase := common.HexToAddress("0x0300")
+	miner.SetCoinbase(coinbase)
+	miner.updatePending()
+
+	header := miner.GetPendingBlock().Header()
+	if want := CalcGasLimitTarget(chain.CurrentBlock(), params.MinGasLimit); header.GasLimit != want {
+		t.Errorf("pending gas limit mismatch: have %d, want %d", header.GasLimit, want)
+	}
+	if header.GasLimit >= chain.CurrentBlock().GasLimit() {
+		t.Errorf("pending gas limit %d not moving towards target", header.GasLimit)
+	}
+	if header.Coinbase != coinbase {
+		t.Errorf("pending coinbase mismatch: have %x, want %x", header.Coinbase, coinbase)
+	}
+}
+
+// Tests that the block of a local delegate is held back until its slot begins,
+// and that slots already over are skipped without assembling a block.
+func TestProduceInSlot(t *testing.T) {
+	var (
+		db, _  = aoadb.NewMemDatabase()
+		config = params.AllDacchainProtocolChanges
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+	)
+	genesis := (&Genesis{
+		Config: config,
+		Agents: GenesisAgents{{Address: addr.Hex(), Vote: 1, Nickname: "test"}},
+	}).MustCommit(db)
+
+	chain, err := NewBlockChain(db, nil, config, dpos.New(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	poolConfig := DefaultTxPoolConfig
+	poolConfig.Journal = ""
+	pool := NewTxPool(poolConfig, config, chain)
+	defer pool.Stop()
+
+	miner := NewDposMiner(config, &testMinerBackend{db: db, chain: chain, txPool: pool}, dpos.New())
+	miner.AddDelegateWalletCallback(&aa.DelegateWalletInfo{Address: addr.Hex(), PrivateKey: key})
+	miner.currentNewRoundHash = &types.ShuffleData{ShuffleHash: &common.Hash{}, ShuffleBlockNumber: new(big.Int)}
+
+	// A slot ended already must be skipped
+	interval := time.Duration(config.BlockInterval.Int64()) * time.Second
+	if _, err := miner.produceInSlot(addr, "test", time.Now().Add(-interval-time.Second)); err != errSlotMissed {
+		t.Fatalf("missed slot error mismatch: have %v, want %v", err, errSlotMissed)
+	}
+	// A block of an upcoming slot must only be released once the slot began
+	slot := time.Unix(time.Now().Unix()+2, 0)
+	block, err := miner.produceInSlot(addr, "test", slot)
+	if err != nil {
+		t.Fatalf("failed to produce block: %v", err)
+	}
+	if time.Now().Before(slot) {
+		t.Errorf("block released %v before its slot", time.Until(slot))
+	}
+	if block.Time().Int64() != slot.Unix() || block.ParentHash() != genesis.Hash() {
+		t.Errorf("block at %v on %x, want %v on %x", block.Time(), block.ParentHash(), slot.Unix(), genesis.Hash())
+	}
+}
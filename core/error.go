@@ -33,6 +33,22 @@ var (
 	// next one expected based on the local chain.
 	ErrNonceTooHigh = errors.New("nonce too high")
 
+	// ErrTooManyTxs is returned if a block contains more transactions than the
+	// chain configuration allows at its height.
+	ErrTooManyTxs = errors.New("too many transactions in block")
+
+	// ErrOversizedTx is returned if a transaction is larger than the chain
+	// configuration allows at the height of its block.
+	ErrOversizedTx = errors.New("oversized transaction")
+
+	// ErrFeePayerNotActive is returned if a transaction sponsored by a fee payer
+	// is included before the fee payer fork of the chain configuration.
+	ErrFeePayerNotActive = errors.New("fee payer not supported yet")
+
+	// ErrMaxInitCodeSizeExceeded is returned if a contract creation transaction
+	// carries more init code than the chain configuration allows at its height.
+	ErrMaxInitCodeSizeExceeded = errors.New("max initcode size exceeded")
+
 	ErrDuplicateRegisterAgent = errors.New("duplicate register vote address")
 
 	ErrAddVote = errors.New("delegate not exist when add vote")
@@ -24,6 +24,17 @@ import (
 // TxPreEvent is posted when a transaction enters the transaction pool.
 type TxPreEvent struct{ Tx *types.Transaction }
 
+// TxPromotedEvent is posted when queued transactions become executable, such as
+// after the nonce gap in front of them was filled.
+type TxPromotedEvent struct{ Txs types.Transactions }
+
+// TxDroppedEvent is posted when transactions are dropped from the transaction
+// pool without being included, along with the reason of the drop.
+type TxDroppedEvent struct {
+	Txs    types.Transactions
+	Reason string
+}
+
 // PendingLogsEvent is posted pre mining and notifies of pending logs.
 type PendingLogsEvent struct {
 	Logs []*types.Log
@@ -52,3 +63,12 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// ReorgEvent is posted when the canonical chain is reorganised. OldChain holds
+// the dropped blocks and NewChain the adopted ones, both ordered from the
+// highest block down to the first block above CommonBlock.
+type ReorgEvent struct {
+	CommonBlock *types.Block
+	OldChain    types.Blocks
+	NewChain    types.Blocks
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+)
+
+var (
+	// errEvidenceSameBlock is returned if both blocks of an evidence are the same.
+	errEvidenceSameBlock = errors.New("evidence blocks are identical")
+
+	// errEvidenceHeight is returned if the blocks of an evidence are at different
+	// heights.
+	errEvidenceHeight = errors.New("evidence blocks at different heights")
+
+	// errEvidenceSlot is returned if the blocks of an evidence are in different
+	// slots, as a delegate may legitimately produce the same height again in a
+	// later slot of its own once its first block was not adopted.
+	errEvidenceSlot = errors.New("evidence blocks in different slots")
+
+	// errEvidenceDelegate is returned if the blocks of an evidence were produced
+	// by different delegates.
+	errEvidenceDelegate = errors.New("evidence blocks by different delegates")
+
+	// errEvidenceSignature is returned if a block of an evidence is not signed by
+	// its producer.
+	errEvidenceSignature = errors.New("evidence block not signed by its producer")
+)
+
+// DoubleSignEvidence proves that a delegate equivocated, signing two different
+// blocks in the same slot. The blocks are kept in hash order, so the same
+// equivocation always yields the same evidence.
+type DoubleSignEvidence struct {
+	First     *types.Header // Header of the block with the lower hash
+	FirstSig  []byte        // Signature of the producer over the first block
+	Second    *types.Header // Header of the block with the higher hash
+	SecondSig []byte        // Signature of the producer over the second block
+}
+
+// NewDoubleSignEvidence creates the evidence of the two given signed blocks.
+func NewDoubleSignEvidence(a, b *types.Block) *DoubleSignEvidence {
+	if bytes.Compare(a.Hash().Bytes(), b.Hash().Bytes()) > 0 {
+		a, b = b, a
+	}
+	return &DoubleSignEvidence{
+		First:     a.Header(),
+		FirstSig:  common.CopyBytes(a.Signature),
+		Second:    b.Header(),
+		SecondSig: common.CopyBytes(b.Signature),
+	}
+}
+
+// Hash returns the identifier of the evidence.
+func (e *DoubleSignEvidence) Hash() common.Hash {
+	return crypto.Keccak256Hash(e.First.Hash().Bytes(), e.Second.Hash().Bytes())
+}
+
+// Delegate returns the address of the equivocating delegate.
+func (e *DoubleSignEvidence) Delegate() common.Address {
+	return e.First.Coinbase
+}
+
+// Number returns the height the delegate signed two blocks at.
+func (e *DoubleSignEvidence) Number() uint64 {
+	return e.First.Number.Uint64()
+}
+
+// Verify checks that the evidence consists of two different blocks at the same
+// height and in the same slot, both produced and signed by the same delegate.
+func (e *DoubleSignEvidence) Verify() error {
+	first, second := e.First.Hash(), e.Second.Hash()
+	switch {
+	case first == second:
+		return errEvidenceSameBlock
+	case e.First.Number.Cmp(e.Second.Number) != 0:
+		return errEvidenceHeight
+	case e.First.Time.Cmp(e.Second.Time) != 0:
+		return errEvidenceSlot
+	case e.First.Coinbase != e.Second.Coinbase:
+		return errEvidenceDelegate
+	}
+	for _, signed := range []struct {
+		hash common.Hash
+		sig  []byte
+	}{{first, e.FirstSig}, {second, e.SecondSig}} {
+		if signer, err := BlockSigner(signed.hash, signed.sig); err != nil || signer != e.First.Coinbase {
+			return errEvidenceSignature
+		}
+	}
+	return nil
+}
+
+// BlockSigner recovers the address of the delegate which signed the block with
+// the given hash.
+func BlockSigner(hash common.Hash, sig []byte) (common.Address, error) {
+	pubkey, err := crypto.SigToPub(hash.Bytes(), sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
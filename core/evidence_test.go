@@ -0,0 +1,86 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+)
+
+// newSignedTestBlock creates a block at the given height and slot time produced
+// and signed by the given key.
+func newSignedTestBlock(t *testing.T, number, time int64, extra byte, key []byte) *types.Block {
+	prv, _ := crypto.ToECDSA(key)
+	header := &types.Header{
+		Number:   big.NewInt(number),
+		Time:     big.NewInt(time),
+		Coinbase: crypto.PubkeyToAddress(prv.PublicKey),
+		Extra:    []byte{extra},
+	}
+	block := types.NewBlockWithHeader(header)
+	sig, err := crypto.Sign(block.Hash().Bytes(), prv)
+	if err != nil {
+		t.Fatalf("failed to sign block: %v", err)
+	}
+	block.Signature = sig
+	return block
+}
+
+// Tests that double sign evidences are only valid for two different blocks at
+// the same height and slot signed by their common producer.
+func TestDoubleSignEvidenceVerify(t *testing.T) {
+	var (
+		key   = common.FromHex("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		other = common.FromHex("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+
+		first  = newSignedTestBlock(t, 1, 1000, 1, key)
+		second = newSignedTestBlock(t, 1, 1000, 2, key)
+	)
+	evidence := NewDoubleSignEvidence(second, first)
+	if err := evidence.Verify(); err != nil {
+		t.Fatalf("valid evidence rejected: %v", err)
+	}
+	if evidence.Hash() != NewDoubleSignEvidence(first, second).Hash() {
+		t.Errorf("evidence hash depends on block order")
+	}
+	if evidence.Delegate() != first.Coinbase() || evidence.Number() != 1 {
+		t.Errorf("evidence accuses %x at #%d, want %x at #1", evidence.Delegate(), evidence.Number(), first.Coinbase())
+	}
+	tests := []struct {
+		a, b *types.Block
+		err  error
+	}{
+		{first, first, errEvidenceSameBlock},
+		{first, newSignedTestBlock(t, 2, 1000, 2, key), errEvidenceHeight},
+		{first, newSignedTestBlock(t, 1, 1010, 2, key), errEvidenceSlot},
+		{first, newSignedTestBlock(t, 1, 1000, 2, other), errEvidenceDelegate},
+	}
+	for i, tt := range tests {
+		if err := NewDoubleSignEvidence(tt.a, tt.b).Verify(); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	forged := NewDoubleSignEvidence(first, second)
+	forged.SecondSig = forged.FirstSig
+	if err := forged.Verify(); err != errEvidenceSignature {
+		t.Errorf("forged signature error mismatch: have %v, want %v", err, errEvidenceSignature)
+	}
+}
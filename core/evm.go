@@ -23,6 +23,7 @@ import (
 	"github.com/Aurorachain-io/go-aoa/core/types"
 	"github.com/Aurorachain-io/go-aoa/core/vm"
 	"github.com/Aurorachain-io/go-aoa/params"
+	"github.com/Aurorachain-io/go-aoa/util"
 )
 
 // ChainContext supports retrieving headers and consensus parameters from the
@@ -55,6 +56,7 @@ func NewEVMContext(msg Message, header *types.Header, chain ChainContext, author
 		Transfer:     Transfer,
 		Vote:         Vote,
 		GetHash:      GetHashFn(header, chain),
+		Delegates:    DelegatesFn(header, chain),
 		Origin:       msg.From(),
 		Coinbase:     header.Coinbase,
 		BlockNumber:  new(big.Int).Set(header.Number),
@@ -92,6 +94,85 @@ func GetHashFn(ref *types.Header, chain ChainContext) func(n uint64) common.Hash
 	}
 }
 
+// DelegatesFn returns a DelegatesFunc which snapshots the delegates from the
+// delegate state of the parent of the given header, once on first use. The
+// producers of the round are reshuffled from the block the header's round was
+// shuffled from.
+func DelegatesFn(ref *types.Header, chain ChainContext) vm.DelegatesFunc {
+	var snapshot *vm.DelegateSnapshot
+
+	return func() (*vm.DelegateSnapshot, error) {
+		if snapshot != nil {
+			return snapshot, nil
+		}
+		reader, ok := chain.(delegateSnapshotReader)
+		if !ok {
+			return nil, errNoDelegateSnapshot
+		}
+		parent := chain.GetHeader(ref.ParentHash, ref.Number.Uint64()-1)
+		genesis := reader.GetHeaderByNumber(0)
+		if parent == nil || genesis == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+		delegates, err := reader.DelegateStateAt(parent.DelegateRoot)
+		if err != nil {
+			return nil, err
+		}
+		// Rounds are aligned to the genesis, see dpos.Rounds
+		config := chain.GetGenesisConfig()
+		length := config.MaxElectDelegate.Uint64() * config.BlockInterval.Uint64()
+		round := (ref.Time.Uint64() - genesis.Time.Uint64()) / length
+
+		producers, err := roundProducers(chain, reader, ref, parent, int64(genesis.Time.Uint64()+round*length))
+		if err != nil {
+			return nil, err
+		}
+		snapshot = &vm.DelegateSnapshot{
+			Candidates: delegates.GetDelegates(),
+			Producers:  producers,
+			Round:      round,
+		}
+		return snapshot, nil
+	}
+}
+
+// roundProducers reshuffles the round of the given header starting at start, and
+// returns its delegates in block producing order. The block the round was
+// shuffled from is looked up through the parent hashes of the header, so forks
+// resolve to their own round.
+func roundProducers(chain ChainContext, reader delegateSnapshotReader, ref, parent *types.Header, start int64) ([]common.Address, error) {
+	if ref.ShuffleBlockNumber == nil {
+		return nil, nil
+	}
+	number := ref.ShuffleBlockNumber.Uint64()
+
+	shuffled := parent
+	for shuffled != nil && shuffled.Number.Uint64() > number {
+		shuffled = chain.GetHeader(shuffled.ParentHash, shuffled.Number.Uint64()-1)
+	}
+	if shuffled == nil || shuffled.Number.Uint64() != number {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	delegates, err := reader.DelegateStateAt(shuffled.DelegateRoot)
+	if err != nil {
+		return nil, err
+	}
+	config := chain.GetGenesisConfig()
+	maxElectDelegate := int(config.MaxElectDelegate.Int64())
+
+	candidates := delegates.GetDelegates()
+	if len(candidates) > maxElectDelegate {
+		candidates = candidates[:maxElectDelegate]
+	}
+	round := util.ShuffleRound(config.ShuffleVersion(shuffled.Number), start, maxElectDelegate, candidates, config.BlockInterval.Int64())
+
+	producers := make([]common.Address, len(round))
+	for i, delegate := range round {
+		producers[i] = common.HexToAddress(delegate.Address)
+	}
+	return producers, nil
+}
+
 // CanTransfer checks wether there are enough funds in the address' account to make a transfer.
 // This does not take the necessary gas in to account to make the transfer valid.
 func CanTransfer(db vm.StateDB, addr common.Address, asset *common.Address, amount *big.Int) bool {
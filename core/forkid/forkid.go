@@ -0,0 +1,189 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package forkid implements the fork identifier advertised in the handshake of
+// the aoa protocol, letting nodes of incompatible fork schedules disconnect
+// before exchanging any chain data.
+package forkid
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"sort"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+var (
+	// ErrRemoteStale is returned by the filter if a remote fork checksum is a
+	// subset of the local forks, but the remote doesn't know the next local fork.
+	ErrRemoteStale = errors.New("remote needs update")
+
+	// ErrLocalIncompatibleOrStale is returned by the filter if a remote fork
+	// checksum doesn't match any local fork, or the remote announces a fork the
+	// local node already passed without knowing it.
+	ErrLocalIncompatibleOrStale = errors.New("local incompatible or needs update")
+)
+
+// Blockchain defines the chain accessors needed to compute and validate fork
+// identifiers.
+type Blockchain interface {
+	// Config retrieves the chain's fork configuration.
+	Config() *params.ChainConfig
+
+	// Genesis retrieves the chain's genesis block.
+	Genesis() *types.Block
+
+	// CurrentHeader retrieves the current head header of the canonical chain.
+	CurrentHeader() *types.Header
+}
+
+// ID is a fork identifier, the CRC32 checksum of the genesis hash and the fork
+// blocks passed so far, along with the block number of the next fork scheduled.
+type ID struct {
+	Hash [4]byte // CRC32 checksum of the genesis block and passed fork block numbers
+	Next uint64  // Block number of the next upcoming fork, or 0 if no forks are known
+}
+
+// Filter validates a remote fork identifier against the local chain.
+type Filter func(id ID) error
+
+// NewID calculates the fork identifier of the chain with the given configuration
+// and genesis at the given head.
+func NewID(config *params.ChainConfig, genesis common.Hash, head uint64) ID {
+	hash := crc32.ChecksumIEEE(genesis[:])
+	for _, fork := range gatherForks(config) {
+		if fork <= head {
+			hash = checksumUpdate(hash, fork)
+			continue
+		}
+		return ID{Hash: checksumToBytes(hash), Next: fork}
+	}
+	return ID{Hash: checksumToBytes(hash), Next: 0}
+}
+
+// NewIDWithChain calculates the fork identifier of the chain at its current head.
+func NewIDWithChain(chain Blockchain) ID {
+	return NewID(chain.Config(), chain.Genesis().Hash(), chain.CurrentHeader().Number.Uint64())
+}
+
+// NewFilter creates a filter validating remote fork identifiers against the
+// current head of the local chain.
+func NewFilter(chain Blockchain) Filter {
+	return newFilter(chain.Config(), chain.Genesis().Hash(), func() uint64 {
+		return chain.CurrentHeader().Number.Uint64()
+	})
+}
+
+// newFilter creates a filter validating remote fork identifiers against the
+// local fork schedule at the head returned by headfn.
+//
+// A remote identifier is accepted if
+//   - it matches the local checksum at the head, and the remote doesn't announce
+//     a fork the local head already passed,
+//   - it matches a past local checksum, announcing the local fork following it,
+//     so the remote is merely behind, or
+//   - it matches a future local checksum, so the local node is merely behind.
+func newFilter(config *params.ChainConfig, genesis common.Hash, headfn func() uint64) Filter {
+	var (
+		forks = gatherForks(config)
+		sums  = make([][4]byte, len(forks)+1) // 0th is the genesis
+	)
+	hash := crc32.ChecksumIEEE(genesis[:])
+	sums[0] = checksumToBytes(hash)
+	for i, fork := range forks {
+		hash = checksumUpdate(hash, fork)
+		sums[i+1] = checksumToBytes(hash)
+	}
+	// Add a sentinel fork at the end so the loop below always finds the
+	// first fork not passed yet
+	forks = append(forks, math.MaxUint64)
+
+	return func(id ID) error {
+		head := headfn()
+		for i, fork := range forks {
+			if head >= fork {
+				continue
+			}
+			// Found the first fork not passed, accept the remote if it is on the
+			// same fork and doesn't announce a fork the local head passed already
+			if sums[i] == id.Hash {
+				if id.Next > 0 && head >= id.Next {
+					return ErrLocalIncompatibleOrStale
+				}
+				return nil
+			}
+			// Accept a remote on a past local fork if it knows the fork following it
+			for j := 0; j < i; j++ {
+				if sums[j] == id.Hash {
+					if forks[j] != id.Next {
+						return ErrRemoteStale
+					}
+					return nil
+				}
+			}
+			// Accept a remote on a future local fork, the local node is behind
+			for j := i + 1; j < len(sums); j++ {
+				if sums[j] == id.Hash {
+					return nil
+				}
+			}
+			return ErrLocalIncompatibleOrStale
+		}
+		log.Error("Impossible fork ID validation", "id", id)
+		return nil
+	}
+}
+
+// checksumUpdate extends a fork checksum with the next fork block number.
+func checksumUpdate(hash uint32, fork uint64) uint32 {
+	var blob [8]byte
+	binary.BigEndian.PutUint64(blob[:], fork)
+	return crc32.Update(hash, crc32.IEEETable, blob[:])
+}
+
+// checksumToBytes converts a fork checksum into its byte representation.
+func checksumToBytes(hash uint32) [4]byte {
+	var blob [4]byte
+	binary.BigEndian.PutUint32(blob[:], hash)
+	return blob
+}
+
+// gatherForks returns the sorted, deduplicated block numbers of all forks of
+// the chain. Forks active from the genesis are left out, they are part of the
+// genesis checksum.
+func gatherForks(config *params.ChainConfig) []uint64 {
+	var forks []uint64
+	for _, fork := range config.Forks() {
+		if fork.Block.Sign() > 0 && fork.Block.IsUint64() {
+			forks = append(forks, fork.Block.Uint64())
+		}
+	}
+	sort.Slice(forks, func(i, j int) bool { return forks[i] < forks[j] })
+
+	deduped := forks[:0]
+	for i, fork := range forks {
+		if i == 0 || fork != forks[i-1] {
+			deduped = append(deduped, fork)
+		}
+	}
+	return deduped
+}
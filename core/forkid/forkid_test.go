@@ -0,0 +1,103 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package forkid
+
+import (
+	"hash/crc32"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+var (
+	testGenesis = common.Hash{1}
+	testConfig  = &params.ChainConfig{
+		ByzantiumBlock: big.NewInt(0),
+		FeePayerBlock:  big.NewInt(100),
+		LowSBlock:      big.NewInt(100),
+		AresBlock:      big.NewInt(200),
+		AthenaBlock:    big.NewInt(300),
+	}
+)
+
+// Tests that fork identifiers change exactly at the fork blocks, with forks at
+// the genesis and duplicate fork blocks folded into one.
+func TestNewID(t *testing.T) {
+	var (
+		genesis = crc32.ChecksumIEEE(testGenesis[:])
+		first   = checksumUpdate(genesis, 100)
+		second  = checksumUpdate(first, 200)
+		third   = checksumUpdate(second, 300)
+	)
+	tests := []struct {
+		head uint64
+		want ID
+	}{
+		{0, ID{Hash: checksumToBytes(genesis), Next: 100}},
+		{99, ID{Hash: checksumToBytes(genesis), Next: 100}},
+		{100, ID{Hash: checksumToBytes(first), Next: 200}},
+		{199, ID{Hash: checksumToBytes(first), Next: 200}},
+		{200, ID{Hash: checksumToBytes(second), Next: 300}},
+		{300, ID{Hash: checksumToBytes(third), Next: 0}},
+		{math.MaxUint64, ID{Hash: checksumToBytes(third), Next: 0}},
+	}
+	for i, tt := range tests {
+		if have := NewID(testConfig, testGenesis, tt.head); have != tt.want {
+			t.Errorf("test %d: fork ID mismatch: have %x, want %x", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that remote fork identifiers are accepted or rejected depending on the
+// local head.
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		head uint64
+		id   ID
+		err  error
+	}{
+		// Local and remote on the same fork, remote knowing the next fork or not
+		{150, NewID(testConfig, testGenesis, 150), nil},
+		{150, ID{Hash: NewID(testConfig, testGenesis, 150).Hash}, nil},
+
+		// Remote on the same fork, announcing a fork the local head passed
+		{250, ID{Hash: NewID(testConfig, testGenesis, 250).Hash, Next: 260}, nil},
+		{270, ID{Hash: NewID(testConfig, testGenesis, 250).Hash, Next: 260}, ErrLocalIncompatibleOrStale},
+
+		// Remote behind, knowing the local fork following it
+		{250, NewID(testConfig, testGenesis, 150), nil},
+
+		// Remote behind, not knowing the local fork following it
+		{250, ID{Hash: NewID(testConfig, testGenesis, 150).Hash, Next: 220}, ErrRemoteStale},
+		{250, ID{Hash: NewID(testConfig, testGenesis, 150).Hash}, ErrRemoteStale},
+
+		// Local behind, remote already on a fork known locally
+		{50, NewID(testConfig, testGenesis, 350), nil},
+
+		// Remote on a different chain altogether
+		{150, ID{Hash: [4]byte{0xde, 0xad, 0xbe, 0xef}}, ErrLocalIncompatibleOrStale},
+	}
+	for i, tt := range tests {
+		filter := newFilter(testConfig, testGenesis, func() uint64 { return tt.head })
+		if err := filter(tt.id); err != tt.err {
+			t.Errorf("test %d: validation error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
@@ -18,6 +18,7 @@ package core
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -27,7 +28,9 @@ import (
 	"github.com/Aurorachain-io/go-aoa/common/math"
 	"github.com/Aurorachain-io/go-aoa/consensus/delegatestate"
 	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/systemcontracts"
 	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/crypto"
 	"github.com/Aurorachain-io/go-aoa/crypto/sha3"
 	"github.com/Aurorachain-io/go-aoa/aoadb"
 	"github.com/Aurorachain-io/go-aoa/log"
@@ -155,6 +158,14 @@ func SetupGenesisBlock(db aoadb.Database, genesis *Genesis) (*params.ChainConfig
 	if genesis != nil && genesis.Config == nil {
 		return params.AllDacchainProtocolChanges, common.Hash{}, genesis, errGenesisNoConfig
 	}
+	if genesis != nil {
+		if err := genesis.Validate(); err != nil {
+			return genesis.Config, common.Hash{}, genesis, err
+		}
+		if err := systemcontracts.Validate(genesis.Config); err != nil {
+			return genesis.Config, common.Hash{}, genesis, err
+		}
+	}
 
 	// Just commit the new block if there is no stored genesis block.
 	stored := GetCanonicalHash(db, 0)
@@ -166,12 +177,18 @@ func SetupGenesisBlock(db aoadb.Database, genesis *Genesis) (*params.ChainConfig
 			log.Info("Writing custom genesis block")
 		}
 		block, err := genesis.Commit(db)
-		return genesis.Config, block.Hash(), genesis, err
+		if err != nil {
+			return genesis.Config, common.Hash{}, genesis, err
+		}
+		return genesis.Config, block.Hash(), genesis, nil
 	}
 
 	// Check whether the genesis block is already written.
 	if genesis != nil {
-		block, _, _ := genesis.ToBlock()
+		block, _, _, err := genesis.ToBlock()
+		if err != nil {
+			return genesis.Config, common.Hash{}, genesis, err
+		}
 		hash := block.Hash()
 		if hash != stored {
 			return genesis.Config, block.Hash(), genesis, &GenesisMismatchError{stored, hash}
@@ -201,6 +218,11 @@ func SetupGenesisBlock(db aoadb.Database, genesis *Genesis) (*params.ChainConfig
 	if height == missingNumber {
 		return newcfg, stored, genesis, fmt.Errorf("missing block number for head header hash")
 	}
+	if height != 0 {
+		if err := storedcfg.CheckConsensusParams(newcfg); err != nil {
+			return newcfg, stored, genesis, err
+		}
+	}
 	compatErr := storedcfg.CheckCompatible(newcfg, height)
 	if compatErr != nil && height != 0 && compatErr.RewindTo != 0 {
 		return newcfg, stored, genesis, compatErr
@@ -222,7 +244,11 @@ func (g *Genesis) configOrDefault(ghash common.Hash) *params.ChainConfig {
 }
 
 // ToBlock creates the block and state of a genesis specification.
-func (g *Genesis) ToBlock() (*types.Block, *state.StateDB, *delegatestate.DelegateDB) {
+func (g *Genesis) ToBlock() (*types.Block, *state.StateDB, *delegatestate.DelegateDB, error) {
+	config := g.Config
+	if config == nil {
+		config = params.AllDacchainProtocolChanges
+	}
 	db, _ := aoadb.NewMemDatabase()
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
 	delegatedb, _ := delegatestate.New(common.Hash{}, delegatestate.NewDatabase(db))
@@ -234,6 +260,10 @@ func (g *Genesis) ToBlock() (*types.Block, *state.StateDB, *delegatestate.Delega
 			statedb.SetState(addr, key, value)
 		}
 	}
+	// deploy the system contracts scheduled at the genesis block
+	if err := systemcontracts.Upgrade(config, new(big.Int).SetUint64(g.Number), statedb); err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot deploy system contracts: %v", err)
+	}
 	root := statedb.IntermediateRoot(false)
 	// add agents
 	for _, agent := range g.Agents {
@@ -243,12 +273,11 @@ func (g *Genesis) ToBlock() (*types.Block, *state.StateDB, *delegatestate.Delega
 	}
 	delegateRoot := delegatedb.IntermediateRoot(false)
 	topDelegates := delegatedb.GetDelegates()
-	config := g.Config
 	MaxElectDelegate := config.MaxElectDelegate.Int64()
 	if len(topDelegates) > int(MaxElectDelegate) {
 		topDelegates = topDelegates[:int(MaxElectDelegate)]
 	}
-	shuffleNewRound := util.ShuffleNewRound(int64(g.Timestamp), int(MaxElectDelegate), topDelegates, config.BlockInterval.Int64())
+	shuffleNewRound := util.ShuffleNewRound(config.ShuffleVersion(new(big.Int).SetUint64(g.Number)), int64(g.Timestamp), int(MaxElectDelegate), topDelegates, config.BlockInterval.Int64())
 	shuffleList := types.ShuffleList{ShuffleDels: shuffleNewRound}
 	rlpShufflehash := rlpHash(shuffleList)
 
@@ -272,13 +301,71 @@ func (g *Genesis) ToBlock() (*types.Block, *state.StateDB, *delegatestate.Delega
 	if g.GasLimit == 0 {
 		head.GasLimit = params.GenesisGasLimit
 	}
-	return types.NewBlock(head, nil, nil), statedb, delegatedb
+	return types.NewBlock(head, nil, nil), statedb, delegatedb, nil
+}
+
+// ExportGenesis assembles a genesis specification recreating the state and the
+// delegate set of the given block, to start a fresh chain from it (regenesis).
+// A genesis can't carry vote lists, so locked vote balances are returned to the
+// voters while the delegates keep their votes. States holding assets can't be
+// exported.
+func ExportGenesis(config *params.ChainConfig, header *types.Header, statedb *state.StateDB, delegatedb *delegatestate.DelegateDB) (*Genesis, error) {
+	// System contract forks activated up to the block are part of its state
+	cfg := *config
+	cfg.SystemContractForks = nil
+	for _, fork := range config.SystemContractForks {
+		if fork.Block != nil && fork.Block.Cmp(header.Number) > 0 {
+			cfg.SystemContractForks = append(cfg.SystemContractForks, fork)
+		}
+	}
+	genesis := &Genesis{
+		Config:    &cfg,
+		Timestamp: header.Time.Uint64(),
+		ExtraData: []byte(genesisExtra),
+		GasLimit:  header.GasLimit,
+		Alloc:     make(GenesisAlloc),
+		Agents:    delegatedb.GetDelegates(),
+	}
+	err := statedb.ForEachAccount(func(addr common.Address) error {
+		if statedb.IsAssetAccount(addr) || len(statedb.GetAssets(addr)) > 0 {
+			return fmt.Errorf("account %x holds assets", addr)
+		}
+		account := GenesisAccount{
+			Code:    statedb.GetCode(addr),
+			Balance: new(big.Int).Add(statedb.GetBalance(addr), statedb.GetLockBalance(addr)),
+			Nonce:   statedb.GetNonce(addr),
+		}
+		err := statedb.ForEachStorage(addr, func(key, value common.Hash) bool {
+			if (value != common.Hash{}) {
+				if account.Storage == nil {
+					account.Storage = make(map[common.Hash]common.Hash)
+				}
+				account.Storage[key] = value
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		genesis.Alloc[addr] = account
+		return nil
+	})
+	if err == nil {
+		err = statedb.Error()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return genesis, nil
 }
 
 // Commit writes the block and state of a genesis specification to the database.
 // The block is committed as the canonical head block.
 func (g *Genesis) Commit(db aoadb.Database) (*types.Block, error) {
-	block, statedb, delegatedb := g.ToBlock()
+	block, statedb, delegatedb, err := g.ToBlock()
+	if err != nil {
+		return nil, err
+	}
 	if block.Number().Sign() != 0 {
 		return nil, fmt.Errorf("can't commit genesis block with number > 0")
 	}
@@ -370,25 +457,49 @@ func DefaultRinkebyGenesisBlock() *Genesis {
 	}
 }
 
-// DeveloperGenesisBlock returns the 'em --dev' genesis block. Note, this must
-// be seeded with the
-func DeveloperGenesisBlock(developer common.Address) *Genesis {
+// DeveloperAccounts is the number of deterministic accounts pre-funded in the
+// genesis of developer chains.
+const DeveloperAccounts = 10
+
+// DeveloperKey returns the private key of the i-th deterministic account funded
+// by DeveloperGenesisBlock. The keys are public, never use them outside of
+// developer chains.
+func DeveloperKey(i int) *ecdsa.PrivateKey {
+	key, err := crypto.ToECDSA(crypto.Keccak256([]byte(fmt.Sprintf("aoa developer account %d", i))))
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// DeveloperGenesisBlock returns the 'aoa --dev' genesis block. The developer is
+// the single delegate of the chain, sealing blocks instantly or every period
+// seconds. Both the developer and the deterministic DeveloperKey accounts are
+// pre-funded.
+func DeveloperGenesisBlock(period uint64, developer common.Address) *Genesis {
 	// Override the default period to the user requested one
 	config := *params.AllDacchainProtocolChanges
+	config.Instant = &params.InstantConfig{Period: period}
+
 	encodeBytes := hexutil.Encode([]byte(genesisExtra))
 	agentName := hexutil.MustDecode(encodeBytes)
 	var geneAgents GenesisAgents
 	candidates := make([]types.Candidate, 0)
 	candidates = append(candidates, types.Candidate{Address: strings.ToLower(developer.Hex()), Vote: 1, Nickname: developer.Hex(), RegisterTime: uint64(11111112111)})
 	geneAgents = append(geneAgents, candidates...)
-	// Assemble and return the genesis with the precompiles and faucet pre-funded
+
+	// Assemble and return the genesis with the developer accounts pre-funded
+	balance := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(9))
+	alloc := make(GenesisAlloc)
+	for i := 0; i < DeveloperAccounts; i++ {
+		alloc[crypto.PubkeyToAddress(DeveloperKey(i).PublicKey)] = GenesisAccount{Balance: new(big.Int).Rsh(balance, 8)}
+	}
+	alloc[developer] = GenesisAccount{Balance: balance}
 	return &Genesis{
 		Config:    &config,
 		ExtraData: agentName,
 		GasLimit:  250000000,
-		Alloc: map[common.Address]GenesisAccount{
-			developer: {Balance: new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(9))},
-		},
+		Alloc:     alloc,
 		Agents:    geneAgents,
 		Timestamp: 1492009146,
 	}
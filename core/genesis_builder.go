@@ -0,0 +1,170 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+var (
+	errGenesisNoDelegates = errors.New("genesis has no delegates")
+	errGenesisNoBalance   = errors.New("genesis account without balance")
+)
+
+// GenesisBuilder assembles the genesis specification of a private network from
+// its chain configuration, pre-funded accounts and bootstrap delegates, instead
+// of hand-crafting the RLP encoded allocations and agents.
+type GenesisBuilder struct {
+	genesis *Genesis
+	err     error // First error encountered while building, returned by Build
+}
+
+// NewGenesisBuilder creates a builder of a genesis block running the given chain
+// configuration, with the default genesis gas limit and extra-data.
+func NewGenesisBuilder(config *params.ChainConfig) *GenesisBuilder {
+	return &GenesisBuilder{
+		genesis: &Genesis{
+			Config:    config,
+			ExtraData: []byte(genesisExtra),
+			GasLimit:  params.GenesisGasLimit,
+			Alloc:     make(GenesisAlloc),
+		},
+	}
+}
+
+// Timestamp sets the time of the genesis block, which also is the registration
+// time of the bootstrap delegates and the start of the first delegate round.
+func (b *GenesisBuilder) Timestamp(timestamp uint64) *GenesisBuilder {
+	b.genesis.Timestamp = timestamp
+	return b
+}
+
+// GasLimit sets the gas limit of the genesis block.
+func (b *GenesisBuilder) GasLimit(gasLimit uint64) *GenesisBuilder {
+	b.genesis.GasLimit = gasLimit
+	return b
+}
+
+// ExtraData sets the extra-data of the genesis block.
+func (b *GenesisBuilder) ExtraData(extra []byte) *GenesisBuilder {
+	b.genesis.ExtraData = common.CopyBytes(extra)
+	return b
+}
+
+// Fund pre-funds the given account with balance, adding up multiple fundings of
+// the same account.
+func (b *GenesisBuilder) Fund(addr common.Address, balance *big.Int) *GenesisBuilder {
+	if balance == nil || balance.Sign() <= 0 {
+		b.fail(fmt.Errorf("%v: %x", errGenesisNoBalance, addr))
+		return b
+	}
+	account := b.genesis.Alloc[addr]
+	if account.Balance == nil {
+		account.Balance = new(big.Int)
+	}
+	account.Balance = new(big.Int).Add(account.Balance, balance)
+	b.genesis.Alloc[addr] = account
+	return b
+}
+
+// Deploy places the given contract code into the genesis state.
+func (b *GenesisBuilder) Deploy(addr common.Address, code []byte, storage map[common.Hash]common.Hash) *GenesisBuilder {
+	account := b.genesis.Alloc[addr]
+	if account.Balance == nil {
+		account.Balance = new(big.Int)
+	}
+	account.Code, account.Storage = common.CopyBytes(code), storage
+	b.genesis.Alloc[addr] = account
+	return b
+}
+
+// Delegate registers a bootstrap delegate with the given nickname and number of
+// votes, electing it into the first delegate rounds of the network.
+func (b *GenesisBuilder) Delegate(addr common.Address, nickname string, votes uint64) *GenesisBuilder {
+	b.genesis.Agents = append(b.genesis.Agents, types.Candidate{
+		Address:  strings.ToLower(addr.Hex()),
+		Vote:     votes,
+		Nickname: nickname,
+	})
+	return b
+}
+
+// Build validates and returns the assembled genesis specification.
+func (b *GenesisBuilder) Build() (*Genesis, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	genesis := *b.genesis
+	genesis.Agents = make(GenesisAgents, len(b.genesis.Agents))
+	for i, agent := range b.genesis.Agents {
+		agent.RegisterTime = genesis.Timestamp
+		genesis.Agents[i] = agent
+	}
+	if len(genesis.Agents) == 0 {
+		return nil, errGenesisNoDelegates
+	}
+	if err := genesis.Validate(); err != nil {
+		return nil, err
+	}
+	return &genesis, nil
+}
+
+func (b *GenesisBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// Validate checks whether the genesis specification describes a chain able to
+// produce blocks: its chain configuration and bootstrap delegates must be valid.
+func (g *Genesis) Validate() error {
+	if g.Config == nil {
+		return errGenesisNoConfig
+	}
+	if err := g.Config.Validate(); err != nil {
+		return err
+	}
+	return g.Agents.Validate()
+}
+
+// Validate checks whether the bootstrap delegates are well formed and registered
+// only once.
+func (ga GenesisAgents) Validate() error {
+	seen := make(map[common.Address]bool, len(ga))
+	for _, agent := range ga {
+		if !common.IsHexAddress(agent.Address) && !common.IsAoaAddress(agent.Address) {
+			return fmt.Errorf("invalid genesis delegate address %q", agent.Address)
+		}
+		addr := common.HexToAddress(agent.Address)
+		if seen[addr] {
+			return fmt.Errorf("duplicate genesis delegate %x", addr)
+		}
+		seen[addr] = true
+
+		if agent.Nickname == "" {
+			return fmt.Errorf("genesis delegate %x without nickname", addr)
+		}
+	}
+	return nil
+}
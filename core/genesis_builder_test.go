@@ -0,0 +1,100 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// Tests that a genesis assembled by the builder survives a JSON round trip and
+// bootstraps its delegates into the genesis delegate state.
+func TestGenesisBuilder(t *testing.T) {
+	config := *params.AllDacchainProtocolChanges
+	config.MaxElectDelegate = big.NewInt(2)
+
+	genesis, err := NewGenesisBuilder(&config).
+		Timestamp(1600000000).
+		Fund(common.Address{0x01}, big.NewInt(1000)).
+		Fund(common.Address{0x01}, big.NewInt(500)).
+		Delegate(common.Address{0xaa}, "alice", 2).
+		Delegate(common.Address{0xbb}, "bob", 1).
+		Build()
+	if err != nil {
+		t.Fatalf("failed to build genesis: %v", err)
+	}
+	if balance := genesis.Alloc[common.Address{0x01}].Balance; balance.Cmp(big.NewInt(1500)) != 0 {
+		t.Errorf("balance mismatch: have %v, want 1500", balance)
+	}
+	blob, err := json.Marshal(genesis)
+	if err != nil {
+		t.Fatalf("failed to encode genesis: %v", err)
+	}
+	decoded := new(Genesis)
+	if err := json.Unmarshal(blob, decoded); err != nil {
+		t.Fatalf("failed to decode genesis: %v", err)
+	}
+	block, _, delegates, _ := genesis.ToBlock()
+	if decodedBlock, _, _, _ := decoded.ToBlock(); decodedBlock.Hash() != block.Hash() {
+		t.Fatalf("genesis hash mismatch after JSON round trip: have %x, want %x", decodedBlock.Hash(), block.Hash())
+	}
+	elected := delegates.GetDelegates()
+	if len(elected) != 2 || elected[0].Nickname != "alice" || elected[0].RegisterTime != 1600000000 {
+		t.Errorf("bootstrap delegates mismatch: have %+v", elected)
+	}
+	// Setting up the genesis is idempotent, but rejects a different one
+	db, _ := aoadb.NewMemDatabase()
+	if _, hash, _, err := SetupGenesisBlock(db, genesis); err != nil || hash != block.Hash() {
+		t.Fatalf("failed to set up genesis: hash %x, err %v", hash, err)
+	}
+	if _, _, _, err := SetupGenesisBlock(db, decoded); err != nil {
+		t.Fatalf("failed to set up matching genesis: %v", err)
+	}
+	other, _ := NewGenesisBuilder(&config).Delegate(common.Address{0xcc}, "carol", 1).Build()
+	if _, _, _, err := SetupGenesisBlock(db, other); err == nil {
+		t.Fatalf("mismatching genesis accepted")
+	} else if _, ok := err.(*GenesisMismatchError); !ok {
+		t.Fatalf("error mismatch: have %v, want *GenesisMismatchError", err)
+	}
+}
+
+// Tests that malformed genesis specifications are rejected by the builder.
+func TestGenesisBuilderInvalid(t *testing.T) {
+	config := params.AllDacchainProtocolChanges
+	tests := []*GenesisBuilder{
+		NewGenesisBuilder(config),
+		NewGenesisBuilder(config).Delegate(common.Address{0xaa}, "", 1),
+		NewGenesisBuilder(config).Delegate(common.Address{0xaa}, "alice", 1).Delegate(common.Address{0xaa}, "bob", 1),
+		NewGenesisBuilder(config).Delegate(common.Address{0xaa}, "alice", 1).Fund(common.Address{0x01}, big.NewInt(0)),
+		NewGenesisBuilder(&params.ChainConfig{}).Delegate(common.Address{0xaa}, "alice", 1),
+	}
+	for i, builder := range tests {
+		if _, err := builder.Build(); err == nil {
+			t.Errorf("test %d: invalid genesis accepted", i)
+		}
+	}
+	for _, genesis := range []*Genesis{DefaultGenesisBlock(), DefaultTestnetGenesisBlock(), DefaultRinkebyGenesisBlock()} {
+		if err := genesis.Validate(); err != nil {
+			t.Errorf("built-in genesis rejected: %v", err)
+		}
+	}
+}
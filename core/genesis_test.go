@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bytes"
 	"math/big"
 	"reflect"
 	"testing"
@@ -25,8 +26,11 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"github.com/Aurorachain-io/go-aoa/common"
 	"github.com/Aurorachain-io/go-aoa/common/hexutil"
+	"github.com/Aurorachain-io/go-aoa/consensus/delegatestate"
+	"github.com/Aurorachain-io/go-aoa/core/state"
 	"github.com/Aurorachain-io/go-aoa/core/types"
-	"github.com/Aurorachain-io/go-aoa/emdb"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/aoadb"
 	"github.com/Aurorachain-io/go-aoa/params"
 	"github.com/Aurorachain-io/go-aoa/rlp"
 	"io/ioutil"
@@ -49,11 +53,11 @@ func TestDefaultGenesisBlock(t *testing.T) {
 	bytes, _ := genesis.MarshalJSON()
 	json := string(bytes)
 	fmt.Println(json)
-	block, _, _ := genesis.ToBlock()
+	block, _, _, _ := genesis.ToBlock()
 	if block.Hash() != params.MainnetGenesisHash {
 		t.Errorf("wrong mainnet genesis hash, got %v, want %v", block.Hash(), params.MainnetGenesisHash)
 	}
-	block, _, _ = DefaultTestnetGenesisBlock().ToBlock()
+	block, _, _, _ = DefaultTestnetGenesisBlock().ToBlock()
 	if block.Hash() != params.TestnetGenesisHash {
 		t.Errorf("wrong testnet genesis hash, got %v, want %v", block.Hash(), params.TestnetGenesisHash)
 	}
@@ -68,11 +72,11 @@ func TestDefaultGenesisBlock(t *testing.T) {
 
 func TestDefaultGenesisBlock2(t *testing.T) {
 	genesis := DefaultGenesisBlock()
-	block, _, _ := genesis.ToBlock()
+	block, _, _, _ := genesis.ToBlock()
 	fmt.Println("main genesisBlockHash = ", block.Hash().Hex())
 
 	genesis2 := DefaultTestnetGenesisBlock()
-	block2, _, _ := genesis2.ToBlock()
+	block2, _, _, _ := genesis2.ToBlock()
 	fmt.Println("test genesisBlockHash = ", block2.Hash().Hex())
 }
 
@@ -119,14 +123,14 @@ func TestSetupGenesis(t *testing.T) {
 	oldcustomg.Config = &params.ChainConfig{}
 	tests := []struct {
 		name       string
-		fn         func(emdb.Database) (*params.ChainConfig, common.Hash, *Genesis, error)
+		fn         func(aoadb.Database) (*params.ChainConfig, common.Hash, *Genesis, error)
 		wantConfig *params.ChainConfig
 		wantHash   common.Hash
 		wantErr    error
 	}{
 		{
 			name: "genesis without ChainConfig",
-			fn: func(db emdb.Database) (*params.ChainConfig, common.Hash, *Genesis, error) {
+			fn: func(db aoadb.Database) (*params.ChainConfig, common.Hash, *Genesis, error) {
 				return SetupGenesisBlock(db, new(Genesis))
 			},
 			wantErr: errGenesisNoConfig,
@@ -134,7 +138,7 @@ func TestSetupGenesis(t *testing.T) {
 		},
 		{
 			name: "no block in DB, genesis == nil",
-			fn: func(db emdb.Database) (*params.ChainConfig, common.Hash, *Genesis, error) {
+			fn: func(db aoadb.Database) (*params.ChainConfig, common.Hash, *Genesis, error) {
 				return SetupGenesisBlock(db, nil)
 			},
 			wantHash:   params.MainnetGenesisHash,
@@ -142,7 +146,7 @@ func TestSetupGenesis(t *testing.T) {
 		},
 		{
 			name: "mainnet block in DB, genesis == nil",
-			fn: func(db emdb.Database) (*params.ChainConfig, common.Hash, *Genesis, error) {
+			fn: func(db aoadb.Database) (*params.ChainConfig, common.Hash, *Genesis, error) {
 				DefaultGenesisBlock().MustCommit(db)
 				return SetupGenesisBlock(db, nil)
 			},
@@ -151,7 +155,7 @@ func TestSetupGenesis(t *testing.T) {
 		},
 		{
 			name: "custom block in DB, genesis == nil",
-			fn: func(db emdb.Database) (*params.ChainConfig, common.Hash, *Genesis, error) {
+			fn: func(db aoadb.Database) (*params.ChainConfig, common.Hash, *Genesis, error) {
 				customg.MustCommit(db)
 				return SetupGenesisBlock(db, nil)
 			},
@@ -160,7 +164,7 @@ func TestSetupGenesis(t *testing.T) {
 		},
 		{
 			name: "custom block in DB, genesis == testnet",
-			fn: func(db emdb.Database) (*params.ChainConfig, common.Hash, *Genesis, error) {
+			fn: func(db aoadb.Database) (*params.ChainConfig, common.Hash, *Genesis, error) {
 				customg.MustCommit(db)
 				return SetupGenesisBlock(db, DefaultTestnetGenesisBlock())
 			},
@@ -170,7 +174,7 @@ func TestSetupGenesis(t *testing.T) {
 		},
 		{
 			name: "compatible config in DB",
-			fn: func(db emdb.Database) (*params.ChainConfig, common.Hash, *Genesis, error) {
+			fn: func(db aoadb.Database) (*params.ChainConfig, common.Hash, *Genesis, error) {
 				oldcustomg.MustCommit(db)
 				return SetupGenesisBlock(db, &customg)
 			},
@@ -180,7 +184,7 @@ func TestSetupGenesis(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		db, _ := emdb.NewMemDatabase()
+		db, _ := aoadb.NewMemDatabase()
 		config, hash, _, err := test.fn(db)
 		// Check the return values.
 		if !reflect.DeepEqual(err, test.wantErr) {
@@ -204,7 +208,7 @@ func TestSetupGenesis(t *testing.T) {
 
 func TestDefaultTestnetGenesisBlock(t *testing.T) {
 	testnetGenesisBlock := DefaultTestnetGenesisBlock()
-	block, _, _ := testnetGenesisBlock.ToBlock()
+	block, _, _, _ := testnetGenesisBlock.ToBlock()
 	fmt.Println(block.Hash().Hex())
 	//decode := hexutil.MustDecode("0x11bbe8db4e347b4e8c937c1c8370e4b5ed33adb3db69cbdb7a38e1e50b1b82fa")
 	//fmt.Println(string(bitutil.CompressBytes(decode)))
@@ -212,7 +216,7 @@ func TestDefaultTestnetGenesisBlock(t *testing.T) {
 
 func TestDefaultGenesisBlock3(t *testing.T) {
 	defaultGenesisBlock := DefaultGenesisBlock()
-	block, _, _ := defaultGenesisBlock.ToBlock()
+	block, _, _, _ := defaultGenesisBlock.ToBlock()
 	fmt.Println(block.Hash().Hex())
 }
 
@@ -255,3 +259,102 @@ func TestAmountToHex(t *testing.T) {
 	result := fmt.Sprintf("%x", amount)
 	fmt.Println(result)
 }
+
+// Tests that a genesis exported from the state of a block recreates the same
+// state and delegate set, also after a round trip through JSON.
+func TestExportGenesis(t *testing.T) {
+	contract := common.HexToAddress("0x1000")
+	genesis := &Genesis{
+		Config:    params.AllDacchainProtocolChanges,
+		Timestamp: 1600000000,
+		GasLimit:  params.GenesisGasLimit,
+		Alloc: GenesisAlloc{
+			common.HexToAddress("0x01"): {Balance: big.NewInt(1000), Nonce: 3},
+			contract: {
+				Code:    []byte{0x60, 0x00},
+				Balance: big.NewInt(1),
+				Storage: map[common.Hash]common.Hash{common.HexToHash("0x01"): common.HexToHash("0x02")},
+			},
+		},
+		Agents: decodeGenesisAgents(mainnetAgentData)[:3],
+	}
+	db, _ := aoadb.NewMemDatabase()
+	block := genesis.MustCommit(db)
+
+	statedb, err := state.New(block.Root(), state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	delegatedb, err := delegatestate.New(block.Header().DelegateRoot, delegatestate.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to open delegate state: %v", err)
+	}
+	exported, err := ExportGenesis(genesis.Config, block.Header(), statedb, delegatedb)
+	if err != nil {
+		t.Fatalf("failed to export genesis: %v", err)
+	}
+	blob, err := json.Marshal(exported)
+	if err != nil {
+		t.Fatalf("failed to encode genesis: %v", err)
+	}
+	var decoded Genesis
+	if err := json.Unmarshal(blob, &decoded); err != nil {
+		t.Fatalf("failed to decode genesis: %v", err)
+	}
+	for i, spec := range []*Genesis{exported, &decoded} {
+		regenesis, _, _, _ := spec.ToBlock()
+		if regenesis.Root() != block.Root() {
+			t.Errorf("spec %d: state root mismatch: have %x, want %x", i, regenesis.Root(), block.Root())
+		}
+		if regenesis.Header().DelegateRoot != block.Header().DelegateRoot {
+			t.Errorf("spec %d: delegate root mismatch: have %x, want %x", i, regenesis.Header().DelegateRoot, block.Header().DelegateRoot)
+		}
+	}
+	if code := exported.Alloc[contract].Code; !bytes.Equal(code, []byte{0x60, 0x00}) {
+		t.Errorf("contract code mismatch: have %x", code)
+	}
+}
+
+// Tests that developer genesis blocks are deterministic, sealing instantly and
+// pre-funding both the developer and the well known developer accounts.
+func TestDeveloperGenesisDeterministic(t *testing.T) {
+	developer := common.Address{0xde, 0xad}
+
+	first, _, _, _ := DeveloperGenesisBlock(5, developer).ToBlock()
+	second, statedb, _, _ := DeveloperGenesisBlock(5, developer).ToBlock()
+	if first.Hash() != second.Hash() {
+		t.Fatalf("genesis hash mismatch: %x != %x", first.Hash(), second.Hash())
+	}
+	if config := DeveloperGenesisBlock(5, developer).Config; config.Instant == nil || config.Instant.Period != 5 {
+		t.Fatalf("instant sealing config mismatch: have %+v", config.Instant)
+	}
+	if statedb.GetBalance(developer).Sign() <= 0 {
+		t.Errorf("developer %x not funded", developer)
+	}
+	for i := 0; i < DeveloperAccounts; i++ {
+		addr := crypto.PubkeyToAddress(DeveloperKey(i).PublicKey)
+		if statedb.GetBalance(addr).Sign() <= 0 {
+			t.Errorf("developer account %d (%x) not funded", i, addr)
+		}
+	}
+}
+
+// Tests that a genesis scheduling system contract code at a non-reserved
+// address fails to build instead of silently skipping the deployment.
+func TestGenesisSystemContractsRejected(t *testing.T) {
+	config := *params.AllDacchainProtocolChanges
+	config.SystemContractForks = []params.SystemContractsFork{
+		{Block: big.NewInt(0), Code: map[common.Address]hexutil.Bytes{{0x01}: {0x00}}},
+	}
+	genesis := &Genesis{Config: &config}
+	if _, _, _, err := genesis.ToBlock(); err == nil {
+		t.Errorf("genesis with non-reserved system contract built")
+	}
+	db, _ := aoadb.NewMemDatabase()
+	if _, err := genesis.Commit(db); err == nil {
+		t.Errorf("genesis with non-reserved system contract committed")
+	}
+	if stored := GetCanonicalHash(db, 0); (stored != common.Hash{}) {
+		t.Errorf("genesis block written: %x", stored)
+	}
+}
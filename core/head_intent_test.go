@@ -0,0 +1,153 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus/dpos"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/core/vm"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// newHeadIntentChain imports a short chain of value transfers into a fresh
+// database, returning the database, the running chain and the blocks imported.
+func newHeadIntentChain(t *testing.T, cacheConfig *CacheConfig) (*aoadb.MemDatabase, *BlockChain, types.Blocks) {
+	var (
+		db, _  = aoadb.NewMemDatabase()
+		config = params.AllDacchainProtocolChanges
+		signer = types.MakeSigner(config, big.NewInt(1))
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+	)
+	gspec := &Genesis{
+		Config: config,
+		Alloc:  GenesisAlloc{addr: {Balance: big.NewInt(params.Em)}},
+		Agents: GenesisAgents{{Address: "0x0200", Vote: 1, Nickname: "test"}},
+	}
+	genesis := gspec.MustCommit(db)
+
+	// Generate the blocks on a separate database to keep their states out of db
+	gendb, _ := aoadb.NewMemDatabase()
+	gspec.MustCommit(gendb)
+	blocks, _ := GenerateChain(config, genesis, dpos.New(), gendb, 4, func(i int, gen *BlockGen) {
+		tx := types.NewTransaction(gen.TxNonce(addr), common.HexToAddress("0x01"), big.NewInt(10), 100000, big.NewInt(1), nil, types.ActionTrans, nil, "")
+		tx, _ = types.SignTx(tx, signer, key)
+		gen.AddTx(tx)
+	})
+	chain, err := NewBlockChain(db, cacheConfig, config, dpos.New(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	if GetHeadIntent(db) != (common.Hash{}) {
+		t.Fatalf("head intent left behind after import")
+	}
+	return db, chain, blocks
+}
+
+// interruptHeadUpdate rewinds the database to the point where the last block
+// was committed together with its head intent, but never marked as the head.
+func interruptHeadUpdate(db *aoadb.MemDatabase, blocks types.Blocks) {
+	head, parent := blocks[len(blocks)-1], blocks[len(blocks)-2]
+
+	DeleteCanonicalHash(db, head.NumberU64())
+	WriteHeadBlockHash(db, parent.Hash())
+	WriteHeadFastBlockHash(db, parent.Hash())
+	WriteHeadHeaderHash(db, parent.Hash())
+	WriteHeadIntent(db, head.Hash())
+}
+
+// checkHeadResolved reopens the chain and checks that it is headed by want, with
+// the head intent cleared.
+func checkHeadResolved(t *testing.T, db *aoadb.MemDatabase, cacheConfig *CacheConfig, want *types.Block) {
+	chain, err := NewBlockChain(db, cacheConfig, params.AllDacchainProtocolChanges, dpos.New(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to reopen chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if head := chain.CurrentBlock(); head.Hash() != want.Hash() {
+		t.Errorf("head mismatch: have #%d [%x…], want #%d [%x…]", head.NumberU64(), head.Hash().Bytes()[:4], want.NumberU64(), want.Hash().Bytes()[:4])
+	}
+	if hash := GetCanonicalHash(db, want.NumberU64()); hash != want.Hash() {
+		t.Errorf("canonical hash mismatch: have %x, want %x", hash, want.Hash())
+	}
+	if hash := GetHeadBlockHash(db); hash != want.Hash() {
+		t.Errorf("stored head mismatch: have %x, want %x", hash, want.Hash())
+	}
+	if !chain.HasBlockAndState(want.Hash()) {
+		t.Errorf("head state missing")
+	}
+	if hash := GetHeadIntent(db); hash != (common.Hash{}) {
+		t.Errorf("head intent not cleared: %x", hash)
+	}
+}
+
+// Tests that a crash after committing a block and its state, but before marking
+// it as the head, is resolved by rolling the head forward.
+func TestHeadIntentRollForward(t *testing.T) {
+	cacheConfig := &CacheConfig{Disabled: true}
+
+	db, chain, blocks := newHeadIntentChain(t, cacheConfig)
+	chain.Stop()
+
+	interruptHeadUpdate(db, blocks)
+	checkHeadResolved(t, db, cacheConfig, blocks[len(blocks)-1])
+}
+
+// Tests that a crash after committing a block, but losing the state flushed
+// along with it, is resolved by re-executing the blocks up to it.
+func TestHeadIntentReexecute(t *testing.T) {
+	// Keep the states in memory only and never stop the chain, losing them
+	db, chain, blocks := newHeadIntentChain(t, nil)
+	defer chain.Stop()
+
+	interruptHeadUpdate(db, blocks)
+	checkHeadResolved(t, db, nil, blocks[len(blocks)-1])
+}
+
+// Tests that a crash after marking the head, but before clearing the intent, is
+// resolved by dropping the stale intent.
+func TestHeadIntentStale(t *testing.T) {
+	cacheConfig := &CacheConfig{Disabled: true}
+
+	db, chain, blocks := newHeadIntentChain(t, cacheConfig)
+	chain.Stop()
+
+	WriteHeadIntent(db, blocks[len(blocks)-1].Hash())
+	checkHeadResolved(t, db, cacheConfig, blocks[len(blocks)-1])
+}
+
+// Tests that an intent for a block never committed is discarded without
+// touching the head.
+func TestHeadIntentMissingBlock(t *testing.T) {
+	cacheConfig := &CacheConfig{Disabled: true}
+
+	db, chain, blocks := newHeadIntentChain(t, cacheConfig)
+	chain.Stop()
+
+	WriteHeadIntent(db, common.HexToHash("0xdeadbeef"))
+	checkHeadResolved(t, db, cacheConfig, blocks[len(blocks)-1])
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/log"
+)
+
+// SetHistoryLimit sets the number of recent blocks whose bodies and receipts are
+// retained. Older ones are pruned in the background as the head advances, moving
+// the history tail advertised to peers; zero retains the entire history.
+func (bc *BlockChain) SetHistoryLimit(limit uint64) {
+	atomic.StoreUint64(&bc.historyLimit, limit)
+
+	select {
+	case bc.txIndexReq <- struct{}{}:
+	default:
+	}
+}
+
+// HistoryLimit returns the number of recent blocks whose bodies and receipts are
+// retained, zero meaning the entire chain.
+func (bc *BlockChain) HistoryLimit() uint64 {
+	return atomic.LoadUint64(&bc.historyLimit)
+}
+
+// pruneHistory deletes the bodies, receipts and transaction lookup entries of the
+// canonical blocks falling out of the configured history limit relative to the
+// given head, moving the stored history tail as it goes. The genesis block is
+// always retained.
+func (bc *BlockChain) pruneHistory(head uint64) {
+	limit := bc.HistoryLimit()
+	if limit == 0 || head+1 <= limit {
+		return
+	}
+	from, to := GetHistoryTail(bc.chainDb), head-limit+1
+	if from == 0 {
+		from = 1
+	}
+	if from >= to {
+		return
+	}
+	var (
+		start  = time.Now()
+		batch  = bc.chainDb.NewBatch()
+		txTail = uint64(0)
+	)
+	if stored := GetTxIndexTail(bc.chainDb); stored != nil {
+		txTail = *stored
+	}
+	err := ForEachCanonicalBlock(bc.chainDb, from, to-1, func(block *types.Block, _ types.Receipts) error {
+		hash, number := block.Hash(), block.NumberU64()
+		for _, tx := range block.Transactions() {
+			DeleteTxLookupEntry(batch, tx.Hash())
+		}
+		DeleteBody(batch, hash, number)
+		DeleteBlockReceipts(batch, hash, number)
+
+		bc.bodyCache.Remove(hash)
+		bc.bodyRLPCache.Remove(hash)
+		bc.blockCache.Remove(hash)
+
+		if tail := number + 1; (tail-from)%txIndexBatch == 0 || tail == to {
+			// Transactions of pruned blocks are gone from the index as well
+			if txTail < tail {
+				WriteTxIndexTail(batch, tail)
+			}
+			WriteHistoryTail(batch, tail)
+			if !bc.flushMaintenance(batch) {
+				return errMaintenanceStopped
+			}
+			batch = bc.chainDb.NewBatch()
+		}
+		return nil
+	})
+	if err != nil {
+		if err != errMaintenanceStopped {
+			log.Error("Failed to prune block history", "err", err)
+		}
+		return
+	}
+	log.Info("Pruned block history", "blocks", to-from, "tail", to, "elapsed", common.PrettyDuration(time.Since(start)))
+}
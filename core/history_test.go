@@ -0,0 +1,57 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+// Tests that pruning the block history deletes the bodies, receipts and lookup
+// entries of old blocks and moves the stored history and index tails.
+func TestPruneHistory(t *testing.T) {
+	chain, blocks, db := newIndexedTestChain(t, 20)
+	defer chain.Stop()
+
+	// Retain the last 8 blocks, everything up to #12 should be pruned
+	atomic.StoreUint64(&chain.historyLimit, 8)
+	chain.pruneHistory(20)
+
+	if tail := GetHistoryTail(db); tail != 13 {
+		t.Fatalf("history tail mismatch: have %d, want %d", tail, 13)
+	}
+	checkTxIndex(t, db, blocks, 13)
+	if GetBody(db, chain.Genesis().Hash(), 0) == nil {
+		t.Fatalf("genesis body pruned")
+	}
+	for _, block := range blocks {
+		hash, number := block.Hash(), block.NumberU64()
+		pruned := number < 13
+		if have := GetBody(db, hash, number) == nil; have != pruned {
+			t.Errorf("block #%d: body pruned mismatch: have %v, want %v", number, have, pruned)
+		}
+		if have := GetBlockReceipts(db, hash, number) == nil; have != pruned {
+			t.Errorf("block #%d: receipts pruned mismatch: have %v, want %v", number, have, pruned)
+		}
+		if have := chain.GetBlock(hash, number) == nil; have != pruned {
+			t.Errorf("block #%d: cached block pruned mismatch: have %v, want %v", number, have, pruned)
+		}
+	}
+	// Reindexing the entire chain must stop at the history tail
+	chain.updateTxIndex(20)
+	checkTxIndex(t, db, blocks, 13)
+}
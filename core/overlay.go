@@ -0,0 +1,326 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus"
+	"github.com/Aurorachain-io/go-aoa/consensus/delegatestate"
+	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/params"
+	"github.com/Aurorachain-io/go-aoa/trie"
+)
+
+// errOverlayTime is returned if a hypothetical block would not be younger than
+// its parent.
+var errOverlayTime = errors.New("overlay block time not after parent")
+
+// overlayDatabase is a database keeping all writes in memory, reading through to
+// the chain's trie node cache, and from there the disk, for anything not written.
+type overlayDatabase struct {
+	disk   trie.DatabaseReader
+	memory *aoadb.MemDatabase
+}
+
+func (db *overlayDatabase) Get(key []byte) ([]byte, error) {
+	if value, err := db.memory.Get(key); err == nil {
+		return value, nil
+	}
+	return db.disk.Get(key)
+}
+
+func (db *overlayDatabase) Has(key []byte) (bool, error) {
+	if has, _ := db.memory.Has(key); has {
+		return true, nil
+	}
+	return db.disk.Has(key)
+}
+
+func (db *overlayDatabase) Put(key []byte, value []byte) error { return db.memory.Put(key, value) }
+func (db *overlayDatabase) Delete(key []byte) error            { return db.memory.Delete(key) }
+func (db *overlayDatabase) NewBatch() aoadb.Batch              { return db.memory.NewBatch() }
+func (db *overlayDatabase) Close()                             {}
+
+// ChainOverlay is an in-memory fork of the chain branching off a canonical block.
+// Hypothetical blocks are executed on top of it with all their state kept in
+// memory, so the chain database is never written to.
+//
+// The chain accessors implementing ChainContext and consensus.ChainReader serve
+// the execution of blocks and must not be used concurrently with InsertBlock.
+type ChainOverlay struct {
+	bc            *BlockChain
+	db            *overlayDatabase
+	stateCache    state.Database
+	delegateCache delegatestate.Database
+
+	base     *types.Header                 // Canonical header the overlay branches off
+	headers  map[common.Hash]*types.Header // Headers of the hypothetical blocks by hash
+	blocks   []*types.Block                // Hypothetical blocks, in insertion order
+	receipts []types.Receipts              // Receipts of the hypothetical blocks
+
+	lock sync.RWMutex
+}
+
+// NewOverlay creates an in-memory fork of the chain on top of the given header,
+// whose state must be available.
+func (bc *BlockChain) NewOverlay(base *types.Header) (*ChainOverlay, error) {
+	if _, err := state.New(base.Root, bc.stateCache); err != nil {
+		return nil, fmt.Errorf("missing state of block #%d: %v", base.Number, err)
+	}
+	memory, _ := aoadb.NewMemDatabase()
+	db := &overlayDatabase{disk: bc.stateCache.TrieDB(), memory: memory}
+
+	return &ChainOverlay{
+		bc:            bc,
+		db:            db,
+		stateCache:    state.NewDatabase(db),
+		delegateCache: delegatestate.NewDatabase(db),
+		base:          types.CopyHeader(base),
+		headers:       make(map[common.Hash]*types.Header),
+	}, nil
+}
+
+// Base returns the canonical header the overlay branches off.
+func (o *ChainOverlay) Base() *types.Header {
+	return types.CopyHeader(o.base)
+}
+
+// Blocks returns the hypothetical blocks inserted into the overlay so far.
+func (o *ChainOverlay) Blocks() []*types.Block {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	return append([]*types.Block(nil), o.blocks...)
+}
+
+// Head returns the header of the last hypothetical block, or the base header
+// if none was inserted yet.
+func (o *ChainOverlay) Head() *types.Header {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	return types.CopyHeader(o.head())
+}
+
+func (o *ChainOverlay) head() *types.Header {
+	if len(o.blocks) == 0 {
+		return o.base
+	}
+	return o.blocks[len(o.blocks)-1].Header()
+}
+
+// State returns a mutable copy of the state at the head of the overlay.
+func (o *ChainOverlay) State() (*state.StateDB, error) {
+	return o.StateAt(o.Head().Root)
+}
+
+// StateAt returns a mutable copy of the state with the given root, which may be
+// the state of a hypothetical or a canonical block.
+func (o *ChainOverlay) StateAt(root common.Hash) (*state.StateDB, error) {
+	return state.New(root, o.stateCache)
+}
+
+// DelegateStateAt returns the delegate state with the given root, which may be
+// the state of a hypothetical or a canonical block.
+func (o *ChainOverlay) DelegateStateAt(root common.Hash) (*delegatestate.DelegateDB, error) {
+	return delegatestate.New(root, o.delegateCache)
+}
+
+// BlockByNumber returns the block of the overlay at the given height, which is
+// a hypothetical block above the base and a canonical one up to it.
+func (o *ChainOverlay) BlockByNumber(number uint64) *types.Block {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	if block := o.blockByNumber(number); block != nil {
+		return block
+	}
+	if number > o.base.Number.Uint64() {
+		return nil
+	}
+	return o.bc.GetBlockByNumber(number)
+}
+
+// GetReceipts returns the receipts of a hypothetical block.
+func (o *ChainOverlay) GetReceipts(hash common.Hash) types.Receipts {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	for i, block := range o.blocks {
+		if block.Hash() == hash {
+			return o.receipts[i]
+		}
+	}
+	return nil
+}
+
+// InsertBlock executes the given transactions in a hypothetical block on top of
+// the overlay head, produced by coinbase at the given time. A zero time places
+// the block one block interval after its parent. Any transaction failing to
+// apply rejects the whole block, leaving the overlay unchanged.
+func (o *ChainOverlay) InsertBlock(coinbase common.Address, time uint64, txs types.Transactions) (*types.Block, types.Receipts, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	parent := o.head()
+	if time == 0 {
+		time = parent.Time.Uint64()
+		if interval := o.bc.config.BlockInterval; interval != nil {
+			time += interval.Uint64()
+		}
+	}
+	if time <= parent.Time.Uint64() {
+		return nil, nil, errOverlayTime
+	}
+	gasLimit := CalcGasLimit(types.NewBlockWithHeader(parent))
+	if gasLimit > params.MaxGasLimit {
+		gasLimit = params.MaxGasLimit
+	}
+	header := &types.Header{
+		ParentHash:         parent.Hash(),
+		Number:             new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:           gasLimit,
+		Extra:              common.CopyBytes(parent.Extra),
+		Time:               new(big.Int).SetUint64(time),
+		Coinbase:           coinbase,
+		ShuffleHash:        parent.ShuffleHash,
+		ShuffleBlockNumber: parent.ShuffleBlockNumber,
+	}
+	statedb, err := state.New(parent.Root, o.stateCache)
+	if err != nil {
+		return nil, nil, err
+	}
+	delegatedb, err := delegatestate.New(parent.DelegateRoot, o.delegateCache)
+	if err != nil {
+		return nil, nil, err
+	}
+	receipts, usedGas, err := executeBlock(o, types.NewBlock(header, txs, nil), statedb, delegatedb)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Keep the resulting states in memory and seal the block with their roots
+	if header.Root, err = statedb.CommitTo(o.db, false); err != nil {
+		return nil, nil, err
+	}
+	if header.DelegateRoot, err = delegatedb.CommitTo(o.db, false); err != nil {
+		return nil, nil, err
+	}
+	header.GasUsed = usedGas
+	header.Bloom = types.CreateBloom(receipts)
+
+	block := types.NewBlock(header, txs, receipts)
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			log.BlockHash = block.Hash()
+		}
+	}
+	o.blocks = append(o.blocks, block)
+	o.receipts = append(o.receipts, receipts)
+	o.headers[block.Hash()] = block.Header()
+
+	return block, receipts, nil
+}
+
+// Config implements consensus.ChainReader, retrieving the chain configuration.
+func (o *ChainOverlay) Config() *params.ChainConfig { return o.bc.config }
+
+// GetGenesisConfig implements ChainContext, retrieving the chain configuration.
+func (o *ChainOverlay) GetGenesisConfig() *params.ChainConfig { return o.bc.config }
+
+// Engine implements ChainContext, retrieving the consensus engine of the chain.
+func (o *ChainOverlay) Engine() consensus.Engine { return o.bc.dacEngine }
+
+// CurrentHeader implements consensus.ChainReader, retrieving the overlay head.
+func (o *ChainOverlay) CurrentHeader() *types.Header { return o.head() }
+
+// GetHeader retrieves a header of the overlay by hash and number, looking up the
+// canonical chain below the hypothetical blocks.
+func (o *ChainOverlay) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := o.headers[hash]; header != nil {
+		return header
+	}
+	if number > o.base.Number.Uint64() {
+		return nil
+	}
+	return o.bc.GetHeader(hash, number)
+}
+
+// GetHeaderByHash retrieves a header of the overlay by hash.
+func (o *ChainOverlay) GetHeaderByHash(hash common.Hash) *types.Header {
+	if header := o.headers[hash]; header != nil {
+		return header
+	}
+	if number := o.bc.hc.GetBlockNumber(hash); number != missingNumber {
+		return o.GetHeader(hash, number)
+	}
+	return nil
+}
+
+// GetHeaderByNumber retrieves the header of the overlay at the given height.
+func (o *ChainOverlay) GetHeaderByNumber(number uint64) *types.Header {
+	if block := o.blockByNumber(number); block != nil {
+		return block.Header()
+	}
+	if number > o.base.Number.Uint64() {
+		return nil
+	}
+	return o.bc.GetHeaderByNumber(number)
+}
+
+// GetBlock retrieves a block of the overlay by hash and number.
+func (o *ChainOverlay) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if block := o.blockByNumber(number); block != nil {
+		if block.Hash() == hash {
+			return block
+		}
+		return nil
+	}
+	if number > o.base.Number.Uint64() {
+		return nil
+	}
+	return o.bc.GetBlock(hash, number)
+}
+
+// blockByNumber returns the hypothetical block at the given height, if any.
+func (o *ChainOverlay) blockByNumber(number uint64) *types.Block {
+	base := o.base.Number.Uint64()
+	if number <= base || number-base > uint64(len(o.blocks)) {
+		return nil
+	}
+	return o.blocks[number-base-1]
+}
+
+// GetDelegatePoll implements ChainContext, retrieving the delegates of the
+// delegate state at the overlay head.
+func (o *ChainOverlay) GetDelegatePoll() (*map[common.Address]types.Candidate, error) {
+	delegatedb, err := delegatestate.New(o.head().DelegateRoot, o.delegateCache)
+	if err != nil {
+		return nil, err
+	}
+	poll := make(map[common.Address]types.Candidate)
+	for _, delegate := range delegatedb.GetDelegates() {
+		poll[common.HexToAddress(delegate.Address)] = delegate
+	}
+	return &poll, nil
+}
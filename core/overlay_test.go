@@ -0,0 +1,159 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus/dpos"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/core/vm"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// Tests that hypothetical blocks can be executed on an overlay, building on each
+// other, without writing anything into the chain database.
+func TestChainOverlay(t *testing.T) {
+	var (
+		db, _  = aoadb.NewMemDatabase()
+		config = params.AllDacchainProtocolChanges
+		signer = types.MakeSigner(config, big.NewInt(1))
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		to     = common.HexToAddress("0x01")
+	)
+	genesis := (&Genesis{
+		Config: config,
+		Alloc:  GenesisAlloc{addr: {Balance: big.NewInt(params.Em)}},
+		Agents: GenesisAgents{{Address: "0x0200", Vote: 1, Nickname: "test"}},
+	}).MustCommit(db)
+
+	chain, err := NewBlockChain(db, nil, config, dpos.New(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	transfer := func(nonce uint64) types.Transactions {
+		tx, err := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(10), 100000, big.NewInt(1), nil, uint64(types.ActionTrans), nil, ""), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		return types.Transactions{tx}
+	}
+	entries := db.Len()
+
+	overlay, err := chain.NewOverlay(genesis.Header())
+	if err != nil {
+		t.Fatalf("failed to create overlay: %v", err)
+	}
+	coinbase := common.HexToAddress("0x0400")
+	for i := uint64(0); i < 2; i++ {
+		block, receipts, err := overlay.InsertBlock(coinbase, 0, transfer(i))
+		if err != nil {
+			t.Fatalf("failed to insert block %d: %v", i+1, err)
+		}
+		if block.NumberU64() != i+1 || len(receipts) != 1 {
+			t.Fatalf("block %d: number %d, %d receipts", i+1, block.NumberU64(), len(receipts))
+		}
+		if head := overlay.Head(); head.Hash() != block.Hash() {
+			t.Fatalf("block %d: head mismatch: have %x, want %x", i+1, head.Hash(), block.Hash())
+		}
+	}
+	statedb, err := overlay.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve overlay state: %v", err)
+	}
+	if balance := statedb.GetBalance(to); balance.Cmp(big.NewInt(20)) != 0 {
+		t.Errorf("overlay balance mismatch: have %v, want 20", balance)
+	}
+	if nonce := statedb.GetNonce(addr); nonce != 2 {
+		t.Errorf("overlay nonce mismatch: have %d, want 2", nonce)
+	}
+	if parent := overlay.BlockByNumber(2).ParentHash(); parent != overlay.BlockByNumber(1).Hash() {
+		t.Errorf("overlay blocks not linked: parent %x", parent)
+	}
+	// Failing blocks must leave the overlay unchanged
+	head := overlay.Head()
+	if _, _, err := overlay.InsertBlock(coinbase, 0, transfer(0)); err == nil {
+		t.Errorf("block with stale nonce inserted")
+	}
+	if _, _, err := overlay.InsertBlock(coinbase, head.Time.Uint64(), nil); err != errOverlayTime {
+		t.Errorf("block time error mismatch: have %v, want %v", err, errOverlayTime)
+	}
+	if overlay.Head().Hash() != head.Hash() {
+		t.Errorf("failed insertion changed overlay head")
+	}
+	// The chain itself must be untouched
+	if n := db.Len(); n != entries {
+		t.Errorf("chain database modified: have %d entries, want %d", n, entries)
+	}
+	if number := chain.CurrentBlock().NumberU64(); number != 0 {
+		t.Errorf("chain head moved to %d", number)
+	}
+	chainState, _ := chain.State()
+	if balance := chainState.GetBalance(to); balance.Sign() != 0 {
+		t.Errorf("chain balance modified: have %v", balance)
+	}
+}
+
+// Tests that overlays can branch off blocks whose state is only held in the
+// chain's trie node cache and was never flushed to disk.
+func TestChainOverlayCachedState(t *testing.T) {
+	var (
+		db, _  = aoadb.NewMemDatabase()
+		config = params.AllDacchainProtocolChanges
+		addr   = common.HexToAddress("0x01")
+	)
+	genesis := (&Genesis{
+		Config: config,
+		Agents: GenesisAgents{{Address: "0x0200", Vote: 1, Nickname: "test"}},
+	}).MustCommit(db)
+
+	chain, err := NewBlockChain(db, nil, config, dpos.New(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	statedb, _ := chain.State()
+	statedb.AddBalance(addr, big.NewInt(5))
+	root, err := statedb.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if _, err := db.Get(root.Bytes()); err == nil {
+		t.Fatalf("state root flushed to disk")
+	}
+	base := genesis.Header()
+	base.Root = root
+
+	overlay, err := chain.NewOverlay(base)
+	if err != nil {
+		t.Fatalf("failed to create overlay: %v", err)
+	}
+	if statedb, err = overlay.State(); err != nil {
+		t.Fatalf("failed to retrieve overlay state: %v", err)
+	}
+	if balance := statedb.GetBalance(addr); balance.Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("overlay balance mismatch: have %v, want 5", balance)
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus"
+	"github.com/Aurorachain-io/go-aoa/consensus/delegatestate"
+	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/core/vm"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/metrics"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+var (
+	parallelMergedCounter = metrics.NewCounter("chain/parallel/merged") // Pre-executed transactions merged into the block state
+	parallelSerialCounter = metrics.NewCounter("chain/parallel/serial") // Transactions (re-)executed serially
+)
+
+// preExecution is the outcome of executing a transaction against the parent
+// state of its block, in isolation from the other transactions of the block.
+type preExecution struct {
+	from     common.Address
+	gas      uint64
+	failed   bool
+	innerTxs []*types.InnerTx
+
+	state  *state.StateDB   // Overlay of the parent state holding the results
+	access *state.AccessSet // Accounts read and modified by the transaction
+}
+
+// mergeable reports whether the pre-execution yields the same result as serial
+// execution would, i.e. none of the accounts it read was modified by an earlier
+// transaction of the block, and all its credits can be replayed.
+func (pre *preExecution) mergeable(written map[common.Address]struct{}) bool {
+	for _, addr := range pre.access.Reads() {
+		if _, ok := written[addr]; ok {
+			return false
+		}
+	}
+	for _, addr := range pre.access.Writes() {
+		// Zero credits only touch accounts, replaying them might delete an
+		// existing empty account the transaction reverted its touch of.
+		if amount, ok := pre.access.Credit(addr); ok && amount.Sign() == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ParallelProcessor is a Processor pre-executing the transactions of a block
+// concurrently against the parent state. Transactions not depending on earlier
+// ones of the same block have their results merged directly, conflicting ones
+// fall back to serial execution. The resulting state is identical to the one
+// produced by the StateProcessor.
+//
+// ParallelProcessor implements Processor.
+type ParallelProcessor struct {
+	*StateProcessor
+	workers int // Number of goroutines pre-executing transactions
+}
+
+// NewParallelProcessor initialises a new ParallelProcessor running the given
+// number of pre-execution goroutines.
+func NewParallelProcessor(config *params.ChainConfig, bc *BlockChain, engine consensus.Engine, workers int) *ParallelProcessor {
+	return &ParallelProcessor{
+		StateProcessor: NewStateProcessor(config, bc, engine),
+		workers:        workers,
+	}
+}
+
+// Process processes the state changes of a block like StateProcessor.Process,
+// pre-executing the transactions in parallel first.
+func (p *ParallelProcessor) Process(block *types.Block, statedb *state.StateDB, cfg vm.Config, db *delegatestate.DelegateDB) (types.Receipts, []*types.Log, uint64, error) {
+	// Tracing requires the transactions to run on the block state one by one
+	if p.workers < 2 || block.Transactions().Len() < 2 || cfg.Debug {
+		return p.StateProcessor.Process(block, statedb, cfg, db)
+	}
+	var (
+		receipts types.Receipts
+		usedGas  = new(uint64)
+		header   = block.Header()
+		allLogs  []*types.Log
+		gp       = new(GasPool).AddGas(block.GasLimit())
+		pres     = p.preExecute(block, statedb, cfg)
+		written  = make(map[common.Address]struct{})
+	)
+	for i, tx := range block.Transactions() {
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		db.Prepare(tx.Hash(), block.Hash(), i)
+
+		if pre := pres[i]; pre != nil && gp.Gas() >= tx.Gas() && pre.mergeable(written) {
+			receipt := p.merge(statedb, tx, pre, gp, usedGas)
+			for _, addr := range pre.access.Writes() {
+				written[addr] = struct{}{}
+			}
+			receipts = append(receipts, receipt)
+			allLogs = append(allLogs, receipt.Logs...)
+			parallelMergedCounter.Inc(1)
+			continue
+		}
+		statedb.StartAccessTracking()
+		receipt, _, err := ApplyTransaction(p.config, p.bc, nil, gp, statedb, header, tx, usedGas, cfg, db, block.Time().Uint64(), true)
+		access := statedb.StopAccessTracking()
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		for _, addr := range access.Writes() {
+			written[addr] = struct{}{}
+		}
+		receipts = append(receipts, receipt)
+		allLogs = append(allLogs, receipt.Logs...)
+		parallelSerialCounter.Inc(1)
+	}
+	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
+	if _, err := p.engine.Finalize(p.bc, header, statedb, db, block.Transactions(), receipts); err != nil {
+		return nil, nil, 0, err
+	}
+
+	return receipts, allLogs, *usedGas, nil
+}
+
+// preExecute executes all transactions of the block concurrently, each on its
+// own overlay of the parent state. The slot of a transaction is nil if it has
+// to be executed serially.
+func (p *ParallelProcessor) preExecute(block *types.Block, parent *state.StateDB, cfg vm.Config) []*preExecution {
+	var (
+		txs   = block.Transactions()
+		pres  = make([]*preExecution, len(txs))
+		tasks = make(chan int, len(txs))
+		wg    sync.WaitGroup
+	)
+	for i := range txs {
+		tasks <- i
+	}
+	close(tasks)
+
+	workers := p.workers
+	if workers > len(txs) {
+		workers = len(txs)
+	}
+	for n := 0; n < workers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range tasks {
+				pres[i] = p.preExecuteTx(block, i, parent, cfg)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return pres
+}
+
+// preExecuteTx executes a single transaction on an overlay of the parent state,
+// tracking the accounts it accesses. Only plain transfers and contract calls
+// and creations are pre-executed, delegate related transactions also modify
+// the delegate state and are always executed serially.
+func (p *ParallelProcessor) preExecuteTx(block *types.Block, index int, parent *state.StateDB, cfg vm.Config) *preExecution {
+	tx := block.Transactions()[index]
+	switch tx.TxDataAction() {
+	case types.ActionTrans, types.ActionCreateContract, types.ActionCallContract:
+	default:
+		return nil
+	}
+	header := block.Header()
+	msg, err := tx.AsMessage(types.MakeSigner(p.config, header.Number))
+	if err != nil {
+		return nil
+	}
+	statedb := parent.Overlay()
+	statedb.Prepare(tx.Hash(), block.Hash(), index)
+	statedb.StartAccessTracking()
+
+	vmenv := vm.NewEVM(NewEVMContext(msg, header, p.bc, nil), statedb, p.config, cfg)
+	vmenv.WatchInnerTx = true
+
+	_, gas, failed, err := ApplyMessage(vmenv, msg, new(GasPool).AddGas(header.GasLimit))
+	if err != nil {
+		// Most likely the transaction depends on an earlier one, e.g. by nonce
+		return nil
+	}
+	access := statedb.StopAccessTracking()
+	statedb.Finalise(true)
+
+	return &preExecution{
+		from:     msg.From(),
+		gas:      gas,
+		failed:   failed,
+		innerTxs: vmenv.InnerTxs,
+		state:    statedb,
+		access:   access,
+	}
+}
+
+// merge applies the results of a pre-executed transaction to the block state,
+// creating the receipt the serial execution would have.
+func (p *ParallelProcessor) merge(statedb *state.StateDB, tx *types.Transaction, pre *preExecution, gp *GasPool, usedGas *uint64) *types.Receipt {
+	// The availability of the gas was checked by the caller
+	gp.SubGas(pre.gas)
+
+	statedb.MergeOverlay(pre.state, pre.access)
+	for _, l := range pre.state.GetLogs(tx.Hash()) {
+		statedb.AddLog(l)
+	}
+	statedb.Finalise(true)
+	*usedGas += pre.gas
+
+	receipt := types.NewReceipt(pre.failed, *usedGas)
+	receipt.Action = tx.TxDataAction()
+	receipt.TxHash = tx.Hash()
+	receipt.GasUsed = pre.gas
+	if tx.TxDataAction() == types.ActionCreateContract {
+		receipt.ContractAddress = crypto.CreateAddress(pre.from, tx.Nonce())
+	}
+	receipt.Logs = statedb.GetLogs(tx.Hash())
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+	if len(pre.innerTxs) > 0 {
+		if err := p.bc.innerTxDb.Set(tx.Hash(), pre.innerTxs); err != nil {
+			log.Warn("save inner transactions error", "err", err)
+		}
+	}
+	return receipt
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus/dpos"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/core/vm"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// Tests that the parallel processor produces the same state and receipts as
+// the serial one, both for independent and conflicting transactions.
+func TestParallelProcessor(t *testing.T) {
+	var (
+		db, _   = aoadb.NewMemDatabase()
+		config  = params.AllDacchainProtocolChanges
+		signer  = types.MakeSigner(config, big.NewInt(1))
+		keys    = make([]*ecdsa.PrivateKey, 4)
+		addrs   = make([]common.Address, len(keys))
+		alloc   = make(GenesisAlloc)
+		emitter = common.HexToAddress("0x0300")
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		alloc[addrs[i]] = GenesisAccount{Balance: big.NewInt(params.Em)}
+	}
+	// PUSH1 0 PUSH1 0 LOG0
+	alloc[emitter] = GenesisAccount{Balance: new(big.Int), Code: []byte{0x60, 0x00, 0x60, 0x00, 0xa0}}
+
+	genesis := (&Genesis{
+		Config: config,
+		Alloc:  alloc,
+		Agents: GenesisAgents{{Address: "0x0200", Vote: 1, Nickname: "test"}},
+	}).MustCommit(db)
+
+	chain, err := NewBlockChain(db, nil, config, dpos.New(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	sign := func(key *ecdsa.PrivateKey, nonce uint64, to common.Address, action uint64) *types.Transaction {
+		tx := types.NewTransaction(nonce, to, big.NewInt(10), 100000, big.NewInt(1), nil, action, nil, "")
+		tx, err := types.SignTx(tx, signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		return tx
+	}
+	txs := types.Transactions{
+		sign(keys[0], 0, common.HexToAddress("0x01"), types.ActionTrans), // independent
+		sign(keys[1], 0, common.HexToAddress("0x02"), types.ActionTrans), // independent
+		sign(keys[0], 1, common.HexToAddress("0x03"), types.ActionTrans), // nonce depends on the first
+		sign(keys[2], 0, addrs[0], types.ActionTrans),                    // reads a modified account
+		sign(keys[3], 0, emitter, types.ActionCallContract),              // emits a log
+		sign(keys[1], 1, emitter, types.ActionCallContract),              // emits a log, depends on the second
+	}
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		GasLimit:   genesis.GasLimit(),
+		Time:       new(big.Int).Add(genesis.Time(), big.NewInt(10)),
+		Coinbase:   common.HexToAddress("0x0400"),
+	}
+	block := types.NewBlock(header, txs, nil)
+
+	process := func(processor Processor) (common.Hash, types.Receipts, uint64) {
+		statedb, err := chain.StateAt(genesis.Root())
+		if err != nil {
+			t.Fatalf("failed to open state: %v", err)
+		}
+		delegatedb, err := chain.DelegateStateAt(genesis.DelegateRoot())
+		if err != nil {
+			t.Fatalf("failed to open delegate state: %v", err)
+		}
+		receipts, _, gas, err := processor.Process(block, statedb, vm.Config{}, delegatedb)
+		if err != nil {
+			t.Fatalf("failed to process block: %v", err)
+		}
+		return statedb.IntermediateRoot(true), receipts, gas
+	}
+	serialRoot, serialReceipts, serialGas := process(NewStateProcessor(config, chain, dpos.New()))
+	parallelRoot, parallelReceipts, parallelGas := process(NewParallelProcessor(config, chain, dpos.New(), 4))
+
+	if parallelRoot != serialRoot {
+		t.Errorf("state root mismatch: have %x, want %x", parallelRoot, serialRoot)
+	}
+	if parallelGas != serialGas {
+		t.Errorf("gas used mismatch: have %d, want %d", parallelGas, serialGas)
+	}
+	if !reflect.DeepEqual(parallelReceipts, serialReceipts) {
+		t.Errorf("receipts mismatch:\nhave %v\nwant %v", parallelReceipts, serialReceipts)
+	}
+	if logs := parallelReceipts[5].Logs; len(logs) != 1 || logs[0].Index != 1 || logs[0].TxIndex != 5 {
+		t.Errorf("log position mismatch: have %v", logs)
+	}
+}
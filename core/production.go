@@ -0,0 +1,72 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/Aurorachain-io/go-aoa/common"
+)
+
+// DelegateProduction counts the block slots a delegate was scheduled for within
+// an epoch, split into the ones it produced a block in and the ones it missed.
+type DelegateProduction struct {
+	Address  common.Address
+	Produced uint64
+	Missed   uint64
+}
+
+// EpochProduction is the summary of the block production of all delegates
+// scheduled within a single delegate epoch of the chain.
+type EpochProduction struct {
+	Epoch     uint64                // Index of the epoch the summary belongs to
+	Delegates []*DelegateProduction // Counters of the delegates, in order of first appearance
+}
+
+// NewEpochProduction creates an empty production summary for the given epoch.
+func NewEpochProduction(epoch uint64) *EpochProduction {
+	return &EpochProduction{Epoch: epoch}
+}
+
+// AddProduced counts a block produced by the given delegate.
+func (p *EpochProduction) AddProduced(address common.Address) {
+	p.delegate(address).Produced++
+}
+
+// AddMissed counts a slot the given delegate did not produce a block in.
+func (p *EpochProduction) AddMissed(address common.Address) {
+	p.delegate(address).Missed++
+}
+
+// Delegate returns the counters of the given delegate, or nil if it was never
+// scheduled within the epoch.
+func (p *EpochProduction) Delegate(address common.Address) *DelegateProduction {
+	for _, delegate := range p.Delegates {
+		if delegate.Address == address {
+			return delegate
+		}
+	}
+	return nil
+}
+
+// delegate returns the counters of the given delegate, creating them if needed.
+func (p *EpochProduction) delegate(address common.Address) *DelegateProduction {
+	if delegate := p.Delegate(address); delegate != nil {
+		return delegate
+	}
+	delegate := &DelegateProduction{Address: address}
+	p.Delegates = append(p.Delegates, delegate)
+	return delegate
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+)
+
+// Tests that produced and missed slots are counted per delegate.
+func TestEpochProductionAdd(t *testing.T) {
+	var (
+		prod = NewEpochProduction(2)
+		a    = common.HexToAddress("0x01")
+		b    = common.HexToAddress("0x02")
+	)
+	prod.AddProduced(a)
+	prod.AddMissed(b)
+	prod.AddProduced(a)
+	prod.AddProduced(b)
+
+	want := []*DelegateProduction{
+		{Address: a, Produced: 2},
+		{Address: b, Produced: 1, Missed: 1},
+	}
+	if !reflect.DeepEqual(prod.Delegates, want) {
+		t.Fatalf("counters mismatch: have %v, want %v", prod.Delegates, want)
+	}
+	if delegate := prod.Delegate(common.HexToAddress("0x03")); delegate != nil {
+		t.Fatalf("unscheduled delegate returned: %v", delegate)
+	}
+}
+
+// Tests that production counters survive a database round-trip.
+func TestEpochProductionStorage(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+
+	if prod := GetEpochProduction(db, 5); prod != nil {
+		t.Fatalf("non existent epoch returned: %v", prod)
+	}
+	prod := NewEpochProduction(5)
+	prod.AddProduced(common.HexToAddress("0x01"))
+	prod.AddMissed(common.HexToAddress("0x02"))
+	if err := WriteEpochProduction(db, prod); err != nil {
+		t.Fatalf("failed to write epoch production: %v", err)
+	}
+	if stored := GetEpochProduction(db, 5); !reflect.DeepEqual(stored, prod) {
+		t.Fatalf("stored epoch mismatch: have %v, want %v", stored, prod)
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus"
+	"github.com/Aurorachain-io/go-aoa/consensus/delegatestate"
+	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/params"
+	"github.com/Aurorachain-io/go-aoa/rlp"
+)
+
+// ReplayResult is the outcome of replaying a canonical block on a shadow fork.
+type ReplayResult struct {
+	Number uint64
+	Hash   common.Hash
+
+	GasUsed      uint64      // Gas used by the replayed block
+	Root         common.Hash // State root resulting from the replay
+	DelegateRoot common.Hash // Delegate state root resulting from the replay
+	Receipts     []int       // Indexes of the receipts differing from the canonical ones
+
+	Canonical *types.Header // Header of the canonical block
+}
+
+// Diverged reports whether the replay resulted in anything differing from the
+// canonical chain.
+func (r *ReplayResult) Diverged() bool {
+	return r.GasUsed != r.Canonical.GasUsed || r.Root != r.Canonical.Root ||
+		r.DelegateRoot != r.Canonical.DelegateRoot || len(r.Receipts) > 0
+}
+
+// String implements fmt.Stringer, summarizing the differences to the canonical
+// block.
+func (r *ReplayResult) String() string {
+	if !r.Diverged() {
+		return fmt.Sprintf("block #%d [%x…] matches", r.Number, r.Hash[:4])
+	}
+	var diff bytes.Buffer
+	fmt.Fprintf(&diff, "block #%d [%x…] diverged:", r.Number, r.Hash[:4])
+	if r.GasUsed != r.Canonical.GasUsed {
+		fmt.Fprintf(&diff, " gas used %d != %d,", r.GasUsed, r.Canonical.GasUsed)
+	}
+	if r.Root != r.Canonical.Root {
+		fmt.Fprintf(&diff, " state root %x != %x,", r.Root, r.Canonical.Root)
+	}
+	if r.DelegateRoot != r.Canonical.DelegateRoot {
+		fmt.Fprintf(&diff, " delegate root %x != %x,", r.DelegateRoot, r.Canonical.DelegateRoot)
+	}
+	if len(r.Receipts) > 0 {
+		fmt.Fprintf(&diff, " receipts %v differ,", r.Receipts)
+	}
+	return strings.TrimSuffix(diff.String(), ",")
+}
+
+// replayChain is the chain context of blocks replayed on a shadow fork. Headers
+// are served from the canonical chain, the delegate poll from the shadow state.
+type replayChain struct {
+	*HeaderChain
+	delegates *delegatestate.DelegateDB // Shadow delegate state before the replayed block
+}
+
+func (c *replayChain) GetGenesisConfig() *params.ChainConfig { return c.Config() }
+
+func (c *replayChain) GetDelegatePoll() (*map[common.Address]types.Candidate, error) {
+	poll := make(map[common.Address]types.Candidate)
+	for _, candidate := range c.delegates.GetDelegates() {
+		poll[common.HexToAddress(candidate.Address)] = candidate
+	}
+	return &poll, nil
+}
+
+// ShadowReplayer executes the canonical blocks of a source chain database again
+// with the chain rules of this build, e.g. to validate an upgrade before its
+// activation. The resulting states form a shadow fork, which is kept in a
+// separate database, leaving the source untouched.
+type ShadowReplayer struct {
+	source  aoadb.Database // Database of the canonical chain
+	db      aoadb.Database // Shadow database layered over the source
+	headers *HeaderChain
+
+	number       uint64      // Number of the last block replayed
+	root         common.Hash // Shadow state root after the last block replayed
+	delegateRoot common.Hash // Shadow delegate state root after the last block replayed
+}
+
+// NewShadowReplayer creates a replayer continuing from the canonical state of
+// the given block, which must be available in the source database. Shadow states
+// are written into the shadow database. If no chain config is given, the one of
+// the source chain is used.
+func NewShadowReplayer(source, shadow aoadb.Database, config *params.ChainConfig, engine consensus.Engine, number uint64) (*ShadowReplayer, error) {
+	if config == nil {
+		genesis := GetCanonicalHash(source, 0)
+		if genesis == (common.Hash{}) {
+			return nil, ErrNoGenesis
+		}
+		stored, err := GetChainConfig(source, genesis)
+		if err != nil {
+			return nil, err
+		}
+		config = stored
+	}
+	headers, err := NewHeaderChain(source, config, engine, func() bool { return false })
+	if err != nil {
+		return nil, err
+	}
+	start := headers.GetHeaderByNumber(number)
+	if start == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	r := &ShadowReplayer{
+		source:       source,
+		db:           aoadb.NewOverlayDatabase(shadow, source),
+		headers:      headers,
+		number:       number,
+		root:         start.Root,
+		delegateRoot: start.DelegateRoot,
+	}
+	if _, err := state.New(r.root, state.NewDatabase(r.db)); err != nil {
+		return nil, fmt.Errorf("state of block #%d unavailable: %v", number, err)
+	}
+	return r, nil
+}
+
+// Next replays the canonical block following the last one replayed on top of
+// the shadow state, and compares the results to the canonical ones. Execution
+// failures are returned as errors, as there's no shadow state to continue from.
+func (r *ShadowReplayer) Next() (*ReplayResult, error) {
+	number := r.number + 1
+
+	hash := GetCanonicalHash(r.source, number)
+	if hash == (common.Hash{}) {
+		return nil, fmt.Errorf("canonical block #%d not found", number)
+	}
+	block := GetBlock(r.source, hash, number)
+	if block == nil {
+		return nil, fmt.Errorf("block #%d [%x…] not found", number, hash[:4])
+	}
+	statedb, err := state.New(r.root, state.NewDatabase(r.db))
+	if err != nil {
+		return nil, err
+	}
+	delegatedb, err := delegatestate.New(r.delegateRoot, delegatestate.NewDatabase(r.db))
+	if err != nil {
+		return nil, err
+	}
+	poll, err := delegatestate.New(r.delegateRoot, delegatestate.NewDatabase(r.db))
+	if err != nil {
+		return nil, err
+	}
+	receipts, usedGas, err := executeBlock(&replayChain{HeaderChain: r.headers, delegates: poll}, block, statedb, delegatedb)
+	if err != nil {
+		return nil, fmt.Errorf("block #%d [%x…] failed: %v", number, hash[:4], err)
+	}
+	root, err := statedb.CommitTo(r.db, false)
+	if err != nil {
+		return nil, err
+	}
+	delegateRoot, err := delegatedb.CommitTo(r.db, false)
+	if err != nil {
+		return nil, err
+	}
+	r.number, r.root, r.delegateRoot = number, root, delegateRoot
+
+	result := &ReplayResult{
+		Number:       number,
+		Hash:         hash,
+		GasUsed:      usedGas,
+		Root:         root,
+		DelegateRoot: delegateRoot,
+		Canonical:    block.Header(),
+	}
+	canonical := GetBlockReceipts(r.source, hash, number)
+	for i, receipt := range receipts {
+		if i >= len(canonical) || !equalReceipts(receipt, canonical[i]) {
+			result.Receipts = append(result.Receipts, i)
+		}
+	}
+	return result, nil
+}
+
+// equalReceipts reports whether the consensus fields of two receipts match.
+func equalReceipts(a, b *types.Receipt) bool {
+	blobA, errA := rlp.EncodeToBytes(a)
+	blobB, errB := rlp.EncodeToBytes(b)
+	return errA == nil && errB == nil && bytes.Equal(blobA, blobB)
+}
@@ -0,0 +1,86 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus/dpos"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// Tests that replaying a canonical chain on a shadow fork reproduces it without
+// touching the source database, and that differing results are reported.
+func TestShadowReplay(t *testing.T) {
+	var (
+		source, _ = aoadb.NewMemDatabase()
+		config    = params.AllDacchainProtocolChanges
+		signer    = types.MakeSigner(config, big.NewInt(1))
+		key, _    = crypto.GenerateKey()
+		addr      = crypto.PubkeyToAddress(key.PublicKey)
+	)
+	genesis := (&Genesis{
+		Config: config,
+		Alloc:  GenesisAlloc{addr: {Balance: big.NewInt(params.Em)}},
+		Agents: GenesisAgents{{Address: "0x0200", Vote: 1, Nickname: "test"}},
+	}).MustCommit(source)
+
+	blocks, receipts := GenerateChain(config, genesis, dpos.New(), source, 4, func(i int, gen *BlockGen) {
+		tx := types.NewTransaction(gen.TxNonce(addr), common.HexToAddress("0x01"), big.NewInt(10), 100000, big.NewInt(1), nil, types.ActionTrans, nil, "")
+		tx, _ = types.SignTx(tx, signer, key)
+		gen.AddTx(tx)
+	})
+	for i, block := range blocks {
+		WriteBlock(source, block)
+		WriteBlockReceipts(source, block.Hash(), block.NumberU64(), receipts[i])
+		WriteCanonicalHash(source, block.Hash(), block.NumberU64())
+	}
+	// Tamper with a canonical receipt to have the replay diverge from it
+	tampered := *receipts[2][0]
+	tampered.CumulativeGasUsed++
+	WriteBlockReceipts(source, blocks[2].Hash(), 3, types.Receipts{&tampered})
+
+	size := source.Len()
+	shadow, _ := aoadb.NewMemDatabase()
+	replayer, err := NewShadowReplayer(source, shadow, nil, dpos.New(), 0)
+	if err != nil {
+		t.Fatalf("failed to create replayer: %v", err)
+	}
+	for i, block := range blocks {
+		result, err := replayer.Next()
+		if err != nil {
+			t.Fatalf("block %d: failed to replay: %v", i+1, err)
+		}
+		if result.Hash != block.Hash() || result.Root != block.Root() || result.DelegateRoot != block.Header().DelegateRoot {
+			t.Errorf("block %d: replay mismatch: %v", i+1, result)
+		}
+		if diverged := result.Diverged(); diverged != (i == 2) {
+			t.Errorf("block %d: divergence mismatch: have %v, want %v: %v", i+1, diverged, i == 2, result)
+		}
+	}
+	if _, err := replayer.Next(); err == nil {
+		t.Errorf("replayed beyond the canonical head")
+	}
+	if source.Len() != size {
+		t.Errorf("source database modified: have %d entries, want %d", source.Len(), size)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+)
+
+// AccessSet is the set of accounts read and modified through a state while it
+// was tracking accesses. Accounts whose balance was only ever increased (e.g.
+// the coinbase collecting fees) are tracked separately as credits, since such
+// modifications commute with each other.
+type AccessSet struct {
+	reads   map[common.Address]struct{} // Accounts whose content was observed
+	writes  map[common.Address]struct{} // Accounts modified, including credits
+	credits map[common.Address]*big.Int // Balance before the first credit, the credited amount once stopped
+
+	crediting bool // Whether a balance credit is in progress, suppressing the read
+}
+
+// newAccessSet creates an empty access set.
+func newAccessSet() *AccessSet {
+	return &AccessSet{
+		reads:   make(map[common.Address]struct{}),
+		writes:  make(map[common.Address]struct{}),
+		credits: make(map[common.Address]*big.Int),
+	}
+}
+
+// read marks an account as observed, unless it's being credited.
+func (a *AccessSet) read(addr common.Address) {
+	if !a.crediting {
+		a.reads[addr] = struct{}{}
+	}
+}
+
+// credit remembers the balance of an account before it's first credited.
+func (a *AccessSet) credit(addr common.Address, balance *big.Int) {
+	if _, ok := a.credits[addr]; !ok {
+		a.credits[addr] = new(big.Int).Set(balance)
+	}
+}
+
+// Read reports whether the content of the account was observed.
+func (a *AccessSet) Read(addr common.Address) bool {
+	_, ok := a.reads[addr]
+	return ok
+}
+
+// Reads returns the accounts whose content was observed.
+func (a *AccessSet) Reads() []common.Address {
+	addrs := make([]common.Address, 0, len(a.reads))
+	for addr := range a.reads {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// Writes returns the accounts modified, including the credited ones.
+func (a *AccessSet) Writes() []common.Address {
+	addrs := make([]common.Address, 0, len(a.writes))
+	for addr := range a.writes {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// Credit returns the amount an account was credited with, if all modifications
+// of it were balance credits and its content was never observed otherwise.
+func (a *AccessSet) Credit(addr common.Address) (*big.Int, bool) {
+	if a.Read(addr) {
+		return nil, false
+	}
+	amount, ok := a.credits[addr]
+	return amount, ok
+}
+
+// StartAccessTracking starts recording the accounts read and modified through
+// the state into a new access set. Since all modifications made so far would be
+// attributed to the set as well, it should be started on a freshly created or
+// finalised state.
+func (self *StateDB) StartAccessTracking() {
+	self.access = newAccessSet()
+}
+
+// StopAccessTracking stops recording accesses and returns the access set built
+// since StartAccessTracking. Credited amounts are only accurate if tracking is
+// stopped before the state is finalised, deleted accounts count as zero.
+func (self *StateDB) StopAccessTracking() *AccessSet {
+	set := self.access
+	if set == nil {
+		return nil
+	}
+	self.access = nil
+
+	for addr := range self.stateObjectsDirty {
+		set.writes[addr] = struct{}{}
+	}
+	for addr, prev := range set.credits {
+		amount := new(big.Int)
+		if obj := self.stateObjects[addr]; obj != nil && !obj.deleted {
+			amount.Sub(obj.Balance(), prev)
+		}
+		set.credits[addr] = amount
+	}
+	return set
+}
+
+// MergeOverlay applies the modifications an overlay of this state recorded in
+// the given access set, as if they were made on this state directly. Modified
+// accounts are copied over, credits are applied as balance increases to allow
+// concurrent credits of the same account. The caller is responsible for making
+// sure none of the accounts read by the overlay were modified since it was
+// created. The overlay must have been finalised and not be used afterwards.
+func (self *StateDB) MergeOverlay(overlay *StateDB, set *AccessSet) {
+	for addr := range set.writes {
+		if amount, ok := set.Credit(addr); ok {
+			self.AddBalance(addr, amount)
+			continue
+		}
+		obj := overlay.stateObjects[addr]
+		if obj == nil {
+			continue
+		}
+		self.setStateObject(obj.deepCopy(self, self.MarkStateObjectDirty))
+		self.stateObjectsDirty[addr] = struct{}{}
+	}
+	for hash, preimage := range overlay.preimages {
+		self.AddPreimage(hash, preimage)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"sync"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+)
+
+// MaxStatsTrieDepth is the size of the trie depth distributions of the access
+// statistics, deeper lookups are counted in the last bucket.
+const MaxStatsTrieDepth = 16
+
+// storageSlot identifies a single storage slot of an account.
+type storageSlot struct {
+	addr common.Address
+	key  common.Hash
+}
+
+// AccessStats collects statistics about the state accessed through a StateDB
+// and its overlays, e.g. while processing a block. Cache hits are accesses
+// served from the live objects and storage caches, misses are lookups in the
+// tries. It's safe for concurrent use; the counters must only be read once
+// the state isn't used any more.
+type AccessStats struct {
+	AccountHits   int // Account accesses served from the live objects
+	AccountMisses int // Account lookups in the account trie
+	SlotHits      int // Storage accesses served from the storage caches
+	SlotMisses    int // Storage lookups in the storage tries
+
+	AccountDepths [MaxStatsTrieDepth]int // Distribution of account trie lookup depths
+	SlotDepths    [MaxStatsTrieDepth]int // Distribution of storage trie lookup depths
+
+	accounts map[common.Address]struct{}
+	slots    map[storageSlot]struct{}
+	lock     sync.Mutex
+}
+
+// newAccessStats creates an empty statistics collector.
+func newAccessStats() *AccessStats {
+	return &AccessStats{
+		accounts: make(map[common.Address]struct{}),
+		slots:    make(map[storageSlot]struct{}),
+	}
+}
+
+// Accounts returns the number of unique accounts accessed.
+func (s *AccessStats) Accounts() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.accounts)
+}
+
+// Slots returns the number of unique storage slots accessed.
+func (s *AccessStats) Slots() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.slots)
+}
+
+// account records an access of an account, looked up in the given trie on a
+// cache miss.
+func (s *AccessStats) account(addr common.Address, hit bool, tr Trie) {
+	depth := 0
+	if !hit {
+		depth = trieDepth(tr, addr[:])
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.accounts[addr] = struct{}{}
+	if hit {
+		s.AccountHits++
+	} else {
+		s.AccountMisses++
+		s.AccountDepths[depth]++
+	}
+}
+
+// slot records an access of a storage slot, looked up in the given trie on a
+// cache miss.
+func (s *AccessStats) slot(addr common.Address, key common.Hash, hit bool, tr Trie) {
+	depth := 0
+	if !hit {
+		depth = trieDepth(tr, key[:])
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.slots[storageSlot{addr, key}] = struct{}{}
+	if hit {
+		s.SlotHits++
+	} else {
+		s.SlotMisses++
+		s.SlotDepths[depth]++
+	}
+}
+
+// trieDepth returns the depth of the last lookup of key, capped to the size of
+// the distributions. Tries not able to report it count as depth zero.
+func trieDepth(tr Trie, key []byte) int {
+	dt, ok := tr.(interface{ Depth([]byte) int })
+	if !ok {
+		return 0
+	}
+	if depth := dt.Depth(key); depth < MaxStatsTrieDepth {
+		return depth
+	}
+	return MaxStatsTrieDepth - 1
+}
+
+// EnableAccessStats starts collecting statistics of the state accessed through
+// the StateDB and all overlays created from it afterwards.
+func (self *StateDB) EnableAccessStats() {
+	self.stats = newAccessStats()
+}
+
+// AccessStats returns the state access statistics collected since calling
+// EnableAccessStats, or nil if not enabled.
+func (self *StateDB) AccessStats() *AccessStats {
+	return self.stats
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+)
+
+func TestAccessStats(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+	statedb, _ := New(common.Hash{}, NewDatabase(db))
+
+	var (
+		addr = common.HexToAddress("0x01")
+		key  = common.HexToHash("0x02")
+	)
+	for i := byte(0); i < 16; i++ {
+		statedb.AddBalance(common.BytesToAddress([]byte{0x10, i}), big.NewInt(1))
+	}
+	statedb.AddBalance(addr, big.NewInt(1))
+	statedb.SetState(addr, key, common.HexToHash("0x03"))
+	root, _ := statedb.CommitTo(db, false)
+
+	statedb, _ = New(root, NewDatabase(db))
+	statedb.EnableAccessStats()
+
+	statedb.GetBalance(addr)
+	statedb.GetBalance(addr)
+	statedb.GetState(addr, key)
+	statedb.Overlay().GetState(addr, key)
+
+	stats := statedb.AccessStats()
+	if stats.Accounts() != 1 || stats.Slots() != 1 {
+		t.Errorf("unique accesses mismatch: have %d/%d, want 1/1", stats.Accounts(), stats.Slots())
+	}
+	// The overlay loads the account and the slot from its own trie again
+	if stats.AccountHits != 2 || stats.AccountMisses != 2 {
+		t.Errorf("account cache mismatch: have %d/%d hits/misses, want 2/2", stats.AccountHits, stats.AccountMisses)
+	}
+	if stats.SlotHits != 0 || stats.SlotMisses != 2 {
+		t.Errorf("slot cache mismatch: have %d/%d hits/misses, want 0/2", stats.SlotHits, stats.SlotMisses)
+	}
+	// The account shares its first key nibble with another one, so lookups pass
+	// two full nodes before reaching its leaf
+	deep := 0
+	for depth := 3; depth < MaxStatsTrieDepth; depth++ {
+		deep += stats.AccountDepths[depth]
+	}
+	if deep != 2 {
+		t.Errorf("account depth mismatch: have %v", stats.AccountDepths)
+	}
+	if stats.SlotDepths[1] != 2 {
+		t.Errorf("slot depth mismatch: have %v", stats.SlotDepths)
+	}
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/Aurorachain-io/go-aoa/common"
 	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/rlp"
 	"github.com/Aurorachain-io/go-aoa/trie"
 	"github.com/hashicorp/golang-lru"
 )
@@ -56,6 +57,8 @@ type Database interface {
 	CopyTrie(Trie) Trie
 	// Accessing assetdata
 	AssetData(addrHash, assetHash common.Hash) ([]byte, error)
+	// TrieDB returns the node database the tries are committed to.
+	TrieDB() *trie.NodeDatabase
 }
 
 // Trie is a eminer-pro Merkle Trie.
@@ -67,17 +70,39 @@ type Trie interface {
 	Hash() common.Hash
 	NodeIterator(startKey []byte) trie.NodeIterator
 	GetKey([]byte) []byte // TODO(fjl): remove this when SecureTrie is removed
+	Prove(key []byte, fromLevel uint, proofDb trie.DatabaseWriter) error
 }
 
 // NewDatabase creates a backing store for state. The returned database is safe for
 // concurrent use and retains cached trie nodes in memory.
 func NewDatabase(db aoadb.Database) Database {
+	return NewDatabaseWithCache(db, 0)
+}
+
+// NewDatabaseWithCache creates a backing store for state like NewDatabase, also
+// caching up to the given number of megabytes of clean trie nodes read from disk.
+func NewDatabaseWithCache(db aoadb.Database, cache int) Database {
 	csc, _ := lru.New(codeSizeCacheSize)
-	return &cachingDB{db: db, codeSizeCache: csc}
+	return &cachingDB{
+		db:            db,
+		triedb:        trie.NewNodeDatabase(db, cache*1024*1024, storageRoots),
+		codeSizeCache: csc,
+	}
+}
+
+// storageRoots resolves the storage trie root referenced from an account leaf
+// of the account trie, so the node database can keep it alive along with it.
+func storageRoots(leaf []byte) []common.Hash {
+	var account Account
+	if err := rlp.DecodeBytes(leaf, &account); err != nil {
+		return nil
+	}
+	return []common.Hash{account.Root}
 }
 
 type cachingDB struct {
 	db            aoadb.Database
+	triedb        *trie.NodeDatabase
 	mu            sync.Mutex
 	pastTries     []*trie.SecureTrie
 	codeSizeCache *lru.Cache
@@ -92,7 +117,7 @@ func (db *cachingDB) OpenTrie(root common.Hash) (Trie, error) {
 			return cachedTrie{db.pastTries[i].Copy(), db}, nil
 		}
 	}
-	tr, err := trie.NewSecure(root, db.db, MaxTrieCacheGen)
+	tr, err := trie.NewSecure(root, db.triedb, MaxTrieCacheGen)
 	if err != nil {
 		return nil, err
 	}
@@ -112,7 +137,7 @@ func (db *cachingDB) pushTrie(t *trie.SecureTrie) {
 }
 
 func (db *cachingDB) OpenStorageTrie(addrHash, root common.Hash) (Trie, error) {
-	return trie.NewSecure(root, db.db, 0)
+	return trie.NewSecure(root, db.triedb, 0)
 }
 
 func (db *cachingDB) CopyTrie(t Trie) Trie {
@@ -161,6 +186,10 @@ func (db *cachingDB) AssetData(addrHash, assetHash common.Hash) ([]byte, error)
 	return db.db.Get(assetHash[:])
 }
 
+func (db *cachingDB) TrieDB() *trie.NodeDatabase {
+	return db.triedb
+}
+
 func AbiKey(codeHash []byte) []byte {
 	return append(codeHash, []byte(abiKeySuffix)...)
 }
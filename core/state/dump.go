@@ -21,17 +21,19 @@ import (
 	"fmt"
 
 	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/common/hexutil"
 	"github.com/Aurorachain-io/go-aoa/rlp"
 	"github.com/Aurorachain-io/go-aoa/trie"
 )
 
 type DumpAccount struct {
-	Balance  string            `json:"balance"`
-	Nonce    uint64            `json:"nonce"`
-	Root     string            `json:"root"`
-	CodeHash string            `json:"codeHash"`
-	Code     string            `json:"code"`
-	Storage  map[string]string `json:"storage"`
+	Balance   string            `json:"balance"`
+	Nonce     uint64            `json:"nonce"`
+	Root      string            `json:"root"`
+	CodeHash  string            `json:"codeHash"`
+	Code      string            `json:"code"`
+	Storage   map[string]string `json:"storage"`
+	SecureKey hexutil.Bytes     `json:"key,omitempty"` // Hashed trie key, only set in iterative dumps
 }
 
 type Dump struct {
@@ -39,6 +41,14 @@ type Dump struct {
 	Accounts map[string]DumpAccount `json:"accounts"`
 }
 
+// IteratorDump is a page of the accounts in the state, along with the hashed
+// trie key to continue iterating from.
+type IteratorDump struct {
+	Root     string                 `json:"root"`
+	Accounts map[string]DumpAccount `json:"accounts"`
+	Next     hexutil.Bytes          `json:"next,omitempty"` // nil if no more accounts are left
+}
+
 func (self *StateDB) RawDump() Dump {
 	dump := Dump{
 		Root:     fmt.Sprintf("%x", self.trie.Hash()),
@@ -48,27 +58,87 @@ func (self *StateDB) RawDump() Dump {
 	it := trie.NewIterator(self.trie.NodeIterator(nil))
 	for it.Next() {
 		addr := self.trie.GetKey(it.Key)
-		var data Account
-		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
+		account, err := self.dumpAccount(addr, it.Value, false, false)
+		if err != nil {
 			panic(err)
 		}
+		dump.Accounts[common.Bytes2Hex(addr)] = account
+	}
+	return dump
+}
+
+// IteratorDump dumps at most maxResults accounts of the state, starting at the
+// given hashed trie key. Accounts are keyed by address, or by their hashed key
+// if the address preimage is unknown. Code and storage may be excluded to keep
+// the pages small.
+func (self *StateDB) IteratorDump(start []byte, maxResults int, excludeCode, excludeStorage bool) (IteratorDump, error) {
+	dump := IteratorDump{
+		Root:     fmt.Sprintf("%x", self.trie.Hash()),
+		Accounts: make(map[string]DumpAccount),
+	}
+	it := trie.NewIterator(self.trie.NodeIterator(start))
+	for i := 0; i < maxResults && it.Next(); i++ {
+		addr := self.trie.GetKey(it.Key)
+		account, err := self.dumpAccount(addr, it.Value, excludeCode, excludeStorage)
+		if err != nil {
+			return IteratorDump{}, err
+		}
+		account.SecureKey = common.CopyBytes(it.Key)
 
-		obj := newObject(nil, common.BytesToAddress(addr), data, nil)
-		account := DumpAccount{
-			Balance:  data.Balance.String(),
-			Nonce:    data.Nonce,
-			Root:     common.Bytes2Hex(data.Root[:]),
-			CodeHash: common.Bytes2Hex(data.CodeHash),
-			Code:     common.Bytes2Hex(obj.Code(self.db)),
-			Storage:  make(map[string]string),
+		if addr != nil {
+			dump.Accounts[common.Bytes2Hex(addr)] = account
+		} else {
+			dump.Accounts[common.Bytes2Hex(it.Key)] = account
 		}
+	}
+	// Add the key to continue from, if there are accounts left
+	if it.Next() {
+		dump.Next = common.CopyBytes(it.Key)
+	}
+	return dump, it.Err
+}
+
+// dumpAccount assembles the dump of a single account from its trie entry.
+func (self *StateDB) dumpAccount(addr []byte, blob []byte, excludeCode, excludeStorage bool) (DumpAccount, error) {
+	var data Account
+	if err := rlp.DecodeBytes(blob, &data); err != nil {
+		return DumpAccount{}, err
+	}
+	obj := newObject(nil, common.BytesToAddress(addr), data, nil)
+	account := DumpAccount{
+		Balance:  data.Balance.String(),
+		Nonce:    data.Nonce,
+		Root:     common.Bytes2Hex(data.Root[:]),
+		CodeHash: common.Bytes2Hex(data.CodeHash),
+		Storage:  make(map[string]string),
+	}
+	if !excludeCode {
+		account.Code = common.Bytes2Hex(obj.Code(self.db))
+	}
+	if !excludeStorage {
 		storageIt := trie.NewIterator(obj.getTrie(self.db).NodeIterator(nil))
 		for storageIt.Next() {
 			account.Storage[common.Bytes2Hex(self.trie.GetKey(storageIt.Key))] = common.Bytes2Hex(storageIt.Value)
 		}
-		dump.Accounts[common.Bytes2Hex(addr)] = account
 	}
-	return dump
+	return account, nil
+}
+
+// ForEachAccount calls cb with the address of every account in the state, in the
+// order of their hashed trie keys, stopping at the first error. It fails for
+// accounts whose address preimage is unknown.
+func (self *StateDB) ForEachAccount(cb func(addr common.Address) error) error {
+	it := trie.NewIterator(self.trie.NodeIterator(nil))
+	for it.Next() {
+		addr := self.trie.GetKey(it.Key)
+		if addr == nil {
+			return fmt.Errorf("missing preimage of account %x", it.Key)
+		}
+		if err := cb(common.BytesToAddress(addr)); err != nil {
+			return err
+		}
+	}
+	return it.Err
 }
 
 func (self *StateDB) Dump() []byte {
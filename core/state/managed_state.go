@@ -36,10 +36,11 @@ type ManagedState struct {
 	accounts map[common.Address]*account
 }
 
-// ManagedState returns a new managed state with the statedb as it's backing layer
+// ManagedState returns a new managed state with an overlay of the statedb as
+// it's backing layer. The statedb must not be modified afterwards.
 func ManageState(statedb *StateDB) *ManagedState {
 	return &ManagedState{
-		StateDB:  statedb.Copy(),
+		StateDB:  statedb.Overlay(),
 		accounts: make(map[common.Address]*account),
 	}
 }
@@ -83,6 +83,7 @@ type stateObject struct {
 
 	cachedStorage Storage // Storage entry cache to avoid duplicate reads
 	dirtyStorage  Storage // Storage entries that need to be flushed to disk
+	fakeStorage   Storage // Storage replacing the original one, set for debugging purposes
 
 	// Cache flags.
 	// When an object is marked suicided it will be delete from the trie
@@ -118,6 +119,9 @@ func newObject(db *StateDB, address common.Address, data Account, onDirty func(a
 	if data.Balance == nil {
 		data.Balance = new(big.Int)
 	}
+	if data.LockBalance == nil {
+		data.LockBalance = new(big.Int)
+	}
 	if data.CodeHash == nil {
 		data.CodeHash = emptyCodeHash
 	}
@@ -184,12 +188,22 @@ func (c *stateObject) getTrie(db Database) Trie {
 
 // GetState returns a value in account storage.
 func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
+	// If the fake storage is set, only lookup the state here (in the debugging mode)
+	if self.fakeStorage != nil {
+		return self.fakeStorage[key]
+	}
 	value, exists := self.cachedStorage[key]
 	if exists {
+		if self.db.stats != nil {
+			self.db.stats.slot(self.address, key, true, nil)
+		}
 		return value
 	}
 	// Load from DB in case it is missing.
 	enc, err := self.getTrie(db).TryGet(key[:])
+	if self.db.stats != nil {
+		self.db.stats.slot(self.address, key, false, self.trie)
+	}
 	if err != nil {
 		self.setError(err)
 		return common.Hash{}
@@ -218,9 +232,24 @@ func (self *stateObject) SetState(db Database, key, value common.Hash) {
 }
 
 func (self *stateObject) setState(key, value common.Hash) {
-	self.cachedStorage[key] = value
-	self.dirtyStorage[key] = value
+	if self.fakeStorage != nil {
+		self.fakeStorage[key] = value
+	} else {
+		self.cachedStorage[key] = value
+		self.dirtyStorage[key] = value
+	}
+	self.tryMarkDirty()
+}
 
+// SetStorage replaces the entire storage with the given one. Afterwards the
+// original storage is ignored, lookups only hit the fake storage.
+//
+// Note this function should only be used for debugging purpose.
+func (self *stateObject) SetStorage(storage map[common.Hash]common.Hash) {
+	self.fakeStorage = make(Storage, len(storage))
+	for key, value := range storage {
+		self.fakeStorage[key] = value
+	}
 	self.tryMarkDirty()
 }
 
@@ -333,12 +362,19 @@ func (c *stateObject) ReturnGas(gas *big.Int) {}
 
 func (self *stateObject) deepCopy(db *StateDB, onDirty func(addr common.Address)) *stateObject {
 	stateObject := newObject(db, self.address, self.data, onDirty)
+	stateObject.data.AssetList = self.data.AssetList.Copy() // Asset balances are modified in place
 	if self.trie != nil {
 		stateObject.trie = db.db.CopyTrie(self.trie)
 	}
 	stateObject.code = self.code
+	stateObject.abi = self.abi
+	stateObject.assetData = self.assetData
+	stateObject.dirtyAssetData = self.dirtyAssetData
 	stateObject.dirtyStorage = self.dirtyStorage.Copy()
 	stateObject.cachedStorage = self.dirtyStorage.Copy()
+	if self.fakeStorage != nil {
+		stateObject.fakeStorage = self.fakeStorage.Copy()
+	}
 	stateObject.suicided = self.suicided
 	stateObject.dirtyCode = self.dirtyCode
 	stateObject.deleted = self.deleted
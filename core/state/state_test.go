@@ -86,6 +86,44 @@ func (s *StateSuite) TestDump(c *checker.C) {
 	}
 }
 
+func (s *StateSuite) TestIteratorDump(c *checker.C) {
+	// generate a few entries and write them to the trie
+	for i := byte(1); i <= 5; i++ {
+		obj := s.state.GetOrNewStateObject(toAddr([]byte{i}))
+		obj.AddBalance(big.NewInt(int64(i)))
+		obj.SetCode(crypto.Keccak256Hash([]byte{i}), []byte{i})
+		s.state.updateStateObject(obj)
+	}
+	s.state.CommitTo(s.db, false)
+
+	// page through the accounts and check that all of them are returned once
+	var (
+		start []byte
+		seen  = make(map[string]bool)
+		pages int
+	)
+	for {
+		dump, err := s.state.IteratorDump(start, 2, true, true)
+		c.Assert(err, checker.IsNil)
+		c.Assert(len(dump.Accounts) <= 2, checker.Equals, true)
+
+		for addr, account := range dump.Accounts {
+			c.Assert(seen[addr], checker.Equals, false)
+			c.Assert(account.Code, checker.Equals, "")
+			c.Assert(len(account.SecureKey), checker.Equals, 32)
+			seen[addr] = true
+		}
+		pages++
+		if dump.Next == nil {
+			break
+		}
+		start = dump.Next
+	}
+	c.Assert(pages, checker.Equals, 3)
+	c.Assert(len(seen), checker.Equals, 5)
+	c.Assert(seen["0000000000000000000000000000000000000003"], checker.Equals, true)
+}
+
 func (s *StateSuite) SetUpTest(c *checker.C) {
 	s.db, _ = emdb.NewMemDatabase()
 	s.state, _ = New(common.Hash{}, NewDatabase(s.db))
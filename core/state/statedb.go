@@ -52,6 +52,15 @@ type StateDB struct {
 	stateObjects      map[common.Address]*stateObject
 	stateObjectsDirty map[common.Address]struct{}
 
+	// Dirty objects of the state this one overlays, copied on first access.
+	overlaid map[common.Address]*stateObject
+
+	// Accounts accessed since access tracking was started, nil if not tracking.
+	access *AccessSet
+
+	// Statistics of the state accessed, shared with overlays, nil if disabled.
+	stats *AccessStats
+
 	// DB error.
 	// State objects are used by the consensus core and VM which are
 	// unable to deal with database-level errors. Any error that occurs
@@ -116,6 +125,9 @@ func (self *StateDB) Reset(root common.Hash) error {
 	self.trie = tr
 	self.stateObjects = make(map[common.Address]*stateObject)
 	self.stateObjectsDirty = make(map[common.Address]struct{})
+	self.overlaid = nil
+	self.access = nil
+	self.stats = nil
 	self.thash = common.Hash{}
 	self.bhash = common.Hash{}
 	self.txIndex = 0
@@ -200,6 +212,31 @@ func (self *StateDB) GetLockBalance(addr common.Address) *big.Int {
 	return common.Big0
 }
 
+// BalanceChanges returns the accounts whose balance or locked balance changed
+// since the state was last finalised, in the order of their first change.
+func (self *StateDB) BalanceChanges() []common.Address {
+	var (
+		changed []common.Address
+		seen    = make(map[common.Address]bool)
+	)
+	for _, entry := range self.journal {
+		var addr *common.Address
+		switch change := entry.(type) {
+		case balanceChange:
+			addr = change.account
+		case lockBalanceChange:
+			addr = change.account
+		default:
+			continue
+		}
+		if !seen[*addr] {
+			seen[*addr] = true
+			changed = append(changed, *addr)
+		}
+	}
+	return changed
+}
+
 func (self *StateDB) GetAssetBalance(addr common.Address, asset common.Address) *big.Int {
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
@@ -216,6 +253,16 @@ func (self *StateDB) GetAssets(addr common.Address) []types.Asset {
 	return nil
 }
 
+// IsAssetAccount reports whether the given address holds the info of a
+// published asset.
+func (self *StateDB) IsAssetAccount(addr common.Address) bool {
+	stateObject := self.getStateObject(addr)
+	if stateObject != nil {
+		return stateObject.IsAssetAccount()
+	}
+	return false
+}
+
 func (self *StateDB) GetVoteList(addr common.Address) []common.Address {
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
@@ -291,6 +338,35 @@ func (self *StateDB) StorageTrie(a common.Address) Trie {
 	return cpy.updateTrie(self.db)
 }
 
+// GetProof returns the merkle proof of the account with the given address
+// against the state root.
+func (self *StateDB) GetProof(a common.Address) ([][]byte, error) {
+	var proof trie.ProofList
+	err := self.trie.Prove(crypto.Keccak256(a.Bytes()), 0, &proof)
+	return [][]byte(proof), err
+}
+
+// GetStorageProof returns the merkle proof of a storage slot of the account with
+// the given address against the account's storage root.
+func (self *StateDB) GetStorageProof(a common.Address, key common.Hash) ([][]byte, error) {
+	var proof trie.ProofList
+	storage := self.StorageTrie(a)
+	if storage == nil {
+		return proof, errors.New("storage trie for requested address does not exist")
+	}
+	err := storage.Prove(crypto.Keccak256(key.Bytes()), 0, &proof)
+	return [][]byte(proof), err
+}
+
+// GetStorageRoot returns the storage root of the account with the given address.
+func (self *StateDB) GetStorageRoot(a common.Address) common.Hash {
+	stateObject := self.getStateObject(a)
+	if stateObject == nil {
+		return common.Hash{}
+	}
+	return stateObject.data.Root
+}
+
 func (self *StateDB) HasSuicided(addr common.Address) bool {
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
@@ -305,7 +381,14 @@ func (self *StateDB) HasSuicided(addr common.Address) bool {
 
 // AddBalance adds amount to the account associated with addr
 func (self *StateDB) AddBalance(addr common.Address, amount *big.Int) {
+	if self.access != nil {
+		self.access.crediting = true
+	}
 	stateObject := self.GetOrNewStateObject(addr)
+	if self.access != nil {
+		self.access.crediting = false
+		self.access.credit(addr, stateObject.Balance())
+	}
 	if stateObject != nil {
 		stateObject.AddBalance(amount)
 	}
@@ -400,6 +483,15 @@ func (self *StateDB) SetState(addr common.Address, key common.Hash, value common
 	}
 }
 
+// SetStorage replaces the entire storage of the specified account with the given
+// one. This function should only be used for debugging.
+func (self *StateDB) SetStorage(addr common.Address, storage map[common.Hash]common.Hash) {
+	stateObject := self.GetOrNewStateObject(addr)
+	if stateObject != nil {
+		stateObject.SetStorage(storage)
+	}
+}
+
 // Suicide marks the given account as suicided.
 // This clears the account balance.
 //
@@ -444,16 +536,39 @@ func (self *StateDB) deleteStateObject(stateObject *stateObject) {
 
 // Retrieve a state object given my the address. Returns nil if not found.
 func (self *StateDB) getStateObject(addr common.Address) (stateObject *stateObject) {
+	if self.access != nil {
+		self.access.read(addr)
+	}
 	// Prefer 'live' objects.
 	if obj := self.stateObjects[addr]; obj != nil {
+		if self.stats != nil {
+			self.stats.account(addr, true, nil)
+		}
 		if obj.deleted {
 			return nil
 		}
 		return obj
 	}
+	// Copy the object of an overlaid state on first access.
+	if obj := self.overlaid[addr]; obj != nil {
+		if self.stats != nil {
+			self.stats.account(addr, true, nil)
+		}
+		delete(self.overlaid, addr)
 
+		cpy := obj.deepCopy(self, self.MarkStateObjectDirty)
+		self.setStateObject(cpy)
+		self.stateObjectsDirty[addr] = struct{}{}
+		if cpy.deleted {
+			return nil
+		}
+		return cpy
+	}
 	// Load the object from the database.
 	enc, err := self.trie.TryGet(addr[:])
+	if self.stats != nil {
+		self.stats.account(addr, false, self.trie)
+	}
 	if len(enc) == 0 {
 		self.setError(err)
 		return nil
@@ -520,25 +635,42 @@ func (self *StateDB) CreateAccount(addr common.Address) {
 	}
 }
 
-func (db *StateDB) ForEachStorage(addr common.Address, cb func(key, value common.Hash) bool) {
+// ForEachStorage calls cb with every storage slot of the given account until
+// it returns false. It fails for slots whose key preimage is unknown.
+func (db *StateDB) ForEachStorage(addr common.Address, cb func(key, value common.Hash) bool) error {
 	so := db.getStateObject(addr)
 	if so == nil {
-		return
+		return nil
 	}
 
 	// When iterating over the storage check the cache first
 	for h, value := range so.cachedStorage {
-		cb(h, value)
+		if !cb(h, value) {
+			return nil
+		}
 	}
 
-	it := trie.NewIterator(so.getTrie(db.db).NodeIterator(nil))
+	tr := so.getTrie(db.db)
+	it := trie.NewIterator(tr.NodeIterator(nil))
 	for it.Next() {
+		preimage := tr.GetKey(it.Key)
+		if preimage == nil {
+			return fmt.Errorf("missing preimage of storage slot %x of account %x", it.Key, addr)
+		}
 		// ignore cached values
-		key := common.BytesToHash(db.trie.GetKey(it.Key))
-		if _, ok := so.cachedStorage[key]; !ok {
-			cb(key, common.BytesToHash(it.Value))
+		key := common.BytesToHash(preimage)
+		if _, ok := so.cachedStorage[key]; ok {
+			continue
+		}
+		_, content, _, err := rlp.Split(it.Value)
+		if err != nil {
+			return err
+		}
+		if !cb(key, common.BytesToHash(content)) {
+			return nil
 		}
 	}
+	return it.Err
 }
 
 // Copy creates a deep, independent copy of the state.
@@ -570,7 +702,43 @@ func (self *StateDB) Copy() *StateDB {
 	for hash, preimage := range self.preimages {
 		state.preimages[hash] = preimage
 	}
+	if len(self.overlaid) > 0 {
+		state.overlaid = make(map[common.Address]*stateObject, len(self.overlaid))
+		for addr, obj := range self.overlaid {
+			state.overlaid[addr] = obj
+		}
+	}
+	return state
+}
 
+// Overlay creates a copy-on-write view of the state for speculative execution.
+// Unlike Copy, no state object is deep-copied upfront: the overlay shares the
+// dirty objects of its parent and copies each one only when first accessed, all
+// other accounts are read from a copy of the parent's trie. Modifications of the
+// overlay never reach the parent, but the parent must not be modified while the
+// overlay is in use. Overlays may be nested and share the access statistics of
+// their parent.
+func (self *StateDB) Overlay() *StateDB {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	state := &StateDB{
+		db:                self.db,
+		trie:              self.db.CopyTrie(self.trie),
+		stateObjects:      make(map[common.Address]*stateObject),
+		stateObjectsDirty: make(map[common.Address]struct{}),
+		overlaid:          make(map[common.Address]*stateObject, len(self.stateObjectsDirty)+len(self.overlaid)),
+		refund:            self.refund,
+		logs:              make(map[common.Hash][]*types.Log),
+		preimages:         make(map[common.Hash][]byte),
+		stats:             self.stats,
+	}
+	for addr, obj := range self.overlaid {
+		state.overlaid[addr] = obj
+	}
+	for addr := range self.stateObjectsDirty {
+		state.overlaid[addr] = self.stateObjects[addr]
+	}
 	return state
 }
 
@@ -611,6 +779,7 @@ func (self *StateDB) GetRefund() uint64 {
 // Finalise finalises the state by removing the self destructed objects
 // and clears the journal as well as the refunds.
 func (s *StateDB) Finalise(deleteEmptyObjects bool) {
+	s.inheritOverlaid()
 	for addr := range s.stateObjectsDirty {
 		stateObject := s.stateObjects[addr]
 		if stateObject.suicided || (deleteEmptyObjects && stateObject.empty()) {
@@ -624,6 +793,14 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 	s.clearJournalAndRefund()
 }
 
+// inheritOverlaid copies all not yet accessed objects of an overlaid state, so
+// that their changes are included when updating the trie.
+func (s *StateDB) inheritOverlaid() {
+	for addr := range s.overlaid {
+		s.getStateObject(addr)
+	}
+}
+
 // IntermediateRoot computes the current root hash of the state trie.
 // It is called in between transactions to get the root hash that
 // goes into transaction receipts.
@@ -648,7 +825,22 @@ func (s *StateDB) clearJournalAndRefund() {
 
 // CommitTo writes the state to the given database.
 func (s *StateDB) CommitTo(dbw trie.DatabaseWriter, deleteEmptyObjects bool) (root common.Hash, err error) {
+	return s.commit(dbw, dbw, deleteEmptyObjects)
+}
+
+// Commit writes the trie nodes of the state into the node database of the
+// state's backing store, leaving it to the owner of the node database to flush
+// them. Contract code, abis and asset data are written to disk directly.
+func (s *StateDB) Commit(deleteEmptyObjects bool) (root common.Hash, err error) {
+	triedb := s.db.TrieDB()
+	return s.commit(triedb.DiskDB(), triedb, deleteEmptyObjects)
+}
+
+// commit writes the trie nodes of the state to triew and all other data needed
+// to access it to dataw.
+func (s *StateDB) commit(dataw, triew trie.DatabaseWriter, deleteEmptyObjects bool) (root common.Hash, err error) {
 	defer s.clearJournalAndRefund()
+	s.inheritOverlaid()
 
 	// Commit objects to the trie.
 	for addr, stateObject := range s.stateObjects {
@@ -661,25 +853,25 @@ func (s *StateDB) CommitTo(dbw trie.DatabaseWriter, deleteEmptyObjects bool) (ro
 		case isDirty:
 			// Write any contract code associated with the state object
 			if stateObject.code != nil && stateObject.dirtyCode {
-				if err := dbw.Put(stateObject.CodeHash(), stateObject.code); err != nil {
+				if err := dataw.Put(stateObject.CodeHash(), stateObject.code); err != nil {
 					return common.Hash{}, err
 				}
 				stateObject.dirtyCode = false
 				if len(stateObject.abi) > 0 {
 					abikey := AbiKey(stateObject.CodeHash())
-					if err := dbw.Put(abikey, []byte(stateObject.abi)); err != nil {
+					if err := dataw.Put(abikey, []byte(stateObject.abi)); err != nil {
 						return common.Hash{}, err
 					}
 				}
 			}
 			if stateObject.assetData != nil && stateObject.dirtyAssetData {
-				if err := dbw.Put(stateObject.AssetHash(), stateObject.assetData); err != nil {
+				if err := dataw.Put(stateObject.AssetHash(), stateObject.assetData); err != nil {
 					return common.Hash{}, err
 				}
 				stateObject.dirtyAssetData = false
 			}
 			// Write any storage changes in the state object to its storage trie.
-			if err := stateObject.CommitTrie(s.db, dbw); err != nil {
+			if err := stateObject.CommitTrie(s.db, triew); err != nil {
 				return common.Hash{}, err
 			}
 			// Update the object in the main account trie.
@@ -688,7 +880,7 @@ func (s *StateDB) CommitTo(dbw trie.DatabaseWriter, deleteEmptyObjects bool) (ro
 		delete(s.stateObjectsDirty, addr)
 	}
 	// Write trie changes.
-	root, err = s.trie.CommitTo(dbw)
+	root, err = s.trie.CommitTo(triew)
 	return root, err
 }
 
@@ -0,0 +1,76 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+)
+
+func TestOverlay(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+	parent, _ := New(common.Hash{}, NewDatabase(db))
+
+	var (
+		committed = common.HexToAddress("0x01")
+		dirty     = common.HexToAddress("0x02")
+		fresh     = common.HexToAddress("0x03")
+	)
+	parent.AddBalance(committed, big.NewInt(10))
+	root, _ := parent.CommitTo(db, false)
+	parent, _ = New(root, NewDatabase(db))
+	parent.AddBalance(dirty, big.NewInt(20)) // not yet flushed into the trie
+
+	overlay := parent.Overlay()
+	if have := overlay.GetBalance(committed); have.Cmp(big.NewInt(10)) != 0 {
+		t.Fatalf("committed balance mismatch: have %v, want 10", have)
+	}
+	if have := overlay.GetBalance(dirty); have.Cmp(big.NewInt(20)) != 0 {
+		t.Fatalf("dirty balance mismatch: have %v, want 20", have)
+	}
+	// Modifications of the overlay and its snapshots must not leak into the parent
+	snap := overlay.Snapshot()
+	overlay.AddBalance(dirty, big.NewInt(1))
+	overlay.AddBalance(fresh, big.NewInt(3))
+	inner := overlay.Snapshot()
+	overlay.AddBalance(fresh, big.NewInt(4))
+	overlay.RevertToSnapshot(inner)
+
+	if have := overlay.GetBalance(fresh); have.Cmp(big.NewInt(3)) != 0 {
+		t.Fatalf("reverted overlay balance mismatch: have %v, want 3", have)
+	}
+	if have := parent.GetBalance(dirty); have.Cmp(big.NewInt(20)) != 0 {
+		t.Fatalf("parent balance modified: have %v, want 20", have)
+	}
+	if parent.Exist(fresh) {
+		t.Fatalf("overlay account leaked into parent")
+	}
+	overlay.RevertToSnapshot(snap)
+
+	// A nested overlay must produce the same root as a deep copy
+	nested := overlay.Overlay()
+	nested.AddBalance(fresh, big.NewInt(5))
+	cpy := parent.Copy()
+	cpy.AddBalance(fresh, big.NewInt(5))
+
+	if have, want := nested.IntermediateRoot(false), cpy.IntermediateRoot(false); have != want {
+		t.Fatalf("overlay root mismatch: have %x, want %x", have, want)
+	}
+}
@@ -196,6 +196,62 @@ func TestCopy(t *testing.T) {
 	}
 }
 
+// Tests that replacing the storage of an account hides all its original slots,
+// while still allowing the replaced storage to be modified and reverted.
+func TestSetStorage(t *testing.T) {
+	mem, _ := emdb.NewMemDatabase()
+	state, _ := New(common.Hash{}, NewDatabase(mem))
+
+	addr := common.BytesToAddress([]byte{0x01})
+	state.SetState(addr, common.Hash{0x01}, common.Hash{0x11})
+	state.SetState(addr, common.Hash{0x02}, common.Hash{0x22})
+	root, _ := state.CommitTo(mem, false)
+	state, _ = New(root, NewDatabase(mem))
+
+	state.SetStorage(addr, map[common.Hash]common.Hash{{0x02}: {0x33}})
+	if value := state.GetState(addr, common.Hash{0x01}); value != (common.Hash{}) {
+		t.Errorf("original slot visible: have %x, want empty", value)
+	}
+	if value := state.GetState(addr, common.Hash{0x02}); value != (common.Hash{0x33}) {
+		t.Errorf("replaced slot mismatch: have %x, want %x", value, common.Hash{0x33})
+	}
+	snapshot := state.Snapshot()
+	state.SetState(addr, common.Hash{0x02}, common.Hash{0x44})
+	if value := state.Copy().GetState(addr, common.Hash{0x02}); value != (common.Hash{0x44}) {
+		t.Errorf("copied slot mismatch: have %x, want %x", value, common.Hash{0x44})
+	}
+	state.RevertToSnapshot(snapshot)
+	if value := state.GetState(addr, common.Hash{0x02}); value != (common.Hash{0x33}) {
+		t.Errorf("reverted slot mismatch: have %x, want %x", value, common.Hash{0x33})
+	}
+}
+
+// Tests that iterating the storage of an account fails if the preimage of a slot
+// key is unknown, instead of reporting the slot under a wrong key.
+func TestForEachStorageMissingPreimage(t *testing.T) {
+	mem, _ := emdb.NewMemDatabase()
+	state, _ := New(common.Hash{}, NewDatabase(mem))
+
+	addr := common.BytesToAddress([]byte{0x01})
+	state.SetState(addr, common.Hash{0x01}, common.Hash{0x11})
+	root, _ := state.CommitTo(mem, false)
+
+	state, _ = New(root, NewDatabase(mem))
+	slots := 0
+	if err := state.ForEachStorage(addr, func(key, value common.Hash) bool { slots++; return true }); err != nil || slots != 1 {
+		t.Fatalf("storage iteration mismatch: have %d slots, err %v, want 1 slot", slots, err)
+	}
+	for _, key := range mem.Keys() {
+		if bytes.HasPrefix(key, []byte("secure-key-")) {
+			mem.Delete(key)
+		}
+	}
+	state, _ = New(root, NewDatabase(mem))
+	if err := state.ForEachStorage(addr, func(key, value common.Hash) bool { return true }); err == nil {
+		t.Errorf("storage iterated without slot preimages")
+	}
+}
+
 func TestSnapshotRandom(t *testing.T) {
 	config := &quick.Config{MaxCount: 1000}
 	err := quick.Check((*snapshotTest).run, config)
@@ -0,0 +1,113 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+)
+
+// MissingStateError is returned if the state of a block is requested that is not
+// (or not fully) available locally, e.g. because it was pruned.
+type MissingStateError struct {
+	Number uint64      // Number of the block whose state was requested
+	Root   common.Hash // State root of the block
+	Err    error       // Underlying database error
+}
+
+// Error implements the error interface.
+func (e *MissingStateError) Error() string {
+	return fmt.Sprintf("missing state of block #%d [%x…]: %v", e.Number, e.Root.Bytes()[:4], e.Err)
+}
+
+// StateAtHeader returns a new mutable state based on the given header, failing
+// with a MissingStateError if the state root is unavailable.
+func (bc *BlockChain) StateAtHeader(header *types.Header) (*state.StateDB, error) {
+	statedb, err := bc.StateAt(header.Root)
+	if err != nil {
+		return nil, &MissingStateError{Number: header.Number.Uint64(), Root: header.Root, Err: err}
+	}
+	return statedb, nil
+}
+
+// StateAtNumber returns a new mutable state based on the canonical block with
+// the given number.
+func (bc *BlockChain) StateAtNumber(number uint64) (*state.StateDB, error) {
+	header := bc.GetHeaderByNumber(number)
+	if header == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	return bc.StateAtHeader(header)
+}
+
+// readStateAt runs a read against the state of the canonical block with the
+// given number, converting any trie node missing on access into an error.
+func (bc *BlockChain) readStateAt(number uint64, read func(statedb *state.StateDB)) error {
+	header := bc.GetHeaderByNumber(number)
+	if header == nil {
+		return fmt.Errorf("block #%d not found", number)
+	}
+	statedb, err := bc.StateAtHeader(header)
+	if err != nil {
+		return err
+	}
+	read(statedb)
+	if err := statedb.Error(); err != nil {
+		return &MissingStateError{Number: number, Root: header.Root, Err: err}
+	}
+	return nil
+}
+
+// GetBalanceAt returns the balance of an account at the given canonical block.
+func (bc *BlockChain) GetBalanceAt(addr common.Address, number uint64) (*big.Int, error) {
+	var balance *big.Int
+	err := bc.readStateAt(number, func(statedb *state.StateDB) {
+		balance = statedb.GetBalance(addr)
+	})
+	return balance, err
+}
+
+// GetNonceAt returns the nonce of an account at the given canonical block.
+func (bc *BlockChain) GetNonceAt(addr common.Address, number uint64) (uint64, error) {
+	var nonce uint64
+	err := bc.readStateAt(number, func(statedb *state.StateDB) {
+		nonce = statedb.GetNonce(addr)
+	})
+	return nonce, err
+}
+
+// GetCodeAt returns the contract code of an account at the given canonical block.
+func (bc *BlockChain) GetCodeAt(addr common.Address, number uint64) ([]byte, error) {
+	var code []byte
+	err := bc.readStateAt(number, func(statedb *state.StateDB) {
+		code = statedb.GetCode(addr)
+	})
+	return code, err
+}
+
+// GetStorageAt returns a storage slot of an account at the given canonical block.
+func (bc *BlockChain) GetStorageAt(addr common.Address, key common.Hash, number uint64) (common.Hash, error) {
+	var value common.Hash
+	err := bc.readStateAt(number, func(statedb *state.StateDB) {
+		value = statedb.GetState(addr, key)
+	})
+	return value, err
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/vm"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+func TestHistoricalStateAccess(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+	var (
+		addr    = common.HexToAddress("0x0100")
+		key     = common.HexToHash("0x01")
+		value   = common.HexToHash("0x0202")
+		code    = []byte{0x60, 0x00}
+		genesis = &Genesis{
+			Config: params.AllDacchainProtocolChanges,
+			Alloc: GenesisAlloc{addr: {
+				Balance: big.NewInt(1000),
+				Nonce:   3,
+				Code:    code,
+				Storage: map[common.Hash]common.Hash{key: value},
+			}},
+			Agents: GenesisAgents{{Address: "0x0200", Vote: 1, Nickname: "test"}},
+		}
+	)
+	genesis.MustCommit(db)
+	chain, err := NewBlockChain(db, nil, params.AllDacchainProtocolChanges, nil, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if balance, err := chain.GetBalanceAt(addr, 0); err != nil || balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("balance mismatch: have %v/%v, want 1000/nil", balance, err)
+	}
+	if nonce, err := chain.GetNonceAt(addr, 0); err != nil || nonce != 3 {
+		t.Errorf("nonce mismatch: have %v/%v, want 3/nil", nonce, err)
+	}
+	if have, err := chain.GetCodeAt(addr, 0); err != nil || !bytes.Equal(have, code) {
+		t.Errorf("code mismatch: have %x/%v, want %x/nil", have, err, code)
+	}
+	if have, err := chain.GetStorageAt(addr, key, 0); err != nil || have != value {
+		t.Errorf("storage mismatch: have %x/%v, want %x/nil", have, err, value)
+	}
+	if _, err := chain.GetBalanceAt(addr, 1); err == nil {
+		t.Errorf("no error for unknown block")
+	}
+	// Drop the state root and ensure the error is reported instead of a panic
+	db.Delete(chain.Genesis().Root().Bytes())
+	chain.stateCache = state.NewDatabase(db)
+
+	if _, err := chain.GetBalanceAt(addr, 0); err == nil {
+		t.Errorf("no error for missing state")
+	} else if _, ok := err.(*MissingStateError); !ok {
+		t.Errorf("error type mismatch: have %T, want *MissingStateError", err)
+	}
+}
@@ -82,7 +82,9 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		allLogs = append(allLogs, receipt.Logs...)
 	}
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
-	p.engine.Finalize(p.bc, header, statedb, db, block.Transactions(), receipts)
+	if _, err := p.engine.Finalize(p.bc, header, statedb, db, block.Transactions(), receipts); err != nil {
+		return nil, nil, 0, err
+	}
 
 	return receipts, allLogs, *usedGas, nil
 }
@@ -92,10 +94,28 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 // for the transaction, gas used and an error if the transaction failed,
 // indicating the block was invalid.
 func ApplyTransaction(config *params.ChainConfig, bc *BlockChain, author *common.Address, gp *GasPool, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, cfg vm.Config, db *delegatestate.DelegateDB, blockTime uint64, watchInnerTx bool) (*types.Receipt, uint64, error) {
-	msg, err := tx.AsMessage(types.MakeSigner(config, header.Number))
+	receipt, gas, innerTxs, err := applyTransaction(config, bc, author, gp, statedb, header, tx, usedGas, cfg, db, blockTime, watchInnerTx)
 	if err != nil {
 		return nil, 0, err
 	}
+	//inner transactions
+	if len(innerTxs) > 0 {
+		itxerr := bc.innerTxDb.Set(tx.Hash(), innerTxs)
+		if nil != itxerr {
+			log.Warn("save inner transactions error", "err", itxerr)
+		}
+	}
+	return receipt, gas, nil
+}
+
+// applyTransaction applies a transaction like ApplyTransaction, reading the
+// chain through the given context and returning the inner transactions watched
+// instead of storing them.
+func applyTransaction(config *params.ChainConfig, bc ChainContext, author *common.Address, gp *GasPool, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, cfg vm.Config, db *delegatestate.DelegateDB, blockTime uint64, watchInnerTx bool) (*types.Receipt, uint64, []*types.InnerTx, error) {
+	msg, err := tx.AsMessage(types.MakeSigner(config, header.Number))
+	if err != nil {
+		return nil, 0, nil, err
+	}
 	// Create a new context to be used in the EVM environment
 	context := NewEVMContext(msg, header, bc, author)
 	// Create a new environment which holds all relevant information
@@ -105,12 +125,18 @@ func ApplyTransaction(config *params.ChainConfig, bc *BlockChain, author *common
 	// Apply the transaction to the current state (included in the env)
 	_, gas, failed, err := ApplyMessage(vmenv, msg, gp)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
 	err = voteChangeToDelegateState(msg.From(), tx, statedb, db, blockTime, header.Number.Int64())
 	// log.Debug("applyTransaction|voteChangeToDelegateState cost", "timestamp", time.Now().Sub(now4))
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
+	}
+	if config.IsVoteDecay(header.Number) {
+		if err := refreshVotes(msg.From(), tx, statedb, db, config.VoteDecayPeriod(header.Number)); err != nil {
+			return nil, 0, nil, err
+		}
+		db.RestakeVoters(statedb, statedb.BalanceChanges())
 	}
 	// Update the state with pending changes
 	statedb.Finalise(true)
@@ -130,15 +156,7 @@ func ApplyTransaction(config *params.ChainConfig, bc *BlockChain, author *common
 	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
 	//now4 := time.Now()
 
-	//inner transactions
-	if len(vmenv.InnerTxs) > 0 {
-		itxerr := bc.innerTxDb.Set(tx.Hash(), vmenv.InnerTxs)
-		if nil != itxerr {
-			log.Warn("save inner transactions error", "err", itxerr)
-		}
-	}
-
-	return receipt, gas, err
+	return receipt, gas, vmenv.InnerTxs, err
 }
 
 // trx vote change to delegate state
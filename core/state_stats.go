@@ -0,0 +1,79 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync/atomic"
+
+	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/metrics"
+)
+
+var (
+	stateAccountsHistogram = metrics.NewHistogram("state/access/accounts") // Unique accounts accessed per block
+	stateSlotsHistogram    = metrics.NewHistogram("state/access/slots")    // Unique storage slots accessed per block
+
+	stateAccountDepthHistogram = metrics.NewHistogram("state/access/account/depth") // Account trie lookup depths
+	stateSlotDepthHistogram    = metrics.NewHistogram("state/access/slot/depth")    // Storage trie lookup depths
+
+	stateAccountHitMeter  = metrics.NewMeter("state/access/account/hits")
+	stateAccountMissMeter = metrics.NewMeter("state/access/account/misses")
+	stateSlotHitMeter     = metrics.NewMeter("state/access/slot/hits")
+	stateSlotMissMeter    = metrics.NewMeter("state/access/slot/misses")
+)
+
+// SetStateStats toggles collecting statistics of the state accessed while
+// processing blocks. The statistics are aggregated into the state/access
+// metrics, informing the sizing of caches.
+func (bc *BlockChain) SetStateStats(enabled bool) {
+	var flag int32
+	if enabled {
+		flag = 1
+	}
+	atomic.StoreInt32(&bc.stateStats, flag)
+}
+
+// StateStats reports whether state access statistics are collected.
+func (bc *BlockChain) StateStats() bool {
+	return atomic.LoadInt32(&bc.stateStats) == 1
+}
+
+// reportStateStats aggregates the state access statistics of a processed block
+// into the metrics.
+func reportStateStats(block *types.Block, stats *state.AccessStats) {
+	accounts, slots := stats.Accounts(), stats.Slots()
+
+	stateAccountsHistogram.Update(int64(accounts))
+	stateSlotsHistogram.Update(int64(slots))
+	for depth := 0; depth < state.MaxStatsTrieDepth; depth++ {
+		for i := 0; i < stats.AccountDepths[depth]; i++ {
+			stateAccountDepthHistogram.Update(int64(depth))
+		}
+		for i := 0; i < stats.SlotDepths[depth]; i++ {
+			stateSlotDepthHistogram.Update(int64(depth))
+		}
+	}
+	stateAccountHitMeter.Mark(int64(stats.AccountHits))
+	stateAccountMissMeter.Mark(int64(stats.AccountMisses))
+	stateSlotHitMeter.Mark(int64(stats.SlotHits))
+	stateSlotMissMeter.Mark(int64(stats.SlotMisses))
+
+	log.Debug("State access statistics", "number", block.Number(), "hash", block.Hash(), "accounts", accounts, "slots", slots,
+		"accounthits", stats.AccountHits, "accountmisses", stats.AccountMisses, "slothits", stats.SlotHits, "slotmisses", stats.SlotMisses)
+}
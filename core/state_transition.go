@@ -59,6 +59,7 @@ type StateTransition struct {
 	data       []byte
 	state      vm.StateDB
 	evm        *vm.EVM
+	vmerr      error // error the EVM execution failed with, if any
 }
 
 // Message represents a message sent to a contract.
@@ -80,6 +81,7 @@ type Message interface {
 	AssetInfo() types.AssetInfo
 	SubAddress() string
 	Abi() string
+	FeePayer() *common.Address
 }
 
 // IntrinsicGas computes the 'intrinsic gas' for a message with the given data.
@@ -125,6 +127,21 @@ func IntrinsicGas(data []byte, action uint64) (uint64, error) {
 	return gas, nil
 }
 
+// InitCodeGas checks the init code of a contract creation transaction against
+// the given contract limits, returning the gas metered for it on top of the
+// intrinsic gas.
+func InitCodeGas(limits params.ContractLimitsFork, code []byte) (uint64, error) {
+	size := uint64(len(code))
+	if limits.MaxInitCodeSize != 0 && size > limits.MaxInitCodeSize {
+		return 0, ErrMaxInitCodeSizeExceeded
+	}
+	words := (size + 31) / 32
+	if limits.InitCodeWordGas != 0 && math.MaxUint64/limits.InitCodeWordGas < words {
+		return 0, vm.ErrOutOfGas
+	}
+	return words * limits.InitCodeWordGas, nil
+}
+
 // NewStateTransition initialises and returns a new state transition object.
 func NewStateTransition(evm *vm.EVM, msg Message, gp *GasPool) *StateTransition {
 	return &StateTransition{
@@ -149,6 +166,39 @@ func ApplyMessage(evm *vm.EVM, msg Message, gp *GasPool) ([]byte, uint64, bool,
 	return NewStateTransition(evm, msg, gp).TransitionDb()
 }
 
+// ExecutionResult is the outcome of applying a message, including the error the
+// EVM execution failed with, which doesn't make the message invalid.
+type ExecutionResult struct {
+	UsedGas    uint64 // Gas used by the execution, including refunds
+	Err        error  // Error the execution failed with (e.g. out of gas or reverted)
+	ReturnData []byte // Data returned by the execution, or the revert data
+}
+
+// Failed reports whether the execution failed.
+func (result *ExecutionResult) Failed() bool { return result.Err != nil }
+
+// Revert returns the data returned along with a revert, nil if the execution
+// didn't revert.
+func (result *ExecutionResult) Revert() []byte {
+	if result.Err != vm.ErrExecutionReverted {
+		return nil
+	}
+	return common.CopyBytes(result.ReturnData)
+}
+
+// ApplyMessageResult is like ApplyMessage, but reports the outcome of the
+// execution in detail. The error returned is the same core error ApplyMessage
+// returns.
+func ApplyMessageResult(evm *vm.EVM, msg Message, gp *GasPool) (*ExecutionResult, error) {
+	st := NewStateTransition(evm, msg, gp)
+
+	ret, gas, _, err := st.TransitionDb()
+	if err != nil {
+		return nil, err
+	}
+	return &ExecutionResult{UsedGas: gas, Err: st.vmerr, ReturnData: ret}, nil
+}
+
 func (st *StateTransition) from() vm.AccountRef {
 	f := st.msg.From()
 	if !st.state.Exist(f) {
@@ -182,13 +232,25 @@ func (st *StateTransition) useGas(amount uint64) error {
 	return nil
 }
 
+// payer returns the account paying the gas of the message, the fee payer if it
+// is sponsored or its sender otherwise.
+func (st *StateTransition) payer() vm.AccountRef {
+	if payer := st.msg.FeePayer(); payer != nil {
+		if !st.state.Exist(*payer) {
+			st.state.CreateAccount(*payer)
+		}
+		return vm.AccountRef(*payer)
+	}
+	return st.from()
+}
+
 func (st *StateTransition) buyGas() error {
 	var (
-		state  = st.state
-		sender = st.from()
+		state = st.state
+		payer = st.payer()
 	)
 	mgval := new(big.Int).Mul(new(big.Int).SetUint64(st.msg.Gas()), st.gasPrice)
-	if state.GetBalance(sender.Address()).Cmp(mgval) < 0 {
+	if state.GetBalance(payer.Address()).Cmp(mgval) < 0 {
 		return errInsufficientBalanceForGas
 	}
 	if err := st.gp.SubGas(st.msg.Gas()); err != nil {
@@ -197,7 +259,7 @@ func (st *StateTransition) buyGas() error {
 	st.gas += st.msg.Gas()
 
 	st.initialGas = st.msg.Gas()
-	state.SubBalance(sender.Address(), mgval)
+	state.SubBalance(payer.Address(), mgval)
 	return nil
 }
 
@@ -210,6 +272,10 @@ func (st *StateTransition) preCheck() error {
 	msg := st.msg
 	sender := st.from()
 
+	if msg.FeePayer() != nil && !st.evm.ChainConfig().IsFeePayer(st.evm.BlockNumber) {
+		return ErrFeePayerNotActive
+	}
+
 	// Make sure this transaction's nonce is correct
 	if msg.CheckNonce() {
 		nonce := st.state.GetNonce(sender.Address())
@@ -240,6 +306,15 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 	if err = st.useGas(gas); err != nil {
 		return nil, 0, false, err
 	}
+	if msg.Action() == types.ActionCreateContract {
+		gas, err = InitCodeGas(st.evm.ChainConfig().ContractLimits(st.evm.BlockNumber), st.data)
+		if err != nil {
+			return nil, 0, false, err
+		}
+		if err = st.useGas(gas); err != nil {
+			return nil, 0, false, err
+		}
+	}
 
 	var (
 		evm = st.evm
@@ -308,6 +383,8 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 		}
 	}
 
+	st.vmerr = vmerr
+
 	st.refundGas()
 	st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), st.gasPrice))
 
@@ -322,11 +399,11 @@ func (st *StateTransition) refundGas() {
 	}
 	st.gas += refund
 
-	// Return DAC for remaining gas, exchanged at the original rate.
-	sender := st.from()
+	// Return DAC for remaining gas to whoever bought it, exchanged at the original rate.
+	payer := st.payer()
 
 	remaining := new(big.Int).Mul(new(big.Int).SetUint64(st.gas), st.gasPrice)
-	st.state.AddBalance(sender.Address(), remaining)
+	st.state.AddBalance(payer.Address(), remaining)
 
 	// Also return remaining gas to the block gas counter so it is
 	// available for the next transaction.
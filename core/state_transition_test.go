@@ -0,0 +1,100 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/core/vm"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// Tests that the gas of a sponsored transaction is bought and refunded by its
+// fee payer, leaving the sender to pay the transferred value only.
+func TestFeePayerStateTransition(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	payerKey, _ := crypto.GenerateKey()
+	sender, payer := crypto.PubkeyToAddress(key.PublicKey), crypto.PubkeyToAddress(payerKey.PublicKey)
+	recipient, coinbase := common.HexToAddress("0x01"), common.HexToAddress("0x02")
+
+	db, _ := aoadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.AddBalance(sender, big.NewInt(100))
+	statedb.AddBalance(payer, big.NewInt(params.Em))
+
+	config := *params.AllDacchainProtocolChanges
+	signer := types.NewAuroraSigner(config.ChainId)
+
+	price := big.NewInt(2)
+	tx := types.NewTransaction(0, recipient, big.NewInt(100), 100000, price, nil, types.ActionTrans, nil, "")
+	tx, err := types.SignTx(tx.WithFeePayer(payer), signer, key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if tx, err = types.SignFeePayer(tx, signer, payerKey); err != nil {
+		t.Fatalf("failed to sign transaction as fee payer: %v", err)
+	}
+	msg, err := tx.AsMessage(signer)
+	if err != nil {
+		t.Fatalf("failed to derive message: %v", err)
+	}
+	if have := msg.FeePayer(); have == nil || *have != payer {
+		t.Fatalf("fee payer mismatch: have %v, want %x", have, payer)
+	}
+	apply := func(number int64) (uint64, error) {
+		context := vm.Context{
+			CanTransfer: CanTransfer,
+			Transfer:    Transfer,
+			Origin:      sender,
+			GasPrice:    price,
+			Coinbase:    coinbase,
+			GasLimit:    10000000,
+			BlockNumber: big.NewInt(number),
+			Time:        new(big.Int),
+			Difficulty:  new(big.Int),
+		}
+		_, gas, _, err := ApplyMessage(vm.NewEVM(context, statedb, &config, vm.Config{}), msg, new(GasPool).AddGas(10000000))
+		return gas, err
+	}
+	config.FeePayerBlock = big.NewInt(1)
+	if _, err := apply(0); err != ErrFeePayerNotActive {
+		t.Fatalf("error mismatch before the fee payer fork: have %v, want %v", err, ErrFeePayerNotActive)
+	}
+	gas, err := apply(1)
+	if err != nil {
+		t.Fatalf("failed to apply sponsored transaction: %v", err)
+	}
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(gas), price)
+	if balance := statedb.GetBalance(sender); balance.Sign() != 0 {
+		t.Errorf("sender balance mismatch: have %v, want 0", balance)
+	}
+	if balance, want := statedb.GetBalance(payer), new(big.Int).Sub(big.NewInt(params.Em), fee); balance.Cmp(want) != 0 {
+		t.Errorf("fee payer balance mismatch: have %v, want %v", balance, want)
+	}
+	if balance := statedb.GetBalance(coinbase); balance.Cmp(fee) != 0 {
+		t.Errorf("coinbase balance mismatch: have %v, want %v", balance, fee)
+	}
+	if balance := statedb.GetBalance(recipient); balance.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("recipient balance mismatch: have %v, want 100", balance)
+	}
+}
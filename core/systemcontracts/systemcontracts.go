@@ -0,0 +1,174 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package systemcontracts implements the consensus-critical contracts living at
+// reserved addresses, together with the helpers the consensus engine uses to
+// upgrade and call them while finalizing blocks.
+package systemcontracts
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/core/vm"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+var (
+	GovernanceContract       = common.HexToAddress("0x0000000000000000000000000000000000001000") // On-chain governance proposals and votes
+	RewardContract           = common.HexToAddress("0x0000000000000000000000000000000000001001") // Block reward bookkeeping
+	DelegateRegistryContract = common.HexToAddress("0x0000000000000000000000000000000000001002") // Delegate metadata registry
+
+	// SystemCaller is the sender of all protocol calls into system contracts.
+	SystemCaller = common.HexToAddress("0xfffffffffffffffffffffffffffffffffffffffe")
+)
+
+// callGas is the gas allowance of a single protocol call into a system contract.
+const callGas = uint64(5000000)
+
+var (
+	// ErrNotSystemContract is returned if code is installed at, or a call is made
+	// to, an address outside of the reserved system contract range.
+	ErrNotSystemContract = errors.New("not a system contract")
+
+	// ErrNotDeployed is returned if a system contract is called before any code
+	// was deployed at its address.
+	ErrNotDeployed = errors.New("system contract not deployed")
+)
+
+// blockFinalizedMethod is the selector of blockFinalized(address), invoked on the
+// reward contract with the producer of every finalized block.
+var blockFinalizedMethod = crypto.Keccak256([]byte("blockFinalized(address)"))[:4]
+
+var systemContracts = map[common.Address]bool{
+	GovernanceContract:       true,
+	RewardContract:           true,
+	DelegateRegistryContract: true,
+}
+
+// IsSystemContract reports whether addr is reserved for a system contract.
+func IsSystemContract(addr common.Address) bool {
+	return systemContracts[addr]
+}
+
+// Validate checks that every system contract fork of the chain configuration
+// only installs code at reserved addresses.
+func Validate(config *params.ChainConfig) error {
+	for _, fork := range config.SystemContractForks {
+		for addr := range fork.Code {
+			if !IsSystemContract(addr) {
+				return fmt.Errorf("%v: %x at block %v", ErrNotSystemContract, addr, fork.Block)
+			}
+		}
+	}
+	return nil
+}
+
+// Upgrade installs the system contract code scheduled by the chain configuration
+// at the given block. Nothing is installed if any scheduled address is not a
+// reserved one.
+func Upgrade(config *params.ChainConfig, number *big.Int, statedb vm.StateDB) error {
+	upgrades := config.SystemContractUpgrades(number)
+	for addr := range upgrades {
+		if !IsSystemContract(addr) {
+			return fmt.Errorf("%v: %x", ErrNotSystemContract, addr)
+		}
+	}
+	for addr, code := range upgrades {
+		if !statedb.Exist(addr) {
+			statedb.CreateAccount(addr)
+		}
+		statedb.SetCode(addr, code)
+	}
+	return nil
+}
+
+// Call executes a message from SystemCaller to a system contract on top of the
+// state of the block being finalized and returns its output. State changes are
+// kept if the call succeeds and reverted otherwise.
+func Call(chain consensus.ChainReader, header *types.Header, statedb vm.StateDB, contract common.Address, input []byte) ([]byte, error) {
+	if !IsSystemContract(contract) {
+		return nil, ErrNotSystemContract
+	}
+	if statedb.GetCodeSize(contract) == 0 {
+		return nil, ErrNotDeployed
+	}
+	context := vm.Context{
+		CanTransfer: canTransfer,
+		Transfer:    transfer,
+		GetHash:     getHashFn(chain, header),
+		Origin:      SystemCaller,
+		GasPrice:    new(big.Int),
+		Coinbase:    header.Coinbase,
+		GasLimit:    header.GasLimit,
+		BlockNumber: new(big.Int).Set(header.Number),
+		Time:        new(big.Int).Set(header.Time),
+		Difficulty:  new(big.Int).Set(types.BlockDifficult),
+	}
+	evm := vm.NewEVM(context, statedb, chain.Config(), vm.Config{})
+	ret, _, err := evm.Call(vm.AccountRef(SystemCaller), contract, input, callGas, types.ActionCallContract, new(big.Int))
+	return ret, err
+}
+
+// NotifyBlockFinalized informs the reward contract, if deployed, of the producer
+// of the block being finalized.
+func NotifyBlockFinalized(chain consensus.ChainReader, header *types.Header, statedb vm.StateDB) error {
+	if statedb.GetCodeSize(RewardContract) == 0 {
+		return nil
+	}
+	input := append(append([]byte{}, blockFinalizedMethod...), common.LeftPadBytes(header.Coinbase.Bytes(), 32)...)
+	_, err := Call(chain, header, statedb, RewardContract, input)
+	return err
+}
+
+// getHashFn returns a BLOCKHASH resolver walking the ancestors of header.
+func getHashFn(chain consensus.ChainReader, header *types.Header) vm.GetHashFunc {
+	return func(n uint64) common.Hash {
+		for h := header; h.Number.Uint64() > n; {
+			number := h.Number.Uint64() - 1
+			if h = chain.GetHeader(h.ParentHash, number); h == nil {
+				break
+			}
+			if number == n {
+				return h.Hash()
+			}
+		}
+		return common.Hash{}
+	}
+}
+
+func canTransfer(db vm.StateDB, addr common.Address, asset *common.Address, amount *big.Int) bool {
+	if asset != nil {
+		return db.GetAssetBalance(addr, *asset).Cmp(amount) >= 0
+	}
+	return db.GetBalance(addr).Cmp(amount) >= 0
+}
+
+func transfer(db vm.StateDB, sender, recipient common.Address, asset *common.Address, amount *big.Int) {
+	if asset != nil {
+		if db.SubAssetBalance(sender, *asset, amount) {
+			db.AddAssetBalance(recipient, *asset, amount)
+		}
+		return
+	}
+	db.SubBalance(sender, amount)
+	db.AddBalance(recipient, amount)
+}
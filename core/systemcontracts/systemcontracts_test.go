@@ -0,0 +1,118 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package systemcontracts
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/common/hexutil"
+	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// testChain is a consensus.ChainReader without any headers.
+type testChain struct{ config *params.ChainConfig }
+
+func (c *testChain) Config() *params.ChainConfig                           { return c.config }
+func (c *testChain) CurrentHeader() *types.Header                          { return nil }
+func (c *testChain) GetHeader(common.Hash, uint64) *types.Header           { return nil }
+func (c *testChain) GetHeaderByNumber(uint64) *types.Header                { return nil }
+func (c *testChain) GetHeaderByHash(common.Hash) *types.Header             { return nil }
+func (c *testChain) GetBlock(hash common.Hash, number uint64) *types.Block { return nil }
+
+func newTestState(t *testing.T) *state.StateDB {
+	db, _ := aoadb.NewMemDatabase()
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	return statedb
+}
+
+// Tests that scheduled system contract code is installed exactly at its fork
+// block, and that forks touching non-reserved addresses are rejected.
+func TestUpgrade(t *testing.T) {
+	config := &params.ChainConfig{SystemContractForks: []params.SystemContractsFork{
+		{Block: big.NewInt(0), Code: map[common.Address]hexutil.Bytes{GovernanceContract: {0x00}}},
+		{Block: big.NewInt(10), Code: map[common.Address]hexutil.Bytes{GovernanceContract: {0x01, 0x00}}},
+	}}
+	if err := Validate(config); err != nil {
+		t.Fatalf("valid config rejected: %v", err)
+	}
+	statedb := newTestState(t)
+	for _, number := range []int64{0, 5} {
+		if err := Upgrade(config, big.NewInt(number), statedb); err != nil {
+			t.Fatalf("block %d: upgrade failed: %v", number, err)
+		}
+	}
+	if code := statedb.GetCode(GovernanceContract); string(code) != "\x00" {
+		t.Errorf("code mismatch before fork: have %x, want 00", code)
+	}
+	if err := Upgrade(config, big.NewInt(10), statedb); err != nil {
+		t.Fatalf("upgrade failed: %v", err)
+	}
+	if code := statedb.GetCode(GovernanceContract); string(code) != "\x01\x00" {
+		t.Errorf("code mismatch after fork: have %x, want 0100", code)
+	}
+
+	config.SystemContractForks = append(config.SystemContractForks, params.SystemContractsFork{
+		Block: big.NewInt(20), Code: map[common.Address]hexutil.Bytes{{0x01}: {0x00}},
+	})
+	if err := Validate(config); err == nil {
+		t.Errorf("non-reserved address accepted by validation")
+	}
+	if err := Upgrade(config, big.NewInt(20), statedb); err == nil {
+		t.Errorf("non-reserved address accepted by upgrade")
+	}
+	if statedb.GetCodeSize(common.Address{0x01}) != 0 {
+		t.Errorf("code installed at non-reserved address")
+	}
+}
+
+// Tests that the reward contract is called by the system caller with the
+// producer of the finalized block.
+func TestNotifyBlockFinalized(t *testing.T) {
+	chain := &testChain{config: params.AllDacchainProtocolChanges}
+	header := &types.Header{Coinbase: common.Address{0xaa}, Number: big.NewInt(1), Time: big.NewInt(1), GasLimit: 1000000}
+	statedb := newTestState(t)
+
+	// Nothing to notify without a deployed reward contract
+	if err := NotifyBlockFinalized(chain, header, statedb); err != nil {
+		t.Fatalf("notification without contract failed: %v", err)
+	}
+	if _, err := Call(chain, header, statedb, RewardContract, nil); err != ErrNotDeployed {
+		t.Errorf("call error mismatch: have %v, want %v", err, ErrNotDeployed)
+	}
+	if _, err := Call(chain, header, statedb, common.Address{0x01}, nil); err != ErrNotSystemContract {
+		t.Errorf("call error mismatch: have %v, want %v", err, ErrNotSystemContract)
+	}
+	// CALLDATALOAD(4) -> slot 0, CALLER -> slot 1
+	statedb.SetCode(RewardContract, hexutil.MustDecode("0x600435600055336001550000"))
+	if err := NotifyBlockFinalized(chain, header, statedb); err != nil {
+		t.Fatalf("notification failed: %v", err)
+	}
+	if producer := statedb.GetState(RewardContract, common.Hash{}); producer != header.Coinbase.Hash() {
+		t.Errorf("producer mismatch: have %x, want %x", producer, header.Coinbase.Hash())
+	}
+	if caller := statedb.GetState(RewardContract, common.BigToHash(big.NewInt(1))); caller != SystemCaller.Hash() {
+		t.Errorf("caller mismatch: have %x, want %x", caller, SystemCaller.Hash())
+	}
+}
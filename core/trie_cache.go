@@ -0,0 +1,110 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/log"
+)
+
+// triesInMemory is the number of recent block states kept in the trie node
+// cache before being garbage collected, allowing reorgs within this depth.
+const triesInMemory = 128
+
+// CacheConfig contains the configuration values for the trie node caching of
+// a blockchain.
+type CacheConfig struct {
+	Disabled           bool   // Whether to write every state to disk (archive node)
+	TrieCleanLimit     int    // Memory allowance (MB) to use for caching clean trie nodes read from disk
+	TrieDirtyLimit     int    // Memory limit (MB) at which to start flushing dirty trie nodes to disk
+	TrieCommitInterval uint64 // Number of blocks after which an entire state is flushed to disk
+}
+
+// defaultCacheConfig are the trie caching settings used if none are given.
+var defaultCacheConfig = &CacheConfig{
+	TrieCleanLimit:     256,
+	TrieDirtyLimit:     256,
+	TrieCommitInterval: 4096,
+}
+
+// commitState hands the state of a newly written block over to the trie node
+// cache, keeping the most recent states in memory. Older ones are garbage
+// collected, except for one flushed to disk every commit interval, and the
+// oldest cached nodes are flushed once the memory limit is exceeded.
+func (bc *BlockChain) commitState(block *types.Block, root common.Hash) error {
+	triedb := bc.stateCache.TrieDB()
+	if bc.cacheConfig.Disabled {
+		return triedb.Commit(root)
+	}
+	triedb.Reference(root)
+	bc.triegc.Push(root, -float32(block.NumberU64()))
+
+	current := block.NumberU64()
+	if current <= triesInMemory {
+		return nil
+	}
+	limit := common.StorageSize(bc.cacheConfig.TrieDirtyLimit) * 1024 * 1024
+	if nodes, preimages := triedb.Size(); nodes+preimages > limit {
+		if err := triedb.Cap(limit - aoadb.IdealBatchSize); err != nil {
+			return err
+		}
+	}
+	// Flush the state about to leave the cache if it's due, so a crash only
+	// requires reprocessing a bounded number of blocks
+	chosen := current - triesInMemory
+	if interval := bc.cacheConfig.TrieCommitInterval; interval > 0 && chosen%interval == 0 {
+		if header := bc.GetHeaderByNumber(chosen); header != nil {
+			if err := triedb.Commit(header.Root); err != nil {
+				return err
+			}
+		}
+	}
+	// Garbage collect the states of all blocks not retained any more
+	for !bc.triegc.Empty() {
+		root, number := bc.triegc.Pop()
+		if uint64(-number) > chosen {
+			bc.triegc.Push(root, number)
+			break
+		}
+		triedb.Dereference(root.(common.Hash))
+	}
+	return nil
+}
+
+// flushState writes the states of the head block, its parent and the oldest
+// retained block from the trie node cache to disk, so the chain can be resumed
+// and reorged after a restart.
+func (bc *BlockChain) flushState() {
+	if bc.cacheConfig.Disabled {
+		return
+	}
+	triedb := bc.stateCache.TrieDB()
+	for _, offset := range []uint64{0, 1, triesInMemory - 1} {
+		number := bc.CurrentBlock().NumberU64()
+		if number < offset {
+			continue
+		}
+		if recent := bc.GetBlockByNumber(number - offset); recent != nil {
+			log.Info("Writing cached state to disk", "block", recent.Number(), "hash", recent.Hash(), "root", recent.Root())
+			if err := triedb.Commit(recent.Root()); err != nil {
+				log.Error("Failed to commit recent state trie", "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/log"
+)
+
+// txIndexBatch is the number of blocks (un)indexed before the progress is
+// flushed to disk and the quit channel is checked again.
+const txIndexBatch = 1024
+
+// errMaintenanceStopped is returned by the chain walker callbacks if the index
+// or history maintenance was interrupted by the chain shutting down.
+var errMaintenanceStopped = errors.New("chain maintenance stopped")
+
+// SetTxLookupLimit sets the number of recent blocks whose transactions are kept
+// in the hash based lookup index. Older entries are removed in the background as
+// the head advances; zero keeps (and if needed restores) the entire index.
+func (bc *BlockChain) SetTxLookupLimit(limit uint64) {
+	atomic.StoreUint64(&bc.txLookupLimit, limit)
+
+	select {
+	case bc.txIndexReq <- struct{}{}:
+	default:
+	}
+}
+
+// TxLookupLimit returns the number of recent blocks whose transactions are
+// indexed, zero meaning the entire chain.
+func (bc *BlockChain) TxLookupLimit() uint64 {
+	return atomic.LoadUint64(&bc.txLookupLimit)
+}
+
+// maintainTxIndex is a background loop moving the transaction index and history
+// tails along with the chain head, according to the configured limits.
+func (bc *BlockChain) maintainTxIndex() {
+	defer bc.wg.Done()
+
+	// Subscribe to the feed directly, the scope might already be closed if the
+	// chain is stopped right after creation. Termination is signalled via quit.
+	heads := make(chan ChainHeadEvent, 10)
+	sub := bc.chainHeadFeed.Subscribe(heads)
+	defer sub.Unsubscribe()
+
+	var (
+		done    chan struct{} // Non-nil while an indexing run is in progress
+		pending bool          // Whether a request arrived during the current run
+	)
+	run := func() {
+		if done != nil {
+			pending = true
+			return
+		}
+		done, pending = make(chan struct{}), false
+		go func(head uint64) {
+			defer close(done)
+			bc.updateTxIndex(head)
+			bc.pruneHistory(head)
+		}(bc.CurrentBlock().NumberU64())
+	}
+	run()
+	for {
+		select {
+		case <-heads:
+			run()
+		case <-bc.txIndexReq:
+			run()
+		case <-done:
+			// Rerun if the head or the limits changed in the meantime
+			if done = nil; pending {
+				run()
+			}
+		case <-sub.Err():
+			if done != nil {
+				<-done
+			}
+			return
+		case <-bc.quit:
+			if done != nil {
+				<-done
+			}
+			return
+		}
+	}
+}
+
+// updateTxIndex moves the transaction index tail to match the configured limit
+// relative to the given head, unindexing or reindexing blocks as required.
+func (bc *BlockChain) updateTxIndex(head uint64) {
+	var (
+		limit = bc.TxLookupLimit()
+		tail  = uint64(0)
+		want  = uint64(0)
+	)
+	if stored := GetTxIndexTail(bc.chainDb); stored != nil {
+		tail = *stored
+	}
+	if limit != 0 && head+1 > limit {
+		want = head - limit + 1
+	}
+	// Transactions of pruned blocks cannot be indexed anymore
+	if history := GetHistoryTail(bc.chainDb); want < history {
+		want = history
+	}
+	switch {
+	case want > tail:
+		bc.unindexTransactions(tail, want)
+	case want < tail:
+		bc.indexTransactions(want, tail)
+	}
+}
+
+// unindexTransactions removes the lookup entries of the canonical blocks in the
+// range [from, to), advancing the stored tail as it goes.
+func (bc *BlockChain) unindexTransactions(from, to uint64) {
+	var (
+		start = time.Now()
+		txs   = 0
+		batch = bc.chainDb.NewBatch()
+	)
+	err := ForEachCanonicalBlock(bc.chainDb, from, to-1, func(block *types.Block, _ types.Receipts) error {
+		for _, tx := range block.Transactions() {
+			DeleteTxLookupEntry(batch, tx.Hash())
+		}
+		txs += len(block.Transactions())
+
+		if tail := block.NumberU64() + 1; (tail-from)%txIndexBatch == 0 || tail == to {
+			if !bc.flushTxIndex(batch, tail) {
+				return errMaintenanceStopped
+			}
+			batch = bc.chainDb.NewBatch()
+		}
+		return nil
+	})
+	if err != nil {
+		if err != errMaintenanceStopped {
+			log.Error("Failed to unindex transactions", "err", err)
+		}
+		return
+	}
+	log.Info("Unindexed transactions", "blocks", to-from, "txs", txs, "tail", to, "elapsed", common.PrettyDuration(time.Since(start)))
+}
+
+// indexTransactions writes the lookup entries of the canonical blocks in the
+// range [from, to), moving the stored tail backwards as it goes.
+func (bc *BlockChain) indexTransactions(from, to uint64) {
+	start, txs := time.Now(), 0
+	for number := to; number > from; {
+		first := from
+		if number-from > txIndexBatch {
+			first = number - txIndexBatch
+		}
+		batch := bc.chainDb.NewBatch()
+		err := ForEachCanonicalBlock(bc.chainDb, first, number-1, func(block *types.Block, _ types.Receipts) error {
+			txs += len(block.Transactions())
+			return WriteTxLookupEntries(batch, block)
+		})
+		if err != nil {
+			log.Error("Failed to index transactions", "err", err)
+			return
+		}
+		if number = first; !bc.flushTxIndex(batch, number) {
+			return
+		}
+	}
+	log.Info("Indexed transactions", "blocks", to-from, "txs", txs, "tail", from, "elapsed", common.PrettyDuration(time.Since(start)))
+}
+
+// flushTxIndex writes an index batch together with the new tail, reporting
+// whether processing may continue.
+func (bc *BlockChain) flushTxIndex(batch aoadb.Batch, tail uint64) bool {
+	WriteTxIndexTail(batch, tail)
+	return bc.flushMaintenance(batch)
+}
+
+// flushMaintenance writes a batch of background chain maintenance, reporting
+// whether processing may continue.
+func (bc *BlockChain) flushMaintenance(batch aoadb.Batch) bool {
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to write chain maintenance batch", "err", err)
+		return false
+	}
+	select {
+	case <-bc.quit:
+		return false
+	default:
+		return true
+	}
+}
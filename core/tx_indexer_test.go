@@ -0,0 +1,128 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus/dpos"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/core/vm"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// newIndexedTestChain creates a chain of the given number of blocks with a single
+// transaction each, all of them indexed, headed by the last block.
+func newIndexedTestChain(t *testing.T, n int) (*BlockChain, []*types.Block, aoadb.Database) {
+	var (
+		db, _  = aoadb.NewMemDatabase()
+		config = params.AllDacchainProtocolChanges
+		signer = types.MakeSigner(config, big.NewInt(1))
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+	)
+	genesis := (&Genesis{
+		Config: config,
+		Alloc:  GenesisAlloc{addr: {Balance: big.NewInt(params.Em)}},
+		Agents: GenesisAgents{{Address: "0x0200", Vote: 1, Nickname: "test"}},
+	}).MustCommit(db)
+
+	blocks, receipts := GenerateChain(config, genesis, dpos.New(), db, n, func(i int, gen *BlockGen) {
+		tx := types.NewTransaction(gen.TxNonce(addr), common.HexToAddress("0x01"), big.NewInt(10), 100000, big.NewInt(1), nil, types.ActionTrans, nil, "")
+		tx, _ = types.SignTx(tx, signer, key)
+		gen.AddTx(tx)
+	})
+	for i, block := range blocks {
+		WriteBlock(db, block)
+		WriteBlockReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		WriteTxLookupEntries(db, block)
+	}
+	WriteHeadBlockHash(db, blocks[n-1].Hash())
+
+	chain, err := NewBlockChain(db, nil, config, dpos.New(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if head := chain.CurrentBlock().NumberU64(); head != uint64(n) {
+		t.Fatalf("chain head mismatch: have #%d, want #%d", head, n)
+	}
+	return chain, blocks, db
+}
+
+// checkTxIndex verifies that exactly the transactions of the blocks at or above
+// the given tail are indexed.
+func checkTxIndex(t *testing.T, db aoadb.Database, blocks []*types.Block, tail uint64) {
+	if stored := GetTxIndexTail(db); stored == nil {
+		t.Fatalf("index tail missing, want %d", tail)
+	} else if *stored != tail {
+		t.Fatalf("index tail mismatch: have %d, want %d", *stored, tail)
+	}
+	for _, block := range blocks {
+		hash, _, _ := GetTxLookupEntry(db, block.Transactions()[0].Hash())
+		if indexed := hash != (common.Hash{}); indexed != (block.NumberU64() >= tail) {
+			t.Errorf("tail %d: block #%d indexed mismatch: have %v, want %v", tail, block.NumberU64(), indexed, !indexed)
+		}
+	}
+}
+
+// Tests that the transaction index tail follows the lookup limit, unindexing and
+// reindexing blocks as the limit shrinks and grows.
+func TestUpdateTxIndex(t *testing.T) {
+	chain, blocks, db := newIndexedTestChain(t, 20)
+	defer chain.Stop()
+
+	for _, tt := range []struct {
+		limit uint64
+		tail  uint64
+	}{
+		{5, 16},  // unindex the oldest blocks
+		{10, 11}, // reindex some of them
+		{30, 0},  // limit beyond the chain, index everything
+		{8, 13},  // unindex again
+		{0, 0},   // restore the entire index
+	} {
+		atomic.StoreUint64(&chain.txLookupLimit, tt.limit)
+		chain.updateTxIndex(20)
+		checkTxIndex(t, db, blocks, tt.tail)
+	}
+}
+
+// Tests that lookup limit changes arriving while the background maintenance is
+// busy are not lost, but applied once the running pass finishes.
+func TestTxLookupLimitQueued(t *testing.T) {
+	chain, blocks, db := newIndexedTestChain(t, 1000)
+	defer chain.Stop()
+
+	// Start unindexing most of the chain and change the limit while it's running
+	chain.SetTxLookupLimit(5)
+	time.Sleep(5 * time.Millisecond)
+	chain.SetTxLookupLimit(10)
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if tail := GetTxIndexTail(db); tail != nil && *tail == 991 {
+			break
+		}
+	}
+	checkTxIndex(t, db, blocks, 991)
+}
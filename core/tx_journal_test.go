@@ -0,0 +1,67 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+)
+
+// Tests that transactions written into a dump file are loaded back in order.
+func TestTransactionDumpRoundtrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txdump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "txpool.rlp")
+
+	var (
+		to  = common.HexToAddress("0x01")
+		all = map[common.Address]types.Transactions{
+			common.HexToAddress("0xaa"): {
+				types.NewTransaction(0, to, big.NewInt(1), 21000, big.NewInt(1), nil, types.ActionTrans, nil, ""),
+				types.NewTransaction(1, to, big.NewInt(2), 21000, big.NewInt(1), nil, types.ActionTrans, nil, ""),
+			},
+		}
+	)
+	written, err := writeTransactions(path, all)
+	if err != nil || written != 2 {
+		t.Fatalf("failed to write transactions: have %d/%v, want 2/nil", written, err)
+	}
+	var loaded types.Transactions
+	if err := newTxJournal(path).load(func(tx *types.Transaction) error {
+		loaded = append(loaded, tx)
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to load transactions: %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("loaded transaction count mismatch: have %d, want 2", len(loaded))
+	}
+	for i, tx := range loaded {
+		if want := all[common.HexToAddress("0xaa")][i].Hash(); tx.Hash() != want {
+			t.Errorf("transaction %d: hash mismatch: have %x, want %x", i, tx.Hash(), want)
+		}
+	}
+}
@@ -668,7 +668,9 @@ func (tList *txTypeList) Get(txType common.Address) (int, *types.TxByPrice) {
 	return index, txList
 }
 
-func (tList *txTypeList) Underpriced(tx *types.Transaction) bool {
+// Underpriced checks whether a transaction is cheaper than (or as cheap as) the
+// lowest priced remote transaction of its type currently being tracked.
+func (tList *txTypeList) Underpriced(tx *types.Transaction, local *accountSet) bool {
 	txType := tx.GetTransactionType()
 	_, txList := tList.Get(txType)
 	sort.Sort(txList)
@@ -684,8 +686,25 @@ func (tList *txTypeList) Underpriced(tx *types.Transaction) bool {
 		log.Error("Pricing query for empty pool") // This cannot happen, print to catch programming errors
 		return false
 	}
-	cheapest := (*txList)[len(*txList)-1]
-	return cheapest.GasPrice().Cmp(tx.GasPrice()) >= 0
+	cheapest := tList.Cheapest(txList, local)
+	return cheapest != nil && cheapest.GasPrice().Cmp(tx.GasPrice()) >= 0
+}
+
+// Cheapest retrieves the lowest priced transaction of the list that is still
+// tracked and not sent from a local account, nil if there's none.
+func (tList *txTypeList) Cheapest(txList *types.TxByPrice, local *accountSet) *types.Transaction {
+	sort.Sort(txList)
+	for i := len(*txList) - 1; i >= 0; i-- {
+		tx := (*txList)[i]
+		if _, ok := (*tList.all)[tx.Hash()]; !ok {
+			continue
+		}
+		if local.containsTx(tx) {
+			continue
+		}
+		return tx
+	}
+	return nil
 }
 
 func (tList *txTypeList) Cap(threshold *big.Int, local *accountSet, db *state.StateDB) types.Transactions {
@@ -726,9 +745,23 @@ func (tList *txTypeList) RemoveByHash(txType common.Address, hash common.Hash) {
 	}
 }
 
-func (tList *txTypeList) RemoveTx() common.Hash {
-	txList := (*tList.items)[0].value
-	sort.Sort(txList)
-	removeTx := (*txList)[len(*txList)-1]
-	return removeTx.Hash()
+// RemoveTx picks the cheapest remote transaction of the type with the most
+// transactions as the one to evict, returning false if all are local.
+func (tList *txTypeList) RemoveTx(local *accountSet) (common.Hash, bool) {
+	var (
+		removeTx *types.Transaction
+		size     int
+	)
+	for _, kv := range *tList.items {
+		if kv.GetValueLen() <= size {
+			continue
+		}
+		if tx := tList.Cheapest(kv.value, local); tx != nil {
+			removeTx, size = tx, kv.GetValueLen()
+		}
+	}
+	if removeTx == nil {
+		return common.Hash{}, false
+	}
+	return removeTx.Hash(), true
 }
@@ -0,0 +1,53 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/Aurorachain-io/go-aoa/core/types"
+)
+
+// TransactionIterator walks a set of pending transactions in the order the
+// block producer should try to include them.
+type TransactionIterator interface {
+	// Peek returns the next transaction to include, or nil if none are left.
+	Peek() *types.Transaction
+
+	// Shift replaces the current transaction with the next one from the same
+	// account, after the current one was successfully included.
+	Shift()
+
+	// Pop discards the current transaction together with every subsequent one
+	// from the same account, which can't be executed without it anymore.
+	Pop()
+}
+
+// TxOrdering decides in which order the pending transactions of the pool are
+// offered to the block producer. Implementations must honour the nonce order
+// of every single account, since an out of order transaction is rejected.
+type TxOrdering interface {
+	Order(signer types.Signer, pending types.TxByPrice) TransactionIterator
+}
+
+// DefaultTxOrdering selects transactions by gas price, the way the block
+// producer always has.
+var DefaultTxOrdering TxOrdering = priceNonceOrdering{}
+
+type priceNonceOrdering struct{}
+
+func (priceNonceOrdering) Order(signer types.Signer, pending types.TxByPrice) TransactionIterator {
+	return types.NewTransactionsByPriceAndNonce2(signer, pending)
+}
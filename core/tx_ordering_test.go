@@ -0,0 +1,107 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+)
+
+var _ TransactionIterator = (*types.TransactionsByPriceAndNonce)(nil)
+
+// whitelistOrdering offers the transactions of whitelisted senders first, each
+// group being ordered by DefaultTxOrdering.
+type whitelistOrdering struct {
+	senders map[common.Address]bool
+}
+
+func (o whitelistOrdering) Order(signer types.Signer, pending types.TxByPrice) TransactionIterator {
+	var first, rest types.TxByPrice
+	for _, tx := range pending {
+		from, _ := types.Sender(signer, tx)
+		if o.senders[from] {
+			first = append(first, tx)
+		} else {
+			rest = append(rest, tx)
+		}
+	}
+	return &chainedIterator{its: []TransactionIterator{
+		DefaultTxOrdering.Order(signer, first),
+		DefaultTxOrdering.Order(signer, rest),
+	}}
+}
+
+// chainedIterator exhausts its iterators one after the other.
+type chainedIterator struct {
+	its []TransactionIterator
+}
+
+func (c *chainedIterator) Peek() *types.Transaction {
+	for len(c.its) > 0 {
+		if tx := c.its[0].Peek(); tx != nil {
+			return tx
+		}
+		c.its = c.its[1:]
+	}
+	return nil
+}
+
+func (c *chainedIterator) Shift() { c.its[0].Shift() }
+func (c *chainedIterator) Pop()   { c.its[0].Pop() }
+
+// Tests that a custom ordering overrides the price based selection of the
+// default one.
+func TestTxOrdering(t *testing.T) {
+	signer := types.NewAuroraSigner(testPoolChainConfig.ChainId)
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	pending := types.TxByPrice{
+		poolTransaction(t, keys[0], 0, 3),
+		poolTransaction(t, keys[1], 0, 2),
+		poolTransaction(t, keys[2], 0, 1),
+	}
+	// Every account has a single transaction, so each one is popped
+	collect := func(it TransactionIterator) []*types.Transaction {
+		var txs []*types.Transaction
+		for tx := it.Peek(); tx != nil; tx = it.Peek() {
+			txs = append(txs, tx)
+			it.Pop()
+		}
+		return txs
+	}
+	check := func(name string, have []*types.Transaction, want ...*types.Transaction) {
+		if len(have) != len(want) {
+			t.Fatalf("%s: ordered transaction count mismatch: have %d, want %d", name, len(have), len(want))
+		}
+		for i := range want {
+			if have[i].Hash() != want[i].Hash() {
+				t.Errorf("%s: transaction %d mismatch: have %x, want %x", name, i, have[i].Hash(), want[i].Hash())
+			}
+		}
+	}
+	check("default", collect(DefaultTxOrdering.Order(signer, append(types.TxByPrice{}, pending...))), pending[0], pending[1], pending[2])
+
+	ordering := whitelistOrdering{senders: map[common.Address]bool{crypto.PubkeyToAddress(keys[2].PublicKey): true}}
+	check("whitelist", collect(ordering.Order(signer, append(types.TxByPrice{}, pending...))), pending[2], pending[0], pending[1])
+}
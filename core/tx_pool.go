@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"os"
 	"sort"
 	"sync"
 	"time"
@@ -76,6 +77,10 @@ var (
 
 	ErrInsufficientAssetFunds = errors.New("insufficient funds of asset for transfer")
 
+	// ErrInsufficientFeePayerFunds is returned if the gas of a sponsored transaction
+	// costs more than the balance of its fee payer.
+	ErrInsufficientFeePayerFunds = errors.New("insufficient funds of fee payer for gas")
+
 	// ErrIntrinsicGas is returned if the transaction is specified to use less gas
 	// than required to start the invocation.
 	ErrIntrinsicGas = errors.New("intrinsic gas too low")
@@ -92,6 +97,26 @@ var (
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
 	ErrOversizedData = errors.New("oversized data")
+
+	// ErrUnprotectedTx is returned if a transaction signed without a chain id is
+	// added to the pool. Such transactions are not valid on chain either.
+	ErrUnprotectedTx = errors.New("unprotected transaction, only replay-protected transactions allowed")
+
+	// ErrCrossChainReplay is returned if a transaction signed for a different
+	// chain is added to the pool.
+	ErrCrossChainReplay = errors.New("transaction signed for a different chain")
+
+	// ErrNonceGap is returned if the nonces of a transaction sequence aren't
+	// consecutive or don't continue right after the sender's pending nonce.
+	ErrNonceGap = errors.New("nonce gap in transaction sequence")
+
+	// ErrSequenceOverlap is returned if a transaction sequence contains a nonce
+	// the pool already holds a transaction of.
+	ErrSequenceOverlap = errors.New("transaction sequence overlaps pooled transactions")
+
+	// ErrMixedSenders is returned if the transactions of a sequence aren't all
+	// signed by the same account.
+	ErrMixedSenders = errors.New("transaction sequence from multiple senders")
 )
 
 var (
@@ -115,6 +140,9 @@ var (
 	// General tx metrics
 	invalidTxCounter     = metrics.NewCounter("txpool/invalid")
 	underpricedTxCounter = metrics.NewCounter("txpool/underpriced")
+	replayTxCounter      = metrics.NewCounter("txpool/replay")      // Signed for a different chain
+	unprotectedTxCounter = metrics.NewCounter("txpool/unprotected") // Signed without a chain id
+	malleableTxCounter   = metrics.NewCounter("txpool/malleable")   // Signed with a non-canonical high-s value
 
 	// Contract tx counter
 	//	contractTxCounter = metrics.NewTransactionCounter("txpool/contractTx")
@@ -122,6 +150,14 @@ var (
 	//	contractRatio     = uint64(3)
 )
 
+// Reasons of dropping transactions from the pool, as reported by TxDroppedEvent.
+const (
+	DropUnderpriced = "underpriced" // Evicted from a full pool, or below the pool's price limit
+	DropUnpayable   = "unpayable"   // Sender can no longer pay for the transaction
+	DropOverflow    = "overflow"    // Exceeded the per-account or global slot limits
+	DropExpired     = "expired"     // Queued for longer than the configured lifetime
+)
+
 // TxStatus is the current status of a transaction as seen by the pool.
 type TxStatus uint
 
@@ -146,9 +182,10 @@ type blockChain interface {
 
 // TxPoolConfig are the configuration parameters of the transaction pool.
 type TxPoolConfig struct {
-	NoLocals  bool          // Whether local transaction handling should be disabled
-	Journal   string        // Journal of local transactions to survive node restarts
-	Rejournal time.Duration // Time interval to regenerate the local transaction journal
+	Locals    []common.Address // Addresses that should be treated by default as local
+	NoLocals  bool             // Whether local transaction handling should be disabled
+	Journal   string           // Journal of local transactions to survive node restarts
+	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
@@ -194,6 +231,18 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool price bump", "provided", conf.PriceBump, "updated", DefaultTxPoolConfig.PriceBump)
 		conf.PriceBump = DefaultTxPoolConfig.PriceBump
 	}
+	if conf.AccountSlots < 1 {
+		log.Warn("Sanitizing invalid txpool account slots", "provided", conf.AccountSlots, "updated", DefaultTxPoolConfig.AccountSlots)
+		conf.AccountSlots = DefaultTxPoolConfig.AccountSlots
+	}
+	if conf.GlobalSlots < 1 {
+		log.Warn("Sanitizing invalid txpool global slots", "provided", conf.GlobalSlots, "updated", DefaultTxPoolConfig.GlobalSlots)
+		conf.GlobalSlots = DefaultTxPoolConfig.GlobalSlots
+	}
+	if conf.Lifetime < 1 {
+		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultTxPoolConfig.Lifetime)
+		conf.Lifetime = DefaultTxPoolConfig.Lifetime
+	}
 	return conf
 }
 
@@ -210,15 +259,20 @@ type TxPool struct {
 	chain        blockChain
 	gasPrice     *big.Int
 	txFeed       event.Feed
+	promoteFeed  event.Feed
+	dropFeed     event.Feed
 	scope        event.SubscriptionScope
 	chainHeadCh  chan ChainHeadEvent
 	chainHeadSub event.Subscription
 	signer       types.Signer
 	mu           sync.RWMutex
 
-	currentState  *state.StateDB      // Current state in the blockchain head
-	pendingState  *state.ManagedState // Pending state tracking virtual nonces
-	currentMaxGas uint64              // Current gas limit for transaction caps
+	currentState   *state.StateDB            // Current state in the blockchain head
+	pendingState   *state.ManagedState       // Pending state tracking virtual nonces
+	currentMaxGas  uint64                    // Current gas limit for transaction caps
+	currentMaxSize uint64                    // Current consensus size limit for transactions (0 = unbounded)
+	rules          params.Rules              // Fork rules of the next block the transactions are validated against
+	contractLimits params.ContractLimitsFork // Contract code limits of the next block
 
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *txJournal  // Journal of local transaction to back up to disk
@@ -256,6 +310,10 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		gasPrice:    new(big.Int).SetUint64(config.PriceLimit),
 	}
 	pool.locals = newAccountSet(pool.signer)
+	for _, addr := range config.Locals {
+		log.Info("Setting new local account", "address", addr)
+		pool.locals.add(addr)
+	}
 	pool.priced = newTxTypeList(&pool.all)
 	pool.reset(nil, chain.CurrentBlock().Header())
 
@@ -334,23 +392,18 @@ func (pool *TxPool) loop() {
 		case <-evict.C:
 			pool.mu.Lock()
 
-			for addr := range pool.pending {
+			for addr := range pool.queue {
 				// Skip local transactions from the eviction mechanism
-				//if pool.locals.contains(addr) {
-				//	continue
-				//}
-				// Any non-locals old enough should be removed
-				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
-					for _, tx := range pool.pending[addr].Flatten() {
-						pool.removeTx(tx.Hash())
-					}
+				if pool.locals.contains(addr) {
+					continue
 				}
-			}
-			for addr := range pool.queue {
+				// Any non-locals queued for too long should be removed
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
-					for _, tx := range pool.queue[addr].Flatten() {
+					expired := pool.queue[addr].Flatten()
+					for _, tx := range expired {
 						pool.removeTx(tx.Hash())
 					}
+					pool.dropped(DropExpired, expired)
 				}
 			}
 			if pool.priced.items.TxNum() != len(*pool.priced.all) {
@@ -446,6 +499,10 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.currentState = statedb
 	pool.pendingState = state.ManageState(statedb)
 	pool.currentMaxGas = newHead.GasLimit
+	next := new(big.Int).Add(newHead.Number, common.Big1)
+	pool.currentMaxSize = pool.chainconfig.BlockLimits(next).MaxTxSize
+	pool.rules = pool.chainconfig.Rules(next)
+	pool.contractLimits = pool.chainconfig.ContractLimits(next)
 
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
@@ -488,6 +545,25 @@ func (pool *TxPool) SubscribeTxPreEvent(ch chan<- TxPreEvent) event.Subscription
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeTxPromotedEvent registers a subscription of TxPromotedEvent and
+// starts sending event to the given channel.
+func (pool *TxPool) SubscribeTxPromotedEvent(ch chan<- TxPromotedEvent) event.Subscription {
+	return pool.scope.Track(pool.promoteFeed.Subscribe(ch))
+}
+
+// SubscribeTxDroppedEvent registers a subscription of TxDroppedEvent and
+// starts sending event to the given channel.
+func (pool *TxPool) SubscribeTxDroppedEvent(ch chan<- TxDroppedEvent) event.Subscription {
+	return pool.scope.Track(pool.dropFeed.Subscribe(ch))
+}
+
+// dropped notifies subscribers of transactions dropped for the given reason.
+func (pool *TxPool) dropped(reason string, txs types.Transactions) {
+	if len(txs) > 0 {
+		go pool.dropFeed.Send(TxDroppedEvent{Txs: txs, Reason: reason})
+	}
+}
+
 // GasPrice returns the current gas price enforced by the transaction pool.
 func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
@@ -503,9 +579,11 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 	defer pool.mu.Unlock()
 
 	pool.gasPrice = price
-	for _, tx := range pool.priced.Cap(price, pool.locals, pool.currentState) {
+	drops := pool.priced.Cap(price, pool.locals, pool.currentState)
+	for _, tx := range drops {
 		pool.removeTx(tx.Hash())
 	}
+	pool.dropped(DropUnderpriced, drops)
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
 
@@ -572,6 +650,66 @@ func (pool *TxPool) Content() (map[common.Address]types.Transactions, map[common
 	return pending, queued
 }
 
+// ContentFrom retrieves the data content of the transaction pool for a single
+// account, returning its pending as well as queued transactions sorted by nonce.
+func (pool *TxPool) ContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	var pending types.Transactions
+	if list, ok := pool.pending[addr]; ok {
+		pending = list.Flatten()
+	}
+	var queued types.Transactions
+	if list, ok := pool.queue[addr]; ok {
+		queued = list.Flatten()
+	}
+	return pending, queued
+}
+
+// DumpTransactions writes all pending and queued transactions of the pool into
+// the given file, in the format of the local transaction journal. It returns the
+// number of transactions written.
+func (pool *TxPool) DumpTransactions(path string) (int, error) {
+	pending, queued := pool.Content()
+	for addr, txs := range queued {
+		pending[addr] = append(pending[addr], txs...)
+	}
+	return writeTransactions(path, pending)
+}
+
+// LoadTransactions reads transactions previously saved by DumpTransactions and
+// adds them to the pool, as local transactions if their sender is tracked as a
+// local account and as remote ones otherwise. It returns the number of
+// transactions accepted by the pool.
+func (pool *TxPool) LoadTransactions(path string) (int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, err
+	}
+	added := 0
+	err := newTxJournal(path).load(func(tx *types.Transaction) error {
+		add := pool.AddRemote
+		if pool.isLocalTx(tx) {
+			add = pool.AddLocal
+		}
+		if err := add(tx); err != nil {
+			return err
+		}
+		added++
+		return nil
+	})
+	return added, err
+}
+
+// isLocalTx reports whether the sender of the given transaction is tracked as a
+// local account.
+func (pool *TxPool) isLocalTx(tx *types.Transaction) bool {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.locals.containsTx(tx)
+}
+
 // Pending retrieves all currently processable transactions, groupped by origin
 // account and sorted by nonce. The returned transaction set is a copy and can be
 // freely modified by calling code.
@@ -680,6 +818,10 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if tx.Size() > 32*1024 {
 		return ErrOversizedData
 	}
+	// Reject transactions that can't be included in the next block
+	if pool.currentMaxSize > 0 && uint64(tx.Size()) > pool.currentMaxSize {
+		return ErrOversizedTx
+	}
 	// Transactions can't be negative. This may never happen using RLP decoded
 	// transactions but may occur if you create a transaction using the RPC.
 	if tx.Value().Sign() < 0 {
@@ -692,11 +834,37 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if params.MaxOneContractGasLimit < tx.Gas() {
 		return errors.New("Gas over Limit!")
 	}
+	// Make sure the transaction is meant for this chain
+	if !tx.Protected() {
+		unprotectedTxCounter.Inc(1)
+		return ErrUnprotectedTx
+	}
+	if tx.ChainId().Cmp(pool.chainconfig.ChainId) != 0 {
+		replayTxCounter.Inc(1)
+		return ErrCrossChainReplay
+	}
 	// Make sure the transaction is signed properly
 	from, err := types.Sender(pool.signer, tx)
 	if err != nil {
+		if err == types.ErrMalleableSig {
+			malleableTxCounter.Inc(1)
+		}
 		return ErrInvalidSender
 	}
+	// Make sure a sponsored transaction is signed by a fee payer affording its gas
+	if tx.FeePayer() != nil {
+		if !pool.rules.IsFeePayer {
+			return ErrFeePayerNotActive
+		}
+		payer, err := pool.signer.FeePayer(tx)
+		if err != nil || payer == from {
+			return types.ErrInvalidFeePayer
+		}
+		gas := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas()))
+		if pool.currentState.GetBalance(payer).Cmp(gas) < 0 {
+			return ErrInsufficientFeePayerFunds
+		}
+	}
 	// Drop non-local transactions under our own minimal accepted gas price
 	local = local || pool.locals.contains(from) // account may be local even if the transaction arrived from the network
 	if !local && pool.gasPrice.Cmp(tx.GasPrice()) > 0 {
@@ -780,6 +948,15 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if err != nil {
 		return err
 	}
+	if tx.TxDataAction() == types.ActionCreateContract {
+		initGas, err := InitCodeGas(pool.contractLimits, tx.Data())
+		if err != nil {
+			return err
+		}
+		if intrGas += initGas; intrGas < initGas {
+			return ErrIntrinsicGas
+		}
+	}
 	if tx.Gas() < intrGas {
 		return ErrIntrinsicGas
 	}
@@ -840,33 +1017,49 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		return false, err
 	}
 
-	// If the transaction pool is full, discard underpriced transactions
-	if uint64(len(pool.all)) >= wholeTransactionNumber {
+	// If the transaction pool is full, discard underpriced transactions. Replacements
+	// of a pending or queued nonce don't grow the pool, so they need no room made.
+	from, _ := types.Sender(pool.signer, tx) // already validated
+	local = local || pool.locals.contains(from) // account may be local even if the transaction arrived from the network
+
+	if uint64(len(pool.all)) >= wholeTransactionNumber && !pool.replaces(from, tx) {
 		//log.Debug("Transaction pool is full", "timestamp", time.Now().UnixNano())
 		index, txList := pool.priced.Get(txType)
 		var removeTxHash common.Hash
-		// if Transaction Type exist, replace the unpriced one
+		// if Transaction Type exist, replace the unpriced one. Local transactions are
+		// never evicted, nor are new ones rejected for their price.
 		if -1 == index {
 			if len(*pool.priced.items) > maxTrxType {
 				log.Trace("Transaction pool is full, discarding transaction", "hash", hash)
 				return false, ErrFullPending
 			}
-			removeTxHash = pool.priced.RemoveTx()
+			removeHash, ok := pool.priced.RemoveTx(pool.locals)
+			if !ok {
+				log.Trace("Transaction pool is full of local transactions, discarding transaction", "hash", hash)
+				return false, ErrFullPending
+			}
+			removeTxHash = removeHash
 		} else {
-			if pool.priced.Underpriced(tx) {
+			if !local && pool.priced.Underpriced(tx, pool.locals) {
 				log.Trace("Discarding underpriced transaction", "hash", hash, "price", tx.GasPrice())
 				underpricedTxCounter.Inc(1)
 				return false, ErrUnderpriced
 			}
-			sort.Sort(txList)
-			removeTx := (*txList)[len(*txList)-1]
+			removeTx := pool.priced.Cheapest(txList, pool.locals)
+			if removeTx == nil {
+				log.Trace("Transaction pool is full of local transactions, discarding transaction", "hash", hash)
+				return false, ErrFullPending
+			}
 			removeTxHash = removeTx.Hash()
 		}
+		evicted := pool.all[removeTxHash]
 		pool.removeTx(removeTxHash)
+		if evicted != nil {
+			pool.dropped(DropUnderpriced, types.Transactions{evicted})
+		}
 		//log.Debug("Transaction pool is full| remove other tx", "hash", removeTxHash, "timestamp", time.Now().UnixNano())
 	}
 	// If the transaction is replacing an already pending one, do directly
-	from, _ := types.Sender(pool.signer, tx) // already validated
 	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
 		// Nonce already pending, check if required price bump is met
 		inserted, old := list.Add(tx, pool.config.PriceBump)
@@ -910,6 +1103,20 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 	return replace, nil
 }
 
+// replaces returns whether the transaction has the same nonce as one of the
+// account's pending or queued transactions.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) replaces(from common.Address, tx *types.Transaction) bool {
+	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
+		return true
+	}
+	if list := pool.queue[from]; list != nil && list.Overlaps(tx) {
+		return true
+	}
+	return false
+}
+
 func IsContractTransaction(tx *types.Transaction, db *state.StateDB) bool {
 	switch tx.TxDataAction() {
 	case types.ActionRegister, types.ActionAddVote, types.ActionSubVote, types.ActionPublishAsset:
@@ -947,6 +1154,10 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 		pool.priced.RemoveByHash(old.GetTransactionType(), old.Hash())
 		queuedReplaceCounter.Inc(1)
 	}
+	// Start the lifetime of accounts with nothing in the pool yet
+	if _, ok := pool.beats[from]; !ok {
+		pool.beats[from] = time.Now()
+	}
 	pool.all[hash] = tx
 	pool.priced.Put(tx, pool.currentState)
 	return old != nil, nil
@@ -964,10 +1175,11 @@ func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
 	}
 }
 
-// promoteTx adds a transaction to the pending (processable) list of transactions.
+// promoteTx adds a transaction to the pending (processable) list of transactions,
+// reporting whether it was inserted.
 //
 // Note, this method assumes the pool lock is held!
-func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.Transaction) {
+func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.Transaction) bool {
 	// Try to insert the transaction into the pending queue
 	if pool.pending[addr] == nil {
 		pool.pending[addr] = newTxList(true)
@@ -981,7 +1193,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.RemoveByHash(tx.GetTransactionType(), hash)
 
 		pendingDiscardCounter.Inc(1)
-		return
+		return false
 	}
 	// Otherwise discard any previous transaction and mark this
 	if old != nil {
@@ -1000,6 +1212,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	pool.pendingState.SetNonce(addr, tx.Nonce()+1)
 
 	go pool.txFeed.Send(TxPreEvent{tx})
+	return true
 }
 
 // AddLocal enqueues a single transaction into the pool if it is valid, marking
@@ -1030,6 +1243,67 @@ func (pool *TxPool) AddRemotes(txs []*types.Transaction) []error {
 	return pool.addTxs(txs, false)
 }
 
+// AddLocalSequence adds a nonce ordered sequence of transactions of a single
+// sender, continuing right after its pending nonce, as local transactions. The
+// sequence is admitted as a whole or not at all.
+func (pool *TxPool) AddLocalSequence(txs []*types.Transaction) error {
+	return pool.addSequence(txs, !pool.config.NoLocals)
+}
+
+// addSequence admits a transaction sequence atomically. Everything that could
+// make a single transaction fail is checked upfront. The sender is only marked
+// local and the transactions journaled once all of them are queued, so removing
+// the already queued ones is all it takes should one still be rejected.
+func (pool *TxPool) addSequence(txs []*types.Transaction, local bool) error {
+	if len(txs) == 0 {
+		return nil
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	from, err := types.Sender(pool.signer, txs[0])
+	if err != nil {
+		return ErrInvalidSender
+	}
+	nonce := pool.pendingState.GetNonce(from)
+	for i, tx := range txs {
+		if pool.all[tx.Hash()] != nil {
+			return fmt.Errorf("transaction %d: known transaction: %x", i, tx.Hash())
+		}
+		if err := pool.validateTx(tx, local); err != nil {
+			return fmt.Errorf("transaction %d: %v", i, err)
+		}
+		if sender, _ := types.Sender(pool.signer, tx); sender != from {
+			return ErrMixedSenders
+		}
+		if tx.Nonce() != nonce+uint64(i) {
+			return ErrNonceGap
+		}
+		if pool.replaces(from, tx) {
+			return ErrSequenceOverlap
+		}
+	}
+	if uint64(len(pool.all)+len(txs)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
+		return ErrFullPending
+	}
+	for i, tx := range txs {
+		if _, err := pool.enqueueTx(tx.Hash(), tx); err != nil {
+			for _, added := range txs[:i] {
+				pool.removeTx(added.Hash())
+			}
+			return fmt.Errorf("transaction %d: %v", i, err)
+		}
+	}
+	if local {
+		pool.locals.add(from)
+	}
+	for _, tx := range txs {
+		pool.journalTx(from, tx)
+	}
+	pool.promoteExecutables([]common.Address{from})
+	return nil
+}
+
 // addTx enqueues a single transaction into the pool if it is valid.
 func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 	pool.mu.Lock()
@@ -1135,7 +1409,9 @@ func (pool *TxPool) removeTx(hash common.Hash) {
 			// If no more transactions are left, remove the list
 			if pending.Empty() {
 				delete(pool.pending, addr)
-				delete(pool.beats, addr)
+				if pool.queue[addr] == nil {
+					delete(pool.beats, addr)
+				}
 			} else {
 				// Otherwise postpone any invalidated transactions
 				for _, tx := range invalids {
@@ -1154,6 +1430,9 @@ func (pool *TxPool) removeTx(hash common.Hash) {
 		future.Remove(tx)
 		if future.Empty() {
 			delete(pool.queue, addr)
+			if pool.pending[addr] == nil {
+				delete(pool.beats, addr)
+			}
 		}
 	}
 }
@@ -1171,6 +1450,9 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			accounts = append(accounts, addr)
 		}
 	}
+	// Track the promoted and dropped transactions to notify subscribers of
+	var promoted, unpayable, overflow types.Transactions
+
 	// Iterate over all accounts and promote any executable transactions
 	for _, addr := range accounts {
 		list := pool.queue[addr]
@@ -1196,6 +1478,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			delete(pool.all, hash)
 			pool.priced.RemoveByHash(txType, hash)
 			queuedNofundsCounter.Inc(1)
+			unpayable = append(unpayable, tx)
 			//countTransaction(tx, subTransactionCount, pool.currentState)
 		}
 		//log.Info("Tx_Pool|promoteExecutables|After drop costly transaction", "contractTxNumber", contractTxCounter.Count(), "normalTxNumber", normalTxCounter.Count(), "pending remain", int64(wholeTransactionNumber)-contractTxCounter.Count()-normalTxCounter.Count())
@@ -1203,7 +1486,9 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		for _, tx := range list.Ready(pool.pendingState.GetNonce(addr)) {
 			hash := tx.Hash()
 			log.Trace("Promoting queued transaction", "hash", hash)
-			pool.promoteTx(addr, hash, tx)
+			if pool.promoteTx(addr, hash, tx) {
+				promoted = append(promoted, tx)
+			}
 		}
 		// Drop all transactions over the allowed limit
 		if !pool.locals.contains(addr) {
@@ -1214,6 +1499,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 				pool.priced.RemoveByHash(txType, hash)
 				queuedRateLimitCounter.Inc(1)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
+				overflow = append(overflow, tx)
 				//countTransaction(tx, subTransactionCount, pool.currentState)
 			}
 		}
@@ -1221,6 +1507,9 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		// Delete the entire queue entry if it became empty.
 		if list.Empty() {
 			delete(pool.queue, addr)
+			if pool.pending[addr] == nil {
+				delete(pool.beats, addr)
+			}
 		}
 	}
 	// If the pending limit is overflown, start equalizing allowances
@@ -1267,6 +1556,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 								pool.pendingState.SetNonce(offenders[i], nonce)
 							}
 							log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
+							overflow = append(overflow, tx)
 						}
 						pending--
 					}
@@ -1292,6 +1582,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 							pool.pendingState.SetNonce(addr, nonce)
 						}
 						log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
+						overflow = append(overflow, tx)
 					}
 					pending--
 				}
@@ -1326,6 +1617,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			if size := uint64(list.Len()); size <= drop {
 				for _, tx := range list.Flatten() {
 					pool.removeTx(tx.Hash())
+					overflow = append(overflow, tx)
 					//countTransaction(tx, subTransactionCount, pool.currentState)
 				}
 				drop -= size
@@ -1336,11 +1628,17 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			txs := list.Flatten()
 			for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
 				pool.removeTx(txs[i].Hash())
+				overflow = append(overflow, txs[i])
 				drop--
 				queuedRateLimitCounter.Inc(1)
 			}
 		}
 	}
+	if len(promoted) > 0 {
+		go pool.promoteFeed.Send(TxPromotedEvent{promoted})
+	}
+	pool.dropped(DropUnpayable, unpayable)
+	pool.dropped(DropOverflow, overflow)
 	//log.Info("Tx_Pool|promoteExecutables|End", "contractTxNumber", contractTxCounter.Count(), "normalTxNumber", normalTxCounter.Count(), "pending remain", int64(wholeTransactionNumber)-contractTxCounter.Count()-normalTxCounter.Count())
 }
 
@@ -1350,6 +1648,8 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 func (pool *TxPool) demoteUnexecutables() {
 	//wholeTransactionNumber := pool.config.GlobalQueue + pool.config.GlobalSlots
 	//log.Info("Tx_Pool|demoteUnexecutables|Start", "contractTxNumber", contractTxCounter.Count(), "normalTxNumber", normalTxCounter.Count(), "pending remain", int64(wholeTransactionNumber)-contractTxCounter.Count()-normalTxCounter.Count())
+	var unpayable types.Transactions
+
 	// Iterate over all accounts and demote any non-executable transactions
 	for addr, list := range pool.pending {
 		nonce := pool.currentState.GetNonce(addr)
@@ -1373,6 +1673,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			delete(pool.all, hash)
 			pool.priced.RemoveByHash(txType, hash)
 			pendingNofundsCounter.Inc(1)
+			unpayable = append(unpayable, tx)
 			//countTransaction(tx, subTransactionCount, pool.currentState)
 		}
 		//log.Info("Tx_Pool|demoteUnexecutables|After Drop all transactions that are too costly", "contractTxNumber", contractTxCounter.Count(), "normalTxNumber", normalTxCounter.Count(), "pending remain", int64(wholeTransactionNumber)-contractTxCounter.Count()-normalTxCounter.Count())
@@ -1392,9 +1693,12 @@ func (pool *TxPool) demoteUnexecutables() {
 		// Delete the entire queue entry if it became empty.
 		if list.Empty() {
 			delete(pool.pending, addr)
-			delete(pool.beats, addr)
+			if pool.queue[addr] == nil {
+				delete(pool.beats, addr)
+			}
 		}
 	}
+	pool.dropped(DropUnpayable, unpayable)
 }
 
 // addressByHeartbeat is an account address tagged with its last activity timestamp.
@@ -19,19 +19,26 @@ package core
 import (
 	"bytes"
 	"container/heap"
+	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
+	"github.com/Aurorachain-io/go-aoa/aoadb"
 	"github.com/Aurorachain-io/go-aoa/common"
 	"github.com/Aurorachain-io/go-aoa/common/hexutil"
+	"github.com/Aurorachain-io/go-aoa/consensus/delegatestate"
+	"github.com/Aurorachain-io/go-aoa/core/state"
 	"github.com/Aurorachain-io/go-aoa/core/types"
 	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/event"
 	"github.com/Aurorachain-io/go-aoa/metrics"
+	"github.com/Aurorachain-io/go-aoa/params"
 	"github.com/Aurorachain-io/go-aoa/rlp"
 	"io"
 	"io/ioutil"
 	"math/big"
 	"net/http"
 	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"sort"
@@ -400,3 +407,493 @@ func TestDecode(t *testing.T) {
 	_ = rlp.DecodeBytes(decode, tx)
 	fmt.Println(tx)
 }
+
+// Tests that the content of a single account is retrieved from both the pending
+// and the queued transactions, sorted by nonce.
+func TestTransactionPoolContentFrom(t *testing.T) {
+	var (
+		from  = common.HexToAddress("0xaa")
+		other = common.HexToAddress("0xbb")
+		to    = common.HexToAddress("0x01")
+	)
+	pool := &TxPool{
+		pending: make(map[common.Address]*txList),
+		queue:   make(map[common.Address]*txList),
+	}
+	add := func(lists map[common.Address]*txList, addr common.Address, nonce uint64) {
+		if lists[addr] == nil {
+			lists[addr] = newTxList(false)
+		}
+		lists[addr].Add(types.NewTransaction(nonce, to, big.NewInt(1), 21000, big.NewInt(1), nil, types.ActionTrans, nil, ""), 0)
+	}
+	add(pool.pending, from, 1)
+	add(pool.pending, from, 0)
+	add(pool.queue, from, 5)
+	add(pool.pending, other, 0)
+
+	pending, queued := pool.ContentFrom(from)
+	if len(pending) != 2 || pending[0].Nonce() != 0 || pending[1].Nonce() != 1 {
+		t.Errorf("pending content mismatch: have %d txs, want nonces 0 and 1", len(pending))
+	}
+	if len(queued) != 1 || queued[0].Nonce() != 5 {
+		t.Errorf("queued content mismatch: have %d txs, want nonce 5", len(queued))
+	}
+	if pending, queued := pool.ContentFrom(to); len(pending) != 0 || len(queued) != 0 {
+		t.Errorf("unknown account content mismatch: have %d/%d txs, want none", len(pending), len(queued))
+	}
+}
+
+// testPoolChain is a blockchain stub with a single funded state, just enough for
+// a transaction pool to validate transactions against.
+type testPoolChain struct {
+	statedb       *state.StateDB
+	chainHeadFeed event.Feed
+}
+
+func (c *testPoolChain) CurrentBlock() *types.Block {
+	return types.NewBlock(&types.Header{Number: new(big.Int), GasLimit: 10000000}, nil, nil)
+}
+
+func (c *testPoolChain) GetBlock(common.Hash, uint64) *types.Block { return c.CurrentBlock() }
+
+func (c *testPoolChain) StateAt(common.Hash) (*state.StateDB, error) { return c.statedb, nil }
+
+func (c *testPoolChain) DelegateStateAt(common.Hash) (*delegatestate.DelegateDB, error) {
+	return nil, nil
+}
+
+func (c *testPoolChain) GetDelegatePoll() (*map[common.Address]types.Candidate, error) {
+	return nil, nil
+}
+
+func (c *testPoolChain) SubscribeChainHeadEvent(ch chan<- ChainHeadEvent) event.Subscription {
+	return c.chainHeadFeed.Subscribe(ch)
+}
+
+// testPoolChainConfig is the chain configuration transaction pools are tested with.
+var testPoolChainConfig = &params.ChainConfig{ChainId: big.NewInt(1), MaxElectDelegate: big.NewInt(1)}
+
+// newTestPoolChain creates a blockchain stub funding the accounts of the given keys.
+func newTestPoolChain(keys ...*ecdsa.PrivateKey) *testPoolChain {
+	db, _ := aoadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	for _, key := range keys {
+		statedb.AddBalance(crypto.PubkeyToAddress(key.PublicKey), new(big.Int).Mul(big.NewInt(params.Em), big.NewInt(1000)))
+	}
+	return &testPoolChain{statedb: statedb}
+}
+
+// poolTransaction creates a value transfer signed by the given key.
+func poolTransaction(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, price int64) *types.Transaction {
+	tx := types.NewTransaction(nonce, common.HexToAddress("0x01"), big.NewInt(1), 100000, big.NewInt(price), nil, types.ActionTrans, nil, "")
+	signed, err := types.SignTx(tx, types.NewAuroraSigner(testPoolChainConfig.ChainId), key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return signed
+}
+
+// Tests that a transaction with the nonce of a pending or queued one replaces it
+// only if its gas price is bumped enough, and that replacing a transaction in a
+// full pool doesn't evict any other one.
+func TestTransactionReplacement(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := DefaultTxPoolConfig
+	config.Journal, config.GlobalSlots, config.GlobalQueue = "", 2, 1
+
+	pool := NewTxPool(config, testPoolChainConfig, newTestPoolChain(key))
+	defer pool.Stop()
+
+	transaction := func(nonce uint64, price int64) *types.Transaction {
+		return poolTransaction(t, key, nonce, price)
+	}
+	price := int64(config.PriceLimit) * 100
+
+	// Fill the pool up with two pending and one queued transaction
+	for _, tx := range []*types.Transaction{transaction(0, price), transaction(1, price), transaction(3, price)} {
+		if err := pool.AddRemote(tx); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", tx.Nonce(), err)
+		}
+	}
+	// Replacements without enough of a price bump must be rejected
+	bumped := price * int64(100+config.PriceBump) / 100
+	if err := pool.AddRemote(transaction(1, bumped-1)); err != ErrReplaceUnderpriced {
+		t.Errorf("underpriced pending replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
+	if err := pool.AddRemote(transaction(3, bumped-1)); err != ErrReplaceUnderpriced {
+		t.Errorf("underpriced queued replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
+	// Properly bumped replacements must evict the replaced transactions only
+	if err := pool.AddRemote(transaction(1, bumped)); err != nil {
+		t.Errorf("failed to replace pending transaction: %v", err)
+	}
+	if err := pool.AddRemote(transaction(3, bumped)); err != nil {
+		t.Errorf("failed to replace queued transaction: %v", err)
+	}
+	pending, queued := pool.ContentFrom(from)
+	if len(pending) != 2 || len(queued) != 1 {
+		t.Fatalf("pool content mismatch: have %d pending and %d queued, want 2 and 1", len(pending), len(queued))
+	}
+	for _, tx := range append(pending, queued...) {
+		want := bumped
+		if tx.Nonce() == 0 {
+			want = price
+		}
+		if tx.GasPrice().Int64() != want {
+			t.Errorf("transaction %d price mismatch: have %v, want %d", tx.Nonce(), tx.GasPrice(), want)
+		}
+	}
+}
+
+// Tests that transactions of accounts configured as local are neither rejected
+// nor evicted for their price when the pool is full.
+func TestTransactionLocals(t *testing.T) {
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+
+	config := DefaultTxPoolConfig
+	config.Journal, config.GlobalSlots, config.GlobalQueue = "", 2, 1
+	config.Locals = []common.Address{crypto.PubkeyToAddress(local.PublicKey)}
+
+	pool := NewTxPool(config, testPoolChainConfig, newTestPoolChain(local, remote, other))
+	defer pool.Stop()
+
+	// Fill both the pending slots and the queue with remote transactions
+	price := int64(config.PriceLimit) * 100
+	for _, nonce := range []uint64{0, 1, 3} {
+		if err := pool.AddRemote(poolTransaction(t, remote, nonce, price)); err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", nonce, err)
+		}
+	}
+	// A cheap transaction of the local account arriving from the network must
+	// be accepted into the full pool, evicting a remote one
+	if err := pool.AddRemote(poolTransaction(t, local, 0, price/2)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	// Pricier remote transactions may only evict the remaining remote ones
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		if err := pool.AddRemote(poolTransaction(t, other, nonce, price*10)); err != nil {
+			t.Fatalf("failed to add pricier remote transaction %d: %v", nonce, err)
+		}
+	}
+	if err := pool.AddRemote(poolTransaction(t, other, 2, price*10)); err != ErrUnderpriced {
+		t.Errorf("remote transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	if pending, _ := pool.ContentFrom(crypto.PubkeyToAddress(local.PublicKey)); len(pending) != 1 {
+		t.Errorf("local transaction evicted: have %d pending, want 1", len(pending))
+	}
+	if pending, queued := pool.ContentFrom(crypto.PubkeyToAddress(remote.PublicKey)); len(pending)+len(queued) != 0 {
+		t.Errorf("remote transactions not evicted: have %d pending and %d queued", len(pending), len(queued))
+	}
+}
+
+// Tests that local transactions are journaled and restored into the pool on
+// restart, whereas remote ones are not.
+func TestTransactionJournaling(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txjournal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+
+	config := DefaultTxPoolConfig
+	config.Journal = filepath.Join(dir, "transactions.rlp")
+
+	chain := newTestPoolChain(local, remote)
+	pool := NewTxPool(config, testPoolChainConfig, chain)
+
+	price := int64(config.PriceLimit) * 100
+	if err := pool.AddLocal(poolTransaction(t, local, 0, price)); err != nil {
+		t.Fatalf("failed to add pending local transaction: %v", err)
+	}
+	if err := pool.AddLocal(poolTransaction(t, local, 2, price)); err != nil {
+		t.Fatalf("failed to add queued local transaction: %v", err)
+	}
+	if err := pool.AddRemote(poolTransaction(t, remote, 0, price)); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	pool.Stop()
+
+	// Restart the pool and check that only the local transactions were restored
+	pool = NewTxPool(config, testPoolChainConfig, chain)
+	defer pool.Stop()
+
+	if pending, queued := pool.ContentFrom(crypto.PubkeyToAddress(local.PublicKey)); len(pending) != 1 || len(queued) != 1 {
+		t.Errorf("restored local transactions mismatch: have %d pending and %d queued, want 1 and 1", len(pending), len(queued))
+	}
+	if pending, queued := pool.ContentFrom(crypto.PubkeyToAddress(remote.PublicKey)); len(pending)+len(queued) != 0 {
+		t.Errorf("remote transactions restored: have %d pending and %d queued", len(pending), len(queued))
+	}
+	if !pool.locals.contains(crypto.PubkeyToAddress(local.PublicKey)) {
+		t.Errorf("restored local account not tracked as local")
+	}
+}
+
+// Tests that transactions dumped from a pool are restored by loading the dump,
+// keeping the transactions of local accounts local.
+func TestTransactionDumpLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txdump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+
+	config := DefaultTxPoolConfig
+	config.Journal = ""
+	config.Locals = []common.Address{crypto.PubkeyToAddress(local.PublicKey)}
+
+	chain := newTestPoolChain(local, remote)
+	pool := NewTxPool(config, testPoolChainConfig, chain)
+
+	price := int64(config.PriceLimit) * 100
+	for _, tx := range []*types.Transaction{poolTransaction(t, local, 0, price), poolTransaction(t, local, 2, price), poolTransaction(t, remote, 0, price)} {
+		if err := pool.AddRemote(tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	dump := filepath.Join(dir, "dump.rlp")
+	if n, err := pool.DumpTransactions(dump); err != nil || n != 3 {
+		t.Fatalf("failed to dump transactions: %d dumped, %v", n, err)
+	}
+	pool.Stop()
+
+	// Load the dump into a journaling pool, which must only journal the local ones
+	config.Journal = filepath.Join(dir, "transactions.rlp")
+	pool = NewTxPool(config, testPoolChainConfig, chain)
+	if n, err := pool.LoadTransactions(dump); err != nil || n != 3 {
+		t.Fatalf("failed to load transactions: %d loaded, %v", n, err)
+	}
+	if pending, queued := pool.ContentFrom(crypto.PubkeyToAddress(remote.PublicKey)); len(pending) != 1 || len(queued) != 0 {
+		t.Errorf("loaded remote transactions mismatch: have %d pending and %d queued, want 1 and 0", len(pending), len(queued))
+	}
+	pool.Stop()
+
+	config.Locals = nil
+	pool = NewTxPool(config, testPoolChainConfig, chain)
+	defer pool.Stop()
+
+	if pending, queued := pool.ContentFrom(crypto.PubkeyToAddress(local.PublicKey)); len(pending) != 1 || len(queued) != 1 {
+		t.Errorf("journaled local transactions mismatch: have %d pending and %d queued, want 1 and 1", len(pending), len(queued))
+	}
+	if pending, queued := pool.ContentFrom(crypto.PubkeyToAddress(remote.PublicKey)); len(pending)+len(queued) != 0 {
+		t.Errorf("remote transactions journaled: have %d pending and %d queued", len(pending), len(queued))
+	}
+}
+
+// Tests that remote transactions queued for longer than the configured lifetime
+// are evicted, whereas executable and local ones are kept.
+func TestTransactionQueueLifetime(t *testing.T) {
+	defer func(old time.Duration) { evictionInterval = old }(evictionInterval)
+	evictionInterval = 50 * time.Millisecond
+
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+
+	config := DefaultTxPoolConfig
+	config.Journal, config.Lifetime = "", 100*time.Millisecond
+	config.Locals = []common.Address{crypto.PubkeyToAddress(local.PublicKey)}
+
+	pool := NewTxPool(config, testPoolChainConfig, newTestPoolChain(local, remote))
+	defer pool.Stop()
+
+	price := int64(config.PriceLimit) * 100
+	for _, tx := range []*types.Transaction{poolTransaction(t, remote, 0, price), poolTransaction(t, remote, 2, price), poolTransaction(t, local, 1, price)} {
+		if err := pool.AddRemote(tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	time.Sleep(2*config.Lifetime + 2*evictionInterval)
+
+	if pending, queued := pool.ContentFrom(crypto.PubkeyToAddress(remote.PublicKey)); len(pending) != 1 || len(queued) != 0 {
+		t.Errorf("remote transactions mismatch: have %d pending and %d queued, want 1 and 0", len(pending), len(queued))
+	}
+	if _, queued := pool.ContentFrom(crypto.PubkeyToAddress(local.PublicKey)); len(queued) != 1 {
+		t.Errorf("local transaction evicted: have %d queued, want 1", len(queued))
+	}
+}
+
+// Tests that queued transactions are announced once the nonce gap in front of
+// them is filled, and that dropped transactions are announced with the reason.
+func TestTransactionPromotionEvents(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	config := DefaultTxPoolConfig
+	config.Journal = ""
+
+	pool := NewTxPool(config, testPoolChainConfig, newTestPoolChain(key))
+	defer pool.Stop()
+
+	promoted := make(chan TxPromotedEvent, 16)
+	defer pool.SubscribeTxPromotedEvent(promoted).Unsubscribe()
+	dropped := make(chan TxDroppedEvent, 16)
+	defer pool.SubscribeTxDroppedEvent(dropped).Unsubscribe()
+
+	price := int64(config.PriceLimit) * 100
+	gapped := poolTransaction(t, key, 1, price)
+	if err := pool.AddRemote(gapped); err != nil {
+		t.Fatalf("failed to add gapped transaction: %v", err)
+	}
+	select {
+	case ev := <-promoted:
+		t.Fatalf("gapped transaction promoted: %v", ev.Txs)
+	case <-time.After(100 * time.Millisecond):
+	}
+	// Filling the gap must promote both transactions
+	if err := pool.AddRemote(poolTransaction(t, key, 0, price)); err != nil {
+		t.Fatalf("failed to add gap filling transaction: %v", err)
+	}
+	select {
+	case ev := <-promoted:
+		if len(ev.Txs) != 2 || ev.Txs[1].Hash() != gapped.Hash() {
+			t.Errorf("promoted transactions mismatch: have %v", ev.Txs)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("promotion not announced")
+	}
+	// Raising the price limit above the transactions must drop them
+	pool.SetGasPrice(big.NewInt(price + 1))
+	select {
+	case ev := <-dropped:
+		if len(ev.Txs) != 2 || ev.Reason != DropUnderpriced {
+			t.Errorf("dropped transactions mismatch: have %d, reason %q", len(ev.Txs), ev.Reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("drop not announced")
+	}
+}
+
+// Tests that transaction sequences are admitted as a whole only if they are
+// gapless, from a single sender and continue its pending nonce.
+func TestTransactionSequence(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := DefaultTxPoolConfig
+	config.Journal = ""
+
+	pool := NewTxPool(config, testPoolChainConfig, newTestPoolChain(key, other))
+	defer pool.Stop()
+
+	price := int64(config.PriceLimit) * 100
+	sequence := func(nonces ...uint64) []*types.Transaction {
+		txs := make([]*types.Transaction, len(nonces))
+		for i, nonce := range nonces {
+			txs[i] = poolTransaction(t, key, nonce, price)
+		}
+		return txs
+	}
+	if err := pool.AddLocal(poolTransaction(t, key, 3, price)); err != nil {
+		t.Fatalf("failed to add queued transaction: %v", err)
+	}
+	tests := []struct {
+		txs []*types.Transaction
+		err error
+	}{
+		{sequence(1, 2), ErrNonceGap},
+		{sequence(0, 2), ErrNonceGap},
+		{append(sequence(0, 1, 2), poolTransaction(t, key, 3, 2*price)), ErrSequenceOverlap},
+		{append(sequence(0), poolTransaction(t, other, 0, price)), ErrMixedSenders},
+	}
+	for i, tt := range tests {
+		if err := pool.AddLocalSequence(tt.txs); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if pending, queued := pool.ContentFrom(from); len(pending) != 0 || len(queued) != 1 {
+			t.Errorf("test %d: partial sequence admitted: have %d pending and %d queued", i, len(pending), len(queued))
+		}
+	}
+	// Rejected sequences must not mark their sender local
+	if err := pool.AddLocalSequence([]*types.Transaction{poolTransaction(t, other, 1, price)}); err != ErrNonceGap {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrNonceGap)
+	}
+	if pool.locals.contains(crypto.PubkeyToAddress(other.PublicKey)) {
+		t.Errorf("sender of rejected sequence marked local")
+	}
+	// A gapless sequence must be admitted, promoting the queued transaction too
+	if err := pool.AddLocalSequence(sequence(0, 1, 2)); err != nil {
+		t.Fatalf("failed to add sequence: %v", err)
+	}
+	if pending, queued := pool.ContentFrom(from); len(pending) != 4 || len(queued) != 0 {
+		t.Errorf("pool content mismatch: have %d pending and %d queued, want 4 and 0", len(pending), len(queued))
+	}
+}
+
+// sponsoredTransaction creates a value transfer signed by the given key, whose
+// gas is paid by the given fee payer and signed by the given payer key.
+func sponsoredTransaction(t *testing.T, key *ecdsa.PrivateKey, payer common.Address, payerKey *ecdsa.PrivateKey, nonce uint64, price int64) *types.Transaction {
+	signer := types.NewAuroraSigner(testPoolChainConfig.ChainId)
+
+	tx := types.NewTransaction(nonce, common.HexToAddress("0x01"), big.NewInt(1), 100000, big.NewInt(price), nil, types.ActionTrans, nil, "")
+	signed, err := types.SignTx(tx.WithFeePayer(payer), signer, key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if signed, err = types.SignFeePayer(signed, signer, payerKey); err != nil {
+		t.Fatalf("failed to sign transaction as fee payer: %v", err)
+	}
+	return signed
+}
+
+// Tests that sponsored transactions are only accepted after the fee payer fork,
+// if signed by their fee payer and if the fee payer can afford their gas.
+func TestTransactionFeePayer(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	payerKey, _ := crypto.GenerateKey()
+	poorKey, _ := crypto.GenerateKey()
+	payer, poor := crypto.PubkeyToAddress(payerKey.PublicKey), crypto.PubkeyToAddress(poorKey.PublicKey)
+
+	config := DefaultTxPoolConfig
+	config.Journal = ""
+
+	chainconfig := *testPoolChainConfig
+	chainconfig.FeePayerBlock = big.NewInt(0)
+
+	// Fund the sender for the transferred value only, its gas has to be sponsored
+	chain := newTestPoolChain(payerKey)
+	chain.statedb.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1))
+
+	price := int64(config.PriceLimit)
+	tests := []struct {
+		config *params.ChainConfig
+		tx     *types.Transaction
+		err    error
+	}{
+		{testPoolChainConfig, sponsoredTransaction(t, key, payer, payerKey, 0, price), ErrFeePayerNotActive},
+		{&chainconfig, poolTransaction(t, key, 0, price), ErrInsufficientFunds},
+		{&chainconfig, sponsoredTransaction(t, key, payer, poorKey, 0, price), types.ErrInvalidFeePayer},
+		{&chainconfig, sponsoredTransaction(t, key, poor, poorKey, 0, price), ErrInsufficientFeePayerFunds},
+		{&chainconfig, sponsoredTransaction(t, key, payer, payerKey, 0, price), nil},
+	}
+	for i, tt := range tests {
+		pool := NewTxPool(config, tt.config, chain)
+		if err := pool.AddLocal(tt.tx); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		pool.Stop()
+	}
+}
+
+// Tests that unusable pool limits are replaced by the defaults.
+func TestTxPoolConfigSanitize(t *testing.T) {
+	config := DefaultTxPoolConfig
+	config.AccountSlots, config.GlobalSlots, config.AccountQueue, config.GlobalQueue = 0, 0, 0, 0
+
+	conf := config.sanitize()
+	if conf.AccountSlots != DefaultTxPoolConfig.AccountSlots || conf.GlobalSlots != DefaultTxPoolConfig.GlobalSlots {
+		t.Errorf("slots mismatch: have %d/%d, want %d/%d", conf.AccountSlots, conf.GlobalSlots, DefaultTxPoolConfig.AccountSlots, DefaultTxPoolConfig.GlobalSlots)
+	}
+	// A zero queue disables queueing of future transactions and must be kept
+	if conf.AccountQueue != 0 || conf.GlobalQueue != 0 {
+		t.Errorf("queue mismatch: have %d/%d, want 0/0", conf.AccountQueue, conf.GlobalQueue)
+	}
+}
@@ -131,6 +131,15 @@ func (as *Assets) GetAssets() []Asset {
 	return nil
 }
 
+// Copy returns a deep copy of the asset list.
+func (as *Assets) Copy() *Assets {
+	cpy := &Assets{assetList: make([]Asset, len(as.assetList))}
+	for i, a := range as.assetList {
+		cpy.assetList[i] = Asset{ID: a.ID, Balance: new(big.Int).Set(a.Balance), Extens: common.CopyBytes(a.Extens)}
+	}
+	return cpy
+}
+
 func (as *Assets) IsEmpty() bool {
 	return len(as.assetList) == 0
 }
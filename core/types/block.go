@@ -82,10 +82,11 @@ type Header struct {
 	Extra    []byte   `json:"extraData"        gencodec:"required"`
 	// MixDigest    common.Hash    `json:"mixHash"          gencodec:"required"`
 	// Nonce              BlockNonce  `json:"nonce"            gencodec:"required"`
-	AgentName          []byte      `json:"agentName"        gencodec:"required"`
-	DelegateRoot       common.Hash `json:"delegateRoot"     gencodec:"required"`
-	ShuffleHash        common.Hash `json:"shuffleHash"      gencodec:"required"`
-	ShuffleBlockNumber *big.Int    `json:"shuffleBlockNumber"        gencodec:"required"`
+	AgentName          []byte        `json:"agentName"        gencodec:"required"`
+	DelegateRoot       common.Hash   `json:"delegateRoot"     gencodec:"required"`
+	ShuffleHash        common.Hash   `json:"shuffleHash"      gencodec:"required"`
+	ShuffleBlockNumber *big.Int      `json:"shuffleBlockNumber"        gencodec:"required"`
+	Checkpoint         []common.Hash `json:"checkpoint,omitempty" rlp:"tail"` // Delegate set root in epoch checkpoint blocks, empty (and not encoded) otherwise
 }
 
 // field type overrides for gencodec
@@ -242,6 +243,9 @@ func CopyHeader(h *Header) *Header {
 		cpy.Extra = make([]byte, len(h.Extra))
 		copy(cpy.Extra, h.Extra)
 	}
+	if len(h.Checkpoint) > 0 {
+		cpy.Checkpoint = append([]common.Hash(nil), h.Checkpoint...)
+	}
 	return &cpy
 }
 
@@ -346,6 +350,16 @@ func (b *Block) WithSeal(header *Header) *Block {
 	}
 }
 
+// WithSignature returns a new block with the data from b signed by the given
+// producer signature.
+func (b *Block) WithSignature(sig []byte) *Block {
+	return &Block{
+		header:       b.header,
+		transactions: b.transactions,
+		Signature:    common.CopyBytes(sig),
+	}
+}
+
 // WithBody returns a new block with the given transaction and uncle contents.
 func (b *Block) WithBody(transactions []*Transaction) *Block {
 	block := &Block{
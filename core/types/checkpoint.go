@@ -0,0 +1,63 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/rlp"
+)
+
+// DelegateSet is the ranked list of delegates a block producing round was
+// shuffled from, committed to by the checkpoint headers.
+type DelegateSet []common.Address
+
+// NewDelegateSet creates the delegate set of the top ranked candidates, at most
+// maxElectDelegate of them.
+func NewDelegateSet(candidates []Candidate, maxElectDelegate int) DelegateSet {
+	if len(candidates) > maxElectDelegate {
+		candidates = candidates[:maxElectDelegate]
+	}
+	set := make(DelegateSet, len(candidates))
+	for i, candidate := range candidates {
+		set[i] = common.HexToAddress(candidate.Address)
+	}
+	return set
+}
+
+// Len returns the number of delegates in the set.
+func (s DelegateSet) Len() int { return len(s) }
+
+// GetRlp returns the RLP encoding of the delegate at the given rank.
+func (s DelegateSet) GetRlp(i int) []byte {
+	enc, _ := rlp.EncodeToBytes(s[i])
+	return enc
+}
+
+// Root returns the trie root of the delegate set, allowing to prove the rank of
+// a single delegate against a checkpoint header.
+func (s DelegateSet) Root() common.Hash {
+	return DeriveSha(s)
+}
+
+// CheckpointRoot returns the delegate set root committed to by the header, ok is
+// false if the header is no checkpoint.
+func (h *Header) CheckpointRoot() (root common.Hash, ok bool) {
+	if len(h.Checkpoint) == 0 {
+		return common.Hash{}, false
+	}
+	return h.Checkpoint[0], true
+}
@@ -21,12 +21,6 @@ import (
 	"math/big"
 )
 
-const (
-	// MaxElectDelegate = 101
-	BlockInterval = 10
-	// DelegateAmount   = (MaxElectDelegate / 3) * 2
-)
-
 const DelegatePrefix = "dacchain-delegates"
 
 type ShuffleDel struct {
@@ -0,0 +1,139 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// ExtraVersion1 is the first version of the structured header extra-data. Its
+// layout is, with all integers big endian:
+//
+//	version (1 byte) | producer version (3 bytes) | shuffle epoch (4 bytes) | vanity (0-8 bytes)
+const ExtraVersion1 = 1
+
+const (
+	extraV1FixedSize = 8 // Size of the fixed fields of a version 1 extra-data
+	extraV1MaxVanity = 8 // Maximum vanity length fitting into params.MaximumExtraDataSize
+)
+
+var (
+	// ErrExtraTooShort is returned if the extra-data is shorter than the fixed
+	// fields of its version.
+	ErrExtraTooShort = errors.New("extra-data too short")
+
+	// ErrExtraVanityTooLong is returned if the vanity doesn't fit into the
+	// extra-data of a header.
+	ErrExtraVanityTooLong = errors.New("extra-data vanity too long")
+
+	// ErrExtraProducerVersion is returned if the producer version doesn't fit
+	// into its three bytes.
+	ErrExtraProducerVersion = errors.New("extra-data producer version out of range")
+
+	// ErrExtraShuffleEpoch is returned if the shuffle epoch doesn't fit into its
+	// four bytes.
+	ErrExtraShuffleEpoch = errors.New("extra-data shuffle epoch out of range")
+)
+
+// HeaderExtra is the parsed content of the structured extra-data of a header.
+// An empty extra-data carries no fields and decodes into a nil HeaderExtra.
+type HeaderExtra struct {
+	Version         uint8  // Layout version of the extra-data
+	ProducerVersion uint32 // Client version of the producer, major<<16 | minor<<8 | patch
+	ShuffleEpoch    uint64 // Block number of the shuffle round the block was produced in
+	Vanity          []byte // Free-form producer vanity
+}
+
+// NewHeaderExtra creates a version 1 extra-data of the running client for a
+// block produced in the given shuffle round.
+func NewHeaderExtra(shuffleEpoch uint64, vanity []byte) *HeaderExtra {
+	return &HeaderExtra{
+		Version:         ExtraVersion1,
+		ProducerVersion: params.VersionMajor<<16 | params.VersionMinor<<8 | params.VersionPatch,
+		ShuffleEpoch:    shuffleEpoch,
+		Vanity:          vanity,
+	}
+}
+
+// Validate checks that all fields of the extra-data are representable in the
+// encoding of its version.
+func (e *HeaderExtra) Validate() error {
+	if e.Version != ExtraVersion1 {
+		return fmt.Errorf("unsupported extra-data version %d", e.Version)
+	}
+	if e.ProducerVersion >= 1<<24 {
+		return ErrExtraProducerVersion
+	}
+	if e.ShuffleEpoch > math.MaxUint32 {
+		return ErrExtraShuffleEpoch
+	}
+	if len(e.Vanity) > extraV1MaxVanity {
+		return ErrExtraVanityTooLong
+	}
+	return nil
+}
+
+// EncodeHeaderExtra validates and serializes the extra-data into its binary
+// header representation.
+func EncodeHeaderExtra(e *HeaderExtra) ([]byte, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	enc := make([]byte, extraV1FixedSize+len(e.Vanity))
+	binary.BigEndian.PutUint32(enc[0:4], e.ProducerVersion)
+	enc[0] = e.Version
+	binary.BigEndian.PutUint32(enc[4:8], uint32(e.ShuffleEpoch))
+	copy(enc[extraV1FixedSize:], e.Vanity)
+
+	return enc, nil
+}
+
+// DecodeHeaderExtra parses the binary extra-data of a header, rejecting unknown
+// versions and malformed content. An empty input decodes into nil.
+func DecodeHeaderExtra(extra []byte) (*HeaderExtra, error) {
+	if len(extra) == 0 {
+		return nil, nil
+	}
+	if uint64(len(extra)) > params.MaximumExtraDataSize {
+		return nil, fmt.Errorf("extra-data too long: %d > %d", len(extra), params.MaximumExtraDataSize)
+	}
+	if extra[0] != ExtraVersion1 {
+		return nil, fmt.Errorf("unsupported extra-data version %d", extra[0])
+	}
+	if len(extra) < extraV1FixedSize {
+		return nil, ErrExtraTooShort
+	}
+	e := &HeaderExtra{
+		Version:         extra[0],
+		ProducerVersion: binary.BigEndian.Uint32(extra[0:4]) & 0xffffff,
+		ShuffleEpoch:    uint64(binary.BigEndian.Uint32(extra[4:8])),
+	}
+	if len(extra) > extraV1FixedSize {
+		e.Vanity = append([]byte{}, extra[extraV1FixedSize:]...)
+	}
+	return e, nil
+}
+
+// DecodeExtra parses the structured extra-data of the header.
+func (h *Header) DecodeExtra() (*HeaderExtra, error) {
+	return DecodeHeaderExtra(h.Extra)
+}
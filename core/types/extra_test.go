@@ -0,0 +1,71 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+)
+
+func TestHeaderExtraEncoding(t *testing.T) {
+	extra := &HeaderExtra{Version: ExtraVersion1, ProducerVersion: 0x010203, ShuffleEpoch: 4000, Vanity: []byte("aoa")}
+	enc, err := EncodeHeaderExtra(extra)
+	if err != nil {
+		t.Fatalf("failed to encode extra-data: %v", err)
+	}
+	if want := common.FromHex("0x0101020300000fa0616f61"); !bytes.Equal(enc, want) {
+		t.Fatalf("encoding mismatch: have %x, want %x", enc, want)
+	}
+	dec, err := DecodeHeaderExtra(enc)
+	if err != nil {
+		t.Fatalf("failed to decode extra-data: %v", err)
+	}
+	if !reflect.DeepEqual(dec, extra) {
+		t.Fatalf("decoded extra-data mismatch: have %+v, want %+v", dec, extra)
+	}
+	if dec, err := DecodeHeaderExtra(nil); dec != nil || err != nil {
+		t.Fatalf("empty extra-data: have %v/%v, want nil/nil", dec, err)
+	}
+}
+
+func TestHeaderExtraValidation(t *testing.T) {
+	invalid := []*HeaderExtra{
+		{Version: 2},
+		{Version: ExtraVersion1, ProducerVersion: 1 << 24},
+		{Version: ExtraVersion1, ShuffleEpoch: 1 << 32},
+		{Version: ExtraVersion1, Vanity: make([]byte, extraV1MaxVanity+1)},
+	}
+	for i, extra := range invalid {
+		if _, err := EncodeHeaderExtra(extra); err == nil {
+			t.Errorf("test %d: invalid extra-data encoded", i)
+		}
+	}
+	malformed := [][]byte{
+		common.FromHex("0x02000000000000000000"), // unknown version
+		common.FromHex("0x01000000"),             // truncated fixed fields
+		make([]byte, 17),                         // oversized
+		[]byte("EM genesis"),                     // legacy free-form bytes
+	}
+	for i, extra := range malformed {
+		if _, err := DecodeHeaderExtra(extra); err == nil {
+			t.Errorf("test %d: malformed extra-data %x decoded", i, extra)
+		}
+	}
+}
@@ -31,6 +31,7 @@ func (h Header) MarshalJSON() ([]byte, error) {
 		DelegateRoot       common.Hash    `json:"delegateRoot"     gencodec:"required"`
 		ShuffleHash        common.Hash    `json:"shuffleHash"      gencodec:"required"`
 		ShuffleBlockNumber *big.Int       `json:"shuffleBlockNumber"        gencodec:"required"`
+		Checkpoint         []common.Hash  `json:"checkpoint,omitempty" rlp:"tail"`
 		Hash               common.Hash    `json:"hash"`
 	}
 	var enc Header
@@ -49,6 +50,7 @@ func (h Header) MarshalJSON() ([]byte, error) {
 	enc.DelegateRoot = h.DelegateRoot
 	enc.ShuffleHash = h.ShuffleHash
 	enc.ShuffleBlockNumber = h.ShuffleBlockNumber
+	enc.Checkpoint = h.Checkpoint
 	enc.Hash = h.Hash()
 	return json.Marshal(&enc)
 }
@@ -71,6 +73,7 @@ func (h *Header) UnmarshalJSON(input []byte) error {
 		DelegateRoot       *common.Hash    `json:"delegateRoot"     gencodec:"required"`
 		ShuffleHash        *common.Hash    `json:"shuffleHash"      gencodec:"required"`
 		ShuffleBlockNumber *big.Int        `json:"shuffleBlockNumber"        gencodec:"required"`
+		Checkpoint         []common.Hash   `json:"checkpoint,omitempty" rlp:"tail"`
 	}
 	var dec Header
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -136,5 +139,8 @@ func (h *Header) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'shuffleBlockNumber' for Header")
 	}
 	h.ShuffleBlockNumber = dec.ShuffleBlockNumber
+	if dec.Checkpoint != nil {
+		h.Checkpoint = dec.Checkpoint
+	}
 	return nil
 }
@@ -35,6 +35,7 @@ import (
 )
 
 //go:generate gencodec -type txdata -field-override txdataMarshaling -out gen_tx_json.go
+//go:generate gencodec -type FeePayer -field-override feePayerMarshaling -out gen_feepayer_json.go
 
 const (
 	ActionTrans = iota
@@ -54,8 +55,10 @@ const (
 )
 
 var (
-	ErrInvalidSig = errors.New("invalid transaction v, r, s values")
-	errNoSigner   = errors.New("missing signing methods")
+	ErrInvalidSig      = errors.New("invalid transaction v, r, s values")
+	ErrMalleableSig    = errors.New("non-canonical high-s transaction signature")
+	ErrInvalidFeePayer = errors.New("invalid fee payer signature")
+	errNoSigner        = errors.New("missing signing methods")
 )
 
 // deriveSigner makes a *best* guess about which signer to use.
@@ -143,6 +146,29 @@ type txdata struct {
 
 	// This is only used when marshaling to JSON.
 	Hash *common.Hash `json:"hash" rlp:"-"`
+
+	// Sponsor paying the gas in place of the sender, at most one. It is appended
+	// to the encoding only when present, so unsponsored transactions are encoded
+	// the same as before fee payers were introduced.
+	FeePayer []*FeePayer `json:"feePayer,omitempty" rlp:"tail"`
+}
+
+// FeePayer is the sponsor of a transaction, paying its gas in place of the
+// sender. The sender commits to the address of the fee payer, which in turn
+// signs the transaction signed by the sender.
+type FeePayer struct {
+	Address common.Address `json:"address" gencodec:"required"`
+
+	// Signature values
+	V *big.Int `json:"v" gencodec:"required"`
+	R *big.Int `json:"r" gencodec:"required"`
+	S *big.Int `json:"s" gencodec:"required"`
+}
+
+type feePayerMarshaling struct {
+	V *hexutil.Big
+	R *hexutil.Big
+	S *hexutil.Big
 }
 
 type txdataMarshaling struct {
@@ -240,18 +266,18 @@ func (tx *Transaction) GetTransactionType() common.Address {
 }
 
 // Protected returns whether the transaction is protected from replay protection.
-//func (tx *Transaction) Protected() bool {
-//	return isProtectedV(tx.data.V)
-//}
-//
-//func isProtectedV(V *big.Int) bool {
-//	if V.BitLen() <= 8 {
-//		v := V.Uint64()
-//		return v != 27 && v != 28
-//	}
-//	// anything not 27 or 28 are considered unprotected
-//	return true
-//}
+func (tx *Transaction) Protected() bool {
+	return isProtectedV(tx.data.V)
+}
+
+func isProtectedV(V *big.Int) bool {
+	if V.BitLen() <= 8 {
+		v := V.Uint64()
+		return v != 27 && v != 28
+	}
+	// anything not 27 or 28 are considered unprotected
+	return true
+}
 
 // EncodeRLP implements rlp.Encoder
 func (tx *Transaction) EncodeRLP(w io.Writer) error {
@@ -336,6 +362,41 @@ func (tx *Transaction) AssetInfo() *AssetInfo {
 }
 func (tx *Transaction) Abi() string { return tx.data.Abi }
 
+// FeePayer returns the address of the account sponsoring the gas of the
+// transaction, or nil if the sender pays it.
+func (tx *Transaction) FeePayer() *common.Address {
+	if len(tx.data.FeePayer) == 0 {
+		return nil
+	}
+	payer := tx.data.FeePayer[0].Address
+	return &payer
+}
+
+// WithFeePayer returns a new unsigned transaction whose gas is paid by the
+// given account. Both the sender and the fee payer have to sign it afterwards,
+// in this order.
+func (tx *Transaction) WithFeePayer(payer common.Address) *Transaction {
+	cpy := &Transaction{data: tx.data}
+	cpy.data.V, cpy.data.R, cpy.data.S = new(big.Int), new(big.Int), new(big.Int)
+	cpy.data.FeePayer = []*FeePayer{{Address: payer, V: new(big.Int), R: new(big.Int), S: new(big.Int)}}
+	return cpy
+}
+
+// WithFeePayerSignature returns a new transaction with the given fee payer
+// signature, in the [R || S || V] format where V is 0 or 1.
+func (tx *Transaction) WithFeePayerSignature(signer Signer, sig []byte) (*Transaction, error) {
+	if len(tx.data.FeePayer) == 0 {
+		return nil, errors.New("transaction has no fee payer")
+	}
+	r, s, v, err := signer.SignatureValues(tx, sig)
+	if err != nil {
+		return nil, err
+	}
+	cpy := &Transaction{data: tx.data}
+	cpy.data.FeePayer = []*FeePayer{{Address: tx.data.FeePayer[0].Address, V: v, R: r, S: s}}
+	return cpy, nil
+}
+
 // To returns the recipient address of the transaction.
 // It returns nil if the transaction is a contract creation.
 func (tx *Transaction) To() *common.Address {
@@ -414,7 +475,15 @@ func (tx *Transaction) AsMessage(s Signer) (Message, error) {
 	//now := time.Now()
 	msg.from, err = Sender(s, tx)
 	//log.Info("AsMessage", "time", time.Now().Sub(now))
-	return msg, err
+	if err != nil || len(tx.data.FeePayer) == 0 {
+		return msg, err
+	}
+	payer, err := s.FeePayer(tx)
+	if err != nil {
+		return msg, err
+	}
+	msg.feePayer = &payer
+	return msg, nil
 }
 
 // WithSignature returns a new transaction with the given signature.
@@ -430,10 +499,14 @@ func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, e
 	return cpy, nil
 }
 
-// EmCost returns em required.
+// EmCost returns em required from the sender, which doesn't include the gas
+// if a fee payer sponsors it.
 func (tx *Transaction) EmCost() *big.Int {
 	log.Info("Transaction|EmCost,", "transaction", tx.data)
-	total := new(big.Int).Mul(tx.data.Price, new(big.Int).SetUint64(tx.data.GasLimit))
+	total := new(big.Int)
+	if len(tx.data.FeePayer) == 0 {
+		total.Mul(tx.data.Price, new(big.Int).SetUint64(tx.data.GasLimit))
+	}
 	// agent register cost
 	switch tx.data.Action {
 	case ActionTrans:
@@ -719,6 +792,8 @@ func NewTransactionsByPriceAndNonce(signer Signer, txs map[common.Address]Transa
 	}
 }
 
+// NewTransactionsByPriceAndNonce2 creates a transaction set out of a flat list
+// of transactions from any number of accounts, retrieving them price sorted.
 func NewTransactionsByPriceAndNonce2(signer Signer, price TxByPrice) *TransactionsByPriceAndNonce {
 	txByNonce := new(TxByNonce)
 	*txByNonce = append(*txByNonce, price...)
@@ -779,6 +854,7 @@ type Message struct {
 	assetInfo  *AssetInfo
 	subAddress string
 	abi        string
+	feePayer   *common.Address
 }
 
 func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, checkNonce bool, action uint64, vote []Vote, asset *common.Address, assetInfo *AssetInfo, subAddress string, abi string) Message {
@@ -819,3 +895,7 @@ func (m Message) AssetInfo() AssetInfo {
 	return AssetInfo{}
 }
 func (m Message) Abi() string { return m.abi }
+
+// FeePayer returns the account paying the gas of the message, or nil if the
+// sender pays it.
+func (m Message) FeePayer() *common.Address { return m.feePayer }
@@ -0,0 +1,64 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+)
+
+func TestReplayProtection(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	tx := NewTransaction(0, common.HexToAddress("0x01"), big.NewInt(1), 21000, big.NewInt(1), nil, ActionTrans, nil, "")
+	signer := NewAuroraSigner(big.NewInt(60))
+
+	// Protected transactions recover only on their own chain
+	protected, err := SignTx(tx, signer, key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if !protected.Protected() || protected.ChainId().Cmp(big.NewInt(60)) != 0 {
+		t.Fatalf("protection mismatch: protected %v, chain id %v", protected.Protected(), protected.ChainId())
+	}
+	if from, err := Sender(signer, protected); err != nil || from != addr {
+		t.Fatalf("sender mismatch: have %x/%v, want %x/nil", from, err, addr)
+	}
+	if _, err := Sender(NewAuroraSigner(big.NewInt(61)), protected); err != ErrInvalidChainId {
+		t.Fatalf("cross chain error mismatch: have %v, want %v", err, ErrInvalidChainId)
+	}
+	// Unprotected transactions are signed without a chain id
+	sig, err := crypto.Sign(rlpHash(sigFields(tx)).Bytes(), key)
+	if err != nil {
+		t.Fatalf("failed to sign hash: %v", err)
+	}
+	unprotected, err := tx.WithSignature(NewAuroraSigner(new(big.Int)), sig)
+	if err != nil {
+		t.Fatalf("failed to attach signature: %v", err)
+	}
+	if unprotected.Protected() || unprotected.ChainId().Sign() != 0 {
+		t.Fatalf("protection mismatch: protected %v, chain id %v", unprotected.Protected(), unprotected.ChainId())
+	}
+	// Unprotected transactions are not valid on any chain
+	if _, err := Sender(signer, unprotected); err != ErrInvalidChainId {
+		t.Fatalf("unprotected error mismatch: have %v, want %v", err, ErrInvalidChainId)
+	}
+}
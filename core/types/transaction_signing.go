@@ -57,6 +57,17 @@ func SignTx(tx *Transaction, s Signer, prv *ecdsa.PrivateKey) (*Transaction, err
 	return tx.WithSignature(s, sig)
 }
 
+// SignFeePayer signs the transaction as its fee payer using the given signer
+// and private key. The transaction must already be signed by its sender.
+func SignFeePayer(tx *Transaction, s Signer, prv *ecdsa.PrivateKey) (*Transaction, error) {
+	h := s.FeePayerHash(tx)
+	sig, err := crypto.Sign(h[:], prv)
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithFeePayerSignature(s, sig)
+}
+
 // Sender returns the address derived from the signature (V, R, S) using secp256k1
 // elliptic curve and an error if it failed deriving or upon an incorrect
 // signature.
@@ -93,6 +104,11 @@ type Signer interface {
 	SignatureValues(tx *Transaction, sig []byte) (r, s, v *big.Int, err error)
 	// Hash returns the hash to be signed.
 	Hash(tx *Transaction) common.Hash
+	// FeePayer returns the address of the fee payer of the transaction after
+	// verifying it signed the transaction.
+	FeePayer(tx *Transaction) (common.Address, error)
+	// FeePayerHash returns the hash to be signed by the fee payer.
+	FeePayerHash(tx *Transaction) common.Hash
 	// Equal returns true if the given signer is the same as the receiver.
 	Equal(Signer) bool
 }
@@ -131,7 +147,46 @@ func (s AuroraSigner) Sender(tx *Transaction) (common.Address, error) {
 // Hash returns the hash to be signed by the sender.
 // It does not uniquely identify the transaction.
 func (s AuroraSigner) Hash(tx *Transaction) common.Hash {
+	return rlpHash(append(sigFields(tx), s.chainId, uint(0), uint(0)))
+}
+
+// FeePayer returns the address of the fee payer of the transaction, verifying
+// it is the one which signed it.
+func (s AuroraSigner) FeePayer(tx *Transaction) (common.Address, error) {
+	if len(tx.data.FeePayer) != 1 {
+		return common.Address{}, ErrInvalidFeePayer
+	}
+	payer := tx.data.FeePayer[0]
+	if deriveChainId(payer.V).Cmp(s.chainId) != 0 {
+		return common.Address{}, ErrInvalidChainId
+	}
+	V := new(big.Int).Sub(payer.V, s.chainIdMul)
+	V.Sub(V, big8)
+	addr, err := recoverPlain(s.FeePayerHash(tx), payer.R, payer.S, V, true)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if addr != payer.Address {
+		return common.Address{}, ErrInvalidFeePayer
+	}
+	return addr, nil
+}
+
+// FeePayerHash returns the hash to be signed by the fee payer. It covers the
+// signature of the sender, so a fee payer only sponsors the transaction it was
+// presented with.
+func (s AuroraSigner) FeePayerHash(tx *Transaction) common.Hash {
 	return rlpHash([]interface{}{
+		"feePayer",
+		s.Hash(tx),
+		tx.data.V, tx.data.R, tx.data.S,
+	})
+}
+
+// sigFields returns the transaction fields signed by the sender. The fee payer
+// is only included when present, not to change the hash of other transactions.
+func sigFields(tx *Transaction) []interface{} {
+	fields := []interface{}{
 		tx.data.AccountNonce,
 		tx.data.Price,
 		tx.data.GasLimit,
@@ -145,8 +200,11 @@ func (s AuroraSigner) Hash(tx *Transaction) common.Hash {
 		tx.data.AssetInfo,
 		tx.data.SubAddress,
 		tx.data.Abi,
-		s.chainId, uint(0), uint(0),
-	})
+	}
+	if len(tx.data.FeePayer) > 0 {
+		fields = append(fields, tx.data.FeePayer[0].Address)
+	}
+	return fields
 }
 
 // WithSignature returns a new transaction with the given signature. This signature
@@ -183,6 +241,10 @@ func recoverPlain(sighash common.Hash, R, S, Vb *big.Int, homestead bool) (commo
 	}
 	V := byte(Vb.Uint64() - 27)
 	if !crypto.ValidateSignatureValues(V, R, S, homestead) {
+		// Tell malleated variants of otherwise valid signatures apart
+		if homestead && crypto.ValidateSignatureValues(V, R, S, false) {
+			return common.Address{}, ErrMalleableSig
+		}
 		log.Error("recoverPlain2| err", "V", Vb.Uint64()-27)
 		return common.Address{}, ErrInvalidSig
 	}
@@ -209,9 +271,9 @@ func recoverPlain(sighash common.Hash, R, S, Vb *big.Int, homestead bool) (commo
 func deriveChainId(v *big.Int) *big.Int {
 	if v.BitLen() <= 64 {
 		v := v.Uint64()
-		//if v == 27 || v == 28 {
-		//	return new(big.Int)
-		//}
+		if v == 27 || v == 28 {
+			return new(big.Int)
+		}
 		return new(big.Int).SetUint64((v - 35) / 2)
 	}
 	v = new(big.Int).Sub(v, big.NewInt(35))
@@ -0,0 +1,95 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+const (
+	// UtilizationBuckets is the number of equally sized buckets (10% each) the
+	// gas limit utilization of a block is sorted into.
+	UtilizationBuckets = 10
+
+	// GasUsedBuckets is the number of power-of-two buckets, measured in units of
+	// params.TxGas, the gas used by a block is sorted into. The last bucket
+	// collects everything above.
+	GasUsedBuckets = 16
+
+	// TxCountBuckets is the number of power-of-two buckets the transaction count
+	// of a block is sorted into. Bucket 0 holds empty blocks, bucket i holds
+	// blocks with [2^(i-1), 2^i) transactions and the last bucket everything above.
+	TxCountBuckets = 16
+)
+
+// EpochUtilization is a compact summary of the gas usage and transaction load of
+// all canonical blocks within a single delegate epoch of the chain.
+type EpochUtilization struct {
+	Epoch   uint64 // Index of the epoch the summary belongs to
+	Blocks  uint64 // Number of blocks aggregated so far
+	GasUsed uint64 // Total gas used by the aggregated blocks
+	TxCount uint64 // Total transaction count of the aggregated blocks
+	MaxGas  uint64 // Highest gas used by a single block
+	MaxTxs  uint64 // Highest transaction count of a single block
+
+	GasUsedHist     []uint64 // Histogram of per-block gas used
+	TxCountHist     []uint64 // Histogram of per-block transaction counts
+	UtilizationHist []uint64 // Histogram of per-block gas used / gas limit
+}
+
+// NewEpochUtilization creates an empty utilization summary for the given epoch.
+func NewEpochUtilization(epoch uint64) *EpochUtilization {
+	return &EpochUtilization{
+		Epoch:           epoch,
+		GasUsedHist:     make([]uint64, GasUsedBuckets),
+		TxCountHist:     make([]uint64, TxCountBuckets),
+		UtilizationHist: make([]uint64, UtilizationBuckets),
+	}
+}
+
+// Add accumulates the statistics of a single block into the summary.
+func (u *EpochUtilization) Add(gasUsed, gasLimit uint64, txs int) {
+	u.Blocks++
+	u.GasUsed += gasUsed
+	u.TxCount += uint64(txs)
+	if gasUsed > u.MaxGas {
+		u.MaxGas = gasUsed
+	}
+	if uint64(txs) > u.MaxTxs {
+		u.MaxTxs = uint64(txs)
+	}
+	u.GasUsedHist[log2Bucket(gasUsed/params.TxGas, GasUsedBuckets)]++
+	u.TxCountHist[log2Bucket(uint64(txs), TxCountBuckets)]++
+
+	bucket := 0
+	if gasLimit > 0 {
+		bucket = int(gasUsed * UtilizationBuckets / gasLimit)
+	}
+	if bucket >= UtilizationBuckets {
+		bucket = UtilizationBuckets - 1
+	}
+	u.UtilizationHist[bucket]++
+}
+
+// log2Bucket returns the power-of-two bucket index of v, capped at size-1.
+func log2Bucket(v uint64, size int) int {
+	bucket := 0
+	for ; v > 0 && bucket < size-1; v >>= 1 {
+		bucket++
+	}
+	return bucket
+}
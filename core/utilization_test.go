@@ -0,0 +1,65 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// Tests that block statistics are sorted into the correct histogram buckets.
+func TestEpochUtilizationAdd(t *testing.T) {
+	util := NewEpochUtilization(3)
+
+	util.Add(0, 1000, 0)
+	util.Add(params.TxGas, 1000*params.TxGas, 1)
+	util.Add(5*params.TxGas, 10*params.TxGas, 5)
+	util.Add(20*params.TxGas, 10*params.TxGas, 1<<20)
+
+	if util.Blocks != 4 || util.TxCount != 1+5+1<<20 || util.GasUsed != 26*params.TxGas {
+		t.Fatalf("totals mismatch: blocks %d, txs %d, gas %d", util.Blocks, util.TxCount, util.GasUsed)
+	}
+	if util.MaxGas != 20*params.TxGas || util.MaxTxs != 1<<20 {
+		t.Fatalf("maximums mismatch: gas %d, txs %d", util.MaxGas, util.MaxTxs)
+	}
+	if want := []uint64{2, 0, 0, 0, 0, 1, 0, 0, 0, 1}; !reflect.DeepEqual(util.UtilizationHist, want) {
+		t.Errorf("utilization histogram mismatch: have %v, want %v", util.UtilizationHist, want)
+	}
+	if util.TxCountHist[0] != 1 || util.TxCountHist[1] != 1 || util.TxCountHist[3] != 1 || util.TxCountHist[TxCountBuckets-1] != 1 {
+		t.Errorf("tx count histogram mismatch: %v", util.TxCountHist)
+	}
+}
+
+// Tests that epoch summaries survive a database round-trip.
+func TestEpochUtilizationStorage(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+
+	if util := GetEpochUtilization(db, 7); util != nil {
+		t.Fatalf("non existent epoch returned: %v", util)
+	}
+	util := NewEpochUtilization(7)
+	util.Add(params.TxGas, 2*params.TxGas, 1)
+	if err := WriteEpochUtilization(db, util); err != nil {
+		t.Fatalf("failed to write epoch utilization: %v", err)
+	}
+	if stored := GetEpochUtilization(db, 7); !reflect.DeepEqual(stored, util) {
+		t.Fatalf("stored epoch mismatch: have %v, want %v", stored, util)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+)
+
+// AccessListTracer is a Tracer recording the accounts and storage slots touched
+// by an execution. The sender and recipient of the message, as well as the
+// pre-compiled contracts, are left out unless their storage is accessed, as
+// they're always accessed themselves.
+type AccessListTracer struct {
+	excluded map[common.Address]struct{}                 // Accounts left out of the list
+	accessed map[common.Address]map[common.Hash]struct{} // Accounts and storage slots touched
+}
+
+// NewAccessListTracer creates a tracer recording the accesses of an execution.
+func NewAccessListTracer() *AccessListTracer {
+	excluded := make(map[common.Address]struct{})
+	for _, addr := range precompiledAddresses() {
+		excluded[addr] = struct{}{}
+	}
+	return &AccessListTracer{
+		excluded: excluded,
+		accessed: make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+// addAddress records an account as touched, unless it's excluded.
+func (a *AccessListTracer) addAddress(addr common.Address) {
+	if _, ok := a.excluded[addr]; ok {
+		return
+	}
+	if _, ok := a.accessed[addr]; !ok {
+		a.accessed[addr] = make(map[common.Hash]struct{})
+	}
+}
+
+// addSlot records a storage slot of an account as touched. The slots of excluded
+// accounts are recorded too, as only the account itself is always accessed.
+func (a *AccessListTracer) addSlot(addr common.Address, slot common.Hash) {
+	if _, ok := a.accessed[addr]; !ok {
+		a.accessed[addr] = make(map[common.Hash]struct{})
+	}
+	a.accessed[addr][slot] = struct{}{}
+}
+
+// CaptureStart excludes the sender and recipient of the message from the list.
+func (a *AccessListTracer) CaptureStart(from common.Address, to common.Address, call bool, input []byte, gas uint64, value *big.Int) error {
+	a.excluded[from] = struct{}{}
+	a.excluded[to] = struct{}{}
+	delete(a.accessed, from)
+	delete(a.accessed, to)
+	return nil
+}
+
+// CaptureState records the accounts and storage slots the opcode about to be
+// executed accesses.
+func (a *AccessListTracer) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	switch {
+	case (op == SLOAD || op == SSTORE) && stack.len() >= 1:
+		a.addSlot(contract.Address(), common.BigToHash(stack.Back(0)))
+
+	case (op == EXTCODECOPY || op == EXTCODESIZE || op == BALANCE || op == SELFDESTRUCT) && stack.len() >= 1:
+		a.addAddress(common.BigToAddress(stack.Back(0)))
+
+	case (op == CALL || op == CALLCODE || op == DELEGATECALL || op == STATICCALL) && stack.len() >= 2:
+		a.addAddress(common.BigToAddress(stack.Back(1)))
+	}
+	return nil
+}
+
+// CaptureFault implements Tracer, doing nothing.
+func (a *AccessListTracer) CaptureFault(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements Tracer, doing nothing.
+func (a *AccessListTracer) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
+	return nil
+}
+
+// CaptureEnter implements Tracer, doing nothing.
+func (a *AccessListTracer) CaptureEnter(typ OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureExit implements Tracer, doing nothing.
+func (a *AccessListTracer) CaptureExit(output []byte, gasUsed uint64, err error) error {
+	return nil
+}
+
+// AccessList returns the accounts and storage slots touched, sorted so that the
+// list is deterministic.
+func (a *AccessListTracer) AccessList() types.AccessList {
+	list := make(types.AccessList, 0, len(a.accessed))
+	for addr, slots := range a.accessed {
+		tuple := types.AccessTuple{Address: addr, StorageKeys: make([]common.Hash, 0, len(slots))}
+		for slot := range slots {
+			tuple.StorageKeys = append(tuple.StorageKeys, slot)
+		}
+		sort.Slice(tuple.StorageKeys, func(i, j int) bool {
+			return bytes.Compare(tuple.StorageKeys[i][:], tuple.StorageKeys[j][:]) < 0
+		})
+		list = append(list, tuple)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return bytes.Compare(list[i].Address[:], list[j].Address[:]) < 0
+	})
+	return list
+}
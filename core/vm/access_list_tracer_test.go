@@ -0,0 +1,72 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+)
+
+func TestAccessListTracer(t *testing.T) {
+	var (
+		tracer   = NewAccessListTracer()
+		mem      = NewMemory()
+		contract = NewContract(&dummyContractRef{}, &dummyContractRef{}, nil, new(big.Int), 0)
+
+		sender    = common.HexToAddress("0x0100")
+		recipient = common.HexToAddress("0x0200")
+		other     = common.HexToAddress("0x0300")
+	)
+	tracer.CaptureStart(sender, recipient, false, nil, 0, new(big.Int))
+
+	capture := func(op OpCode, args ...*big.Int) {
+		stack := newstack()
+		for i := len(args) - 1; i >= 0; i-- {
+			stack.push(args[i])
+		}
+		tracer.CaptureState(nil, 0, op, 0, 0, mem, stack, contract, 0, nil)
+	}
+	capture(SLOAD, big.NewInt(2))
+	capture(SSTORE, big.NewInt(1), big.NewInt(5))
+	capture(SLOAD, big.NewInt(2))
+	capture(BALANCE, other.Big())
+	capture(BALANCE, sender.Big())
+	capture(CALL, big.NewInt(0), recipient.Big(), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0))
+	capture(STATICCALL, big.NewInt(0), common.BytesToAddress([]byte{1}).Big(), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0))
+
+	list := tracer.AccessList()
+	if len(list) != 2 || list.StorageKeys() != 2 {
+		t.Fatalf("access list size mismatch: have %d accounts, %d slots, want 2 accounts, 2 slots: %v", len(list), list.StorageKeys(), list)
+	}
+	if list[0].Address != contract.Address() || list[0].StorageKeys[0] != common.BigToHash(big.NewInt(1)) || list[0].StorageKeys[1] != common.BigToHash(big.NewInt(2)) {
+		t.Errorf("contract accesses mismatch: %v", list[0])
+	}
+	if list[1].Address != other || len(list[1].StorageKeys) != 0 {
+		t.Errorf("account accesses mismatch: %v", list[1])
+	}
+	// Storage of the excluded recipient must still be recorded
+	tracer = NewAccessListTracer()
+	tracer.CaptureStart(sender, contract.Address(), false, nil, 0, new(big.Int))
+	capture(SLOAD, big.NewInt(3))
+
+	list = tracer.AccessList()
+	if len(list) != 1 || list[0].Address != contract.Address() || len(list[0].StorageKeys) != 1 || list[0].StorageKeys[0] != common.BigToHash(big.NewInt(3)) {
+		t.Errorf("recipient accesses mismatch: %v", list)
+	}
+}
@@ -18,12 +18,14 @@ package vm
 
 import (
 	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"math/big"
 
 	"github.com/Aurorachain-io/go-aoa/common"
 	"github.com/Aurorachain-io/go-aoa/common/math"
 	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/crypto/blake2b"
 	"github.com/Aurorachain-io/go-aoa/crypto/bn256"
 	"github.com/Aurorachain-io/go-aoa/params"
 	"golang.org/x/crypto/ripemd160"
@@ -50,6 +52,101 @@ var PrecompiledContracts = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{8}): &bn256Pairing{},
 }
 
+// PrecompiledContractsAres contains the pre-compiled contracts added by the
+// ares fork.
+var PrecompiledContractsAres = map[common.Address]PrecompiledContract{
+	common.BytesToAddress([]byte{9}): &blake2F{},
+}
+
+// PrecompiledContractsAthena contains the pre-compiled contracts repriced by
+// the athena fork.
+var PrecompiledContractsAthena = map[common.Address]PrecompiledContract{
+	common.BytesToAddress([]byte{5}): &bigModExp{repriced: true},
+}
+
+// StatefulPrecompiledContract is a native Go contract which, unlike the plain
+// pre-compiled contracts, reads the state of the executing EVM, e.g. to expose
+// delegate information to contracts.
+type StatefulPrecompiledContract interface {
+	PrecompiledContract
+
+	// RunStateful runs the contract on behalf of the caller in the given EVM.
+	RunStateful(evm *EVM, caller common.Address, input []byte) ([]byte, error)
+}
+
+// precompileSet is a set of pre-compiled contracts activated by the chain rules,
+// replacing the contracts of earlier sets at the same addresses.
+type precompileSet struct {
+	active    func(params.Rules) bool
+	contracts map[common.Address]PrecompiledContract
+}
+
+// precompileRegistry lists pre-compiled contract sets in activation order.
+type precompileRegistry []precompileSet
+
+// precompiles is the registry of the pre-compiled contracts of the chain.
+var precompiles = precompileRegistry{
+	{func(params.Rules) bool { return true }, PrecompiledContracts},
+	{func(rules params.Rules) bool { return rules.IsAres }, PrecompiledContractsAres},
+	{func(rules params.Rules) bool { return rules.IsAthena }, PrecompiledContractsAthena},
+}
+
+// RegisterPrecompiles adds a set of chain specific pre-compiled contracts,
+// active under the chain rules accepted by active. Chain specific contracts are
+// placed from address 0x100 on, leaving the lower addresses to the standard
+// ones. It is meant to be called on package initialisation, before any EVM is
+// created.
+func RegisterPrecompiles(active func(params.Rules) bool, contracts map[common.Address]PrecompiledContract) {
+	precompiles.register(active, contracts)
+}
+
+// ActivePrecompiles returns the pre-compiled contracts active under the given
+// chain rules.
+func ActivePrecompiles(rules params.Rules) map[common.Address]PrecompiledContract {
+	return precompiles.active(rules)
+}
+
+// precompiledAddresses returns the addresses of all registered pre-compiled
+// contracts, whether active or not.
+func precompiledAddresses() []common.Address {
+	return precompiles.addresses()
+}
+
+// register appends a set of pre-compiled contracts to the registry.
+func (r *precompileRegistry) register(active func(params.Rules) bool, contracts map[common.Address]PrecompiledContract) {
+	*r = append(*r, precompileSet{active, contracts})
+}
+
+// active returns the pre-compiled contracts of the registry active under the
+// given chain rules.
+func (r precompileRegistry) active(rules params.Rules) map[common.Address]PrecompiledContract {
+	contracts := make(map[common.Address]PrecompiledContract)
+	for _, set := range r {
+		if set.active(rules) {
+			for addr, p := range set.contracts {
+				contracts[addr] = p
+			}
+		}
+	}
+	return contracts
+}
+
+// addresses returns the addresses of all pre-compiled contracts of the registry,
+// whether active or not.
+func (r precompileRegistry) addresses() []common.Address {
+	var addrs []common.Address
+	seen := make(map[common.Address]bool)
+	for _, set := range r {
+		for addr := range set.contracts {
+			if !seen[addr] {
+				seen[addr] = true
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	return addrs
+}
+
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
 func RunPrecompiledContract(p PrecompiledContract, input []byte, contract *Contract) (ret []byte, err error) {
 	gas := p.RequiredGas(input)
@@ -59,6 +156,20 @@ func RunPrecompiledContract(p PrecompiledContract, input []byte, contract *Contr
 	return nil, ErrOutOfGas
 }
 
+// runPrecompiledContract runs a precompiled contract in the given EVM, giving
+// stateful contracts access to it.
+func runPrecompiledContract(evm *EVM, p PrecompiledContract, input []byte, contract *Contract) (ret []byte, err error) {
+	sp, ok := p.(StatefulPrecompiledContract)
+	if !ok {
+		return RunPrecompiledContract(p, input, contract)
+	}
+	gas := sp.RequiredGas(input)
+	if contract.UseGas(gas) {
+		return sp.RunStateful(evm, contract.Caller(), input)
+	}
+	return nil, ErrOutOfGas
+}
+
 // ECRECOVER implemented as a native contract.
 type ecrecover struct{}
 
@@ -138,11 +249,14 @@ func (c *dataCopy) Run(in []byte) ([]byte, error) {
 }
 
 // bigModExp implements a native big integer exponential modular operation.
-type bigModExp struct{}
+type bigModExp struct {
+	repriced bool // Whether the cheaper, word based complexity of athena applies
+}
 
 var (
 	big1      = big.NewInt(1)
 	big4      = big.NewInt(4)
+	big7      = big.NewInt(7)
 	big8      = big.NewInt(8)
 	big16     = big.NewInt(16)
 	big32     = big.NewInt(32)
@@ -191,6 +305,23 @@ func (c *bigModExp) RequiredGas(input []byte) uint64 {
 
 	// Calculate the gas cost of the operation
 	gas := new(big.Int).Set(math.BigMax(modLen, baseLen))
+	if c.repriced {
+		// The complexity is the square of the number of 64 bit words, divided
+		// by a smaller divisor and bounded by a minimum price
+		gas.Add(gas, big7)
+		gas.Div(gas, big8)
+		gas.Mul(gas, gas)
+
+		gas.Mul(gas, math.BigMax(adjExpLen, big1))
+		gas.Div(gas, new(big.Int).SetUint64(params.ModExpAthenaQuadDiv))
+		if gas.BitLen() > 64 {
+			return math.MaxUint64
+		}
+		if gas.Uint64() < params.ModExpMinGas {
+			return params.ModExpMinGas
+		}
+		return gas.Uint64()
+	}
 	switch {
 	case gas.Cmp(big64) <= 0:
 		gas.Mul(gas, gas)
@@ -368,3 +499,60 @@ func (c *bn256Pairing) Run(input []byte) ([]byte, error) {
 	}
 	return false32Byte, nil
 }
+
+var (
+	errBlake2FInvalidInputLength = errors.New("invalid input length")
+	errBlake2FInvalidFinalFlag   = errors.New("invalid final flag")
+)
+
+const blake2FInputLength = 213
+
+// blake2F implements the BLAKE2b compression function F as a native contract.
+type blake2F struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract,
+// charged per round of the compression function.
+func (c *blake2F) RequiredGas(input []byte) uint64 {
+	if len(input) != blake2FInputLength {
+		return 0
+	}
+	return uint64(binary.BigEndian.Uint32(input[0:4])) * params.Blake2FRoundGas
+}
+
+func (c *blake2F) Run(input []byte) ([]byte, error) {
+	// The input is the number of rounds, the state, the message block, the
+	// offset counter and the final block flag
+	if len(input) != blake2FInputLength {
+		return nil, errBlake2FInvalidInputLength
+	}
+	if input[212] != 0 && input[212] != 1 {
+		return nil, errBlake2FInvalidFinalFlag
+	}
+	var (
+		rounds = binary.BigEndian.Uint32(input[0:4])
+		final  = input[212] == 1
+
+		h [8]uint64
+		m [16]uint64
+		t [2]uint64
+	)
+	for i := 0; i < 8; i++ {
+		offset := 4 + i*8
+		h[i] = binary.LittleEndian.Uint64(input[offset : offset+8])
+	}
+	for i := 0; i < 16; i++ {
+		offset := 68 + i*8
+		m[i] = binary.LittleEndian.Uint64(input[offset : offset+8])
+	}
+	t[0] = binary.LittleEndian.Uint64(input[196:204])
+	t[1] = binary.LittleEndian.Uint64(input[204:212])
+
+	blake2b.F(&h, m, t, final, rounds)
+
+	output := make([]byte, 64)
+	for i := 0; i < 8; i++ {
+		offset := i * 8
+		binary.LittleEndian.PutUint64(output[offset:offset+8], h[i])
+	}
+	return output, nil
+}
@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/params"
 )
 
 // precompiledTest defines the input/output pairs for precompiled contract tests.
@@ -336,8 +337,11 @@ var bn256PairingTests = []precompiledTest{
 	},
 }
 
+// testPrecompiles are the pre-compiled contracts under test, the ones of ares.
+var testPrecompiles = ActivePrecompiles(params.Rules{IsByzantium: true, IsAres: true})
+
 func testPrecompiled(addr string, test precompiledTest, t *testing.T) {
-	p := PrecompiledContracts[common.HexToAddress(addr)]
+	p := testPrecompiles[common.HexToAddress(addr)]
 	in := common.Hex2Bytes(test.input)
 	contract := NewContract(AccountRef(common.HexToAddress("1337")),
 		nil, nil, new(big.Int), p.RequiredGas(in))
@@ -354,7 +358,7 @@ func benchmarkPrecompiled(addr string, test precompiledTest, bench *testing.B) {
 	if test.noBenchmark {
 		return
 	}
-	p := PrecompiledContracts[common.HexToAddress(addr)]
+	p := testPrecompiles[common.HexToAddress(addr)]
 	in := common.Hex2Bytes(test.input)
 	reqGas := p.RequiredGas(in)
 	contract := NewContract(AccountRef(common.HexToAddress("1337")),
@@ -387,6 +391,24 @@ func benchmarkPrecompiled(addr string, test precompiledTest, bench *testing.B) {
 }
 
 // Benchmarks the sample inputs from the ECRECOVER precompile.
+// blake2FTests are the test and benchmark data for the blake2f precompiled
+// contract, compressing the single block of "abc".
+var blake2FTests = []precompiledTest{
+	{
+		input:    "0000000048c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b61626300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000001",
+		expected: "08c9bcf367e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d282e6ad7f520e511f6c3e2b8c68059b9442be0454267ce079217e1319cde05b",
+		name:     "vector 4",
+	}, {
+		input:    "0000000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b61626300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000001",
+		expected: "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923",
+		name:     "vector 5",
+	}, {
+		input:    "0000000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b61626300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000000",
+		expected: "75ab69d3190a562c51aef8d88f1c2775876944407270c42c9844252c26d2875298743e7f6d5ea2f2d3e8d226039cd31b4e426ac4f2d3d666a610c2116fde4735",
+		name:     "vector 6",
+	},
+}
+
 func BenchmarkPrecompiledEcrecover(bench *testing.B) {
 	t := precompiledTest{
 		input:    "38d18acb67d25c8bb9942764b62f18e17054f66a817bd4295423adf9ed98873e000000000000000000000000000000000000000000000000000000000000001b38d18acb67d25c8bb9942764b62f18e17054f66a817bd4295423adf9ed98873e789d1dd423d25f0772d2748d60f7e4b81bb14d086eba8e8e8efb6dcff8a4ae02",
@@ -481,3 +503,66 @@ func BenchmarkPrecompiledBn256Pairing(bench *testing.B) {
 		benchmarkPrecompiled("08", test, bench)
 	}
 }
+
+func TestPrecompiledBlake2F(t *testing.T) {
+	for _, test := range blake2FTests {
+		testPrecompiled("09", test, t)
+	}
+	// Inputs of the wrong length or with an invalid final flag are rejected
+	p := &blake2F{}
+	if _, err := p.Run(make([]byte, blake2FInputLength-1)); err != errBlake2FInvalidInputLength {
+		t.Errorf("short input error mismatch: have %v, want %v", err, errBlake2FInvalidInputLength)
+	}
+	input := make([]byte, blake2FInputLength)
+	input[blake2FInputLength-1] = 2
+	if _, err := p.Run(input); err != errBlake2FInvalidFinalFlag {
+		t.Errorf("final flag error mismatch: have %v, want %v", err, errBlake2FInvalidFinalFlag)
+	}
+}
+
+func BenchmarkPrecompiledBlake2F(bench *testing.B) {
+	for _, test := range blake2FTests {
+		benchmarkPrecompiled("09", test, bench)
+	}
+}
+
+// Tests that the pre-compiled contracts are activated and repriced by the forks.
+func TestActivePrecompiles(t *testing.T) {
+	var (
+		blake2FAddr = common.BytesToAddress([]byte{9})
+		modExpAddr  = common.BytesToAddress([]byte{5})
+		chainAddr   = common.BytesToAddress([]byte{1, 0})
+	)
+	if _, ok := ActivePrecompiles(params.Rules{})[blake2FAddr]; ok {
+		t.Errorf("blake2f active before ares")
+	}
+	if _, ok := ActivePrecompiles(params.Rules{IsAres: true})[blake2FAddr]; !ok {
+		t.Errorf("blake2f inactive since ares")
+	}
+	// Exponentiate with single byte lengths, below the minimum price of athena
+	input := common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000001" +
+		"0000000000000000000000000000000000000000000000000000000000000001" +
+		"0000000000000000000000000000000000000000000000000000000000000001" +
+		"020305")
+	if gas := ActivePrecompiles(params.Rules{IsAres: true})[modExpAddr].RequiredGas(input); gas != 0 {
+		t.Errorf("modexp gas mismatch before athena: have %d, want %d", gas, 0)
+	}
+	if gas := ActivePrecompiles(params.Rules{IsAthena: true})[modExpAddr].RequiredGas(input); gas != params.ModExpMinGas {
+		t.Errorf("modexp gas mismatch since athena: have %d, want %d", gas, params.ModExpMinGas)
+	}
+	// Chain specific contracts are registered alongside the standard ones. They
+	// go into a copy of the registry, leaving the global one untouched.
+	registry := append(precompileRegistry(nil), precompiles...)
+	registry.register(func(rules params.Rules) bool { return rules.IsAthena }, map[common.Address]PrecompiledContract{
+		chainAddr: &dataCopy{},
+	})
+	if _, ok := registry.active(params.Rules{IsAres: true})[chainAddr]; ok {
+		t.Errorf("chain specific contract active before athena")
+	}
+	if _, ok := registry.active(params.Rules{IsAthena: true})[chainAddr]; !ok {
+		t.Errorf("chain specific contract inactive since athena")
+	}
+	if _, ok := ActivePrecompiles(params.Rules{IsAthena: true})[chainAddr]; ok {
+		t.Errorf("chain specific contract leaked into the global registry")
+	}
+}
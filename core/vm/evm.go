@@ -40,14 +40,16 @@ type (
 	// and is used by the BLOCKHASH EVM op code.
 	GetHashFunc func(uint64) common.Hash
 	VoteFunc    func(StateDB, common.Address, []types.Vote, *map[common.Address]types.Candidate, int64) error
+	// DelegatesFunc returns the consensus snapshot of the delegates a block is
+	// produced on.
+	DelegatesFunc func() (*DelegateSnapshot, error)
 )
 
 // run runs the given contract and takes care of running precompiles with a fallback to the byte code interpreter.
 func run(evm *EVM, contract *Contract, input []byte) ([]byte, error) {
 	if contract.CodeAddr != nil {
-		precompiles := PrecompiledContracts
-		if p := precompiles[*contract.CodeAddr]; p != nil {
-			return RunPrecompiledContract(p, input, contract)
+		if p := evm.precompiles[*contract.CodeAddr]; p != nil {
+			return runPrecompiledContract(evm, p, input, contract)
 		}
 	}
 	return evm.interpreter.Run(contract, input)
@@ -65,6 +67,8 @@ type Context struct {
 	GetHash GetHashFunc
 
 	Vote VoteFunc
+	// Delegates returns the snapshot of the delegates read by contracts
+	Delegates DelegatesFunc
 
 	// Message information
 	Origin   common.Address // Provides information for ORIGIN
@@ -79,6 +83,15 @@ type Context struct {
 	DelegateList *map[common.Address]types.Candidate
 }
 
+// DelegateSnapshot is the view of the delegates contracts get, taken from the
+// shuffled round of the block and the delegate state of its parent so that it's
+// the same for every transaction of a block.
+type DelegateSnapshot struct {
+	Candidates []types.Candidate // Delegate candidates, ranked by their votes
+	Producers  []common.Address  // Delegates of the producing round, in block producing order
+	Round      uint64            // Index of the producing round since the genesis
+}
+
 // EVM is the eminer-pro Virtual Machine base object and provides
 // the necessary tools to run a contract on the given state with
 // the provided context. It should be noted that any error
@@ -100,6 +113,10 @@ type EVM struct {
 	chainConfig *params.ChainConfig
 	// chain rules contains the chain rules for the current epoch
 	chainRules params.Rules
+	// precompiles contains the pre-compiled contracts active under the chain rules
+	precompiles map[common.Address]PrecompiledContract
+	// contractLimits contains the contract code limits for the current block
+	contractLimits params.ContractLimitsFork
 	// virtual machine configuration options used to initialise the
 	// evm.
 	vmConfig Config
@@ -132,6 +149,8 @@ func NewEVM(ctx Context, statedb StateDB, chainConfig *params.ChainConfig, vmCon
 		chainConfig: chainConfig,
 		chainRules:  chainConfig.Rules(ctx.BlockNumber),
 	}
+	evm.precompiles = ActivePrecompiles(evm.chainRules)
+	evm.contractLimits = chainConfig.ContractLimits(ctx.BlockNumber)
 
 	evm.interpreter = NewInterpreter(evm, vmConfig)
 	return evm
@@ -143,6 +162,11 @@ func (evm *EVM) Cancel() {
 	atomic.StoreInt32(&evm.abort, 1)
 }
 
+// Cancelled returns true if Cancel has been called
+func (evm *EVM) Cancelled() bool {
+	return atomic.LoadInt32(&evm.abort) == 1
+}
+
 // Call executes the contract associated with the addr with the given input as
 // parameters. It also handles any necessary value transfer required and takes
 // the necessary steps to create accounts and reverses the state in case of an
@@ -151,6 +175,12 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
 		return nil, gas, nil
 	}
+	if evm.vmConfig.Debug && evm.depth > 0 {
+		evm.vmConfig.Tracer.CaptureEnter(CALL, caller.Address(), addr, input, gas, value)
+		defer func(startGas uint64) { // Lazy evaluation of the results
+			evm.vmConfig.Tracer.CaptureExit(ret, startGas-leftOverGas, err)
+		}(gas)
+	}
 	var asset *common.Address
 	for _, a := range args {
 		switch a.(type) {
@@ -175,8 +205,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 		snapshot = evm.StateDB.Snapshot()
 	)
 	if !evm.StateDB.Exist(addr) {
-		precompiles := PrecompiledContracts
-		if precompiles[addr] == nil && value.Sign() == 0 {
+		if evm.precompiles[addr] == nil && value.Sign() == 0 {
 			if evm.vmConfig.Debug && evm.depth == 0 {
 				evm.vmConfig.Tracer.CaptureStart(caller.Address(), addr, false, input, gas, value)
 				evm.vmConfig.Tracer.CaptureEnd(ret, 0, 0, nil)
@@ -210,7 +239,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	// when we're in homestead this also counts for code storage gas errors.
 	if err != nil {
 		evm.StateDB.RevertToSnapshot(snapshot)
-		if err != errExecutionReverted {
+		if err != ErrExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
 	}
@@ -228,6 +257,12 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
 		return nil, gas, nil
 	}
+	if evm.vmConfig.Debug && evm.depth > 0 {
+		evm.vmConfig.Tracer.CaptureEnter(CALLCODE, caller.Address(), addr, input, gas, value)
+		defer func(startGas uint64) { // Lazy evaluation of the results
+			evm.vmConfig.Tracer.CaptureExit(ret, startGas-leftOverGas, err)
+		}(gas)
+	}
 
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
@@ -251,7 +286,7 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 	ret, err = run(evm, contract, input)
 	if err != nil {
 		evm.StateDB.RevertToSnapshot(snapshot)
-		if err != errExecutionReverted {
+		if err != ErrExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
 	}
@@ -267,6 +302,12 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
 		return nil, gas, nil
 	}
+	if evm.vmConfig.Debug && evm.depth > 0 {
+		evm.vmConfig.Tracer.CaptureEnter(DELEGATECALL, caller.Address(), addr, input, gas, nil)
+		defer func(startGas uint64) { // Lazy evaluation of the results
+			evm.vmConfig.Tracer.CaptureExit(ret, startGas-leftOverGas, err)
+		}(gas)
+	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
@@ -284,7 +325,7 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 	ret, err = run(evm, contract, input)
 	if err != nil {
 		evm.StateDB.RevertToSnapshot(snapshot)
-		if err != errExecutionReverted {
+		if err != ErrExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
 	}
@@ -299,6 +340,12 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
 		return nil, gas, nil
 	}
+	if evm.vmConfig.Debug && evm.depth > 0 {
+		evm.vmConfig.Tracer.CaptureEnter(STATICCALL, caller.Address(), addr, input, gas, new(big.Int))
+		defer func(startGas uint64) { // Lazy evaluation of the results
+			evm.vmConfig.Tracer.CaptureExit(ret, startGas-leftOverGas, err)
+		}(gas)
+	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
@@ -327,7 +374,7 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	ret, err = run(evm, contract, input)
 	if err != nil {
 		evm.StateDB.RevertToSnapshot(snapshot)
-		if err != errExecutionReverted {
+		if err != ErrExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
 	}
@@ -336,7 +383,21 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 
 // Create creates a new contract using code as deployment code.
 func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, asset *common.Address, value *big.Int, abi string) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+	contractAddr = crypto.CreateAddress(caller.Address(), evm.StateDB.GetNonce(caller.Address()))
+	return evm.create(caller, code, gas, asset, value, abi, contractAddr, CREATE)
+}
+
+// Create2 creates a new contract using code as deployment code, at an address
+// derived from the caller, the salt and the hash of the code instead of the
+// nonce of the caller.
+func (evm *EVM) Create2(caller ContractRef, code []byte, gas uint64, value *big.Int, salt *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+	contractAddr = crypto.CreateAddress2(caller.Address(), common.BigToHash(salt), crypto.Keccak256(code))
+	return evm.create(caller, code, gas, nil, value, "", contractAddr, CREATE2)
+}
 
+// create deploys code as a new contract at the given address on behalf of the
+// caller, op being the creating opcode reported to tracers.
+func (evm *EVM) create(caller ContractRef, code []byte, gas uint64, asset *common.Address, value *big.Int, abi string, contractAddr common.Address, op OpCode) (ret []byte, _ common.Address, leftOverGas uint64, err error) {
 	// Depth check execution. Fail if we're trying to execute above the
 	// limit.
 	if evm.depth > int(params.CallCreateDepth) {
@@ -349,7 +410,12 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, asset *commo
 	nonce := evm.StateDB.GetNonce(caller.Address())
 	evm.StateDB.SetNonce(caller.Address(), nonce+1)
 
-	contractAddr = crypto.CreateAddress(caller.Address(), nonce)
+	if evm.vmConfig.Debug && evm.depth > 0 {
+		evm.vmConfig.Tracer.CaptureEnter(op, caller.Address(), contractAddr, code, gas, value)
+		defer func(startGas uint64) { // Lazy evaluation of the results
+			evm.vmConfig.Tracer.CaptureExit(ret, startGas-leftOverGas, err)
+		}(gas)
+	}
 	contractHash := evm.StateDB.GetCodeHash(contractAddr)
 	if evm.StateDB.GetNonce(contractAddr) != 0 || (contractHash != (common.Hash{}) && contractHash != emptyCodeHash) {
 		return nil, common.Address{}, 0, ErrContractAddressCollision
@@ -380,13 +446,14 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, asset *commo
 	ret, err = run(evm, contract, nil)
 
 	// check whether the max code size has been exceeded
-	maxCodeSizeExceeded := len(ret) > params.MaxCodeSize
+	maxCodeSize := evm.contractLimits.MaxCodeSize
+	maxCodeSizeExceeded := maxCodeSize != 0 && uint64(len(ret)) > maxCodeSize
 	// if the contract creation ran successfully and no errors were returned
 	// calculate the gas required to store the code. If the code could not
 	// be stored due to not enough gas set an error and let it be handled
 	// by the error checking condition below.
 	if err == nil && !maxCodeSizeExceeded {
-		createDataGas := uint64(len(ret)) * params.CreateDataGas
+		createDataGas := uint64(len(ret)) * evm.interpreter.gasTable.CreateData
 		createDataGas += uint64(len(abi)) * params.TxABIGas
 		if contract.UseGas(createDataGas) {
 			evm.StateDB.SetCode(contractAddr, ret)
@@ -401,7 +468,7 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, asset *commo
 	// when we're in homestead this also counts for code storage gas errors.
 	if maxCodeSizeExceeded || (err != nil && (err != ErrCodeStoreOutOfGas)) {
 		evm.StateDB.RevertToSnapshot(snapshot)
-		if err != errExecutionReverted {
+		if err != ErrExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
 	}
@@ -418,6 +485,9 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, asset *commo
 // ChainConfig returns the environment's chain configuration
 func (evm *EVM) ChainConfig() *params.ChainConfig { return evm.chainConfig }
 
+// Precompiles returns the pre-compiled contracts active in the environment.
+func (evm *EVM) Precompiles() map[common.Address]PrecompiledContract { return evm.precompiles }
+
 // Interpreter returns the EVM interpreter
 func (evm *EVM) Interpreter() *Interpreter { return evm.interpreter }
 
@@ -17,6 +17,8 @@
 package vm
 
 import (
+	"math/big"
+
 	"github.com/Aurorachain-io/go-aoa/common"
 	"github.com/Aurorachain-io/go-aoa/common/math"
 	"github.com/Aurorachain-io/go-aoa/params"
@@ -79,7 +81,7 @@ func gasCallDataCopy(gt params.GasTable, evm *EVM, contract *Contract, stack *St
 		return 0, errGasUintOverflow
 	}
 
-	if words, overflow = math.SafeMul(toWordSize(words), params.CopyGas); overflow {
+	if words, overflow = math.SafeMul(toWordSize(words), gt.Copy); overflow {
 		return 0, errGasUintOverflow
 	}
 
@@ -105,7 +107,7 @@ func gasReturnDataCopy(gt params.GasTable, evm *EVM, contract *Contract, stack *
 		return 0, errGasUintOverflow
 	}
 
-	if words, overflow = math.SafeMul(toWordSize(words), params.CopyGas); overflow {
+	if words, overflow = math.SafeMul(toWordSize(words), gt.Copy); overflow {
 		return 0, errGasUintOverflow
 	}
 
@@ -126,14 +128,14 @@ func gasSStore(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, m
 	// 3. From a non-zero to a non-zero                         (CHANGE)
 	if common.EmptyHash(val) && !common.EmptyHash(common.BigToHash(y)) {
 		// 0 => non 0
-		return params.SstoreSetGas, nil
+		return gt.SStoreSet, nil
 	} else if !common.EmptyHash(val) && common.EmptyHash(common.BigToHash(y)) {
-		evm.StateDB.AddRefund(params.SstoreRefundGas)
+		evm.StateDB.AddRefund(gt.SStoreRefund)
 
-		return params.SstoreClearGas, nil
+		return gt.SStoreClear, nil
 	} else {
 		// non 0 => non 0 (or 0 => 0)
-		return params.SstoreResetGas, nil
+		return gt.SStoreReset, nil
 	}
 }
 
@@ -149,15 +151,15 @@ func makeGasLog(n uint64) gasFunc {
 			return 0, err
 		}
 
-		if gas, overflow = math.SafeAdd(gas, params.LogGas); overflow {
+		if gas, overflow = math.SafeAdd(gas, gt.Log); overflow {
 			return 0, errGasUintOverflow
 		}
-		if gas, overflow = math.SafeAdd(gas, n*params.LogTopicGas); overflow {
+		if gas, overflow = math.SafeAdd(gas, n*gt.LogTopic); overflow {
 			return 0, errGasUintOverflow
 		}
 
 		var memorySizeGas uint64
-		if memorySizeGas, overflow = math.SafeMul(requestedSize, params.LogDataGas); overflow {
+		if memorySizeGas, overflow = math.SafeMul(requestedSize, gt.LogData); overflow {
 			return 0, errGasUintOverflow
 		}
 		if gas, overflow = math.SafeAdd(gas, memorySizeGas); overflow {
@@ -174,7 +176,7 @@ func gasSha3(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem
 		return 0, err
 	}
 
-	if gas, overflow = math.SafeAdd(gas, params.Sha3Gas); overflow {
+	if gas, overflow = math.SafeAdd(gas, gt.Sha3); overflow {
 		return 0, errGasUintOverflow
 	}
 
@@ -182,7 +184,7 @@ func gasSha3(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem
 	if overflow {
 		return 0, errGasUintOverflow
 	}
-	if wordGas, overflow = math.SafeMul(toWordSize(wordGas), params.Sha3WordGas); overflow {
+	if wordGas, overflow = math.SafeMul(toWordSize(wordGas), gt.Sha3Word); overflow {
 		return 0, errGasUintOverflow
 	}
 	if gas, overflow = math.SafeAdd(gas, wordGas); overflow {
@@ -206,7 +208,7 @@ func gasCodeCopy(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack,
 	if overflow {
 		return 0, errGasUintOverflow
 	}
-	if wordGas, overflow = math.SafeMul(toWordSize(wordGas), params.CopyGas); overflow {
+	if wordGas, overflow = math.SafeMul(toWordSize(wordGas), gt.Copy); overflow {
 		return 0, errGasUintOverflow
 	}
 	if gas, overflow = math.SafeAdd(gas, wordGas); overflow {
@@ -231,7 +233,7 @@ func gasExtCodeCopy(gt params.GasTable, evm *EVM, contract *Contract, stack *Sta
 		return 0, errGasUintOverflow
 	}
 
-	if wordGas, overflow = math.SafeMul(toWordSize(wordGas), params.CopyGas); overflow {
+	if wordGas, overflow = math.SafeMul(toWordSize(wordGas), gt.Copy); overflow {
 		return 0, errGasUintOverflow
 	}
 
@@ -277,13 +279,68 @@ func gasMStore(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, m
 	return gas, nil
 }
 
+// gasInitCode checks the size of the init code of a contract creation against
+// the contract limits of the block, returning the gas metered for its words.
+func gasInitCode(evm *EVM, size *big.Int) (uint64, error) {
+	length, overflow := bigUint64(size)
+	if overflow {
+		return 0, errGasUintOverflow
+	}
+	limits := evm.contractLimits
+	if limits.MaxInitCodeSize != 0 && length > limits.MaxInitCodeSize {
+		return 0, errMaxInitCodeSizeExceeded
+	}
+	gas, overflow := math.SafeMul(toWordSize(length), limits.InitCodeWordGas)
+	if overflow {
+		return 0, errGasUintOverflow
+	}
+	return gas, nil
+}
+
 func gasCreate(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
 	var overflow bool
 	gas, err := memoryGasCost(mem, memorySize)
 	if err != nil {
 		return 0, err
 	}
-	if gas, overflow = math.SafeAdd(gas, params.CreateGas); overflow {
+	if gas, overflow = math.SafeAdd(gas, gt.Create); overflow {
+		return 0, errGasUintOverflow
+	}
+	initCodeGas, err := gasInitCode(evm, stack.Back(2))
+	if err != nil {
+		return 0, err
+	}
+	if gas, overflow = math.SafeAdd(gas, initCodeGas); overflow {
+		return 0, errGasUintOverflow
+	}
+	return gas, nil
+}
+
+func gasCreate2(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	var overflow bool
+	gas, err := memoryGasCost(mem, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	if gas, overflow = math.SafeAdd(gas, gt.Create); overflow {
+		return 0, errGasUintOverflow
+	}
+	initCodeGas, err := gasInitCode(evm, stack.Back(2))
+	if err != nil {
+		return 0, err
+	}
+	if gas, overflow = math.SafeAdd(gas, initCodeGas); overflow {
+		return 0, errGasUintOverflow
+	}
+	// The init code is hashed to derive the contract address
+	wordGas, overflow := bigUint64(stack.Back(2))
+	if overflow {
+		return 0, errGasUintOverflow
+	}
+	if wordGas, overflow = math.SafeMul(toWordSize(wordGas), gt.Sha3Word); overflow {
+		return 0, errGasUintOverflow
+	}
+	if gas, overflow = math.SafeAdd(gas, wordGas); overflow {
 		return 0, errGasUintOverflow
 	}
 	return gas, nil
@@ -293,6 +350,10 @@ func gasBalance(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack,
 	return gt.Balance, nil
 }
 
+func gasExtCodeHash(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	return gt.ExtcodeHash, nil
+}
+
 func gasExtCodeSize(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
 	return gt.ExtcodeSize, nil
 }
@@ -323,7 +384,7 @@ func gasCall(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem
 	)
 
 	if !evm.StateDB.Exist(address) {
-		gas += params.CallNewAccountGas
+		gas += gt.CallNewAccount
 	}
 	//if false {
 	//	if transfersValue && evm.StateDB.Empty(address) {
@@ -333,7 +394,7 @@ func gasCall(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem
 	//	gas += params.CallNewAccountGas
 	//}
 	if transfersValue {
-		gas += params.CallValueTransferGas
+		gas += gt.CallValueTransfer
 	}
 	memoryGas, err := memoryGasCost(mem, memorySize)
 	if err != nil {
@@ -357,7 +418,7 @@ func gasCall(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem
 func gasCallCode(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
 	gas := gt.Calls
 	if stack.Back(2).Sign() != 0 {
-		gas += params.CallValueTransferGas
+		gas += gt.CallValueTransfer
 	}
 	memoryGas, err := memoryGasCost(mem, memorySize)
 	if err != nil {
@@ -400,7 +461,7 @@ func gasSuicide(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack,
 	//}
 
 	if !evm.StateDB.HasSuicided(contract.Address()) {
-		evm.StateDB.AddRefund(params.SuicideRefundGas)
+		evm.StateDB.AddRefund(gt.SuicideRefund)
 	}
 	return gas, nil
 }
@@ -458,13 +519,13 @@ func gasDup(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem
 }
 
 func gasBalanceOf(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
-	return params.BalanceOfGas, nil
+	return gt.BalanceOf, nil
 }
 
 func gasTransferAsset(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
 	gas := gt.Calls
 	var overflow bool
-	if gas, overflow = math.SafeAdd(gas, params.TransferAssetGas); overflow {
+	if gas, overflow = math.SafeAdd(gas, gt.TransferAsset); overflow {
 		return 0, errGasUintOverflow
 	}
 	return gas, nil
@@ -16,7 +16,12 @@
 
 package vm
 
-import "testing"
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/params"
+)
 
 func TestMemoryGasCost(t *testing.T) {
 	//size := uint64(math.MaxUint64 - 64)
@@ -34,3 +39,25 @@ func TestMemoryGasCost(t *testing.T) {
 		t.Error("expected error")
 	}
 }
+
+func TestInitCodeGas(t *testing.T) {
+	evm := &EVM{contractLimits: params.ContractLimitsFork{MaxInitCodeSize: 64, InitCodeWordGas: 2}}
+	tests := []struct {
+		size uint64
+		gas  uint64
+		err  error
+	}{
+		{0, 0, nil}, {1, 2, nil}, {32, 2, nil}, {33, 4, nil}, {64, 4, nil}, {65, 0, errMaxInitCodeSizeExceeded},
+	}
+	for _, tt := range tests {
+		gas, err := gasInitCode(evm, new(big.Int).SetUint64(tt.size))
+		if gas != tt.gas || err != tt.err {
+			t.Errorf("size %d: have %d/%v, want %d/%v", tt.size, gas, err, tt.gas, tt.err)
+		}
+	}
+	// Without limits the init code is unbounded and free
+	evm.contractLimits = params.ContractLimitsFork{}
+	if gas, err := gasInitCode(evm, big.NewInt(1<<20)); gas != 0 || err != nil {
+		t.Errorf("unlimited: have %d/%v, want 0/nil", gas, err)
+	}
+}
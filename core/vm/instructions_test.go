@@ -159,6 +159,33 @@ func TestSAR(t *testing.T) {
 	testTwoOperandOp(t, tests, opSAR)
 }
 
+func TestAresInstructionSet(t *testing.T) {
+	config := &params.ChainConfig{ChainId: big.NewInt(60), MaxElectDelegate: big.NewInt(21), AresBlock: big.NewInt(10)}
+	for _, tt := range []struct {
+		number *big.Int
+		valid  bool
+	}{
+		{big.NewInt(9), false},
+		{big.NewInt(10), true},
+	} {
+		env := NewEVM(Context{BlockNumber: tt.number}, nil, config, Config{})
+		for _, op := range []OpCode{EXTCODEHASH, CHAINID, SELFBALANCE, CREATE2} {
+			if valid := env.interpreter.cfg.JumpTable[op].valid; valid != tt.valid {
+				t.Errorf("block %v: %v validity mismatch: have %v, want %v", tt.number, op, valid, tt.valid)
+			}
+		}
+		if !env.interpreter.cfg.JumpTable[SHL].valid {
+			t.Errorf("block %v: SHL not valid", tt.number)
+		}
+	}
+	env := NewEVM(Context{BlockNumber: big.NewInt(10)}, nil, config, Config{})
+	stack := newstack()
+	opChainID(new(uint64), env, nil, nil, stack)
+	if chainID := stack.pop(); chainID.Cmp(config.ChainId) != 0 {
+		t.Errorf("chain ID mismatch: have %v, want %v", chainID, config.ChainId)
+	}
+}
+
 func TestSGT(t *testing.T) {
 	tests := []twoOperandTest{
 
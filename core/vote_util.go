@@ -158,6 +158,31 @@ func CountTrxVote(from string, tx *types.Transaction, statedb *state.StateDB, db
 	return candidates, nil
 }
 
+// refreshVotes records the votes of the sender of a vote transaction as backed by
+// its stake in the given decay period, restoring their full weight, and drops
+// the stake records of the votes it withdraws. Voters with a stake record are
+// indexed among the voters of their delegates, who share their transaction fees
+// with them since the fee sharing fork.
+func refreshVotes(voter common.Address, tx *types.Transaction, statedb *state.StateDB, db *delegatestate.DelegateDB, period uint64) error {
+	if action := tx.TxDataAction(); action != types.ActionAddVote && action != types.ActionSubVote {
+		return nil
+	}
+	votes, err := types.BytesToVote(tx.Vote())
+	if err != nil {
+		return err
+	}
+	for _, vote := range votes {
+		if vote.Operation == 1 {
+			db.WithdrawVote(*vote.Candidate, voter)
+		}
+	}
+	stake := delegatestate.VoterStake(statedb, voter)
+	for _, delegate := range statedb.GetVoteList(voter) {
+		db.CastVote(delegate, voter, stake, period)
+	}
+	return nil
+}
+
 func recoverPlainPubKey(signHash common.Hash, R, S, Vb *big.Int, homestead bool) ([]byte, error) {
 	if Vb.BitLen() > 8 {
 		return nil, ErrInvalidSig
@@ -0,0 +1,219 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus/delegatestate"
+	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// Tests that refreshed votes are weighted by the stake of their voter and
+// that votes not refreshed decay, down to the legacy votes cast before the fork.
+func TestRefreshVotes(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	dState, _ := delegatestate.New(common.Hash{}, delegatestate.NewDatabase(db))
+
+	var (
+		first, second = common.Address{1}, common.Address{2}
+		voter         = common.Address{0xff}
+	)
+	dState.GetOrNewStateObject(first, "first", 1)
+	dState.GetOrNewStateObject(second, "second", 2)
+	dState.AddVote(first, big.NewInt(1)) // Cast before the fork
+	dState.AddVote(second, big.NewInt(4))
+
+	vote := func(op uint, delegates ...common.Address) *types.Transaction {
+		votes := make([]types.Vote, len(delegates))
+		for i := range delegates {
+			votes[i] = types.Vote{Candidate: &delegates[i], Operation: op}
+		}
+		enc, _ := types.VoteToBytes(votes)
+		action := uint64(types.ActionAddVote)
+		if op == 1 {
+			action = types.ActionSubVote
+		}
+		return types.NewVoteTransaction(0, 0, new(big.Int), action, enc)
+	}
+	// Vote for the first delegate with a stake of 2, one of it locked
+	statedb.SetVoteList(voter, []common.Address{first, second})
+	statedb.SetBalance(voter, big.NewInt(params.Em))
+	statedb.SetLockBalance(voter, big.NewInt(params.Em))
+	dState.AddVote(first, big.NewInt(1))
+	if err := refreshVotes(voter, vote(0, first), statedb, dState, 10); err != nil {
+		t.Fatalf("failed to refresh votes: %v", err)
+	}
+	dState.UpdateVoteWeights(10, 10)
+	if weight := dState.GetVoteWeight(first); weight != 3 {
+		t.Errorf("first weight mismatch: have %d, want %d", weight, 3)
+	}
+	if delegates := dState.GetDelegates(); delegates[0].Address != second.Hex() {
+		t.Errorf("ranking mismatch: have %s first, want %s", delegates[0].Address, second.Hex())
+	}
+	// Let a period pass, halving the weights of all votes
+	dState.UpdateVoteWeights(11, 10)
+	if weight := dState.GetVoteWeight(first); weight != 1 {
+		t.Errorf("decayed first weight mismatch: have %d, want %d", weight, 1)
+	}
+	if weight := dState.GetVoteWeight(second); weight != 2 {
+		t.Errorf("decayed second weight mismatch: have %d, want %d", weight, 2)
+	}
+	if delegates := dState.GetDelegates(); delegates[0].Address != second.Hex() {
+		t.Errorf("ranking mismatch: have %s first, want %s", delegates[0].Address, second.Hex())
+	}
+	// Withdrawing a vote drops its stake, while the vote kept is refreshed
+	statedb.SetVoteList(voter, []common.Address{second})
+	dState.SubVote(first, big.NewInt(1))
+	if err := refreshVotes(voter, vote(1, first), statedb, dState, 11); err != nil {
+		t.Fatalf("failed to refresh votes: %v", err)
+	}
+	dState.UpdateVoteWeights(11, 10)
+	if weight := dState.GetVoteWeight(first); weight != 0 {
+		t.Errorf("withdrawn first weight mismatch: have %d, want %d", weight, 0)
+	}
+	if weight := dState.GetVoteWeight(second); weight != 3 {
+		t.Errorf("refreshed second weight mismatch: have %d, want %d", weight, 3)
+	}
+	// Votes not refreshed fully decay eventually
+	dState.UpdateVoteWeights(11+params.MaxVoteDecayPeriods, 10)
+	if weight := dState.GetVoteWeight(second); weight != 0 {
+		t.Errorf("expired second weight mismatch: have %d, want %d", weight, 0)
+	}
+}
+
+// Tests that voters are indexed among the voters of the delegates they vote for,
+// dropped from the index when withdrawing, and that only the highest staked
+// voters are indexed once the index is full.
+func TestIndexVoters(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	dState, _ := delegatestate.New(common.Hash{}, delegatestate.NewDatabase(db))
+
+	var (
+		delegate = common.Address{1}
+		voters   = []common.Address{{0xa}, {0xb}, {0xc}}
+	)
+	dState.GetOrNewStateObject(delegate, "delegate", 1)
+
+	vote := func(op uint) *types.Transaction {
+		enc, _ := types.VoteToBytes([]types.Vote{{Candidate: &delegate, Operation: op}})
+		action := uint64(types.ActionAddVote)
+		if op == 1 {
+			action = types.ActionSubVote
+		}
+		return types.NewVoteTransaction(0, 0, new(big.Int), action, enc)
+	}
+	for i, voter := range voters {
+		statedb.SetVoteList(voter, []common.Address{delegate})
+		statedb.SetLockBalance(voter, new(big.Int).Mul(big.NewInt(int64(i+1)), big.NewInt(params.Em)))
+		if err := refreshVotes(voter, vote(0), statedb, dState, 10); err != nil {
+			t.Fatalf("failed to refresh votes: %v", err)
+		}
+	}
+	// Refreshing a vote keeps a single index entry
+	if err := refreshVotes(voters[0], vote(0), statedb, dState, 10); err != nil {
+		t.Fatalf("failed to refresh votes: %v", err)
+	}
+	if have := dState.Voters(delegate); len(have) != 3 || have[0] != voters[0] || have[2] != voters[2] {
+		t.Fatalf("voters mismatch: have %x, want %x", have, voters)
+	}
+	if weight := dState.VoterWeight(delegate, voters[1], 11); weight.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("decayed voter weight mismatch: have %v, want 1", weight)
+	}
+	// Withdrawing moves the last voter into the freed position
+	statedb.SetVoteList(voters[0], nil)
+	if err := refreshVotes(voters[0], vote(1), statedb, dState, 10); err != nil {
+		t.Fatalf("failed to refresh votes: %v", err)
+	}
+	if have := dState.Voters(delegate); len(have) != 2 || have[0] != voters[2] || have[1] != voters[1] {
+		t.Fatalf("voters after withdrawal mismatch: have %x", have)
+	}
+	// Fill the index with low staked voters, which can't push out higher stakes
+	for i := uint64(2); i < params.MaxFeeShareVoters; i++ {
+		dState.CastVote(delegate, common.Address{0xee, byte(i)}, big.NewInt(5), 10)
+	}
+	dState.CastVote(delegate, common.Address{0xff}, big.NewInt(1), 10)
+	if have := dState.Voters(delegate); uint64(len(have)) != params.MaxFeeShareVoters || have[len(have)-1] == (common.Address{0xff}) {
+		t.Fatalf("low staked voter indexed into a full index")
+	}
+	// A higher staked voter replaces the lowest staked one
+	dState.CastVote(delegate, common.Address{0xff}, big.NewInt(10), 10)
+	have := dState.Voters(delegate)
+	if uint64(len(have)) != params.MaxFeeShareVoters {
+		t.Fatalf("index size mismatch: have %d, want %d", len(have), params.MaxFeeShareVoters)
+	}
+	for _, voter := range have {
+		if voter == voters[1] {
+			t.Errorf("lowest staked voter %x still indexed", voter)
+		}
+	}
+	if have[len(have)-1] != (common.Address{0xff}) {
+		t.Errorf("higher staked voter not indexed")
+	}
+}
+
+// Tests that the votes of a voter follow its stake when its balance changes,
+// without restoring the weight they lost to decay.
+func TestRestakeVoters(t *testing.T) {
+	db, _ := aoadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	dState, _ := delegatestate.New(common.Hash{}, delegatestate.NewDatabase(db))
+
+	var (
+		delegate = common.Address{1}
+		voter    = common.Address{0xff}
+		outsider = common.Address{0xee}
+	)
+	dState.GetOrNewStateObject(delegate, "delegate", 1)
+
+	enc, _ := types.VoteToBytes([]types.Vote{{Candidate: &delegate}})
+	statedb.SetVoteList(voter, []common.Address{delegate})
+	statedb.SetBalance(voter, big.NewInt(params.Em))
+	statedb.SetLockBalance(voter, big.NewInt(params.Em))
+	if err := refreshVotes(voter, types.NewVoteTransaction(0, 0, new(big.Int), types.ActionAddVote, enc), statedb, dState, 10); err != nil {
+		t.Fatalf("failed to refresh votes: %v", err)
+	}
+	statedb.Finalise(true)
+
+	// Receiving funds increases the stake of the votes, but keeps their period
+	statedb.AddBalance(voter, new(big.Int).Mul(big.NewInt(6), big.NewInt(params.Em)))
+	statedb.AddBalance(outsider, big.NewInt(params.Em))
+	if changed := statedb.BalanceChanges(); len(changed) != 2 || changed[0] != voter || changed[1] != outsider {
+		t.Fatalf("balance changes mismatch: have %x", changed)
+	}
+	dState.RestakeVoters(statedb, statedb.BalanceChanges())
+	if weight := dState.VoterWeight(delegate, voter, 10); weight.Cmp(big.NewInt(8)) != 0 {
+		t.Errorf("restaked weight mismatch: have %v, want 8", weight)
+	}
+	if weight := dState.VoterWeight(delegate, voter, 11); weight.Cmp(big.NewInt(4)) != 0 {
+		t.Errorf("decayed restaked weight mismatch: have %v, want 4", weight)
+	}
+	if weight := dState.VoterWeight(delegate, outsider, 10); weight.Sign() != 0 {
+		t.Errorf("non-voter weight mismatch: have %v, want 0", weight)
+	}
+	statedb.Finalise(true)
+	if changed := statedb.BalanceChanges(); len(changed) != 0 {
+		t.Errorf("balance changes not cleared by finalisation: have %x", changed)
+	}
+}
@@ -0,0 +1,350 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus"
+	"github.com/Aurorachain-io/go-aoa/consensus/delegatestate"
+	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/core/vm"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+	"github.com/Aurorachain-io/go-aoa/trie"
+)
+
+// errWitnessParent is returned if a witness lacks the parent header of the block
+// it was given for.
+var errWitnessParent = errors.New("witness misses parent header")
+
+// Witness contains everything read from the chain and the state while
+// processing a block, allowing it to be executed again without access to
+// either of them.
+type Witness struct {
+	Headers   []*types.Header   // Parent and ancestor headers accessed
+	Nodes     [][]byte          // Trie nodes and contract code, keyed by their hash
+	Entries   []WitnessEntry    // Other state database entries (e.g. contract abis)
+	Delegates []types.Candidate // Delegate poll, if votes were cast
+}
+
+// WitnessEntry is a state database entry not keyed by the hash of its value.
+type WitnessEntry struct {
+	Key   []byte
+	Value []byte
+}
+
+// header retrieves a header from the witness by hash.
+func (w *Witness) header(hash common.Hash) *types.Header {
+	for _, header := range w.Headers {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
+}
+
+// witnessRecorder is a state database reading through to a backing store and
+// recording all entries retrieved. Writes are kept in memory only.
+type witnessRecorder struct {
+	source trie.DatabaseReader
+	writes *aoadb.MemDatabase
+	reads  map[string][]byte
+	lock   sync.Mutex
+}
+
+func newWitnessRecorder(source trie.DatabaseReader) *witnessRecorder {
+	writes, _ := aoadb.NewMemDatabase()
+	return &witnessRecorder{
+		source: source,
+		writes: writes,
+		reads:  make(map[string][]byte),
+	}
+}
+
+func (r *witnessRecorder) Get(key []byte) ([]byte, error) {
+	if value, err := r.writes.Get(key); err == nil {
+		return value, nil
+	}
+	value, err := r.source.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	r.lock.Lock()
+	r.reads[string(key)] = common.CopyBytes(value)
+	r.lock.Unlock()
+	return value, nil
+}
+
+func (r *witnessRecorder) Has(key []byte) (bool, error) {
+	if has, _ := r.writes.Has(key); has {
+		return true, nil
+	}
+	return r.source.Has(key)
+}
+
+func (r *witnessRecorder) Put(key []byte, value []byte) error { return r.writes.Put(key, value) }
+func (r *witnessRecorder) Delete(key []byte) error            { return r.writes.Delete(key) }
+func (r *witnessRecorder) NewBatch() aoadb.Batch              { return r.writes.NewBatch() }
+func (r *witnessRecorder) Close()                             {}
+
+// witness assembles the entries recorded, and the headers and delegate poll
+// accessed through the given chain, into a witness.
+func (r *witnessRecorder) witness(chain *witnessChain) *Witness {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	w := new(Witness)
+	for key, value := range r.reads {
+		if len(key) == common.HashLength && bytes.Equal(crypto.Keccak256(value), []byte(key)) {
+			w.Nodes = append(w.Nodes, value)
+		} else {
+			w.Entries = append(w.Entries, WitnessEntry{Key: []byte(key), Value: value})
+		}
+	}
+	sort.Slice(w.Nodes, func(i, j int) bool { return bytes.Compare(w.Nodes[i], w.Nodes[j]) < 0 })
+	sort.Slice(w.Entries, func(i, j int) bool { return bytes.Compare(w.Entries[i].Key, w.Entries[j].Key) < 0 })
+
+	for _, header := range chain.headers {
+		w.Headers = append(w.Headers, header)
+	}
+	sort.Slice(w.Headers, func(i, j int) bool { return w.Headers[i].Number.Cmp(w.Headers[j].Number) > 0 })
+
+	if chain.delegates != nil {
+		for _, candidate := range *chain.delegates {
+			w.Delegates = append(w.Delegates, candidate)
+		}
+		sort.Slice(w.Delegates, func(i, j int) bool { return w.Delegates[i].Address < w.Delegates[j].Address })
+	}
+	return w
+}
+
+// witnessChain is the chain context of a block executed for a witness. When
+// generating a witness it reads through to a live chain, recording the headers
+// and delegate poll accessed, otherwise it serves them from the witness.
+type witnessChain struct {
+	config *params.ChainConfig
+	engine consensus.Engine
+	live   *BlockChain   // Chain to read through to, nil when verifying
+	parent *types.Header // Parent of the block executed, anchoring lookups by number
+
+	delegateDB delegatestate.Database // Database to open past delegate states from
+	headers    map[common.Hash]*types.Header
+	delegates  *map[common.Address]types.Candidate
+}
+
+func (c *witnessChain) Config() *params.ChainConfig           { return c.config }
+func (c *witnessChain) GetGenesisConfig() *params.ChainConfig { return c.config }
+func (c *witnessChain) Engine() consensus.Engine              { return c.engine }
+
+// CurrentHeader is not available to blocks executed for witnesses.
+func (c *witnessChain) CurrentHeader() *types.Header { return nil }
+
+// GetBlock is not available to blocks executed for witnesses, as witnesses
+// only contain headers.
+func (c *witnessChain) GetBlock(hash common.Hash, number uint64) *types.Block { return nil }
+
+func (c *witnessChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if c.live != nil {
+		return c.record(c.live.GetHeader(hash, number))
+	}
+	if header := c.headers[hash]; header != nil && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+func (c *witnessChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	if c.live != nil {
+		return c.record(c.live.GetHeaderByHash(hash))
+	}
+	return c.headers[hash]
+}
+
+// GetHeaderByNumber retrieves an ancestor of the block executed by following the
+// parent hashes back from its parent, recording every header passed when
+// generating a witness. Headers of a witness not linked to the block are never
+// served.
+func (c *witnessChain) GetHeaderByNumber(number uint64) *types.Header {
+	header := c.parent
+	for header != nil && header.Number.Uint64() > number {
+		header = c.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	if header == nil || header.Number.Uint64() != number {
+		return nil
+	}
+	return header
+}
+
+// DelegateStateAt opens the delegate state of a past block, recording the nodes
+// accessed when generating a witness.
+func (c *witnessChain) DelegateStateAt(root common.Hash) (*delegatestate.DelegateDB, error) {
+	return delegatestate.New(root, c.delegateDB)
+}
+
+func (c *witnessChain) GetDelegatePoll() (*map[common.Address]types.Candidate, error) {
+	if c.live != nil {
+		delegates, err := c.live.GetDelegatePoll()
+		if err != nil {
+			return nil, err
+		}
+		c.delegates = delegates
+		return delegates, nil
+	}
+	return c.delegates, nil
+}
+
+// record adds a header retrieved from the live chain to the witness.
+func (c *witnessChain) record(header *types.Header) *types.Header {
+	if header != nil {
+		c.headers[header.Hash()] = header
+	}
+	return header
+}
+
+// executionChain is the access to the chain needed to execute a block outside
+// of a BlockChain.
+type executionChain interface {
+	ChainContext
+	consensus.ChainReader
+}
+
+// executeBlock processes the transactions of a block on top of the given
+// states like StateProcessor.Process, reading the chain through the given
+// context instead of a BlockChain.
+func executeBlock(chain executionChain, block *types.Block, statedb *state.StateDB, delegatedb *delegatestate.DelegateDB) (types.Receipts, uint64, error) {
+	var (
+		receipts types.Receipts
+		usedGas  = new(uint64)
+		header   = block.Header()
+		gp       = new(GasPool).AddGas(block.GasLimit())
+	)
+	for i, tx := range block.Transactions() {
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		delegatedb.Prepare(tx.Hash(), block.Hash(), i)
+		receipt, _, _, err := applyTransaction(chain.Config(), chain, nil, gp, statedb, header, tx, usedGas, vm.Config{}, delegatedb, block.Time().Uint64(), false)
+		if err != nil {
+			return nil, 0, err
+		}
+		receipts = append(receipts, receipt)
+	}
+	if _, err := chain.Engine().Finalize(chain, header, statedb, delegatedb, block.Transactions(), receipts); err != nil {
+		return nil, 0, err
+	}
+
+	// Missing trie nodes are swallowed by the states, surface them instead of
+	// reporting a root mismatch
+	if err := statedb.Error(); err != nil {
+		return nil, 0, err
+	}
+	if err := delegatedb.Error(); err != nil {
+		return nil, 0, err
+	}
+	return receipts, *usedGas, nil
+}
+
+// GenerateWitness executes a block on top of the state of its parent, recording
+// all trie nodes, contract code and headers accessed into a witness, which is
+// sufficient to verify the block without access to the chain.
+func (bc *BlockChain) GenerateWitness(block *types.Block) (*Witness, error) {
+	parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	var (
+		recorder = newWitnessRecorder(bc.stateCache.TrieDB())
+		chain    = &witnessChain{
+			config:     bc.config,
+			engine:     bc.dacEngine,
+			live:       bc,
+			parent:     parent,
+			delegateDB: delegatestate.NewDatabase(recorder),
+			headers:    map[common.Hash]*types.Header{parent.Hash(): parent},
+		}
+	)
+	statedb, err := state.New(parent.Root, state.NewDatabase(recorder))
+	if err != nil {
+		return nil, err
+	}
+	delegatedb, err := delegatestate.New(parent.DelegateRoot, delegatestate.NewDatabase(recorder))
+	if err != nil {
+		return nil, err
+	}
+	receipts, usedGas, err := executeBlock(chain, block, statedb, delegatedb)
+	if err != nil {
+		return nil, err
+	}
+	// Validating the result hashes the states, which resolves the nodes needed
+	// to collapse deleted paths, so these end up in the witness too
+	if err := NewBlockValidator(bc.config, bc, bc.dacEngine).ValidateState(block, nil, statedb, receipts, usedGas, delegatedb); err != nil {
+		return nil, err
+	}
+	return recorder.witness(chain), nil
+}
+
+// VerifyWitness executes a block using only the state contained in its witness,
+// and checks that the results match the ones committed to by the block header.
+func VerifyWitness(config *params.ChainConfig, engine consensus.Engine, block *types.Block, witness *Witness) error {
+	parent := witness.header(block.ParentHash())
+	if parent == nil || parent.Number.Uint64()+1 != block.NumberU64() {
+		return errWitnessParent
+	}
+	db, _ := aoadb.NewMemDatabase()
+	for _, node := range witness.Nodes {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	for _, entry := range witness.Entries {
+		db.Put(entry.Key, entry.Value)
+	}
+	// Headers are only retrieved by hash or through the parent hashes of the block,
+	// so any forged ones are unreachable
+	chain := &witnessChain{
+		config:     config,
+		engine:     engine,
+		parent:     parent,
+		delegateDB: delegatestate.NewDatabase(db),
+		headers:    make(map[common.Hash]*types.Header),
+	}
+	for _, header := range witness.Headers {
+		chain.headers[header.Hash()] = header
+	}
+	delegates := make(map[common.Address]types.Candidate)
+	for _, candidate := range witness.Delegates {
+		delegates[common.HexToAddress(candidate.Address)] = candidate
+	}
+	chain.delegates = &delegates
+	statedb, err := state.New(parent.Root, state.NewDatabase(db))
+	if err != nil {
+		return fmt.Errorf("incomplete witness: %v", err)
+	}
+	delegatedb, err := delegatestate.New(parent.DelegateRoot, delegatestate.NewDatabase(db))
+	if err != nil {
+		return fmt.Errorf("incomplete witness: %v", err)
+	}
+	receipts, usedGas, err := executeBlock(chain, block, statedb, delegatedb)
+	if err != nil {
+		return err
+	}
+	return NewBlockValidator(config, nil, engine).ValidateState(block, nil, statedb, receipts, usedGas, delegatedb)
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/consensus/dpos"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/core/vm"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+	"github.com/Aurorachain-io/go-aoa/rlp"
+)
+
+// Tests that a block can be verified from its witness alone, and that witnesses
+// lacking state or not matching the block are rejected.
+func TestWitness(t *testing.T) {
+	var (
+		db, _   = aoadb.NewMemDatabase()
+		config  = params.AllDacchainProtocolChanges
+		signer  = types.MakeSigner(config, big.NewInt(1))
+		key, _  = crypto.GenerateKey()
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		emitter = common.HexToAddress("0x0300")
+	)
+	genesis := (&Genesis{
+		Config: config,
+		Alloc: GenesisAlloc{
+			addr: {Balance: big.NewInt(params.Em)},
+			// PUSH1 0 PUSH1 0 LOG0
+			emitter: {Balance: new(big.Int), Code: []byte{0x60, 0x00, 0x60, 0x00, 0xa0}},
+		},
+		Agents: GenesisAgents{{Address: "0x0200", Vote: 1, Nickname: "test"}},
+	}).MustCommit(db)
+
+	chain, err := NewBlockChain(db, nil, config, dpos.New(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	var txs types.Transactions
+	for i, to := range []common.Address{common.HexToAddress("0x01"), emitter} {
+		action := uint64(types.ActionTrans)
+		if to == emitter {
+			action = types.ActionCallContract
+		}
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), to, big.NewInt(10), 100000, big.NewInt(1), nil, action, nil, ""), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		txs = append(txs, tx)
+	}
+	// Assemble a valid block by processing it once and filling in the results
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		GasLimit:   genesis.GasLimit(),
+		Time:       new(big.Int).Add(genesis.Time(), big.NewInt(10)),
+		Coinbase:   common.HexToAddress("0x0400"),
+	}
+	statedb, _ := chain.StateAt(genesis.Root())
+	delegatedb, _ := chain.DelegateStateAt(genesis.DelegateRoot())
+	receipts, _, usedGas, err := chain.Processor().Process(types.NewBlock(header, txs, nil), statedb, vm.Config{}, delegatedb)
+	if err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	header.GasUsed = usedGas
+	header.Root = statedb.IntermediateRoot(false)
+	header.DelegateRoot = delegatedb.IntermediateRoot(false)
+	block := types.NewBlock(header, txs, receipts)
+
+	witness, err := chain.GenerateWitness(block)
+	if err != nil {
+		t.Fatalf("failed to generate witness: %v", err)
+	}
+	blob, err := rlp.EncodeToBytes(witness)
+	if err != nil {
+		t.Fatalf("failed to encode witness: %v", err)
+	}
+	decoded := new(Witness)
+	if err := rlp.DecodeBytes(blob, decoded); err != nil {
+		t.Fatalf("failed to decode witness: %v", err)
+	}
+	if err := VerifyWitness(config, dpos.New(), block, decoded); err != nil {
+		t.Fatalf("failed to verify witness: %v", err)
+	}
+	if len(decoded.Nodes) == 0 || len(decoded.Headers) == 0 {
+		t.Fatalf("witness incomplete: %d nodes, %d headers", len(decoded.Nodes), len(decoded.Headers))
+	}
+	// A witness missing any node must not verify
+	for i := range decoded.Nodes {
+		incomplete := *decoded
+		incomplete.Nodes = append(append([][]byte{}, decoded.Nodes[:i]...), decoded.Nodes[i+1:]...)
+		if err := VerifyWitness(config, dpos.New(), block, &incomplete); err == nil {
+			t.Errorf("witness missing node %d verified", i)
+		}
+	}
+	// A witness must not verify a block with different results
+	forged := types.CopyHeader(header)
+	forged.Root = common.Hash{0x01}
+	if err := VerifyWitness(config, dpos.New(), block.WithSeal(forged), decoded); err == nil {
+		t.Errorf("witness verified block with forged state root")
+	}
+}
+
+// Tests that ancestors are only served by number from a witness if they are
+// linked to the block executed through their parent hashes.
+func TestWitnessHeaderByNumber(t *testing.T) {
+	var headers []*types.Header
+	for i := 0; i < 4; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), Extra: []byte("canonical")}
+		if i > 0 {
+			header.ParentHash = headers[i-1].Hash()
+		}
+		headers = append(headers, header)
+	}
+	forged := &types.Header{Number: big.NewInt(1), Extra: []byte("forged")}
+
+	chain := &witnessChain{parent: headers[3], headers: make(map[common.Hash]*types.Header)}
+	for _, header := range []*types.Header{forged, headers[3], headers[2], headers[1]} {
+		chain.headers[header.Hash()] = header
+	}
+	// The linked headers are served, never the forged one of the same number
+	for number := 1; number <= 3; number++ {
+		if header := chain.GetHeaderByNumber(uint64(number)); header == nil || header.Hash() != headers[number].Hash() {
+			t.Errorf("header #%d mismatch: have %v, want %x", number, header, headers[number].Hash())
+		}
+	}
+	// Headers missing from the witness or beyond the parent are not served
+	if header := chain.GetHeaderByNumber(0); header != nil {
+		t.Errorf("missing header served: %x", header.Hash())
+	}
+	if header := chain.GetHeaderByNumber(4); header != nil {
+		t.Errorf("descendant header served: %x", header.Hash())
+	}
+}
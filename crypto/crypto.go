@@ -73,6 +73,12 @@ func CreateAddress(b common.Address, nonce uint64) common.Address {
 	return common.BytesToAddress(Keccak256(data)[12:])
 }
 
+// CreateAddress2 creates an eminer-pro address given the address bytes, the salt
+// and the hash of the contract initialisation code.
+func CreateAddress2(b common.Address, salt [32]byte, inithash []byte) common.Address {
+	return common.BytesToAddress(Keccak256([]byte{0xff}, b.Bytes(), salt[:], inithash)[12:])
+}
+
 // ToECDSA creates a private key with the given D value.
 func ToECDSA(d []byte) (*ecdsa.PrivateKey, error) {
 	return toECDSA(d, true)
@@ -183,13 +189,48 @@ func ValidateSignatureValues(v byte, r, s *big.Int, homestead bool) bool {
 	}
 	// reject upper range of s values (ECDSA malleability)
 	// see discussion in secp256k1/libsecp256k1/include/secp256k1.h
-	if homestead && s.Cmp(secp256k1_halfN) > 0 {
+	if homestead && !IsLowS(s) {
 		return false
 	}
 	// Frontier: allow s to be in full N range
 	return r.Cmp(secp256k1_N) < 0 && s.Cmp(secp256k1_N) < 0 && (v == 0 || v == 1)
 }
 
+// IsLowS reports whether the s value of a signature lies in the lower half of the
+// curve order. For every signature (r, s) the variant (r, N-s) is valid too, the
+// low-s one is the canonical form of the two.
+func IsLowS(s *big.Int) bool {
+	return s.Cmp(secp256k1_halfN) <= 0
+}
+
+// IsCanonicalSignature reports whether a signature in the [R || S || V] format is
+// in its canonical low-s form.
+func IsCanonicalSignature(sig []byte) bool {
+	return len(sig) == 65 && IsLowS(new(big.Int).SetBytes(sig[32:64]))
+}
+
+// NormalizeSignature converts a signature in the [R || S || V] format into its
+// canonical low-s form, recovering the same public key. Signatures already in
+// canonical form or of an invalid length are returned as is, the input is never
+// modified.
+func NormalizeSignature(sig []byte) []byte {
+	if len(sig) != 65 {
+		return sig
+	}
+	s := new(big.Int).SetBytes(sig[32:64])
+	if IsLowS(s) {
+		return sig
+	}
+	// Negating s flips the parity of the recovered point, so the recovery id too
+	norm := make([]byte, 65)
+	copy(norm[:32], sig[:32])
+	s.Sub(secp256k1_N, s)
+	sb := s.Bytes()
+	copy(norm[64-len(sb):64], sb)
+	norm[64] = sig[64] ^ 1
+	return norm
+}
+
 func PubkeyToAddress(p ecdsa.PublicKey) common.Address {
 	pubBytes := FromECDSAPub(&p)
 	return common.BytesToAddress(Keccak256(pubBytes[1:])[12:])
@@ -122,6 +122,29 @@ func TestNewContractAddress(t *testing.T) {
 	checkAddr(t, common.HexToAddress("c9ddedf451bc62ce88bf9292afb13df35b670699"), caddr2)
 }
 
+func TestCreateAddress2(t *testing.T) {
+	// Test vectors of the salted contract address derivation
+	tests := []struct {
+		origin   string
+		salt     string
+		code     string
+		expected string
+	}{
+		{"0000000000000000000000000000000000000000", "0000000000000000000000000000000000000000000000000000000000000000", "00", "4d1a2e2bb4f88f0250f26ffff098b0b30b26bf38"},
+		{"deadbeef00000000000000000000000000000000", "000000000000000000000000feed000000000000000000000000000000000000", "00", "d04116cdd17bebe565eb2422f2497e06cc1c9833"},
+		{"00000000000000000000000000000000deadbeef", "00000000000000000000000000000000000000000000000000000000cafebabe", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef", "1d8bfdc5d46dc4f61d6b6115972536ebe6a8854c"},
+		{"0000000000000000000000000000000000000000", "0000000000000000000000000000000000000000000000000000000000000000", "", "e33c0c7f7df4809055c3eba6c09cfe4baf1bd9e0"},
+	}
+	for i, tt := range tests {
+		origin := common.BytesToAddress(common.FromHex(tt.origin))
+		salt := common.BytesToHash(common.FromHex(tt.salt))
+		address := CreateAddress2(origin, salt, Keccak256(common.FromHex(tt.code)))
+		if expected := common.BytesToAddress(common.FromHex(tt.expected)); address != expected {
+			t.Errorf("test %d: address mismatch: have %x, want %x", i, address, expected)
+		}
+	}
+}
+
 func TestLoadECDSAFile(t *testing.T) {
 	keyBytes := common.FromHex(testPrivHex)
 	fileName0 := "test_key0"
@@ -203,6 +226,42 @@ func TestValidateSignatureValues(t *testing.T) {
 	check(false, 0, one, minusOne)
 }
 
+func TestNormalizeSignature(t *testing.T) {
+	key, _ := HexToECDSA(testPrivHex)
+	addr := common.HexToAddress(testAddrHex)
+
+	msg := Keccak256([]byte("foo"))
+	sig, err := Sign(msg, key)
+	if err != nil {
+		t.Fatalf("Sign error: %s", err)
+	}
+	if !IsCanonicalSignature(sig) {
+		t.Fatalf("signature not canonical: %x", sig)
+	}
+	// Malleate the signature into its high-s twin, still recovering the signer
+	s := new(big.Int).Sub(secp256k1_N, new(big.Int).SetBytes(sig[32:64]))
+	high := make([]byte, 65)
+	copy(high, sig[:32])
+	copy(high[64-len(s.Bytes()):64], s.Bytes())
+	high[64] = sig[64] ^ 1
+
+	if IsCanonicalSignature(high) {
+		t.Fatalf("high-s signature reported canonical: %x", high)
+	}
+	pub, err := SigToPub(msg, high)
+	if err != nil {
+		t.Fatalf("ECRecover error: %s", err)
+	}
+	checkAddr(t, addr, PubkeyToAddress(*pub))
+
+	if norm := NormalizeSignature(high); !bytes.Equal(norm, sig) {
+		t.Errorf("normalized signature mismatch: have %x, want %x", norm, sig)
+	}
+	if norm := NormalizeSignature(sig); !bytes.Equal(norm, sig) {
+		t.Errorf("canonical signature modified: have %x, want %x", norm, sig)
+	}
+}
+
 func checkhash(t *testing.T, name string, f func([]byte) []byte, msg, exp []byte) {
 	sum := f(msg)
 	if !bytes.Equal(exp, sum) {
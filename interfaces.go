@@ -21,6 +21,7 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"time"
 
 	"github.com/Aurorachain-io/go-aoa/common"
 	"github.com/Aurorachain-io/go-aoa/core/types"
@@ -105,6 +106,9 @@ type SyncProgress struct {
 	HighestBlock  uint64 // Highest alleged block number in the chain
 	PulledStates  uint64 // Number of state trie entries already downloaded
 	KnownStates   uint64 // Total number of state trie entries known about
+
+	Phase     string        // Phase of the running or last synchronisation
+	Remaining time.Duration // Estimated time until the chain is downloaded, zero if unknown
 }
 
 // ChainSyncReader wraps access to the node's current sync status. If there's no
@@ -26,6 +26,7 @@ import (
 
 	"bytes"
 	"github.com/Aurorachain-io/go-aoa/accounts"
+	"github.com/Aurorachain-io/go-aoa/accounts/abi"
 	"github.com/Aurorachain-io/go-aoa/accounts/keystore"
 	aa "github.com/Aurorachain-io/go-aoa/accounts/walletType"
 	"github.com/Aurorachain-io/go-aoa/common"
@@ -33,6 +34,7 @@ import (
 	"github.com/Aurorachain-io/go-aoa/common/math"
 	"github.com/Aurorachain-io/go-aoa/common/ntp"
 	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/state"
 	"github.com/Aurorachain-io/go-aoa/core/types"
 	"github.com/Aurorachain-io/go-aoa/core/vm"
 	"github.com/Aurorachain-io/go-aoa/crypto"
@@ -69,6 +71,37 @@ func (s *PublicDacchainAPI) GasPrice(ctx context.Context) (*big.Int, error) {
 	return s.b.SuggestPrice(ctx)
 }
 
+// feeHistoryResult is the result of an aoa_feeHistory API call.
+type feeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+// FeeHistory returns the gas prices paid at the given percentiles of the gas
+// used in each of the blockCount blocks up to lastBlock, along with the ratio of
+// their gas limits used, letting wallets pick a price instead of overpaying.
+func (s *PublicDacchainAPI) FeeHistory(ctx context.Context, blockCount math.HexOrDecimal64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*feeHistoryResult, error) {
+	oldest, rewards, gasUsedRatio, err := s.b.FeeHistory(ctx, int(blockCount), lastBlock, rewardPercentiles)
+	if err != nil {
+		return nil, err
+	}
+	result := &feeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(oldest),
+		GasUsedRatio: gasUsedRatio,
+	}
+	if rewards != nil {
+		result.Reward = make([][]*hexutil.Big, len(rewards))
+		for i, prices := range rewards {
+			result.Reward[i] = make([]*hexutil.Big, len(prices))
+			for j, price := range prices {
+				result.Reward[i][j] = (*hexutil.Big)(price)
+			}
+		}
+	}
+	return result, nil
+}
+
 // ProtocolVersion returns the current eminer-pro protocol version this node supports
 func (s *PublicDacchainAPI) ProtocolVersion() hexutil.Uint {
 	return hexutil.Uint(s.b.ProtocolVersion())
@@ -81,8 +114,12 @@ func (s *PublicDacchainAPI) ProtocolVersion() hexutil.Uint {
 // - highestBlock:  block number of the highest block header this node has received from peers
 // - pulledStates:  number of state entries processed until now
 // - knownStates:   number of known state entries that still need to be pulled
+// - phase:         phase of the running or last sync cycle
+// - active:        whether a sync cycle is running, a lagging node without one is stalled
+// - remaining:     estimated number of seconds until the chain is downloaded, zero if unknown
 func (s *PublicDacchainAPI) Syncing() (interface{}, error) {
-	progress := s.b.Downloader().Progress()
+	downloader := s.b.Downloader()
+	progress := downloader.Progress()
 
 	// Return not syncing if the synchronisation already completed
 	if progress.CurrentBlock >= progress.HighestBlock {
@@ -95,6 +132,9 @@ func (s *PublicDacchainAPI) Syncing() (interface{}, error) {
 		"highestBlock":  hexutil.Uint64(progress.HighestBlock),
 		"pulledStates":  hexutil.Uint64(progress.PulledStates),
 		"knownStates":   hexutil.Uint64(progress.KnownStates),
+		"phase":         progress.Phase,
+		"active":        downloader.Synchronising(),
+		"remaining":     hexutil.Uint64(progress.Remaining / time.Second),
 	}, nil
 }
 
@@ -120,7 +160,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range pending {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
 		}
 		content["pending"][account.Hex()] = dump
 	}
@@ -128,13 +168,31 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range queue {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
 		}
 		content["queued"][account.Hex()] = dump
 	}
 	return content
 }
 
+// ContentFrom returns the transactions contained within the transaction pool
+// sent by the given account, keyed by their nonce.
+func (s *PublicTxPoolAPI) ContentFrom(addr common.Address) map[string]map[string]*RPCTransaction {
+	content := map[string]map[string]*RPCTransaction{
+		"pending": make(map[string]*RPCTransaction),
+		"queued":  make(map[string]*RPCTransaction),
+	}
+	pending, queue := s.b.TxPoolContentFrom(addr)
+
+	for _, tx := range pending {
+		content["pending"][fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
+	}
+	for _, tx := range queue {
+		content["queued"][fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
+	}
+	return content
+}
+
 // Status returns the number of pending and queued transaction in the pool.
 func (s *PublicTxPoolAPI) Status() map[string]hexutil.Uint {
 	pending, queue := s.b.Stats()
@@ -668,6 +726,35 @@ func (s *PublicBlockChainAPI) GetBlockByHash(ctx context.Context, blockHash comm
 	return nil, err
 }
 
+// GetHeaderByNumber returns the requested header, without assembling the block
+// body. When blockNr is -1 the chain head is returned. When inclRLP is true the
+// RLP encoding of the header is included.
+func (s *PublicBlockChainAPI) GetHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber, inclRLP *bool) (map[string]interface{}, error) {
+	header, err := s.b.HeaderByNumber(ctx, blockNr)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	response, err := rpcOutputHeader(header, inclRLP != nil && *inclRLP)
+	if err != nil {
+		return nil, err
+	}
+	if blockNr == rpc.PendingBlockNumber {
+		// Pending headers have no final hash yet
+		response["hash"] = nil
+	}
+	return response, nil
+}
+
+// GetHeaderByHash returns the requested header, without assembling the block
+// body. When inclRLP is true the RLP encoding of the header is included.
+func (s *PublicBlockChainAPI) GetHeaderByHash(ctx context.Context, blockHash common.Hash, inclRLP *bool) (map[string]interface{}, error) {
+	header, err := s.b.HeaderByHash(ctx, blockHash)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	return rpcOutputHeader(header, inclRLP != nil && *inclRLP)
+}
+
 // GetCode returns the code stored at the given address in the state for the given block number.
 func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
 	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
@@ -700,6 +787,74 @@ func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.A
 	return res[:], state.Error()
 }
 
+// AccountResult is the merkle proof of an account and a set of its storage
+// slots, modelled after EIP-1186.
+type AccountResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []string        `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	LockBalance  *hexutil.Big    `json:"lockBalance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// StorageResult is the merkle proof of a single storage slot.
+type StorageResult struct {
+	Key   string       `json:"key"`
+	Value *hexutil.Big `json:"value"`
+	Proof []string     `json:"proof"`
+}
+
+// GetProof returns the merkle proof of the account and the given storage keys
+// against the state root of the given block.
+func (s *PublicBlockChainAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*AccountResult, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	storageProof := make([]StorageResult, len(storageKeys))
+	if storageTrie := state.StorageTrie(address); storageTrie != nil {
+		for i, key := range storageKeys {
+			proof, err := state.GetStorageProof(address, common.HexToHash(key))
+			if err != nil {
+				return nil, err
+			}
+			value := state.GetState(address, common.HexToHash(key)).Big()
+			storageProof[i] = StorageResult{key, (*hexutil.Big)(value), toHexSlice(proof)}
+		}
+	} else {
+		// Non-existent account, every slot is empty without a proof
+		for i, key := range storageKeys {
+			storageProof[i] = StorageResult{key, &hexutil.Big{}, []string{}}
+		}
+	}
+	accountProof, err := state.GetProof(address)
+	if err != nil {
+		return nil, err
+	}
+	return &AccountResult{
+		Address:      address,
+		AccountProof: toHexSlice(accountProof),
+		Balance:      (*hexutil.Big)(state.GetBalance(address)),
+		LockBalance:  (*hexutil.Big)(state.GetLockBalance(address)),
+		CodeHash:     state.GetCodeHash(address),
+		Nonce:        hexutil.Uint64(state.GetNonce(address)),
+		StorageHash:  state.GetStorageRoot(address),
+		StorageProof: storageProof,
+	}, state.Error()
+}
+
+// toHexSlice creates a slice of hex-strings based on []byte.
+func toHexSlice(b [][]byte) []string {
+	r := make([]string, len(b))
+	for i := range b {
+		r[i] = hexutil.Encode(b[i])
+	}
+	return r
+}
+
 // CallArgs represents the arguments for a call.
 type CallArgs struct {
 	From       common.Address   `json:"from"`
@@ -716,13 +871,84 @@ type CallArgs struct {
 	Abi        string           `json:"abi"`
 }
 
-func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
+// OverrideAccount indicates the overriding fields of an account during the
+// execution of a message call. State replaces the entire storage of the account,
+// while StateDiff only overrides the given slots; they're mutually exclusive.
+type OverrideAccount struct {
+	Nonce     *hexutil.Uint64              `json:"nonce"`
+	Code      *hexutil.Bytes               `json:"code"`
+	Balance   *hexutil.Big                 `json:"balance"`
+	State     *map[common.Hash]common.Hash `json:"state"`
+	StateDiff *map[common.Hash]common.Hash `json:"stateDiff"`
+}
+
+// StateOverride is the collection of overridden accounts.
+type StateOverride map[common.Address]OverrideAccount
+
+// Apply overrides the fields of the specified accounts in the given state.
+func (diff *StateOverride) Apply(statedb *state.StateDB) error {
+	if diff == nil {
+		return nil
+	}
+	for addr, account := range *diff {
+		if account.Nonce != nil {
+			statedb.SetNonce(addr, uint64(*account.Nonce))
+		}
+		if account.Code != nil {
+			statedb.SetCode(addr, *account.Code)
+		}
+		if account.Balance != nil {
+			statedb.SetBalance(addr, account.Balance.ToInt())
+		}
+		if account.State != nil && account.StateDiff != nil {
+			return fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
+		}
+		if account.State != nil {
+			statedb.SetStorage(addr, *account.State)
+		}
+		if account.StateDiff != nil {
+			for key, value := range *account.StateDiff {
+				statedb.SetState(addr, key, value)
+			}
+		}
+	}
+	return nil
+}
+
+// BlockOverrides is the collection of block context fields overridden during the
+// execution of a message call.
+type BlockOverrides struct {
+	Number *hexutil.Big `json:"number"`
+	Time   *hexutil.Big `json:"time"`
+}
+
+// Apply returns a copy of the given header with the specified fields overridden.
+func (diff *BlockOverrides) Apply(header *types.Header) *types.Header {
+	if diff == nil {
+		return header
+	}
+	header = types.CopyHeader(header)
+	if diff.Number != nil {
+		header.Number = diff.Number.ToInt()
+	}
+	if diff.Time != nil {
+		header.Time = diff.Time.ToInt()
+	}
+	return header
+}
+
+func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride, blockOverrides *BlockOverrides, vmCfg vm.Config, timeout time.Duration) (*core.ExecutionResult, error) {
 	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
 	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
-		return nil, 0, false, err
+		return nil, err
 	}
+	if err := overrides.Apply(state); err != nil {
+		return nil, err
+	}
+	header = blockOverrides.Apply(header)
+
 	// Set sender address or use a default if none specified
 	addr := args.From
 	if addr == (common.Address{}) {
@@ -737,6 +963,10 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	if gas == 0 {
 		gas = 5000000
 	}
+	if cap := s.b.RPCGasCap(); cap != 0 && gas > cap {
+		log.Warn("Caller gas above allowance, capping", "requested", gas, "cap", cap)
+		gas = cap
+	}
 	if gasPrice.Sign() == 0 {
 		gasPrice = new(big.Int).SetUint64(defaultGasPrice)
 	}
@@ -763,7 +993,7 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	// Get a new instance of the EVM.
 	evm, vmError, err := s.b.GetEVM(ctx, msg, state, header, vmCfg)
 	if err != nil {
-		return nil, 0, false, err
+		return nil, err
 	}
 	// Wait for the context to be done and cancel the evm. Even if the
 	// EVM has finished, cancelling may be done (repeatedly)
@@ -775,18 +1005,28 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	// Setup the gas pool (also for unmetered requests)
 	// and apply the message.
 	gp := new(core.GasPool).AddGas(math.MaxUint64)
-	res, gas, failed, err := core.ApplyMessage(evm, msg, gp)
+	result, err := core.ApplyMessageResult(evm, msg, gp)
 	if err := vmError(); err != nil {
-		return nil, 0, false, err
+		return nil, err
+	}
+	// If the timer caused an abort, return an appropriate error message
+	if evm.Cancelled() {
+		return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
 	}
-	return res, gas, failed, err
+	return result, err
 }
 
 // Call executes the given transaction on the state for the given block number.
 // It doesn't make and changes in the state/blockchain and is useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	result, _, _, err := s.doCall(ctx, args, blockNr, vm.Config{}, 5*time.Second)
-	return (hexutil.Bytes)(result), err
+//
+// Optionally, the state of accounts and fields of the block context can be
+// overridden to simulate the call under different conditions.
+func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride, blockOverrides *BlockOverrides) (hexutil.Bytes, error) {
+	result, err := s.doCall(ctx, args, blockNr, overrides, blockOverrides, vm.Config{}, s.b.RPCEVMTimeout())
+	if err != nil {
+		return nil, err
+	}
+	return (hexutil.Bytes)(result.ReturnData), nil
 }
 
 // EstimateGas returns an estimate of the amount of gas needed to execute the
@@ -818,36 +1058,98 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 		}
 		hi = block.GasLimit()
 	}
+	// Never search above the gas cap of RPC calls, the execution would be capped anyway
+	if gasCap := s.b.RPCGasCap(); gasCap != 0 && hi > gasCap {
+		log.Warn("Caller gas above allowance, capping", "requested", hi, "cap", gasCap)
+		hi = gasCap
+	}
 	cap = hi
 
-	// Create a helper to check if a gas allowance results in an executable transaction
-	executable := func(gas uint64) bool {
+	// Create a helper to check if a gas allowance results in an executable transaction.
+	// Running out of gas, even on the intrinsic part, only fails the attempt, any
+	// other error rejects the transaction outright.
+	executable := func(gas uint64) (bool, *core.ExecutionResult, error) {
 		args.Gas = hexutil.Uint64(gas)
 
-		_, _, failed, err := s.doCall(ctx, args, rpc.PendingBlockNumber, vm.Config{}, 0)
-		if err != nil || failed {
-			return false
+		result, err := s.doCall(ctx, args, rpc.PendingBlockNumber, nil, nil, vm.Config{}, s.b.RPCEVMTimeout())
+		if err != nil {
+			if err == vm.ErrOutOfGas {
+				return true, nil, nil
+			}
+			return true, nil, err
+		}
+		return result.Failed(), result, nil
+	}
+	// Execute at the highest allowance first, rejecting transactions that can't
+	// succeed with any amount of gas. The gas used is a lower bound of the limit
+	// needed, as refunds only ever lower it.
+	failed, result, err := executable(hi)
+	if err != nil {
+		return 0, err
+	}
+	if failed {
+		if result != nil && result.Err != vm.ErrOutOfGas {
+			return 0, newRevertError(result)
 		}
-		return true
+		return 0, fmt.Errorf("gas required exceeds allowance (%d)", cap)
+	}
+	if result.UsedGas > lo+1 {
+		lo = result.UsedGas - 1
 	}
 	// Execute the binary search and hone in on an executable gas limit
 	for lo+1 < hi {
 		mid := (hi + lo) / 2
-		if !executable(mid) {
+		failed, _, err := executable(mid)
+		if err != nil {
+			return 0, err
+		}
+		if failed {
 			lo = mid
 		} else {
 			hi = mid
 		}
 	}
-	// Reject the transaction as invalid if it still fails at the highest allowance
-	if hi == cap {
-		if !executable(hi) {
-			return 0, fmt.Errorf("gas required exceeds allowance or always failing transaction")
-		}
-	}
 	return hexutil.Uint64(hi), nil
 }
 
+// accessListResult is the result of an aoa_createAccessList API call.
+type accessListResult struct {
+	Accesslist *types.AccessList `json:"accessList"`
+	Error      string            `json:"error,omitempty"`
+	GasUsed    hexutil.Uint64    `json:"gasUsed"`
+}
+
+// CreateAccessList executes the given transaction on the state for the given
+// block number, returning the accounts and storage slots it accesses along with
+// the gas it uses. A failing execution is reported in the result, as the list
+// of accesses made until the failure is still meaningful.
+func (s *PublicBlockChainAPI) CreateAccessList(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (*accessListResult, error) {
+	tracer := vm.NewAccessListTracer()
+
+	result, err := s.doCall(ctx, args, blockNr, nil, nil, vm.Config{Debug: true, Tracer: tracer}, s.b.RPCEVMTimeout())
+	if err != nil {
+		return nil, err
+	}
+	list := tracer.AccessList()
+	res := &accessListResult{Accesslist: &list, GasUsed: hexutil.Uint64(result.UsedGas)}
+	if result.Failed() {
+		res.Error = newRevertError(result).Error()
+	}
+	return res, nil
+}
+
+// newRevertError creates the error rejecting a transaction whose execution
+// failed, including the reason given to a revert if it can be decoded.
+func newRevertError(result *core.ExecutionResult) error {
+	if result.Err != vm.ErrExecutionReverted {
+		return fmt.Errorf("execution failed: %v", result.Err)
+	}
+	if reason, ok := abi.UnpackRevert(result.Revert()); ok {
+		return fmt.Errorf("execution reverted: %v", reason)
+	}
+	return errors.New("execution reverted")
+}
+
 //
 func (s *PublicBlockChainAPI) GetAssetInfo(ctx context.Context, asset common.Address) (*types.AssetInfo, error) {
 	state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.BlockNumber(s.b.CurrentBlock().Number().Int64()))
@@ -919,21 +1221,18 @@ func FormatLogs(logs []vm.StructLog) []StructLogRes {
 	return formatted
 }
 
-// rpcOutputBlock converts the given block to the RPC output which depends on fullTx. If inclTx is true transactions are
-// returned. When fullTx is true the returned block contains full transaction details, otherwise it will only contain
-// transaction hashes.
-func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx bool) (map[string]interface{}, error) {
-	head := b.Header() // copies the header once
+// rpcOutputFields converts the given header to the RPC output fields shared by
+// blocks and headers.
+func rpcOutputFields(head *types.Header, hash common.Hash) map[string]interface{} {
 	fields := map[string]interface{}{
 		"number":     (*hexutil.Big)(head.Number),
-		"hash":       b.Hash(),
+		"hash":       hash,
 		"parentHash": head.ParentHash,
 		// "logsBloom": head.Bloom,
 		"stateRoot":               head.Root,
 		"delegateRoot":            head.DelegateRoot,
 		"validatorAddress":        head.Coinbase,
 		"extraData":               hexutil.Bytes(head.Extra),
-		"size":                    hexutil.Uint64(uint64(b.Size().Int64())),
 		"gasLimit":                hexutil.Uint64(head.GasLimit),
 		"gasUsed":                 hexutil.Uint64(head.GasUsed),
 		"timestamp":               (*hexutil.Big)(head.Time),
@@ -943,6 +1242,32 @@ func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx
 		"shuffleDelegateListHash": head.ShuffleHash,
 		"shuffleBlockNumber":      (*hexutil.Big)(head.ShuffleBlockNumber),
 	}
+	if root, ok := head.CheckpointRoot(); ok {
+		fields["checkpointRoot"] = root
+	}
+	return fields
+}
+
+// rpcOutputHeader converts the given header to the RPC output. If inclRLP is true
+// the RLP encoding of the header is included.
+func rpcOutputHeader(head *types.Header, inclRLP bool) (map[string]interface{}, error) {
+	fields := rpcOutputFields(head, head.Hash())
+	if inclRLP {
+		blob, err := rlp.EncodeToBytes(head)
+		if err != nil {
+			return nil, err
+		}
+		fields["rlp"] = hexutil.Bytes(blob)
+	}
+	return fields, nil
+}
+
+// rpcOutputBlock converts the given block to the RPC output which depends on fullTx. If inclTx is true transactions are
+// returned. When fullTx is true the returned block contains full transaction details, otherwise it will only contain
+// transaction hashes.
+func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx bool) (map[string]interface{}, error) {
+	fields := rpcOutputFields(b.Header(), b.Hash()) // copies the header once
+	fields["size"] = hexutil.Uint64(uint64(b.Size().Int64()))
 
 	if inclTx {
 		formatTx := func(tx *types.Transaction) (interface{}, error) {
@@ -992,6 +1317,7 @@ type RPCTransaction struct {
 	AssetInfo        *SendTxAssetInfo `json:"assetInfo,omitempty"`
 	SubAddress       string           `json:"subAddress,omitempty"`
 	Abi              string           `json:"abi,omitempty"`
+	FeePayer         *common.Address  `json:"feePayer,omitempty"`
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
@@ -1039,11 +1365,12 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 	}
 	result.SubAddress = tx.SubAddress()
 	result.Abi = tx.Abi()
+	result.FeePayer = tx.FeePayer()
 	return result
 }
 
-// newRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
-func newRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
+// NewRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
+func NewRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
 	return newRPCTransaction(tx, common.Hash{}, 0, 0)
 }
 
@@ -1168,7 +1495,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, has
 	}
 	// No finalized transaction, try to retrieve it from the pool
 	if tx := s.b.GetPoolTransaction(hash); tx != nil {
-		return newRPCPendingTransaction(tx)
+		return NewRPCPendingTransaction(tx)
 	}
 	// Transaction unknown, return as such
 	return nil
@@ -1268,6 +1595,28 @@ func (s *PublicTransactionPoolAPI) sign(addr common.Address, tx *types.Transacti
 	return wallet.SignTx(account, tx, chainID)
 }
 
+// signFeePayer signs the transaction as its fee payer, which must be an account
+// of this node.
+func (s *PublicTransactionPoolAPI) signFeePayer(tx *types.Transaction) (*types.Transaction, error) {
+	payer := tx.FeePayer()
+	if payer == nil {
+		return nil, errors.New("transaction has no fee payer")
+	}
+	account := accounts.Account{Address: *payer}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	signer := types.MakeSigner(s.b.ChainConfig(), s.b.CurrentBlock().Number())
+	hash := signer.FeePayerHash(tx)
+	sig, err := wallet.SignHash(account, hash[:])
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithFeePayerSignature(signer, sig)
+}
+
 // SendTxArgs represents the arguments to sumbit a new transaction into the transaction pool.
 type SendTxArgs struct {
 	From     common.Address  `json:"from"`
@@ -1287,6 +1636,7 @@ type SendTxArgs struct {
 	AssetInfo  *SendTxAssetInfo `json:"assetInfo,omitempty"`
 	SubAddress string           `json:"subAddress,omitempty"`
 	Abi        string           `json:"abi,omitempty"`
+	FeePayer   *common.Address  `json:"feePayer,omitempty"`
 }
 
 type SendTxAssetInfo struct {
@@ -1477,6 +1827,14 @@ func sliceContains(address common.Address, slice []common.Address) (int, bool) {
 }
 
 func (args *SendTxArgs) toTransaction() (*types.Transaction, error) {
+	tx, err := args.toUnsponsoredTransaction()
+	if err != nil || args.FeePayer == nil {
+		return tx, err
+	}
+	return tx.WithFeePayer(*args.FeePayer), nil
+}
+
+func (args *SendTxArgs) toUnsponsoredTransaction() (*types.Transaction, error) {
 	var input []byte
 	if args.Data != nil {
 		input = *args.Data
@@ -1570,6 +1928,11 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return common.Hash{}, err
 	}
+	if args.FeePayer != nil {
+		if signed, err = s.signFeePayer(signed); err != nil {
+			return common.Hash{}, err
+		}
+	}
 	return submitTransaction(ctx, s.b, signed)
 }
 
@@ -1580,7 +1943,34 @@ func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encod
 	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
 		return common.Hash{}, err
 	}
-	return submitTransaction(ctx, s.b, tx)
+	hash, err := submitTransaction(ctx, s.b, tx)
+	if err == core.ErrCrossChainReplay {
+		return common.Hash{}, fmt.Errorf("%v: have chain id %v, want %v", err, tx.ChainId(), s.b.ChainConfig().ChainId)
+	}
+	return hash, err
+}
+
+// SendTransactions admits a nonce ordered sequence of signed transactions of a
+// single sender into the transaction pool, either all of them or none, and
+// returns their hashes. The sequence must continue right after the sender's
+// pending nonce.
+func (s *PublicTransactionPoolAPI) SendTransactions(ctx context.Context, encodedTxs []hexutil.Bytes) ([]common.Hash, error) {
+	txs := make([]*types.Transaction, len(encodedTxs))
+	for i, encodedTx := range encodedTxs {
+		txs[i] = new(types.Transaction)
+		if err := rlp.DecodeBytes(encodedTx, txs[i]); err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+	}
+	if err := s.b.SendTxs(ctx, txs); err != nil {
+		return nil, err
+	}
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	log.Debug("Submitted transaction sequence", "count", len(txs))
+	return hashes, nil
 }
 
 // Sign calculates an ECDSA signature for:
@@ -1644,6 +2034,30 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 	return &SignTransactionResult{data, signedTx}, nil
 }
 
+// SignTransactionAsFeePayer signs the given transaction, already signed by its
+// sender, as its fee payer. The node needs to have the private key of the fee
+// payer and it needs to be unlocked. The returned transaction is ready to be
+// submitted with sendRawTransaction.
+func (s *PublicTransactionPoolAPI) SignTransactionAsFeePayer(ctx context.Context, encodedTx hexutil.Bytes) (*SignTransactionResult, error) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+		return nil, err
+	}
+	signer := types.MakeSigner(s.b.ChainConfig(), s.b.CurrentBlock().Number())
+	if _, err := types.Sender(signer, tx); err != nil {
+		return nil, fmt.Errorf("invalid sender signature: %v", err)
+	}
+	signedTx, err := s.signFeePayer(tx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := rlp.EncodeToBytes(signedTx)
+	if err != nil {
+		return nil, err
+	}
+	return &SignTransactionResult{data, signedTx}, nil
+}
+
 // PendingTransactions returns the transactions that are in the transaction pool and have a from address that is one of
 // the accounts this node manages.
 func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, error) {
@@ -1658,7 +2072,7 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 
 		from, _ := types.Sender(signer, tx)
 		if _, err := s.b.AccountManager().Find(accounts.Account{Address: from}); err == nil {
-			transactions = append(transactions, newRPCPendingTransaction(tx))
+			transactions = append(transactions, NewRPCPendingTransaction(tx))
 		}
 	}
 	return transactions, nil
@@ -1738,6 +2152,73 @@ func (api *PublicDebugAPI) GetBlockRlp(ctx context.Context, number uint64) (stri
 	return fmt.Sprintf("%x", encoded), nil
 }
 
+// GetRawHeader retrieves the RLP encoding of a single header, exactly as stored
+// in the database.
+func (api *PublicDebugAPI) GetRawHeader(ctx context.Context, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+	header, _ := api.b.HeaderByNumber(ctx, blockNr)
+	if header == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	if blob := core.GetHeaderRLP(api.b.ChainDb(), header.Hash(), header.Number.Uint64()); len(blob) > 0 {
+		return hexutil.Bytes(blob), nil
+	}
+	// Pending headers aren't stored, encode them instead
+	return rlp.EncodeToBytes(header)
+}
+
+// GetRawBlock retrieves the RLP encoding of a single block, assembled from the
+// header and body exactly as stored in the database.
+func (api *PublicDebugAPI) GetRawBlock(ctx context.Context, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+	header, _ := api.b.HeaderByNumber(ctx, blockNr)
+	if header == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	if blob := core.GetBlockRLP(api.b.ChainDb(), header.Hash(), header.Number.Uint64()); len(blob) > 0 {
+		return hexutil.Bytes(blob), nil
+	}
+	// Pending blocks aren't stored, encode them instead
+	block, _ := api.b.BlockByNumber(ctx, blockNr)
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	return rlp.EncodeToBytes(block)
+}
+
+// GetRawTransaction retrieves the RLP encoding of a single transaction, exactly
+// as stored in the body of its block, or as pooled if it's not yet included.
+func (api *PublicDebugAPI) GetRawTransaction(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
+	if blob := core.GetTransactionRLP(api.b.ChainDb(), hash); len(blob) > 0 {
+		return hexutil.Bytes(blob), nil
+	}
+	tx := api.b.GetPoolTransaction(hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %#x not found", hash)
+	}
+	return rlp.EncodeToBytes(tx)
+}
+
+// GetRawReceipts retrieves the consensus RLP encodings of the receipts of all
+// the transactions in a single block, as hashed into its receipt root.
+func (api *PublicDebugAPI) GetRawReceipts(ctx context.Context, blockNr rpc.BlockNumber) ([]hexutil.Bytes, error) {
+	header, _ := api.b.HeaderByNumber(ctx, blockNr)
+	if header == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	receipts, err := api.b.GetReceipts(ctx, header.Hash())
+	if err != nil {
+		return nil, err
+	}
+	result := make([]hexutil.Bytes, len(receipts))
+	for i, receipt := range receipts {
+		blob, err := rlp.EncodeToBytes(receipt)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = blob
+	}
+	return result, nil
+}
+
 // PrintBlock retrieves a block and returns its pretty printed form.
 func (api *PublicDebugAPI) PrintBlock(ctx context.Context, number uint64) (string, error) {
 	block, _ := api.b.BlockByNumber(ctx, rpc.BlockNumber(number))
@@ -0,0 +1,98 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoaapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/rpc"
+)
+
+// headerTestBackend is a backend serving a single header. Methods not
+// overridden panic if called.
+type headerTestBackend struct {
+	Backend
+	header *types.Header
+}
+
+func (b *headerTestBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber || uint64(blockNr) == b.header.Number.Uint64() {
+		return b.header, nil
+	}
+	return nil, nil
+}
+
+func (b *headerTestBackend) HeaderByHash(ctx context.Context, blockHash common.Hash) (*types.Header, error) {
+	if b.header.Hash() == blockHash {
+		return b.header, nil
+	}
+	return nil, nil
+}
+
+// Tests that headers are served by number and hash, and that nonexistent ones
+// are returned as null instead of a header with empty fields.
+func TestGetHeader(t *testing.T) {
+	header := &types.Header{Number: big.NewInt(7), Time: big.NewInt(1000)}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("aoa", NewPublicBlockChainAPI(&headerTestBackend{header: header})); err != nil {
+		t.Fatalf("failed to register API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	tests := []struct {
+		method string
+		arg    interface{}
+		exist  bool
+	}{
+		{"aoa_getHeaderByNumber", "0x7", true},
+		{"aoa_getHeaderByNumber", "0x8", false},
+		{"aoa_getHeaderByHash", header.Hash(), true},
+		{"aoa_getHeaderByHash", common.Hash{0x01}, false},
+	}
+	for i, tt := range tests {
+		var result map[string]interface{}
+		if err := client.Call(&result, tt.method, tt.arg); err != nil {
+			t.Fatalf("test %d: failed to call %s: %v", i, tt.method, err)
+		}
+		if !tt.exist {
+			if result != nil {
+				t.Errorf("test %d: nonexistent header mismatch: have %v, want nil", i, result)
+			}
+			continue
+		}
+		if hash := result["hash"]; hash != header.Hash().Hex() {
+			t.Errorf("test %d: hash mismatch: have %v, want %x", i, hash, header.Hash())
+		}
+	}
+	// Pending headers hide the hash not known yet, without adding other fields
+	fields, err := NewPublicBlockChainAPI(&headerTestBackend{header: header}).GetHeaderByNumber(context.Background(), rpc.PendingBlockNumber, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve pending header: %v", err)
+	}
+	if hash, ok := fields["hash"]; !ok || hash != nil {
+		t.Errorf("pending header hash not cleared: %v", hash)
+	}
+	if len(fields) != len(rpcOutputFields(header, header.Hash())) {
+		t.Errorf("pending header field count mismatch: have %d, want %d", len(fields), len(rpcOutputFields(header, header.Hash())))
+	}
+}
@@ -20,6 +20,7 @@ package aoaapi
 import (
 	"context"
 	"math/big"
+	"time"
 
 	"github.com/Aurorachain-io/go-aoa/accounts"
 	aa "github.com/Aurorachain-io/go-aoa/accounts/walletType"
@@ -43,6 +44,7 @@ type Backend interface {
 	Downloader() *downloader.Downloader
 	ProtocolVersion() int
 	SuggestPrice(ctx context.Context) (*big.Int, error)
+	FeeHistory(ctx context.Context, blockCount int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []float64, error)
 	ChainDb() aoadb.Database
 	AccountManager() *accounts.Manager
 	GetDelegateWalletInfoCallback() func(data *aa.DelegateWalletInfo)
@@ -50,6 +52,7 @@ type Backend interface {
 	// BlockChain API
 	SetHead(number uint64)
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
+	HeaderByHash(ctx context.Context, blockHash common.Hash) (*types.Header, error)
 	BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error)
 	StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error)
 	GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error)
@@ -63,15 +66,19 @@ type Backend interface {
 
 	// TxPool API
 	SendTx(ctx context.Context, signedTx *types.Transaction) error
+	SendTxs(ctx context.Context, signedTxs []*types.Transaction) error
 	GetPoolTransactions() (types.Transactions, error)
 	GetPoolTransaction(txHash common.Hash) *types.Transaction
 	GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error)
 	Stats() (pending int, queued int)
 	TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions)
+	TxPoolContentFrom(addr common.Address) (types.Transactions, types.Transactions)
 	SubscribeTxPreEvent(chan<- core.TxPreEvent) event.Subscription
 
 	ChainConfig() *params.ChainConfig
 	CurrentBlock() *types.Block
+	RPCGasCap() uint64            // Gas cap of the calls executed over RPC, DoS protection
+	RPCEVMTimeout() time.Duration // Time limit of the calls executed over RPC, DoS protection
 
 	// inner transactions watcher
 	IsWatchInnerTxEnable() bool
@@ -0,0 +1,169 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoaapi
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/common/hexutil"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/rpc"
+)
+
+// ethEmptyUncleHash is the uncle hash of Ethereum blocks without uncles, which
+// all blocks of the delegated chain are.
+var ethEmptyUncleHash = common.HexToHash("0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347")
+
+// aoaAddressPattern matches the JSON encoding of AOA prefixed addresses.
+var aoaAddressPattern = regexp.MustCompile(`"(?:AOA|aoa)([0-9a-fA-F]{40})"`)
+
+// EthCompatAPIs aliases all the services of the aoa namespace under the eth
+// namespace, so that Ethereum tooling can talk to the node unmodified. Results
+// of the aliased methods use 0x prefixed addresses, and blocks and receipts are
+// extended with the fields of the standard Ethereum shapes.
+func EthCompatAPIs(b Backend, apis []rpc.API) []rpc.API {
+	var aliases []rpc.API
+	for _, api := range apis {
+		if api.Namespace == "aoa" {
+			aliases = append(aliases, rpc.API{
+				Namespace: "eth",
+				Version:   api.Version,
+				Service:   api.Service,
+				Public:    api.Public,
+				Filter:    EthResultFilter,
+			})
+		}
+	}
+	// Register the reshaping methods last to override the aliased ones
+	aliases = append(aliases, rpc.API{
+		Namespace: "eth",
+		Version:   "1.0",
+		Service:   NewPublicEthCompatAPI(b),
+		Public:    true,
+		Filter:    EthResultFilter,
+	})
+	return append(apis, aliases...)
+}
+
+// EthResultFilter re-encodes an RPC result with the AOA prefixed addresses in
+// it replaced by 0x prefixed ones.
+func EthResultFilter(result interface{}) (interface{}, error) {
+	blob, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(aoaAddressPattern.ReplaceAll(blob, []byte(`"0x$1"`))), nil
+}
+
+// PublicEthCompatAPI provides the eth namespace methods whose results differ in
+// shape from their aoa counterparts, or which have no counterpart at all.
+type PublicEthCompatAPI struct {
+	b     Backend
+	chain *PublicBlockChainAPI
+	txs   *PublicTransactionPoolAPI
+}
+
+// NewPublicEthCompatAPI creates a new Ethereum compatible API.
+func NewPublicEthCompatAPI(b Backend) *PublicEthCompatAPI {
+	return &PublicEthCompatAPI{
+		b:     b,
+		chain: NewPublicBlockChainAPI(b),
+		txs:   NewPublicTransactionPoolAPI(b, new(AddrLocker)),
+	}
+}
+
+// ChainId returns the chain id used for replay protected transaction signing.
+func (s *PublicEthCompatAPI) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(s.b.ChainConfig().ChainId)
+}
+
+// GetBlockByNumber returns the requested block in the Ethereum block shape.
+func (s *PublicEthCompatAPI) GetBlockByNumber(ctx context.Context, blockNr rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
+	block, err := s.b.BlockByNumber(ctx, blockNr)
+	if block == nil {
+		return nil, err
+	}
+	fields, err := s.ethBlock(block, fullTx)
+	if err == nil && blockNr == rpc.PendingBlockNumber {
+		// Pending blocks need to nil out a few fields
+		for _, field := range []string{"hash", "nonce", "miner"} {
+			fields[field] = nil
+		}
+	}
+	return fields, err
+}
+
+// GetBlockByHash returns the requested block in the Ethereum block shape.
+func (s *PublicEthCompatAPI) GetBlockByHash(ctx context.Context, blockHash common.Hash, fullTx bool) (map[string]interface{}, error) {
+	block, err := s.b.GetBlock(ctx, blockHash)
+	if block == nil {
+		return nil, err
+	}
+	return s.ethBlock(block, fullTx)
+}
+
+// GetUncleCountByBlockNumber returns the number of uncles in a block, which is
+// always zero on the delegated chain.
+func (s *PublicEthCompatAPI) GetUncleCountByBlockNumber(ctx context.Context, blockNr rpc.BlockNumber) *hexutil.Uint {
+	if block, _ := s.b.BlockByNumber(ctx, blockNr); block != nil {
+		return new(hexutil.Uint)
+	}
+	return nil
+}
+
+// GetUncleCountByBlockHash returns the number of uncles in a block, which is
+// always zero on the delegated chain.
+func (s *PublicEthCompatAPI) GetUncleCountByBlockHash(ctx context.Context, blockHash common.Hash) *hexutil.Uint {
+	if block, _ := s.b.GetBlock(ctx, blockHash); block != nil {
+		return new(hexutil.Uint)
+	}
+	return nil
+}
+
+// GetTransactionReceipt returns the receipt of a transaction in the Ethereum
+// receipt shape.
+func (s *PublicEthCompatAPI) GetTransactionReceipt(hash common.Hash) (map[string]interface{}, error) {
+	fields, err := s.txs.GetTransactionReceipt(hash)
+	if fields == nil {
+		return nil, err
+	}
+	fields["type"] = hexutil.Uint(0)
+	return fields, err
+}
+
+// ethBlock converts a block into its aoa RPC output, extended with the fields of
+// the Ethereum block shape.
+func (s *PublicEthCompatAPI) ethBlock(block *types.Block, fullTx bool) (map[string]interface{}, error) {
+	fields, err := s.chain.rpcOutputBlock(block, true, fullTx)
+	if err != nil {
+		return nil, err
+	}
+	header := block.Header()
+
+	fields["miner"] = header.Coinbase
+	fields["logsBloom"] = header.Bloom
+	fields["sha3Uncles"] = ethEmptyUncleHash
+	fields["uncles"] = []common.Hash{}
+	fields["nonce"] = types.BlockNonce{}
+	fields["mixHash"] = common.Hash{}
+	fields["difficulty"] = (*hexutil.Big)(common.Big0)
+	fields["totalDifficulty"] = (*hexutil.Big)(common.Big0)
+	return fields, nil
+}
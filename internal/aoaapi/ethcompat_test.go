@@ -0,0 +1,135 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoaapi
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/rpc"
+)
+
+// ethCompatTestBackend is a backend serving a single block to the Ethereum
+// compatible API. Methods not overridden panic if called.
+type ethCompatTestBackend struct {
+	Backend
+	block *types.Block
+}
+
+func (b *ethCompatTestBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
+	return b.block, nil
+}
+
+func (b *ethCompatTestBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
+	if b.block.Hash() == blockHash {
+		return b.block, nil
+	}
+	return nil, nil
+}
+
+// Tests that AOA prefixed addresses are rewritten to 0x prefixed ones anywhere
+// within a result, while other strings are left alone.
+func TestEthResultFilter(t *testing.T) {
+	addr := common.HexToAddress("0x00000000000000000000000000000000000000ff")
+	result := map[string]interface{}{
+		"address": addr,
+		"nested":  []interface{}{map[string]interface{}{"to": &addr}},
+		"name":    "AOA",
+	}
+	filtered, err := EthResultFilter(result)
+	if err != nil {
+		t.Fatalf("failed to filter result: %v", err)
+	}
+	have := string(filtered.(json.RawMessage))
+	want := `{"address":"0x00000000000000000000000000000000000000ff","name":"AOA","nested":[{"to":"0x00000000000000000000000000000000000000ff"}]}`
+	if have != want {
+		t.Fatalf("filtered result mismatch:\nhave %s\nwant %s", have, want)
+	}
+}
+
+// Tests that the services of the aoa namespace are aliased under eth with the
+// result filter installed, followed by the reshaping service.
+func TestEthCompatAPIs(t *testing.T) {
+	aoa, net := new(PublicBlockChainAPI), new(PublicNetAPI)
+	apis := EthCompatAPIs(nil, []rpc.API{
+		{Namespace: "aoa", Version: "1.0", Service: aoa, Public: true},
+		{Namespace: "net", Version: "1.0", Service: net, Public: true},
+	})
+	if len(apis) != 4 {
+		t.Fatalf("API count mismatch: have %d, want %d", len(apis), 4)
+	}
+	if apis[0].Service != aoa || apis[0].Filter != nil || apis[1].Service != net || apis[1].Filter != nil {
+		t.Errorf("original APIs modified: %v", apis[:2])
+	}
+	if alias := apis[2]; alias.Namespace != "eth" || alias.Service != aoa || !alias.Public || alias.Filter == nil {
+		t.Errorf("aoa alias mismatch: %+v", alias)
+	}
+	if compat := apis[3]; compat.Namespace != "eth" || compat.Filter == nil {
+		t.Errorf("compat API mismatch: %+v", compat)
+	} else if _, ok := compat.Service.(*PublicEthCompatAPI); !ok {
+		t.Errorf("compat service type mismatch: have %T", compat.Service)
+	}
+}
+
+// Tests that blocks are served in the Ethereum block shape.
+func TestEthCompatBlock(t *testing.T) {
+	miner := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	block := types.NewBlockWithHeader(&types.Header{
+		Number:   big.NewInt(7),
+		Time:     big.NewInt(1000),
+		Coinbase: miner,
+	})
+	api := NewPublicEthCompatAPI(&ethCompatTestBackend{block: block})
+
+	fields, err := api.GetBlockByHash(context.Background(), block.Hash(), false)
+	if err != nil {
+		t.Fatalf("failed to retrieve block: %v", err)
+	}
+	for _, field := range []string{"miner", "logsBloom", "sha3Uncles", "uncles", "nonce", "mixHash", "difficulty", "totalDifficulty", "transactions"} {
+		if _, ok := fields[field]; !ok {
+			t.Errorf("field %q missing", field)
+		}
+	}
+	if fields["miner"] != miner || fields["sha3Uncles"] != ethEmptyUncleHash {
+		t.Errorf("miner or uncle hash mismatch: have %v, %v", fields["miner"], fields["sha3Uncles"])
+	}
+	filtered, err := EthResultFilter(fields)
+	if err != nil {
+		t.Fatalf("failed to filter block: %v", err)
+	}
+	if blob := string(filtered.(json.RawMessage)); !strings.Contains(blob, `"miner":"0x00000000000000000000000000000000000000aa"`) {
+		t.Errorf("miner not rewritten: %s", blob)
+	}
+	// Pending blocks hide the fields not known yet
+	fields, err = api.GetBlockByNumber(context.Background(), rpc.PendingBlockNumber, false)
+	if err != nil {
+		t.Fatalf("failed to retrieve pending block: %v", err)
+	}
+	for _, field := range []string{"hash", "nonce", "miner"} {
+		if fields[field] != nil {
+			t.Errorf("pending block field %q not cleared: %v", field, fields[field])
+		}
+	}
+	if count := api.GetUncleCountByBlockHash(context.Background(), block.Hash()); count == nil || *count != 0 {
+		t.Errorf("uncle count mismatch: have %v, want 0", count)
+	}
+}
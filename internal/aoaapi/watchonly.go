@@ -0,0 +1,192 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoaapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Aurorachain-io/go-aoa/accounts"
+	"github.com/Aurorachain-io/go-aoa/accounts/watchonly"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/common/hexutil"
+	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/rpc"
+)
+
+// errWatchOnlyUnsupported is returned if the account manager has no watch-only
+// backend registered.
+var errWatchOnlyUnsupported = errors.New("watch-only accounts not supported")
+
+// fetchWatchOnly retrieves the watch-only backend from the account manager.
+func fetchWatchOnly(am *accounts.Manager) (*watchonly.Backend, error) {
+	backends := am.Backends(watchonly.BackendType)
+	if len(backends) == 0 {
+		return nil, errWatchOnlyUnsupported
+	}
+	return backends[0].(*watchonly.Backend), nil
+}
+
+// WatchAccount adds a watch-only address to the account manager. The address
+// holds no key material, but is listed among the accounts of the node.
+func (s *PrivateAccountAPI) WatchAccount(addr common.Address) (common.Address, error) {
+	backend, err := fetchWatchOnly(s.am)
+	if err != nil {
+		return common.Address{}, err
+	}
+	acc, err := backend.Watch(addr)
+	return acc.Address, err
+}
+
+// UnwatchAccount removes a watch-only address from the account manager.
+func (s *PrivateAccountAPI) UnwatchAccount(addr common.Address) error {
+	backend, err := fetchWatchOnly(s.am)
+	if err != nil {
+		return err
+	}
+	return backend.Unwatch(addr)
+}
+
+// accountBalance is the balance of a single account managed by the node.
+type accountBalance struct {
+	Address   common.Address `json:"address"`
+	Balance   *hexutil.Big   `json:"balance"`
+	WatchOnly bool           `json:"watchOnly"`
+}
+
+// ListAccountBalances returns all accounts managed by the node, including the
+// watch-only ones, together with their balances at the given block.
+func (s *PrivateAccountAPI) ListAccountBalances(ctx context.Context, blockNr rpc.BlockNumber) ([]accountBalance, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	balances := make([]accountBalance, 0) // return [] instead of nil if empty
+	for _, wallet := range s.am.Wallets() {
+		watched := wallet.URL().Scheme == watchonly.Scheme
+		for _, account := range wallet.Accounts() {
+			balances = append(balances, accountBalance{
+				Address:   account.Address,
+				Balance:   (*hexutil.Big)(state.GetBalance(account.Address)),
+				WatchOnly: watched,
+			})
+		}
+	}
+	return balances, nil
+}
+
+// watchedTransaction is the notification sent for a transaction in a new block
+// touching a watch-only address.
+type watchedTransaction struct {
+	Address     common.Address  `json:"address"`
+	Hash        common.Hash     `json:"hash"`
+	BlockHash   common.Hash     `json:"blockHash"`
+	BlockNumber hexutil.Uint64  `json:"blockNumber"`
+	From        common.Address  `json:"from"`
+	To          *common.Address `json:"to"`
+	Value       *hexutil.Big    `json:"value"`
+	Incoming    bool            `json:"incoming"`
+	Inner       bool            `json:"inner"` // Whether the transfer is an inner transaction of a contract call
+}
+
+// WatchedTransactions creates a subscription that is triggered for every
+// transaction included in a new canonical block that was sent from or to one of
+// the watch-only addresses. If the node watches inner transactions, transfers
+// made by contract calls are reported too.
+func (s *PrivateAccountAPI) WatchedTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	backend, err := fetchWatchOnly(s.am)
+	if err != nil {
+		return nil, err
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		chainEvents := make(chan core.ChainEvent, 16)
+		chainSub := s.b.SubscribeChainEvent(chainEvents)
+		defer chainSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-chainEvents:
+				for _, note := range s.watchedTransactions(backend, ev.Block) {
+					notifier.Notify(rpcSub.ID, note)
+				}
+			case <-chainSub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// watchedTransactions collects the transfers of a block sent from or to one of
+// the watch-only addresses, including the inner transactions recorded by the
+// inner transaction watcher if enabled.
+func (s *PrivateAccountAPI) watchedTransactions(backend *watchonly.Backend, block *types.Block) []watchedTransaction {
+	var (
+		signer = types.MakeSigner(s.b.ChainConfig(), block.Number())
+		notes  []watchedTransaction
+	)
+	collect := func(note watchedTransaction) {
+		if backend.Watched(note.From) {
+			note.Address = note.From
+			notes = append(notes, note)
+		}
+		if to := note.To; to != nil && *to != note.From && backend.Watched(*to) {
+			note.Address, note.Incoming = *to, true
+			notes = append(notes, note)
+		}
+	}
+	for _, tx := range block.Transactions() {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			continue
+		}
+		note := watchedTransaction{
+			Hash:        tx.Hash(),
+			BlockHash:   block.Hash(),
+			BlockNumber: hexutil.Uint64(block.NumberU64()),
+			From:        from,
+			To:          tx.To(),
+			Value:       (*hexutil.Big)(tx.Value()),
+		}
+		collect(note)
+
+		if !s.b.IsWatchInnerTxEnable() {
+			continue
+		}
+		itxs, err := s.b.GetInnerTxDb().Get(tx.Hash())
+		if err != nil {
+			continue // no inner transactions recorded
+		}
+		for _, itx := range itxs {
+			to := itx.To
+			note.From, note.To, note.Value, note.Inner = itx.From, &to, (*hexutil.Big)(itx.Value), true
+			collect(note)
+		}
+	}
+	return notes
+}
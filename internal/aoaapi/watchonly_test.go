@@ -0,0 +1,109 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package aoaapi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/accounts/watchonly"
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/core/watch"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// watchOnlyTestBackend is a backend serving the chain config and the inner
+// transactions to the watch-only notifications. Methods not overridden panic.
+type watchOnlyTestBackend struct {
+	Backend
+	config *params.ChainConfig
+	inner  bool
+	itxdb  watch.InnerTxDb
+}
+
+func (b *watchOnlyTestBackend) ChainConfig() *params.ChainConfig { return b.config }
+func (b *watchOnlyTestBackend) IsWatchInnerTxEnable() bool       { return b.inner }
+func (b *watchOnlyTestBackend) GetInnerTxDb() watch.InnerTxDb    { return b.itxdb }
+
+// Tests that the transfers of a block touching watch-only addresses are collected,
+// including the inner transactions recorded by the watcher if enabled.
+func TestWatchedTransactions(t *testing.T) {
+	var (
+		config       = &params.ChainConfig{ChainId: big.NewInt(1)}
+		signer       = types.MakeSigner(config, nil)
+		watched      = common.HexToAddress("0x0a")
+		contract     = common.HexToAddress("0x0c")
+		senderKey, _ = crypto.GenerateKey()
+		sender       = crypto.PubkeyToAddress(senderKey.PublicKey)
+		db, _        = aoadb.NewMemDatabase()
+		backend      = &watchOnlyTestBackend{config: config, itxdb: watch.NewInnerTxDb(db)}
+	)
+	watcher, _ := watchonly.NewBackend("")
+	if _, err := watcher.Watch(watched); err != nil {
+		t.Fatalf("failed to watch address: %v", err)
+	}
+	if _, err := watcher.Watch(sender); err != nil {
+		t.Fatalf("failed to watch address: %v", err)
+	}
+	transfer := func(nonce uint64, to common.Address) *types.Transaction {
+		tx := types.NewTransaction(nonce, to, big.NewInt(1), 100000, big.NewInt(1), nil, types.ActionTrans, nil, "")
+		signed, err := types.SignTx(tx, signer, senderKey)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		return signed
+	}
+	txs := types.Transactions{transfer(0, watched), transfer(1, contract)}
+	backend.itxdb.Set(txs[1].Hash(), []*types.InnerTx{{From: contract, To: watched, Value: big.NewInt(5)}})
+
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1)}, txs, nil)
+	api := &PrivateAccountAPI{b: backend}
+
+	type want struct {
+		address  common.Address
+		hash     common.Hash
+		incoming bool
+		inner    bool
+	}
+	check := func(notes []watchedTransaction, wants []want) {
+		if len(notes) != len(wants) {
+			t.Fatalf("notification count mismatch: have %d, want %d", len(notes), len(wants))
+		}
+		for i, note := range notes {
+			if have := (want{note.Address, note.Hash, note.Incoming, note.Inner}); have != wants[i] {
+				t.Errorf("notification %d mismatch: have %+v, want %+v", i, have, wants[i])
+			}
+		}
+	}
+	// Without inner transaction watching only the direct transfers are reported
+	check(api.watchedTransactions(watcher, block), []want{
+		{sender, txs[0].Hash(), false, false},
+		{watched, txs[0].Hash(), true, false},
+		{sender, txs[1].Hash(), false, false},
+	})
+	// With inner transaction watching the transfer of the contract is reported
+	backend.inner = true
+	check(api.watchedTransactions(watcher, block), []want{
+		{sender, txs[0].Hash(), false, false},
+		{watched, txs[0].Hash(), true, false},
+		{sender, txs[1].Hash(), false, false},
+		{watched, txs[1].Hash(), true, true},
+	})
+}
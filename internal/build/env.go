@@ -20,7 +20,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -38,19 +40,28 @@ type Environment struct {
 	Name                string // name of the environment
 	Repo                string // name of GitHub repo
 	Commit, Branch, Tag string // Git info
+	Date                string // Commit date of Commit as YYYYMMDD
 	Buildnum            string
 	IsPullRequest       bool
 	IsCronJob           bool
 }
 
 func (env Environment) String() string {
-	return fmt.Sprintf("%s env (commit:%s branch:%s tag:%s buildnum:%s pr:%t)",
-		env.Name, env.Commit, env.Branch, env.Tag, env.Buildnum, env.IsPullRequest)
+	return fmt.Sprintf("%s env (commit:%s date:%s branch:%s tag:%s buildnum:%s pr:%t)",
+		env.Name, env.Commit, env.Date, env.Branch, env.Tag, env.Buildnum, env.IsPullRequest)
 }
 
 // Env returns metadata about the current CI environment, falling back to LocalEnv
 // if not running on CI.
 func Env() Environment {
+	env := ciEnv()
+	if env.Commit != "" && env.Date == "" {
+		env.Date = commitDate(env.Commit)
+	}
+	return env
+}
+
+func ciEnv() Environment {
 	switch {
 	case os.Getenv("CI") == "true" && os.Getenv("TRAVIS") == "true":
 		return Environment{
@@ -103,6 +114,16 @@ func LocalEnv() Environment {
 	return env
 }
 
+// commitDate returns the committer date of the given commit as YYYYMMDD in UTC.
+// Unlike the build time it is the same for every build of the revision.
+func commitDate(commit string) string {
+	stamp, err := strconv.ParseInt(firstLine(RunGit("show", "-s", "--format=%ct", commit)), 10, 64)
+	if err != nil {
+		return ""
+	}
+	return time.Unix(stamp, 0).UTC().Format("20060102")
+}
+
 func firstLine(s string) string {
 	return strings.Split(s, "\n")[0]
 }
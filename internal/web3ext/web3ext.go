@@ -22,10 +22,13 @@ var Modules = map[string]string{
 	"chequebook": Chequebook_JS,
 	"clique":     Clique_JS,
 	"debug":      Debug_JS,
+	"delegate":   Delegate_JS,
 	"aoa":         AOA_JS,
+	"miner":      Miner_JS,
 	"net":        Net_JS,
 	"personal":   Personal_JS,
 	"rpc":        RPC_JS,
+	"scheduler":  Scheduler_JS,
 	"shh":        Shh_JS,
 	"swarmfs":    SWARMFS_JS,
 	"txpool":     TxPool_JS,
@@ -126,6 +129,16 @@ web3._extend({
 			call: 'admin_removePeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'addTrustedPeer',
+			call: 'admin_addTrustedPeer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'removeTrustedPeer',
+			call: 'admin_removeTrustedPeer',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -137,11 +150,31 @@ web3._extend({
 			call: 'admin_importChain',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'dumpTxPool',
+			call: 'admin_dumpTxPool',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'loadTxPool',
+			call: 'admin_loadTxPool',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'startHTTP',
+			call: 'admin_startHTTP',
+			params: 5,
+			inputFormatter: [null, null, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'stopHTTP',
+			call: 'admin_stopHTTP'
+		}),
 		new web3._extend.Method({
 			name: 'startRPC',
 			call: 'admin_startRPC',
@@ -162,6 +195,14 @@ web3._extend({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new web3._extend.Method({
+			name: 'startIPC',
+			call: 'admin_startIPC'
+		}),
+		new web3._extend.Method({
+			name: 'stopIPC',
+			call: 'admin_stopIPC'
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -172,10 +213,42 @@ web3._extend({
 			name: 'peers',
 			getter: 'admin_peers'
 		}),
+		new web3._extend.Property({
+			name: 'rpcEndpoints',
+			getter: 'admin_rpcEndpoints'
+		}),
 		new web3._extend.Property({
 			name: 'datadir',
 			getter: 'admin_datadir'
 		}),
+		new web3._extend.Method({
+			name: 'pauseIndexing',
+			call: 'admin_pauseIndexing',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'resumeIndexing',
+			call: 'admin_resumeIndexing',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Property({
+			name: 'syncPeers',
+			getter: 'admin_syncPeers'
+		}),
+		new web3._extend.Property({
+			name: 'indexProgress',
+			getter: 'admin_indexProgress'
+		}),
+		new web3._extend.Property({
+			name: 'versionInfo',
+			getter: 'admin_versionInfo'
+		}),
+		new web3._extend.Property({
+			name: 'panics',
+			getter: 'admin_panics'
+		}),
 	]
 });
 `
@@ -194,6 +267,29 @@ web3._extend({
 			call: 'debug_getBlockRlp',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getRawHeader',
+			call: 'debug_getRawHeader',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getRawBlock',
+			call: 'debug_getRawBlock',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getRawTransaction',
+			call: 'debug_getRawTransaction',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getRawReceipts',
+			call: 'debug_getRawReceipts',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'setHead',
 			call: 'debug_setHead',
@@ -209,6 +305,12 @@ web3._extend({
 			call: 'debug_dumpBlock',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'accountRange',
+			call: 'debug_accountRange',
+			params: 5,
+			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter, null, null, null, null]
+		}),
 		new web3._extend.Method({
 			name: 'chaindbProperty',
 			call: 'debug_chaindbProperty',
@@ -377,11 +479,143 @@ web3._extend({
 			name: 'getTranTypeNum',
 			call: 'debug_getTranTypeNum',
 		}),
+		new web3._extend.Method({
+			name: 'getBlockWitness',
+			call: 'debug_getBlockWitness',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'verifyBlockWitness',
+			call: 'debug_verifyBlockWitness',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'overlayCreate',
+			call: 'debug_overlayCreate',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'overlayDrop',
+			call: 'debug_overlayDrop',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'overlayInsertBlock',
+			call: 'debug_overlayInsertBlock',
+			params: 4,
+			inputFormatter: [null, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'overlayGetBlockByNumber',
+			call: 'debug_overlayGetBlockByNumber',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'overlayGetBalance',
+			call: 'debug_overlayGetBalance',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter],
+			outputFormatter: web3._extend.utils.toBigNumber
+		}),
+		new web3._extend.Method({
+			name: 'overlayGetTransactionCount',
+			call: 'debug_overlayGetTransactionCount',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter],
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Method({
+			name: 'overlayGetCode',
+			call: 'debug_overlayGetCode',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'overlayGetStorageAt',
+			call: 'debug_overlayGetStorageAt',
+			params: 4,
+			inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'overlayCall',
+			call: 'debug_overlayCall',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputCallFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 	],
 	properties: []
 });
 `
 
+const Delegate_JS = `
+web3._extend({
+	property: 'delegate',
+	methods: [
+		new web3._extend.Method({
+			name: 'candidates',
+			call: 'delegate_candidates',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'candidate',
+			call: 'delegate_candidate',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'voteWeight',
+			call: 'delegate_voteWeight',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter],
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Method({
+			name: 'checkpoint',
+			call: 'delegate_checkpoint',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getProductionStats',
+			call: 'delegate_getProductionStats',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'roundAt',
+			call: 'delegate_roundAt',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'doubleSignEvidences',
+			call: 'delegate_doubleSignEvidences',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'currentRound',
+			getter: 'delegate_currentRound'
+		}),
+		new web3._extend.Property({
+			name: 'nextShuffle',
+			getter: 'delegate_nextShuffle'
+		}),
+		new web3._extend.Property({
+			name: 'doubleSigners',
+			getter: 'delegate_doubleSigners'
+		}),
+	]
+});
+`
+
 const AOA_JS = `
 web3._extend({
 	property: 'aoa',
@@ -448,6 +682,16 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'signTransactionAsFeePayer',
+			call: 'aoa_signTransactionAsFeePayer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'sendTransactions',
+			call: 'aoa_sendTransactions',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'submitTransaction',
 			call: 'aoa_submitTransaction',
@@ -486,8 +730,70 @@ web3._extend({
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter],
 			outputFormatter: web3._extend.formatters.outputBigNumberFormatter
 		}),
+		new web3._extend.Method({
+			name: 'getEpochUtilization',
+			call: 'aoa_getEpochUtilization',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getLogTransactions',
+			call: 'aoa_getLogTransactions',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getTokenTransfers',
+			call: 'aoa_getTokenTransfers',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getContractCreation',
+			call: 'aoa_getContractCreation',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getProof',
+			call: 'aoa_getProof',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getHeaderByNumber',
+			call: 'aoa_getHeaderByNumber',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'getHeaderByHash',
+			call: 'aoa_getHeaderByHash',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'feeHistory',
+			call: 'aoa_feeHistory',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'createAccessList',
+			call: 'aoa_createAccessList',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputCallFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
 	],
 	properties: [
+		new web3._extend.Property({
+			name: 'pendingLogs',
+			getter: 'aoa_pendingLogs'
+		}),
+		new web3._extend.Property({
+			name: 'pendingReceipts',
+			getter: 'aoa_pendingReceipts'
+		}),
 		new web3._extend.Property({
 			name: 'pendingTransactions',
 			getter: 'aoa_pendingTransactions',
@@ -558,6 +864,24 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'watchAccount',
+			call: 'personal_watchAccount',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'unwatchAccount',
+			call: 'personal_unwatchAccount',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'listAccountBalances',
+			call: 'personal_listAccountBalances',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -601,6 +925,31 @@ web3._extend({
 });
 `
 
+const Miner_JS = `
+web3._extend({
+	property: 'miner',
+	methods: [
+		new web3._extend.Method({
+			name: 'setExtra',
+			call: 'miner_setExtra',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'setGasLimit',
+			call: 'miner_setGasLimit',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'setCoinbase',
+			call: 'miner_setCoinbase',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+	]
+});
+`
+
 const SWARMFS_JS = `
 web3._extend({
 	property: 'swarmfs',
@@ -628,7 +977,14 @@ web3._extend({
 const TxPool_JS = `
 web3._extend({
 	property: 'txpool',
-	methods: [],
+	methods:
+	[
+		new web3._extend.Method({
+			name: 'contentFrom',
+			call: 'txpool_contentFrom',
+			params: 1,
+		}),
+	],
 	properties:
 	[
 		new web3._extend.Property({
@@ -651,3 +1007,30 @@ web3._extend({
 	]
 });
 `
+
+const Scheduler_JS = `
+web3._extend({
+	property: 'scheduler',
+	methods:
+	[
+		new web3._extend.Method({
+			name: 'schedule',
+			call: 'scheduler_schedule',
+			params: 2,
+			inputFormatter: [null, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'cancel',
+			call: 'scheduler_cancel',
+			params: 1
+		}),
+	],
+	properties:
+	[
+		new web3._extend.Property({
+			name: 'scheduled',
+			getter: 'scheduler_scheduled'
+		}),
+	]
+});
+`
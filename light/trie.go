@@ -18,6 +18,7 @@ package light
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/Aurorachain-io/go-aoa/common"
@@ -98,6 +99,10 @@ func (db *odrDatabase) AssetData(addrHash, assetHash common.Hash) ([]byte, error
 	return db.ContractCode(addrHash, assetHash)
 }
 
+func (db *odrDatabase) TrieDB() *trie.NodeDatabase {
+	return nil
+}
+
 type odrTrie struct {
 	db   *odrDatabase
 	id   *TrieID
@@ -150,6 +155,10 @@ func (t *odrTrie) GetKey(sha []byte) []byte {
 	return nil
 }
 
+func (t *odrTrie) Prove(key []byte, fromLevel uint, proofDb trie.DatabaseWriter) error {
+	return errors.New("not implemented, needs client/server interface split")
+}
+
 // do tries and retries to execute a function until it returns with no error or
 // an error type other than MissingNodeError
 func (t *odrTrie) do(key []byte, fn func() error) error {
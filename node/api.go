@@ -31,6 +31,10 @@ import (
 	"github.com/rcrowley/go-metrics"
 )
 
+// peerEventsBuffer is the number of peer events buffered for a subscriber of
+// admin_peerEvents.
+const peerEventsBuffer = 128
+
 // PrivateAdminAPI is the collection of administrative API methods exposed only
 // over a secure RPC channel.
 type PrivateAdminAPI struct {
@@ -67,6 +71,11 @@ func (api *PrivateAdminAPI) AddPeer(url string) (bool, error) {
 	}
 	server.AddPeer(node)
 	log.Info(node.String())
+
+	// Persist the peer so it is reconnected after a restart
+	if err := api.node.updatePersistentNodes(datadirStaticNodes, node, true); err != nil {
+		return true, fmt.Errorf("failed to persist static node: %v", err)
+	}
 	return true, nil
 }
 
@@ -83,11 +92,57 @@ func (api *PrivateAdminAPI) RemovePeer(url string) (bool, error) {
 		return false, fmt.Errorf("invalid enode: %v", err)
 	}
 	server.RemovePeer(node)
+
+	if err := api.node.updatePersistentNodes(datadirStaticNodes, node, false); err != nil {
+		return true, fmt.Errorf("failed to persist static node removal: %v", err)
+	}
+	return true, nil
+}
+
+// AddTrustedPeer allows a remote node to always connect, even if slots are full.
+// The node is persisted and trusted again after a restart.
+func (api *PrivateAdminAPI) AddTrustedPeer(url string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	server.AddTrustedPeer(node)
+
+	if err := api.node.updatePersistentNodes(datadirTrustedNodes, node, true); err != nil {
+		return true, fmt.Errorf("failed to persist trusted node: %v", err)
+	}
+	return true, nil
+}
+
+// RemoveTrustedPeer removes a remote node from the trusted peer set, but it
+// does not disconnect it automatically.
+func (api *PrivateAdminAPI) RemoveTrustedPeer(url string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	server.RemoveTrustedPeer(node)
+
+	if err := api.node.updatePersistentNodes(datadirTrustedNodes, node, false); err != nil {
+		return true, fmt.Errorf("failed to persist trusted node removal: %v", err)
+	}
 	return true, nil
 }
 
 // PeerEvents creates an RPC subscription which receives peer events from the
-// node's p2p.Server
+// node's p2p.Server: peers connecting and disconnecting and, if message events
+// are enabled, connections failing the handshakes, ping round trip times and
+// messages.
 func (api *PrivateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
 	// Make sure the server is running, fail otherwise
 	server := api.node.Server()
@@ -103,7 +158,8 @@ func (api *PrivateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription,
 	rpcSub := notifier.CreateSubscription()
 
 	go func() {
-		events := make(chan *p2p.PeerEvent)
+		// Buffer the events, the feed blocks the peers until delivered
+		events := make(chan *p2p.PeerEvent, peerEventsBuffer)
 		sub := server.SubscribeEvents(events)
 		defer sub.Unsubscribe()
 
@@ -124,12 +180,12 @@ func (api *PrivateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription,
 	return rpcSub, nil
 }
 
-// StartRPC starts the HTTP RPC API server.
-func (api *PrivateAdminAPI) StartRPC(host *string, port *int, cors *string, apis *string) (bool, error) {
+// StartHTTP starts the HTTP RPC API server.
+func (api *PrivateAdminAPI) StartHTTP(host *string, port *int, cors *string, apis *string, vhosts *string) (bool, error) {
 	api.node.lock.Lock()
 	defer api.node.lock.Unlock()
 
-	if api.node.httpHandler != nil {
+	if api.node.http != nil {
 		return false, fmt.Errorf("HTTP RPC already running on %s", api.node.httpEndpoint)
 	}
 
@@ -146,44 +202,55 @@ func (api *PrivateAdminAPI) StartRPC(host *string, port *int, cors *string, apis
 
 	allowedOrigins := api.node.config.HTTPCors
 	if cors != nil {
-		allowedOrigins = nil
-		for _, origin := range strings.Split(*cors, ",") {
-			allowedOrigins = append(allowedOrigins, strings.TrimSpace(origin))
-		}
+		allowedOrigins = splitList(*cors)
 	}
-
-	modules := api.node.httpWhitelist
+	allowedVHosts := api.node.config.HTTPVirtualHosts
+	if vhosts != nil {
+		allowedVHosts = splitList(*vhosts)
+	}
+	modules := api.node.config.HTTPModules
 	if apis != nil {
-		modules = nil
-		for _, m := range strings.Split(*apis, ",") {
-			modules = append(modules, strings.TrimSpace(m))
-		}
+		modules = splitList(*apis)
 	}
 
-	if err := api.node.startHTTP(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, allowedOrigins); err != nil {
+	if err := api.node.startHTTP(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, allowedOrigins, allowedVHosts); err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
-// StopRPC terminates an already running HTTP RPC API endpoint.
-func (api *PrivateAdminAPI) StopRPC() (bool, error) {
+// StartRPC starts the HTTP RPC API server.
+//
+// Deprecated: use StartHTTP instead.
+func (api *PrivateAdminAPI) StartRPC(host *string, port *int, cors *string, apis *string) (bool, error) {
+	return api.StartHTTP(host, port, cors, apis, nil)
+}
+
+// StopHTTP terminates an already running HTTP RPC API endpoint.
+func (api *PrivateAdminAPI) StopHTTP() (bool, error) {
 	api.node.lock.Lock()
 	defer api.node.lock.Unlock()
 
-	if api.node.httpHandler == nil {
+	if api.node.http == nil {
 		return false, fmt.Errorf("HTTP RPC not running")
 	}
 	api.node.stopHTTP()
 	return true, nil
 }
 
+// StopRPC terminates an already running HTTP RPC API endpoint.
+//
+// Deprecated: use StopHTTP instead.
+func (api *PrivateAdminAPI) StopRPC() (bool, error) {
+	return api.StopHTTP()
+}
+
 // StartWS starts the websocket RPC API server.
 func (api *PrivateAdminAPI) StartWS(host *string, port *int, allowedOrigins *string, apis *string) (bool, error) {
 	api.node.lock.Lock()
 	defer api.node.lock.Unlock()
 
-	if api.node.wsHandler != nil {
+	if api.node.ws != nil {
 		return false, fmt.Errorf("WebSocket RPC already running on %s", api.node.wsEndpoint)
 	}
 
@@ -200,18 +267,11 @@ func (api *PrivateAdminAPI) StartWS(host *string, port *int, allowedOrigins *str
 
 	origins := api.node.config.WSOrigins
 	if allowedOrigins != nil {
-		origins = nil
-		for _, origin := range strings.Split(*allowedOrigins, ",") {
-			origins = append(origins, strings.TrimSpace(origin))
-		}
+		origins = splitList(*allowedOrigins)
 	}
-
 	modules := api.node.config.WSModules
 	if apis != nil {
-		modules = nil
-		for _, m := range strings.Split(*apis, ",") {
-			modules = append(modules, strings.TrimSpace(m))
-		}
+		modules = splitList(*apis)
 	}
 
 	if err := api.node.startWS(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, origins, api.node.config.WSExposeAll); err != nil {
@@ -220,18 +280,87 @@ func (api *PrivateAdminAPI) StartWS(host *string, port *int, allowedOrigins *str
 	return true, nil
 }
 
-// StopRPC terminates an already running websocket RPC API endpoint.
+// StopWS terminates an already running websocket RPC API endpoint.
 func (api *PrivateAdminAPI) StopWS() (bool, error) {
 	api.node.lock.Lock()
 	defer api.node.lock.Unlock()
 
-	if api.node.wsHandler == nil {
+	if api.node.ws == nil {
 		return false, fmt.Errorf("WebSocket RPC not running")
 	}
 	api.node.stopWS()
 	return true, nil
 }
 
+// StartIPC reopens the IPC RPC endpoint at the configured path.
+func (api *PrivateAdminAPI) StartIPC() (bool, error) {
+	api.node.lock.Lock()
+	defer api.node.lock.Unlock()
+
+	if api.node.ipcEndpoint == "" {
+		return false, fmt.Errorf("IPC RPC disabled")
+	}
+	if api.node.ipc != nil {
+		return false, fmt.Errorf("IPC RPC already running on %s", api.node.ipcEndpoint)
+	}
+	if err := api.node.startIPC(api.node.rpcAPIs); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// StopIPC terminates an already running IPC RPC API endpoint. Note, invoking it
+// over IPC drops the very connection the request arrived on.
+func (api *PrivateAdminAPI) StopIPC() (bool, error) {
+	api.node.lock.Lock()
+	defer api.node.lock.Unlock()
+
+	if api.node.ipc == nil {
+		return false, fmt.Errorf("IPC RPC not running")
+	}
+	api.node.stopIPC()
+	return true, nil
+}
+
+// RPCEndpoints retrieves the currently running RPC endpoints of the node along
+// with the API modules requested on them, keyed by transport.
+func (api *PrivateAdminAPI) RPCEndpoints() map[string]*RPCEndpointInfo {
+	api.node.lock.RLock()
+	defer api.node.lock.RUnlock()
+
+	endpoints := make(map[string]*RPCEndpointInfo)
+	if api.node.ipc != nil {
+		endpoints["ipc"] = api.node.ipc.info()
+	}
+	if api.node.http != nil {
+		endpoints["http"] = api.node.http.info()
+	}
+	if api.node.ws != nil {
+		endpoints["ws"] = api.node.ws.info()
+	}
+	return endpoints
+}
+
+// splitList splits a comma separated list of values, trimming the whitespace
+// around each of them.
+func splitList(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		values = append(values, strings.TrimSpace(value))
+	}
+	return values
+}
+
+// Panics retrieves the recent panics recovered in the supervised subsystems of
+// the running services.
+func (api *PrivateAdminAPI) Panics() ([]*PanicReport, error) {
+	supervisor := api.node.Supervisor()
+	if supervisor == nil {
+		return nil, ErrNodeStopped
+	}
+	return supervisor.Reports(), nil
+}
+
 // PublicAdminAPI is the collection of administrative API methods exposed over
 // both secure and unsecure RPC channels.
 type PublicAdminAPI struct {
@@ -0,0 +1,132 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Tests that the HTTP endpoint can be started, stopped and restarted at runtime
+// through the admin API, without affecting the rest of the node.
+func TestAdminHTTPLifeCycle(t *testing.T) {
+	stack, err := New(testNodeConfig())
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	var (
+		admin = &PrivateAdminAPI{node: stack}
+		host  = "127.0.0.1"
+		port  = 0
+		apis  = "web3"
+	)
+	for i := 0; i < 2; i++ {
+		if _, err := admin.StartHTTP(&host, &port, nil, &apis, nil); err != nil {
+			t.Fatalf("iteration %d: failed to start HTTP endpoint: %v", i, err)
+		}
+		if _, err := admin.StartHTTP(&host, &port, nil, &apis, nil); err == nil {
+			t.Fatalf("iteration %d: duplicate HTTP endpoint started", i)
+		}
+		info := admin.RPCEndpoints()["http"]
+		if info == nil || len(info.Modules) != 1 || info.Modules[0] != "web3" {
+			t.Fatalf("iteration %d: endpoint info mismatch: %+v", i, info)
+		}
+		addr := stack.http.listener.Addr().String()
+		resp, err := http.Post("http://"+addr, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"web3_clientVersion"}`))
+		if err != nil {
+			t.Fatalf("iteration %d: failed to query HTTP endpoint: %v", i, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("iteration %d: status mismatch: have %d, want %d", i, resp.StatusCode, http.StatusOK)
+		}
+		if _, err := admin.StopHTTP(); err != nil {
+			t.Fatalf("iteration %d: failed to stop HTTP endpoint: %v", i, err)
+		}
+		if _, err := admin.StopHTTP(); err == nil {
+			t.Fatalf("iteration %d: stopped HTTP endpoint stopped again", i)
+		}
+		if _, ok := admin.RPCEndpoints()["http"]; ok {
+			t.Fatalf("iteration %d: stopped HTTP endpoint still reported", i)
+		}
+		if _, err := http.Post("http://"+addr, "application/json", strings.NewReader("{}")); err == nil {
+			t.Fatalf("iteration %d: stopped HTTP endpoint still serving", i)
+		}
+	}
+}
+
+// Tests that static and trusted peers added through the admin API are persisted
+// in the data directory and reloaded when the node restarts.
+func TestAdminPeerPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := testNodeConfig()
+	config.DataDir = dir
+
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	var (
+		admin   = &PrivateAdminAPI{node: stack}
+		static  = "enode://a979fb575495b8d6db44f750317d0f4622bf4c2aa3365d6af7c284339968eef29b69ad0dce72a4d8db5ebb4968de0e3bec910127f134779fbcb0cb6d3331163c@52.16.188.185:30303"
+		removed = "enode://3f1d12044546b76342d59d4a05532c14b85aa669704bfe1f864fe079415aa2c02d743e03218e57a33fb94523adb54032871a6c51b2cc5514cb7c7e35b3ed0a99@13.93.211.84:30303"
+		trusted = "enode://78de8a0916848093c73790ead81d1928bec737d565119932b98c6b100d944b7a95e94f847f689fc723399d2e31129d182f7ef3863f2b4c820abbf3ab2722344d@191.235.84.50:30303"
+	)
+	for _, url := range []string{static, removed} {
+		if _, err := admin.AddPeer(url); err != nil {
+			t.Fatalf("failed to add peer: %v", err)
+		}
+	}
+	if _, err := admin.RemovePeer(removed); err != nil {
+		t.Fatalf("failed to remove peer: %v", err)
+	}
+	if _, err := admin.AddTrustedPeer(trusted); err != nil {
+		t.Fatalf("failed to add trusted peer: %v", err)
+	}
+	stack.Stop()
+
+	// Restart the node and check that the persisted lists are picked up
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to restart protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	statics := stack.Server().StaticNodes
+	if len(statics) != 1 || statics[0].String() != static {
+		t.Errorf("static nodes mismatch: have %v, want [%s]", statics, static)
+	}
+	trusteds := stack.Server().TrustedNodes
+	if len(trusteds) != 1 || trusteds[0].String() != trusted {
+		t.Errorf("trusted nodes mismatch: have %v, want [%s]", trusteds, trusted)
+	}
+}
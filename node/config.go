@@ -18,9 +18,11 @@ package node
 
 import (
 	"crypto/ecdsa"
+	"encoding/json"
 	"fmt"
 	"github.com/Aurorachain-io/go-aoa/accounts"
 	"github.com/Aurorachain-io/go-aoa/accounts/keystore"
+	"github.com/Aurorachain-io/go-aoa/accounts/watchonly"
 	"github.com/Aurorachain-io/go-aoa/common"
 	"github.com/Aurorachain-io/go-aoa/crypto"
 	"github.com/Aurorachain-io/go-aoa/log"
@@ -31,6 +33,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 )
 
 const (
@@ -39,6 +42,7 @@ const (
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
+	datadirWatchOnly       = "watchonly.json"     // Path within the datadir to the watch-only address list
 )
 
 // Config represents a small collection of configuration values to fine tune the
@@ -89,6 +93,11 @@ type Config struct {
 	// relative), then that specific path is enforced. An empty path disables IPC.
 	IPCPath string `toml:",omitempty"`
 
+	// IPCModules is a list of API modules to expose via the IPC interface. If the
+	// module list is empty, all RPC API endpoints are exposed, as only local users
+	// with access to the socket can reach them.
+	IPCModules []string `toml:",omitempty"`
+
 	// HTTPHost is the host interface on which to start the HTTP RPC server. If this
 	// field is empty, no HTTP API endpoint will be started.
 	HTTPHost string `toml:",omitempty"`
@@ -103,9 +112,14 @@ type Config struct {
 	// useless for custom HTTP clients.
 	HTTPCors []string `toml:",omitempty"`
 
+	// HTTPVirtualHosts is the list of virtual hostnames which are allowed on incoming
+	// requests, protecting against DNS rebinding attacks. Requests to an IP address
+	// are always allowed, "*" allows any host.
+	HTTPVirtualHosts []string `toml:",omitempty"`
+
 	// HTTPModules is a list of API modules to expose via the HTTP RPC interface.
 	// If the module list is empty, all RPC API endpoints designated public will be
-	// exposed.
+	// exposed, except for the admin, debug and personal modules.
 	HTTPModules []string `toml:",omitempty"`
 
 	// WSHost is the host interface on which to start the websocket RPC server. If
@@ -124,7 +138,7 @@ type Config struct {
 
 	// WSModules is a list of API modules to expose via the websocket RPC interface.
 	// If the module list is empty, all RPC API endpoints designated public will be
-	// exposed.
+	// exposed, except for the admin, debug and personal modules.
 	WSModules []string `toml:",omitempty"`
 
 	// WSExposeAll exposes all API modules via the WebSocket RPC interface rather
@@ -134,6 +148,21 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// BatchRequestLimit is the maximum number of requests in a batch accepted by
+	// the IPC, HTTP and websocket RPC servers. Zero disables the limit.
+	BatchRequestLimit int `toml:",omitempty"`
+
+	// BatchResponseMaxSize is the maximum aggregate size in bytes of the responses
+	// to a batch sent by the IPC, HTTP and websocket RPC servers. Requests of the
+	// batch left once it is exceeded are answered with an error. Zero disables
+	// the limit.
+	BatchResponseMaxSize int `toml:",omitempty"`
+
+	// MethodTimeouts caps the execution time of individual RPC methods, keyed by
+	// their full name (e.g. aoa_getLogs), so that long running requests can't
+	// stall a public node. Only methods accepting a context can be interrupted.
+	MethodTimeouts map[string]time.Duration `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger
 }
@@ -361,6 +390,32 @@ func (c *Config) parsePersistentNodes(path string) []*discover.Node {
 	return nodes
 }
 
+// savePersistentNodes stores a list of discovery node URLs into a .json file
+// within the data directory, replacing any previous content.
+func (c *Config) savePersistentNodes(path string, nodes []*discover.Node) error {
+	// Short circuit if there's no data directory to persist into
+	if c.DataDir == "" {
+		return nil
+	}
+	nodelist := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		nodelist = append(nodelist, node.String())
+	}
+	blob, err := json.MarshalIndent(nodelist, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// Write to a temporary file first to never leave a truncated list behind
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, blob, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
 // AccountConfig determines the settings for scrypt and keydirectory
 func (c *Config) AccountConfig() (int, int, string, error) {
 	scryptN := keystore.StandardScryptN
@@ -408,6 +463,12 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 	backends := []accounts.Backend{
 		keystore.NewKeyStore(keydir, scryptN, scryptP),
 	}
+	// Track any watch-only (cold storage) addresses alongside the keystore
+	watched, err := watchonly.NewBackend(conf.resolvePath(datadirWatchOnly))
+	if err != nil {
+		return nil, "", err
+	}
+	backends = append(backends, watched)
 	//if !conf.NoUSB {
 	//	// Start a USB hub for Ledger hardware wallets
 	//	if ledgerhub, err := usbwallet.NewLedgerHub(); err != nil {
@@ -24,6 +24,7 @@ import (
 
 	"github.com/Aurorachain-io/go-aoa/p2p"
 	"github.com/Aurorachain-io/go-aoa/p2p/nat"
+	"github.com/Aurorachain-io/go-aoa/rpc"
 )
 
 const (
@@ -35,11 +36,15 @@ const (
 
 // DefaultConfig contains reasonable default settings.
 var DefaultConfig = Config{
-	DataDir:     DefaultDataDir(),
-	HTTPPort:    DefaultHTTPPort,
-	HTTPModules: []string{"net", "web3"},
-	WSPort:      DefaultWSPort,
-	WSModules:   []string{"net", "web3"},
+	DataDir:          DefaultDataDir(),
+	HTTPPort:         DefaultHTTPPort,
+	HTTPModules:      []string{"net", "web3"},
+	HTTPVirtualHosts: []string{"localhost"},
+	WSPort:           DefaultWSPort,
+	WSModules:        []string{"net", "web3"},
+
+	BatchRequestLimit:    rpc.DefaultBatchItemLimit,
+	BatchResponseMaxSize: rpc.DefaultBatchResponseLimit,
 	P2P: p2p.Config{
 		ListenAddr: ":30303",
 		MaxPeers:   25,
@@ -0,0 +1,120 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/rpc"
+)
+
+// rpcEndpoint is a running IPC, HTTP or websocket RPC endpoint of the node. Each
+// endpoint owns its listener and request handler, so that it can be started and
+// stopped at runtime without touching the rest of the node.
+type rpcEndpoint struct {
+	endpoint string        // Socket path or interface and port listened on
+	modules  []string      // API modules requested on the endpoint (empty = defaults)
+	listener net.Listener  // Listener socket accepting the API connections
+	handler  *rpc.Server   // RPC request handler serving the exposed APIs
+	server   *http.Server  // HTTP server tracking open connections (nil for IPC)
+	quit     chan struct{} // Channel closed when the endpoint is shut down
+}
+
+// newRPCEndpoint wraps an already opened listener and its request handler. The
+// server, if any, is started on the listener.
+func newRPCEndpoint(endpoint string, modules []string, listener net.Listener, handler *rpc.Server, server *http.Server) *rpcEndpoint {
+	if server != nil {
+		go server.Serve(listener)
+	}
+	return &rpcEndpoint{
+		endpoint: endpoint,
+		modules:  modules,
+		listener: listener,
+		handler:  handler,
+		server:   server,
+		quit:     make(chan struct{}),
+	}
+}
+
+// serveIPC accepts IPC connections on the endpoint until it is closed.
+func (e *rpcEndpoint) serveIPC(logger log.Logger) {
+	for {
+		conn, err := e.listener.Accept()
+		if err != nil {
+			// Terminate if the endpoint was closed
+			select {
+			case <-e.quit:
+				return
+			default:
+			}
+			// Not closed, just some error; report and continue
+			logger.Error(fmt.Sprintf("IPC accept failed: %v", err))
+			continue
+		}
+		go e.handler.ServeCodec(rpc.NewJSONCodec(conn), rpc.OptionMethodInvocation|rpc.OptionSubscriptions)
+	}
+}
+
+// close shuts down the listener, the open connections and the request handler
+// of the endpoint.
+func (e *rpcEndpoint) close() {
+	close(e.quit)
+	if e.server != nil {
+		e.server.Close()
+	} else {
+		e.listener.Close()
+	}
+	e.handler.Stop()
+}
+
+// info returns the publicly reportable details of the endpoint.
+func (e *rpcEndpoint) info() *RPCEndpointInfo {
+	return &RPCEndpointInfo{
+		Endpoint: e.endpoint,
+		Modules:  e.modules,
+	}
+}
+
+// RPCEndpointInfo describes a running RPC endpoint of the node.
+type RPCEndpointInfo struct {
+	Endpoint string   `json:"endpoint"`
+	Modules  []string `json:"modules"`
+}
+
+// newRPCHandler creates a request handler configured with the node's batch and
+// method limits, registering the APIs accepted by the filter.
+func (n *Node) newRPCHandler(transport string, apis []rpc.API, filter func(api rpc.API) bool) (*rpc.Server, error) {
+	handler := rpc.NewServer()
+	handler.SetBatchLimits(n.config.BatchRequestLimit, n.config.BatchResponseMaxSize)
+	handler.SetMethodTimeouts(n.config.MethodTimeouts)
+	for _, api := range apis {
+		if filter(api) {
+			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
+				handler.Stop()
+				return nil, err
+			}
+			if api.Filter != nil {
+				handler.SetResultFilter(api.Namespace, api.Filter)
+			}
+			n.log.Debug(fmt.Sprintf("%s registered %T under '%s'", transport, api.Service, api.Namespace))
+		}
+	}
+	return handler, nil
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/Aurorachain-io/go-aoa/internal/debug"
 	"github.com/Aurorachain-io/go-aoa/log"
 	"github.com/Aurorachain-io/go-aoa/p2p"
+	"github.com/Aurorachain-io/go-aoa/p2p/discover"
 	"github.com/Aurorachain-io/go-aoa/rpc"
 	"github.com/prometheus/prometheus/util/flock"
 )
@@ -50,25 +51,23 @@ type Node struct {
 
 	serviceFuncs []ServiceConstructor     // Service constructors (in dependency order)
 	services     map[reflect.Type]Service // Currently running services
+	supervisor   *Supervisor              // Supervisor of the subsystems run by the services
 
 	rpcAPIs       []rpc.API   // List of APIs currently provided by the node
 	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
 
-	ipcEndpoint string       // IPC endpoint to listen at (empty = IPC disabled)
-	ipcListener net.Listener // IPC RPC listener socket to serve API requests
-	ipcHandler  *rpc.Server  // IPC RPC request handler to process the API requests
-
-	httpEndpoint  string       // HTTP endpoint (interface + port) to listen at (empty = HTTP disabled)
-	httpWhitelist []string     // HTTP RPC modules to allow through this endpoint
-	httpListener  net.Listener // HTTP RPC listener socket to server API requests
-	httpHandler   *rpc.Server  // HTTP RPC request handler to process the API requests
-	wsEndpoint    string       // Websocket endpoint (interface + port) to listen at (empty = websocket disabled)
-	wsListener    net.Listener // Websocket RPC listener socket to server API requests
-	wsHandler     *rpc.Server  // Websocket RPC request handler to process the API requests
+	ipcEndpoint  string       // IPC endpoint to listen at (empty = IPC disabled)
+	ipc          *rpcEndpoint // Running IPC endpoint, nil if stopped
+	httpEndpoint string       // HTTP endpoint (interface + port) to listen at (empty = HTTP disabled)
+	http         *rpcEndpoint // Running HTTP endpoint, nil if stopped
+	wsEndpoint   string       // Websocket endpoint (interface + port) to listen at (empty = websocket disabled)
+	ws           *rpcEndpoint // Running websocket endpoint, nil if stopped
 
 	stop chan struct{} // Channel to wait for termination notifications
 	lock sync.RWMutex
 
+	nodesLock sync.Mutex // Serializes updates of the persisted static and trusted node lists
+
 	log log.Logger
 }
 
@@ -152,12 +151,8 @@ func (n *Node) Start() error {
 	n.serverConfig.PrivateKey = n.config.NodeKey()
 	n.serverConfig.Name = n.config.NodeName()
 	n.serverConfig.Logger = n.log
-	if n.serverConfig.StaticNodes == nil {
-		n.serverConfig.StaticNodes = n.config.StaticNodes()
-	}
-	if n.serverConfig.TrustedNodes == nil {
-		n.serverConfig.TrustedNodes = n.config.TrustedNodes()
-	}
+	n.serverConfig.StaticNodes = mergeNodes(n.serverConfig.StaticNodes, n.config.StaticNodes())
+	n.serverConfig.TrustedNodes = mergeNodes(n.serverConfig.TrustedNodes, n.config.TrustedNodes())
 	if n.serverConfig.NodeDatabase == "" {
 		n.serverConfig.NodeDatabase = n.config.NodeDB()
 	}
@@ -165,6 +160,7 @@ func (n *Node) Start() error {
 	n.log.Info("Starting peer-to-peer node", "instance", n.serverConfig.Name)
 
 	// Otherwise copy and specialize the P2P configuration
+	supervisor := NewSupervisor(n.log)
 	services := make(map[reflect.Type]Service)
 	for _, constructor := range n.serviceFuncs {
 		// Create a new context for the particular service
@@ -173,6 +169,7 @@ func (n *Node) Start() error {
 			services:       make(map[reflect.Type]Service),
 			EventMux:       n.eventmux,
 			AccountManager: n.accman,
+			Supervisor:     supervisor,
 		}
 		for kind, s := range services { // copy needed for threaded access
 			ctx.services[kind] = s
@@ -200,6 +197,7 @@ func (n *Node) Start() error {
 	for kind, service := range services {
 		// Start the next service, stopping all previous upon failure
 		if err := service.Start(running); err != nil {
+			supervisor.Stop()
 			for _, kind := range started {
 				services[kind].Stop()
 			}
@@ -212,6 +210,7 @@ func (n *Node) Start() error {
 	}
 	// Lastly start the configured RPC interfaces
 	if err := n.startRPC(services); err != nil {
+		supervisor.Stop()
 		for _, service := range services {
 			service.Stop()
 		}
@@ -220,6 +219,7 @@ func (n *Node) Start() error {
 	}
 	// Finish initializing the startup
 	n.services = services
+	n.supervisor = supervisor
 	n.server = running
 	n.stop = make(chan struct{})
 	return nil
@@ -261,7 +261,7 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 		n.stopInProc()
 		return err
 	}
-	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors); err != nil {
+	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors, n.config.HTTPVirtualHosts); err != nil {
 		n.stopIPC()
 		n.stopInProc()
 		return err
@@ -285,6 +285,9 @@ func (n *Node) startInProc(apis []rpc.API) error {
 		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 			return err
 		}
+		if api.Filter != nil {
+			handler.SetResultFilter(api.Namespace, api.Filter)
+		}
 		n.log.Debug(fmt.Sprintf("InProc registered %T under '%s'", api.Service, api.Namespace))
 	}
 	n.inprocHandler = handler
@@ -305,115 +308,75 @@ func (n *Node) startIPC(apis []rpc.API) error {
 	if n.ipcEndpoint == "" {
 		return nil
 	}
-	// Register all the APIs exposed by the services
-	handler := rpc.NewServer()
-	for _, api := range apis {
-		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
-			return err
-		}
-		n.log.Debug(fmt.Sprintf("IPC registered %T under '%s'", api.Service, api.Namespace))
+	// Register all the APIs exposed by the services, IPC being local exposes all
+	// of them unless restricted
+	whitelist := n.moduleWhitelist("IPC", apis, n.config.IPCModules)
+	handler, err := n.newRPCHandler("IPC", apis, func(api rpc.API) bool {
+		return len(whitelist) == 0 || whitelist[api.Namespace]
+	})
+	if err != nil {
+		return err
 	}
 	// All APIs registered, start the IPC listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = rpc.CreateIPCListener(n.ipcEndpoint); err != nil {
+	listener, err := rpc.CreateIPCListener(n.ipcEndpoint)
+	if err != nil {
+		handler.Stop()
 		return err
 	}
-	go func() {
-		n.log.Info(fmt.Sprintf("IPC endpoint opened: %s", n.ipcEndpoint))
-
-		for {
-			conn, err := listener.Accept()
-			if err != nil {
-				// Terminate if the listener was closed
-				n.lock.RLock()
-				closed := n.ipcListener == nil
-				n.lock.RUnlock()
-				if closed {
-					return
-				}
-				// Not closed, just some error; report and continue
-				n.log.Error(fmt.Sprintf("IPC accept failed: %v", err))
-				continue
-			}
-			go handler.ServeCodec(rpc.NewJSONCodec(conn), rpc.OptionMethodInvocation|rpc.OptionSubscriptions)
-		}
-	}()
-	// All listeners booted successfully
-	n.ipcListener = listener
-	n.ipcHandler = handler
+	n.ipc = newRPCEndpoint(n.ipcEndpoint, n.config.IPCModules, listener, handler, nil)
+	go n.ipc.serveIPC(n.log)
+	n.log.Info(fmt.Sprintf("IPC endpoint opened: %s", n.ipcEndpoint))
 
 	return nil
 }
 
 // stopIPC terminates the IPC RPC endpoint.
 func (n *Node) stopIPC() {
-	if n.ipcListener != nil {
-		n.ipcListener.Close()
-		n.ipcListener = nil
+	if n.ipc != nil {
+		n.ipc.close()
+		n.ipc = nil
 
 		n.log.Info(fmt.Sprintf("IPC endpoint closed: %s", n.ipcEndpoint))
 	}
-	if n.ipcHandler != nil {
-		n.ipcHandler.Stop()
-		n.ipcHandler = nil
-	}
 }
 
 // startHTTP initializes and starts the HTTP RPC endpoint.
-func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string) error {
+func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string) error {
 	// Short circuit if the HTTP endpoint isn't being exposed
 	if endpoint == "" {
 		return nil
 	}
-	// Generate the whitelist based on the allowed modules
-	whitelist := make(map[string]bool)
-	for _, module := range modules {
-		whitelist[module] = true
-	}
 	// Register all the APIs exposed by the services
-	handler := rpc.NewServer()
-	for _, api := range apis {
-		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
-			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
-				return err
-			}
-			n.log.Debug(fmt.Sprintf("HTTP registered %T under '%s'", api.Service, api.Namespace))
-		}
+	whitelist := n.moduleWhitelist("HTTP", apis, modules)
+	handler, err := n.newRPCHandler("HTTP", apis, func(api rpc.API) bool {
+		return exposeAPI(api, whitelist)
+	})
+	if err != nil {
+		return err
 	}
 	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		handler.Stop()
 		return err
 	}
-	go rpc.NewHTTPServer(cors, handler).Serve(listener)
-	n.log.Info(fmt.Sprintf("HTTP endpoint opened: http://%s", endpoint))
+	n.log.Info(fmt.Sprintf("HTTP endpoint opened: http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","))
 
 	// All listeners booted successfully
 	n.httpEndpoint = endpoint
-	n.httpListener = listener
-	n.httpHandler = handler
+	n.http = newRPCEndpoint(endpoint, modules, listener, handler, rpc.NewHTTPServer(cors, vhosts, handler))
 
 	return nil
 }
 
 // stopHTTP terminates the HTTP RPC endpoint.
 func (n *Node) stopHTTP() {
-	if n.httpListener != nil {
-		n.httpListener.Close()
-		n.httpListener = nil
+	if n.http != nil {
+		n.http.close()
+		n.http = nil
 
 		n.log.Info(fmt.Sprintf("HTTP endpoint closed: http://%s", n.httpEndpoint))
 	}
-	if n.httpHandler != nil {
-		n.httpHandler.Stop()
-		n.httpHandler = nil
-	}
 }
 
 // startWS initializes and starts the websocket RPC endpoint.
@@ -422,52 +385,71 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	if endpoint == "" {
 		return nil
 	}
-	// Generate the whitelist based on the allowed modules
-	whitelist := make(map[string]bool)
-	for _, module := range modules {
-		whitelist[module] = true
-	}
 	// Register all the APIs exposed by the services
-	handler := rpc.NewServer()
-	for _, api := range apis {
-		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
-			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
-				return err
-			}
-			n.log.Debug(fmt.Sprintf("WebSocket registered %T under '%s'", api.Service, api.Namespace))
-		}
+	whitelist := n.moduleWhitelist("WebSocket", apis, modules)
+	handler, err := n.newRPCHandler("WebSocket", apis, func(api rpc.API) bool {
+		return exposeAll || exposeAPI(api, whitelist)
+	})
+	if err != nil {
+		return err
 	}
-	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	// All APIs registered, start the websocket listener
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		handler.Stop()
 		return err
 	}
-	go rpc.NewWSServer(wsOrigins, handler).Serve(listener)
 	n.log.Info(fmt.Sprintf("WebSocket endpoint opened: ws://%s", listener.Addr()))
 
 	// All listeners booted successfully
 	n.wsEndpoint = endpoint
-	n.wsListener = listener
-	n.wsHandler = handler
+	n.ws = newRPCEndpoint(endpoint, modules, listener, handler, rpc.NewWSServer(wsOrigins, handler))
 
 	return nil
 }
 
 // stopWS terminates the websocket RPC endpoint.
 func (n *Node) stopWS() {
-	if n.wsListener != nil {
-		n.wsListener.Close()
-		n.wsListener = nil
+	if n.ws != nil {
+		n.ws.close()
+		n.ws = nil
 
 		n.log.Info(fmt.Sprintf("WebSocket endpoint closed: ws://%s", n.wsEndpoint))
 	}
-	if n.wsHandler != nil {
-		n.wsHandler.Stop()
-		n.wsHandler = nil
+}
+
+// unsafeModules are the API namespaces granting control over the node or its
+// accounts. They are never exposed over HTTP or websockets unless listed.
+var unsafeModules = map[string]bool{"admin": true, "debug": true, "personal": true}
+
+// moduleWhitelist assembles the set of modules to expose over a transport,
+// warning about modules no API is registered under and about unsafe modules
+// exposed over the network.
+func (n *Node) moduleWhitelist(transport string, apis []rpc.API, modules []string) map[string]bool {
+	available := make(map[string]bool)
+	for _, api := range apis {
+		available[api.Namespace] = true
+	}
+	whitelist := make(map[string]bool)
+	for _, module := range modules {
+		if !available[module] {
+			n.log.Warn("Unavailable API module requested", "transport", transport, "module", module)
+		} else if unsafeModules[module] && transport != "IPC" {
+			n.log.Warn("Exposing unsafe API module", "transport", transport, "module", module)
+		}
+		whitelist[module] = true
+	}
+	return whitelist
+}
+
+// exposeAPI returns whether an API is registered on a network endpoint with the
+// given module whitelist. Without a whitelist, the public APIs of all but the
+// unsafe modules are exposed.
+func exposeAPI(api rpc.API, whitelist map[string]bool) bool {
+	if len(whitelist) == 0 {
+		return api.Public && !unsafeModules[api.Namespace]
 	}
+	return whitelist[api.Namespace]
 }
 
 // Stop terminates a running node along with all it's services. In the node was
@@ -489,6 +471,7 @@ func (n *Node) Stop() error {
 	failure := &StopError{
 		Services: make(map[reflect.Type]error),
 	}
+	n.supervisor.Stop()
 	for kind, service := range n.services {
 		if err := service.Stop(); err != nil {
 			failure.Services[kind] = err
@@ -496,6 +479,7 @@ func (n *Node) Stop() error {
 	}
 	n.server.Stop()
 	n.services = nil
+	n.supervisor = nil
 	n.server = nil
 
 	// Release instance directory lock.
@@ -582,6 +566,44 @@ func (n *Node) Server() *p2p.Server {
 	return n.server
 }
 
+// updatePersistentNodes adds the given node to, or removes it from, the node list
+// persisted in the given file of the data directory, so that the change survives
+// a restart. Nodes are matched by their ID.
+func (n *Node) updatePersistentNodes(file string, node *discover.Node, add bool) error {
+	n.nodesLock.Lock()
+	defer n.nodesLock.Unlock()
+
+	path := n.config.resolvePath(file)
+	if path == "" {
+		return nil
+	}
+	var nodes []*discover.Node
+	for _, old := range n.config.parsePersistentNodes(path) {
+		if old.ID != node.ID {
+			nodes = append(nodes, old)
+		}
+	}
+	if add {
+		nodes = append(nodes, node)
+	}
+	return n.config.savePersistentNodes(path, nodes)
+}
+
+// mergeNodes appends the nodes missing from the given list, deduplicated by ID.
+func mergeNodes(nodes []*discover.Node, extra []*discover.Node) []*discover.Node {
+	known := make(map[discover.NodeID]bool, len(nodes))
+	for _, node := range nodes {
+		known[node.ID] = true
+	}
+	for _, node := range extra {
+		if !known[node.ID] {
+			known[node.ID] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
 // Service retrieves a currently running service registered of a specific type.
 func (n *Node) Service(service interface{}) error {
 	n.lock.RLock()
@@ -600,6 +622,15 @@ func (n *Node) Service(service interface{}) error {
 	return ErrServiceUnknown
 }
 
+// Supervisor retrieves the supervisor of the currently running services' subsystems.
+// If the node is not running, nil is returned.
+func (n *Node) Supervisor() *Supervisor {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	return n.supervisor
+}
+
 // DataDir retrieves the current datadir used by the protocol stack.
 // Deprecated: No files should be stored in this directory, use InstanceDir instead.
 func (n *Node) DataDir() string {
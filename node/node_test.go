@@ -573,3 +573,25 @@ func TestAPIGather(t *testing.T) {
 		}
 	}
 }
+
+// Tests that only the public APIs of safe modules are exposed over the network
+// by default, while explicitly listed modules are exposed entirely.
+func TestExposeAPI(t *testing.T) {
+	tests := []struct {
+		api       rpc.API
+		whitelist map[string]bool
+		exposed   bool
+	}{
+		{rpc.API{Namespace: "web3", Public: true}, nil, true},
+		{rpc.API{Namespace: "aoa", Public: false}, nil, false},
+		{rpc.API{Namespace: "admin", Public: true}, nil, false},
+		{rpc.API{Namespace: "debug", Public: true}, nil, false},
+		{rpc.API{Namespace: "admin", Public: false}, map[string]bool{"admin": true}, true},
+		{rpc.API{Namespace: "web3", Public: true}, map[string]bool{"admin": true}, false},
+	}
+	for i, tt := range tests {
+		if exposed := exposeAPI(tt.api, tt.whitelist); exposed != tt.exposed {
+			t.Errorf("test %d: exposure mismatch for %s: have %v, want %v", i, tt.api.Namespace, exposed, tt.exposed)
+		}
+	}
+}
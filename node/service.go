@@ -34,6 +34,7 @@ type ServiceContext struct {
 	services       map[reflect.Type]Service // Index of the already constructed services
 	EventMux       *event.TypeMux           // Event multiplexer used for decoupled notifications
 	AccountManager *accounts.Manager        // Account manager created by the node.
+	Supervisor     *Supervisor              // Supervisor restarting crashed non-consensus subsystems
 }
 
 // OpenDatabase opens an existing database with the given name (or creates one
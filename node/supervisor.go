@@ -0,0 +1,162 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/log"
+)
+
+const (
+	minRestartBackoff = time.Second // Delay before restarting a freshly crashed module
+	maxRestartBackoff = time.Minute // Upper limit of the exponentially growing restart delay
+	maxPanicReports   = 32          // Number of recent panic reports retained for inspection
+)
+
+// PanicReport describes a panic recovered by the supervisor in one of the
+// modules it is responsible for.
+type PanicReport struct {
+	Module   string    `json:"module"`   // Name of the module that crashed
+	Panic    string    `json:"panic"`    // Value the module panicked with
+	Stack    string    `json:"stack"`    // Stack trace of the crashed goroutine
+	Restarts int       `json:"restarts"` // Number of consecutive crashes of the module
+	Time     time.Time `json:"time"`     // Time of the crash
+}
+
+// Supervisor runs non-consensus subsystems of the services (indexers, watchers,
+// request servers) in isolation, so that a panic in one of them is logged and
+// the module restarted with backoff instead of crashing the entire node.
+//
+// Consensus critical code must not be supervised, a panic there is a fatal
+// inconsistency which has to stop the node.
+type Supervisor struct {
+	quit    chan struct{}  // Channel closed when the supervised modules should terminate
+	wg      sync.WaitGroup // Tracks the running supervised modules
+	reports []*PanicReport // Recently recovered panics, oldest first
+	lock    sync.Mutex     // Protects the panic reports
+
+	log log.Logger
+}
+
+// NewSupervisor creates a supervisor logging the recovered panics into logger.
+func NewSupervisor(logger log.Logger) *Supervisor {
+	if logger == nil {
+		logger = log.Root()
+	}
+	return &Supervisor{
+		quit: make(chan struct{}),
+		log:  logger,
+	}
+}
+
+// Go starts run on a new goroutine under supervision. The function is expected
+// to return when quit is closed. If it panics, the crash is reported and run is
+// invoked again after an exponentially increasing delay, reset once the module
+// manages to stay alive longer than the maximum delay.
+//
+// Modules must not be started without a supervisor, as nothing would ever close
+// their quit channel.
+func (s *Supervisor) Go(module string, run func(quit <-chan struct{})) {
+	s.wg.Add(1)
+	go s.supervise(module, run)
+}
+
+// supervise is the loop keeping a single module alive until the supervisor is
+// stopped or the module terminates without a panic.
+func (s *Supervisor) supervise(module string, run func(quit <-chan struct{})) {
+	defer s.wg.Done()
+
+	var (
+		restarts int
+		backoff  = minRestartBackoff
+	)
+	for {
+		started := time.Now()
+		report := s.run(module, run)
+		if report == nil {
+			return
+		}
+		if time.Since(started) > maxRestartBackoff {
+			restarts, backoff = 0, minRestartBackoff
+		}
+		restarts++
+		report.Restarts = restarts
+		s.record(report)
+
+		s.log.Error("Supervised module crashed", "module", module, "panic", report.Panic, "restarts", restarts, "backoff", backoff)
+		s.log.Debug("Supervised module crash stack", "module", module, "stack", report.Stack)
+
+		select {
+		case <-s.quit:
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxRestartBackoff {
+			backoff = maxRestartBackoff
+		}
+		s.log.Info("Restarting supervised module", "module", module)
+	}
+}
+
+// run executes a single life cycle of a module, returning the details of the
+// panic that terminated it, or nil if it returned cleanly.
+func (s *Supervisor) run(module string, run func(quit <-chan struct{})) (report *PanicReport) {
+	defer func() {
+		if r := recover(); r != nil {
+			report = &PanicReport{
+				Module: module,
+				Panic:  fmt.Sprint(r),
+				Stack:  string(debug.Stack()),
+				Time:   time.Now(),
+			}
+		}
+	}()
+	run(s.quit)
+	return nil
+}
+
+// record appends a panic report, discarding the oldest above the retention limit.
+func (s *Supervisor) record(report *PanicReport) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.reports = append(s.reports, report)
+	if len(s.reports) > maxPanicReports {
+		s.reports = s.reports[len(s.reports)-maxPanicReports:]
+	}
+}
+
+// Reports retrieves the recently recovered panics, oldest first.
+func (s *Supervisor) Reports() []*PanicReport {
+	if s == nil {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]*PanicReport(nil), s.reports...)
+}
+
+// Stop signals all supervised modules to terminate and waits until they do.
+func (s *Supervisor) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
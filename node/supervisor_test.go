@@ -0,0 +1,105 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests that a panicking module is reported and restarted, and that stopping the
+// supervisor terminates the restarted module.
+func TestSupervisorRestart(t *testing.T) {
+	supervisor := NewSupervisor(nil)
+
+	runs := make(chan int, 2)
+	count := 0
+	supervisor.Go("test", func(quit <-chan struct{}) {
+		count++
+		runs <- count
+		if count == 1 {
+			panic("boom")
+		}
+		<-quit
+	})
+	for want := 1; want <= 2; want++ {
+		select {
+		case run := <-runs:
+			if run != want {
+				t.Fatalf("run mismatch: have %d, want %d", run, want)
+			}
+		case <-time.After(3 * minRestartBackoff):
+			t.Fatalf("module run %d not started", want)
+		}
+	}
+	reports := supervisor.Reports()
+	if len(reports) != 1 {
+		t.Fatalf("report count mismatch: have %d, want 1", len(reports))
+	}
+	if reports[0].Module != "test" || reports[0].Panic != "boom" || reports[0].Restarts != 1 {
+		t.Errorf("report mismatch: have %+v", reports[0])
+	}
+	done := make(chan struct{})
+	go func() {
+		supervisor.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("supervisor failed to stop")
+	}
+}
+
+// Tests that the services of a running node are handed a supervisor, which is
+// stopped together with the node.
+func TestSupervisorLifecycle(t *testing.T) {
+	stack, err := New(testNodeConfig())
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	var supervisor *Supervisor
+	constructor := func(ctx *ServiceContext) (Service, error) {
+		supervisor = ctx.Supervisor
+		return new(NoopService), nil
+	}
+	if err := stack.Register(constructor); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	if supervisor == nil || stack.Supervisor() != supervisor {
+		t.Fatalf("service supervisor mismatch")
+	}
+	stopped := make(chan struct{})
+	supervisor.Go("test", func(quit <-chan struct{}) {
+		<-quit
+		close(stopped)
+	})
+	if err := stack.Stop(); err != nil {
+		t.Fatalf("failed to stop protocol stack: %v", err)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Fatalf("supervised module still running")
+	}
+	if stack.Supervisor() != nil {
+		t.Fatalf("supervisor retained after stop")
+	}
+}
@@ -269,11 +269,10 @@ func (s *dialstate) newTasks(nRunning int, peers map[discover.NodeID]*Peer, now
 	i := 0
 	for ; i < len(s.commonLookupBuf) && needDynDials > 0; i++ {
 		if addDial(dynDialedConn, s.commonLookupBuf[i]) {
-			s.commonLookupBuf = append(s.commonLookupBuf[:i], s.commonLookupBuf[i+1:]...)
 			needDynDials--
 		}
-
 	}
+	s.commonLookupBuf = s.commonLookupBuf[:copy(s.commonLookupBuf, s.commonLookupBuf[i:])]
 
 	// Launch a discovery lookup if more candidates are needed.
 	if len(s.commonLookupBuf) < needDynDials && !s.commonLookupRunning {
@@ -295,6 +294,7 @@ func (s *dialstate) newTasks(nRunning int, peers map[discover.NodeID]*Peer, now
 				needDynDials--
 			}
 		}
+		s.topLookupBuf = s.topLookupBuf[:copy(s.topLookupBuf, s.topLookupBuf[i:])]
 
 		// Launch a discovery lookup if more candidates are needed.
 		if len(s.topLookupBuf) < needDynDials && !s.topLookupRunning {
@@ -64,7 +64,7 @@ func runDialTest(t *testing.T, test dialtest) {
 			test.init.taskDone(task, vtime)
 		}
 
-		new := test.init.newTasks(running, pm(round.peers), vtime, discover.ConsNet)
+		new := test.init.newTasks(running, pm(round.peers), vtime, false)
 		if !sametasks(new, round.new) {
 			t.Errorf("round %d: new tasks mismatch:\ngot %v\nwant %v\nstate: %v\nrunning: %v\n",
 				i, spew.Sdump(new), spew.Sdump(round.new), spew.Sdump(test.init), spew.Sdump(running))
@@ -82,6 +82,8 @@ func (t fakeTable) Self() *discover.Node                                { return
 func (t fakeTable) Close()                                              {}
 func (t fakeTable) Lookup(discover.NodeID, byte) []*discover.Node       { return nil }
 func (t fakeTable) Resolve(discover.NodeID) *discover.Node              { return nil }
+func (t fakeTable) Delete(discover.NodeID)                              {}
+func (t fakeTable) OpenTopNet()                                         {}
 func (t fakeTable) ReadRandomNodes(buf []*discover.Node, tyoe byte) int { return copy(buf, t) }
 
 // This test checks that dynamic dials are launched from discovery results.
@@ -92,18 +94,18 @@ func TestDialStateDynDial(t *testing.T) {
 			// A discovery query is launched.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: staticDialedConn, id: uintID(0), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.ConsNet}},
+					{rw: &conn{flags: staticDialedConn, id: uintID(0), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.CommNet}},
 				},
-				new: []task{&discoverTask{netType: discover.ConsNet}},
+				new: []task{&discoverTask{netType: discover.CommNet}},
 			},
 			// Dynamic dials are launched when it completes.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: staticDialedConn, id: uintID(0), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.ConsNet}},
+					{rw: &conn{flags: staticDialedConn, id: uintID(0), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.CommNet}},
 				},
 				done: []task{
 					&discoverTask{results: []*discover.Node{
@@ -113,59 +115,57 @@ func TestDialStateDynDial(t *testing.T) {
 						{ID: uintID(5)},
 						{ID: uintID(6)}, // these are not tried because max dyn dials is 5
 						{ID: uintID(7)}, // ...
-					}, netType: discover.ConsNet},
+					}, netType: discover.CommNet},
 				},
 				new: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(3)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}, netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(3)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}},
 				},
 			},
 			// Some of the dials complete but no new ones are launched yet because
 			// the sum of active dial count and dynamic peer count is == maxDynDials.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: staticDialedConn, id: uintID(0), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(3), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(4), netType: discover.ConsNet}},
+					{rw: &conn{flags: staticDialedConn, id: uintID(0), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(3), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(4), netType: discover.CommNet}},
 				},
 				done: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(3)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}, netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(3)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}},
 				},
 			},
 			// No new dial tasks are launched in the this round because
-			// maxDynDials has been reached.
+			// maxDynDials has been reached. Completed dials are not kept
+			// in the dial history, so there is nothing to wait for either.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: staticDialedConn, id: uintID(0), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(3)}, netType: discover.ConsNet},
-					{rw: &conn{flags: dynDialedConn, id: uintID(4)}, netType: discover.ConsNet},
-					{rw: &conn{flags: dynDialedConn, id: uintID(5)}, netType: discover.ConsNet},
+					{rw: &conn{flags: staticDialedConn, id: uintID(0), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(3), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(4), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(5), netType: discover.CommNet}},
 				},
 				done: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}, netType: discover.ConsNet},
-				},
-				new: []task{
-					&waitExpireTask{Duration: 14 * time.Second, netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}},
 				},
 			},
 			// In this round, the peer with id 2 drops off. The query
 			// results from last discovery lookup are reused.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: staticDialedConn, id: uintID(0), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(3), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(4), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(5), netType: discover.ConsNet}},
+					{rw: &conn{flags: staticDialedConn, id: uintID(0), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(3), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(4), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(5), netType: discover.CommNet}},
 				},
 				new: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(6)}, netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(6)}},
 				},
 			},
 			// More peers (3,4) drop off and dial for ID 6 completes.
@@ -173,16 +173,16 @@ func TestDialStateDynDial(t *testing.T) {
 			// and a new one is spawned because more candidates are needed.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: staticDialedConn, id: uintID(0), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(5), netType: discover.ConsNet}},
+					{rw: &conn{flags: staticDialedConn, id: uintID(0), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(5), netType: discover.CommNet}},
 				},
 				done: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(6)}, netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(6)}},
 				},
 				new: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(7)}, netType: discover.ConsNet},
-					&discoverTask{netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(7)}},
+					&discoverTask{netType: discover.CommNet},
 				},
 			},
 			// Peer 7 is connected, but there still aren't enough dynamic peers
@@ -190,29 +190,29 @@ func TestDialStateDynDial(t *testing.T) {
 			// no new is started.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: staticDialedConn, id: uintID(0)}, netType: discover.ConsNet},
-					{rw: &conn{flags: dynDialedConn, id: uintID(1)}, netType: discover.ConsNet},
-					{rw: &conn{flags: dynDialedConn, id: uintID(5)}, netType: discover.ConsNet},
-					{rw: &conn{flags: dynDialedConn, id: uintID(7)}, netType: discover.ConsNet},
+					{rw: &conn{flags: staticDialedConn, id: uintID(0), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(5), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(7), netType: discover.CommNet}},
 				},
 				done: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(7)}, netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(7)}},
 				},
 			},
 			// Finish the running node discovery with an empty set. A new lookup
 			// should be immediately requested.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: staticDialedConn, id: uintID(0)}, netType: discover.ConsNet},
-					{rw: &conn{flags: dynDialedConn, id: uintID(1)}, netType: discover.ConsNet},
-					{rw: &conn{flags: dynDialedConn, id: uintID(5)}, netType: discover.ConsNet},
-					{rw: &conn{flags: dynDialedConn, id: uintID(7)}, netType: discover.ConsNet},
+					{rw: &conn{flags: staticDialedConn, id: uintID(0), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(5), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(7), netType: discover.CommNet}},
 				},
 				done: []task{
-					&discoverTask{netType: discover.ConsNet},
+					&discoverTask{netType: discover.CommNet},
 				},
 				new: []task{
-					&discoverTask{netType: discover.ConsNet},
+					&discoverTask{netType: discover.CommNet},
 				},
 			},
 		},
@@ -239,68 +239,75 @@ func TestDialStateDynDialBootnode(t *testing.T) {
 			// 2 dynamic dials attempted, bootnodes pending fallback interval
 			{
 				new: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}, netType: discover.ConsNet},
-					&discoverTask{netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}},
+					&discoverTask{netType: discover.CommNet},
 				},
 			},
-			// No dials succeed, bootnodes still pending fallback interval
+			// No dials succeed, the random nodes are retried right away since
+			// finished dials are not kept in the dial history. Bootnodes are
+			// still pending fallback interval
 			{
 				done: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}, netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}},
+				},
+				new: []task{
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}},
 				},
 			},
 			// No dials succeed, bootnodes still pending fallback interval
 			{},
-			// No dials succeed, 2 dynamic dials attempted and 1 bootnode too as fallback interval was reached
+			// Fallback interval was reached, 1 bootnode is attempted next to the running dynamic dials
 			{
 				new: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(1)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}, netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(1)}},
 				},
 			},
-			// No dials succeed, 2nd bootnode is attempted
+			// No dials succeed, 2nd bootnode is attempted and the random nodes retried
 			{
 				done: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(1)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}, netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(1)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}},
 				},
 				new: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(2)}, netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(2)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}},
 				},
 			},
 			// No dials succeed, 3rd bootnode is attempted
 			{
 				done: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(2)}, netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(2)}},
 				},
 				new: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(3)}, netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(3)}},
 				},
 			},
-			// No dials succeed, 1st bootnode is attempted again, expired random nodes retried
+			// No dials succeed, 1st bootnode is attempted again
 			{
 				done: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(3)}, netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(3)}},
 				},
 				new: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(1)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}, netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(1)}},
 				},
 			},
-			// Random dial succeeds, no more bootnodes are attempted
+			// Random dial succeeds, no more bootnodes are attempted, the other random node is retried
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: dynDialedConn, id: uintID(4)}, netType: discover.ConsNet},
+					{rw: &conn{flags: dynDialedConn, id: uintID(4), netType: discover.CommNet}},
 				},
 				done: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(1)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}, netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(1)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}},
+				},
+				new: []task{
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}},
 				},
 			},
 		},
@@ -327,63 +334,63 @@ func TestDialStateDynDialFromTable(t *testing.T) {
 			// 5 out of 8 of the nodes returned by ReadRandomNodes are dialed.
 			{
 				new: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(1)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(2)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(3)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}, netType: discover.ConsNet},
-					&discoverTask{netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(1)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(2)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(3)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}},
+					&discoverTask{netType: discover.CommNet},
 				},
 			},
 			// Dialing nodes 1,2 succeeds. Dials from the lookup are launched.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.ConsNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.CommNet}},
 				},
 				done: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(1)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(2)}, netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(1)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(2)}},
 					&discoverTask{results: []*discover.Node{
 						{ID: uintID(10)},
 						{ID: uintID(11)},
 						{ID: uintID(12)},
-					}},
+					}, netType: discover.CommNet},
 				},
 				new: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(10)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(11)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(12)}, netType: discover.ConsNet},
-					&discoverTask{netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(10)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(11)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(12)}},
+					&discoverTask{netType: discover.CommNet},
 				},
 			},
 			// Dialing nodes 3,4,5 fails. The dials from the lookup succeed.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: dynDialedConn, id: uintID(1)}, netType: discover.ConsNet},
-					{rw: &conn{flags: dynDialedConn, id: uintID(2)}, netType: discover.ConsNet},
-					{rw: &conn{flags: dynDialedConn, id: uintID(10)}, netType: discover.ConsNet},
-					{rw: &conn{flags: dynDialedConn, id: uintID(11)}, netType: discover.ConsNet},
-					{rw: &conn{flags: dynDialedConn, id: uintID(12)}, netType: discover.ConsNet},
+					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(10), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(11), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(12), netType: discover.CommNet}},
 				},
 				done: []task{
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(3)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(10)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(11)}, netType: discover.ConsNet},
-					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(12)}, netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(3)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(4)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(5)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(10)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(11)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(12)}},
 				},
 			},
 			// Waiting for expiry. No waitExpireTask is launched because the
 			// discovery query is still running.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(10), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(11), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(12), netType: discover.ConsNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(10), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(11), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(12), netType: discover.CommNet}},
 				},
 			},
 			// Nodes 3,4 are not tried again because only the first two
@@ -391,11 +398,11 @@ func TestDialStateDynDialFromTable(t *testing.T) {
 			// already connected.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(10), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(11), netType: discover.ConsNet}},
-					{rw: &conn{flags: dynDialedConn, id: uintID(12), netType: discover.ConsNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(10), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(11), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(12), netType: discover.CommNet}},
 				},
 			},
 		},
@@ -424,8 +431,8 @@ func TestDialStateNetRestrict(t *testing.T) {
 		rounds: []round{
 			{
 				new: []task{
-					&dialTask{flags: dynDialedConn, dest: table[4], netType: discover.ConsNet},
-					&discoverTask{netType: discover.ConsNet},
+					&dialTask{flags: dynDialedConn, dest: table[4]},
+					&discoverTask{netType: discover.CommNet},
 				},
 			},
 		},
@@ -453,69 +460,66 @@ func TestDialStateStaticDial(t *testing.T) {
 					{rw: &conn{flags: dynDialedConn, id: uintID(2)}},
 				},
 				new: []task{
-					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(3)}, netType: discover.ConsNet},
-					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(4)}, netType: discover.ConsNet},
-					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(5)}, netType: discover.ConsNet},
+					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(3)}},
+					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(4)}},
+					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(5)}},
 				},
 			},
 			// No new tasks are launched in this round because all static
 			// nodes are either connected or still being dialed.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: dynDialedConn, id: uintID(1)}, netType: discover.ConsNet},
-					{rw: &conn{flags: dynDialedConn, id: uintID(2)}, netType: discover.ConsNet},
-					{rw: &conn{flags: staticDialedConn, id: uintID(3)}, netType: discover.ConsNet},
+					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.CommNet}},
+					{rw: &conn{flags: staticDialedConn, id: uintID(3), netType: discover.CommNet}},
 				},
 				done: []task{
-					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(3)}, netType: discover.ConsNet},
+					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(3)}},
 				},
 			},
 			// No new dial tasks are launched because all static
 			// nodes are now connected.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: dynDialedConn, id: uintID(1)}, netType: discover.ConsNet},
-					{rw: &conn{flags: dynDialedConn, id: uintID(2)}, netType: discover.ConsNet},
-					{rw: &conn{flags: staticDialedConn, id: uintID(3)}, netType: discover.ConsNet},
-					{rw: &conn{flags: staticDialedConn, id: uintID(4)}, netType: discover.ConsNet},
-					{rw: &conn{flags: staticDialedConn, id: uintID(5)}, netType: discover.ConsNet},
+					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.CommNet}},
+					{rw: &conn{flags: staticDialedConn, id: uintID(3), netType: discover.CommNet}},
+					{rw: &conn{flags: staticDialedConn, id: uintID(4), netType: discover.CommNet}},
+					{rw: &conn{flags: staticDialedConn, id: uintID(5), netType: discover.CommNet}},
 				},
 				done: []task{
-					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(4)}, netType: discover.ConsNet},
-					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(5)}, netType: discover.ConsNet},
-				},
-				new: []task{
-					&waitExpireTask{Duration: 14 * time.Second, netType: discover.ConsNet},
+					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(4)}},
+					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(5)}},
 				},
 			},
-			// Wait a round for dial history to expire, no new tasks should spawn.
+			// No new tasks should spawn while all static nodes stay connected.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: dynDialedConn, id: uintID(1)}, netType: discover.ConsNet},
-					{rw: &conn{flags: dynDialedConn, id: uintID(2)}, netType: discover.ConsNet},
-					{rw: &conn{flags: staticDialedConn, id: uintID(3)}, netType: discover.ConsNet},
-					{rw: &conn{flags: staticDialedConn, id: uintID(4)}, netType: discover.ConsNet},
-					{rw: &conn{flags: staticDialedConn, id: uintID(5)}, netType: discover.ConsNet},
+					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.CommNet}},
+					{rw: &conn{flags: staticDialedConn, id: uintID(3), netType: discover.CommNet}},
+					{rw: &conn{flags: staticDialedConn, id: uintID(4), netType: discover.CommNet}},
+					{rw: &conn{flags: staticDialedConn, id: uintID(5), netType: discover.CommNet}},
 				},
 			},
 			// If a static node is dropped, it should be immediately redialed,
 			// irrespective whether it was originally static or dynamic.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: dynDialedConn, id: uintID(1)}, netType: discover.ConsNet},
-					{rw: &conn{flags: staticDialedConn, id: uintID(3)}, netType: discover.ConsNet},
-					{rw: &conn{flags: staticDialedConn, id: uintID(5)}, netType: discover.ConsNet},
+					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: staticDialedConn, id: uintID(3), netType: discover.CommNet}},
+					{rw: &conn{flags: staticDialedConn, id: uintID(5), netType: discover.CommNet}},
 				},
 				new: []task{
-					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(2)}, netType: discover.ConsNet},
-					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(4)}, netType: discover.ConsNet},
+					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(2)}},
+					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(4)}},
 				},
 			},
 		},
 	})
 }
 
-// This test checks that past dials are not retried for some time.
+// This test checks that failed static dials are retried right away.
 func TestDialStateCache(t *testing.T) {
 	wantStatic := []*discover.Node{
 		{ID: uintID(1)},
@@ -531,52 +535,42 @@ func TestDialStateCache(t *testing.T) {
 			{
 				peers: nil,
 				new: []task{
-					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(1)}, netType: discover.ConsNet},
-					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(2)}, netType: discover.ConsNet},
-					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(3)}, netType: discover.ConsNet},
+					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(1)}},
+					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(2)}},
+					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(3)}},
 				},
 			},
 			// No new tasks are launched in this round because all static
 			// nodes are either connected or still being dialed.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: staticDialedConn, id: uintID(1), netType: discover.ConsNet}},
-					{rw: &conn{flags: staticDialedConn, id: uintID(2), netType: discover.ConsNet}},
+					{rw: &conn{flags: staticDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: staticDialedConn, id: uintID(2), netType: discover.CommNet}},
 				},
 				done: []task{
-					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(1)}, netType: discover.ConsNet},
-					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(2)}, netType: discover.ConsNet},
+					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(1)}},
+					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(2)}},
 				},
 			},
-			// A salvage task is launched to wait for node 3's history
-			// entry to expire.
+			// The dial to node 3 fails. Finished dials are not kept in the
+			// dial history, so it is retried immediately.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: dynDialedConn, id: uintID(1)}, netType: discover.ConsNet},
-					{rw: &conn{flags: dynDialedConn, id: uintID(2)}, netType: discover.ConsNet},
+					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.CommNet}},
 				},
 				done: []task{
-					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(3)}, netType: discover.ConsNet},
+					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(3)}},
 				},
 				new: []task{
-					&waitExpireTask{Duration: 14 * time.Second, netType: discover.ConsNet},
+					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(3)}},
 				},
 			},
-			// Still waiting for node 3's entry to expire in the cache.
+			// No new tasks while node 3 is still being dialed.
 			{
 				peers: []*Peer{
-					{rw: &conn{flags: dynDialedConn, id: uintID(1)}, netType: discover.ConsNet},
-					{rw: &conn{flags: dynDialedConn, id: uintID(2)}, netType: discover.ConsNet},
-				},
-			},
-			// The cache entry for node 3 has expired and is retried.
-			{
-				peers: []*Peer{
-					{rw: &conn{flags: dynDialedConn, id: uintID(1)}, netType: discover.ConsNet},
-					{rw: &conn{flags: dynDialedConn, id: uintID(2)}, netType: discover.ConsNet},
-				},
-				new: []task{
-					&dialTask{flags: staticDialedConn, dest: &discover.Node{ID: uintID(3)}, netType: discover.ConsNet},
+					{rw: &conn{flags: dynDialedConn, id: uintID(1), netType: discover.CommNet}},
+					{rw: &conn{flags: dynDialedConn, id: uintID(2), netType: discover.CommNet}},
 				},
 			},
 		},
@@ -591,8 +585,8 @@ func TestDialResolve(t *testing.T) {
 	// Check that the task is generated with an incomplete ID.
 	dest := discover.NewNode(uintID(1), nil, 0, 0)
 	state.addStatic(dest)
-	tasks := state.newTasks(0, nil, time.Time{}, discover.ConsNet)
-	if !reflect.DeepEqual(tasks, []task{&dialTask{flags: staticDialedConn, dest: dest, netType: discover.ConsNet}}) {
+	tasks := state.newTasks(0, nil, time.Time{}, false)
+	if !sametasks(tasks, []task{&dialTask{flags: staticDialedConn, dest: dest}}) {
 		t.Fatalf("expected dial task, got %#v", tasks)
 	}
 
@@ -619,7 +613,7 @@ func sametasks(a, b []task) bool {
 next:
 	for _, ta := range a {
 		for _, tb := range b {
-			if reflect.DeepEqual(ta, tb) {
+			if sametask(ta, tb) {
 				continue next
 			}
 		}
@@ -628,6 +622,18 @@ next:
 	return true
 }
 
+// compares two tasks, ignoring the dial state a dial task is bound to.
+func sametask(a, b task) bool {
+	da, ok1 := a.(*dialTask)
+	db, ok2 := b.(*dialTask)
+	if ok1 && ok2 {
+		ca, cb := *da, *db
+		ca.ds, cb.ds = nil, nil
+		return reflect.DeepEqual(&ca, &cb)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
 func uintID(i uint32) discover.NodeID {
 	var id discover.NodeID
 	binary.BigEndian.PutUint32(id[:], i)
@@ -648,5 +654,7 @@ func (t *resolveMock) Resolve(id discover.NodeID) *discover.Node {
 func (t *resolveMock) Self() *discover.Node                                   { return new(discover.Node) }
 func (t *resolveMock) Close()                                                 {}
 func (t *resolveMock) Bootstrap([]*discover.Node)                             {}
+func (t *resolveMock) Delete(discover.NodeID)                                 {}
+func (t *resolveMock) OpenTopNet()                                            {}
 func (t *resolveMock) Lookup(discover.NodeID, byte) []*discover.Node          { return nil }
 func (t *resolveMock) ReadRandomNodes(buf []*discover.Node, netType byte) int { return 0 }
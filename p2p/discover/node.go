@@ -79,8 +79,8 @@ func (n *Node) Incomplete() bool {
 	return n.IP == nil
 }
 
-// checks whether n is a valid complete node.
-func (n *Node) validateComplete() error {
+// ValidateComplete checks whether n is a valid complete node.
+func (n *Node) ValidateComplete() error {
 	if n.Incomplete() {
 		return errors.New("incomplete node")
 	}
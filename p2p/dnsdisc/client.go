@@ -0,0 +1,303 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/p2p/discover"
+	"github.com/Aurorachain-io/go-aoa/p2p/enr"
+)
+
+const (
+	// defaultTimeout is the time a single DNS lookup may take by default.
+	defaultTimeout = 5 * time.Second
+
+	// maxCacheEntries is the number of verified entries kept across syncs, so
+	// unchanged parts of a tree are not looked up again.
+	maxCacheEntries = 4096
+)
+
+var (
+	errNoRoot        = errors.New("no valid root found")
+	errHashMismatch  = errors.New("entry does not match its hash")
+	errENRInLinkTree = errors.New("node record in link tree")
+	errLinkInENRTree = errors.New("link in node tree")
+	errStaleRoot     = errors.New("root older than previously seen")
+)
+
+// Resolver is the DNS interface used by the client to look up tree records.
+type Resolver interface {
+	LookupTXT(ctx context.Context, domain string) ([]string, error)
+}
+
+// Config holds the settings of a tree client.
+type Config struct {
+	Timeout  time.Duration // Time a single DNS lookup may take
+	Resolver Resolver      // DNS resolver, the system resolver by default
+	Logger   log.Logger    // Logger, the root logger by default
+}
+
+// Client retrieves and verifies the node trees published in DNS.
+type Client struct {
+	cfg     Config
+	entries map[string]entry // Verified entries by full domain name
+	seqs    map[string]uint  // Highest root sequence numbers seen by tree domain
+	lock    sync.Mutex
+}
+
+// NewClient creates a tree client, filling in the defaults of unset settings.
+func NewClient(cfg Config) *Client {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.Resolver == nil {
+		cfg.Resolver = net.DefaultResolver
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.Root()
+	}
+	return &Client{cfg: cfg, entries: make(map[string]entry), seqs: make(map[string]uint)}
+}
+
+// SyncTree retrieves the complete tree at the given URL, verifying the signature
+// of its root against the public key of the URL.
+func (c *Client) SyncTree(ctx context.Context, url string) (*Tree, error) {
+	link, err := parseLink(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tree URL %q: %v", url, err)
+	}
+	return c.syncTree(ctx, link)
+}
+
+// Nodes retrieves the trees at the given URLs and all trees linked from them,
+// returning the nodes they contain. Trees failing to resolve are skipped, an
+// error is only returned if none of the trees could be retrieved.
+func (c *Client) Nodes(ctx context.Context, urls []string) ([]*discover.Node, error) {
+	var (
+		queue   []*linkEntry
+		visited = make(map[string]bool)
+		seen    = make(map[discover.NodeID]bool)
+		nodes   []*discover.Node
+		synced  int
+		lastErr error
+	)
+	for _, url := range urls {
+		link, err := parseLink(url)
+		if err != nil {
+			lastErr = fmt.Errorf("invalid tree URL %q: %v", url, err)
+			continue
+		}
+		queue = append(queue, link)
+	}
+	for len(queue) > 0 {
+		link := queue[0]
+		queue = queue[1:]
+		if visited[link.domain] {
+			continue
+		}
+		visited[link.domain] = true
+
+		tree, err := c.syncTree(ctx, link)
+		if err != nil {
+			c.cfg.Logger.Debug("Failed to sync DNS node tree", "domain", link.domain, "err", err)
+			lastErr = err
+			continue
+		}
+		synced++
+		for _, record := range tree.Nodes() {
+			node, err := nodeFromRecord(record)
+			if err != nil {
+				c.cfg.Logger.Trace("Skipping DNS tree node", "domain", link.domain, "err", err)
+				continue
+			}
+			if !seen[node.ID] {
+				seen[node.ID] = true
+				nodes = append(nodes, node)
+			}
+		}
+		for _, e := range tree.entries {
+			if le, ok := e.(*linkEntry); ok {
+				queue = append(queue, le)
+			}
+		}
+	}
+	if synced == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return nodes, nil
+}
+
+// syncTree retrieves the tree the given link points to.
+func (c *Client) syncTree(ctx context.Context, link *linkEntry) (*Tree, error) {
+	root, err := c.resolveRoot(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	tree := &Tree{root: root, entries: make(map[string]entry)}
+	if err := c.syncSubtree(ctx, link.domain, root.eroot, tree, false); err != nil {
+		return nil, err
+	}
+	if err := c.syncSubtree(ctx, link.domain, root.lroot, tree, true); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// syncSubtree retrieves the entry with the given hash and all entries below it,
+// adding them to the tree.
+func (c *Client) syncSubtree(ctx context.Context, domain, hash string, tree *Tree, links bool) error {
+	if _, ok := tree.entries[hash]; ok {
+		return nil
+	}
+	e, err := c.resolveEntry(ctx, domain, hash)
+	if err != nil {
+		return err
+	}
+	switch e := e.(type) {
+	case *branchEntry:
+		for _, child := range e.children {
+			if err := c.syncSubtree(ctx, domain, child, tree, links); err != nil {
+				return err
+			}
+		}
+	case *enrEntry:
+		if links {
+			return errENRInLinkTree
+		}
+	case *linkEntry:
+		if !links {
+			return errLinkInENRTree
+		}
+	}
+	tree.entries[hash] = e
+	return nil
+}
+
+// resolveRoot looks up the root of the tree the given link points to. Roots with
+// a lower sequence number than the last one seen for the tree are rejected.
+func (c *Client) resolveRoot(ctx context.Context, link *linkEntry) (*rootEntry, error) {
+	txts, err := c.lookup(ctx, link.domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, txt := range txts {
+		if !strings.HasPrefix(txt, rootPrefix) {
+			continue
+		}
+		root, err := parseRoot(txt)
+		if err != nil {
+			return nil, err
+		}
+		if !root.verify(link.pubkey) {
+			return nil, errInvalidSig
+		}
+		// Reject replays of older signed roots once a newer one was seen
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		if seq, ok := c.seqs[link.domain]; ok && root.seq < seq {
+			return nil, errStaleRoot
+		}
+		c.seqs[link.domain] = root.seq
+		return root, nil
+	}
+	return nil, errNoRoot
+}
+
+// resolveEntry looks up the entry with the given hash, verifying that its content
+// matches the hash.
+func (c *Client) resolveEntry(ctx context.Context, domain, hash string) (entry, error) {
+	name := hash + "." + domain
+
+	c.lock.Lock()
+	e, ok := c.entries[name]
+	c.lock.Unlock()
+	if ok {
+		return e, nil
+	}
+	txts, err := c.lookup(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, txt := range txts {
+		e, err := parseEntry(txt)
+		if err == errUnknownEntry {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid entry at %s: %v", name, err)
+		}
+		if subdomain(e) != hash {
+			return nil, errHashMismatch
+		}
+		c.lock.Lock()
+		if len(c.entries) >= maxCacheEntries {
+			c.entries = make(map[string]entry)
+		}
+		c.entries[name] = e
+		c.lock.Unlock()
+		return e, nil
+	}
+	return nil, fmt.Errorf("no entry found at %s", name)
+}
+
+// lookup retrieves the TXT records of the given domain name.
+func (c *Client) lookup(ctx context.Context, name string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	return c.cfg.Resolver.LookupTXT(ctx, name)
+}
+
+// nodeFromRecord converts a node record of a tree into a discovery node, which
+// requires the record to contain an IP address and both ports.
+func nodeFromRecord(record *enr.Record) (*discover.Node, error) {
+	var (
+		key enr.Secp256k1
+		ip4 enr.IP4
+		ip6 enr.IP6
+		ip  net.IP
+		tcp enr.TCP
+		udp enr.UDP
+	)
+	if err := record.Load(&key); err != nil {
+		return nil, err
+	}
+	if err := record.Load(&ip4); err == nil {
+		ip = net.IP(ip4)
+	} else if err := record.Load(&ip6); err == nil {
+		ip = net.IP(ip6)
+	} else {
+		return nil, err
+	}
+	if err := record.Load(&tcp); err != nil {
+		return nil, err
+	}
+	if err := record.Load(&udp); err != nil {
+		return nil, err
+	}
+	pubkey := ecdsa.PublicKey(key)
+	return discover.NewNode(discover.PubkeyID(&pubkey), ip, uint16(udp), uint16(tcp)), nil
+}
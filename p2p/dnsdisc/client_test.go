@@ -0,0 +1,191 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/p2p/discover"
+	"github.com/Aurorachain-io/go-aoa/p2p/enr"
+)
+
+// mapResolver is a DNS resolver serving TXT records from memory.
+type mapResolver map[string]string
+
+func (mr mapResolver) add(records map[string]string) {
+	for name, txt := range records {
+		mr[name] = txt
+	}
+}
+
+func (mr mapResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if txt, ok := mr[name]; ok {
+		return []string{txt}, nil
+	}
+	return nil, fmt.Errorf("no such host: %s", name)
+}
+
+// testRecords creates n signed node records with distinct endpoints.
+func testRecords(t *testing.T, n int) ([]*enr.Record, []discover.NodeID) {
+	records := make([]*enr.Record, n)
+	ids := make([]discover.NodeID, n)
+	for i := range records {
+		key, _ := crypto.GenerateKey()
+		records[i] = new(enr.Record)
+		records[i].Set(enr.IP4(net.IP{10, 0, byte(i >> 8), byte(i)}))
+		records[i].Set(enr.TCP(30303))
+		records[i].Set(enr.UDP(30303))
+		if err := records[i].Sign(key); err != nil {
+			t.Fatalf("failed to sign record: %v", err)
+		}
+		ids[i] = discover.PubkeyID(&key.PublicKey)
+	}
+	return records, ids
+}
+
+// publish signs a tree of the given records and links, adding its records to the
+// resolver and returning its URL.
+func publish(t *testing.T, resolver mapResolver, key *ecdsa.PrivateKey, domain string, records []*enr.Record, links []string) string {
+	tree, err := MakeTree(1, records, links)
+	if err != nil {
+		t.Fatalf("failed to make tree: %v", err)
+	}
+	url, err := tree.Sign(key, domain)
+	if err != nil {
+		t.Fatalf("failed to sign tree: %v", err)
+	}
+	resolver.add(tree.ToTXT(domain))
+	return url
+}
+
+// Tests that the client retrieves the nodes of a tree and of the trees linked
+// from it, including trees large enough to need multiple levels of branches.
+func TestClientNodes(t *testing.T) {
+	var (
+		resolver = make(mapResolver)
+		key1, _  = crypto.GenerateKey()
+		key2, _  = crypto.GenerateKey()
+	)
+	records, ids := testRecords(t, 3*maxChildren)
+	linked := publish(t, resolver, key2, "linked.example.org", records[3:], nil)
+	url := publish(t, resolver, key1, "nodes.example.org", records[:3], []string{linked})
+
+	client := NewClient(Config{Resolver: resolver})
+	nodes, err := client.Nodes(context.Background(), []string{url})
+	if err != nil {
+		t.Fatalf("failed to retrieve nodes: %v", err)
+	}
+	if len(nodes) != len(ids) {
+		t.Fatalf("node count mismatch: have %d, want %d", len(nodes), len(ids))
+	}
+	found := make(map[discover.NodeID]bool)
+	for _, node := range nodes {
+		found[node.ID] = true
+		if node.TCP != 30303 || node.UDP != 30303 {
+			t.Errorf("node %x: endpoint mismatch: have %d/%d, want 30303", node.ID[:8], node.TCP, node.UDP)
+		}
+	}
+	for _, id := range ids {
+		if !found[id] {
+			t.Errorf("node %x missing", id[:8])
+		}
+	}
+}
+
+// Tests that trees signed by a key other than the one of the URL, or containing
+// entries not matching their hash, are rejected.
+func TestClientVerification(t *testing.T) {
+	var (
+		resolver = make(mapResolver)
+		key, _   = crypto.GenerateKey()
+		other, _ = crypto.GenerateKey()
+	)
+	records, _ := testRecords(t, 2)
+	url := publish(t, resolver, key, "nodes.example.org", records, nil)
+
+	// Re-sign the tree with another key, the URL still names the original one
+	forged, err := MakeTree(2, records, nil)
+	if err != nil {
+		t.Fatalf("failed to make tree: %v", err)
+	}
+	forged.Sign(other, "nodes.example.org")
+	resolver["nodes.example.org"] = forged.ToTXT("nodes.example.org")["nodes.example.org"]
+
+	if _, err := NewClient(Config{Resolver: resolver}).SyncTree(context.Background(), url); err != errInvalidSig {
+		t.Errorf("forged root: error mismatch: have %v, want %v", err, errInvalidSig)
+	}
+	// Publish a valid root, but swap the records of two entries
+	url = publish(t, resolver, key, "nodes.example.org", records, nil)
+	var names []string
+	for name, txt := range resolver {
+		if name != "nodes.example.org" && txt[:len(enrPrefix)] == enrPrefix {
+			names = append(names, name)
+		}
+	}
+	resolver[names[0]], resolver[names[1]] = resolver[names[1]], resolver[names[0]]
+
+	if _, err := NewClient(Config{Resolver: resolver}).SyncTree(context.Background(), url); err != errHashMismatch {
+		t.Errorf("swapped entries: error mismatch: have %v, want %v", err, errHashMismatch)
+	}
+}
+
+// Tests that an older signed root of a tree is rejected once a newer one was
+// retrieved, so that outdated trees can't be replayed.
+func TestClientRootReplay(t *testing.T) {
+	var (
+		resolver = make(mapResolver)
+		key, _   = crypto.GenerateKey()
+		domain   = "nodes.example.org"
+	)
+	records, _ := testRecords(t, 2)
+	roots := make(map[uint]string)
+	var url string
+	for _, seq := range []uint{1, 2} {
+		tree, err := MakeTree(seq, records[:seq], nil)
+		if err != nil {
+			t.Fatalf("failed to make tree %d: %v", seq, err)
+		}
+		if url, err = tree.Sign(key, domain); err != nil {
+			t.Fatalf("failed to sign tree %d: %v", seq, err)
+		}
+		txts := tree.ToTXT(domain)
+		resolver.add(txts)
+		roots[seq] = txts[domain]
+	}
+	client := NewClient(Config{Resolver: resolver})
+	tree, err := client.SyncTree(context.Background(), url)
+	if err != nil {
+		t.Fatalf("failed to sync tree: %v", err)
+	}
+	if tree.Seq() != 2 {
+		t.Fatalf("tree seq mismatch: have %d, want 2", tree.Seq())
+	}
+	// Serve the validly signed, but older root again
+	resolver[domain] = roots[1]
+	if _, err := client.SyncTree(context.Background(), url); err != errStaleRoot {
+		t.Errorf("replayed root: error mismatch: have %v, want %v", err, errStaleRoot)
+	}
+	// A fresh client has no record of the newer root
+	if _, err := NewClient(Config{Resolver: resolver}).SyncTree(context.Background(), url); err != nil {
+		t.Errorf("failed to sync older tree with fresh client: %v", err)
+	}
+}
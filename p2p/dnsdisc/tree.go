@@ -0,0 +1,324 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package dnsdisc implements node discovery via signed DNS trees of node
+// records (EIP-1459).
+//
+// A tree is published as a set of TXT records below a domain. The root record
+// at the domain itself is signed and references the roots of two subtrees, one
+// holding node records and one holding links to the trees of other domains.
+// All other records are named after the hash of their content, so the whole
+// tree can be verified starting from the signed root.
+package dnsdisc
+
+import (
+	"crypto/ecdsa"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/p2p/enr"
+)
+
+const (
+	rootPrefix   = "enrtree-root:v1"
+	linkPrefix   = "enrtree://"
+	branchPrefix = "enrtree-branch:"
+	enrPrefix    = "enr:"
+
+	// maxChildren is the number of children of a branch entry, keeping the TXT
+	// record below the size of a single UDP DNS response.
+	maxChildren = 370 / (b32HashLength + 1)
+
+	b32HashLength = 26 // length of a base32 encoded 16 byte hash
+	sigLength     = 65 // length of a root signature including the recovery id
+)
+
+// b32format is the encoding of entry hashes and link public keys.
+var b32format = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+var (
+	errUnknownEntry = errors.New("unknown entry type")
+	errNoPubkey     = errors.New("missing public key")
+	errBadPubkey    = errors.New("invalid public key")
+	errInvalidENR   = errors.New("invalid node record")
+	errInvalidChild = errors.New("invalid child hash")
+	errInvalidSig   = errors.New("invalid root signature")
+	errSyntax       = errors.New("invalid syntax")
+)
+
+// Tree is a merkle tree of node records and links to other trees.
+type Tree struct {
+	root    *rootEntry
+	entries map[string]entry
+}
+
+// MakeTree creates a tree of the given node records and links. The tree must
+// be signed before it can be published.
+func MakeTree(seq uint, nodes []*enr.Record, links []string) (*Tree, error) {
+	// Sort the records and links, so that the same lists always yield the
+	// same tree
+	records := make([]entry, 0, len(nodes))
+	for _, node := range nodes {
+		if !node.Signed() {
+			return nil, errInvalidENR
+		}
+		records = append(records, &enrEntry{node})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].String() < records[j].String()
+	})
+	sortedLinks := append([]string(nil), links...)
+	sort.Strings(sortedLinks)
+
+	linkEntries := make([]entry, 0, len(sortedLinks))
+	for _, link := range sortedLinks {
+		le, err := parseLink(link)
+		if err != nil {
+			return nil, err
+		}
+		linkEntries = append(linkEntries, le)
+	}
+	t := &Tree{entries: make(map[string]entry)}
+	eroot := t.build(records)
+	t.entries[subdomain(eroot)] = eroot
+	lroot := t.build(linkEntries)
+	t.entries[subdomain(lroot)] = lroot
+	t.root = &rootEntry{seq: seq, eroot: subdomain(eroot), lroot: subdomain(lroot)}
+	return t, nil
+}
+
+// build adds the given leaves to the tree below a hierarchy of branch entries,
+// returning the topmost branch.
+func (t *Tree) build(leaves []entry) entry {
+	if len(leaves) == 0 {
+		return &branchEntry{}
+	}
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	if len(leaves) <= maxChildren {
+		children := make([]string, len(leaves))
+		for i, leaf := range leaves {
+			children[i] = subdomain(leaf)
+			t.entries[children[i]] = leaf
+		}
+		return &branchEntry{children}
+	}
+	var subtrees []entry
+	for len(leaves) > 0 {
+		n := maxChildren
+		if len(leaves) < n {
+			n = len(leaves)
+		}
+		subtrees = append(subtrees, t.build(leaves[:n]))
+		leaves = leaves[n:]
+	}
+	return t.build(subtrees)
+}
+
+// Sign signs the tree with the given key, returning the URL of the tree
+// published at the given domain.
+func (t *Tree) Sign(key *ecdsa.PrivateKey, domain string) (string, error) {
+	sig, err := crypto.Sign(t.root.sigHash(), key)
+	if err != nil {
+		return "", err
+	}
+	t.root.sig = sig
+	return (&linkEntry{domain: domain, pubkey: &key.PublicKey}).String(), nil
+}
+
+// Seq returns the sequence number of the tree.
+func (t *Tree) Seq() uint {
+	return t.root.seq
+}
+
+// Nodes returns all node records contained in the tree.
+func (t *Tree) Nodes() []*enr.Record {
+	var nodes []*enr.Record
+	for _, e := range t.entries {
+		if ee, ok := e.(*enrEntry); ok {
+			nodes = append(nodes, ee.node)
+		}
+	}
+	return nodes
+}
+
+// Links returns the URLs of all trees linked from the tree.
+func (t *Tree) Links() []string {
+	var links []string
+	for _, e := range t.entries {
+		if le, ok := e.(*linkEntry); ok {
+			links = append(links, le.String())
+		}
+	}
+	return links
+}
+
+// ToTXT returns the TXT records of the tree published at the given domain,
+// keyed by their full domain name.
+func (t *Tree) ToTXT(domain string) map[string]string {
+	records := map[string]string{domain: t.root.String()}
+	for hash, e := range t.entries {
+		records[hash+"."+domain] = e.String()
+	}
+	return records
+}
+
+// entry is a single record of a tree.
+type entry interface {
+	fmt.Stringer
+}
+
+type (
+	rootEntry struct {
+		eroot string
+		lroot string
+		seq   uint
+		sig   []byte
+	}
+	branchEntry struct {
+		children []string
+	}
+	enrEntry struct {
+		node *enr.Record
+	}
+	linkEntry struct {
+		domain string
+		pubkey *ecdsa.PublicKey
+	}
+)
+
+func (e *rootEntry) sigHash() []byte {
+	return crypto.Keccak256([]byte(fmt.Sprintf("%s e=%s l=%s seq=%d", rootPrefix, e.eroot, e.lroot, e.seq)))
+}
+
+func (e *rootEntry) verify(pubkey *ecdsa.PublicKey) bool {
+	if len(e.sig) != sigLength {
+		return false
+	}
+	return crypto.VerifySignature(crypto.CompressPubkey(pubkey), e.sigHash(), e.sig[:sigLength-1])
+}
+
+func (e *rootEntry) String() string {
+	return fmt.Sprintf("%s e=%s l=%s seq=%d sig=%s", rootPrefix, e.eroot, e.lroot, e.seq, base64.RawURLEncoding.EncodeToString(e.sig))
+}
+
+func (e *branchEntry) String() string {
+	return branchPrefix + strings.Join(e.children, ",")
+}
+
+func (e *enrEntry) String() string {
+	text, _ := e.node.Text()
+	return text
+}
+
+func (e *linkEntry) String() string {
+	return linkPrefix + b32format.EncodeToString(crypto.CompressPubkey(e.pubkey)) + "@" + e.domain
+}
+
+// subdomain returns the name of the record holding the given entry, which is
+// the base32 encoding of the truncated hash of its content.
+func subdomain(e entry) string {
+	return b32format.EncodeToString(crypto.Keccak256([]byte(e.String()))[:16])
+}
+
+// parseRoot parses the signed root record of a tree.
+func parseRoot(text string) (*rootEntry, error) {
+	var (
+		e   rootEntry
+		sig string
+	)
+	if _, err := fmt.Sscanf(text, rootPrefix+" e=%s l=%s seq=%d sig=%s", &e.eroot, &e.lroot, &e.seq, &sig); err != nil {
+		return nil, errSyntax
+	}
+	if !isValidHash(e.eroot) || !isValidHash(e.lroot) {
+		return nil, errInvalidChild
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || len(raw) != sigLength {
+		return nil, errInvalidSig
+	}
+	e.sig = raw
+	return &e, nil
+}
+
+// parseEntry parses a non-root record of a tree.
+func parseEntry(text string) (entry, error) {
+	switch {
+	case strings.HasPrefix(text, linkPrefix):
+		return parseLink(text)
+	case strings.HasPrefix(text, branchPrefix):
+		return parseBranch(text)
+	case strings.HasPrefix(text, enrPrefix):
+		node, err := enr.ParseText(text)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", errInvalidENR, err)
+		}
+		return &enrEntry{node}, nil
+	default:
+		return nil, errUnknownEntry
+	}
+}
+
+func parseBranch(text string) (entry, error) {
+	text = strings.TrimPrefix(text, branchPrefix)
+	if text == "" {
+		return &branchEntry{}, nil
+	}
+	children := strings.Split(text, ",")
+	for _, child := range children {
+		if !isValidHash(child) {
+			return nil, errInvalidChild
+		}
+	}
+	return &branchEntry{children}, nil
+}
+
+// parseLink parses a tree URL of the form enrtree://<public key>@<domain>.
+func parseLink(text string) (*linkEntry, error) {
+	if !strings.HasPrefix(text, linkPrefix) {
+		return nil, errSyntax
+	}
+	text = strings.TrimPrefix(text, linkPrefix)
+	pos := strings.IndexByte(text, '@')
+	if pos < 0 {
+		return nil, errNoPubkey
+	}
+	key, domain := text[:pos], text[pos+1:]
+	if domain == "" {
+		return nil, errSyntax
+	}
+	raw, err := b32format.DecodeString(key)
+	if err != nil {
+		return nil, errBadPubkey
+	}
+	pubkey, err := crypto.DecompressPubkey(raw)
+	if err != nil {
+		return nil, errBadPubkey
+	}
+	return &linkEntry{domain: domain, pubkey: pubkey}, nil
+}
+
+// isValidHash reports whether the given string is a base32 encoded entry hash.
+func isValidHash(s string) bool {
+	raw, err := b32format.DecodeString(s)
+	return err == nil && len(raw) == 16 && len(s) == b32HashLength
+}
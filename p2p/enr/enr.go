@@ -30,10 +30,12 @@ package enr
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
 	"sort"
+	"strings"
 
 	"github.com/Aurorachain-io/go-aoa/crypto"
 	"github.com/Aurorachain-io/go-aoa/crypto/sha3"
@@ -44,6 +46,8 @@ const SizeLimit = 300 // maximum encoded size of a node record in bytes
 
 const ID_SECP256k1_KECCAK = ID("secp256k1-keccak") // the default identity scheme
 
+const textPrefix = "enr:" // prefix of the text form of node records
+
 var (
 	errNoID           = errors.New("unknown or unspecified identity scheme")
 	errInvalidSigsize = errors.New("invalid signature size")
@@ -54,6 +58,7 @@ var (
 	errTooBig         = fmt.Errorf("record bigger than %d bytes", SizeLimit)
 	errEncodeUnsigned = errors.New("can't encode unsigned record")
 	errNotFound       = errors.New("no such key in record")
+	errTextPrefix     = errors.New("missing \"enr:\" prefix")
 )
 
 // Record represents a node record. The zero value is an empty record.
@@ -288,3 +293,28 @@ func (r *Record) verifySignature() error {
 	}
 	return nil
 }
+
+// Text returns the text form of a signed record, "enr:" followed by the URL-safe,
+// unpadded base64 encoding of the record.
+func (r *Record) Text() (string, error) {
+	if r.signature == nil {
+		return "", errEncodeUnsigned
+	}
+	return textPrefix + base64.RawURLEncoding.EncodeToString(r.raw), nil
+}
+
+// ParseText decodes the text form of a record, verifying its signature.
+func ParseText(text string) (*Record, error) {
+	if !strings.HasPrefix(text, textPrefix) {
+		return nil, errTextPrefix
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(text[len(textPrefix):])
+	if err != nil {
+		return nil, err
+	}
+	var r Record
+	if err := rlp.DecodeBytes(raw, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
@@ -224,6 +224,31 @@ func TestSignEncodeAndDecode(t *testing.T) {
 	assert.Equal(t, blob, blob2)
 }
 
+// TestTextEncoding tests the text form of a record.
+func TestTextEncoding(t *testing.T) {
+	var r Record
+	_, err := r.Text()
+	require.Equal(t, errEncodeUnsigned, err)
+
+	r.Set(IP4{127, 0, 0, 1})
+	r.Set(TCP(30303))
+	r.Set(UDP(30303))
+	require.NoError(t, r.Sign(privkey))
+
+	text, err := r.Text()
+	require.NoError(t, err)
+	r2, err := ParseText(text)
+	require.NoError(t, err)
+	assert.Equal(t, r, *r2)
+
+	var port TCP
+	require.NoError(t, r2.Load(&port))
+	assert.Equal(t, TCP(30303), port)
+
+	_, err = ParseText(text[len(textPrefix):])
+	assert.Equal(t, errTextPrefix, err)
+}
+
 func TestNodeAddr(t *testing.T) {
 	var r Record
 	if addr := r.NodeAddr(); addr != nil {
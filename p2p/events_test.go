@@ -0,0 +1,116 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/common/mclock"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/event"
+	"github.com/Aurorachain-io/go-aoa/p2p/discover"
+	"github.com/Aurorachain-io/go-aoa/rlp"
+)
+
+// Tests that connections failing the handshakes are reported on the event feed
+// of the server.
+func TestServerHandshakeFailEvent(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	srv := &Server{Config: Config{
+		PrivateKey:      key,
+		MaxPeers:        10,
+		ListenAddr:      "127.0.0.1:0",
+		NoDiscovery:     true,
+		EnableMsgEvents: true,
+	}}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer srv.Stop()
+
+	events := make(chan *PeerEvent, 1)
+	sub := srv.SubscribeEvents(events)
+	defer sub.Unsubscribe()
+
+	fd, err := net.Dial("tcp", srv.ListenAddr)
+	if err != nil {
+		t.Fatalf("failed to dial server: %v", err)
+	}
+	fd.Write([]byte("not a handshake"))
+	fd.Close()
+
+	select {
+	case ev := <-events:
+		if ev.Type != PeerEventTypeHandshakeFail {
+			t.Fatalf("event type mismatch: have %q, want %q", ev.Type, PeerEventTypeHandshakeFail)
+		}
+		if ev.RemoteAddress != fd.LocalAddr().String() {
+			t.Errorf("remote address mismatch: have %s, want %s", ev.RemoteAddress, fd.LocalAddr())
+		}
+		if ev.Error == "" {
+			t.Error("missing handshake error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handshake failure not reported")
+	}
+}
+
+// Tests that the answer to a ping reports the round trip time of the peer.
+func TestPeerLatencyEvent(t *testing.T) {
+	var feed event.Feed
+	events := make(chan *PeerEvent, 1)
+	sub := feed.Subscribe(events)
+	defer sub.Unsubscribe()
+
+	key, _ := crypto.GenerateKey()
+	fd, _ := net.Pipe()
+	peer := newPeer(&conn{fd: fd, id: discover.PubkeyID(&key.PublicKey)}, nil)
+	peer.events = &feed
+
+	// Unsolicited pongs are ignored
+	pong := func() Msg {
+		size, payload, _ := rlp.EncodeToReader([]interface{}{})
+		return Msg{Code: pongMsg, Size: uint32(size), Payload: payload}
+	}
+	if err := peer.handle(pong()); err != nil {
+		t.Fatalf("failed to handle pong: %v", err)
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected event for unsolicited pong: %v", ev.Type)
+	default:
+	}
+	// Pongs to a ping report the time since the ping
+	atomic.StoreInt64(&peer.pingSent, int64(mclock.Now()-mclock.AbsTime(50*time.Millisecond)))
+	if err := peer.handle(pong()); err != nil {
+		t.Fatalf("failed to handle pong: %v", err)
+	}
+	select {
+	case ev := <-events:
+		if ev.Type != PeerEventTypeLatency || ev.Peer != peer.ID() {
+			t.Fatalf("event mismatch: have %s of %x, want %s of %x", ev.Type, ev.Peer[:8], PeerEventTypeLatency, peer.ID().Bytes()[:8])
+		}
+		if *ev.Latency < 50*time.Millisecond {
+			t.Errorf("latency too low: have %v, want >= %v", *ev.Latency, 50*time.Millisecond)
+		}
+	default:
+		t.Fatal("latency not reported")
+	}
+}
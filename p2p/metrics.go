@@ -29,6 +29,14 @@ var (
 	ingressTrafficMeter = metrics.NewMeter("p2p/InboundTraffic")
 	egressConnectMeter  = metrics.NewMeter("p2p/OutboundConnects")
 	egressTrafficMeter  = metrics.NewMeter("p2p/OutboundTraffic")
+
+	// Sizes of the messages before and after snappy compression, along with the
+	// compressed size of each outbound message in percent of its plain size
+	ingressPlainMeter      = metrics.NewMeter("p2p/compression/in/plain")
+	ingressCompressedMeter = metrics.NewMeter("p2p/compression/in/compressed")
+	egressPlainMeter       = metrics.NewMeter("p2p/compression/out/plain")
+	egressCompressedMeter  = metrics.NewMeter("p2p/compression/out/compressed")
+	egressRatioHistogram   = metrics.NewHistogram("p2p/compression/out/ratio")
 )
 
 // meteredConn is a wrapper around a network TCP connection that meters both the
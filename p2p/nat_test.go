@@ -0,0 +1,51 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/p2p/nat"
+)
+
+// Tests that the node advertises the external IP of its NAT mechanism and the
+// configured external port instead of its local listening endpoint.
+func TestServerExternalEndpoint(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	srv := &Server{Config: Config{
+		PrivateKey:   key,
+		MaxPeers:     10,
+		ListenAddr:   "127.0.0.1:0",
+		NoDiscovery:  true,
+		NAT:          nat.ExtIP(net.IP{10, 1, 2, 3}),
+		ExternalPort: 40404,
+	}}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer srv.Stop()
+
+	self := srv.Self()
+	if !self.IP.Equal(net.IP{10, 1, 2, 3}) {
+		t.Errorf("advertised IP mismatch: have %v, want %v", self.IP, net.IP{10, 1, 2, 3})
+	}
+	if self.TCP != 40404 {
+		t.Errorf("advertised port mismatch: have %d, want %d", self.TCP, 40404)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/p2p/discover"
+)
+
+// Tests that snappy compression is negotiated from the base protocol versions
+// exchanged in the handshake.
+func TestCompressionNegotiation(t *testing.T) {
+	tests := []struct {
+		dialer, listener uint64
+		compressed       bool
+	}{
+		{snappyProtocolVersion, snappyProtocolVersion, true},
+		{snappyProtocolVersion - 1, snappyProtocolVersion - 1, false},
+	}
+	for i, tt := range tests {
+		var (
+			prv0, _ = crypto.GenerateKey()
+			prv1, _ = crypto.GenerateKey()
+			node1   = &discover.Node{ID: discover.PubkeyID(&prv1.PublicKey), IP: net.IP{5, 6, 7, 8}, TCP: 44}
+
+			fd0, fd1 = net.Pipe()
+			t0, t1   = newRLPX(fd0).(*rlpx), newRLPX(fd1).(*rlpx)
+			errc     = make(chan error, 1)
+		)
+		go func() {
+			if _, err := t1.doEncHandshake(prv1, nil); err != nil {
+				errc <- err
+				return
+			}
+			hs := &protoHandshake{Version: tt.listener, ID: node1.ID}
+			if _, err := t1.doProtoHandshake(hs); err != nil {
+				errc <- err
+				return
+			}
+			// Echo a message back, exercising the negotiated encoding
+			msg, err := t1.ReadMsg()
+			if err != nil {
+				errc <- err
+				return
+			}
+			errc <- t1.WriteMsg(msg)
+		}()
+		if _, err := t0.doEncHandshake(prv0, node1); err != nil {
+			t.Fatalf("test %d: encryption handshake failed: %v", i, err)
+		}
+		hs := &protoHandshake{Version: tt.dialer, ID: discover.PubkeyID(&prv0.PublicKey)}
+		if _, err := t0.doProtoHandshake(hs); err != nil {
+			t.Fatalf("test %d: protocol handshake failed: %v", i, err)
+		}
+		if err := Send(t0, 0x10, []string{"ping"}); err != nil {
+			t.Fatalf("test %d: failed to send message: %v", i, err)
+		}
+		if err := ExpectMsg(t0, 0x10, []string{"ping"}); err != nil {
+			t.Fatalf("test %d: echo mismatch: %v", i, err)
+		}
+		if err := <-errc; err != nil {
+			t.Fatalf("test %d: listener failed: %v", i, err)
+		}
+		if t0.rw.snappy != tt.compressed || t1.rw.snappy != tt.compressed {
+			t.Errorf("test %d: compression mismatch: dialer %v, listener %v, want %v", i, t0.rw.snappy, t1.rw.snappy, tt.compressed)
+		}
+		fd0.Close()
+		fd1.Close()
+	}
+}
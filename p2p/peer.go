@@ -22,6 +22,7 @@ import (
 	"net"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Aurorachain-io/go-aoa/common/mclock"
@@ -82,17 +83,27 @@ const (
 	// PeerEventTypeMsgRecv is the type of event emitted when a
 	// message is received from a peer
 	PeerEventTypeMsgRecv PeerEventType = "msgrecv"
+
+	// PeerEventTypeHandshakeFail is the type of event emitted when a
+	// connection fails the handshakes or is rejected before becoming a peer
+	PeerEventTypeHandshakeFail PeerEventType = "handshakefail"
+
+	// PeerEventTypeLatency is the type of event emitted when a peer
+	// answers a ping, carrying the measured round trip time
+	PeerEventTypeLatency PeerEventType = "latency"
 )
 
 // PeerEvent is an event emitted when peers are either added or dropped from
 // a p2p.Server or when a message is sent or received on a peer connection
 type PeerEvent struct {
-	Type     PeerEventType   `json:"type"`
-	Peer     discover.NodeID `json:"peer"`
-	Error    string          `json:"error,omitempty"`
-	Protocol string          `json:"protocol,omitempty"`
-	MsgCode  *uint64         `json:"msg_code,omitempty"`
-	MsgSize  *uint32         `json:"msg_size,omitempty"`
+	Type          PeerEventType   `json:"type"`
+	Peer          discover.NodeID `json:"peer"`
+	RemoteAddress string          `json:"remote_addr,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	Protocol      string          `json:"protocol,omitempty"`
+	MsgCode       *uint64         `json:"msg_code,omitempty"`
+	MsgSize       *uint32         `json:"msg_size,omitempty"`
+	Latency       *time.Duration  `json:"latency,omitempty"`
 }
 
 // Peer represents a connected remote node.
@@ -109,6 +120,11 @@ type Peer struct {
 
 	// events receives message send / receive events if set
 	events *event.Feed
+
+	pingSent int64 // Time the unanswered ping was sent (mclock.AbsTime), zero if none
+
+	// reputation tracks the misbehaviours of the peer if set
+	reputation *reputation
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -184,6 +200,25 @@ func newPeer(conn *conn, protocols []Protocol) *Peer {
 	return p
 }
 
+// Priority returns whether the peer is a priority node, which may occupy the
+// connection slots reserved for them.
+func (p *Peer) Priority() bool {
+	return p.rw.is(priorityConn)
+}
+
+// Penalize reports a misbehaviour of the peer, disconnecting and banning it if
+// it misbehaved too much. Trusted, static and priority peers are never banned,
+// as the node keeps them connected on purpose.
+func (p *Peer) Penalize(kind Misbehaviour) {
+	if p.reputation == nil || p.rw.is(trustedConn|staticDialedConn|priorityConn) {
+		return
+	}
+	if timeout := p.reputation.penalize(p.ID(), kind); timeout > 0 {
+		p.log.Info("Banning misbehaving peer", "reason", kind, "timeout", timeout)
+		p.Disconnect(DiscUselessPeer)
+	}
+}
+
 func (p *Peer) Log() log.Logger {
 	return p.log
 }
@@ -244,6 +279,7 @@ func (p *Peer) pingLoop() {
 	for {
 		select {
 		case <-ping.C:
+			atomic.StoreInt64(&p.pingSent, int64(mclock.Now()))
 			if err := SendItems(p.rw, pingMsg); err != nil {
 				p.protoErr <- err
 				return
@@ -276,6 +312,18 @@ func (p *Peer) handle(msg Msg) error {
 	case msg.Code == pingMsg:
 		msg.Discard()
 		go SendItems(p.rw, pongMsg)
+	case msg.Code == pongMsg:
+		// Like message events, latencies are only reported if enabled, as
+		// the send blocks the read loop until all subscribers received it
+		if sent := atomic.SwapInt64(&p.pingSent, 0); sent != 0 && p.events != nil {
+			latency := time.Duration(mclock.Now() - mclock.AbsTime(sent))
+			p.events.Send(&PeerEvent{
+				Type:    PeerEventTypeLatency,
+				Peer:    p.ID(),
+				Latency: &latency,
+			})
+		}
+		return msg.Discard()
 	case msg.Code == discMsg:
 		var reason [1]DiscReason
 		// This is the last message. We don't need to discard or
@@ -425,6 +473,8 @@ type PeerInfo struct {
 	Network struct {
 		LocalAddress  string `json:"localAddress"`  // Local endpoint of the TCP data connection
 		RemoteAddress string `json:"remoteAddress"` // Remote endpoint of the TCP data connection
+		Version       uint64 `json:"version"`       // Base protocol version of the remote node
+		Compressed    bool   `json:"compressed"`    // Whether messages are snappy compressed
 	} `json:"network"`
 	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
 }
@@ -446,6 +496,8 @@ func (p *Peer) Info() *PeerInfo {
 	}
 	info.Network.LocalAddress = p.LocalAddr().String()
 	info.Network.RemoteAddress = p.RemoteAddr().String()
+	info.Network.Version = p.rw.version
+	info.Network.Compressed = p.rw.version >= snappyProtocolVersion
 
 	// Gather all the running protocol infos
 	for _, proto := range p.running {
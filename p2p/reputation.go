@@ -0,0 +1,169 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/common/mclock"
+	"github.com/Aurorachain-io/go-aoa/metrics"
+	"github.com/Aurorachain-io/go-aoa/p2p/discover"
+)
+
+// Misbehaviour is a kind of misbehaviour of a remote peer, worsening its score.
+type Misbehaviour int
+
+const (
+	UselessMessage   Misbehaviour = iota // Malformed, unexpected or oversized message
+	RequestTimeout                       // Request not answered in time
+	StallingPeer                         // Peer stalling or failing a synchronisation
+	InvalidBlock                         // Block failing validation
+	IncompatiblePeer                     // Peer of another network, genesis or fork schedule
+	numMisbehaviours
+)
+
+// String implements fmt.Stringer.
+func (m Misbehaviour) String() string {
+	switch m {
+	case UselessMessage:
+		return "useless message"
+	case RequestTimeout:
+		return "request timeout"
+	case StallingPeer:
+		return "stalling peer"
+	case InvalidBlock:
+		return "invalid block"
+	case IncompatiblePeer:
+		return "incompatible peer"
+	default:
+		return "unknown misbehaviour"
+	}
+}
+
+// misbehaviourPenalties are the points each kind of misbehaviour adds to the
+// score of a peer, a score reaching banScore getting the peer banned. Only
+// incompatible peers are banned right away, as a single invalid block may be
+// relayed in good faith.
+var misbehaviourPenalties = [numMisbehaviours]float64{
+	UselessMessage:   10,
+	RequestTimeout:   20,
+	StallingPeer:     50,
+	InvalidBlock:     50,
+	IncompatiblePeer: banScore,
+}
+
+const (
+	banScore         = 100              // Score at which a peer gets banned
+	scoreHalfLife    = 10 * time.Minute // Time for the score of a peer to recover by half
+	baseBanTimeout   = time.Minute      // Duration of the first ban of a peer, doubled with each further ban
+	maxBanTimeout    = 24 * time.Hour   // Maximum duration of a ban
+	maxTrackedScores = 1024             // Number of peer scores tracked before forgotten ones are pruned
+)
+
+var (
+	penaltyMeter = metrics.NewMeter("p2p/reputation/penalties") // Misbehaviours reported for peers
+	banMeter     = metrics.NewMeter("p2p/reputation/bans")      // Peers banned for misbehaving
+)
+
+// peerScore is the misbehaviour record of a remote node.
+type peerScore struct {
+	score   float64                  // Penalty points, decaying over time
+	updated mclock.AbsTime           // Time the score was last decayed
+	counts  [numMisbehaviours]uint64 // Number of reported misbehaviours by kind
+	bans    uint                     // Number of times the node was banned
+	until   mclock.AbsTime           // End of the current ban
+}
+
+// decay lowers the score of the node by the time passed since the last update.
+func (s *peerScore) decay(now mclock.AbsTime) {
+	if elapsed := time.Duration(now - s.updated); elapsed > 0 {
+		s.score *= math.Pow(0.5, float64(elapsed)/float64(scoreHalfLife))
+	}
+	s.updated = now
+}
+
+// reputation tracks the scores of remote nodes, banning the ones misbehaving
+// too much for exponentially growing timeouts.
+type reputation struct {
+	scores map[discover.NodeID]*peerScore
+	clock  func() mclock.AbsTime
+	lock   sync.Mutex
+}
+
+func newReputation() *reputation {
+	return &reputation{
+		scores: make(map[discover.NodeID]*peerScore),
+		clock:  mclock.Now,
+	}
+}
+
+// penalize records a misbehaviour of the given node, returning the duration it
+// was banned for, or zero if its score is still acceptable.
+func (r *reputation) penalize(id discover.NodeID, kind Misbehaviour) time.Duration {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := r.clock()
+	score := r.scores[id]
+	if score == nil {
+		if len(r.scores) >= maxTrackedScores {
+			r.prune(now)
+		}
+		score = &peerScore{updated: now}
+		r.scores[id] = score
+	}
+	score.decay(now)
+	score.score += misbehaviourPenalties[kind]
+	score.counts[kind]++
+	penaltyMeter.Mark(1)
+
+	if score.score < banScore {
+		return 0
+	}
+	// Ban the node, doubling the timeout with each ban
+	timeout := maxBanTimeout
+	if score.bans < 32 && baseBanTimeout<<score.bans < maxBanTimeout {
+		timeout = baseBanTimeout << score.bans
+	}
+	score.score = 0
+	score.bans++
+	score.until = now + mclock.AbsTime(timeout)
+	banMeter.Mark(1)
+
+	return timeout
+}
+
+// banned returns whether the given node is currently banned.
+func (r *reputation) banned(id discover.NodeID) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	score := r.scores[id]
+	return score != nil && r.clock() < score.until
+}
+
+// prune forgets the nodes neither banned nor misbehaving for a long time. The
+// ban count of a node is thus reset once it behaved for the maximum ban timeout.
+func (r *reputation) prune(now mclock.AbsTime) {
+	for id, score := range r.scores {
+		if now-score.until > mclock.AbsTime(maxBanTimeout) && now-score.updated > mclock.AbsTime(maxBanTimeout) {
+			delete(r.scores, id)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/common/mclock"
+	"github.com/Aurorachain-io/go-aoa/p2p/discover"
+)
+
+// Tests that misbehaving nodes get banned once their score is exhausted, with
+// the ban timeouts doubling for repeated offenders.
+func TestReputationBans(t *testing.T) {
+	var (
+		now mclock.AbsTime
+		r   = newReputation()
+		id  = discover.NodeID{1}
+	)
+	r.clock = func() mclock.AbsTime { return now }
+
+	for i, want := range []time.Duration{baseBanTimeout, 2 * baseBanTimeout, 4 * baseBanTimeout} {
+		// A single stall is tolerated, a second one in a row bans the node
+		if timeout := r.penalize(id, StallingPeer); timeout != 0 {
+			t.Fatalf("ban %d: node banned after a single stall for %v", i, timeout)
+		}
+		if r.banned(id) {
+			t.Fatalf("ban %d: node banned before its score was exhausted", i)
+		}
+		if timeout := r.penalize(id, StallingPeer); timeout != want {
+			t.Fatalf("ban %d: ban timeout mismatch: have %v, want %v", i, timeout, want)
+		}
+		if !r.banned(id) {
+			t.Fatalf("ban %d: node not banned", i)
+		}
+		now += mclock.AbsTime(want)
+		if r.banned(id) {
+			t.Fatalf("ban %d: node still banned after the timeout", i)
+		}
+	}
+	// Timeouts are capped at the maximum
+	for i := 0; i < 32; i++ {
+		r.penalize(id, IncompatiblePeer)
+	}
+	if timeout := r.penalize(id, IncompatiblePeer); timeout != maxBanTimeout {
+		t.Fatalf("ban timeout mismatch: have %v, want %v", timeout, maxBanTimeout)
+	}
+	// Other nodes are unaffected, and a single invalid block is tolerated
+	if r.banned(discover.NodeID{2}) {
+		t.Fatalf("well behaving node banned")
+	}
+	if timeout := r.penalize(discover.NodeID{3}, InvalidBlock); timeout != 0 {
+		t.Fatalf("node banned after a single invalid block for %v", timeout)
+	}
+}
+
+// Tests that the score of a node recovers over time, so sporadic misbehaviours
+// never get it banned.
+func TestReputationRecovery(t *testing.T) {
+	var (
+		now mclock.AbsTime
+		r   = newReputation()
+		id  = discover.NodeID{1}
+	)
+	r.clock = func() mclock.AbsTime { return now }
+
+	for i := 0; i < 100; i++ {
+		if timeout := r.penalize(id, StallingPeer); timeout != 0 {
+			t.Fatalf("penalty %d: sporadically stalling node banned for %v", i, timeout)
+		}
+		now += mclock.AbsTime(2 * scoreHalfLife)
+	}
+	if counts := r.scores[id].counts[StallingPeer]; counts != 100 {
+		t.Fatalf("misbehaviour count mismatch: have %d, want %d", counts, 100)
+	}
+}
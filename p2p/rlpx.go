@@ -601,8 +601,15 @@ func (rw *rlpxFrameRW) WriteMsg(msg Msg) error {
 			return errPlainMessageTooLarge
 		}
 		payload, _ := ioutil.ReadAll(msg.Payload)
+		plain := len(payload)
 		payload = snappy.Encode(nil, payload)
 
+		egressPlainMeter.Mark(int64(plain))
+		egressCompressedMeter.Mark(int64(len(payload)))
+		if plain > 0 {
+			egressRatioHistogram.Update(int64(len(payload) * 100 / plain))
+		}
+
 		msg.Payload = bytes.NewReader(payload)
 		msg.Size = uint32(len(payload))
 	}
@@ -705,6 +712,9 @@ func (rw *rlpxFrameRW) ReadMsg() (msg Msg, err error) {
 		if size > int(maxUint24) {
 			return msg, errPlainMessageTooLarge
 		}
+		ingressCompressedMeter.Mark(int64(len(payload)))
+		ingressPlainMeter.Mark(int64(size))
+
 		payload, err = snappy.Decode(nil, payload)
 		if err != nil {
 			return msg, err
@@ -378,9 +378,12 @@ type handshakeAuthTest struct {
 	input       string
 	isPlain     bool
 	wantVersion uint
+	wantNetType byte
 	wantRest    []rlp.RawValue
 }
 
+// The EIP-8 vectors follow the examples from the EIP, re-encoded with the
+// network type that is carried in the handshake messages.
 var eip8HandshakeAuthTests = []handshakeAuthTest{
 	// (Auth₁) RLPx v4 plain encoding
 	{
@@ -400,38 +403,44 @@ var eip8HandshakeAuthTests = []handshakeAuthTest{
 	// (Auth₂) EIP-8 encoding
 	{
 		input: `
-			01b304ab7578555167be8154d5cc456f567d5ba302662433674222360f08d5f1534499d3678b513b
-			0fca474f3a514b18e75683032eb63fccb16c156dc6eb2c0b1593f0d84ac74f6e475f1b8d56116b84
-			9634a8c458705bf83a626ea0384d4d7341aae591fae42ce6bd5c850bfe0b999a694a49bbbaf3ef6c
-			da61110601d3b4c02ab6c30437257a6e0117792631a4b47c1d52fc0f8f89caadeb7d02770bf999cc
-			147d2df3b62e1ffb2c9d8c125a3984865356266bca11ce7d3a688663a51d82defaa8aad69da39ab6
-			d5470e81ec5f2a7a47fb865ff7cca21516f9299a07b1bc63ba56c7a1a892112841ca44b6e0034dee
-			70c9adabc15d76a54f443593fafdc3b27af8059703f88928e199cb122362a4b35f62386da7caad09
-			c001edaeb5f8a06d2b26fb6cb93c52a9fca51853b68193916982358fe1e5369e249875bb8d0d0ec3
-			6f917bc5e1eafd5896d46bd61ff23f1a863a8a8dcd54c7b109b771c8e61ec9c8908c733c0263440e
-			2aa067241aaa433f0bb053c7b31a838504b148f570c0ad62837129e547678c5190341e4f1693956c
-			3bf7678318e2d5b5340c9e488eefea198576344afbdf66db5f51204a6961a63ce072c8926c
+			022104222de16e475bcedee67e11835af3de3cef8163144bd3e9877053bcec13160ce7b6251b3a84
+			2c4814b2ef7c9452869c8957d0fa9f4fa7b98fba117514b79112bdf32d72db7e67bb90f8722a55ee
+			0001416ad9989743fb8f916cf3ca21c64ea3ec4ae75f0d2b05748a6b6bad82fd387ea1833b49749d
+			e7411d779f4850d9fb961a1a134d1b4e5c5feecb0a55c43ee69ef0233f557dc190b44ccd4f21a658
+			2171feddee86403274f9fdd6bf596939f2b5ff539710bda860c74ce2e7048ca83769c3e2f0596939
+			5bfc74f7f5550b4c20967348ae1625fb3b94fcc4afceadd376e4bec0e9506c62ab532d42e695550a
+			af292649caca3ee94fa8cae625aa4b2b75e1b3b77a3600d634aad85c5a827c10871a0e978f0b386a
+			c6379b4972a76e08af14d1d4feb7f4f292a2df4de6e8b871e609064db3b0b4fe9a795cd5d5c40dce
+			d0c9f7cc6ed44ce7513384fe12cc917f11d433a6e2cf3444f758432b3d74ba91653c0e7b24f52b4e
+			ee4fdeb6236000f95dea396b9287de32062d9ee79f3a671240c73973796aec17be973e584c63cee7
+			28722c9d770f9ef4173c6d1815bda291df8b5329e41fd01023b61e039c910f54c6caff344b618139
+			626685c8ea358aced5626fb04c663450f4beba6ec0037c3c1c7e625353f9cf35652ad55c19b3792e
+			66b42f9044e7c533993b5bef1de997523478265ea6d2c29b50b3a9665313f5a0125216e8f382f9de
+			047a55f19fa3cc7efef2ffefb7f1a82f574f1499110dffd0d2369c
 		`,
 		wantVersion: 4,
+		wantNetType: discover.CommNet,
 		wantRest:    []rlp.RawValue{},
 	},
 	// (Auth₃) RLPx v4 EIP-8 encoding with version 56, additional list elements
 	{
 		input: `
-			01b8044c6c312173685d1edd268aa95e1d495474c6959bcdd10067ba4c9013df9e40ff45f5bfd6f7
-			2471f93a91b493f8e00abc4b80f682973de715d77ba3a005a242eb859f9a211d93a347fa64b597bf
-			280a6b88e26299cf263b01b8dfdb712278464fd1c25840b995e84d367d743f66c0e54a586725b7bb
-			f12acca27170ae3283c1073adda4b6d79f27656993aefccf16e0d0409fe07db2dc398a1b7e8ee93b
-			cd181485fd332f381d6a050fba4c7641a5112ac1b0b61168d20f01b479e19adf7fdbfa0905f63352
-			bfc7e23cf3357657455119d879c78d3cf8c8c06375f3f7d4861aa02a122467e069acaf513025ff19
-			6641f6d2810ce493f51bee9c966b15c5043505350392b57645385a18c78f14669cc4d960446c1757
-			1b7c5d725021babbcd786957f3d17089c084907bda22c2b2675b4378b114c601d858802a55345a15
-			116bc61da4193996187ed70d16730e9ae6b3bb8787ebcaea1871d850997ddc08b4f4ea668fbf3740
-			7ac044b55be0908ecb94d4ed172ece66fd31bfdadf2b97a8bc690163ee11f5b575a4b44e36e2bfb2
-			f0fce91676fd64c7773bac6a003f481fddd0bae0a1f31aa27504e2a533af4cef3b623f4791b2cca6
-			d490
+			01e20412f38db8289dad7f9a94db1086a825d5e6d34f3c6fb95e6e7d4500c2be463e5a73490f344f
+			dce99ae85fa687b5c81ed7c319f9a5a10e4dbf5dc69f9415b9ce770d08d3af5c550618eddb7903d8
+			5f773d433494abb2d24868f04a1e24863236fa2cbb3289bfcde1414a0a4e219cd603e27ecad088e1
+			553d337114e5e160b9eac1ba650fc18a89273130a70325b915b035fe067170f7dd7b9b078487bce8
+			dd97a8a48aca6d278b35958b447c976bbf6a46c6dc7afe2ff6d11c823f5f464ec9e298bbeb0ee309
+			bc48a2f2760589cf89cb9db3dfb4c1044432b9de82e2363376a95d7f81d0358ef3facb244e2e647c
+			58fc42a59cafe6d2ec19eefb685ec416592e8cc4e3e731d18fbe0258943472982fd9163d900a1748
+			c1658b8f036168279eb7dc196008fa706b47ee835369a4b663f7c6080145df6ac16ca11c1d454f0f
+			760db8842ca84232d70f37281aa2fd5402e0f35bc126be767cc2d16f095f0f9075b88d24149f97dd
+			ffab60c1797284e849a11523fd3612185737815725c38091e071c271dfcdda36c4496f0b95460d52
+			7b805a5652b2ff729eb200cbac409356958b652fdaa7057fc54e5d6025d128080fa5f9bfd5ca8a34
+			6c65ba993b52b2f2ab67110faf9a32823a349315d807fdef9a4e8895ec3212a22e54f67413490e39
+			0c51f134
 		`,
 		wantVersion: 56,
+		wantNetType: discover.CommNet,
 		wantRest:    []rlp.RawValue{{0x01}, {0x02}, {0xC2, 0x04, 0x05}},
 	},
 }
@@ -439,6 +448,7 @@ var eip8HandshakeAuthTests = []handshakeAuthTest{
 type handshakeAckTest struct {
 	input       string
 	wantVersion uint
+	wantNetType byte
 	wantRest    []rlp.RawValue
 }
 
@@ -458,41 +468,40 @@ var eip8HandshakeRespTests = []handshakeAckTest{
 	// (Ack₂) EIP-8 encoding
 	{
 		input: `
-			01ea0451958701280a56482929d3b0757da8f7fbe5286784beead59d95089c217c9b917788989470
-			b0e330cc6e4fb383c0340ed85fab836ec9fb8a49672712aeabbdfd1e837c1ff4cace34311cd7f4de
-			05d59279e3524ab26ef753a0095637ac88f2b499b9914b5f64e143eae548a1066e14cd2f4bd7f814
-			c4652f11b254f8a2d0191e2f5546fae6055694aed14d906df79ad3b407d94692694e259191cde171
-			ad542fc588fa2b7333313d82a9f887332f1dfc36cea03f831cb9a23fea05b33deb999e85489e645f
-			6aab1872475d488d7bd6c7c120caf28dbfc5d6833888155ed69d34dbdc39c1f299be1057810f34fb
-			e754d021bfca14dc989753d61c413d261934e1a9c67ee060a25eefb54e81a4d14baff922180c395d
-			3f998d70f46f6b58306f969627ae364497e73fc27f6d17ae45a413d322cb8814276be6ddd13b885b
-			201b943213656cde498fa0e9ddc8e0b8f8a53824fbd82254f3e2c17e8eaea009c38b4aa0a3f306e8
-			797db43c25d68e86f262e564086f59a2fc60511c42abfb3057c247a8a8fe4fb3ccbadde17514b7ac
-			8000cdb6a912778426260c47f38919a91f25f4b5ffb455d6aaaf150f7e5529c100ce62d6d92826a7
-			1778d809bdf60232ae21ce8a437eca8223f45ac37f6487452ce626f549b3b5fdee26afd2072e4bc7
-			5833c2464c805246155289f4
+			01c304c179b6bc55d0b758002ac51e550fbd9ca66fa0f70a769f203f9beb8dd95f8fdad2dd65e830
+			b619a41bfa85964185280cc5b67131355fb24226c853ccde0bb2b52d9e5897f8d4c19791a13178b0
+			cbd3ec8551a547b6722deb4cd4b8484c7f781fdcf840c232505c41fbc67c26ed082d9ab081ae78dd
+			6c3119cb52c0b421ba4400c5e84ea3a83fe1626ddd2a2412b21b6c3ae6c16e059af1aea7a821cb9e
+			d3301059d24a8ac5e89ba93ede67ada4a2ccf451b81ffd7c1849e8e0548e5b1e4d8265191726ecb0
+			b857a81ceeb49bbf04149b9ad10c86d488e0df83867cb9dbc3296aa81a963863eb3a789109f400d9
+			48330eb1c5430a8fb5efc0b00bf610ae87047e09c01cea27f224be5d674d77eedf72f43d05f8c03c
+			eaf62c6d79c17cc5eb7e2196cd9f8f3cb2b611e5b41bc515a929e27e198460335578c428d9f29034
+			8a9239e22b7f9a62927c6edb51aa02bd99155acc98629ae5864544bf921aefc43f6b82f2235e9052
+			53cb63acfb80bd2b521adadc58e3837f6672597acf2928949ec724a9ef4f45fe0deb73c302a155f9
+			08d8c678e0dcb42111bbbdd009079e50e2e188435ad3d0ea51367843b4ffe1370cc264a5ab0a788d
+			b709614b4a548e91e4c1ec1eec
 		`,
 		wantVersion: 4,
+		wantNetType: discover.CommNet,
 		wantRest:    []rlp.RawValue{},
 	},
 	// (Ack₃) EIP-8 encoding with version 57, additional list elements
 	{
 		input: `
-			01f004076e58aae772bb101ab1a8e64e01ee96e64857ce82b1113817c6cdd52c09d26f7b90981cd7
-			ae835aeac72e1573b8a0225dd56d157a010846d888dac7464baf53f2ad4e3d584531fa203658fab0
-			3a06c9fd5e35737e417bc28c1cbf5e5dfc666de7090f69c3b29754725f84f75382891c561040ea1d
-			dc0d8f381ed1b9d0d4ad2a0ec021421d847820d6fa0ba66eaf58175f1b235e851c7e2124069fbc20
-			2888ddb3ac4d56bcbd1b9b7eab59e78f2e2d400905050f4a92dec1c4bdf797b3fc9b2f8e84a482f3
-			d800386186712dae00d5c386ec9387a5e9c9a1aca5a573ca91082c7d68421f388e79127a5177d4f8
-			590237364fd348c9611fa39f78dcdceee3f390f07991b7b47e1daa3ebcb6ccc9607811cb17ce51f1
-			c8c2c5098dbdd28fca547b3f58c01a424ac05f869f49c6a34672ea2cbbc558428aa1fe48bbfd6115
-			8b1b735a65d99f21e70dbc020bfdface9f724a0d1fb5895db971cc81aa7608baa0920abb0a565c9c
-			436e2fd13323428296c86385f2384e408a31e104670df0791d93e743a3a5194ee6b076fb6323ca59
-			3011b7348c16cf58f66b9633906ba54a2ee803187344b394f75dd2e663a57b956cb830dd7a908d4f
-			39a2336a61ef9fda549180d4ccde21514d117b6c6fd07a9102b5efe710a32af4eeacae2cb3b1dec0
-			35b9593b48b9d3ca4c13d245d5f04169b0b1
+			01b004b1096a48ec5394d1d0d762b40aebb7abc40fee90af86c34177a180b7e4a830da5a98bd0dfd
+			8b87eacfdc94fe932ad8c792b395e93713cce4c40f612879887654f7ab993c33f2044b7a75f65ce6
+			528a9bdf3a6794bdfa5c41476e13b56b02daaa182491b9b402fdce66d8d785239e7cc4231c1e7a40
+			4d2459f2edf954687e978cc5c6fa01c0e6dd806192ff383840a627a85ed9552f5e0b0d5aca35c6fa
+			1c83e92ff54d20ac2840031695714e5502b8a24f0667700139d8e87c5c66e61d3e355a54f9e84112
+			4119ec0a450d287b644add66cc526d46916352ffa42ad53eab232552ef583c2ec3bc7560c355b299
+			4bb20764d8d168dce2c11b12c66e0e262f74eb253118d48f02f2bd0a0376fdfaafe566dc57a54c69
+			7bd25b821a47d9da886d56c1212d76b2f2bc7a21278dc8c162ac0abba511cc0477bda92f96fd8d29
+			504f95919d4aab89334dc6bea7e907d46b0581df74474bc5c3d3463bd9896ea5d63b9073806ea1ff
+			797002d2ab2ee9587a5ef763ac8835977e7127b13232796e9a3c56fcfd0894982ececef065eb8fa9
+			6010f558318603e9b8dc42ae563dbf0e413a4c3baa84891c0bd0582aa0f33d824951
 		`,
 		wantVersion: 57,
+		wantNetType: discover.CommNet,
 		wantRest:    []rlp.RawValue{{0x06}, {0xC2, 0x07, 0x08}, {0x81, 0xFA}},
 	},
 }
@@ -514,14 +523,14 @@ func TestHandshakeForwardCompatibility(t *testing.T) {
 		_             = authSignature
 	)
 	makeAuth := func(test handshakeAuthTest) *authMsgV4 {
-		msg := &authMsgV4{Version: test.wantVersion, Rest: test.wantRest, gotPlain: test.isPlain}
+		msg := &authMsgV4{Version: test.wantVersion, NetType: test.wantNetType, Rest: test.wantRest, gotPlain: test.isPlain}
 		copy(msg.Signature[:], authSignature)
 		copy(msg.InitiatorPubkey[:], pubA)
 		copy(msg.Nonce[:], nonceA)
 		return msg
 	}
 	makeAck := func(test handshakeAckTest) *authRespV4 {
-		msg := &authRespV4{Version: test.wantVersion, Rest: test.wantRest}
+		msg := &authRespV4{Version: test.wantVersion, NetType: test.wantNetType, Rest: test.wantRest}
 		copy(msg.RandomPubkey[:], ephPubB)
 		copy(msg.Nonce[:], nonceB)
 		return msg
@@ -576,7 +585,7 @@ func TestHandshakeForwardCompatibility(t *testing.T) {
 		authMsg            = makeAuth(eip8HandshakeAuthTests[1])
 		wantAES            = unhex("80e8632c05fed6fc2a13b0f8d31a3cf645366239170ea067065aba8e28bac487")
 		wantMAC            = unhex("2ea74ec5dae199227dff1af715362700e989d889d7a493cb0639691efb8e5f98")
-		wantFooIngressHash = unhex("0c7ec6340062cc46f5e9f1e3cf86f8c8c403c5a0964f5df0ebd34a75ddc86db5")
+		wantFooIngressHash = unhex("05a4cd49e1c41a1c03eaccf3e61328d588c559be7be5c636d52f5f72c6106a40")
 	)
 	if err := hs.handleAuthMsg(authMsg, keyB); err != nil {
 		t.Fatalf("handleAuthMsg: %v", err)
@@ -18,11 +18,13 @@
 package p2p
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Aurorachain-io/go-aoa/common"
@@ -30,7 +32,9 @@ import (
 	"github.com/Aurorachain-io/go-aoa/event"
 	"github.com/Aurorachain-io/go-aoa/log"
 	"github.com/Aurorachain-io/go-aoa/p2p/discover"
-
+	"github.com/Aurorachain-io/go-aoa/p2p/discv5"
+	"github.com/Aurorachain-io/go-aoa/p2p/dnsdisc"
+	"github.com/Aurorachain-io/go-aoa/p2p/enr"
 	"github.com/Aurorachain-io/go-aoa/p2p/nat"
 	"github.com/Aurorachain-io/go-aoa/p2p/netutil"
 )
@@ -52,8 +56,15 @@ const (
 
 	// Maximum amount of time allowed for writing a complete message.
 	frameWriteTimeout = 20 * time.Second
+
+	// Time between two retrievals of the DNS discovery trees.
+	dnsRefreshInterval = 30 * time.Minute
 )
 
+// Interval at which the dialer is asked for new tasks. Finished dials are not
+// kept in the dial history, so this also throttles redials of failing nodes.
+var dialScheduleInterval = 3 * time.Second
+
 var errServerStopped = errors.New("server stopped")
 
 // Config holds Server options.
@@ -68,6 +79,23 @@ type Config struct {
 	// connected. It must be greater than zero.
 	MaxPeers int
 
+	// MaxInboundPeers is the maximum number of peers that can be connected
+	// through inbound connections. Zero means only MaxPeers applies.
+	MaxInboundPeers int `toml:",omitempty"`
+
+	// MaxOutboundPeers is the maximum number of peers that can be connected
+	// through dynamically dialed connections. Zero defaults to half of MaxPeers.
+	MaxOutboundPeers int `toml:",omitempty"`
+
+	// PriorityNodes, typically the known delegates, are always dialed and may
+	// connect in the PrioritySlots reserved in addition to MaxPeers.
+	PriorityNodes []*discover.Node `toml:",omitempty"`
+
+	// PrioritySlots is the number of connection slots reserved for priority
+	// nodes. Zero defaults to the number of priority nodes, including the ones
+	// added through AddPriorityPeer.
+	PrioritySlots int `toml:",omitempty"`
+
 	OpenTopNet bool
 
 	// MaxPendingPeers is the maximum number of peers that can be pending in the
@@ -87,6 +115,16 @@ type Config struct {
 	// with the rest of the network.
 	BootstrapNodes []*discover.Node
 
+	// BootstrapNodesV5 are used to establish connectivity
+	// with the rest of the network using the V5 discovery
+	// protocol.
+	BootstrapNodesV5 []*discv5.Node `toml:",omitempty"`
+
+	// DiscoveryDNS are the URLs of signed DNS node trees, enrtree://<key>@<domain>.
+	// The nodes of the trees are retrieved periodically and used as bootstrap
+	// nodes of the discovery table in addition to BootstrapNodes.
+	DiscoveryDNS []string `toml:",omitempty"`
+
 	// Static nodes are used as pre-configured connections which are always
 	// maintained and re-connected on disconnects.
 	StaticNodes []*discover.Node
@@ -122,6 +160,12 @@ type Config struct {
 	// Internet.
 	NAT nat.Interface `toml:",omitempty"`
 
+	// ExternalPort is the port the node is reachable on from the Internet, if
+	// it differs from the listening port, e.g. when forwarded manually. It is
+	// advertised to other nodes and requested from the NAT port mapper. Zero
+	// uses the listening port.
+	ExternalPort int `toml:",omitempty"`
+
 	// If Dialer is set to a non-nil value, the given Dialer
 	// is used to dial outbound peer connections.
 	Dialer NodeDialer `toml:"-"`
@@ -130,7 +174,8 @@ type Config struct {
 	NoDial bool `toml:",omitempty"`
 
 	// If EnableMsgEvents is set then the server will emit PeerEvents
-	// whenever a message is sent to or received from a peer
+	// whenever a message is sent to or received from a peer, a peer
+	// answers a ping or a connection fails the handshakes
 	EnableMsgEvents bool
 
 	// Logger is a custom logger to use with the p2p.Server.
@@ -151,6 +196,11 @@ type Server struct {
 	running bool
 
 	ntab         discoverTable
+	DiscV5       *discv5.Network // Topic discovery network, nil unless DiscoveryV5 is set
+	record       *enr.Record     // Signed node record of the local node
+	reputation   *reputation     // Misbehaviour scores and bans of remote nodes
+	priorities   int32           // Number of priority nodes, written by the run loop only (atomic)
+	extIP        net.IP          // Internet-facing IP resolved through the NAT mechanism
 	listener     net.Listener
 	ourHandshake *protoHandshake
 	lastLookup   time.Time
@@ -159,16 +209,20 @@ type Server struct {
 	peerOp     chan peerOpFunc
 	peerOpDone chan struct{}
 
-	openTopNetCh  chan struct{}
-	quit          chan struct{}
-	addstatic     chan *discover.Node
-	removestatic  chan *discover.Node
-	posthandshake chan *conn
-	addpeer       chan *conn
-	delpeer       chan peerDrop
-	loopWG        sync.WaitGroup // loop, listenLoop
-	peerFeed      event.Feed
-	log           log.Logger
+	openTopNetCh   chan struct{}
+	quit           chan struct{}
+	addstatic      chan *discover.Node
+	removestatic   chan *discover.Node
+	addtrusted     chan *discover.Node
+	removetrusted  chan *discover.Node
+	addpriority    chan *discover.Node
+	removepriority chan *discover.Node
+	posthandshake  chan *conn
+	addpeer        chan *conn
+	delpeer        chan peerDrop
+	loopWG         sync.WaitGroup // loop, listenLoop
+	peerFeed       event.Feed
+	log            log.Logger
 }
 
 type peerOpFunc func(map[discover.NodeID]*Peer)
@@ -186,6 +240,7 @@ const (
 	staticDialedConn
 	inboundConn
 	trustedConn
+	priorityConn
 )
 
 // conn wraps a network connection with information gathered
@@ -199,6 +254,7 @@ type conn struct {
 	netType byte
 	caps    []Cap  // valid after the protocol handshake
 	name    string // valid after the protocol handshake
+	version uint64 // valid after the protocol handshake
 }
 
 type transport interface {
@@ -229,6 +285,9 @@ func (f connFlag) String() string {
 	if f&trustedConn != 0 {
 		s += "-trusted"
 	}
+	if f&priorityConn != 0 {
+		s += "-priority"
+	}
 	if f&dynDialedConn != 0 {
 		s += "-dyndial"
 	}
@@ -311,6 +370,41 @@ func (srv *Server) RemovePeer(node *discover.Node) {
 	}
 }
 
+// AddTrustedPeer adds the given node to a reserved whitelist which allows the
+// node to always connect, even if the slots are full.
+func (srv *Server) AddTrustedPeer(node *discover.Node) {
+	select {
+	case srv.addtrusted <- node:
+	case <-srv.quit:
+	}
+}
+
+// RemoveTrustedPeer removes the given node from the trusted peer set.
+func (srv *Server) RemoveTrustedPeer(node *discover.Node) {
+	select {
+	case srv.removetrusted <- node:
+	case <-srv.quit:
+	}
+}
+
+// AddPriorityPeer keeps the given node connected, letting it use the slots
+// reserved for priority nodes if all the other ones are taken.
+func (srv *Server) AddPriorityPeer(node *discover.Node) {
+	select {
+	case srv.addpriority <- node:
+	case <-srv.quit:
+	}
+}
+
+// RemovePriorityPeer stops keeping the given node connected. An existing
+// connection is kept, occupying a regular slot.
+func (srv *Server) RemovePriorityPeer(node *discover.Node) {
+	select {
+	case srv.removepriority <- node:
+	case <-srv.quit:
+	}
+}
+
 // SubscribePeers subscribes the given channel to peer events
 func (srv *Server) SubscribeEvents(ch chan *PeerEvent) event.Subscription {
 	return srv.peerFeed.Subscribe(ch)
@@ -335,18 +429,75 @@ func (srv *Server) makeSelf(listener net.Listener, ntab discoverTable) *discover
 		if listener == nil {
 			return &discover.Node{IP: net.ParseIP("0.0.0.0"), ID: discover.PubkeyID(&srv.PrivateKey.PublicKey)}
 		}
-		// Otherwise inject the listener address too
+		// Otherwise inject the listener address too, as reachable from the Internet
 		addr := listener.Addr().(*net.TCPAddr)
+		ip := addr.IP
+		if srv.extIP != nil {
+			ip = srv.extIP
+		}
 		return &discover.Node{
 			ID:  discover.PubkeyID(&srv.PrivateKey.PublicKey),
-			IP:  addr.IP,
-			TCP: uint16(addr.Port),
+			IP:  ip,
+			TCP: uint16(srv.externalPort(addr.Port)),
 		}
 	}
 	// Otherwise return the discovery node.
 	return ntab.Self()
 }
 
+// externalPort returns the port the given local port is reachable on from the
+// Internet.
+func (srv *Server) externalPort(port int) int {
+	if srv.ExternalPort != 0 {
+		return srv.ExternalPort
+	}
+	return port
+}
+
+// makeRecord creates the signed node record of the local node, advertising its
+// endpoint and the discovery protocols it runs. The sequence number is derived
+// from the current time, so the records of later runs supersede earlier ones.
+//
+// Neither discovery protocol carries the record in its packets, so remote nodes
+// never learn it through discovery. It is only served through NodeInfo and
+// Record, e.g. for publishing it in a DNS node list.
+func (srv *Server) makeRecord(self *discover.Node) (*enr.Record, error) {
+	var r enr.Record
+	if ip := self.IP.To4(); ip != nil {
+		r.Set(enr.IP4(ip))
+	} else if len(self.IP) == net.IPv6len {
+		r.Set(enr.IP6(self.IP))
+	}
+	if self.TCP != 0 {
+		r.Set(enr.TCP(self.TCP))
+	}
+	if self.UDP != 0 {
+		if !srv.NoDiscovery {
+			r.Set(enr.UDP(self.UDP))
+		}
+		if srv.DiscoveryV5 {
+			r.Set(enr.DiscPort(self.UDP))
+		}
+	}
+	r.SetSeq(uint64(time.Now().Unix()))
+	if err := r.Sign(srv.PrivateKey); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Record returns the signed node record of the local node, or nil if the server
+// is not running.
+func (srv *Server) Record() *enr.Record {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	if !srv.running {
+		return nil
+	}
+	return srv.record
+}
+
 // Stop terminates the server and all active peer connections.
 // It blocks until all active connections have been closed.
 func (srv *Server) Stop() {
@@ -422,17 +573,31 @@ func (srv *Server) Start() (err error) {
 	srv.posthandshake = make(chan *conn)
 	srv.addstatic = make(chan *discover.Node)
 	srv.removestatic = make(chan *discover.Node)
+	srv.addtrusted = make(chan *discover.Node)
+	srv.removetrusted = make(chan *discover.Node)
+	srv.addpriority = make(chan *discover.Node)
+	srv.removepriority = make(chan *discover.Node)
+	srv.reputation = newReputation()
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
 	srv.openTopNetCh = make(chan struct{})
 	var (
-		conn *net.UDPConn
-
+		conn      *net.UDPConn
+		sconn     *sharedUDPConn
 		realaddr  *net.UDPAddr
 		unhandled chan discover.ReadPacket
 	)
 
-	if !srv.NoDiscovery {
+	if srv.NAT != nil {
+		// Resolve the Internet-facing IP once, all endpoints advertise it
+		// TODO: react to external IP changes over time.
+		if ip, err := srv.NAT.ExternalIP(); err == nil {
+			srv.extIP = ip
+		} else {
+			srv.log.Debug("Failed to resolve external IP", "interface", srv.NAT, "err", err)
+		}
+	}
+	if !srv.NoDiscovery || srv.DiscoveryV5 {
 		addr, err := net.ResolveUDPAddr("udp", srv.ListenAddr)
 		if err != nil {
 			return err
@@ -442,18 +607,23 @@ func (srv *Server) Start() (err error) {
 			return err
 		}
 
-		realaddr = conn.LocalAddr().(*net.UDPAddr)
-		if srv.NAT != nil {
-			if !realaddr.IP.IsLoopback() {
-				go nat.Map(srv.NAT, srv.quit, "udp", realaddr.Port, realaddr.Port, "dacchain discovery")
-			}
-			// TODO: react to external IP changes over time.
-			if ext, err := srv.NAT.ExternalIP(); err == nil {
-				realaddr = &net.UDPAddr{IP: ext, Port: realaddr.Port}
-			}
+		laddr := conn.LocalAddr().(*net.UDPAddr)
+		if srv.NAT != nil && !laddr.IP.IsLoopback() {
+			go nat.Map(srv.NAT, srv.quit, "udp", srv.externalPort(laddr.Port), laddr.Port, "dacchain discovery")
+		}
+		realaddr = &net.UDPAddr{IP: laddr.IP, Port: srv.externalPort(laddr.Port)}
+		if srv.extIP != nil {
+			realaddr.IP = srv.extIP
 		}
 	}
 
+	// Share the socket if both discovery protocols run, the v4 table hands the
+	// packets it can't process over to the v5 network
+	if !srv.NoDiscovery && srv.DiscoveryV5 {
+		unhandled = make(chan discover.ReadPacket, 100)
+		sconn = &sharedUDPConn{conn, unhandled}
+	}
+
 	// node table
 	if !srv.NoDiscovery {
 		ntab, err := discover.ListenUDP(srv.PrivateKey, conn, realaddr, unhandled, srv.NodeDatabase, srv.NetRestrict, srv.Config.OpenTopNet)
@@ -464,13 +634,38 @@ func (srv *Server) Start() (err error) {
 			return err
 		}
 		srv.ntab = ntab
+
+		if len(srv.DiscoveryDNS) > 0 {
+			srv.loopWG.Add(1)
+			go srv.dnsDiscovery(ntab)
+		}
 	}
 
-	dynPeers := (srv.MaxPeers + 1) / 2
+	if srv.DiscoveryV5 {
+		var (
+			ntab *discv5.Network
+			err  error
+		)
+		if sconn != nil {
+			ntab, err = discv5.ListenUDP(srv.PrivateKey, sconn, realaddr, "", srv.NetRestrict)
+		} else {
+			ntab, err = discv5.ListenUDP(srv.PrivateKey, conn, realaddr, "", srv.NetRestrict)
+		}
+		if err != nil {
+			return err
+		}
+		if err := ntab.SetFallbackNodes(srv.BootstrapNodesV5); err != nil {
+			return err
+		}
+		srv.DiscV5 = ntab
+	}
+
+	dynPeers := srv.maxOutboundPeers()
 	if srv.NoDiscovery {
 		dynPeers = 0
 	}
-	dialer := newDialState(srv.StaticNodes, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict)
+	static := append(append([]*discover.Node{}, srv.StaticNodes...), srv.PriorityNodes...)
+	dialer := newDialState(static, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict)
 
 	// handshake
 	srv.ourHandshake = &protoHandshake{Version: baseProtocolVersion, Name: srv.Name, ID: discover.PubkeyID(&srv.PrivateKey.PublicKey)}
@@ -486,6 +681,9 @@ func (srv *Server) Start() (err error) {
 	if srv.NoDial && srv.ListenAddr == "" {
 		srv.log.Warn("P2P server will be useless, neither dialing nor listening")
 	}
+	if srv.record, err = srv.makeRecord(srv.makeSelf(srv.listener, srv.ntab)); err != nil {
+		return err
+	}
 
 	srv.loopWG.Add(1)
 	go srv.run(dialer)
@@ -508,13 +706,65 @@ func (srv *Server) startListening() error {
 	if !laddr.IP.IsLoopback() && srv.NAT != nil {
 		srv.loopWG.Add(1)
 		go func() {
-			nat.Map(srv.NAT, srv.quit, "tcp", laddr.Port, laddr.Port, "dacchain p2p")
+			nat.Map(srv.NAT, srv.quit, "tcp", srv.externalPort(laddr.Port), laddr.Port, "dacchain p2p")
 			srv.loopWG.Done()
 		}()
 	}
 	return nil
 }
 
+// dnsDiscovery periodically retrieves the DNS discovery trees, seeding the
+// discovery table with their nodes in addition to the bootstrap nodes.
+func (srv *Server) dnsDiscovery(ntab *discover.Table) {
+	defer srv.loopWG.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-srv.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	client := dnsdisc.NewClient(dnsdisc.Config{Logger: srv.log})
+	refresh := time.NewTimer(0)
+	defer refresh.Stop()
+
+	for {
+		select {
+		case <-refresh.C:
+			nodes, err := client.Nodes(ctx, srv.DiscoveryDNS)
+			if err != nil {
+				srv.log.Warn("Failed to retrieve DNS discovery nodes", "err", err)
+			} else {
+				srv.log.Debug("Retrieved DNS discovery nodes", "count", len(nodes))
+				if err := ntab.SetFallbackNodes(srv.dnsFallbackNodes(nodes)); err != nil {
+					srv.log.Warn("Failed to set DNS discovery nodes", "err", err)
+				}
+			}
+			refresh.Reset(dnsRefreshInterval)
+		case <-srv.quit:
+			return
+		}
+	}
+}
+
+// dnsFallbackNodes returns the bootstrap nodes followed by the usable nodes of
+// the DNS discovery trees. Incomplete tree nodes are dropped, since the table
+// rejects the whole list if any of them is unusable.
+func (srv *Server) dnsFallbackNodes(nodes []*discover.Node) []*discover.Node {
+	fallback := append([]*discover.Node{}, srv.BootstrapNodes...)
+	for _, n := range nodes {
+		if err := n.ValidateComplete(); err != nil {
+			srv.log.Debug("Skipping DNS discovery node", "id", n.ID, "err", err)
+			continue
+		}
+		fallback = append(fallback, n)
+	}
+	return fallback
+}
+
 type dialer interface {
 	newTasks(running int, peers map[discover.NodeID]*Peer, now time.Time, openTopNet bool) []task
 	taskDone(task, time.Time)
@@ -529,17 +779,22 @@ func (srv *Server) run(dialstate dialer) {
 		peers = make(map[discover.NodeID]*Peer)
 		//consPeers    = make(map[discover.NodeID]*Peer)
 		trusted  = make(map[discover.NodeID]bool, len(srv.TrustedNodes))
+		priority = make(map[discover.NodeID]bool, len(srv.PriorityNodes))
 		taskdone = make(chan task, maxActiveDialTasks)
 
 		runningTasks []task
 		queuedTasks  []task // tasks that can't run yet
 	)
 	// Put trusted nodes into a map to speed up checks.
-	// Trusted peers are loaded on startup and cannot be
-	// modified while the server is running.
+	// Trusted peers are loaded on startup and may be
+	// added or removed via AddTrustedPeer and RemoveTrustedPeer.
 	for _, n := range srv.TrustedNodes {
 		trusted[n.ID] = true
 	}
+	for _, n := range srv.PriorityNodes {
+		priority[n.ID] = true
+	}
+	atomic.StoreInt32(&srv.priorities, int32(len(priority)))
 
 	// removes t from runningTasks
 	delTask := func(t task) {
@@ -577,7 +832,7 @@ func (srv *Server) run(dialstate dialer) {
 		}
 	}
 
-	tick := time.NewTicker(3 * time.Second)
+	tick := time.NewTicker(dialScheduleInterval)
 
 running:
 	for {
@@ -611,6 +866,30 @@ running:
 			if p, ok := peers[n.ID]; ok {
 				p.Disconnect(DiscRequested)
 			}
+		case n := <-srv.addtrusted:
+			// This channel is used by AddTrustedPeer to add a node
+			// to the trusted node set, exempting it from MaxPeers.
+			srv.log.Debug("Adding trusted node", "node", n)
+			trusted[n.ID] = true
+		case n := <-srv.removetrusted:
+			// This channel is used by RemoveTrustedPeer to remove a node
+			// from the trusted node set. Connected peers are kept.
+			srv.log.Debug("Removing trusted node", "node", n)
+			delete(trusted, n.ID)
+		case n := <-srv.addpriority:
+			// This channel is used by AddPriorityPeer to keep a node
+			// connected in the reserved priority slots.
+			srv.log.Debug("Adding priority node", "node", n)
+			priority[n.ID] = true
+			atomic.StoreInt32(&srv.priorities, int32(len(priority)))
+			dialstate.addStatic(n)
+		case n := <-srv.removepriority:
+			// This channel is used by RemovePriorityPeer to stop keeping
+			// a node connected. Connected peers are kept.
+			srv.log.Debug("Removing priority node", "node", n)
+			delete(priority, n.ID)
+			atomic.StoreInt32(&srv.priorities, int32(len(priority)))
+			dialstate.removeStatic(n)
 
 		case op := <-srv.peerOp:
 			// This channel is used by Peers and PeerCount.
@@ -630,6 +909,9 @@ running:
 				// Ensure that the trusted flag is set before checking against MaxPeers.
 				c.flags |= trustedConn
 			}
+			if priority[c.id] {
+				c.flags |= priorityConn
+			}
 			// TODO: track in-progress inbound node IDs (pre-Peer) to avoid dialing them.
 			select {
 			case c.cont <- srv.encHandshakeChecks(peers, c):
@@ -651,6 +933,7 @@ running:
 				if srv.EnableMsgEvents {
 					p.events = &srv.peerFeed
 				}
+				p.reputation = srv.reputation
 				name := truncateName(c.name)
 				srv.log.Debug("Adding p2p peer", "name", name, "addr", c.fd.RemoteAddr(), "peers", len(peers)+1)
 
@@ -669,7 +952,9 @@ running:
 		case pd := <-srv.delpeer:
 			// A peer disconnected.
 			d := common.PrettyDuration(mclock.Now() - pd.created)
-			srv.ntab.Delete(pd.ID())
+			if srv.ntab != nil {
+				srv.ntab.Delete(pd.ID())
+			}
 
 			pd.log.Debug("Removing p2p peer", "duration", d, "peers", len(peers)-1, "req", pd.requested, "err", pd.err)
 			delete(peers, pd.ID())
@@ -683,6 +968,9 @@ running:
 	if srv.ntab != nil {
 		srv.ntab.Close()
 	}
+	if srv.DiscV5 != nil {
+		srv.DiscV5.Close()
+	}
 
 	// Disconnect all peers.
 	for _, p := range peers {
@@ -712,12 +1000,16 @@ func (srv *Server) protoHandshakeChecks(peers map[discover.NodeID]*Peer, c *conn
 
 func (srv *Server) encHandshakeChecks(peers map[discover.NodeID]*Peer, c *conn) error {
 	switch {
-	case !c.is(trustedConn|staticDialedConn) && len(peers) >= srv.MaxPeers:
+	case !c.is(trustedConn) && (c.is(priorityConn) || !c.is(staticDialedConn)) && !srv.hasSlot(peers, c):
+		// Static nodes are exempt from the peer limits, unless they are
+		// priority nodes bound by the reserved slots
 		return DiscTooManyPeers
 	case peers[c.id] != nil:
 		return DiscAlreadyConnected
 	case c.id == srv.Self().ID:
 		return DiscSelf
+	case !c.is(trustedConn) && srv.reputation != nil && srv.reputation.banned(c.id):
+		return DiscUselessPeer
 	default:
 		return nil
 	}
@@ -802,6 +1094,19 @@ func (srv *Server) SetupConn(fd net.Conn, flags connFlag, dialDest *discover.Nod
 	if err != nil {
 		c.close(err)
 		srv.log.Error("Setting up connection failed", "id", c.id, "err", err, "dialDest", dialDest)
+
+		if err != errServerStopped && srv.EnableMsgEvents {
+			id := c.id
+			if id == (discover.NodeID{}) && dialDest != nil {
+				id = dialDest.ID
+			}
+			srv.peerFeed.Send(&PeerEvent{
+				Type:          PeerEventTypeHandshakeFail,
+				Peer:          id,
+				RemoteAddress: fd.RemoteAddr().String(),
+				Error:         err.Error(),
+			})
+		}
 	}
 	return err
 }
@@ -846,7 +1151,7 @@ func (srv *Server) setupConn(c *conn, flags connFlag, dialDest *discover.Node) e
 		clog.Info("Wrong devp2p handshake identity", "err", phs.ID)
 		return DiscUnexpectedIdentity
 	}
-	c.caps, c.name = phs.Caps, phs.Name
+	c.caps, c.name, c.version = phs.Caps, phs.Name, phs.Version
 	err = srv.checkpoint(c, srv.addpeer)
 	if err != nil {
 		clog.Debug("Rejected peer", "err", err)
@@ -891,8 +1196,9 @@ func (srv *Server) runPeer(p *Peer) {
 
 	// broadcast peer add
 	srv.peerFeed.Send(&PeerEvent{
-		Type: PeerEventTypeAdd,
-		Peer: p.ID(),
+		Type:          PeerEventTypeAdd,
+		Peer:          p.ID(),
+		RemoteAddress: p.RemoteAddr().String(),
 	})
 
 	// run the protocol
@@ -900,9 +1206,10 @@ func (srv *Server) runPeer(p *Peer) {
 
 	// broadcast peer drop
 	srv.peerFeed.Send(&PeerEvent{
-		Type:  PeerEventTypeDrop,
-		Peer:  p.ID(),
-		Error: err.Error(),
+		Type:          PeerEventTypeDrop,
+		Peer:          p.ID(),
+		RemoteAddress: p.RemoteAddr().String(),
+		Error:         err.Error(),
 	})
 
 	// Note: run waits for existing peers to be sent on srv.delpeer
@@ -915,6 +1222,7 @@ type NodeInfo struct {
 	ID    string `json:"id"`    // Unique node identifier (also the encryption key)
 	Name  string `json:"name"`  // Name of the node, including client type, version, OS, custom data
 	Enode string `json:"enode"` // Enode URL for adding this peer from remote peers
+	ENR   string `json:"enr"`   // Signed node record in text form
 	IP    string `json:"ip"`    // IP address of the node
 	Ports struct {
 		Discovery int `json:"discovery"` // UDP listening port for discovery protocol
@@ -939,6 +1247,9 @@ func (srv *Server) NodeInfo() *NodeInfo {
 	}
 	info.Ports.Discovery = int(node.UDP)
 	info.Ports.Listener = int(node.TCP)
+	if record := srv.Record(); record != nil {
+		info.ENR, _ = record.Text()
+	}
 
 	// Gather all the running protocol infos (only once per protocol type)
 	for _, proto := range srv.Protocols {
@@ -33,6 +33,7 @@ import (
 
 func init() {
 	// log.Root().SetHandler(log.LvlFilterHandler(log.LvlError, log.StreamHandler(os.Stderr, log.TerminalFormat(false))))
+	dialScheduleInterval = 10 * time.Millisecond
 }
 
 type testTransport struct {
@@ -300,7 +301,7 @@ type taskgen struct {
 	doneFunc func(task)
 }
 
-func (tg taskgen) newTasks(running int, peers map[discover.NodeID]*Peer, now time.Time, netType byte) []task {
+func (tg taskgen) newTasks(running int, peers map[discover.NodeID]*Peer, now time.Time, openTopNet bool) []task {
 	return tg.newFunc(running, peers)
 }
 func (tg taskgen) taskDone(t task, now time.Time) {
@@ -492,6 +493,23 @@ func (c *setupTransport) ReadMsg() (Msg, error) {
 	panic("ReadMsg called on setupTransport")
 }
 
+// Tests that unusable nodes of the DNS discovery trees are dropped, keeping the
+// bootstrap nodes and the usable tree nodes.
+func TestDNSFallbackNodes(t *testing.T) {
+	var (
+		boot     = discover.NewNode(discover.PubkeyID(&newkey().PublicKey), net.IP{10, 0, 0, 1}, 30303, 30303)
+		complete = discover.NewNode(discover.PubkeyID(&newkey().PublicKey), net.IP{10, 0, 0, 2}, 30303, 30303)
+		noUDP    = discover.NewNode(discover.PubkeyID(&newkey().PublicKey), net.IP{10, 0, 0, 3}, 0, 30303)
+		badIP    = discover.NewNode(discover.PubkeyID(&newkey().PublicKey), net.IPv4zero, 30303, 30303)
+	)
+	srv := &Server{Config: Config{BootstrapNodes: []*discover.Node{boot}}, log: log.New()}
+
+	fallback := srv.dnsFallbackNodes([]*discover.Node{noUDP, complete, badIP})
+	if want := []*discover.Node{boot, complete}; !reflect.DeepEqual(fallback, want) {
+		t.Errorf("fallback nodes mismatch: have %v, want %v", fallback, want)
+	}
+}
+
 func newkey() *ecdsa.PrivateKey {
 	key, err := crypto.GenerateKey()
 	if err != nil {
@@ -0,0 +1,83 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"sync/atomic"
+
+	"github.com/Aurorachain-io/go-aoa/p2p/discover"
+)
+
+// hasSlot checks whether a connection slot is free for the given connection,
+// considering the peer limits by direction and the slots reserved for priority
+// nodes.
+func (srv *Server) hasSlot(peers map[discover.NodeID]*Peer, c *conn) bool {
+	var inbound, outbound, prio int
+	for _, p := range peers {
+		switch {
+		case p.rw.is(priorityConn):
+			prio++
+		case p.rw.is(inboundConn):
+			inbound++
+		case p.rw.is(dynDialedConn):
+			outbound++
+		}
+	}
+	// Priority peers occupy the reserved slots first, overflowing into the
+	// regular ones
+	regular := len(peers)
+	if reserved := srv.prioritySlots(); prio > reserved {
+		regular -= reserved
+	} else {
+		regular -= prio
+	}
+	switch {
+	case c.is(priorityConn):
+		return prio < srv.prioritySlots() || regular < srv.MaxPeers
+	case regular >= srv.MaxPeers:
+		return false
+	case c.is(inboundConn):
+		return srv.MaxInboundPeers == 0 || inbound < srv.MaxInboundPeers
+	case c.is(dynDialedConn):
+		return outbound < srv.maxOutboundPeers()
+	default:
+		return true
+	}
+}
+
+// maxOutboundPeers returns the maximum number of dynamically dialed peers.
+func (srv *Server) maxOutboundPeers() int {
+	if srv.MaxOutboundPeers > 0 {
+		return srv.MaxOutboundPeers
+	}
+	return (srv.MaxPeers + 1) / 2
+}
+
+// prioritySlots returns the number of connection slots reserved for priority
+// nodes.
+func (srv *Server) prioritySlots() int {
+	if srv.PrioritySlots > 0 {
+		return srv.PrioritySlots
+	}
+	return int(atomic.LoadInt32(&srv.priorities))
+}
+
+// ReservedSlots returns the number of connection slots reserved for priority
+// nodes in addition to MaxPeers.
+func (srv *Server) ReservedSlots() int {
+	return srv.prioritySlots()
+}
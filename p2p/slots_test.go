@@ -0,0 +1,162 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"crypto/ecdsa"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/p2p/discover"
+)
+
+// Tests that connection slots are allotted according to the direction limits,
+// with priority nodes using reserved slots once the regular ones are taken.
+func TestConnectionSlots(t *testing.T) {
+	srv := &Server{Config: Config{MaxPeers: 4, MaxInboundPeers: 2, MaxOutboundPeers: 2, PrioritySlots: 1}}
+
+	var (
+		peers = make(map[discover.NodeID]*Peer)
+		next  byte
+	)
+	connect := func(flags connFlag) bool {
+		next++
+		fd, _ := net.Pipe()
+		c := &conn{fd: fd, flags: flags, id: discover.NodeID{next}}
+		if !srv.hasSlot(peers, c) {
+			return false
+		}
+		peers[c.id] = newPeer(c, nil)
+		return true
+	}
+	tests := []struct {
+		flags connFlag
+		want  bool
+	}{
+		{inboundConn, true},
+		{inboundConn, true},
+		{inboundConn, false}, // inbound quota reached
+		{dynDialedConn, true},
+		{dynDialedConn, true},
+		{dynDialedConn, false},                // outbound quota and regular slots reached
+		{inboundConn | priorityConn, true},    // reserved slot
+		{dynDialedConn | priorityConn, false}, // reserved and regular slots taken
+	}
+	for i, tt := range tests {
+		if have := connect(tt.flags); have != tt.want {
+			t.Errorf("connection %d (%v): slot mismatch: have %v, want %v", i, tt.flags, have, tt.want)
+		}
+	}
+	// A regular peer leaving lets the priority node use its slot
+	for id, p := range peers {
+		if p.rw.is(dynDialedConn) {
+			delete(peers, id)
+			break
+		}
+	}
+	if !connect(dynDialedConn | priorityConn) {
+		t.Errorf("priority node rejected with a free regular slot")
+	}
+	if connect(inboundConn) {
+		t.Errorf("regular node accepted with all slots taken")
+	}
+}
+
+// idleTransport is the transport of an established connection on which no
+// messages arrive.
+type idleTransport struct {
+	closing chan struct{}
+	once    sync.Once
+}
+
+func (t *idleTransport) doEncHandshake(*ecdsa.PrivateKey, *discover.Node) (discover.NodeID, error) {
+	panic("doEncHandshake called on idleTransport")
+}
+func (t *idleTransport) doProtoHandshake(*protoHandshake) (*protoHandshake, error) {
+	panic("doProtoHandshake called on idleTransport")
+}
+func (t *idleTransport) WriteMsg(Msg) error { return nil }
+func (t *idleTransport) ReadMsg() (Msg, error) {
+	<-t.closing
+	return Msg{}, io.EOF
+}
+func (t *idleTransport) close(error) {
+	t.once.Do(func() { close(t.closing) })
+}
+
+// Tests that a priority node dialing in while the server is at MaxPeers is
+// accepted in a reserved slot, and that priority nodes dialed as static nodes
+// don't get around the reserved slot limit.
+func TestServerPriorityAtCap(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	var prioID, extraID discover.NodeID
+	prioID[0], extraID[0] = 0xf0, 0xf1
+
+	srv := &Server{
+		Config: Config{
+			PrivateKey:    key,
+			MaxPeers:      2,
+			PrioritySlots: 1,
+			NoDial:        true,
+			NoDiscovery:   true,
+			PriorityNodes: []*discover.Node{{ID: prioID}},
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newconn := func(id discover.NodeID, flags connFlag) *conn {
+		fd, _ := net.Pipe()
+		tx := &idleTransport{closing: make(chan struct{})}
+		return &conn{fd: fd, transport: tx, flags: flags, id: id, cont: make(chan error)}
+	}
+	// Fill up the regular slots
+	for i := 0; i < srv.MaxPeers; i++ {
+		c := newconn(discover.NodeID{byte(i + 1)}, inboundConn)
+		if err := srv.checkpoint(c, srv.addpeer); err != nil {
+			t.Fatalf("could not add conn %d: %v", i, err)
+		}
+	}
+	if err := srv.checkpoint(newconn(discover.NodeID{0x10}, inboundConn), srv.posthandshake); err != DiscTooManyPeers {
+		t.Errorf("regular conn at cap: have %v, want %v", err, DiscTooManyPeers)
+	}
+	// The priority node dials in, taking the reserved slot
+	c := newconn(prioID, inboundConn)
+	if err := srv.checkpoint(c, srv.posthandshake); err != nil {
+		t.Fatalf("priority conn at cap rejected: %v", err)
+	}
+	if !c.is(priorityConn) {
+		t.Fatalf("server did not set priority flag")
+	}
+	if err := srv.checkpoint(c, srv.addpeer); err != nil {
+		t.Fatalf("could not add priority conn: %v", err)
+	}
+	if have := len(srv.Peers()); have != srv.MaxPeers+1 {
+		t.Errorf("peer count mismatch: have %d, want %d", have, srv.MaxPeers+1)
+	}
+	// Another priority node dialed as a static node finds the reserved slot taken
+	srv.AddPriorityPeer(&discover.Node{ID: extraID})
+	c = newconn(extraID, staticDialedConn)
+	if err := srv.checkpoint(c, srv.posthandshake); err != DiscTooManyPeers {
+		t.Errorf("static priority conn beyond reserved slots: have %v, want %v", err, DiscTooManyPeers)
+	}
+}
@@ -17,8 +17,10 @@
 package params
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/common/hexutil"
 	"math/big"
 )
 
@@ -56,11 +58,15 @@ var (
 		ByzantiumBlockReward: big.NewInt(1e+18),
 		MaxElectDelegate:     big.NewInt(1),
 		BlockInterval:        big.NewInt(10),
+		FeePayerBlock:        big.NewInt(0),
 	}
 
 	TestChainConfig = &ChainConfig{
-		ChainId:        big.NewInt(1),
-		ByzantiumBlock: big.NewInt(0),
+		ChainId:          big.NewInt(1),
+		ByzantiumBlock:   big.NewInt(0),
+		FeePayerBlock:    big.NewInt(0),
+		MaxElectDelegate: big.NewInt(1),
+		BlockInterval:    big.NewInt(10),
 	}
 )
 
@@ -70,24 +76,207 @@ var (
 // that any network, identified by its genesis block, can have its own
 // set of configuration options.
 type ChainConfig struct {
-	ChainId        *big.Int `json:"chainId"`                  // Chain id identifies the current chain and is used for replay protection
-	ByzantiumBlock *big.Int `json:"byzantiumBlock,omitempty"` // Byzantium switch block (nil = no fork, 0 = already on byzantium)
+	ChainId           *big.Int `json:"chainId"`                     // Chain id identifies the current chain and is used for replay protection
+	ByzantiumBlock    *big.Int `json:"byzantiumBlock,omitempty"`    // Byzantium switch block (nil = no fork, 0 = already on byzantium)
+	FeePayerBlock     *big.Int `json:"feePayerBlock,omitempty"`     // Fee payer switch block enabling sponsored transactions (nil = no fork)
+	LowSBlock         *big.Int `json:"lowSBlock,omitempty"`         // Switch block enforcing canonical low-s block and vote signatures (nil = no fork)
+	DelegateJailBlock *big.Int `json:"delegateJailBlock,omitempty"` // Switch block deactivating delegates missing too many consecutive slots (nil = no fork)
+	ShuffleV2Block    *big.Int `json:"shuffleV2Block,omitempty"`    // Switch block of the v2 delegate shuffle algorithm (nil = no fork, 0 = v2 from genesis)
+	VoteDecayBlock    *big.Int `json:"voteDecayBlock,omitempty"`    // Switch block ranking delegates by stake weighted, decaying votes (nil = no fork)
+	CheckpointBlock   *big.Int `json:"checkpointBlock,omitempty"`   // Switch block committing to the shuffled delegate set in every epoch and new round (nil = no fork)
+	FeeSharingBlock   *big.Int `json:"feeSharingBlock,omitempty"`   // Switch block sharing transaction fees between producers and their voters (nil = no fork)
+	ExtraV1Block      *big.Int `json:"extraV1Block,omitempty"`      // Switch block enforcing the version 1 structured header extra-data (nil = no fork)
+	AresBlock         *big.Int `json:"aresBlock,omitempty"`         // Ares switch block upgrading the EVM instruction set (nil = no fork, 0 = already on ares)
+	AthenaBlock       *big.Int `json:"athenaBlock,omitempty"`       // Athena switch block upgrading the gas schedule and contract limits (nil = no fork, 0 = already on athena)
 
 	FrontierBlockReward  *big.Int // Block reward in wei for successfully produce a block
 	ByzantiumBlockReward *big.Int // Block reward in wei for successfully produce a block upward from Byzantium
 	MaxElectDelegate     *big.Int // dpos max elect delegate number
-	BlockInterval        *big.Int
+	BlockInterval        *big.Int // Seconds between two blocks
+	EpochLength          uint64   `json:"epochLength,omitempty"`  // Number of blocks in a delegate epoch (0 = EpochDuration)
+	RewardPolicy         string   `json:"rewardPolicy,omitempty"` // Name of the policy distributing block rewards and fees ("" = producer)
+
+	BlockLimitForks     []BlockLimitsFork     `json:"blockLimits,omitempty"`     // Transaction limits of blocks, changing at fork heights
+	ContractLimitForks  []ContractLimitsFork  `json:"contractLimits,omitempty"`  // Contract code limits, changing at fork heights
+	SystemContractForks []SystemContractsFork `json:"systemContracts,omitempty"` // System contract code deployments and upgrades
+	RewardForks         []RewardFork          `json:"rewards,omitempty"`         // Block reward schedules, changing at fork heights
+
+	Instant *InstantConfig `json:"instant,omitempty"` // Instant sealing of developer chains (nil = delegate rounds)
+}
+
+// InstantConfig makes the single local delegate of a developer chain seal blocks
+// on demand instead of in shuffled delegate rounds.
+type InstantConfig struct {
+	Period uint64 `json:"period"` // Seconds between empty blocks (0 = seal only if transactions are pending)
+}
+
+// BlockLimitsFork sets the transaction limits of all blocks from its fork block
+// on, until superseded by a later fork. Zero limits are unbounded.
+type BlockLimitsFork struct {
+	Block     *big.Int `json:"block"`               // Fork block activating the limits
+	MaxTxs    uint64   `json:"maxTxs,omitempty"`    // Maximum number of transactions per block
+	MaxTxSize uint64   `json:"maxTxSize,omitempty"` // Maximum encoded size of a single transaction in bytes
+}
+
+// ContractLimitsFork overrides the contract code limits of all blocks from its
+// fork block on, until superseded by a later fork. Zero fields keep the limits
+// of the chain rules, see ChainConfig.ContractLimits.
+type ContractLimitsFork struct {
+	Block           *big.Int `json:"block"`                     // Fork block activating the limits
+	MaxCodeSize     uint64   `json:"maxCodeSize,omitempty"`     // Maximum size of deployed contract code in bytes
+	MaxInitCodeSize uint64   `json:"maxInitCodeSize,omitempty"` // Maximum size of contract init code in bytes
+	InitCodeWordGas uint64   `json:"initCodeWordGas,omitempty"` // Gas charged per word of contract init code
+}
+
+// RewardFork replaces the block reward of all blocks from its fork block on,
+// until superseded by a later fork. The reward halves every halving interval
+// blocks after the fork block.
+type RewardFork struct {
+	Block           *big.Int `json:"block"`                     // Fork block activating the schedule
+	Reward          *big.Int `json:"reward"`                    // Block reward in wei at the fork block
+	HalvingInterval uint64   `json:"halvingInterval,omitempty"` // Blocks between two halvings of the reward (0 = never)
+}
+
+// SystemContractsFork installs the code of system contracts at their reserved
+// addresses when its fork block is finalized. A fork at block zero deploys the
+// contracts into the genesis state.
+type SystemContractsFork struct {
+	Block *big.Int                         `json:"block"` // Fork block installing the code
+	Code  map[common.Address]hexutil.Bytes `json:"code"`  // Contract code keyed by reserved address
 }
 
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
+	engine := "DPOS-BFT"
+	if c.Instant != nil {
+		engine = "instant"
+	}
 	return fmt.Sprintf("{ChainID: %v Byzantium: %v Engine: %v}",
 		c.ChainId,
 		c.ByzantiumBlock,
-		"DPOS-BFT",
+		engine,
 	)
 }
 
+// Validate checks whether the consensus parameters of the chain are usable,
+// rejecting genesis configurations that would stall block production.
+func (c *ChainConfig) Validate() error {
+	if c.MaxElectDelegate == nil || c.MaxElectDelegate.Sign() <= 0 || !c.MaxElectDelegate.IsInt64() {
+		return fmt.Errorf("invalid delegates per round %v, must be positive", c.MaxElectDelegate)
+	}
+	if c.BlockInterval == nil || c.BlockInterval.Sign() <= 0 || !c.BlockInterval.IsInt64() {
+		return fmt.Errorf("invalid block interval %v, must be positive", c.BlockInterval)
+	}
+	if c.EpochLength != 0 && c.EpochLength < c.MaxElectDelegate.Uint64() {
+		return fmt.Errorf("epoch length %d shorter than a round of %v delegates", c.EpochLength, c.MaxElectDelegate)
+	}
+	if c.FeeSharingBlock != nil && (c.VoteDecayBlock == nil || c.VoteDecayBlock.Cmp(c.FeeSharingBlock) > 0) {
+		return fmt.Errorf("fee sharing enabled at %v before vote decay at %v", c.FeeSharingBlock, c.VoteDecayBlock)
+	}
+	return c.CheckForkOrder()
+}
+
+// CheckForkOrder checks whether the named hard forks are scheduled in the order
+// they build upon each other. A fork may be left out, but not be scheduled once
+// a later one was left out.
+func (c *ChainConfig) CheckForkOrder() error {
+	var last Fork
+	for _, fork := range c.hardForks() {
+		switch {
+		case last.Name != "" && last.Block == nil && fork.Block != nil:
+			return fmt.Errorf("unsupported fork ordering: %s not enabled, but %s enabled at %v", last.Name, fork.Name, fork.Block)
+		case last.Block != nil && fork.Block != nil && last.Block.Cmp(fork.Block) > 0:
+			return fmt.Errorf("unsupported fork ordering: %s enabled at %v, but %s enabled at %v", last.Name, last.Block, fork.Name, fork.Block)
+		}
+		last = fork
+	}
+	return nil
+}
+
+// Fork is a rule change of the chain activated at a block height.
+type Fork struct {
+	Name  string   // Name of the fork as reported to users
+	Block *big.Int // Block activating the fork
+}
+
+// hardForks returns the named hard forks of the chain, in the order they build
+// upon each other, including the ones not scheduled.
+func (c *ChainConfig) hardForks() []Fork {
+	return []Fork{
+		{"ares", c.AresBlock},
+		{"athena", c.AthenaBlock},
+	}
+}
+
+// Forks returns the schedule of all rule changes of the chain, the feature forks,
+// the named hard forks and the block limit and system contract upgrades. Forks
+// not scheduled are left out.
+func (c *ChainConfig) Forks() []Fork {
+	forks := []Fork{
+		{"byzantium", c.ByzantiumBlock},
+		{"feePayer", c.FeePayerBlock},
+		{"lowS", c.LowSBlock},
+		{"delegateJail", c.DelegateJailBlock},
+		{"shuffleV2", c.ShuffleV2Block},
+		{"voteDecay", c.VoteDecayBlock},
+		{"checkpoint", c.CheckpointBlock},
+		{"feeSharing", c.FeeSharingBlock},
+		{"extraV1", c.ExtraV1Block},
+	}
+	forks = append(forks, c.hardForks()...)
+	for _, fork := range c.BlockLimitForks {
+		forks = append(forks, Fork{"blockLimits", fork.Block})
+	}
+	for _, fork := range c.ContractLimitForks {
+		forks = append(forks, Fork{"contractLimits", fork.Block})
+	}
+	for _, fork := range c.SystemContractForks {
+		forks = append(forks, Fork{"systemContracts", fork.Block})
+	}
+	for _, fork := range c.RewardForks {
+		forks = append(forks, Fork{"rewards", fork.Block})
+	}
+	scheduled := forks[:0]
+	for _, fork := range forks {
+		if fork.Block != nil {
+			scheduled = append(scheduled, fork)
+		}
+	}
+	return scheduled
+}
+
+// CheckConsensusParams checks whether the consensus parameters of newcfg match
+// the ones the chain was produced with. Unlike fork blocks they apply from the
+// genesis on, so they can't change once blocks were produced.
+func (c *ChainConfig) CheckConsensusParams(newcfg *ChainConfig) error {
+	if !configNumEqual(c.MaxElectDelegate, newcfg.MaxElectDelegate) {
+		return fmt.Errorf("mismatching delegates per round in database (have %v, want %v)", c.MaxElectDelegate, newcfg.MaxElectDelegate)
+	}
+	if !configNumEqual(c.BlockInterval, newcfg.BlockInterval) {
+		return fmt.Errorf("mismatching block interval in database (have %v, want %v)", c.BlockInterval, newcfg.BlockInterval)
+	}
+	if c.Epoch() != newcfg.Epoch() {
+		return fmt.Errorf("mismatching epoch length in database (have %d, want %d)", c.Epoch(), newcfg.Epoch())
+	}
+	if c.RewardPolicy != newcfg.RewardPolicy {
+		return fmt.Errorf("mismatching reward policy in database (have %q, want %q)", c.RewardPolicy, newcfg.RewardPolicy)
+	}
+	return nil
+}
+
+// Epoch returns the number of blocks in a delegate epoch of the chain.
+func (c *ChainConfig) Epoch() uint64 {
+	if c.EpochLength == 0 {
+		return EpochDuration
+	}
+	return c.EpochLength
+}
+
+// EpochOf returns the index of the delegate epoch a block number falls into.
+func (c *ChainConfig) EpochOf(number uint64) uint64 {
+	return number / c.Epoch()
+}
+
 // CheckCompatible checks whether scheduled fork transitions have been imported
 // with a mismatching chain configuration.
 func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *ConfigCompatError {
@@ -111,6 +300,48 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.ByzantiumBlock, newcfg.ByzantiumBlock, head) {
 		return newCompatError("Byzantium fork block", c.ByzantiumBlock, newcfg.ByzantiumBlock)
 	}
+	if isForkIncompatible(c.FeePayerBlock, newcfg.FeePayerBlock, head) {
+		return newCompatError("fee payer fork block", c.FeePayerBlock, newcfg.FeePayerBlock)
+	}
+	if isForkIncompatible(c.LowSBlock, newcfg.LowSBlock, head) {
+		return newCompatError("low-s fork block", c.LowSBlock, newcfg.LowSBlock)
+	}
+	if isForkIncompatible(c.DelegateJailBlock, newcfg.DelegateJailBlock, head) {
+		return newCompatError("delegate jail fork block", c.DelegateJailBlock, newcfg.DelegateJailBlock)
+	}
+	if isForkIncompatible(c.ShuffleV2Block, newcfg.ShuffleV2Block, head) {
+		return newCompatError("shuffle v2 fork block", c.ShuffleV2Block, newcfg.ShuffleV2Block)
+	}
+	if isForkIncompatible(c.VoteDecayBlock, newcfg.VoteDecayBlock, head) {
+		return newCompatError("vote decay fork block", c.VoteDecayBlock, newcfg.VoteDecayBlock)
+	}
+	if isForkIncompatible(c.CheckpointBlock, newcfg.CheckpointBlock, head) {
+		return newCompatError("checkpoint fork block", c.CheckpointBlock, newcfg.CheckpointBlock)
+	}
+	if isForkIncompatible(c.FeeSharingBlock, newcfg.FeeSharingBlock, head) {
+		return newCompatError("fee sharing fork block", c.FeeSharingBlock, newcfg.FeeSharingBlock)
+	}
+	if isForkIncompatible(c.ExtraV1Block, newcfg.ExtraV1Block, head) {
+		return newCompatError("extra-data v1 fork block", c.ExtraV1Block, newcfg.ExtraV1Block)
+	}
+	if isForkIncompatible(c.AresBlock, newcfg.AresBlock, head) {
+		return newCompatError("Ares fork block", c.AresBlock, newcfg.AresBlock)
+	}
+	if isForkIncompatible(c.AthenaBlock, newcfg.AthenaBlock, head) {
+		return newCompatError("Athena fork block", c.AthenaBlock, newcfg.AthenaBlock)
+	}
+	if block := c.blockLimitsConflict(newcfg, head); block != nil {
+		return newCompatError("block limits fork block", block, block)
+	}
+	if block := c.contractLimitsConflict(newcfg, head); block != nil {
+		return newCompatError("contract limits fork block", block, block)
+	}
+	if block := c.systemContractsConflict(newcfg, head); block != nil {
+		return newCompatError("system contracts fork block", block, block)
+	}
+	if block := c.rewardConflict(newcfg, head); block != nil {
+		return newCompatError("reward fork block", block, block)
+	}
 
 	return nil
 }
@@ -174,10 +405,270 @@ func (c *ChainConfig) IsByzantium(num *big.Int) bool {
 	return isForked(c.ByzantiumBlock, num)
 }
 
+// IsFeePayer returns whether transactions sponsored by a fee payer are valid in
+// the block with the given number.
+func (c *ChainConfig) IsFeePayer(num *big.Int) bool {
+	return isForked(c.FeePayerBlock, num)
+}
+
+// IsLowS returns whether the producer and vote signatures of the block with the
+// given number must be in canonical low-s form. Transaction signatures always are.
+func (c *ChainConfig) IsLowS(num *big.Int) bool {
+	return isForked(c.LowSBlock, num)
+}
+
+// IsDelegateJail returns whether the block with the given number deactivates the
+// delegates which missed more than params.MaxMissedSlots consecutive slots.
+func (c *ChainConfig) IsDelegateJail(num *big.Int) bool {
+	return isForked(c.DelegateJailBlock, num)
+}
+
+// Versions of the delegate shuffle algorithm, see util.ShuffleIndices.
+const (
+	ShuffleV1 = 1 // Swaps driven by the leading hex digits of the round seed hash
+	ShuffleV2 = 2 // Fisher-Yates shuffle drawing from a hash chain of the round seed
+)
+
+// IsShuffleV2 returns whether the delegate rounds shuffled from the block with the
+// given number are randomized by the v2 shuffle algorithm.
+func (c *ChainConfig) IsShuffleV2(num *big.Int) bool {
+	return isForked(c.ShuffleV2Block, num)
+}
+
+// ShuffleVersion returns the version of the delegate shuffle algorithm the rounds
+// shuffled from the block with the given number are randomized by.
+func (c *ChainConfig) ShuffleVersion(num *big.Int) int {
+	if c.IsShuffleV2(num) {
+		return ShuffleV2
+	}
+	return ShuffleV1
+}
+
+// IsVoteDecay returns whether votes cast in the block with the given number are
+// weighted by the stake of their voters and decay unless refreshed.
+func (c *ChainConfig) IsVoteDecay(num *big.Int) bool {
+	return isForked(c.VoteDecayBlock, num)
+}
+
+// VoteDecayPeriod returns the vote decay period the block with the given number
+// falls into. Vote weights halve with every period passed since they were cast.
+func (c *ChainConfig) VoteDecayPeriod(num *big.Int) uint64 {
+	return c.EpochOf(num.Uint64()) / VoteHalfLifeEpochs
+}
+
+// IsFeeSharing returns whether the block with the given number shares its
+// transaction fees between its producer and the voters of the producer, and
+// whether vote transactions in it index their voters for that.
+func (c *ChainConfig) IsFeeSharing(num *big.Int) bool {
+	return isForked(c.FeeSharingBlock, num)
+}
+
+// IsCheckpoint returns whether the block with the given number is an epoch
+// checkpoint, committing to the delegate set its round was shuffled from. Blocks
+// switching to a newly shuffled round commit to it as well.
+func (c *ChainConfig) IsCheckpoint(num *big.Int) bool {
+	return isForked(c.CheckpointBlock, num) && num.Sign() > 0 && num.Uint64()%c.Epoch() == 0
+}
+
+// IsExtraV1 returns whether the extra-data of the block with the given number must
+// be in the version 1 structured layout. Earlier blocks carry free-form extra-data.
+func (c *ChainConfig) IsExtraV1(num *big.Int) bool {
+	return isForked(c.ExtraV1Block, num)
+}
+
+// IsAres returns whether the block with the given number runs the Ares rules.
+func (c *ChainConfig) IsAres(num *big.Int) bool {
+	return isForked(c.AresBlock, num)
+}
+
+// IsAthena returns whether the block with the given number runs the Athena rules.
+func (c *ChainConfig) IsAthena(num *big.Int) bool {
+	return isForked(c.AthenaBlock, num)
+}
+
+// BlockLimits returns the transaction limits of the block with the given number,
+// set by the latest block limits fork activated at or before it.
+func (c *ChainConfig) BlockLimits(num *big.Int) BlockLimitsFork {
+	var limits BlockLimitsFork
+	for _, fork := range c.BlockLimitForks {
+		if isForked(fork.Block, num) && (limits.Block == nil || fork.Block.Cmp(limits.Block) > 0) {
+			limits = fork
+		}
+	}
+	return limits
+}
+
+// blockLimitsConflict returns the lowest block at or before head whose limits
+// differ between the two configurations, or nil if the schedules agree.
+func (c *ChainConfig) blockLimitsConflict(newcfg *ChainConfig, head *big.Int) *big.Int {
+	var conflict *big.Int
+	for _, forks := range [][]BlockLimitsFork{c.BlockLimitForks, newcfg.BlockLimitForks} {
+		for _, fork := range forks {
+			if !isForked(fork.Block, head) || (conflict != nil && fork.Block.Cmp(conflict) >= 0) {
+				continue
+			}
+			have, want := c.BlockLimits(fork.Block), newcfg.BlockLimits(fork.Block)
+			if have.MaxTxs != want.MaxTxs || have.MaxTxSize != want.MaxTxSize {
+				conflict = fork.Block
+			}
+		}
+	}
+	return conflict
+}
+
+// ContractLimits returns the contract code limits of the block with the given
+// number. Code size is capped at MaxCodeSize, and since Athena init code is
+// capped at MaxInitCodeSize and metered at InitCodeWordGas per word. The latest
+// contract limits fork activated at or before the block overrides these.
+// Zero limits are unbounded.
+func (c *ChainConfig) ContractLimits(num *big.Int) ContractLimitsFork {
+	limits := ContractLimitsFork{MaxCodeSize: MaxCodeSize}
+	if c.IsAthena(num) {
+		limits.MaxInitCodeSize = MaxInitCodeSize
+		limits.InitCodeWordGas = InitCodeWordGas
+	}
+	var override *ContractLimitsFork
+	for i, fork := range c.ContractLimitForks {
+		if isForked(fork.Block, num) && (override == nil || fork.Block.Cmp(override.Block) > 0) {
+			override = &c.ContractLimitForks[i]
+		}
+	}
+	if override != nil {
+		limits.Block = override.Block
+		if override.MaxCodeSize != 0 {
+			limits.MaxCodeSize = override.MaxCodeSize
+		}
+		if override.MaxInitCodeSize != 0 {
+			limits.MaxInitCodeSize = override.MaxInitCodeSize
+		}
+		if override.InitCodeWordGas != 0 {
+			limits.InitCodeWordGas = override.InitCodeWordGas
+		}
+	}
+	return limits
+}
+
+// contractLimitsConflict returns the lowest block at or before head whose limits
+// differ between the two configurations, or nil if the schedules agree.
+func (c *ChainConfig) contractLimitsConflict(newcfg *ChainConfig, head *big.Int) *big.Int {
+	var conflict *big.Int
+	for _, forks := range [][]ContractLimitsFork{c.ContractLimitForks, newcfg.ContractLimitForks} {
+		for _, fork := range forks {
+			if !isForked(fork.Block, head) || (conflict != nil && fork.Block.Cmp(conflict) >= 0) {
+				continue
+			}
+			have, want := c.ContractLimits(fork.Block), newcfg.ContractLimits(fork.Block)
+			if have.MaxCodeSize != want.MaxCodeSize || have.MaxInitCodeSize != want.MaxInitCodeSize || have.InitCodeWordGas != want.InitCodeWordGas {
+				conflict = fork.Block
+			}
+		}
+	}
+	return conflict
+}
+
+// RewardSchedule returns the latest reward fork activated at or before the block
+// with the given number. A fork without block means the reward of the consensus
+// engine applies.
+func (c *ChainConfig) RewardSchedule(num *big.Int) RewardFork {
+	var schedule RewardFork
+	for _, fork := range c.RewardForks {
+		if isForked(fork.Block, num) && (schedule.Block == nil || fork.Block.Cmp(schedule.Block) > 0) {
+			schedule = fork
+		}
+	}
+	return schedule
+}
+
+// BlockReward returns the block reward in wei of the block with the given number
+// under the active reward fork, or nil if no reward fork is active.
+func (c *ChainConfig) BlockReward(num *big.Int) *big.Int {
+	schedule := c.RewardSchedule(num)
+	if schedule.Block == nil {
+		return nil
+	}
+	reward := new(big.Int)
+	if schedule.Reward != nil {
+		reward.Set(schedule.Reward)
+	}
+	if schedule.HalvingInterval != 0 {
+		halvings := new(big.Int).Sub(num, schedule.Block)
+		halvings.Div(halvings, new(big.Int).SetUint64(schedule.HalvingInterval))
+		if !halvings.IsUint64() || halvings.Uint64() >= uint64(reward.BitLen()) {
+			return reward.SetUint64(0)
+		}
+		reward.Rsh(reward, uint(halvings.Uint64()))
+	}
+	return reward
+}
+
+// rewardConflict returns the lowest block at or before head whose reward differs
+// between the two configurations, or nil if the schedules agree.
+func (c *ChainConfig) rewardConflict(newcfg *ChainConfig, head *big.Int) *big.Int {
+	var conflict *big.Int
+	for _, forks := range [][]RewardFork{c.RewardForks, newcfg.RewardForks} {
+		for _, fork := range forks {
+			if !isForked(fork.Block, head) || (conflict != nil && fork.Block.Cmp(conflict) >= 0) {
+				continue
+			}
+			have, want := c.RewardSchedule(fork.Block), newcfg.RewardSchedule(fork.Block)
+			if !configNumEqual(have.Block, want.Block) || !configNumEqual(have.Reward, want.Reward) || have.HalvingInterval != want.HalvingInterval {
+				conflict = fork.Block
+			}
+		}
+	}
+	return conflict
+}
+
+// SystemContractUpgrades returns the system contract code installed by the forks
+// scheduled exactly at the given block, or nil if there are none.
+func (c *ChainConfig) SystemContractUpgrades(num *big.Int) map[common.Address][]byte {
+	var upgrades map[common.Address][]byte
+	for _, fork := range c.SystemContractForks {
+		if fork.Block == nil || num == nil || fork.Block.Cmp(num) != 0 {
+			continue
+		}
+		if upgrades == nil {
+			upgrades = make(map[common.Address][]byte)
+		}
+		for addr, code := range fork.Code {
+			upgrades[addr] = code
+		}
+	}
+	return upgrades
+}
+
+// systemContractsConflict returns the lowest block at or before head whose system
+// contract upgrades differ between the two configurations, or nil if they agree.
+func (c *ChainConfig) systemContractsConflict(newcfg *ChainConfig, head *big.Int) *big.Int {
+	var conflict *big.Int
+	for _, forks := range [][]SystemContractsFork{c.SystemContractForks, newcfg.SystemContractForks} {
+		for _, fork := range forks {
+			if !isForked(fork.Block, head) || (conflict != nil && fork.Block.Cmp(conflict) >= 0) {
+				continue
+			}
+			have, want := c.SystemContractUpgrades(fork.Block), newcfg.SystemContractUpgrades(fork.Block)
+			if len(have) != len(want) {
+				conflict = fork.Block
+				continue
+			}
+			for addr, code := range have {
+				if other, ok := want[addr]; !ok || !bytes.Equal(code, other) {
+					conflict = fork.Block
+					break
+				}
+			}
+		}
+	}
+	return conflict
+}
+
 // GasTable returns the gas table corresponding to the current phase .
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
 func (c *ChainConfig) GasTable(num *big.Int) GasTable {
+	if c.IsAthena(num) {
+		return GasTableAthena
+	}
 	return GasTableFrontier
 }
 
@@ -189,6 +680,9 @@ func (c *ChainConfig) GasTable(num *big.Int) GasTable {
 type Rules struct {
 	ChainId     *big.Int
 	IsByzantium bool
+	IsFeePayer  bool
+	IsAres      bool
+	IsAthena    bool
 }
 
 func (c *ChainConfig) Rules(num *big.Int) Rules {
@@ -196,5 +690,11 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 	if chainId == nil {
 		chainId = new(big.Int)
 	}
-	return Rules{ChainId: new(big.Int).Set(chainId), IsByzantium: c.IsByzantium(num)}
+	return Rules{
+		ChainId:     new(big.Int).Set(chainId),
+		IsByzantium: c.IsByzantium(num),
+		IsFeePayer:  c.IsFeePayer(num),
+		IsAres:      c.IsAres(num),
+		IsAthena:    c.IsAthena(num),
+	}
 }
@@ -17,9 +17,14 @@
 package params
 
 import (
+	"bytes"
 	"math/big"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/common/hexutil"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -37,6 +42,51 @@ func TestCheckCompatible(t *testing.T) {
 			head:    9,
 			wantErr: nil,
 		},
+		{
+			stored: &ChainConfig{},
+			new:    &ChainConfig{FeePayerBlock: big.NewInt(20)},
+			head:   30,
+			wantErr: &ConfigCompatError{
+				What:         "fee payer fork block",
+				StoredConfig: nil,
+				NewConfig:    big.NewInt(20),
+				RewindTo:     19,
+			},
+		},
+		{
+			stored:  &ChainConfig{},
+			new:     &ChainConfig{BlockLimitForks: []BlockLimitsFork{{Block: big.NewInt(20), MaxTxs: 100}}},
+			head:    19,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{},
+			new:    &ChainConfig{BlockLimitForks: []BlockLimitsFork{{Block: big.NewInt(20), MaxTxs: 100}}},
+			head:   20,
+			wantErr: &ConfigCompatError{
+				What:         "block limits fork block",
+				StoredConfig: big.NewInt(20),
+				NewConfig:    big.NewInt(20),
+				RewindTo:     19,
+			},
+		},
+		{
+			stored:  &ChainConfig{BlockLimitForks: []BlockLimitsFork{{Block: big.NewInt(10), MaxTxSize: 1024}}},
+			new:     &ChainConfig{BlockLimitForks: []BlockLimitsFork{{Block: big.NewInt(10), MaxTxSize: 1024}, {Block: big.NewInt(30), MaxTxSize: 2048}}},
+			head:    25,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{SystemContractForks: []SystemContractsFork{{Block: big.NewInt(10), Code: map[common.Address]hexutil.Bytes{{0x10}: {0x01}}}}},
+			new:    &ChainConfig{SystemContractForks: []SystemContractsFork{{Block: big.NewInt(10), Code: map[common.Address]hexutil.Bytes{{0x10}: {0x02}}}}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "system contracts fork block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -46,3 +96,183 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestBlockLimits(t *testing.T) {
+	config := &ChainConfig{BlockLimitForks: []BlockLimitsFork{
+		{Block: big.NewInt(20), MaxTxs: 50, MaxTxSize: 4096},
+		{Block: big.NewInt(10), MaxTxs: 100},
+	}}
+	tests := []struct {
+		number            int64
+		maxTxs, maxTxSize uint64
+	}{
+		{0, 0, 0}, {9, 0, 0}, {10, 100, 0}, {19, 100, 0}, {20, 50, 4096}, {1000, 50, 4096},
+	}
+	for _, tt := range tests {
+		limits := config.BlockLimits(big.NewInt(tt.number))
+		if limits.MaxTxs != tt.maxTxs || limits.MaxTxSize != tt.maxTxSize {
+			t.Errorf("block %d: limits mismatch: have %d/%d, want %d/%d", tt.number, limits.MaxTxs, limits.MaxTxSize, tt.maxTxs, tt.maxTxSize)
+		}
+	}
+}
+
+func TestGasTable(t *testing.T) {
+	config := &ChainConfig{AthenaBlock: big.NewInt(10)}
+	tests := []struct {
+		number int64
+		want   GasTable
+	}{
+		{0, GasTableFrontier}, {9, GasTableFrontier}, {10, GasTableAthena}, {1000, GasTableAthena},
+	}
+	for _, tt := range tests {
+		if have := config.GasTable(big.NewInt(tt.number)); have != tt.want {
+			t.Errorf("block %d: gas table mismatch: have %+v, want %+v", tt.number, have, tt.want)
+		}
+	}
+	if (&ChainConfig{}).GasTable(big.NewInt(1000)) != GasTableFrontier {
+		t.Errorf("gas table repriced without the athena fork")
+	}
+}
+
+func TestContractLimits(t *testing.T) {
+	config := &ChainConfig{AthenaBlock: big.NewInt(10), ContractLimitForks: []ContractLimitsFork{
+		{Block: big.NewInt(20), MaxCodeSize: 65536},
+		{Block: big.NewInt(5), InitCodeWordGas: 3},
+	}}
+	tests := []struct {
+		number                                int64
+		maxCodeSize, maxInitCodeSize, wordGas uint64
+	}{
+		{0, MaxCodeSize, 0, 0},
+		{5, MaxCodeSize, 0, 3},
+		{10, MaxCodeSize, MaxInitCodeSize, 3},
+		{20, 65536, MaxInitCodeSize, InitCodeWordGas},
+	}
+	for _, tt := range tests {
+		limits := config.ContractLimits(big.NewInt(tt.number))
+		if limits.MaxCodeSize != tt.maxCodeSize || limits.MaxInitCodeSize != tt.maxInitCodeSize || limits.InitCodeWordGas != tt.wordGas {
+			t.Errorf("block %d: limits mismatch: have %d/%d/%d, want %d/%d/%d", tt.number,
+				limits.MaxCodeSize, limits.MaxInitCodeSize, limits.InitCodeWordGas, tt.maxCodeSize, tt.maxInitCodeSize, tt.wordGas)
+		}
+	}
+	// Changing the limits of a passed fork requires a rewind
+	changed := *config
+	changed.ContractLimitForks = []ContractLimitsFork{{Block: big.NewInt(20), MaxCodeSize: 32768}}
+	if err := config.CheckCompatible(&changed, 30); err == nil || err.RewindTo != 4 {
+		t.Errorf("changed contract limits: have %v, want rewind to 4", err)
+	}
+	if err := config.CheckCompatible(&changed, 4); err != nil {
+		t.Errorf("changed future contract limits: have %v, want compatible", err)
+	}
+}
+
+func TestBlockReward(t *testing.T) {
+	config := &ChainConfig{RewardForks: []RewardFork{
+		{Block: big.NewInt(100), Reward: big.NewInt(40), HalvingInterval: 50},
+		{Block: big.NewInt(10), Reward: big.NewInt(7)},
+	}}
+	tests := []struct {
+		number int64
+		want   *big.Int
+	}{
+		{0, nil}, {9, nil}, {10, big.NewInt(7)}, {99, big.NewInt(7)},
+		{100, big.NewInt(40)}, {149, big.NewInt(40)}, {150, big.NewInt(20)}, {250, big.NewInt(5)},
+		{400, big.NewInt(0)}, {1 << 40, big.NewInt(0)},
+	}
+	for _, tt := range tests {
+		if have := config.BlockReward(big.NewInt(tt.number)); !configNumEqual(have, tt.want) {
+			t.Errorf("block %d: reward mismatch: have %v, want %v", tt.number, have, tt.want)
+		}
+	}
+	// Changing the reward of a passed fork requires a rewind
+	changed := *config
+	changed.RewardForks = []RewardFork{{Block: big.NewInt(10), Reward: big.NewInt(7)}}
+	if err := config.CheckCompatible(&changed, 120); err == nil || err.RewindTo != 99 {
+		t.Errorf("changed reward: have %v, want rewind to 99", err)
+	}
+	if err := config.CheckCompatible(&changed, 99); err != nil {
+		t.Errorf("changed future reward: have %v, want compatible", err)
+	}
+}
+
+func TestSystemContractUpgrades(t *testing.T) {
+	governance, rewards := common.Address{0x10}, common.Address{0x11}
+	config := &ChainConfig{SystemContractForks: []SystemContractsFork{
+		{Block: big.NewInt(0), Code: map[common.Address]hexutil.Bytes{governance: {0x01}, rewards: {0x02}}},
+		{Block: big.NewInt(10), Code: map[common.Address]hexutil.Bytes{rewards: {0x03}}},
+	}}
+	if upgrades := config.SystemContractUpgrades(big.NewInt(0)); len(upgrades) != 2 || !bytes.Equal(upgrades[rewards], []byte{0x02}) {
+		t.Errorf("genesis upgrades mismatch: have %x", upgrades)
+	}
+	if upgrades := config.SystemContractUpgrades(big.NewInt(5)); upgrades != nil {
+		t.Errorf("unexpected upgrades at block 5: %x", upgrades)
+	}
+	if upgrades := config.SystemContractUpgrades(big.NewInt(10)); len(upgrades) != 1 || !bytes.Equal(upgrades[rewards], []byte{0x03}) {
+		t.Errorf("fork upgrades mismatch: have %x", upgrades)
+	}
+}
+
+func TestConsensusParams(t *testing.T) {
+	config := &ChainConfig{MaxElectDelegate: big.NewInt(21), BlockInterval: big.NewInt(3), EpochLength: 420}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("valid config rejected: %v", err)
+	}
+	if config.Epoch() != 420 || config.EpochOf(839) != 1 || config.EpochOf(840) != 2 {
+		t.Errorf("epoch mismatch: length %d, epoch of 839: %d", config.Epoch(), config.EpochOf(839))
+	}
+	if epoch := (&ChainConfig{}).Epoch(); epoch != EpochDuration {
+		t.Errorf("default epoch length mismatch: have %d, want %d", epoch, EpochDuration)
+	}
+	invalid := []*ChainConfig{
+		{BlockInterval: big.NewInt(3)},
+		{MaxElectDelegate: big.NewInt(21), BlockInterval: big.NewInt(0)},
+		{MaxElectDelegate: big.NewInt(21), BlockInterval: big.NewInt(3), EpochLength: 20},
+		{MaxElectDelegate: big.NewInt(21), BlockInterval: big.NewInt(3), FeeSharingBlock: big.NewInt(10)},
+		{MaxElectDelegate: big.NewInt(21), BlockInterval: big.NewInt(3), VoteDecayBlock: big.NewInt(20), FeeSharingBlock: big.NewInt(10)},
+	}
+	for i, config := range invalid {
+		if err := config.Validate(); err == nil {
+			t.Errorf("invalid config %d accepted", i)
+		}
+	}
+	if err := config.CheckConsensusParams(&ChainConfig{MaxElectDelegate: big.NewInt(21), BlockInterval: big.NewInt(3), EpochLength: 420}); err != nil {
+		t.Errorf("matching consensus parameters rejected: %v", err)
+	}
+	if err := config.CheckConsensusParams(&ChainConfig{MaxElectDelegate: big.NewInt(21), BlockInterval: big.NewInt(5), EpochLength: 420}); err == nil {
+		t.Errorf("changed block interval accepted")
+	}
+	if err := config.CheckConsensusParams(&ChainConfig{MaxElectDelegate: big.NewInt(21), BlockInterval: big.NewInt(3)}); err == nil {
+		t.Errorf("changed epoch length accepted")
+	}
+}
+
+func TestForks(t *testing.T) {
+	// Schedule every fork block of the config and check they are all reported
+	config := new(ChainConfig)
+	value, names := reflect.ValueOf(config).Elem(), 0
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Type == reflect.TypeOf((*big.Int)(nil)) && strings.HasSuffix(field.Name, "Block") {
+			value.Field(i).Set(reflect.ValueOf(big.NewInt(int64(i))))
+			names++
+		}
+	}
+	if forks := config.Forks(); len(forks) != names {
+		t.Errorf("fork schedule mismatch: have %d forks, want %d", len(forks), names)
+	}
+	if !config.IsAres(config.AresBlock) || config.IsAres(new(big.Int).Sub(config.AresBlock, common.Big1)) {
+		t.Errorf("ares activation mismatch at block %v", config.AresBlock)
+	}
+	if err := config.CheckForkOrder(); err != nil {
+		t.Errorf("ordered hard forks rejected: %v", err)
+	}
+	invalid := []*ChainConfig{
+		{AthenaBlock: big.NewInt(10)},
+		{AresBlock: big.NewInt(20), AthenaBlock: big.NewInt(10)},
+	}
+	for i, config := range invalid {
+		if err := config.CheckForkOrder(); err == nil {
+			t.Errorf("invalid fork order %d accepted", i)
+		}
+	}
+}
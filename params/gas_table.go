@@ -16,9 +16,12 @@
 
 package params
 
+// GasTable holds the gas prices of the EVM operations which may be repriced by
+// hard forks. ChainConfig.GasTable selects the table in force at a block.
 type GasTable struct {
 	ExtcodeSize uint64
 	ExtcodeCopy uint64
+	ExtcodeHash uint64
 	Balance     uint64
 	SLoad       uint64
 	Calls       uint64
@@ -32,13 +35,35 @@ type GasTable struct {
 	// to call. May be left nil. Nil means
 	// not charged.
 	CreateBySuicide uint64
+
+	SStoreSet     uint64 // Once per SSTORE setting a zero slot
+	SStoreReset   uint64 // Once per SSTORE changing a non-zero slot
+	SStoreClear   uint64 // Once per SSTORE clearing a slot
+	SStoreRefund  uint64 // Refunded once per SSTORE clearing a slot
+	SuicideRefund uint64 // Refunded once per SELFDESTRUCT
+
+	Sha3       uint64 // Once per SHA3 operation
+	Sha3Word   uint64 // Per word of the SHA3 operation's data
+	Copy       uint64 // Per word copied by the *COPY operations
+	Create     uint64 // Once per CREATE and CREATE2 operation
+	CreateData uint64 // Per byte of code stored by a contract creation
+
+	CallNewAccount    uint64 // Paid for CALL when the destination didn't exist
+	CallValueTransfer uint64 // Paid for CALL when transferring value
+
+	Log      uint64 // Once per LOG* operation
+	LogTopic uint64 // Per topic of a LOG* operation
+	LogData  uint64 // Per byte of a LOG* operation's data
+
+	BalanceOf     uint64 // Once per BALANCEOF operation
+	TransferAsset uint64 // Once per TRANSFERASSET and SENDASSET operation, on top of a call
 }
 
-// GasTableFrontier contain the gas re-prices for
-// the Frontier phase.
+// GasTableFrontier contain the gas prices since the genesis.
 var GasTableFrontier = GasTable{
 	ExtcodeSize: 45,
 	ExtcodeCopy: 45,
+	ExtcodeHash: 45,
 	Balance:     25,
 	SLoad:       20,
 	Calls:       45,
@@ -46,4 +71,63 @@ var GasTableFrontier = GasTable{
 	ExpByte:     4,
 
 	CreateBySuicide: 2500,
+
+	SStoreSet:     SstoreSetGas,
+	SStoreReset:   SstoreResetGas,
+	SStoreClear:   SstoreClearGas,
+	SStoreRefund:  SstoreRefundGas,
+	SuicideRefund: SuicideRefundGas,
+
+	Sha3:       Sha3Gas,
+	Sha3Word:   Sha3WordGas,
+	Copy:       CopyGas,
+	Create:     CreateGas,
+	CreateData: CreateDataGas,
+
+	CallNewAccount:    CallNewAccountGas,
+	CallValueTransfer: CallValueTransferGas,
+
+	Log:      LogGas,
+	LogTopic: LogTopicGas,
+	LogData:  LogDataGas,
+
+	BalanceOf:     BalanceOfGas,
+	TransferAsset: TransferAssetGas,
+}
+
+// GasTableAthena contain the gas re-prices for the athena phase, raising the
+// prices of the operations reading the state to reflect its growth.
+var GasTableAthena = GasTable{
+	ExtcodeSize: 70,
+	ExtcodeCopy: 70,
+	ExtcodeHash: 70,
+	Balance:     70,
+	SLoad:       80,
+	Calls:       70,
+	Suicide:     500,
+	ExpByte:     4,
+
+	CreateBySuicide: 2500,
+
+	SStoreSet:     SstoreSetGas,
+	SStoreReset:   SstoreResetGas,
+	SStoreClear:   SstoreClearGas,
+	SStoreRefund:  SstoreRefundGas,
+	SuicideRefund: SuicideRefundGas,
+
+	Sha3:       Sha3Gas,
+	Sha3Word:   Sha3WordGas,
+	Copy:       CopyGas,
+	Create:     CreateGas,
+	CreateData: CreateDataGas,
+
+	CallNewAccount:    CallNewAccountGas,
+	CallValueTransfer: CallValueTransferGas,
+
+	Log:      LogGas,
+	LogTopic: LogTopicGas,
+	LogData:  LogDataGas,
+
+	BalanceOf:     70,
+	TransferAsset: TransferAssetGas,
 }
@@ -42,6 +42,11 @@ const (
 	CallStipend            uint64 = 1000   // Free gas given at beginning of call.
 	TxGasAssetPublish      uint64 = 100000 // Gas for publishing an asset.
 	MaxContractGasLimit    uint64 = 60000000
+	MaxMissedSlots         uint64 = 3 // Consecutive own slots a delegate may miss before being deactivated
+	VoteHalfLifeEpochs     uint64 = 390 // Epochs after which the weight of a vote not refreshed halves (~90 days)
+	MaxVoteDecayPeriods    uint64 = 64 // Vote half-lives after which the weight of a vote not refreshed is zero
+	FeeSharePercent        uint64 = 50 // Percentage of the transaction fees of a block shared among the voters of its producer
+	MaxFeeShareVoters      uint64 = 128 // Maximum number of the highest staked voters of a delegate sharing its fees
 	MaxOneContractGasLimit uint64 = 1000000
 
 	// Multi-asset
@@ -54,7 +59,7 @@ const (
 	SstoreClearGas   uint64 = 310  // Once per SSTORE operation if the zeroness doesn't change.
 	SstoreRefundGas  uint64 = 950  // Once per SSTORE operation if the zeroness changes to zero.
 	JumpdestGas      uint64 = 1    // Refunded gas, once per SSTORE operation if the zeroness changes to zero.
-	EpochDuration    uint64 = 2000 // Duration between proof-of-work epochs.
+	EpochDuration    uint64 = 2000 // Default number of blocks in a delegate epoch, see ChainConfig.Epoch.
 	CallGas          uint64 = 3    // Once per CALL operation & message call transaction.
 	CreateDataGas    uint64 = 12   //
 	CallCreateDepth  uint64 = 1024 // Maximum depth of call/create stack.
@@ -70,7 +75,10 @@ const (
 	TxDataNonZeroGas uint64 = 4    // Per byte of data attached to a transaction that is not equal to zero. NOTE: Not payable on data of calls between transactions.
 	TxABIGas         uint64 = 3    // Per byte of abi attached to a transaction.
 
-	MaxCodeSize = 24576 // Maximum bytecode to permit for a contract
+	MaxCodeSize     = 24576           // Maximum bytecode to permit for a contract
+	MaxInitCodeSize = 2 * MaxCodeSize // Maximum initcode to permit in a contract creation since athena
+
+	InitCodeWordGas uint64 = 2 // Once per word of the init code of a contract creation since athena
 
 	// Precompiled contract gas prices
 
@@ -82,8 +90,12 @@ const (
 	IdentityBaseGas         uint64 = 1    // Base price for a data copy operation
 	IdentityPerWordGas      uint64 = 1    // Per-work price for a data copy operation
 	ModExpQuadCoeffDiv      uint64 = 2    // Divisor for the quadratic particle of the big int modular exponentiation
+	ModExpAthenaQuadDiv     uint64 = 3    // Divisor for the quadratic particle of the big int modular exponentiation since athena
+	ModExpMinGas            uint64 = 200  // Minimum price of a big int modular exponentiation since athena
+	Blake2FRoundGas         uint64 = 1    // Per-round price for a BLAKE2b F compression
 	Bn256AddGas             uint64 = 32   // Gas needed for an elliptic curve addition
 	Bn256ScalarMulGas       uint64 = 2500 // Gas needed for an elliptic curve scalar multiplication
 	Bn256PairingBaseGas     uint64 = 6250 // Base price for an elliptic curve pairing check
 	Bn256PairingPerPointGas uint64 = 5000 // Per-point price for an elliptic curve pairing check
+	DelegateInfoGas         uint64 = 800  // Price of a query of the delegate information contract
 )
@@ -36,10 +36,27 @@ var Version = func() string {
 	return v
 }()
 
+// Build metadata injected by the build script through the linker. GitCommit is
+// the source revision the binary was built from and GitDate the commit date of
+// that revision (YYYYMMDD), which keeps builds of the same revision reproducible.
+var (
+	GitCommit = ""
+	GitDate   = ""
+)
+
+// VersionWithCommit returns the textual version string extended with the given
+// commit, falling back to the linked in one. The commit date is only appended
+// if it belongs to that commit.
 func VersionWithCommit(gitCommit string) string {
 	vsn := Version
+	if gitCommit == "" {
+		gitCommit = GitCommit
+	}
 	if len(gitCommit) >= 8 {
 		vsn += "-" + gitCommit[:8]
 	}
+	if gitCommit != "" && gitCommit == GitCommit && GitDate != "" {
+		vsn += "-" + GitDate
+	}
 	return vsn
 }
@@ -0,0 +1,42 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import "testing"
+
+func TestVersionWithCommit(t *testing.T) {
+	defer func(commit, date string) { GitCommit, GitDate = commit, date }(GitCommit, GitDate)
+
+	tests := []struct {
+		linkedCommit, linkedDate string
+		commit                   string
+		want                     string
+	}{
+		{"", "", "", Version},
+		{"", "", "0123456789abcdef", Version + "-01234567"},
+		{"", "20261016", "", Version},
+		{"0123456789abcdef", "20261016", "", Version + "-01234567-20261016"},
+		{"0123456789abcdef", "20261016", "0123456789abcdef", Version + "-01234567-20261016"},
+		{"0123456789abcdef", "20261016", "fedcba9876543210", Version + "-fedcba98"},
+	}
+	for i, tt := range tests {
+		GitCommit, GitDate = tt.linkedCommit, tt.linkedDate
+		if have := VersionWithCommit(tt.commit); have != tt.want {
+			t.Errorf("test %d: version mismatch: have %s, want %s", i, have, tt.want)
+		}
+	}
+}
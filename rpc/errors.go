@@ -64,3 +64,29 @@ type shutdownError struct{}
 func (e *shutdownError) ErrorCode() int { return -32000 }
 
 func (e *shutdownError) Error() string { return "server is shutting down" }
+
+// issued when a batch holds more requests than permitted.
+type batchTooLargeError struct{ limit int }
+
+func (e *batchTooLargeError) ErrorCode() int { return -32600 }
+
+func (e *batchTooLargeError) Error() string {
+	return fmt.Sprintf("batch too large, at most %d requests allowed", e.limit)
+}
+
+// issued for the requests of a batch left unexecuted once the responses to the
+// batch exceeded the permitted size.
+type responseTooLargeError struct{}
+
+func (e *responseTooLargeError) ErrorCode() int { return -32003 }
+
+func (e *responseTooLargeError) Error() string { return "batch response too large" }
+
+// issued when a callback panicked while handling the request.
+type callbackPanicError struct{ method string }
+
+func (e *callbackPanicError) ErrorCode() int { return -32603 }
+
+func (e *callbackPanicError) Error() string {
+	return fmt.Sprintf("internal error while handling %s", e.method)
+}
@@ -58,3 +58,34 @@ func testHTTPErrorResponse(t *testing.T, method, contentType, body string, expec
 		t.Fatalf("response code should be %d not %d", expected, code)
 	}
 }
+
+func TestHTTPVirtualHosts(t *testing.T) {
+	handler := newVHostHandler([]string{"localhost"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		host string
+		code int
+	}{
+		{"localhost:8545", http.StatusOK},
+		{"LOCALHOST", http.StatusOK},
+		{"127.0.0.1:8545", http.StatusOK},
+		{"[::1]:8545", http.StatusOK},
+		{"evil.example.com", http.StatusForbidden},
+		{"evil.example.com:8545", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		request := httptest.NewRequest(http.MethodPost, "http://"+tt.host, nil)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != tt.code {
+			t.Errorf("host %s: response code mismatch: have %d, want %d", tt.host, recorder.Code, tt.code)
+		}
+	}
+	// A wildcard accepts any host
+	handler = newVHostHandler([]string{"*"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "http://evil.example.com", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("wildcard host rejected: code %d", recorder.Code)
+	}
+}
@@ -18,12 +18,15 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"runtime"
+	"runtime/debug"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/Aurorachain-io/go-aoa/log"
 	"gopkg.in/fatih/set.v0"
@@ -31,6 +34,19 @@ import (
 
 const MetadataApi = "rpc"
 
+const (
+	// DefaultBatchItemLimit is the default maximum number of requests in a batch.
+	DefaultBatchItemLimit = 1000
+
+	// DefaultBatchResponseLimit is the default maximum aggregate size in bytes of
+	// the responses to a batch.
+	DefaultBatchResponseLimit = 25 * 1024 * 1024
+
+	// batchConcurrency is the maximum number of requests of a batch executed
+	// concurrently.
+	batchConcurrency = 16
+)
+
 // CodecOption specifies which type of messages this codec supports
 type CodecOption int
 
@@ -45,9 +61,11 @@ const (
 // NewServer will create a new server instance with no registered handlers.
 func NewServer() *Server {
 	server := &Server{
-		services: make(serviceRegistry),
-		codecs:   set.New(),
-		run:      1,
+		services:           make(serviceRegistry),
+		codecs:             set.New(),
+		run:                1,
+		batchItemLimit:     DefaultBatchItemLimit,
+		batchResponseLimit: DefaultBatchResponseLimit,
 	}
 
 	// register a default service which will provide meta information about the RPC service such as the services and
@@ -58,6 +76,30 @@ func NewServer() *Server {
 	return server
 }
 
+// SetBatchLimits sets the maximum number of requests in a batch and the maximum
+// aggregate size in bytes of the responses to a batch. Zero disables a limit.
+func (s *Server) SetBatchLimits(itemLimit, responseLimit int) {
+	s.batchItemLimit = itemLimit
+	s.batchResponseLimit = responseLimit
+}
+
+// SetMethodTimeouts sets the maximum execution time of individual methods, keyed
+// by their full name (e.g. aoa_getLogs). The deadline is enforced through the
+// context handed to the callback, so only methods accepting one are capped.
+func (s *Server) SetMethodTimeouts(timeouts map[string]time.Duration) {
+	s.methodTimeouts = timeouts
+}
+
+// SetResultFilter installs a filter rewriting the results of all the methods of
+// a namespace before they are encoded into the response, as well as the payloads
+// of the subscriptions created through the namespace.
+func (s *Server) SetResultFilter(namespace string, filter ResultFilter) {
+	if s.resultFilters == nil {
+		s.resultFilters = make(map[string]ResultFilter)
+	}
+	s.resultFilters[namespace] = filter
+}
+
 // RPCService gives meta information about the server.
 // e.g. gives information about the loaded modules.
 type RPCService struct {
@@ -285,7 +327,7 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		// active the subscription after the sub id was successfully sent to the client
 		activateSub := func() {
 			notifier, _ := NotifierFromContext(ctx)
-			notifier.activate(subid, req.svcname)
+			notifier.activate(subid, req.svcname, s.resultFilters[req.svcname])
 		}
 
 		return codec.CreateResponse(req.id, subid), activateSub
@@ -301,6 +343,11 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 
 	arguments := []reflect.Value{req.callb.rcvr}
 	if req.callb.hasCtx {
+		if timeout := s.methodTimeouts[req.svcname+serviceMethodSeparator+formatName(req.callb.method.Name)]; timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
 		arguments = append(arguments, reflect.ValueOf(ctx))
 	}
 	if len(req.args) > 0 {
@@ -308,7 +355,10 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	}
 
 	// execute RPC method and return result
-	reply := req.callb.method.Func.Call(arguments)
+	reply, err := s.call(req, arguments)
+	if err != nil {
+		return codec.CreateErrorResponse(&req.id, err), nil
+	}
 	if len(reply) == 0 {
 		return codec.CreateResponse(req.id, nil), nil
 	}
@@ -320,7 +370,27 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 			return res, nil
 		}
 	}
-	return codec.CreateResponse(req.id, reply[0].Interface()), nil
+	result := reply[0].Interface()
+	if filter := s.resultFilters[req.svcname]; filter != nil {
+		var ferr error
+		if result, ferr = filter(result); ferr != nil {
+			return codec.CreateErrorResponse(&req.id, &callbackError{ferr.Error()}), nil
+		}
+	}
+	return codec.CreateResponse(req.id, result), nil
+}
+
+// call invokes the callback of a request, isolating any panic raised by it so
+// that a faulty handler only fails its own request instead of the entire node.
+func (s *Server) call(req *serverRequest, arguments []reflect.Value) (reply []reflect.Value, err Error) {
+	defer func() {
+		if r := recover(); r != nil {
+			method := req.svcname + serviceMethodSeparator + req.callb.method.Name
+			log.Error("RPC handler crashed", "method", method, "panic", r, "stack", string(debug.Stack()))
+			reply, err = nil, &callbackPanicError{method}
+		}
+	}()
+	return req.callb.method.Func.Call(arguments), nil
 }
 
 // exec executes the given request and writes the result back using the codec.
@@ -344,21 +414,55 @@ func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest
 	}
 }
 
-// execBatch executes the given requests and writes the result back using the codec.
-// It will only write the response back when the last request is processed.
+// execBatch executes the given requests concurrently and writes the results back
+// using the codec. It will only write the response back when the last request is
+// processed. Batches holding too many requests are rejected as a whole, and once
+// the responses exceed the permitted size, the remaining requests are answered
+// with an error instead of being executed.
 func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*serverRequest) {
-	responses := make([]interface{}, len(requests))
-	var callbacks []func()
+	if s.batchItemLimit > 0 && len(requests) > s.batchItemLimit {
+		if err := codec.Write(codec.CreateErrorResponse(nil, &batchTooLargeError{s.batchItemLimit})); err != nil {
+			log.Error(fmt.Sprintf("%v\n", err))
+			codec.Close()
+		}
+		return
+	}
+	var (
+		responses = make([]interface{}, len(requests))
+		callbacks = make([]func(), len(requests))
+		size      int64 // Aggregate size of the responses so far
+
+		pend  sync.WaitGroup
+		slots = make(chan struct{}, batchConcurrency)
+	)
 	for i, req := range requests {
-		if req.err != nil {
-			responses[i] = codec.CreateErrorResponse(&req.id, req.err)
-		} else {
-			var callback func()
-			if responses[i], callback = s.handle(ctx, codec, req); callback != nil {
-				callbacks = append(callbacks, callback)
+		slots <- struct{}{}
+		pend.Add(1)
+
+		go func(i int, req *serverRequest) {
+			defer func() {
+				<-slots
+				pend.Done()
+			}()
+			switch {
+			case s.batchResponseLimit > 0 && atomic.LoadInt64(&size) >= int64(s.batchResponseLimit):
+				responses[i] = codec.CreateErrorResponse(&req.id, &responseTooLargeError{})
+				return
+			case req.err != nil:
+				responses[i] = codec.CreateErrorResponse(&req.id, req.err)
+			default:
+				responses[i], callbacks[i] = s.handle(ctx, codec, req)
 			}
-		}
+			// Account for the response, encoding it only once
+			if s.batchResponseLimit > 0 {
+				if blob, err := json.Marshal(responses[i]); err == nil {
+					atomic.AddInt64(&size, int64(len(blob)))
+					responses[i] = json.RawMessage(blob)
+				}
+			}
+		}(i, req)
 	}
+	pend.Wait()
 
 	if err := codec.Write(responses); err != nil {
 		log.Error(fmt.Sprintf("%v\n", err))
@@ -367,7 +471,9 @@ func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*s
 
 	// when request holds one of more subscribe requests this allows these subscriptions to be activated
 	for _, c := range callbacks {
-		c()
+		if c != nil {
+			c()
+		}
 	}
 }
 
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"net"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -160,3 +161,191 @@ func TestServerMethodExecution(t *testing.T) {
 func TestServerMethodWithCtx(t *testing.T) {
 	testServerMethodExecution(t, "echoWithCtx")
 }
+
+// serveBatch sends a batch of requests to the server and returns the raw reply.
+func serveBatch(t *testing.T, server *Server, batch []map[string]interface{}) json.RawMessage {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	go server.ServeCodec(NewJSONCodec(serverConn), OptionMethodInvocation)
+
+	if err := json.NewEncoder(clientConn).Encode(batch); err != nil {
+		t.Fatal(err)
+	}
+	var reply json.RawMessage
+	if err := json.NewDecoder(clientConn).Decode(&reply); err != nil {
+		t.Fatal(err)
+	}
+	return reply
+}
+
+func TestServerBatchLimits(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatalf("%v", err)
+	}
+	batch := make([]map[string]interface{}, 40)
+	for i := range batch {
+		batch[i] = map[string]interface{}{
+			"id":      i,
+			"method":  "test_echo",
+			"version": "2.0",
+			"params":  []interface{}{"string arg", i, &Args{"abcde"}},
+		}
+	}
+	// Batches with too many requests must be rejected as a whole
+	server.SetBatchLimits(len(batch)-1, 0)
+
+	var rejected jsonErrResponse
+	if err := json.Unmarshal(serveBatch(t, server, batch), &rejected); err != nil {
+		t.Fatalf("oversized batch not rejected with a single error: %v", err)
+	}
+	if rejected.Error.Code != -32600 {
+		t.Errorf("error code mismatch: have %d, want %d", rejected.Error.Code, -32600)
+	}
+	// Requests beyond the response limit must be answered with errors, in order
+	server.SetBatchLimits(len(batch), 1)
+
+	var responses []struct {
+		Id     int        `json:"id"`
+		Result *Result    `json:"result"`
+		Error  *jsonError `json:"error"`
+	}
+	if err := json.Unmarshal(serveBatch(t, server, batch), &responses); err != nil {
+		t.Fatalf("failed to decode batch responses: %v", err)
+	}
+	if len(responses) != len(batch) {
+		t.Fatalf("response count mismatch: have %d, want %d", len(responses), len(batch))
+	}
+	var failed int
+	for i, response := range responses {
+		if response.Id != i {
+			t.Errorf("response %d: id mismatch: have %d", i, response.Id)
+		}
+		switch {
+		case response.Error != nil:
+			if response.Error.Code != -32003 {
+				t.Errorf("response %d: error code mismatch: have %d, want %d", i, response.Error.Code, -32003)
+			}
+			failed++
+		case response.Result == nil || response.Result.Int != i:
+			t.Errorf("response %d: invalid result %v", i, response.Result)
+		}
+	}
+	if failed < len(batch)-batchConcurrency {
+		t.Errorf("too few requests cut off: have %d, want at least %d", failed, len(batch)-batchConcurrency)
+	}
+}
+
+func TestServerBatchConcurrency(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatalf("%v", err)
+	}
+	batch := make([]map[string]interface{}, 8)
+	for i := range batch {
+		batch[i] = map[string]interface{}{
+			"id":      i,
+			"method":  "test_sleep",
+			"version": "2.0",
+			"params":  []interface{}{100 * time.Millisecond},
+		}
+	}
+	start := time.Now()
+	serveBatch(t, server, batch)
+	if elapsed := time.Since(start); elapsed >= time.Duration(len(batch))*100*time.Millisecond {
+		t.Errorf("batch executed serially: took %v", elapsed)
+	}
+}
+
+type PanicService struct{}
+
+func (s *PanicService) Crash() string { panic("boom") }
+
+func (s *PanicService) Ok() string { return "ok" }
+
+// Tests that a panicking callback only fails its own request, leaving the server
+// and the other requests of the same batch unaffected.
+func TestServerCallbackPanic(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(PanicService)); err != nil {
+		t.Fatalf("%v", err)
+	}
+	batch := []map[string]interface{}{
+		{"jsonrpc": "2.0", "id": 1, "method": "test_crash"},
+		{"jsonrpc": "2.0", "id": 2, "method": "test_ok"},
+	}
+	var responses []struct {
+		ID     int        `json:"id"`
+		Result string     `json:"result"`
+		Error  *jsonError `json:"error"`
+	}
+	if err := json.Unmarshal(serveBatch(t, server, batch), &responses); err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 2 {
+		t.Fatalf("response count mismatch: have %d, want 2", len(responses))
+	}
+	for _, res := range responses {
+		switch res.ID {
+		case 1:
+			if res.Error == nil || res.Error.Code != -32603 {
+				t.Errorf("crashed request error mismatch: have %+v", res.Error)
+			}
+		case 2:
+			if res.Error != nil || res.Result != "ok" {
+				t.Errorf("healthy request failed: result %q, error %+v", res.Result, res.Error)
+			}
+		}
+	}
+}
+
+// Tests that the execution time caps of methods are enforced through the context
+// handed to their callbacks.
+func TestServerMethodTimeouts(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatalf("%v", err)
+	}
+	server.SetMethodTimeouts(map[string]time.Duration{"test_sleep": 50 * time.Millisecond})
+
+	start := time.Now()
+	serveBatch(t, server, []map[string]interface{}{
+		{"jsonrpc": "2.0", "id": 1, "method": "test_sleep", "params": []interface{}{int64(10 * time.Second)}},
+	})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("method ran past its execution cap: %v", elapsed)
+	}
+}
+
+func TestServerResultFilter(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := server.RegisterName("alias", new(Service)); err != nil {
+		t.Fatalf("%v", err)
+	}
+	server.SetResultFilter("alias", func(result interface{}) (interface{}, error) {
+		return strings.ToUpper(result.(Result).String), nil
+	})
+	reply := serveBatch(t, server, []map[string]interface{}{
+		{"jsonrpc": "2.0", "id": 1, "method": "test_echo", "params": []interface{}{"abc", 1, &Args{"x"}}},
+		{"jsonrpc": "2.0", "id": 2, "method": "alias_echo", "params": []interface{}{"abc", 1, &Args{"x"}}},
+	})
+	var results []struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(reply, &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("result count mismatch: have %d, want 2", len(results))
+	}
+	if want := `{"String":"abc","Int":1,"Args":{"S":"x"}}`; string(results[0].Result) != want {
+		t.Errorf("unfiltered result mismatch: have %s, want %s", results[0].Result, want)
+	}
+	if want := `"ABC"`; string(results[1].Result) != want {
+		t.Errorf("filtered result mismatch: have %s, want %s", results[1].Result, want)
+	}
+}
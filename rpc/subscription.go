@@ -37,7 +37,8 @@ type ID string
 type Subscription struct {
 	ID        ID
 	namespace string
-	err       chan error // closed on unsubscribe
+	filter    ResultFilter // optional rewrite of the notification payloads
+	err       chan error   // closed on unsubscribe
 }
 
 // Err returns a channel that is closed when the client send an unsubscribe request.
@@ -93,6 +94,12 @@ func (n *Notifier) Notify(id ID, data interface{}) error {
 
 	sub, active := n.active[id]
 	if active {
+		if sub.filter != nil {
+			var err error
+			if data, err = sub.filter(data); err != nil {
+				return err
+			}
+		}
 		notification := n.codec.CreateNotification(string(id), sub.namespace, data)
 		if err := n.codec.Write(notification); err != nil {
 			n.codec.Close()
@@ -123,12 +130,14 @@ func (n *Notifier) unsubscribe(id ID) error {
 // activate enables a subscription. Until a subscription is enabled all
 // notifications are dropped. This method is called by the RPC server after
 // the subscription ID was sent to client. This prevents notifications being
-// send to the client before the subscription ID is send to the client.
-func (n *Notifier) activate(id ID, namespace string) {
+// send to the client before the subscription ID is send to the client. The
+// payloads of the notifications are rewritten by the optional filter.
+func (n *Notifier) activate(id ID, namespace string, filter ResultFilter) {
 	n.subMu.Lock()
 	defer n.subMu.Unlock()
 	if sub, found := n.inactive[id]; found {
 		sub.namespace = namespace
+		sub.filter = filter
 		n.active[id] = sub
 		delete(n.inactive, id)
 	}
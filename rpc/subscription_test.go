@@ -328,3 +328,29 @@ func TestSubscriptionMultipleNamespaces(t *testing.T) {
 		}
 	}
 }
+
+func TestNotifierResultFilter(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	notifier := newNotifier(NewJSONCodec(serverConn))
+	plain, filtered := notifier.CreateSubscription(), notifier.CreateSubscription()
+	notifier.activate(plain.ID, "test", nil)
+	notifier.activate(filtered.ID, "alias", func(result interface{}) (interface{}, error) {
+		return result.(int) * 2, nil
+	})
+	go func() {
+		notifier.Notify(plain.ID, 21)
+		notifier.Notify(filtered.ID, 21)
+	}()
+	in := json.NewDecoder(clientConn)
+	for _, want := range []float64{21, 42} {
+		var notification jsonNotification
+		if err := in.Decode(&notification); err != nil {
+			t.Fatalf("failed to decode notification: %v", err)
+		}
+		if have := notification.Params.Result.(float64); have != want {
+			t.Errorf("notification payload mismatch: have %v, want %v", have, want)
+		}
+	}
+}
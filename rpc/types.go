@@ -22,6 +22,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Aurorachain-io/go-aoa/common/hexutil"
 	"gopkg.in/fatih/set.v0"
@@ -29,12 +30,16 @@ import (
 
 // API describes the set of methods offered over the RPC interface
 type API struct {
-	Namespace string      // namespace under which the rpc methods of Service are exposed
-	Version   string      // api version for DApp's
-	Service   interface{} // receiver instance which holds the methods
-	Public    bool        // indication if the methods must be considered safe for public use
+	Namespace string       // namespace under which the rpc methods of Service are exposed
+	Version   string       // api version for DApp's
+	Service   interface{}  // receiver instance which holds the methods
+	Public    bool         // indication if the methods must be considered safe for public use
+	Filter    ResultFilter // optional rewrite of the method results of the namespace
 }
 
+// ResultFilter rewrites the result of an RPC method before it is encoded.
+type ResultFilter func(result interface{}) (interface{}, error)
+
 // callback is a method callback which was registered in the server
 type callback struct {
 	rcvr        reflect.Value  // receiver of method
@@ -74,6 +79,12 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   *set.Set
+
+	batchItemLimit     int // Maximum number of requests in a batch, 0 if unlimited
+	batchResponseLimit int // Maximum aggregate size of the responses to a batch, 0 if unlimited
+
+	methodTimeouts map[string]time.Duration // Execution time caps of individual methods, keyed by full name
+	resultFilters  map[string]ResultFilter  // Rewrites of the method results, keyed by namespace
 }
 
 // rpcRequest represents a raw incoming RPC request
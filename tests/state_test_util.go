@@ -125,7 +125,10 @@ func (t *StateTest) Run(subtest StateSubtest, vmconfig vm.Config) (*state.StateD
 	if !ok {
 		return nil, UnsupportedForkError{subtest.Fork}
 	}
-	block, _, _ := t.genesis(config).ToBlock()
+	block, _, _, err := t.genesis(config).ToBlock()
+	if err != nil {
+		return nil, err
+	}
 	db, _ := emdb.NewMemDatabase()
 	statedb := MakePreState(db, t.json.Pre)
 
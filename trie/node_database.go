@@ -0,0 +1,468 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/metrics"
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+var (
+	memcacheCleanHitMeter   = metrics.NewMeter("trie/memcache/clean/hit")
+	memcacheCleanMissMeter  = metrics.NewMeter("trie/memcache/clean/miss")
+	memcacheGCNodesMeter    = metrics.NewMeter("trie/memcache/gc/nodes")
+	memcacheGCSizeMeter     = metrics.NewMeter("trie/memcache/gc/size")
+	memcacheFlushNodesMeter = metrics.NewMeter("trie/memcache/flush/nodes")
+	memcacheFlushSizeMeter  = metrics.NewMeter("trie/memcache/flush/size")
+)
+
+// LeafResolver returns the roots of the tries referenced from a leaf value of a
+// trie, e.g. the storage trie root of an account. Referenced tries are kept
+// alive by the node containing the leaf.
+type LeafResolver func(leaf []byte) []common.Hash
+
+// cachedNode is a trie node committed to the node database but not yet flushed
+// to disk, along with its reference counts.
+type cachedNode struct {
+	blob     []byte        // Encoded node
+	parents  int           // Number of live nodes and roots referencing this one
+	children []common.Hash // Nodes and tries referenced by this one
+
+	flushPrev common.Hash // Previous node in the flush list
+	flushNext common.Hash // Next node in the flush list
+}
+
+// size returns the approximate memory used by the cached node.
+func (n *cachedNode) size() common.StorageSize {
+	return common.StorageSize(len(n.blob) + (len(n.children)+3)*hashLen)
+}
+
+// NodeDatabase is an intermediate write layer between the tries and the disk
+// database. Committed nodes are kept in memory with reference counts, so nodes
+// of states no longer needed can be garbage collected before ever reaching the
+// disk, and are only flushed when a state is committed explicitly or the memory
+// limit is exceeded. Nodes read from disk are kept in a size limited clean cache.
+//
+// Keys that aren't node hashes, i.e. the secure trie preimages, are buffered
+// until the next flush. NodeDatabase implements Database and is safe for
+// concurrent use.
+type NodeDatabase struct {
+	diskdb aoadb.Database // Persistent storage of the flushed nodes
+	leaf   LeafResolver   // Resolver of the tries referenced from leaves, if any
+
+	nodes     map[common.Hash]*cachedNode // Dirty nodes not yet flushed to disk
+	oldest    common.Hash                 // Oldest dirty node, flushed first
+	newest    common.Hash                 // Newest dirty node, flushed last
+	nodesSize common.StorageSize          // Memory used by the dirty nodes
+
+	preimages     map[string][]byte  // Secure key preimages pending a flush
+	preimagesSize common.StorageSize // Memory used by the pending preimages
+
+	cleans      *simplelru.LRU     // Recently read nodes, nil if disabled
+	cleansSize  common.StorageSize // Memory used by the clean nodes
+	cleansLimit common.StorageSize // Memory allowance of the clean nodes
+	cleansLock  sync.Mutex         // Lock protecting the clean cache, reordered on reads
+
+	lock sync.RWMutex
+}
+
+// NewNodeDatabase creates a node database on top of the given disk database,
+// caching at most cleanLimit bytes of clean nodes. The optional leaf resolver
+// is used to link the tries referenced from leaves to their parents.
+func NewNodeDatabase(diskdb aoadb.Database, cleanLimit int, leaf LeafResolver) *NodeDatabase {
+	db := &NodeDatabase{
+		diskdb:      diskdb,
+		leaf:        leaf,
+		nodes:       make(map[common.Hash]*cachedNode),
+		preimages:   make(map[string][]byte),
+		cleansLimit: common.StorageSize(cleanLimit),
+	}
+	if cleanLimit > 0 {
+		// The clean cache is bounded by size, not by the number of entries
+		db.cleans, _ = simplelru.NewLRU(1<<31-1, func(key, value interface{}) {
+			db.cleansSize -= common.StorageSize(hashLen + len(value.([]byte)))
+		})
+	}
+	return db
+}
+
+// DiskDB returns the persistent database backing the node database.
+func (db *NodeDatabase) DiskDB() aoadb.Database {
+	return db.diskdb
+}
+
+// Put inserts a node committed by a trie into the dirty cache, or buffers a
+// preimage until the next flush.
+func (db *NodeDatabase) Put(key, value []byte) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if len(key) != hashLen {
+		if _, ok := db.preimages[string(key)]; !ok {
+			db.preimages[string(key)] = common.CopyBytes(value)
+			db.preimagesSize += common.StorageSize(len(key) + len(value))
+		}
+		return nil
+	}
+	hash := common.BytesToHash(key)
+	if _, ok := db.nodes[hash]; ok {
+		return nil
+	}
+	node := &cachedNode{
+		blob:      common.CopyBytes(value),
+		children:  db.references(key, value),
+		flushPrev: db.newest,
+	}
+	// Nodes are committed bottom up, any dirty children are already present
+	for _, child := range node.children {
+		if c, ok := db.nodes[child]; ok {
+			c.parents++
+		}
+	}
+	db.nodes[hash] = node
+
+	if db.oldest == (common.Hash{}) {
+		db.oldest = hash
+	} else {
+		db.nodes[db.newest].flushNext = hash
+	}
+	db.newest = hash
+	db.nodesSize += node.size()
+
+	return nil
+}
+
+// references gathers the hashes of the nodes referenced by an encoded node,
+// including the tries referenced from its leaves.
+func (db *NodeDatabase) references(hash, blob []byte) []common.Hash {
+	n, err := decodeNode(hash, blob, 0)
+	if err != nil {
+		return nil
+	}
+	var (
+		refs []common.Hash
+		walk func(n node)
+	)
+	walk = func(n node) {
+		switch n := n.(type) {
+		case *shortNode:
+			walk(n.Val)
+		case *fullNode:
+			for _, child := range n.Children {
+				walk(child)
+			}
+		case hashNode:
+			refs = append(refs, common.BytesToHash(n))
+		case valueNode:
+			if db.leaf != nil {
+				refs = append(refs, db.leaf(n)...)
+			}
+		}
+	}
+	walk(n)
+	return refs
+}
+
+// Get retrieves a node from the dirty cache, the clean cache or the disk, in
+// this order.
+func (db *NodeDatabase) Get(key []byte) ([]byte, error) {
+	db.lock.RLock()
+	if len(key) != hashLen {
+		preimage, ok := db.preimages[string(key)]
+		db.lock.RUnlock()
+		if ok {
+			return preimage, nil
+		}
+		return db.diskdb.Get(key)
+	}
+	hash := common.BytesToHash(key)
+	node, ok := db.nodes[hash]
+	db.lock.RUnlock()
+	if ok {
+		return node.blob, nil
+	}
+	if blob, ok := db.getClean(hash); ok {
+		memcacheCleanHitMeter.Mark(1)
+		return blob, nil
+	}
+	memcacheCleanMissMeter.Mark(1)
+
+	blob, err := db.diskdb.Get(key)
+	if err == nil {
+		db.addClean(hash, blob)
+	}
+	return blob, err
+}
+
+// Has reports whether a node is present in any of the caches or on disk.
+func (db *NodeDatabase) Has(key []byte) (bool, error) {
+	if len(key) != hashLen {
+		db.lock.RLock()
+		_, ok := db.preimages[string(key)]
+		db.lock.RUnlock()
+		if ok {
+			return true, nil
+		}
+		return db.diskdb.Has(key)
+	}
+	hash := common.BytesToHash(key)
+
+	db.lock.RLock()
+	_, ok := db.nodes[hash]
+	db.lock.RUnlock()
+	if ok {
+		return true, nil
+	}
+	if _, ok := db.getClean(hash); ok {
+		return true, nil
+	}
+	return db.diskdb.Has(key)
+}
+
+// getClean retrieves a node from the clean cache.
+func (db *NodeDatabase) getClean(hash common.Hash) ([]byte, bool) {
+	if db.cleans == nil {
+		return nil, false
+	}
+	db.cleansLock.Lock()
+	defer db.cleansLock.Unlock()
+
+	blob, ok := db.cleans.Get(hash)
+	if !ok {
+		return nil, false
+	}
+	return blob.([]byte), true
+}
+
+// addClean inserts a node into the clean cache, evicting the least recently
+// used ones beyond the memory allowance.
+func (db *NodeDatabase) addClean(hash common.Hash, blob []byte) {
+	if db.cleans == nil {
+		return
+	}
+	db.cleansLock.Lock()
+	defer db.cleansLock.Unlock()
+
+	if db.cleans.Contains(hash) {
+		return
+	}
+	db.cleans.Add(hash, blob)
+	db.cleansSize += common.StorageSize(hashLen + len(blob))
+	for db.cleansSize > db.cleansLimit {
+		db.cleans.RemoveOldest()
+	}
+}
+
+// Reference marks a state root as in use, keeping it and all dirty nodes it
+// references alive until it's dereferenced again.
+func (db *NodeDatabase) Reference(root common.Hash) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if node, ok := db.nodes[root]; ok {
+		node.parents++
+	}
+}
+
+// Dereference releases a state root referenced earlier, garbage collecting all
+// dirty nodes not referenced by any other live node any more.
+func (db *NodeDatabase) Dereference(root common.Hash) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	nodes, size := len(db.nodes), db.nodesSize
+	db.dereference(root)
+
+	memcacheGCNodesMeter.Mark(int64(nodes - len(db.nodes)))
+	memcacheGCSizeMeter.Mark(int64(size - db.nodesSize))
+}
+
+// dereference drops a reference to a node, deleting it along with the children
+// only it referenced once the count reaches zero.
+func (db *NodeDatabase) dereference(hash common.Hash) {
+	node, ok := db.nodes[hash]
+	if !ok {
+		return
+	}
+	// A node flushed and then recommitted isn't counted by its older parents,
+	// don't underflow when those are released.
+	if node.parents > 0 {
+		node.parents--
+	}
+	if node.parents == 0 {
+		db.remove(hash)
+		for _, child := range node.children {
+			db.dereference(child)
+		}
+	}
+}
+
+// remove deletes a node from the dirty cache, unlinking it from the flush list.
+func (db *NodeDatabase) remove(hash common.Hash) *cachedNode {
+	node := db.nodes[hash]
+	if hash == db.oldest {
+		db.oldest = node.flushNext
+	} else {
+		db.nodes[node.flushPrev].flushNext = node.flushNext
+	}
+	if hash == db.newest {
+		db.newest = node.flushPrev
+	} else {
+		db.nodes[node.flushNext].flushPrev = node.flushPrev
+	}
+	delete(db.nodes, hash)
+	db.nodesSize -= node.size()
+
+	return node
+}
+
+// Commit flushes all dirty nodes of the given state and the pending preimages
+// to disk, moving the nodes into the clean cache.
+func (db *NodeDatabase) Commit(root common.Hash) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	start := time.Now()
+	batch := db.diskdb.NewBatch()
+	if err := db.flushPreimages(batch); err != nil {
+		return err
+	}
+	var (
+		written = make(map[common.Hash]struct{})
+		order   []common.Hash
+	)
+	if err := db.commit(root, &batch, written, &order); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	size := db.uncache(order)
+	log.Debug("Persisted trie from memory database", "nodes", len(order), "size", size, "elapsed", common.PrettyDuration(time.Since(start)), "livenodes", len(db.nodes), "livesize", db.nodesSize)
+
+	return nil
+}
+
+// commit writes a dirty node into the batch after all its dirty children, in
+// order to never have a node on disk whose children are missing.
+func (db *NodeDatabase) commit(hash common.Hash, batch *aoadb.Batch, written map[common.Hash]struct{}, order *[]common.Hash) error {
+	node, ok := db.nodes[hash]
+	if !ok {
+		return nil
+	}
+	if _, ok := written[hash]; ok {
+		return nil
+	}
+	for _, child := range node.children {
+		if err := db.commit(child, batch, written, order); err != nil {
+			return err
+		}
+	}
+	if err := (*batch).Put(hash[:], node.blob); err != nil {
+		return err
+	}
+	written[hash] = struct{}{}
+	*order = append(*order, hash)
+
+	if (*batch).ValueSize() >= aoadb.IdealBatchSize {
+		if err := (*batch).Write(); err != nil {
+			return err
+		}
+		*batch = db.diskdb.NewBatch()
+	}
+	return nil
+}
+
+// Cap flushes the oldest dirty nodes to disk until the memory used by the dirty
+// cache drops below the given limit. Since nodes are committed bottom up, the
+// children of a node are always flushed before it.
+func (db *NodeDatabase) Cap(limit common.StorageSize) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	start := time.Now()
+	batch := db.diskdb.NewBatch()
+	if err := db.flushPreimages(batch); err != nil {
+		return err
+	}
+	var (
+		size  = db.nodesSize
+		order []common.Hash
+	)
+	for hash := db.oldest; size > limit && hash != (common.Hash{}); {
+		node := db.nodes[hash]
+		if err := batch.Put(hash[:], node.blob); err != nil {
+			return err
+		}
+		if batch.ValueSize() >= aoadb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch = db.diskdb.NewBatch()
+		}
+		order = append(order, hash)
+		size -= node.size()
+		hash = node.flushNext
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	flushed := db.uncache(order)
+	log.Debug("Persisted nodes from memory database", "nodes", len(order), "size", flushed, "elapsed", common.PrettyDuration(time.Since(start)), "livenodes", len(db.nodes), "livesize", db.nodesSize)
+
+	return nil
+}
+
+// flushPreimages writes the pending preimages into the batch.
+func (db *NodeDatabase) flushPreimages(batch aoadb.Batch) error {
+	for key, preimage := range db.preimages {
+		if err := batch.Put([]byte(key), preimage); err != nil {
+			return err
+		}
+	}
+	db.preimages = make(map[string][]byte)
+	db.preimagesSize = 0
+	return nil
+}
+
+// uncache moves nodes written to disk from the dirty into the clean cache,
+// returning the amount of memory released.
+func (db *NodeDatabase) uncache(hashes []common.Hash) common.StorageSize {
+	size := db.nodesSize
+	for _, hash := range hashes {
+		node := db.remove(hash)
+		db.addClean(hash, node.blob)
+	}
+	memcacheFlushNodesMeter.Mark(int64(len(hashes)))
+	memcacheFlushSizeMeter.Mark(int64(size - db.nodesSize))
+
+	return size - db.nodesSize
+}
+
+// Size returns the memory used by the dirty nodes and the pending preimages.
+func (db *NodeDatabase) Size() (common.StorageSize, common.StorageSize) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.nodesSize, db.preimagesSize
+}
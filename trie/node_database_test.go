@@ -0,0 +1,181 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+)
+
+// hashLeaves resolves leaves holding a trie root, as used by the outer tries of
+// the tests below.
+func hashLeaves(leaf []byte) []common.Hash {
+	if len(leaf) != common.HashLength {
+		return nil
+	}
+	return []common.Hash{common.BytesToHash(leaf)}
+}
+
+// commitTestTrie commits a trie with the given entries into the node database.
+func commitTestTrie(t *testing.T, db *NodeDatabase, root common.Hash, entries map[string]string) common.Hash {
+	tr, err := New(root, db)
+	if err != nil {
+		t.Fatalf("failed to open trie %x: %v", root, err)
+	}
+	for k, v := range entries {
+		tr.Update([]byte(k), []byte(v))
+	}
+	root, err = tr.CommitTo(db)
+	if err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	return root
+}
+
+// checkTestTrie verifies that all entries are retrievable from a trie.
+func checkTestTrie(t *testing.T, db Database, root common.Hash, entries map[string]string) {
+	tr, err := New(root, db)
+	if err != nil {
+		t.Fatalf("failed to open trie %x: %v", root, err)
+	}
+	for k, v := range entries {
+		if have, err := tr.TryGet([]byte(k)); err != nil || string(have) != v {
+			t.Errorf("entry %q mismatch: have %q (%v), want %q", k, have, err, v)
+		}
+	}
+}
+
+// testEntries generates n distinct entries with the given value prefix.
+func testEntries(n int, prefix string) map[string]string {
+	entries := make(map[string]string)
+	for i := 0; i < n; i++ {
+		entries[fmt.Sprintf("key-%03d", i)] = fmt.Sprintf("%s-value-%03d-padded-beyond-a-hash", prefix, i)
+	}
+	return entries
+}
+
+// Tests that nodes are only written to disk when committed, and that releasing
+// a state garbage collects the nodes no other state references.
+func TestNodeDatabaseGC(t *testing.T) {
+	diskdb, _ := aoadb.NewMemDatabase()
+	db := NewNodeDatabase(diskdb, 0, nil)
+
+	first, second := testEntries(100, "first"), testEntries(10, "second")
+	root1 := commitTestTrie(t, db, common.Hash{}, first)
+	root2 := commitTestTrie(t, db, root1, second)
+	db.Reference(root1)
+	db.Reference(root2)
+
+	if diskdb.Len() != 0 {
+		t.Fatalf("nodes written to disk before commit: %d", diskdb.Len())
+	}
+	size, _ := db.Size()
+
+	// Releasing the first state must keep all nodes shared with the second one
+	db.Dereference(root1)
+	if after, _ := db.Size(); after >= size || after == 0 {
+		t.Errorf("dirty size after gc mismatch: have %v, before %v", after, size)
+	}
+	merged := make(map[string]string)
+	for k, v := range first {
+		merged[k] = v
+	}
+	for k, v := range second {
+		merged[k] = v
+	}
+	checkTestTrie(t, db, root2, merged)
+
+	// Committing the second state must move it entirely to disk
+	if err := db.Commit(root2); err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if size, _ := db.Size(); size != 0 {
+		t.Errorf("dirty size after commit mismatch: have %v, want 0", size)
+	}
+	checkTestTrie(t, diskdb, root2, merged)
+
+	if _, err := New(root1, diskdb); err == nil {
+		t.Errorf("released state written to disk")
+	}
+}
+
+// Tests that tries referenced from leaves are kept alive and flushed along with
+// the trie referencing them.
+func TestNodeDatabaseLeafReferences(t *testing.T) {
+	diskdb, _ := aoadb.NewMemDatabase()
+	db := NewNodeDatabase(diskdb, 0, hashLeaves)
+
+	inner := testEntries(50, "inner")
+	innerRoot := commitTestTrie(t, db, common.Hash{}, inner)
+	outerRoot := commitTestTrie(t, db, common.Hash{}, map[string]string{"account": string(innerRoot[:])})
+	db.Reference(outerRoot)
+
+	if err := db.Commit(outerRoot); err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if size, _ := db.Size(); size != 0 {
+		t.Errorf("dirty size after commit mismatch: have %v, want 0", size)
+	}
+	checkTestTrie(t, diskdb, innerRoot, inner)
+
+	// Garbage collecting the outer trie must release the inner one too
+	db = NewNodeDatabase(diskdb, 0, hashLeaves)
+	innerRoot = commitTestTrie(t, db, common.Hash{}, testEntries(50, "other"))
+	outerRoot = commitTestTrie(t, db, common.Hash{}, map[string]string{"account": string(innerRoot[:])})
+	db.Reference(outerRoot)
+	db.Dereference(outerRoot)
+
+	if size, _ := db.Size(); size != 0 {
+		t.Errorf("dirty size after gc mismatch: have %v, want 0", size)
+	}
+}
+
+// Tests that capping the dirty nodes flushes the oldest ones, never leaving a
+// node on disk whose children are missing.
+func TestNodeDatabaseCap(t *testing.T) {
+	diskdb, _ := aoadb.NewMemDatabase()
+	db := NewNodeDatabase(diskdb, 1024*1024, nil)
+
+	entries := testEntries(200, "capped")
+	root := commitTestTrie(t, db, common.Hash{}, entries)
+	db.Reference(root)
+
+	size, _ := db.Size()
+	if err := db.Cap(size / 2); err != nil {
+		t.Fatalf("failed to cap dirty nodes: %v", err)
+	}
+	if after, _ := db.Size(); after > size/2 || after == 0 {
+		t.Errorf("dirty size after cap mismatch: have %v, limit %v", after, size/2)
+	}
+	for _, key := range diskdb.Keys() {
+		blob, _ := diskdb.Get(key)
+		for _, child := range db.references(key, blob) {
+			if has, _ := diskdb.Has(child[:]); !has {
+				t.Errorf("flushed node %x misses child %x", key, child)
+			}
+		}
+	}
+	checkTestTrie(t, db, root, entries)
+
+	if err := db.Cap(0); err != nil {
+		t.Fatalf("failed to cap dirty nodes: %v", err)
+	}
+	checkTestTrie(t, diskdb, root, entries)
+}
@@ -119,6 +119,47 @@ func VerifyProof(rootHash common.Hash, key []byte, proofDb DatabaseReader) (valu
 	}
 }
 
+// ProofList is an ordered list of encoded trie nodes as produced by Prove. It
+// implements DatabaseWriter so it can be passed directly as the proof database.
+type ProofList [][]byte
+
+// Put implements DatabaseWriter, appending a copy of the node to the list.
+func (n *ProofList) Put(key []byte, value []byte) error {
+	*n = append(*n, common.CopyBytes(value))
+	return nil
+}
+
+// Get implements DatabaseReader, looking up a node by its hash.
+func (n ProofList) Get(key []byte) ([]byte, error) {
+	for _, node := range n {
+		if bytes.Equal(crypto.Keccak256(node), key) {
+			return node, nil
+		}
+	}
+	return nil, nil
+}
+
+// Has implements DatabaseReader, reporting whether a node with the given hash
+// is part of the list.
+func (n ProofList) Has(key []byte) (bool, error) {
+	node, _ := n.Get(key)
+	return node != nil, nil
+}
+
+// VerifyProofList checks a merkle proof given as a list of encoded nodes against
+// the root hash, returning the proven value or nil if the proof shows that the
+// key is absent.
+func VerifyProofList(rootHash common.Hash, key []byte, proof ProofList) ([]byte, error) {
+	value, err, _ := VerifyProof(rootHash, key, proof)
+	return value, err
+}
+
+// VerifySecureProof checks a merkle proof of a secure trie, in which keys are
+// stored by their keccak256 hash, such as the state and storage tries.
+func VerifySecureProof(rootHash common.Hash, key []byte, proof ProofList) ([]byte, error) {
+	return VerifyProofList(rootHash, crypto.Keccak256(key), proof)
+}
+
 func get(tn node, key []byte) ([]byte, node) {
 	for {
 		switch n := tn.(type) {
@@ -0,0 +1,56 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/Aurorachain-io/go-aoa/crypto"
+)
+
+func TestProofList(t *testing.T) {
+	trie := new(Trie)
+	for i := 0; i < 256; i++ {
+		trie.Update(crypto.Keccak256([]byte(fmt.Sprintf("key-%d", i))), []byte(fmt.Sprintf("value-%d", i)))
+	}
+	root := trie.Hash()
+	for i := 0; i < 256; i++ {
+		var proof ProofList
+		if err := trie.Prove(crypto.Keccak256([]byte(fmt.Sprintf("key-%d", i))), 0, &proof); err != nil {
+			t.Fatalf("key %d: failed to prove: %v", i, err)
+		}
+		value, err := VerifySecureProof(root, []byte(fmt.Sprintf("key-%d", i)), proof)
+		if err != nil {
+			t.Fatalf("key %d: failed to verify: %v", i, err)
+		}
+		if want := []byte(fmt.Sprintf("value-%d", i)); !bytes.Equal(value, want) {
+			t.Fatalf("key %d: value mismatch: have %q, want %q", i, value, want)
+		}
+	}
+	// Absent keys must prove as nil, tampered proofs must fail
+	var proof ProofList
+	trie.Prove(crypto.Keccak256([]byte("missing")), 0, &proof)
+	if value, err := VerifySecureProof(root, []byte("missing"), proof); value != nil || err != nil {
+		t.Fatalf("absent key: have %x/%v, want nil/nil", value, err)
+	}
+	proof = proof[1:]
+	if _, err := VerifySecureProof(root, []byte("missing"), proof); err == nil {
+		t.Fatalf("truncated proof verified")
+	}
+}
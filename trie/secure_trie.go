@@ -84,6 +84,12 @@ func (t *SecureTrie) TryGet(key []byte) ([]byte, error) {
 	return t.trie.TryGet(t.hashKey(key))
 }
 
+// Depth returns the number of nodes on the path to the given key that are
+// resolved in memory, see Trie.Depth.
+func (t *SecureTrie) Depth(key []byte) int {
+	return t.trie.Depth(t.hashKey(key))
+}
+
 // Update associates key with value in the trie. Subsequent calls to
 // Get will return value. If value has length zero, any existing value
 // is deleted from the trie and calls to Get will return nil.
@@ -167,6 +173,12 @@ func (t *SecureTrie) NodeIterator(start []byte) NodeIterator {
 	return t.trie.NodeIterator(start)
 }
 
+// Prove constructs a merkle proof for key, see Trie.Prove. The key is expected
+// to be hashed already, as it is stored in the underlying trie.
+func (t *SecureTrie) Prove(key []byte, fromLevel uint, proofDb DatabaseWriter) error {
+	return t.trie.Prove(key, fromLevel, proofDb)
+}
+
 // CommitTo writes all nodes and the secure hash pre-images to the given database.
 // Nodes are stored with their sha3 hash as the key.
 //
@@ -192,6 +192,29 @@ func (t *Trie) tryGet(origNode node, key []byte, pos int) (value []byte, newnode
 	}
 }
 
+// Depth returns the number of nodes on the path to the given key that are
+// resolved in memory. Directly after a lookup of the key this is the depth
+// the lookup descended to. No nodes are loaded from the database.
+func (t *Trie) Depth(key []byte) int {
+	key = keybytesToHex(key)
+	depth, n := 0, t.root
+	for {
+		switch nd := n.(type) {
+		case *shortNode:
+			depth++
+			if len(key) < len(nd.Key) || !bytes.Equal(nd.Key, key[:len(nd.Key)]) {
+				return depth
+			}
+			key, n = key[len(nd.Key):], nd.Val
+		case *fullNode:
+			depth++
+			key, n = key[1:], nd.Children[key[0]]
+		default:
+			return depth
+		}
+	}
+}
+
 // Update associates key with value in the trie. Subsequent calls to
 // Get will return value. If value has length zero, any existing value
 // is deleted from the trie and calls to Get will return nil.
@@ -0,0 +1,67 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package txscheduler
+
+import (
+	"context"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/common/hexutil"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/rlp"
+)
+
+// PrivateSchedulerAPI provides an API to schedule transactions for inclusion
+// from a given block on. It lists and cancels the transactions of all senders,
+// so it is only exposed to the node operator.
+type PrivateSchedulerAPI struct {
+	s *Service
+}
+
+// NewPrivateSchedulerAPI creates a new transaction scheduler API.
+func NewPrivateSchedulerAPI(s *Service) *PrivateSchedulerAPI {
+	return &PrivateSchedulerAPI{s}
+}
+
+// Schedule holds the given signed transaction back until the given block, its
+// earliest inclusion block, and returns its hash.
+func (api *PrivateSchedulerAPI) Schedule(ctx context.Context, encodedTx hexutil.Bytes, block hexutil.Uint64) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+		return common.Hash{}, err
+	}
+	if err := api.s.Schedule(tx, uint64(block)); err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), nil
+}
+
+// Scheduled returns the hashes of the transactions held back, mapped to the
+// block they are scheduled for.
+func (api *PrivateSchedulerAPI) Scheduled() map[common.Hash]hexutil.Uint64 {
+	scheduled := make(map[common.Hash]hexutil.Uint64)
+	for hash, block := range api.s.Scheduled() {
+		scheduled[hash] = hexutil.Uint64(block)
+	}
+	return scheduled
+}
+
+// Cancel drops the scheduled transaction with the given hash, reporting whether
+// it was still held back.
+func (api *PrivateSchedulerAPI) Cancel(hash common.Hash) (bool, error) {
+	return api.s.Cancel(hash)
+}
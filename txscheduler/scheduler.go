@@ -0,0 +1,316 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package txscheduler implements a node service holding signed transactions
+// back until the block they are scheduled for, and submitting them to the
+// transaction pool just in time to be included into it.
+package txscheduler
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/event"
+	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/p2p"
+	"github.com/Aurorachain-io/go-aoa/params"
+	"github.com/Aurorachain-io/go-aoa/rlp"
+	"github.com/Aurorachain-io/go-aoa/rpc"
+)
+
+const (
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+
+	// maxScheduled is the maximum number of transactions held back at once.
+	maxScheduled = 4096
+)
+
+var (
+	// ErrAlreadyScheduled is returned if a transaction is scheduled twice.
+	ErrAlreadyScheduled = errors.New("transaction already scheduled")
+
+	// ErrSchedulerFull is returned if no more transactions can be scheduled.
+	ErrSchedulerFull = errors.New("scheduled transaction queue is full")
+)
+
+type txPool interface {
+	// AddLocal should add the given transaction to the pool as a local one.
+	AddLocal(tx *types.Transaction) error
+}
+
+type blockChain interface {
+	Config() *params.ChainConfig
+	CurrentBlock() *types.Block
+	State() (*state.StateDB, error)
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// scheduledTx is a transaction held back until its earliest inclusion block.
+type scheduledTx struct {
+	Block uint64
+	Tx    *types.Transaction
+}
+
+// Service is a node service holding transactions in a scheduled queue until
+// their earliest inclusion block, persisting the queue across restarts.
+type Service struct {
+	chain blockChain
+	pool  txPool
+	path  string // Filesystem path to persist the scheduled queue at
+
+	queue map[common.Hash]*scheduledTx
+	mu    sync.Mutex
+
+	headSub event.Subscription
+	headCh  chan core.ChainHeadEvent
+	wg      sync.WaitGroup
+}
+
+// New creates a transaction scheduler submitting transactions to the given pool
+// as the given chain advances, persisting its queue at path.
+func New(path string, chain blockChain, pool txPool) *Service {
+	return &Service{
+		chain:  chain,
+		pool:   pool,
+		path:   path,
+		queue:  make(map[common.Hash]*scheduledTx),
+		headCh: make(chan core.ChainHeadEvent, chainHeadChanSize),
+	}
+}
+
+// Protocols implements node.Service, returning the P2P network protocols used
+// by the scheduler (nil as it doesn't use the devp2p overlay network).
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning the RPC API endpoints provided by the
+// scheduler.
+func (s *Service) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "scheduler",
+			Version:   "1.0",
+			Service:   NewPrivateSchedulerAPI(s),
+		},
+	}
+}
+
+// Start implements node.Service, loading the persisted queue and submitting the
+// transactions due as the chain advances.
+func (s *Service) Start(server *p2p.Server) error {
+	if err := s.load(); err != nil {
+		log.Warn("Failed to load scheduled transactions", "err", err)
+	}
+	s.headSub = s.chain.SubscribeChainHeadEvent(s.headCh)
+	s.promote(s.chain.CurrentBlock().NumberU64())
+
+	s.wg.Add(1)
+	go s.loop()
+
+	log.Info("Transaction scheduler started", "scheduled", s.Len())
+	return nil
+}
+
+// Stop implements node.Service, terminating the scheduler. The queue is always
+// persisted, so nothing is lost.
+func (s *Service) Stop() error {
+	s.headSub.Unsubscribe()
+	s.wg.Wait()
+
+	log.Info("Transaction scheduler stopped")
+	return nil
+}
+
+// loop submits the transactions due whenever a new head is imported.
+func (s *Service) loop() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case ev := <-s.headCh:
+			s.promote(ev.Block.NumberU64())
+
+		case <-s.headSub.Err():
+			return
+		}
+	}
+}
+
+// Schedule holds the signed transaction back until the given block. If the
+// next block is already late enough, it is submitted to the pool right away.
+// Transactions whose nonce was already used or whose sender can't pay for them
+// at the current head are rejected.
+func (s *Service) Schedule(tx *types.Transaction, block uint64) error {
+	head := s.chain.CurrentBlock()
+	from, err := types.Sender(types.MakeSigner(s.chain.Config(), head.Number()), tx)
+	if err != nil {
+		return err
+	}
+	statedb, err := s.chain.State()
+	if err != nil {
+		return err
+	}
+	if statedb.GetNonce(from) > tx.Nonce() {
+		return core.ErrNonceTooLow
+	}
+	if statedb.GetBalance(from).Cmp(tx.EmCost()) < 0 {
+		return core.ErrInsufficientFunds
+	}
+	if block <= head.NumberU64()+1 {
+		return s.pool.AddLocal(tx)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.queue[tx.Hash()]; ok {
+		return ErrAlreadyScheduled
+	}
+	if len(s.queue) >= maxScheduled {
+		return ErrSchedulerFull
+	}
+	s.queue[tx.Hash()] = &scheduledTx{Block: block, Tx: tx}
+	return s.save()
+}
+
+// Cancel drops the scheduled transaction with the given hash, reporting whether
+// it was still held back.
+func (s *Service) Cancel(hash common.Hash) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.queue[hash]; !ok {
+		return false, nil
+	}
+	delete(s.queue, hash)
+	return true, s.save()
+}
+
+// Scheduled returns the transactions held back with the block they are
+// scheduled for.
+func (s *Service) Scheduled() map[common.Hash]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scheduled := make(map[common.Hash]uint64, len(s.queue))
+	for hash, stx := range s.queue {
+		scheduled[hash] = stx.Block
+	}
+	return scheduled
+}
+
+// Len returns the number of transactions held back.
+func (s *Service) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.queue)
+}
+
+// promote submits the transactions which may be included into the block after
+// the given head to the pool, in block and nonce order. Transactions rejected
+// by the pool are dropped.
+func (s *Service) promote(head uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*scheduledTx
+	for hash, stx := range s.queue {
+		if stx.Block <= head+1 {
+			due = append(due, stx)
+			delete(s.queue, hash)
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].Block != due[j].Block {
+			return due[i].Block < due[j].Block
+		}
+		return due[i].Tx.Nonce() < due[j].Tx.Nonce()
+	})
+	for _, stx := range due {
+		if err := s.pool.AddLocal(stx.Tx); err != nil {
+			log.Warn("Dropped scheduled transaction", "hash", stx.Tx.Hash(), "block", stx.Block, "err", err)
+		} else {
+			log.Debug("Submitted scheduled transaction", "hash", stx.Tx.Hash(), "block", stx.Block)
+		}
+	}
+	if err := s.save(); err != nil {
+		log.Warn("Failed to persist scheduled transactions", "err", err)
+	}
+}
+
+// load reads the persisted queue from disk, if any.
+func (s *Service) load() error {
+	input, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stream := rlp.NewStream(input, 0)
+	for {
+		stx := new(scheduledTx)
+		if err := stream.Decode(stx); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		s.queue[stx.Tx.Hash()] = stx
+	}
+}
+
+// save persists the queue to disk, writing it to a temporary file which is
+// synced and then renamed over the previous one, so a crash leaves either the
+// old or the new queue behind. The lock must be held by the caller.
+func (s *Service) save() error {
+	if s.path == "" {
+		return nil
+	}
+	output, err := os.OpenFile(s.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	for _, stx := range s.queue {
+		if err = rlp.Encode(output, stx); err != nil {
+			output.Close()
+			return err
+		}
+	}
+	if err = output.Sync(); err != nil {
+		output.Close()
+		return err
+	}
+	if err = output.Close(); err != nil {
+		return err
+	}
+	return os.Rename(s.path+".new", s.path)
+}
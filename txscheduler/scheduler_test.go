@@ -0,0 +1,212 @@
+// Copyright 2021 The go-aoa Authors
+// This file is part of the go-aoa library.
+//
+// The the go-aoa library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The the go-aoa library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aoa library. If not, see <http://www.gnu.org/licenses/>.
+
+package txscheduler
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Aurorachain-io/go-aoa/aoadb"
+	"github.com/Aurorachain-io/go-aoa/common"
+	"github.com/Aurorachain-io/go-aoa/core"
+	"github.com/Aurorachain-io/go-aoa/core/state"
+	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/crypto"
+	"github.com/Aurorachain-io/go-aoa/event"
+	"github.com/Aurorachain-io/go-aoa/params"
+)
+
+// testChain is a blockchain stub whose head is advanced by the tests.
+type testChain struct {
+	head     uint64
+	statedb  *state.StateDB
+	headFeed event.Feed
+	mu       sync.Mutex
+}
+
+func newTestChain(head uint64) *testChain {
+	db, _ := aoadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	return &testChain{head: head, statedb: statedb}
+}
+
+func (c *testChain) Config() *params.ChainConfig { return params.TestChainConfig }
+
+func (c *testChain) CurrentBlock() *types.Block {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(c.head)}, nil, nil)
+}
+
+func (c *testChain) State() (*state.StateDB, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.statedb.Copy(), nil
+}
+
+func (c *testChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return c.headFeed.Subscribe(ch)
+}
+
+func (c *testChain) setHead(number uint64) {
+	c.mu.Lock()
+	c.head = number
+	c.mu.Unlock()
+
+	c.headFeed.Send(core.ChainHeadEvent{Block: c.CurrentBlock()})
+}
+
+// testPool is a transaction pool stub recording the submitted transactions.
+type testPool struct {
+	added chan *types.Transaction
+}
+
+func (p *testPool) AddLocal(tx *types.Transaction) error {
+	p.added <- tx
+	return nil
+}
+
+// scheduledTransaction creates a transaction from a new account, which is funded
+// in the chain's state.
+func scheduledTransaction(t *testing.T, chain *testChain, nonce uint64) *types.Transaction {
+	key, _ := crypto.GenerateKey()
+
+	chain.mu.Lock()
+	chain.statedb.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(params.Em))
+	chain.mu.Unlock()
+
+	tx := types.NewTransaction(nonce, common.HexToAddress("0x01"), big.NewInt(1), 100000, big.NewInt(1), nil, types.ActionTrans, nil, "")
+	signed, err := types.SignTx(tx, types.NewAuroraSigner(params.TestChainConfig.ChainId), key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return signed
+}
+
+// Tests that scheduled transactions are submitted to the pool once the next
+// block is the one they are scheduled for, and that the scheduled queue
+// survives restarts.
+func TestScheduler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txscheduler-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scheduled.rlp")
+
+	chain := newTestChain(1)
+	pool := &testPool{added: make(chan *types.Transaction, 10)}
+
+	scheduler := New(path, chain, pool)
+	if err := scheduler.Start(nil); err != nil {
+		t.Fatalf("failed to start scheduler: %v", err)
+	}
+	early, late, now := scheduledTransaction(t, chain, 0), scheduledTransaction(t, chain, 0), scheduledTransaction(t, chain, 0)
+	if err := scheduler.Schedule(early, 5); err != nil {
+		t.Fatalf("failed to schedule transaction: %v", err)
+	}
+	if err := scheduler.Schedule(early, 5); err != ErrAlreadyScheduled {
+		t.Fatalf("rescheduling error mismatch: have %v, want %v", err, ErrAlreadyScheduled)
+	}
+	if err := scheduler.Schedule(late, 10); err != nil {
+		t.Fatalf("failed to schedule transaction: %v", err)
+	}
+	// Transactions due in the next block are submitted right away
+	if err := scheduler.Schedule(now, 2); err != nil {
+		t.Fatalf("failed to schedule transaction: %v", err)
+	}
+	expectAdded(t, pool, now)
+	scheduler.Stop()
+
+	// Restart the scheduler and ensure the transactions are submitted just in time
+	scheduler = New(path, chain, pool)
+	if err := scheduler.Start(nil); err != nil {
+		t.Fatalf("failed to restart scheduler: %v", err)
+	}
+	defer scheduler.Stop()
+
+	if scheduled := scheduler.Scheduled(); len(scheduled) != 2 || scheduled[early.Hash()] != 5 || scheduled[late.Hash()] != 10 {
+		t.Fatalf("reloaded queue mismatch: have %v", scheduled)
+	}
+	chain.setHead(3)
+	expectAdded(t, pool)
+
+	chain.setHead(4)
+	expectAdded(t, pool, early)
+
+	if ok, err := scheduler.Cancel(late.Hash()); !ok || err != nil {
+		t.Fatalf("failed to cancel transaction: %v, %v", ok, err)
+	}
+	chain.setHead(10)
+	expectAdded(t, pool)
+
+	if n := scheduler.Len(); n != 0 {
+		t.Errorf("scheduled transaction count mismatch: have %d, want 0", n)
+	}
+}
+
+// Tests that transactions which can't be executed at the current head are not
+// scheduled.
+func TestSchedulerValidation(t *testing.T) {
+	chain := newTestChain(1)
+	scheduler := New("", chain, &testPool{added: make(chan *types.Transaction, 10)})
+
+	tx := scheduledTransaction(t, chain, 0)
+	from, _ := types.Sender(types.NewAuroraSigner(params.TestChainConfig.ChainId), tx)
+
+	chain.statedb.SetNonce(from, 1)
+	if err := scheduler.Schedule(tx, 10); err != core.ErrNonceTooLow {
+		t.Errorf("stale nonce error mismatch: have %v, want %v", err, core.ErrNonceTooLow)
+	}
+	chain.statedb.SetNonce(from, 0)
+	chain.statedb.SetBalance(from, big.NewInt(1))
+	if err := scheduler.Schedule(tx, 10); err != core.ErrInsufficientFunds {
+		t.Errorf("unfunded error mismatch: have %v, want %v", err, core.ErrInsufficientFunds)
+	}
+	if n := scheduler.Len(); n != 0 {
+		t.Errorf("scheduled transaction count mismatch: have %d, want 0", n)
+	}
+}
+
+// expectAdded checks that exactly the given transactions are submitted to the
+// pool in the given order.
+func expectAdded(t *testing.T, pool *testPool, txs ...*types.Transaction) {
+	t.Helper()
+
+	for _, want := range txs {
+		select {
+		case tx := <-pool.added:
+			if tx.Hash() != want.Hash() {
+				t.Fatalf("submitted transaction mismatch: have %x, want %x", tx.Hash(), want.Hash())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("transaction %x not submitted", want.Hash())
+		}
+	}
+	select {
+	case tx := <-pool.added:
+		t.Fatalf("unexpected transaction submitted: %x", tx.Hash())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
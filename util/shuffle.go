@@ -19,9 +19,11 @@ package util
 import (
 	"bytes"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"github.com/Aurorachain-io/go-aoa/core/types"
 	"github.com/Aurorachain-io/go-aoa/log"
+	"github.com/Aurorachain-io/go-aoa/params"
 	"math"
 	"strconv"
 	"time"
@@ -60,16 +62,60 @@ func Shuffle(height int64, delegateNumber int) []int {
 
 }
 
+// ShuffleV2 permutes the indices of delegateNumber delegates by a Fisher-Yates
+// shuffle, drawing the swap positions from a sha256 hash chain seeded with the
+// given height.
+func ShuffleV2(height int64, delegateNumber int) []int {
+	truncDelegateList := make([]int, delegateNumber)
+	for i := range truncDelegateList {
+		truncDelegateList[i] = i
+	}
+	seed := make([]byte, 8)
+	binary.BigEndian.PutUint64(seed, uint64(height))
+	md := sha256.Sum256(seed)
+
+	for i, offset := delegateNumber-1, 0; i > 0; i, offset = i-1, offset+8 {
+		if offset+8 > len(md) {
+			md, offset = sha256.Sum256(md[:]), 0
+		}
+		j := int(binary.BigEndian.Uint64(md[offset:offset+8]) % uint64(i+1))
+		truncDelegateList[i], truncDelegateList[j] = truncDelegateList[j], truncDelegateList[i]
+	}
+	return truncDelegateList
+}
+
+// ShuffleIndices permutes the indices of delegateNumber delegates by the shuffle
+// algorithm of the given version, see params.ShuffleV1 and params.ShuffleV2.
+func ShuffleIndices(version int, height int64, delegateNumber int) []int {
+	if version >= params.ShuffleV2 {
+		return ShuffleV2(height, delegateNumber)
+	}
+	return Shuffle(height, delegateNumber)
+}
+
 // beginTime: current shuffle time
 // delegateNumber: Max delegate number
 // currentDposList: current top delegates
-func ShuffleNewRound(beginTime int64, maxElectDelegate int, currentDposList []types.Candidate, blockInterval int64) []types.ShuffleDel {
+// version: shuffle algorithm version active at the shuffled block
+func ShuffleNewRound(version int, beginTime int64, maxElectDelegate int, currentDposList []types.Candidate, blockInterval int64) []types.ShuffleDel {
 	if len(currentDposList) < maxElectDelegate {
 		maxElectDelegate = len(currentDposList)
 	}
 	log.Info("shuffle", "beginTime", beginTime, "current delegate", len(currentDposList), "delegateNumber", maxElectDelegate)
+	return ShuffleRound(version, beginTime, maxElectDelegate, currentDposList, blockInterval)
+}
+
+// ShuffleRound shuffles a round like ShuffleNewRound, but without the info level
+// logging, for recomputing past rounds in bulk.
+func ShuffleRound(version int, beginTime int64, maxElectDelegate int, currentDposList []types.Candidate, blockInterval int64) []types.ShuffleDel {
+	if len(currentDposList) < maxElectDelegate {
+		maxElectDelegate = len(currentDposList)
+	}
+	if maxElectDelegate == 0 {
+		return nil
+	}
 	var newRoundList []types.ShuffleDel
-	truncDelegateList := Shuffle(beginTime+1, maxElectDelegate)
+	truncDelegateList := ShuffleIndices(version, beginTime+1, maxElectDelegate)
 	log.Debug("shuffle", "beginTime", time.Unix(beginTime, 0), "trunc", truncDelegateList)
 
 	for index := int64(0); index < int64(maxElectDelegate); index++ {
@@ -19,6 +19,7 @@ package util
 import (
 	"fmt"
 	"github.com/Aurorachain-io/go-aoa/core/types"
+	"github.com/Aurorachain-io/go-aoa/params"
 	"testing"
 	"time"
 )
@@ -33,6 +34,28 @@ func TestShuffle(t *testing.T) {
 	}
 }
 
+// Tests that the v2 shuffle deterministically permutes all delegate indices.
+func TestShuffleV2(t *testing.T) {
+	for _, delegateNumber := range []int{1, 4, 21, 101} {
+		for height := int64(1); height < 100; height++ {
+			indices := ShuffleIndices(params.ShuffleV2, height, delegateNumber)
+			seen := make(map[int]bool)
+			for _, index := range indices {
+				if index < 0 || index >= delegateNumber || seen[index] {
+					t.Fatalf("height %d: not a permutation of %d delegates: %v", height, delegateNumber, indices)
+				}
+				seen[index] = true
+			}
+			if len(seen) != delegateNumber {
+				t.Fatalf("height %d: not a permutation of %d delegates: %v", height, delegateNumber, indices)
+			}
+			if again := ShuffleIndices(params.ShuffleV2, height, delegateNumber); fmt.Sprint(again) != fmt.Sprint(indices) {
+				t.Fatalf("height %d: shuffle not deterministic: %v != %v", height, again, indices)
+			}
+		}
+	}
+}
+
 func TestShuffleNewRound(t *testing.T) {
 	var initDelegate = []types.Candidate{
 		{Address: "EM70715a2a44255ddce2779d60ba95968b770fc751", Nickname: "node1"},
@@ -48,7 +71,7 @@ func TestShuffleNewRound(t *testing.T) {
 	}
 
 	lastBlockTime := time.Now().Unix()
-	newRound := ShuffleNewRound(lastBlockTime, 10, initDelegate)
+	newRound := ShuffleNewRound(params.ShuffleV1, lastBlockTime, 10, initDelegate, 10)
 	for _, v := range newRound {
 		fmt.Println(v)
 	}
@@ -56,7 +79,7 @@ func TestShuffleNewRound(t *testing.T) {
 }
 
 func TestCalShuffleTimeByHeaderTime(t *testing.T) {
-	shuffleTime := CalShuffleTimeByHeaderTime(3030, 2040)
+	shuffleTime := CalShuffleTimeByHeaderTime(3030, 2040, 10, 10)
 	fmt.Println(shuffleTime)
 
 }